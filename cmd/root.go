@@ -2,11 +2,17 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
+	"go.opentelemetry.io/otel"
 	"golang.org/x/sync/errgroup"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
@@ -14,11 +20,21 @@ import (
 
 	"github.com/kezhenxu94/bmw-saver/pkg/config"
 	"github.com/kezhenxu94/bmw-saver/pkg/controller"
+	pkgk8s "github.com/kezhenxu94/bmw-saver/pkg/kubernetes"
+	"github.com/kezhenxu94/bmw-saver/pkg/tracing"
+	"github.com/kezhenxu94/bmw-saver/pkg/webhook"
 )
 
 var (
-	configFile string
-	logLevel   string
+	configFile     string
+	logLevel       string
+	httpAddress    string
+	webhookAddress string
+	webhookCert    string
+	webhookKey     string
+	shards         int
+	shardIndex     int
+	leaderElect    bool
 )
 
 // rootCmd represents the base command when called without any subcommands
@@ -60,12 +76,19 @@ func init() {
 	// will be global for your application.
 	rootCmd.PersistentFlags().StringVarP(&configFile, "config", "c", "config.yaml", "Path to the configuration file")
 	rootCmd.PersistentFlags().StringVarP(&logLevel, "log-level", "l", "info", "Log level (debug, info, warn, error)")
+	rootCmd.Flags().StringVar(&httpAddress, "http-address", ":8080", "Address of the internal HTTP server, empty to disable")
+	rootCmd.Flags().StringVar(&webhookAddress, "webhook-address", "", "Address of the HTTPS server of the validating admission webhook, empty to disable")
+	rootCmd.Flags().StringVar(&webhookCert, "webhook-cert-file", "/etc/bmw-saver/webhook/tls.crt", "Path to the TLS certificate of the webhook server")
+	rootCmd.Flags().StringVar(&webhookKey, "webhook-key-file", "/etc/bmw-saver/webhook/tls.key", "Path to the TLS key of the webhook server")
+	rootCmd.Flags().IntVar(&shards, "shards", 1, "Number of shards the node pools are split into by the hash of their names")
+	rootCmd.Flags().IntVar(&shardIndex, "shard-index", 0, "Shard of the node pools scaled by this replica, from 0 to --shards - 1")
+	rootCmd.Flags().BoolVar(&leaderElect, "leader-elect", false, "Elect a leader among the replicas of the shard, only the leader scales node pools")
 }
 
 func run(cmd *cobra.Command, args []string) error {
 	slog.Debug("Starting application", "config_file", configFile)
 
-	// Create Kubernetes client
+	// Create the Kubernetes client shared by the controller, the cloud providers and the config watcher
 	client, err := getKubernetesClient()
 	if err != nil {
 		return fmt.Errorf("failed to create Kubernetes client: %v", err)
@@ -77,8 +100,30 @@ func run(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to read config: %v", err)
 	}
 
+	// Export spans if an OTLP endpoint is configured
+	if opts, ok := tracing.OptionsFromEnv(); ok {
+		slog.Info("Exporting traces", "endpoint", opts.Endpoint)
+		provider, err := tracing.NewProvider(context.Background(), opts)
+		if err != nil {
+			return fmt.Errorf("failed to set up tracing: %v", err)
+		}
+		otel.SetTracerProvider(provider)
+		defer func() {
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := provider.Shutdown(shutdownCtx); err != nil {
+				slog.Warn("Failed to export remaining spans", "error", err)
+			}
+		}()
+	}
+
+	shard := controller.Shard{Index: shardIndex, Count: shards}
+	if err := shard.Validate(); err != nil {
+		return err
+	}
+
 	// Create controller
-	controller, err := controller.NewScalingController(client, cfg)
+	controller, err := controller.NewScalingController(client, cfg, controller.Options{Shard: shard})
 	if err != nil {
 		return fmt.Errorf("failed to create controller: %v", err)
 	}
@@ -87,8 +132,9 @@ func run(cmd *cobra.Command, args []string) error {
 	watcher := config.NewWatcher(configFile, client)
 	watcher.OnConfigChange(controller.UpdateConfig)
 
-	// Start the watcher and controller
-	ctx := context.Background()
+	// Start the watcher and controller, they stop gracefully on SIGTERM or SIGINT
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
 	errGroup, ctx := errgroup.WithContext(ctx)
 
 	errGroup.Go(func() error {
@@ -96,13 +142,91 @@ func run(cmd *cobra.Command, args []string) error {
 	})
 
 	errGroup.Go(func() error {
-		return controller.Run()
+		if !leaderElect {
+			return controller.Run(ctx)
+		}
+		identity, err := os.Hostname()
+		if err != nil {
+			return fmt.Errorf("failed to get identity for leader election: %v", err)
+		}
+		return pkgk8s.RunLeaderElected(ctx, client, pkgk8s.LeaderElectionOptions{
+			Namespace:     os.Getenv("NAMESPACE"),
+			Name:          shard.Name("bmw-saver"),
+			Identity:      identity,
+			LeaseDuration: 15 * time.Second,
+			RenewDeadline: 10 * time.Second,
+			RetryPeriod:   2 * time.Second,
+		}, controller.Run)
 	})
 
-	return errGroup.Wait()
+	if httpAddress != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/debug/schedule/preview", controller.PreviewHandler())
+		mux.Handle("/debug/schedule/explain", controller.ExplainHandler())
+		mux.Handle("/debug/state", controller.StateHandler())
+		mux.Handle("/healthz", controller.HealthzHandler())
+		mux.Handle("/readyz", controller.ReadyzHandler())
+		mux.Handle("/metrics", controller.MetricsHandler())
+		mux.Handle("/slack/interactions", controller.SlackInteractionsHandler())
+		mux.Handle("/boost", controller.BoostHandler())
+		mux.Handle("/budget/alerts", controller.BudgetAlertsHandler())
+		server := &http.Server{
+			Addr:              httpAddress,
+			Handler:           mux,
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+
+		errGroup.Go(func() error {
+			slog.Info("Starting HTTP server", "address", httpAddress)
+			if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				return fmt.Errorf("failed to serve HTTP: %v", err)
+			}
+			return nil
+		})
+
+		errGroup.Go(func() error {
+			<-ctx.Done()
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			return server.Shutdown(shutdownCtx)
+		})
+	}
+
+	if webhookAddress != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/validate", webhook.Handler())
+		server := &http.Server{
+			Addr:              webhookAddress,
+			Handler:           mux,
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+
+		errGroup.Go(func() error {
+			slog.Info("Starting webhook server", "address", webhookAddress)
+			if err := server.ListenAndServeTLS(webhookCert, webhookKey); err != nil && err != http.ErrServerClosed {
+				return fmt.Errorf("failed to serve webhook: %v", err)
+			}
+			return nil
+		})
+
+		errGroup.Go(func() error {
+			<-ctx.Done()
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			return server.Shutdown(shutdownCtx)
+		})
+	}
+
+	// Stopping on a signal is not an error
+	if err := errGroup.Wait(); err != nil && !errors.Is(err, context.Canceled) {
+		return err
+	}
+	slog.Info("Stopped")
+	return nil
 }
 
-func getKubernetesClient() (*kubernetes.Clientset, error) {
+// getKubernetesClient creates a Kubernetes client from the local kubeconfig, or the in-cluster config if there is none
+func getKubernetesClient() (kubernetes.Interface, error) {
 	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
 	configOverrides := &clientcmd.ConfigOverrides{}
 	kubeConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, configOverrides)
@@ -0,0 +1,163 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+)
+
+const (
+	// ConfigMapName is the ConfigMap the audit log is kept in by the ConfigMap sink
+	ConfigMapName = "bmw-saver-audit"
+	// dataKey is the key of the audit log in the ConfigMap, one JSON entry per line
+	dataKey = "audit.jsonl"
+)
+
+// Outcome is the result of a scaling decision
+type Outcome string
+
+const (
+	// OutcomeSucceeded is recorded when the node pool was scaled
+	OutcomeSucceeded Outcome = "succeeded"
+	// OutcomeFailed is recorded when scaling the node pool failed
+	OutcomeFailed Outcome = "failed"
+	// OutcomeDeferred is recorded when the scale down was deferred for active workloads
+	OutcomeDeferred Outcome = "deferred"
+	// OutcomeBlocked is recorded when the scale down was blocked by PodDisruptionBudgets or hooks
+	OutcomeBlocked Outcome = "blocked"
+	// OutcomeSkipped is recorded when the action was skipped because the node pool is in cooldown
+	OutcomeSkipped Outcome = "skipped"
+	// OutcomeDrift is recorded when the node pool was resized outside of bmw-saver and is left alone
+	OutcomeDrift Outcome = "drift"
+)
+
+// Entry is a scaling decision on a node pool
+type Entry struct {
+	Time     time.Time `json:"time"`
+	NodePool string    `json:"nodePool"`
+	// Schedule is the name of the schedule of the node pool, "default" for the top-level schedule
+	Schedule string `json:"schedule"`
+	// Action is "restore" or "scale down to <count> nodes"
+	Action string `json:"action"`
+	// From is the node count before the action, nil if it couldn't be counted, and To the
+	// node count of a scale down, nil for restores to the saved configuration
+	From *int   `json:"from,omitempty"`
+	To   *int32 `json:"to,omitempty"`
+	// Reason is why the schedule decided, e.g. "off time: outside Tuesday work hours 09:00-18:00"
+	Reason  string  `json:"reason,omitempty"`
+	Outcome Outcome `json:"outcome"`
+	// Detail is the error of a failure, or why the action was deferred, blocked or skipped, or the drift
+	Detail string `json:"detail,omitempty"`
+}
+
+// Sink records audit log entries
+type Sink interface {
+	Record(ctx context.Context, entry Entry) error
+}
+
+// ConfigMapSink keeps the latest audit log entries in a ConfigMap, dropping the oldest ones
+// once there are more than the max entries
+type ConfigMapSink struct {
+	client     kubernetes.Interface
+	namespace  string
+	maxEntries int
+	mu         sync.Mutex
+}
+
+// NewConfigMapSink creates a sink keeping up to max entries in the audit ConfigMap of the namespace
+func NewConfigMapSink(client kubernetes.Interface, namespace string, maxEntries int) *ConfigMapSink {
+	return &ConfigMapSink{
+		client:     client,
+		namespace:  namespace,
+		maxEntries: maxEntries,
+	}
+}
+
+// Record appends the entry to the audit ConfigMap, creating it if it doesn't exist
+func (s *ConfigMapSink) Record(ctx context.Context, entry Entry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit entry: %v", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	configMaps := s.client.CoreV1().ConfigMaps(s.namespace)
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cm, err := configMaps.Get(ctx, ConfigMapName, metav1.GetOptions{})
+		if k8serrors.IsNotFound(err) {
+			cm = &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: ConfigMapName, Namespace: s.namespace},
+				Data:       map[string]string{dataKey: string(line) + "\n"},
+			}
+			_, err = configMaps.Create(ctx, cm, metav1.CreateOptions{})
+			return err
+		}
+		if err != nil {
+			return err
+		}
+
+		var lines []string
+		if data := strings.TrimSpace(cm.Data[dataKey]); data != "" {
+			lines = strings.Split(data, "\n")
+		}
+		lines = append(lines, string(line))
+		if s.maxEntries > 0 && len(lines) > s.maxEntries {
+			lines = lines[len(lines)-s.maxEntries:]
+		}
+		if cm.Data == nil {
+			cm.Data = make(map[string]string)
+		}
+		cm.Data[dataKey] = strings.Join(lines, "\n") + "\n"
+		_, err = configMaps.Update(ctx, cm, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record audit entry in ConfigMap: %v", err)
+	}
+	return nil
+}
+
+// FileSink appends audit log entries to a file, e.g. on a persistent volume or collected by a log shipper
+type FileSink struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileSink creates a sink appending to the file at path
+func NewFileSink(path string) *FileSink {
+	return &FileSink{path: path}
+}
+
+// Record appends the entry to the file, creating it if it doesn't exist
+func (s *FileSink) Record(ctx context.Context, entry Entry) error {
+	var line bytes.Buffer
+	if err := json.NewEncoder(&line).Encode(entry); err != nil {
+		return fmt.Errorf("failed to marshal audit entry: %v", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %v", err)
+	}
+	if _, err := f.Write(line.Bytes()); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write audit log: %v", err)
+	}
+	return f.Close()
+}
@@ -0,0 +1,60 @@
+package audit
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestConfigMapSink(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	sink := NewConfigMapSink(client, "bmw-saver", 2)
+
+	start := time.Date(2024, time.June, 4, 18, 0, 0, 0, time.UTC)
+	for i, pool := range []string{"pool-a", "pool-b", "pool-c"} {
+		entry := Entry{Time: start.Add(time.Duration(i) * time.Minute), NodePool: pool, Action: "restore", Outcome: OutcomeSucceeded}
+		if err := sink.Record(context.Background(), entry); err != nil {
+			t.Fatalf("Record() error = %v", err)
+		}
+	}
+
+	cm, err := client.CoreV1().ConfigMaps("bmw-saver").Get(context.Background(), ConfigMapName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get audit ConfigMap: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(cm.Data[dataKey]), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], `"pool-b"`) || !strings.Contains(lines[1], `"pool-c"`) {
+		t.Errorf("Audit log = %q, want the entries of pool-b and pool-c", lines)
+	}
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	sink := NewFileSink(path)
+
+	from, to := 3, int32(1)
+	entries := []Entry{
+		{NodePool: "default-pool", Action: "scale down to 1 nodes", From: &from, To: &to, Outcome: OutcomeSucceeded},
+		{NodePool: "default-pool", Action: "restore", Outcome: OutcomeFailed, Detail: "quota exceeded"},
+	}
+	for _, entry := range entries {
+		if err := sink.Record(context.Background(), entry); err != nil {
+			t.Fatalf("Record() error = %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read audit log: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], `"from":3,"to":1`) || !strings.Contains(lines[1], `"detail":"quota exceeded"`) {
+		t.Errorf("Audit log = %q", lines)
+	}
+}
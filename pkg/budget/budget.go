@@ -0,0 +1,162 @@
+// Package budget parses the notifications of cloud budgets, GCP Billing Budgets published to Pub/Sub
+// and AWS Budgets published to SNS, so that bmw-saver can enter savings mode when a budget is exceeded.
+package budget
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Sources of budget alerts
+const (
+	SourceGCP = "gcp"
+	SourceAWS = "aws"
+)
+
+// SNS message types, sent in the x-amz-sns-message-type header
+const (
+	SNSSubscriptionConfirmation = "SubscriptionConfirmation"
+	SNSNotification             = "Notification"
+)
+
+// confirmTimeout is how long confirming an SNS subscription may take
+const confirmTimeout = 30 * time.Second
+
+// Alert is a notification of a cloud budget
+type Alert struct {
+	// Source is where the alert comes from, "gcp" or "aws"
+	Source string
+	// Budget is the name of the budget
+	Budget string
+	// Threshold is the fraction of the budget whose alert threshold was exceeded, e.g. 0.9,
+	// 0 if no threshold was exceeded or it isn't known
+	Threshold float64
+	// Exceeded is true if an alert threshold of the budget was exceeded
+	Exceeded bool
+}
+
+// String returns a description of the alert, e.g. "gcp budget team-a exceeded 90% threshold"
+func (a Alert) String() string {
+	switch {
+	case !a.Exceeded:
+		return fmt.Sprintf("%s budget %s within thresholds", a.Source, a.Budget)
+	case a.Threshold > 0:
+		return fmt.Sprintf("%s budget %s exceeded %.0f%% threshold", a.Source, a.Budget, a.Threshold*100)
+	default:
+		return fmt.Sprintf("%s budget %s exceeded threshold", a.Source, a.Budget)
+	}
+}
+
+// pubSubPush is the body of a Pub/Sub push request
+type pubSubPush struct {
+	Message struct {
+		Data string `json:"data"`
+	} `json:"message"`
+}
+
+// gcpBudgetNotification is the data of a GCP Billing Budgets notification
+type gcpBudgetNotification struct {
+	BudgetDisplayName string `json:"budgetDisplayName"`
+	// AlertThresholdExceeded is only set once a threshold was exceeded in the budget period
+	AlertThresholdExceeded *float64 `json:"alertThresholdExceeded"`
+}
+
+// ParsePubSub parses a Pub/Sub push request of a GCP Billing Budgets notification. Notifications are
+// published several times a day, whether a threshold was exceeded or not.
+func ParsePubSub(body []byte) (Alert, error) {
+	var push pubSubPush
+	if err := json.Unmarshal(body, &push); err != nil {
+		return Alert{}, fmt.Errorf("failed to decode Pub/Sub message: %v", err)
+	}
+	data, err := base64.StdEncoding.DecodeString(push.Message.Data)
+	if err != nil {
+		return Alert{}, fmt.Errorf("failed to decode Pub/Sub message data: %v", err)
+	}
+	var notification gcpBudgetNotification
+	if err := json.Unmarshal(data, &notification); err != nil {
+		return Alert{}, fmt.Errorf("failed to decode budget notification: %v", err)
+	}
+	if notification.BudgetDisplayName == "" {
+		return Alert{}, fmt.Errorf("not a budget notification")
+	}
+
+	alert := Alert{Source: SourceGCP, Budget: notification.BudgetDisplayName}
+	if threshold := notification.AlertThresholdExceeded; threshold != nil {
+		alert.Exceeded, alert.Threshold = true, *threshold
+	}
+	return alert, nil
+}
+
+// SNSMessage is the body of an SNS HTTP(S) request
+type SNSMessage struct {
+	Type         string `json:"Type"`
+	TopicArn     string `json:"TopicArn"`
+	Subject      string `json:"Subject"`
+	Message      string `json:"Message"`
+	SubscribeURL string `json:"SubscribeURL"`
+}
+
+// ParseSNS parses an SNS request
+func ParseSNS(body []byte) (SNSMessage, error) {
+	var message SNSMessage
+	if err := json.Unmarshal(body, &message); err != nil {
+		return SNSMessage{}, fmt.Errorf("failed to decode SNS message: %v", err)
+	}
+	if message.Type == "" {
+		return SNSMessage{}, fmt.Errorf("not an SNS message")
+	}
+	return message, nil
+}
+
+// Alert returns the alert of an AWS Budgets notification. AWS Budgets only notifies when
+// a threshold was exceeded, the budget is taken from the "Budget Name:" line of the message.
+func (m SNSMessage) Alert() Alert {
+	alert := Alert{Source: SourceAWS, Budget: m.TopicArn, Exceeded: true}
+	scanner := bufio.NewScanner(strings.NewReader(m.Message))
+	for scanner.Scan() {
+		if name, ok := strings.CutPrefix(strings.TrimSpace(scanner.Text()), "Budget Name:"); ok {
+			alert.Budget = strings.TrimSpace(name)
+			break
+		}
+	}
+	return alert
+}
+
+// ConfirmSNS confirms the subscription of an SNS subscription confirmation by getting its subscribe URL.
+// Only HTTPS URLs of SNS are followed.
+func ConfirmSNS(ctx context.Context, message SNSMessage) error {
+	u, err := url.Parse(message.SubscribeURL)
+	if err != nil {
+		return fmt.Errorf("invalid subscribe URL: %v", err)
+	}
+	if u.Scheme != "https" || !strings.HasPrefix(u.Hostname(), "sns.") || !strings.HasSuffix(u.Hostname(), ".amazonaws.com") {
+		return fmt.Errorf("subscribe URL isn't an SNS URL: %s", message.SubscribeURL)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, confirmTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to confirm subscription: %v", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, body)
+	}
+	return nil
+}
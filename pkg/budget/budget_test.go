@@ -0,0 +1,69 @@
+package budget
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+)
+
+func TestParsePubSub(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    Alert
+		wantErr bool
+	}{
+		{
+			name: "Exceeded",
+			data: `{"budgetDisplayName": "team-a", "alertThresholdExceeded": 0.9, "costAmount": 910.5, "budgetAmount": 1000}`,
+			want: Alert{Source: SourceGCP, Budget: "team-a", Threshold: 0.9, Exceeded: true},
+		},
+		{
+			name: "Not Exceeded",
+			data: `{"budgetDisplayName": "team-a", "costAmount": 100, "budgetAmount": 1000}`,
+			want: Alert{Source: SourceGCP, Budget: "team-a"},
+		},
+		{name: "Not A Budget", data: `{"foo": "bar"}`, wantErr: true},
+		{name: "Invalid", data: `budget`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := `{"message": {"data": "` + base64.StdEncoding.EncodeToString([]byte(tt.data)) + `"}, "subscription": "projects/p/subscriptions/s"}`
+			got, err := ParsePubSub([]byte(body))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParsePubSub() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParsePubSub() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSNSMessage_Alert(t *testing.T) {
+	message, err := ParseSNS([]byte(`{
+		"Type": "Notification",
+		"TopicArn": "arn:aws:sns:us-east-1:123456789012:budgets",
+		"Subject": "AWS Budgets: team-a has exceeded your alert threshold",
+		"Message": "AWS Budget Notification June 04, 2024\nAWS Account 123456789012\n\nDear AWS Customer,\n\nYou requested that we alert you when the ACTUAL Cost associated with your team-a budget is greater than $900.00 for the current month.\n\nBudget Name: team-a\nBudget Type: Cost\nBudgeted Amount: $1,000.00\nAlert Type: ACTUAL\nAlert Threshold: > $900.00\nACTUAL Amount: $910.50"
+	}`))
+	if err != nil {
+		t.Fatalf("ParseSNS() error = %v", err)
+	}
+	want := Alert{Source: SourceAWS, Budget: "team-a", Exceeded: true}
+	if got := message.Alert(); got != want {
+		t.Errorf("Alert() = %+v, want %+v", got, want)
+	}
+}
+
+func TestConfirmSNS_RejectsForeignURLs(t *testing.T) {
+	for _, url := range []string{
+		"http://sns.us-east-1.amazonaws.com/?Action=ConfirmSubscription",
+		"https://example.com/?Action=ConfirmSubscription",
+		"https://sns.us-east-1.amazonaws.com.example.com/?Action=ConfirmSubscription",
+	} {
+		if err := ConfirmSNS(context.Background(), SNSMessage{Type: SNSSubscriptionConfirmation, SubscribeURL: url}); err == nil {
+			t.Errorf("ConfirmSNS(%q) error = nil, want error", url)
+		}
+	}
+}
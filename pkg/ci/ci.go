@@ -0,0 +1,70 @@
+// Package ci checks CI systems for pipelines in flight on the cluster's runners,
+// so that the runner node pools aren't scaled down under them.
+package ci
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// defaultTimeout is how long a request to a CI system may take
+const defaultTimeout = 30 * time.Second
+
+// Source lists the pipelines in flight in a CI system
+type Source interface {
+	// InFlight returns descriptions of the queued or running jobs targeting the cluster's runners
+	InFlight(ctx context.Context) ([]string, error)
+}
+
+// getJSON gets the URL with the header and decodes the JSON response into v
+func getJSON(ctx context.Context, client *http.Client, url string, header http.Header, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %v", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, body)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode response: %v", err)
+	}
+	return nil
+}
+
+// matchesAny returns true if labels is empty or has any of the values
+func matchesAny(labels map[string]bool, values []string) bool {
+	if len(labels) == 0 {
+		return true
+	}
+	for _, value := range values {
+		if labels[value] {
+			return true
+		}
+	}
+	return false
+}
+
+// toSet converts the values to a set
+func toSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, value := range values {
+		set[value] = true
+	}
+	return set
+}
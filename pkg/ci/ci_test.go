@@ -0,0 +1,175 @@
+package ci
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestGitHubActions_InFlight(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer token" {
+			t.Errorf("Authorization = %q, want Bearer token", got)
+		}
+		switch r.URL.Path {
+		case "/repos/example/app/actions/runs":
+			if r.URL.Query().Get("status") == "in_progress" {
+				fmt.Fprint(w, `{"workflow_runs": [{"id": 1, "name": "build"}]}`)
+				return
+			}
+			fmt.Fprint(w, `{"workflow_runs": []}`)
+		case "/repos/example/app/actions/runs/1/jobs":
+			fmt.Fprint(w, `{"jobs": [
+				{"name": "test", "status": "in_progress", "labels": ["k8s-runners"]},
+				{"name": "lint", "status": "completed", "labels": ["k8s-runners"]},
+				{"name": "macos", "status": "queued", "labels": ["macos-latest"]}
+			]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name         string
+		repositories []string
+		labels       []string
+		want         []string
+		wantErr      bool
+	}{
+		{
+			name:         "Labels",
+			repositories: []string{"example/app"},
+			labels:       []string{"k8s-runners"},
+			want:         []string{"GitHub Actions job build/test in example/app#1 in_progress"},
+		},
+		{
+			name:         "All Jobs",
+			repositories: []string{"example/app"},
+			want: []string{
+				"GitHub Actions job build/test in example/app#1 in_progress",
+				"GitHub Actions job build/macos in example/app#1 queued",
+			},
+		},
+		{
+			name:         "Unknown Repository",
+			repositories: []string{"example/unknown"},
+			wantErr:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source, err := NewGitHubActions(server.URL, "token", tt.repositories, tt.labels)
+			if err != nil {
+				t.Fatalf("NewGitHubActions() error = %v", err)
+			}
+			got, err := source.InFlight(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("InFlight() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("InFlight() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGitLabCI_InFlight(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("PRIVATE-TOKEN"); got != "token" {
+			t.Errorf("PRIVATE-TOKEN = %q, want token", got)
+		}
+		if r.URL.RawPath != "/api/v4/projects/group%2Fproject/jobs" {
+			http.NotFound(w, r)
+			return
+		}
+		if got := r.URL.Query()["scope[]"]; !reflect.DeepEqual(got, []string{"pending", "running"}) {
+			t.Errorf("scope = %v, want pending and running", got)
+		}
+		fmt.Fprint(w, `[
+			{"id": 7, "name": "test", "status": "pending", "tag_list": ["k8s"]},
+			{"id": 8, "name": "deploy", "status": "running", "tag_list": ["shell"]}
+		]`)
+	}))
+	defer server.Close()
+
+	source, err := NewGitLabCI(server.URL, "token", []string{"group/project"}, []string{"k8s"})
+	if err != nil {
+		t.Fatalf("NewGitLabCI() error = %v", err)
+	}
+	got, err := source.InFlight(context.Background())
+	if err != nil {
+		t.Fatalf("InFlight() error = %v", err)
+	}
+	if want := []string{"GitLab CI job test in group/project#7 pending"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("InFlight() = %v, want %v", got, want)
+	}
+}
+
+func TestJenkins_InFlight(t *testing.T) {
+	queued, running := true, true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, token, ok := r.BasicAuth(); !ok || user != "admin" || token != "token" {
+			t.Errorf("BasicAuth() = %s, %s, want admin, token", user, token)
+		}
+		switch r.URL.Path {
+		case "/queue/api/json":
+			if queued {
+				fmt.Fprint(w, `{"items": [{"id": 1, "task": {"name": "app"}, "why": "Waiting for next available executor"}]}`)
+				return
+			}
+			fmt.Fprint(w, `{"items": []}`)
+		case "/computer/api/json":
+			executable := "null"
+			if running {
+				executable = `{"fullDisplayName": "infra #42"}`
+			}
+			fmt.Fprintf(w, `{"computer": [
+				{"displayName": "k8s-agent-1", "assignedLabels": [{"name": "k8s"}], "executors": [{"currentExecutable": %s}], "oneOffExecutors": []},
+				{"displayName": "built-in", "assignedLabels": [{"name": "built-in"}], "executors": [], "oneOffExecutors": [{"currentExecutable": {"fullDisplayName": "seed #1"}}]}
+			]}`, executable)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	source, err := NewJenkins(server.URL, "admin", "token", []string{"k8s"}, 10*time.Minute)
+	if err != nil {
+		t.Fatalf("NewJenkins() error = %v", err)
+	}
+	now := time.Date(2024, time.June, 4, 18, 0, 0, 0, time.UTC)
+	source.now = func() time.Time { return now }
+
+	steps := []struct {
+		name    string
+		queued  bool
+		running bool
+		after   time.Duration
+		want    []string
+	}{
+		{
+			name:    "Busy",
+			queued:  true,
+			running: true,
+			want:    []string{"Jenkins build of app queued: Waiting for next available executor", "Jenkins build infra #42 running on k8s-agent-1"},
+		},
+		{name: "Recently Idle", after: 5 * time.Minute, want: []string{"Jenkins idle for 5m0s, less than 10m0s"}},
+		{name: "Idle", after: 10 * time.Minute},
+	}
+	for _, step := range steps {
+		queued, running = step.queued, step.running
+		source.now = func() time.Time { return now.Add(step.after) }
+		got, err := source.InFlight(context.Background())
+		if err != nil {
+			t.Fatalf("%s: InFlight() error = %v", step.name, err)
+		}
+		if !reflect.DeepEqual(got, step.want) {
+			t.Errorf("%s: InFlight() = %v, want %v", step.name, got, step.want)
+		}
+	}
+}
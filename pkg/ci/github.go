@@ -0,0 +1,94 @@
+package ci
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// DefaultGitHubAPIURL is the API of github.com
+const DefaultGitHubAPIURL = "https://api.github.com"
+
+// GitHubActions lists the queued and running workflow jobs of GitHub repositories
+type GitHubActions struct {
+	client       *http.Client
+	apiURL       string
+	token        string
+	repositories []string
+	labels       map[string]bool
+}
+
+// githubWorkflowRuns is the subset of a GitHub workflow runs response we need
+type githubWorkflowRuns struct {
+	WorkflowRuns []struct {
+		ID   int64  `json:"id"`
+		Name string `json:"name"`
+	} `json:"workflow_runs"`
+}
+
+// githubJobs is the subset of a GitHub workflow jobs response we need
+type githubJobs struct {
+	Jobs []struct {
+		Name   string   `json:"name"`
+		Status string   `json:"status"`
+		Labels []string `json:"labels"`
+	} `json:"jobs"`
+}
+
+// NewGitHubActions creates a source of the workflow jobs of the repositories ("owner/name") running on runners
+// with any of the labels, all jobs if labels is empty. apiURL is e.g. the API of a GitHub Enterprise Server.
+func NewGitHubActions(apiURL, token string, repositories, labels []string) (*GitHubActions, error) {
+	if len(repositories) == 0 {
+		return nil, fmt.Errorf("github repositories are required")
+	}
+	if apiURL == "" {
+		apiURL = DefaultGitHubAPIURL
+	}
+	return &GitHubActions{
+		client:       &http.Client{Timeout: defaultTimeout},
+		apiURL:       strings.TrimSuffix(apiURL, "/"),
+		token:        token,
+		repositories: repositories,
+		labels:       toSet(labels),
+	}, nil
+}
+
+// InFlight returns the queued and in progress jobs of the repositories running on the runners
+func (g *GitHubActions) InFlight(ctx context.Context) ([]string, error) {
+	header := http.Header{}
+	header.Set("Accept", "application/vnd.github+json")
+	if g.token != "" {
+		header.Set("Authorization", "Bearer "+g.token)
+	}
+
+	var inFlight []string
+	for _, repository := range g.repositories {
+		for _, status := range []string{"queued", "in_progress"} {
+			var runs githubWorkflowRuns
+			url := fmt.Sprintf("%s/repos/%s/actions/runs?status=%s&per_page=100", g.apiURL, repository, status)
+			if err := getJSON(ctx, g.client, url, header, &runs); err != nil {
+				return nil, fmt.Errorf("failed to list workflow runs of %s: %v", repository, err)
+			}
+
+			for _, run := range runs.WorkflowRuns {
+				var jobs githubJobs
+				url := fmt.Sprintf("%s/repos/%s/actions/runs/%d/jobs?per_page=100", g.apiURL, repository, run.ID)
+				if err := getJSON(ctx, g.client, url, header, &jobs); err != nil {
+					return nil, fmt.Errorf("failed to list jobs of workflow run %s#%d: %v", repository, run.ID, err)
+				}
+				for _, job := range jobs.Jobs {
+					if (job.Status == "queued" || job.Status == "in_progress") && matchesAny(g.labels, job.Labels) {
+						inFlight = append(inFlight, fmt.Sprintf("GitHub Actions job %s/%s in %s#%d %s", run.Name, job.Name, repository, run.ID, job.Status))
+					}
+				}
+			}
+		}
+	}
+	return inFlight, nil
+}
+
+// String returns the name of the source for logging
+func (g *GitHubActions) String() string {
+	return "GitHub Actions"
+}
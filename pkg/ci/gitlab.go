@@ -0,0 +1,75 @@
+package ci
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// DefaultGitLabURL is the URL of gitlab.com
+const DefaultGitLabURL = "https://gitlab.com"
+
+// GitLabCI lists the pending and running jobs of GitLab projects
+type GitLabCI struct {
+	client   *http.Client
+	url      string
+	token    string
+	projects []string
+	tags     map[string]bool
+}
+
+// gitlabJob is the subset of a GitLab job we need
+type gitlabJob struct {
+	ID      int64    `json:"id"`
+	Name    string   `json:"name"`
+	Status  string   `json:"status"`
+	TagList []string `json:"tag_list"`
+}
+
+// NewGitLabCI creates a source of the jobs of the projects (IDs or paths, e.g. "group/project") running on runners
+// with any of the tags, all jobs if tags is empty. gitlabURL is e.g. a self-managed GitLab instance.
+func NewGitLabCI(gitlabURL, token string, projects, tags []string) (*GitLabCI, error) {
+	if len(projects) == 0 {
+		return nil, fmt.Errorf("gitlab projects are required")
+	}
+	if gitlabURL == "" {
+		gitlabURL = DefaultGitLabURL
+	}
+	return &GitLabCI{
+		client:   &http.Client{Timeout: defaultTimeout},
+		url:      strings.TrimSuffix(gitlabURL, "/"),
+		token:    token,
+		projects: projects,
+		tags:     toSet(tags),
+	}, nil
+}
+
+// InFlight returns the pending and running jobs of the projects running on the runners
+func (g *GitLabCI) InFlight(ctx context.Context) ([]string, error) {
+	header := http.Header{}
+	if g.token != "" {
+		header.Set("PRIVATE-TOKEN", g.token)
+	}
+
+	var inFlight []string
+	for _, project := range g.projects {
+		var jobs []gitlabJob
+		u := fmt.Sprintf("%s/api/v4/projects/%s/jobs?scope[]=pending&scope[]=running&per_page=100", g.url, url.PathEscape(project))
+		if err := getJSON(ctx, g.client, u, header, &jobs); err != nil {
+			return nil, fmt.Errorf("failed to list jobs of %s: %v", project, err)
+		}
+		for _, job := range jobs {
+			if matchesAny(g.tags, job.TagList) {
+				inFlight = append(inFlight, fmt.Sprintf("GitLab CI job %s in %s#%d %s", job.Name, project, job.ID, job.Status))
+			}
+		}
+	}
+	return inFlight, nil
+}
+
+// String returns the name of the source for logging
+func (g *GitLabCI) String() string {
+	return "GitLab CI"
+}
@@ -0,0 +1,130 @@
+package ci
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Jenkins lists the queued and running builds of a Jenkins controller
+type Jenkins struct {
+	client *http.Client
+	url    string
+	user   string
+	token  string
+	labels map[string]bool
+	// idleFor is how long Jenkins must have been idle for its builds not to be in flight
+	idleFor time.Duration
+	// lastBusy is when builds were last queued or running, zero if not seen yet
+	lastBusy time.Time
+	mu       sync.Mutex
+	// now returns the current time, replaced in tests
+	now func() time.Time
+}
+
+// jenkinsQueue is the subset of the Jenkins queue API response we need
+type jenkinsQueue struct {
+	Items []struct {
+		ID   int64 `json:"id"`
+		Task struct {
+			Name string `json:"name"`
+		} `json:"task"`
+		Why string `json:"why"`
+	} `json:"items"`
+}
+
+// jenkinsExecutor is an executor of a Jenkins agent, running a build if currentExecutable is set
+type jenkinsExecutor struct {
+	CurrentExecutable *struct {
+		FullDisplayName string `json:"fullDisplayName"`
+	} `json:"currentExecutable"`
+}
+
+// jenkinsComputers is the subset of the Jenkins computer API response we need
+type jenkinsComputers struct {
+	Computer []struct {
+		DisplayName    string `json:"displayName"`
+		AssignedLabels []struct {
+			Name string `json:"name"`
+		} `json:"assignedLabels"`
+		Executors       []jenkinsExecutor `json:"executors"`
+		OneOffExecutors []jenkinsExecutor `json:"oneOffExecutors"`
+	} `json:"computer"`
+}
+
+// NewJenkins creates a source of the builds of the Jenkins controller at jenkinsURL, authenticated with the user
+// and API token. Builds running on agents with any of the labels count, on all agents if labels is empty, and all
+// queued builds count. Builds stay in flight until Jenkins was idle for idleFor.
+func NewJenkins(jenkinsURL, user, token string, labels []string, idleFor time.Duration) (*Jenkins, error) {
+	if jenkinsURL == "" {
+		return nil, fmt.Errorf("jenkins URL is required")
+	}
+	return &Jenkins{
+		client:  &http.Client{Timeout: defaultTimeout},
+		url:     strings.TrimSuffix(jenkinsURL, "/"),
+		user:    user,
+		token:   token,
+		labels:  toSet(labels),
+		idleFor: idleFor,
+		now:     time.Now,
+	}, nil
+}
+
+// InFlight returns the queued builds and the builds running on the agents, or that Jenkins was idle for less
+// than the idle period
+func (j *Jenkins) InFlight(ctx context.Context) ([]string, error) {
+	header := http.Header{}
+	if j.user != "" || j.token != "" {
+		header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(j.user+":"+j.token)))
+	}
+
+	var queue jenkinsQueue
+	if err := getJSON(ctx, j.client, j.url+"/queue/api/json?tree=items[id,task[name],why]", header, &queue); err != nil {
+		return nil, fmt.Errorf("failed to get Jenkins queue: %v", err)
+	}
+	var computers jenkinsComputers
+	tree := "computer[displayName,assignedLabels[name],executors[currentExecutable[fullDisplayName]],oneOffExecutors[currentExecutable[fullDisplayName]]]"
+	if err := getJSON(ctx, j.client, j.url+"/computer/api/json?tree="+tree, header, &computers); err != nil {
+		return nil, fmt.Errorf("failed to get Jenkins executors: %v", err)
+	}
+
+	var inFlight []string
+	for _, item := range queue.Items {
+		inFlight = append(inFlight, fmt.Sprintf("Jenkins build of %s queued: %s", item.Task.Name, item.Why))
+	}
+	for _, computer := range computers.Computer {
+		labels := make([]string, 0, len(computer.AssignedLabels))
+		for _, label := range computer.AssignedLabels {
+			labels = append(labels, label.Name)
+		}
+		if !matchesAny(j.labels, labels) {
+			continue
+		}
+		for _, executor := range append(computer.Executors, computer.OneOffExecutors...) {
+			if executor.CurrentExecutable != nil {
+				inFlight = append(inFlight, fmt.Sprintf("Jenkins build %s running on %s", executor.CurrentExecutable.FullDisplayName, computer.DisplayName))
+			}
+		}
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	now := j.now()
+	if len(inFlight) > 0 {
+		j.lastBusy = now
+		return inFlight, nil
+	}
+	if idle := now.Sub(j.lastBusy); !j.lastBusy.IsZero() && idle < j.idleFor {
+		return []string{fmt.Sprintf("Jenkins idle for %s, less than %s", idle.Round(time.Second), j.idleFor)}, nil
+	}
+	return nil, nil
+}
+
+// String returns the name of the source for logging
+func (j *Jenkins) String() string {
+	return "Jenkins"
+}
@@ -2,14 +2,32 @@ package config
 
 import (
 	"fmt"
+	"html/template"
 	"os"
 	"path/filepath"
 	"reflect"
+	"regexp"
 	"strconv"
+	"strings"
+	"time"
 
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"sigs.k8s.io/yaml"
 )
 
+// Weekdays maps lowercase weekday names used in the configuration to time.Weekday
+var Weekdays = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
 // setDefaults sets default values for a struct using 'default' tags
 func setDefaults(v interface{}) {
 	rv := reflect.ValueOf(v)
@@ -57,28 +75,269 @@ func ReadConfigFromBytes(data []byte) (Config, error) {
 		return Config{}, fmt.Errorf("failed to parse config: %v", err)
 	}
 
-	// Initialize WorkDays if not set
-	if cfg.Schedule.WorkDays == nil {
-		cfg.Schedule.WorkDays = &WorkDays{}
+	// Validate the default and the named schedules
+	tiers, err := prepareSchedule(&cfg.Schedule)
+	if err != nil {
+		return Config{}, err
+	}
+	tiersBySchedule := map[string]map[string]bool{"": tiers}
+	for name, workSchedule := range cfg.Schedules {
+		if name == "" {
+			return Config{}, fmt.Errorf("schedule name must not be empty")
+		}
+		tiers, err := prepareSchedule(&workSchedule)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid schedule %s: %v", name, err)
+		}
+		cfg.Schedules[name] = workSchedule
+		tiersBySchedule[name] = tiers
 	}
 
-	// Set default values
-	setDefaults(&cfg.Schedule)
-	setDefaults(cfg.Schedule.WorkDays)
+	if cfg.SafetyPollInterval != "" {
+		if d, err := time.ParseDuration(cfg.SafetyPollInterval); err != nil || d <= 0 {
+			return Config{}, fmt.Errorf("invalid safety poll interval: %q", cfg.SafetyPollInterval)
+		}
+	}
 
-	// Validate that at least one schedule provider is configured
-	if !hasValidScheduleConfig(cfg.Schedule) {
-		return Config{}, fmt.Errorf("no valid schedule configuration provided")
+	if cfg.ReconcileJitter != "" {
+		if d, err := time.ParseDuration(cfg.ReconcileJitter); err != nil || d < 0 {
+			return Config{}, fmt.Errorf("invalid reconcile jitter: %q", cfg.ReconcileJitter)
+		}
 	}
 
-	// Validate individual configurations if present
-	if hasStaticSchedule(cfg.Schedule) {
-		if err := validateStaticSchedule(cfg.Schedule); err != nil {
+	if cfg.MaxBackoff != "" {
+		if d, err := time.ParseDuration(cfg.MaxBackoff); err != nil || d <= 0 {
+			return Config{}, fmt.Errorf("invalid max backoff: %q", cfg.MaxBackoff)
+		}
+	}
+
+	if verification := cfg.RestoreVerification; verification != nil {
+		setDefaults(verification)
+		if d, err := time.ParseDuration(verification.Timeout); err != nil || d <= 0 {
+			return Config{}, fmt.Errorf("invalid restore verification timeout: %q", verification.Timeout)
+		}
+	}
+
+	if prepare := cfg.PrepareToSleep; prepare != nil {
+		setDefaults(prepare)
+		if d, err := time.ParseDuration(prepare.Timeout); err != nil || d <= 0 {
+			return Config{}, fmt.Errorf("invalid prepare to sleep timeout: %q", prepare.Timeout)
+		}
+	}
+
+	if rebalance := cfg.Rebalance; rebalance != nil {
+		setDefaults(rebalance)
+		if d, err := time.ParseDuration(rebalance.Timeout); err != nil || d <= 0 {
+			return Config{}, fmt.Errorf("invalid rebalance timeout: %q", rebalance.Timeout)
+		}
+		if rebalance.MaxEvictions < 0 {
+			return Config{}, fmt.Errorf("invalid rebalance max evictions: %d", rebalance.MaxEvictions)
+		}
+		if cronJob := rebalance.DeschedulerCronJob; cronJob != "" {
+			if namespace, name, ok := strings.Cut(cronJob, "/"); !ok || namespace == "" || name == "" || strings.Contains(name, "/") {
+				return Config{}, fmt.Errorf("invalid descheduler CronJob, want namespace/name: %q", cronJob)
+			}
+		}
+	}
+
+	if approval := cfg.Approval; approval != nil {
+		setDefaults(approval)
+		if approval.Timeout != "" {
+			if d, err := time.ParseDuration(approval.Timeout); err != nil || d <= 0 {
+				return Config{}, fmt.Errorf("invalid approval timeout: %q", approval.Timeout)
+			}
+		}
+		if d, err := time.ParseDuration(approval.SnoozeFor); err != nil || d <= 0 {
+			return Config{}, fmt.Errorf("invalid approval snooze duration: %q", approval.SnoozeFor)
+		}
+	}
+
+	if savedState := cfg.SavedState; savedState != nil {
+		setDefaults(savedState)
+		if d, err := time.ParseDuration(savedState.GCInterval); err != nil || d <= 0 {
+			return Config{}, fmt.Errorf("invalid saved state GC interval: %q", savedState.GCInterval)
+		}
+		if savedState.MaxAge != "" {
+			if d, err := time.ParseDuration(savedState.MaxAge); err != nil || d <= 0 {
+				return Config{}, fmt.Errorf("invalid saved state max age: %q", savedState.MaxAge)
+			}
+		}
+	}
+
+	if keepAlive := cfg.KeepAlive; keepAlive != nil && keepAlive.MaxExtension != "" {
+		if d, err := time.ParseDuration(keepAlive.MaxExtension); err != nil || d < 0 {
+			return Config{}, fmt.Errorf("invalid keep-alive max extension: %q", keepAlive.MaxExtension)
+		}
+	}
+
+	if boost := cfg.Boost; boost != nil {
+		setDefaults(boost)
+		if d, err := time.ParseDuration(boost.MaxDuration); err != nil || d <= 0 {
+			return Config{}, fmt.Errorf("invalid boost max duration: %q", boost.MaxDuration)
+		}
+	}
+
+	if alerts := cfg.BudgetAlerts; alerts != nil {
+		setDefaults(alerts)
+		if alerts.Threshold < 0 {
+			return Config{}, fmt.Errorf("invalid budget alerts threshold: %v", alerts.Threshold)
+		}
+		if d, err := time.ParseDuration(alerts.Duration); err != nil || d <= 0 {
+			return Config{}, fmt.Errorf("invalid budget alerts duration: %q", alerts.Duration)
+		}
+		if alerts.ScaleDownEarlier != "" {
+			if d, err := time.ParseDuration(alerts.ScaleDownEarlier); err != nil || d < 0 {
+				return Config{}, fmt.Errorf("invalid budget alerts scale down earlier: %q", alerts.ScaleDownEarlier)
+			}
+		}
+	}
+
+	if breaker := cfg.CircuitBreaker; breaker != nil {
+		setDefaults(breaker)
+		if breaker.Failures < 0 {
+			return Config{}, fmt.Errorf("invalid circuit breaker failures: %d", breaker.Failures)
+		}
+		if d, err := time.ParseDuration(breaker.SuspendFor); err != nil || d <= 0 {
+			return Config{}, fmt.Errorf("invalid circuit breaker suspend duration: %q", breaker.SuspendFor)
+		}
+	}
+
+	if cfg.Concurrency < 0 {
+		return Config{}, fmt.Errorf("invalid concurrency: %d", cfg.Concurrency)
+	}
+	if cfg.PoolTimeout != "" {
+		if d, err := time.ParseDuration(cfg.PoolTimeout); err != nil || d <= 0 {
+			return Config{}, fmt.Errorf("invalid pool timeout: %q", cfg.PoolTimeout)
+		}
+	}
+
+	if cfg.Cooldown != "" {
+		if d, err := time.ParseDuration(cfg.Cooldown); err != nil || d < 0 {
+			return Config{}, fmt.Errorf("invalid cooldown: %q", cfg.Cooldown)
+		}
+	}
+
+	if err := validateDriftPolicy(cfg.DriftPolicy); err != nil {
+		return Config{}, err
+	}
+
+	if cfg.Notifications != nil && cfg.Notifications.FailureThreshold < 0 {
+		return Config{}, fmt.Errorf("invalid notification failure threshold: %d", cfg.Notifications.FailureThreshold)
+	}
+
+	if cfg.Hooks != nil {
+		for i := range cfg.Hooks.PreScaleDown {
+			if err := prepareHook(&cfg.Hooks.PreScaleDown[i]); err != nil {
+				return Config{}, fmt.Errorf("invalid preScaleDown hook %d: %v", i, err)
+			}
+		}
+		for i := range cfg.Hooks.PostRestore {
+			if err := prepareHook(&cfg.Hooks.PostRestore[i]); err != nil {
+				return Config{}, fmt.Errorf("invalid postRestore hook %d: %v", i, err)
+			}
+		}
+	}
+
+	if guard := cfg.ScaleDownGuard; guard != nil {
+		setDefaults(guard)
+		if d, err := time.ParseDuration(guard.RecentPodAge); err != nil || d < 0 {
+			return Config{}, fmt.Errorf("invalid scale down guard recent pod age: %q", guard.RecentPodAge)
+		}
+		if d, err := time.ParseDuration(guard.MaxDeferral); err != nil || d <= 0 {
+			return Config{}, fmt.Errorf("invalid scale down guard max deferral: %q", guard.MaxDeferral)
+		}
+		for i := range guard.CIPipelines {
+			if err := validateCIPipelines(&guard.CIPipelines[i]); err != nil {
+				return Config{}, fmt.Errorf("invalid scale down guard CI pipelines %d: %v", i, err)
+			}
+		}
+		for _, framework := range guard.BatchFrameworks {
+			switch framework {
+			case BatchFrameworkJob, BatchFrameworkKubeflow, BatchFrameworkRay, BatchFrameworkSpark:
+			default:
+				return Config{}, fmt.Errorf("invalid scale down guard batch framework %q, must be job, kubeflow, ray or spark", framework)
+			}
+		}
+		if gpu := guard.GPU; gpu != nil {
+			setDefaults(gpu)
+			if len(gpu.ResourceNames) == 0 {
+				gpu.ResourceNames = []string{"nvidia.com/gpu", "amd.com/gpu"}
+			}
+			if d, err := time.ParseDuration(gpu.MaxDeferral); err != nil || d <= 0 {
+				return Config{}, fmt.Errorf("invalid scale down guard GPU max deferral: %q", gpu.MaxDeferral)
+			}
+		}
+	}
+
+	if savings := cfg.Savings; savings != nil {
+		setDefaults(savings)
+		if savings.CarbonIntensity < 0 {
+			return Config{}, fmt.Errorf("invalid savings carbon intensity: %v", savings.CarbonIntensity)
+		}
+		if savings.Store == nil {
+			savings.Store = &CacheStoreConfig{}
+		}
+		if err := validateCacheStore(savings.Store); err != nil {
+			return Config{}, fmt.Errorf("invalid savings store: %v", err)
+		}
+	}
+
+	if rightSizing := cfg.RightSizing; rightSizing != nil {
+		setDefaults(rightSizing)
+		if rightSizing.Workdays == 0 {
+			rightSizing.Workdays = 5
+		}
+		if rightSizing.CPUTarget == 0 {
+			rightSizing.CPUTarget = 0.6
+		}
+		if d, err := time.ParseDuration(rightSizing.Interval); err != nil || d <= 0 {
+			return Config{}, fmt.Errorf("invalid right-sizing interval: %q", rightSizing.Interval)
+		}
+		// The utilization of the last 30 days is kept
+		if rightSizing.Workdays < 0 || rightSizing.Workdays > 30 {
+			return Config{}, fmt.Errorf("invalid right-sizing workdays: %d, must be between 1 and 30", rightSizing.Workdays)
+		}
+		if rightSizing.CPUTarget < 0 || rightSizing.CPUTarget > 1 {
+			return Config{}, fmt.Errorf("invalid right-sizing cpu target: %v", rightSizing.CPUTarget)
+		}
+	}
+
+	if cfg.UsageStore != nil {
+		if err := validateCacheStore(cfg.UsageStore); err != nil {
+			return Config{}, fmt.Errorf("invalid usage store: %v", err)
+		}
+	}
+
+	if audit := cfg.Audit; audit != nil {
+		setDefaults(audit)
+		switch audit.Type {
+		case "configmap":
+		case "file":
+			if !filepath.IsAbs(audit.Path) {
+				return Config{}, fmt.Errorf("audit log path must be absolute: %q", audit.Path)
+			}
+		default:
+			return Config{}, fmt.Errorf("unsupported audit log type: %s", audit.Type)
+		}
+		if audit.MaxEntries < 0 {
+			return Config{}, fmt.Errorf("invalid audit log max entries: %d", audit.MaxEntries)
+		}
+	}
+
+	for i, blackout := range cfg.Blackouts {
+		if err := validateBlackout(blackout, i); err != nil {
 			return Config{}, err
 		}
 	}
-	if cfg.Schedule.GoogleCalendar != nil {
-		if err := validateGoogleCalendarSchedule(cfg.Schedule); err != nil {
+
+	nodePools := make(map[string]bool, len(cfg.NodeSpecs))
+	for _, spec := range cfg.NodeSpecs {
+		nodePools[spec.NodePoolName] = true
+	}
+	for i := range cfg.MaintenanceWindows {
+		window := &cfg.MaintenanceWindows[i]
+		setDefaults(window)
+		if err := validateMaintenanceWindow(*window, i, nodePools); err != nil {
 			return Config{}, err
 		}
 	}
@@ -88,11 +347,308 @@ func ReadConfigFromBytes(data []byte) (Config, error) {
 		if err := validateNodeSpec(spec, i); err != nil {
 			return Config{}, err
 		}
+		tiers, ok := tiersBySchedule[spec.Schedule]
+		if !ok {
+			return Config{}, fmt.Errorf("unknown schedule %q for spec %d", spec.Schedule, i)
+		}
+		for tier := range spec.TierCounts {
+			if !tiers[tier] {
+				return Config{}, fmt.Errorf("unknown tier %q for spec %d", tier, i)
+			}
+		}
+	}
+
+	// Validate workload specs
+	for i := range cfg.WorkloadSpecs {
+		spec := &cfg.WorkloadSpecs[i]
+		if err := prepareWorkloadSpec(spec, i); err != nil {
+			return Config{}, err
+		}
+		if _, ok := tiersBySchedule[spec.Schedule]; !ok {
+			return Config{}, fmt.Errorf("unknown schedule %q for workload spec %d", spec.Schedule, i)
+		}
+	}
+
+	for i := range cfg.SleepPages {
+		page := &cfg.SleepPages[i]
+		setDefaults(page)
+		if page.Namespace == "" || page.Ingress == "" {
+			return Config{}, fmt.Errorf("namespace and ingress are required for sleep pages")
+		}
+		for _, text := range []string{page.Message, page.HTML} {
+			if _, err := template.New("sleep-page").Parse(text); err != nil {
+				return Config{}, fmt.Errorf("invalid template of sleep page %s/%s: %v", page.Namespace, page.Ingress, err)
+			}
+		}
+		if _, ok := tiersBySchedule[page.Schedule]; !ok {
+			return Config{}, fmt.Errorf("unknown schedule %q for sleep page %s/%s", page.Schedule, page.Namespace, page.Ingress)
+		}
+	}
+	for _, database := range cfg.Databases {
+		if database.Provider != "cloudsql" && database.Provider != "rds" {
+			return Config{}, fmt.Errorf("invalid provider %q for database %s, must be cloudsql or rds", database.Provider, database.Name)
+		}
+		if database.Name == "" {
+			return Config{}, fmt.Errorf("name is required for databases")
+		}
+		if _, ok := tiersBySchedule[database.Schedule]; !ok {
+			return Config{}, fmt.Errorf("unknown schedule %q for database %s", database.Schedule, database.String())
+		}
+	}
+	if cfg.DatabaseStartTimeout != "" {
+		if d, err := time.ParseDuration(cfg.DatabaseStartTimeout); err != nil || d <= 0 {
+			return Config{}, fmt.Errorf("invalid database start timeout: %q", cfg.DatabaseStartTimeout)
+		}
+	}
+
+	if gitOps := cfg.GitOps; gitOps != nil {
+		for _, application := range gitOps.ArgoCDApplications {
+			if application.Namespace == "" || application.Name == "" {
+				return Config{}, fmt.Errorf("namespace and name are required for ArgoCD applications")
+			}
+			if _, ok := tiersBySchedule[application.Schedule]; !ok {
+				return Config{}, fmt.Errorf("unknown schedule %q for ArgoCD application %s/%s", application.Schedule, application.Namespace, application.Name)
+			}
+		}
+		for _, resource := range gitOps.FluxResources {
+			if resource.Kind != FluxKindKustomization && resource.Kind != FluxKindHelmRelease {
+				return Config{}, fmt.Errorf("invalid kind %q for Flux resource, must be %s or %s", resource.Kind, FluxKindKustomization, FluxKindHelmRelease)
+			}
+			if resource.Namespace == "" || resource.Name == "" {
+				return Config{}, fmt.Errorf("namespace and name are required for Flux resources")
+			}
+			if _, ok := tiersBySchedule[resource.Schedule]; !ok {
+				return Config{}, fmt.Errorf("unknown schedule %q for Flux %s %s/%s", resource.Schedule, resource.Kind, resource.Namespace, resource.Name)
+			}
+		}
 	}
 
 	return cfg, nil
 }
 
+// prepareWorkloadSpec sets the defaults of a workload spec and validates it
+func prepareWorkloadSpec(spec *WorkloadSpec, index int) error {
+	setDefaults(spec)
+	switch spec.Kind {
+	case WorkloadKindDeployment, WorkloadKindStatefulSet, WorkloadKindScaledObject:
+	default:
+		return fmt.Errorf("invalid kind %q for workload spec %d", spec.Kind, index)
+	}
+	if spec.Namespace == "" {
+		return fmt.Errorf("namespace is required for workload spec %d", index)
+	}
+	if (spec.Name == "") == (spec.Selector == "") {
+		return fmt.Errorf("exactly one of name or selector is required for workload spec %d", index)
+	}
+	if spec.Selector != "" {
+		if _, err := labels.Parse(spec.Selector); err != nil {
+			return fmt.Errorf("invalid selector for workload spec %d: %v", index, err)
+		}
+	}
+	if spec.OffTimeReplicas < 0 {
+		return fmt.Errorf("invalid off-time replicas for workload spec %d", index)
+	}
+	return nil
+}
+
+// prepareHook sets the defaults of a hook and validates it
+func prepareHook(hook *HookConfig) error {
+	setDefaults(hook)
+	if hook.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	configured := 0
+	for _, set := range []bool{hook.HTTP != nil, hook.Job != nil, hook.Velero != nil} {
+		if set {
+			configured++
+		}
+	}
+	if configured != 1 {
+		return fmt.Errorf("exactly one of http, job or velero is required for hook %s", hook.Name)
+	}
+	if hook.HTTP != nil && hook.HTTP.URL == "" {
+		return fmt.Errorf("url is required for hook %s", hook.Name)
+	}
+	// Job and Velero hooks name their Jobs and Backups after the hook
+	if hook.Job != nil || hook.Velero != nil {
+		if errs := validation.IsDNS1123Label(hook.Name); len(errs) > 0 {
+			return fmt.Errorf("invalid name for hook %s: %s", hook.Name, strings.Join(errs, ", "))
+		}
+	}
+	if hook.Velero != nil {
+		setDefaults(hook.Velero)
+		if len(hook.Velero.IncludedNamespaces) == 0 {
+			return fmt.Errorf("includedNamespaces are required for hook %s", hook.Name)
+		}
+		if hook.Velero.TTL != "" {
+			if d, err := time.ParseDuration(hook.Velero.TTL); err != nil || d <= 0 {
+				return fmt.Errorf("invalid ttl for hook %s: %q", hook.Name, hook.Velero.TTL)
+			}
+		}
+	}
+	if hook.Job != nil {
+		if len(hook.Job.Spec.Template.Spec.Containers) == 0 {
+			return fmt.Errorf("containers are required for hook %s", hook.Name)
+		}
+	}
+	if d, err := time.ParseDuration(hook.Timeout); err != nil || d <= 0 {
+		return fmt.Errorf("invalid timeout for hook %s: %q", hook.Name, hook.Timeout)
+	}
+	return nil
+}
+
+// prepareSchedule sets the defaults of a schedule and validates it, it returns the names of its capacity tiers
+func prepareSchedule(schedule *WorkSchedule) (map[string]bool, error) {
+	// Initialize WorkDays if not set
+	if schedule.WorkDays == nil {
+		schedule.WorkDays = &WorkDays{}
+	}
+
+	// Set default values
+	setDefaults(schedule)
+	setDefaults(schedule.WorkDays)
+
+	// Validate that at least one schedule provider is configured
+	if !hasValidScheduleConfig(*schedule) {
+		return nil, fmt.Errorf("no valid schedule configuration provided")
+	}
+
+	// Validate individual configurations if present
+	if hasStaticSchedule(*schedule) {
+		if err := validateStaticSchedule(*schedule); err != nil {
+			return nil, err
+		}
+	}
+	if schedule.GoogleCalendar != nil {
+		if err := validateGoogleCalendarSchedule(*schedule); err != nil {
+			return nil, err
+		}
+	}
+
+	if schedule.ICSCalendar != nil {
+		if schedule.ICSCalendar.URL == "" && len(schedule.ICSCalendar.URLs) == 0 {
+			return nil, fmt.Errorf("url or urls is required for ics calendar schedule")
+		}
+		for _, patterns := range [][]string{schedule.ICSCalendar.WorkDayPatterns, schedule.ICSCalendar.HolidayPatterns} {
+			for _, pattern := range patterns {
+				if _, err := regexp.Compile(pattern); err != nil {
+					return nil, fmt.Errorf("invalid pattern %q for ics calendar schedule: %v", pattern, err)
+				}
+			}
+		}
+		if schedule.ICSCalendar.TimeZone != "" {
+			if _, err := time.LoadLocation(schedule.ICSCalendar.TimeZone); err != nil {
+				return nil, fmt.Errorf("invalid time zone for ics calendar schedule: %v", err)
+			}
+		}
+	}
+	if schedule.TeamsShifts != nil {
+		setDefaults(schedule.TeamsShifts)
+		if schedule.TeamsShifts.TeamID == "" {
+			return nil, fmt.Errorf("team ID is required for teams shifts schedule")
+		}
+		if _, err := time.ParseDuration(schedule.TeamsShifts.SyncInterval); err != nil {
+			return nil, fmt.Errorf("invalid sync interval for teams shifts schedule: %v", err)
+		}
+	}
+
+	if schedule.TeamCalendars != nil {
+		setDefaults(schedule.TeamCalendars)
+		if len(schedule.TeamCalendars.EventTypes) == 0 {
+			schedule.TeamCalendars.EventTypes = []string{"leaves"}
+		}
+		if err := validateTeamCalendarsSchedule(*schedule); err != nil {
+			return nil, err
+		}
+	}
+
+	if schedule.HRTimeOff != nil {
+		setDefaults(schedule.HRTimeOff)
+		if err := validateHRTimeOffSchedule(*schedule); err != nil {
+			return nil, err
+		}
+	}
+
+	if schedule.Prometheus != nil {
+		setDefaults(schedule.Prometheus)
+		if err := validatePrometheusSchedule(*schedule); err != nil {
+			return nil, err
+		}
+	}
+
+	if schedule.ClusterIdle != nil {
+		setDefaults(schedule.ClusterIdle)
+		if _, err := time.ParseDuration(schedule.ClusterIdle.Duration); err != nil {
+			return nil, fmt.Errorf("invalid duration for cluster idle schedule: %v", err)
+		}
+	}
+
+	if schedule.Daylight != nil {
+		setDefaults(schedule.Daylight)
+		if err := validateDaylightSchedule(*schedule); err != nil {
+			return nil, err
+		}
+	}
+
+	if schedule.CarbonAware != nil {
+		setDefaults(schedule.CarbonAware)
+		if err := validateCarbonAwareSchedule(*schedule); err != nil {
+			return nil, err
+		}
+	}
+
+	switch schedule.CompositeMode {
+	case "and", "or":
+	case "quorum":
+		if schedule.Quorum < 1 {
+			return nil, fmt.Errorf("quorum must be at least 1 for quorum composite mode")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported composite mode: %s", schedule.CompositeMode)
+	}
+
+	if schedule.ManualOverride != nil {
+		setDefaults(schedule.ManualOverride)
+	}
+
+	if schedule.RestoreLeadTime != "" {
+		if d, err := time.ParseDuration(schedule.RestoreLeadTime); err != nil || d < 0 {
+			return nil, fmt.Errorf("invalid restore lead time: %q", schedule.RestoreLeadTime)
+		}
+	}
+
+	if schedule.ScaleDownDelay != "" {
+		if d, err := time.ParseDuration(schedule.ScaleDownDelay); err != nil || d < 0 {
+			return nil, fmt.Errorf("invalid scale down delay: %q", schedule.ScaleDownDelay)
+		}
+	}
+
+	if schedule.MaxStaleness != "" {
+		if _, err := time.ParseDuration(schedule.MaxStaleness); err != nil {
+			return nil, fmt.Errorf("invalid max staleness: %v", err)
+		}
+	}
+
+	if schedule.CacheStore != nil {
+		if err := validateCacheStore(schedule.CacheStore); err != nil {
+			return nil, err
+		}
+	}
+
+	tiers := make(map[string]bool)
+	for i, tier := range schedule.Tiers {
+		if err := validateTier(tier, i); err != nil {
+			return nil, err
+		}
+		if tiers[tier.Name] {
+			return nil, fmt.Errorf("duplicate tier name %q", tier.Name)
+		}
+		tiers[tier.Name] = true
+	}
+
+	return tiers, nil
+}
+
 // ReadConfig reads config from a file path
 func ReadConfig(path string) (Config, error) {
 	if !filepath.IsAbs(path) {
@@ -116,6 +672,130 @@ func validateStaticSchedule(schedule WorkSchedule) error {
 	if schedule.TimeZone == "" {
 		return fmt.Errorf("time zone is required for static schedule")
 	}
+	if _, err := time.Parse("15:04", schedule.StartTime); err != nil {
+		return fmt.Errorf("invalid start time %q for static schedule", schedule.StartTime)
+	}
+	if _, err := time.Parse("15:04", schedule.EndTime); err != nil {
+		return fmt.Errorf("invalid end time %q for static schedule", schedule.EndTime)
+	}
+	if _, err := time.LoadLocation(schedule.TimeZone); err != nil {
+		return fmt.Errorf("invalid time zone %q for static schedule: %v", schedule.TimeZone, err)
+	}
+	for day, hours := range schedule.DayHours {
+		if _, ok := Weekdays[strings.ToLower(day)]; !ok {
+			return fmt.Errorf("invalid weekday %q in day hours", day)
+		}
+		if _, err := time.Parse("15:04", hours.StartTime); err != nil {
+			return fmt.Errorf("invalid start time for %s: %v", day, err)
+		}
+		if _, err := time.Parse("15:04", hours.EndTime); err != nil {
+			return fmt.Errorf("invalid end time for %s: %v", day, err)
+		}
+	}
+	if schedule.Exceptions != nil {
+		offDates := make(map[string]bool)
+		for _, date := range schedule.Exceptions.OffDates {
+			if _, err := time.Parse("2006-01-02", date); err != nil {
+				return fmt.Errorf("invalid off date %q: %v", date, err)
+			}
+			offDates[date] = true
+		}
+		for _, date := range schedule.Exceptions.WorkDates {
+			if _, err := time.Parse("2006-01-02", date); err != nil {
+				return fmt.Errorf("invalid work date %q: %v", date, err)
+			}
+			if offDates[date] {
+				return fmt.Errorf("date %s is both an off date and a work date", date)
+			}
+		}
+	}
+	return nil
+}
+
+func validateTier(tier CapacityTier, index int) error {
+	if tier.Name == "" {
+		return fmt.Errorf("name is required for tier %d", index)
+	}
+	if onCall := tier.OnCall; onCall != nil {
+		if tier.StartTime != "" || tier.EndTime != "" || len(tier.Days) > 0 {
+			return fmt.Errorf("on-call tier %s can't have start time, end time or days", tier.Name)
+		}
+		if onCall.System != "pagerduty" && onCall.System != "opsgenie" {
+			return fmt.Errorf("unsupported on-call system for tier %s: %s", tier.Name, onCall.System)
+		}
+		if onCall.ScheduleID == "" {
+			return fmt.Errorf("on-call schedule ID is required for tier %s", tier.Name)
+		}
+		return nil
+	}
+	if _, err := time.Parse("15:04", tier.StartTime); err != nil {
+		return fmt.Errorf("invalid start time for tier %s: %v", tier.Name, err)
+	}
+	if _, err := time.Parse("15:04", tier.EndTime); err != nil {
+		return fmt.Errorf("invalid end time for tier %s: %v", tier.Name, err)
+	}
+	for _, day := range tier.Days {
+		if _, ok := Weekdays[strings.ToLower(day)]; !ok {
+			return fmt.Errorf("invalid weekday %q for tier %s", day, tier.Name)
+		}
+	}
+	return nil
+}
+
+func validateBlackout(blackout BlackoutWindow, index int) error {
+	start, err := time.Parse(time.RFC3339, blackout.Start)
+	if err != nil {
+		return fmt.Errorf("invalid start time for blackout %d: %v", index, err)
+	}
+	end, err := time.Parse(time.RFC3339, blackout.End)
+	if err != nil {
+		return fmt.Errorf("invalid end time for blackout %d: %v", index, err)
+	}
+	if !end.After(start) {
+		return fmt.Errorf("end time must be after start time for blackout %d", index)
+	}
+	return nil
+}
+
+func validateMaintenanceWindow(window MaintenanceWindow, index int, nodePools map[string]bool) error {
+	for _, nodePool := range window.NodePools {
+		if !nodePools[nodePool] {
+			return fmt.Errorf("unknown node pool %q for maintenance window %d", nodePool, index)
+		}
+	}
+
+	if window.Start != "" || window.End != "" {
+		if window.StartTime != "" || window.EndTime != "" || len(window.Days) > 0 {
+			return fmt.Errorf("maintenance window %d must either have start and end, or startTime and endTime", index)
+		}
+		start, err := time.Parse(time.RFC3339, window.Start)
+		if err != nil {
+			return fmt.Errorf("invalid start for maintenance window %d: %v", index, err)
+		}
+		end, err := time.Parse(time.RFC3339, window.End)
+		if err != nil {
+			return fmt.Errorf("invalid end for maintenance window %d: %v", index, err)
+		}
+		if !end.After(start) {
+			return fmt.Errorf("end must be after start for maintenance window %d", index)
+		}
+		return nil
+	}
+
+	if _, err := time.Parse("15:04", window.StartTime); err != nil {
+		return fmt.Errorf("invalid start time for maintenance window %d: %v", index, err)
+	}
+	if _, err := time.Parse("15:04", window.EndTime); err != nil {
+		return fmt.Errorf("invalid end time for maintenance window %d: %v", index, err)
+	}
+	if _, err := time.LoadLocation(window.TimeZone); err != nil {
+		return fmt.Errorf("invalid time zone for maintenance window %d: %v", index, err)
+	}
+	for _, day := range window.Days {
+		if _, ok := Weekdays[strings.ToLower(day)]; !ok {
+			return fmt.Errorf("invalid weekday %q for maintenance window %d", day, index)
+		}
+	}
 	return nil
 }
 
@@ -123,11 +803,108 @@ func validateGoogleCalendarSchedule(schedule WorkSchedule) error {
 	if schedule.GoogleCalendar == nil {
 		return fmt.Errorf("google calendar configuration is required when using google_calendar provider")
 	}
-	if schedule.GoogleCalendar.CalendarID == "" {
-		return fmt.Errorf("calendar ID is required for google calendar schedule")
+	if schedule.GoogleCalendar.CalendarID == "" && len(schedule.GoogleCalendar.OutOfOfficeAttendees) == 0 {
+		return fmt.Errorf("calendar ID or out-of-office attendees are required for google calendar schedule")
+	}
+	for _, location := range schedule.GoogleCalendar.OffTimeWorkingLocations {
+		switch location {
+		case "homeOffice", "officeLocation", "customLocation":
+		default:
+			return fmt.Errorf("invalid working location type %q for google calendar schedule", location)
+		}
+	}
+	return nil
+}
+
+func validatePrometheusSchedule(schedule WorkSchedule) error {
+	if schedule.Prometheus.URL == "" {
+		return fmt.Errorf("url is required for prometheus schedule")
+	}
+	if schedule.Prometheus.Query == "" {
+		return fmt.Errorf("query is required for prometheus schedule")
 	}
-	if schedule.GoogleCalendar.CredentialsPath == "" {
-		return fmt.Errorf("credentials file is required for google calendar schedule")
+	if _, err := time.ParseDuration(schedule.Prometheus.Duration); err != nil {
+		return fmt.Errorf("invalid duration for prometheus schedule: %v", err)
+	}
+	return nil
+}
+
+func validateTeamCalendarsSchedule(schedule WorkSchedule) error {
+	if schedule.TeamCalendars.BaseURL == "" {
+		return fmt.Errorf("base url is required for team calendars schedule")
+	}
+	if len(schedule.TeamCalendars.SubCalendarIDs) == 0 {
+		return fmt.Errorf("sub calendar IDs are required for team calendars schedule")
+	}
+	if _, err := time.ParseDuration(schedule.TeamCalendars.SyncInterval); err != nil {
+		return fmt.Errorf("invalid sync interval for team calendars schedule: %v", err)
+	}
+	if schedule.TeamCalendars.TimeZone != "" {
+		if _, err := time.LoadLocation(schedule.TeamCalendars.TimeZone); err != nil {
+			return fmt.Errorf("invalid time zone for team calendars schedule: %v", err)
+		}
+	}
+	return nil
+}
+
+func validateHRTimeOffSchedule(schedule WorkSchedule) error {
+	switch schedule.HRTimeOff.System {
+	case "bamboohr":
+		if schedule.HRTimeOff.CompanyDomain == "" {
+			return fmt.Errorf("company domain is required for bamboohr time off schedule")
+		}
+	case "personio":
+	default:
+		return fmt.Errorf("unsupported system for hr time off schedule: %s", schedule.HRTimeOff.System)
+	}
+	if len(schedule.HRTimeOff.Employees) == 0 {
+		return fmt.Errorf("employees are required for hr time off schedule")
+	}
+	if _, err := time.ParseDuration(schedule.HRTimeOff.SyncInterval); err != nil {
+		return fmt.Errorf("invalid sync interval for hr time off schedule: %v", err)
+	}
+	if schedule.HRTimeOff.TimeZone != "" {
+		if _, err := time.LoadLocation(schedule.HRTimeOff.TimeZone); err != nil {
+			return fmt.Errorf("invalid time zone for hr time off schedule: %v", err)
+		}
+	}
+	return nil
+}
+
+func validateCarbonAwareSchedule(schedule WorkSchedule) error {
+	switch schedule.CarbonAware.Source {
+	case "electricitymaps", "watttime":
+	default:
+		return fmt.Errorf("unsupported source for carbon-aware schedule: %s", schedule.CarbonAware.Source)
+	}
+	if schedule.CarbonAware.Zone == "" {
+		return fmt.Errorf("zone is required for carbon-aware schedule")
+	}
+	if schedule.CarbonAware.HoursPerDay < 1 || schedule.CarbonAware.HoursPerDay > 24 {
+		return fmt.Errorf("invalid hours per day for carbon-aware schedule: %d", schedule.CarbonAware.HoursPerDay)
+	}
+	if schedule.CarbonAware.TimeZone != "" {
+		if _, err := time.LoadLocation(schedule.CarbonAware.TimeZone); err != nil {
+			return fmt.Errorf("invalid time zone for carbon-aware schedule: %v", err)
+		}
+	}
+	if d, err := time.ParseDuration(schedule.CarbonAware.SyncInterval); err != nil || d <= 0 {
+		return fmt.Errorf("invalid sync interval for carbon-aware schedule: %q", schedule.CarbonAware.SyncInterval)
+	}
+	return nil
+}
+
+func validateDaylightSchedule(schedule WorkSchedule) error {
+	if schedule.Daylight.Latitude < -90 || schedule.Daylight.Latitude > 90 {
+		return fmt.Errorf("invalid latitude for daylight schedule: %v", schedule.Daylight.Latitude)
+	}
+	if schedule.Daylight.Longitude < -180 || schedule.Daylight.Longitude > 180 {
+		return fmt.Errorf("invalid longitude for daylight schedule: %v", schedule.Daylight.Longitude)
+	}
+	switch schedule.Daylight.Twilight {
+	case "none", "civil", "nautical":
+	default:
+		return fmt.Errorf("unsupported twilight for daylight schedule: %s", schedule.Daylight.Twilight)
 	}
 	return nil
 }
@@ -136,12 +913,143 @@ func validateNodeSpec(spec NodeSpec, index int) error {
 	if spec.NodePoolName == "" {
 		return fmt.Errorf("node pool name is required for spec %d", index)
 	}
-	if spec.CloudProvider == "" {
+	switch spec.CloudProvider {
+	case "gke", "aws", "azure":
+	case "":
 		return fmt.Errorf("cloud provider is required for spec %d", index)
+	default:
+		return fmt.Errorf("unsupported cloud provider %q for spec %d", spec.CloudProvider, index)
 	}
 	if spec.OffTimeCount < 0 {
 		return fmt.Errorf("invalid off-time node count for spec %d", index)
 	}
+	for tier, count := range spec.TierCounts {
+		if count < 0 {
+			return fmt.Errorf("invalid node count for tier %s in spec %d", tier, index)
+		}
+	}
+	if spec.SavingsModeCount != nil && *spec.SavingsModeCount < 0 {
+		return fmt.Errorf("invalid savings mode node count for spec %d", index)
+	}
+	if idle := spec.IdleScaleDown; idle != nil {
+		setDefaults(idle)
+		if d, err := time.ParseDuration(idle.IdleFor); err != nil || d <= 0 {
+			return fmt.Errorf("invalid idle scale down duration for spec %d: %q", index, idle.IdleFor)
+		}
+		if _, err := resource.ParseQuantity(idle.CPUThreshold); err != nil {
+			return fmt.Errorf("invalid idle scale down cpu threshold for spec %d: %q", index, idle.CPUThreshold)
+		}
+	}
+	if restore := spec.UsageBasedRestore; restore != nil {
+		if restore.Workdays == 0 {
+			restore.Workdays = 5
+		}
+		if restore.Percentile == 0 {
+			restore.Percentile = 95
+		}
+		// The sizes of the last 30 days are kept
+		if restore.Workdays < 0 || restore.Workdays > 30 {
+			return fmt.Errorf("invalid usage-based restore workdays for spec %d: %d, must be between 1 and 30", index, restore.Workdays)
+		}
+		if restore.Percentile < 0 || restore.Percentile > 100 {
+			return fmt.Errorf("invalid usage-based restore percentile for spec %d: %v", index, restore.Percentile)
+		}
+	}
+	if drain := spec.Drain; drain != nil {
+		if drain.GracePeriod != "" {
+			if d, err := time.ParseDuration(drain.GracePeriod); err != nil || d < 0 {
+				return fmt.Errorf("invalid drain grace period for spec %d: %q", index, drain.GracePeriod)
+			}
+		}
+		if drain.Timeout != "" {
+			if d, err := time.ParseDuration(drain.Timeout); err != nil || d <= 0 {
+				return fmt.Errorf("invalid drain timeout for spec %d: %q", index, drain.Timeout)
+			}
+		}
+		if drain.TerminationTimeout != "" {
+			if d, err := time.ParseDuration(drain.TerminationTimeout); err != nil || d <= 0 {
+				return fmt.Errorf("invalid drain termination timeout for spec %d: %q", index, drain.TerminationTimeout)
+			}
+		}
+		if drain.Parallelism < 0 {
+			return fmt.Errorf("invalid drain parallelism for spec %d: %d", index, drain.Parallelism)
+		}
+		switch drain.NodeSelection {
+		case "", NodeSelectionEmptiestFirst, NodeSelectionOldestFirst, NodeSelectionNewestFirst:
+		default:
+			return fmt.Errorf("unsupported drain node selection for spec %d: %q", index, drain.NodeSelection)
+		}
+	}
+	if spec.Cooldown != "" {
+		if d, err := time.ParseDuration(spec.Cooldown); err != nil || d < 0 {
+			return fmt.Errorf("invalid cooldown for spec %d: %q", index, spec.Cooldown)
+		}
+	}
+	if spec.NodePower < 0 {
+		return fmt.Errorf("invalid node power for spec %d: %v", index, spec.NodePower)
+	}
+	if spec.HourlyNodeCost < 0 {
+		return fmt.Errorf("invalid hourly node cost for spec %d: %v", index, spec.HourlyNodeCost)
+	}
+	if err := validateDriftPolicy(spec.DriftPolicy); err != nil {
+		return fmt.Errorf("%v for spec %d", err, index)
+	}
+	return nil
+}
+
+func validateDriftPolicy(policy string) error {
+	switch policy {
+	case "", DriftPolicyEnforce, DriftPolicyRespect, DriftPolicyAlertOnly:
+		return nil
+	default:
+		return fmt.Errorf("unsupported drift policy %q", policy)
+	}
+}
+
+// validateCacheStore sets the defaults of the cache store and validates it
+func validateCacheStore(store *CacheStoreConfig) error {
+	setDefaults(store)
+	switch store.Type {
+	case "configmap":
+	case "file":
+		if !filepath.IsAbs(store.Path) {
+			return fmt.Errorf("cache store path must be absolute: %q", store.Path)
+		}
+	default:
+		return fmt.Errorf("unsupported cache store type: %s", store.Type)
+	}
+	return nil
+}
+
+// validateCIPipelines sets the defaults of the CI systems and validates them
+func validateCIPipelines(pipelines *CIPipelinesConfig) error {
+	if len(pipelines.NodePools) == 0 {
+		return fmt.Errorf("node pools are required")
+	}
+	if pipelines.GitHub == nil && pipelines.GitLab == nil && pipelines.Jenkins == nil {
+		return fmt.Errorf("a CI system is required")
+	}
+	if github := pipelines.GitHub; github != nil {
+		setDefaults(github)
+		if len(github.Repositories) == 0 {
+			return fmt.Errorf("github repositories are required")
+		}
+	}
+	if gitlab := pipelines.GitLab; gitlab != nil {
+		setDefaults(gitlab)
+		if len(gitlab.Projects) == 0 {
+			return fmt.Errorf("gitlab projects are required")
+		}
+	}
+	if jenkins := pipelines.Jenkins; jenkins != nil {
+		setDefaults(jenkins)
+		if jenkins.URL == "" {
+			return fmt.Errorf("jenkins URL is required")
+		}
+		if d, err := time.ParseDuration(jenkins.IdleFor); err != nil || d < 0 {
+			return fmt.Errorf("invalid jenkins idle duration: %q", jenkins.IdleFor)
+		}
+	}
 	return nil
 }
 
@@ -0,0 +1,276 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestReadConfigFromBytes_NamedSchedules(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr bool
+	}{
+		{
+			name: "Named Schedules",
+			data: `
+schedule:
+  startTime: "09:00"
+schedules:
+  night-shift:
+    startTime: "22:00"
+    endTime: "06:00"
+    tiers:
+      - name: weekend
+        startTime: "00:00"
+        endTime: "23:59"
+        days: ["saturday", "sunday"]
+nodeSpecs:
+  - nodePoolName: default-pool
+    cloudProvider: gke
+  - nodePoolName: batch-pool
+    cloudProvider: gke
+    schedule: night-shift
+    tierCounts:
+      weekend: 1
+`,
+		},
+		{
+			name: "Unknown Schedule",
+			data: `
+schedule:
+  startTime: "09:00"
+nodeSpecs:
+  - nodePoolName: default-pool
+    cloudProvider: gke
+    schedule: night-shift
+`,
+			wantErr: true,
+		},
+		{
+			name: "Tier Of Other Schedule",
+			data: `
+schedule:
+  tiers:
+    - name: weekend
+      startTime: "00:00"
+      endTime: "23:59"
+schedules:
+  night-shift:
+    startTime: "22:00"
+    endTime: "06:00"
+nodeSpecs:
+  - nodePoolName: batch-pool
+    cloudProvider: gke
+    schedule: night-shift
+    tierCounts:
+      weekend: 1
+`,
+			wantErr: true,
+		},
+		{
+			name: "Invalid Named Schedule",
+			data: `
+schedule:
+  startTime: "09:00"
+schedules:
+  night-shift:
+    compositeMode: "xor"
+nodeSpecs:
+  - nodePoolName: default-pool
+    cloudProvider: gke
+`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := ReadConfigFromBytes([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ReadConfigFromBytes() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			// Defaults are applied to named schedules too
+			if got := cfg.Schedules["night-shift"].TimeZone; got != "UTC" {
+				t.Errorf("Schedules[night-shift].TimeZone = %q, want default %q", got, "UTC")
+			}
+		})
+	}
+}
+
+func TestReadConfigFromBytes_Hooks(t *testing.T) {
+	tests := []struct {
+		name    string
+		hooks   string
+		wantErr bool
+	}{
+		{
+			name: "HTTP, Job And Velero Hooks",
+			hooks: `
+  preScaleDown:
+    - name: flush-cache
+      http:
+        url: "http://cache.default.svc/flush"
+      blockOnFailure: true
+    - name: nightly-backup
+      timeout: "1h"
+      velero:
+        includedNamespaces: ["team-a"]
+        ttl: "168h"
+  postRestore:
+    - name: warm-up
+      timeout: "10m"
+      job:
+        spec:
+          template:
+            spec:
+              containers:
+                - name: warm-up
+                  image: curlimages/curl
+`,
+		},
+		{
+			name: "Both HTTP And Job",
+			hooks: `
+  preScaleDown:
+    - name: flush-cache
+      http:
+        url: "http://cache.default.svc/flush"
+      job:
+        spec: {}
+`,
+			wantErr: true,
+		},
+		{
+			name: "Invalid Job Name",
+			hooks: `
+  postRestore:
+    - name: Warm_Up
+      job:
+        spec:
+          template:
+            spec:
+              containers:
+                - name: warm-up
+                  image: curlimages/curl
+`,
+			wantErr: true,
+		},
+		{
+			name: "Velero Without Namespaces",
+			hooks: `
+  preScaleDown:
+    - name: nightly-backup
+      velero: {}
+`,
+			wantErr: true,
+		},
+		{
+			name: "Invalid Velero TTL",
+			hooks: `
+  preScaleDown:
+    - name: nightly-backup
+      velero:
+        includedNamespaces: ["team-a"]
+        ttl: "7 days"
+`,
+			wantErr: true,
+		},
+		{
+			name: "Invalid Timeout",
+			hooks: `
+  preScaleDown:
+    - name: flush-cache
+      timeout: "soon"
+      http:
+        url: "http://cache.default.svc/flush"
+`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := ReadConfigFromBytes([]byte("schedule:\n  startTime: \"09:00\"\nhooks:" + tt.hooks))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ReadConfigFromBytes() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := cfg.Hooks.PreScaleDown[0].Timeout; got != "5m" {
+				t.Errorf("PreScaleDown[0].Timeout = %q, want default %q", got, "5m")
+			}
+			if got := cfg.Hooks.PostRestore[0].Job.Spec.Template.Spec.Containers[0].Image; got != "curlimages/curl" {
+				t.Errorf("PostRestore[0] image = %q, want %q", got, "curlimages/curl")
+			}
+		})
+	}
+}
+
+func TestReadConfigFromBytes_WorkloadSpecs(t *testing.T) {
+	tests := []struct {
+		name      string
+		workloads string
+		wantKind  string
+		wantErr   bool
+	}{
+		{
+			name: "Deployment By Default",
+			workloads: `
+  - namespace: team-a
+    name: web`,
+			wantKind: WorkloadKindDeployment,
+		},
+		{
+			name: "ScaledObject By Selector",
+			workloads: `
+  - kind: ScaledObject
+    namespace: team-a
+    selector: tier=batch`,
+			wantKind: WorkloadKindScaledObject,
+		},
+		{
+			name: "StatefulSet",
+			workloads: `
+  - kind: StatefulSet
+    namespace: team-a
+    name: db
+    offTimeReplicas: 0`,
+			wantKind: WorkloadKindStatefulSet,
+		},
+		{
+			name: "Unknown Kind",
+			workloads: `
+  - kind: CronJob
+    namespace: team-a
+    name: report`,
+			wantErr: true,
+		},
+		{
+			name: "Name And Selector",
+			workloads: `
+  - namespace: team-a
+    name: web
+    selector: tier=frontend`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := ReadConfigFromBytes([]byte("schedule:\n  startTime: \"09:00\"\nworkloadSpecs:" + tt.workloads))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ReadConfigFromBytes() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := cfg.WorkloadSpecs[0].Kind; got != tt.wantKind {
+				t.Errorf("WorkloadSpecs[0].Kind = %q, want %q", got, tt.wantKind)
+			}
+		})
+	}
+}
@@ -1,5 +1,9 @@
 package config
 
+import (
+	batchv1 "k8s.io/api/batch/v1"
+)
+
 // WorkDays represents the days of the week when the schedule is active
 type WorkDays struct {
 	Monday    bool `yaml:"monday" default:"true"`
@@ -11,6 +15,20 @@ type WorkDays struct {
 	Sunday    bool `yaml:"sunday" default:"false"`
 }
 
+// TimeRange represents a daily time range
+type TimeRange struct {
+	StartTime string `yaml:"startTime"` // Format: "HH:MM"
+	EndTime   string `yaml:"endTime"`   // Format: "HH:MM"
+}
+
+// ScheduleExceptions lists explicit dates in the schedule time zone, format: "2006-01-02"
+type ScheduleExceptions struct {
+	// OffDates are always off, e.g. company holidays
+	OffDates []string `yaml:"offDates,omitempty"`
+	// WorkDates are always work time, e.g. release days
+	WorkDates []string `yaml:"workDates,omitempty"`
+}
+
 // WorkSchedule represents the schedule for working hours.
 // It defines when the cluster should operate at full capacity.
 type WorkSchedule struct {
@@ -19,20 +37,108 @@ type WorkSchedule struct {
 	EndTime   string    `yaml:"endTime,omitempty" default:"17:00"`   // Format: "HH:MM"
 	TimeZone  string    `yaml:"timeZone,omitempty" default:"UTC"`    // e.g., "America/New_York"
 	WorkDays  *WorkDays `yaml:"workDays,omitempty" default:"{}"`     // Days when the schedule is active
+	// DayHours overrides the start and end times for individual weekdays,
+	// keyed by lowercase weekday name, e.g. "friday"
+	DayHours map[string]TimeRange `yaml:"dayHours,omitempty"`
+	// Exceptions are dates that are always off or always on, checked before the work days and hours
+	Exceptions *ScheduleExceptions `yaml:"exceptions,omitempty"`
 
 	// Google Calendar configuration
 	GoogleCalendar *GoogleCalendarConfig `yaml:"googleCalendar,omitempty"`
 
 	// ICS Calendar configuration
 	ICSCalendar *ICSCalendarConfig `yaml:"icsCalendar,omitempty"`
+
+	// Microsoft Teams Shifts configuration
+	TeamsShifts *TeamsShiftsConfig `yaml:"teamsShifts,omitempty"`
+
+	// Atlassian Team Calendars for Confluence configuration
+	TeamCalendars *TeamCalendarsConfig `yaml:"teamCalendars,omitempty"`
+
+	// HR system time off configuration
+	HRTimeOff *HRTimeOffConfig `yaml:"hrTimeOff,omitempty"`
+
+	// Prometheus metric configuration
+	Prometheus *PrometheusConfig `yaml:"prometheus,omitempty"`
+
+	// Cluster idle detection configuration
+	ClusterIdle *ClusterIdleConfig `yaml:"clusterIdle,omitempty"`
+
+	// Daylight based schedule configuration
+	Daylight *DaylightConfig `yaml:"daylight,omitempty"`
+
+	// Carbon-aware schedule configuration
+	CarbonAware *CarbonAwareConfig `yaml:"carbonAware,omitempty"`
+
+	// Schedule custom resources configuration
+	ScheduleCRD *ScheduleCRDConfig `yaml:"scheduleCRD,omitempty"`
+
+	// Manual override configuration
+	ManualOverride *ManualOverrideConfig `yaml:"manualOverride,omitempty"`
+
+	// CacheStore persists calendar caches across restarts
+	CacheStore *CacheStoreConfig `yaml:"cacheStore,omitempty"`
+
+	// CompositeMode is how the configured providers are combined:
+	// "and" (all must agree it's work time), "or" (any says work time) or "quorum" (default: and)
+	CompositeMode string `yaml:"compositeMode,omitempty" default:"and"`
+	// Quorum is how many providers must say it's work time in "quorum" mode
+	Quorum int `yaml:"quorum,omitempty"`
+
+	// RestoreLeadTime is how long before work time the node pools are restored,
+	// so that nodes are Ready when work starts, e.g. "20m" (default: 0)
+	RestoreLeadTime string `yaml:"restoreLeadTime,omitempty"`
+
+	// ScaleDownDelay is how long off time must persist before node pools are scaled down,
+	// a grace period for people working late, e.g. "30m" (default: 0)
+	ScaleDownDelay string `yaml:"scaleDownDelay,omitempty"`
+
+	// MaxStaleness is how long a calendar may go without a successful sync
+	// before the schedule fails safe to work time, e.g. "6h" (default: disabled)
+	MaxStaleness string `yaml:"maxStaleness,omitempty"`
+
+	// Tiers are named windows of reduced capacity outside work time, e.g. half size in the evening.
+	// The first matching tier wins, node pools map tiers to node counts with tierCounts.
+	Tiers []CapacityTier `yaml:"tiers,omitempty"`
+}
+
+// CapacityTier is a named daily window in the schedule time zone, or the coverage of an on-call rotation
+type CapacityTier struct {
+	Name      string `yaml:"name"`
+	StartTime string `yaml:"startTime,omitempty"` // Format: "HH:MM"
+	EndTime   string `yaml:"endTime,omitempty"`   // Format: "HH:MM"
+	// Days are lowercase weekday names the tier applies on (default: monday to friday)
+	Days []string `yaml:"days,omitempty"`
+	// OnCall applies the tier while the on-call rotation is staffed instead of in a daily window
+	OnCall *OnCallConfig `yaml:"onCall,omitempty"`
+}
+
+// OnCallConfig contains settings for an on-call schedule in an incident management system
+type OnCallConfig struct {
+	// System is "pagerduty", with the REST API key in the PAGERDUTY_API_KEY environment variable,
+	// or "opsgenie", with the API key in the OPSGENIE_API_KEY environment variable
+	System string `yaml:"system"`
+	// ScheduleID is the ID of the on-call schedule
+	ScheduleID string `yaml:"scheduleId"`
+	// SyncInterval is how often to refresh the on-call shifts (default: 1h)
+	SyncInterval string `yaml:"syncInterval,omitempty"`
+	// CacheDays is how many days of on-call shifts to cache (default: 7)
+	CacheDays int `yaml:"cacheDays,omitempty"`
 }
 
 // GoogleCalendarConfig contains settings for Google Calendar integration
 type GoogleCalendarConfig struct {
 	// CalendarID is the ID of the Google Calendar to sync with
 	CalendarID string `yaml:"calendarId"`
-	// CredentialsPath is the path where the credentials file is mounted
+	// CredentialsPath is the path where the credentials file is mounted.
+	// If empty, Application Default Credentials (e.g. GKE Workload Identity) are used.
 	CredentialsPath string `yaml:"credentialsPath,omitempty" default:"/etc/google/credentials.json"`
+	// Subject is the Workspace user to impersonate with domain-wide delegation,
+	// for calendars that can't be shared with service accounts directly
+	Subject string `yaml:"subject,omitempty"`
+	// ServiceAccount is the email of the service account with domain-wide delegation,
+	// only required for impersonation with Application Default Credentials
+	ServiceAccount string `yaml:"serviceAccount,omitempty"`
 	// OffTimeEvents is a search query for events that mark off-time hours (e.g., "<my name> PublicHoliday")
 	// If any matching event is found, that time is considered off-hours
 	OffTimeEvents string `yaml:"offTimeEvents,omitempty"`
@@ -40,12 +146,24 @@ type GoogleCalendarConfig struct {
 	SyncInterval string `yaml:"syncInterval,omitempty" default:"1h"`
 	// CacheDays is how many days of events to cache (default: 7)
 	CacheDays int `yaml:"cacheDays,omitempty" default:"7"`
+	// OutOfOfficeAttendees are users (email addresses) whose native out-of-office events mark off time.
+	// It is off time only when all of them are out of office.
+	OutOfOfficeAttendees []string `yaml:"outOfOfficeAttendees,omitempty"`
+	// OffTimeWorkingLocations are working location types ("homeOffice", "officeLocation",
+	// "customLocation") that count as out of office for the attendees
+	OffTimeWorkingLocations []string `yaml:"offTimeWorkingLocations,omitempty"`
+	// Priority makes the calendar an override: when it has a matching event it decides,
+	// regardless of providers without priority. Higher priorities are consulted first.
+	Priority int `yaml:"priority,omitempty"`
 }
 
 // ICSCalendarConfig contains settings for ICS calendar integration
 type ICSCalendarConfig struct {
-	// URL is the ICS calendar URL to sync with
-	URL string `yaml:"url"`
+	// URL is the ICS calendar URL to sync with.
+	// Besides HTTP(S) URLs, file:// URLs and absolute paths to local files are supported.
+	URL string `yaml:"url,omitempty"`
+	// URLs is a list of additional ICS calendar URLs, events from all calendars are merged
+	URLs []string `yaml:"urls,omitempty"`
 	// WorkDayPatterns is a list of patterns to match work day events
 	// If any pattern matches the event summary, it's considered a work day
 	WorkDayPatterns []string `yaml:"workDayPatterns,omitempty"`
@@ -54,6 +172,249 @@ type ICSCalendarConfig struct {
 	HolidayPatterns []string `yaml:"holidayPatterns,omitempty"`
 	// SyncInterval is how often to refresh the event cache (default: 1h)
 	SyncInterval string `yaml:"syncInterval,omitempty" default:"1h"`
+	// TimeZone is used for all-day events and times without TZID (default: the schedule time zone)
+	TimeZone string `yaml:"timeZone,omitempty"`
+	// Priority makes the calendar an override deciding on days with matching events,
+	// e.g. special work days on weekends
+	Priority int `yaml:"priority,omitempty"`
+}
+
+// TeamsShiftsConfig contains settings for Microsoft Teams Shifts integration.
+// The client secret of the app registration is read from the AZURE_CLIENT_SECRET environment variable.
+type TeamsShiftsConfig struct {
+	// TenantID is the Microsoft Entra tenant ID (default: AZURE_TENANT_ID environment variable)
+	TenantID string `yaml:"tenantId,omitempty"`
+	// ClientID is the app registration client ID (default: AZURE_CLIENT_ID environment variable)
+	ClientID string `yaml:"clientId,omitempty"`
+	// TeamID is the ID of the team whose shifts keep the cluster up
+	TeamID string `yaml:"teamId"`
+	// SchedulingGroupIDs limits the shifts to these scheduling groups (default: all groups)
+	SchedulingGroupIDs []string `yaml:"schedulingGroupIds,omitempty"`
+	// SyncInterval is how often to refresh the shift cache (default: 1h)
+	SyncInterval string `yaml:"syncInterval,omitempty" default:"1h"`
+	// CacheDays is how many days of shifts to cache (default: 7)
+	CacheDays int `yaml:"cacheDays,omitempty"`
+	// Priority makes the shifts an override that always decides, see GoogleCalendarConfig.Priority
+	Priority int `yaml:"priority,omitempty"`
+}
+
+// TeamCalendarsConfig contains settings for Atlassian Team Calendars for Confluence integration.
+// The API token (Cloud) or personal access token (Data Center) is read from the CONFLUENCE_API_TOKEN environment variable.
+type TeamCalendarsConfig struct {
+	// BaseURL is the Confluence base URL, e.g. "https://example.atlassian.net/wiki"
+	BaseURL string `yaml:"baseUrl"`
+	// Username is the Atlassian account email for Confluence Cloud, leave empty for a Data Center personal access token
+	Username string `yaml:"username,omitempty"`
+	// SubCalendarIDs are the IDs of the team calendars to read
+	SubCalendarIDs []string `yaml:"subCalendarIds"`
+	// EventTypes are the event types (e.g. "leaves", "travel") or custom event type IDs that mark off time (default: leaves)
+	EventTypes []string `yaml:"eventTypes,omitempty"`
+	// SyncInterval is how often to refresh the event cache (default: 1h)
+	SyncInterval string `yaml:"syncInterval,omitempty" default:"1h"`
+	// CacheDays is how many days of events to cache (default: 7)
+	CacheDays int `yaml:"cacheDays,omitempty"`
+	// TimeZone is used for all-day events (default: the schedule time zone)
+	TimeZone string `yaml:"timeZone,omitempty"`
+	// Priority makes the calendar an override, see GoogleCalendarConfig.Priority
+	Priority int `yaml:"priority,omitempty"`
+}
+
+// HRTimeOffConfig contains settings for reading approved time off from an HR system.
+// Credentials are read from the BAMBOOHR_API_KEY or PERSONIO_CLIENT_ID and PERSONIO_CLIENT_SECRET environment variables.
+type HRTimeOffConfig struct {
+	// System is the HR system: "bamboohr" or "personio"
+	System string `yaml:"system"`
+	// CompanyDomain is the BambooHR company subdomain, e.g. "acme" for acme.bamboohr.com
+	CompanyDomain string `yaml:"companyDomain,omitempty"`
+	// Employees are the HR system employee IDs of the team, off time when all of them are on time off
+	Employees []string `yaml:"employees"`
+	// SyncInterval is how often to refresh the time off cache (default: 1h)
+	SyncInterval string `yaml:"syncInterval,omitempty" default:"1h"`
+	// CacheDays is how many days of time off to cache (default: 7)
+	CacheDays int `yaml:"cacheDays,omitempty"`
+	// TimeZone is the time zone of the time off dates (default: the schedule time zone)
+	TimeZone string `yaml:"timeZone,omitempty"`
+	// Priority makes the time off an override, see GoogleCalendarConfig.Priority
+	Priority int `yaml:"priority,omitempty"`
+}
+
+// PrometheusConfig contains settings for the Prometheus metric based schedule
+type PrometheusConfig struct {
+	// URL is the base URL of the Prometheus server (e.g., "http://prometheus:9090")
+	URL string `yaml:"url"`
+	// Query is the PromQL query measuring cluster activity (e.g., request rate, active sessions)
+	Query string `yaml:"query"`
+	// Threshold is the value below which the cluster is considered idle
+	Threshold float64 `yaml:"threshold"`
+	// Duration is how long the metric must stay below the threshold before off time (default: 30m)
+	Duration string `yaml:"duration,omitempty" default:"30m"`
+	// Priority makes the metric an override keeping the cluster up while it's active, e.g. past the static work
+	// hours, the other providers decide once it's idle. Without a priority, idle time is off time even during the
+	// work hours of the other providers in the default "and" composite mode.
+	Priority int `yaml:"priority,omitempty"`
+}
+
+// ClusterIdleConfig contains settings for detecting an idle cluster
+type ClusterIdleConfig struct {
+	// Duration is how long the cluster must be idle before off time (default: 1h)
+	Duration string `yaml:"duration,omitempty" default:"1h"`
+	// CPUThreshold is the total non-system CPU usage below which the cluster is idle (default: 200m)
+	CPUThreshold string `yaml:"cpuThreshold,omitempty" default:"200m"`
+	// ExcludedNamespaces are namespaces whose pods are not considered user activity,
+	// in addition to kube-system, kube-public and kube-node-lease
+	ExcludedNamespaces []string `yaml:"excludedNamespaces,omitempty"`
+	// Priority makes idle detection an override keeping the cluster up while it's active, see PrometheusConfig.Priority
+	Priority int `yaml:"priority,omitempty"`
+}
+
+// DaylightConfig contains settings for a schedule following daylight at a location
+type DaylightConfig struct {
+	// Latitude of the location in degrees, north is positive
+	Latitude float64 `yaml:"latitude"`
+	// Longitude of the location in degrees, east is positive
+	Longitude float64 `yaml:"longitude"`
+	// Twilight is how much twilight counts as daylight: "none" (sunrise to sunset),
+	// "civil" or "nautical" (default: civil)
+	Twilight string `yaml:"twilight,omitempty" default:"civil"`
+	// Priority makes daylight an override that always decides, see GoogleCalendarConfig.Priority
+	Priority int `yaml:"priority,omitempty"`
+}
+
+// CarbonAwareConfig contains settings for a schedule that is work time in the hours of the day with the lowest
+// forecast carbon intensity of the power grid, e.g. for batch node pools whose work can be shifted
+type CarbonAwareConfig struct {
+	// Source is the carbon intensity API: "electricitymaps" or "watttime". The ElectricityMaps API token is read
+	// from ELECTRICITYMAPS_API_TOKEN, the WattTime credentials from WATTTIME_USERNAME and WATTTIME_PASSWORD.
+	Source string `yaml:"source"`
+	// Zone is the ElectricityMaps zone, e.g. "DE", or the WattTime region, e.g. "CAISO_NORTH"
+	Zone string `yaml:"zone"`
+	// HoursPerDay is how many hours of each day are work time
+	HoursPerDay int `yaml:"hoursPerDay"`
+	// TimeZone is the time zone of the days (default: the schedule's time zone)
+	TimeZone string `yaml:"timeZone,omitempty"`
+	// SyncInterval is how often the forecast is refreshed (default: 1h)
+	SyncInterval string `yaml:"syncInterval,omitempty" default:"1h"`
+	// Priority makes the carbon-aware schedule an override that always decides, see GoogleCalendarConfig.Priority
+	Priority int `yaml:"priority,omitempty"`
+}
+
+// ScheduleCRDConfig contains settings for schedules managed as Schedule custom resources.
+// Only the Schedules listing any node pool of the schedule apply to it.
+type ScheduleCRDConfig struct {
+	// Namespace limits the Schedules to one namespace (default: all namespaces)
+	Namespace string `yaml:"namespace,omitempty"`
+	// Priority makes the Schedules an override whenever any exist, see GoogleCalendarConfig.Priority
+	Priority int `yaml:"priority,omitempty"`
+}
+
+// ManualOverrideConfig contains settings for forcing work or off time through a ConfigMap
+type ManualOverrideConfig struct {
+	// ConfigMapName is the ConfigMap in the controller namespace holding the override,
+	// with a "work-until" or "off-until" RFC 3339 time (default: bmw-saver-override)
+	ConfigMapName string `yaml:"configMapName,omitempty" default:"bmw-saver-override"`
+	// Priority of the override among the other providers with priority (default: 1000)
+	Priority int `yaml:"priority,omitempty"`
+}
+
+// CacheStoreConfig contains settings for persisting calendar caches
+type CacheStoreConfig struct {
+	// Type is where caches are stored: "configmap" or "file" (default: configmap)
+	Type string `yaml:"type,omitempty" default:"configmap"`
+	// Path is the directory for the "file" type, e.g. an emptyDir mount
+	Path string `yaml:"path,omitempty"`
+}
+
+// Workload kinds
+const (
+	WorkloadKindDeployment   = "Deployment"
+	WorkloadKindStatefulSet  = "StatefulSet"
+	WorkloadKindScaledObject = "ScaledObject"
+)
+
+// WorkloadSpec represents the configuration for workloads scaled during off time, by name or label selector.
+// The replicas of Deployments and StatefulSets are saved before they are scaled down and restored at work time,
+// KEDA ScaledObjects are paused at the off-time replicas instead.
+type WorkloadSpec struct {
+	// Kind of the workloads, "Deployment", "StatefulSet" or "ScaledObject" (default: Deployment)
+	Kind string `yaml:"kind,omitempty" default:"Deployment"`
+	// Namespace of the workloads
+	Namespace string `yaml:"namespace"`
+	// Name of the workload, or Selector a label selector of the workloads, exactly one is required
+	Name     string `yaml:"name,omitempty"`
+	Selector string `yaml:"selector,omitempty"`
+	// OffTimeReplicas is the number of replicas during off time
+	OffTimeReplicas int32 `yaml:"offTimeReplicas"`
+	// Schedule is the name of a schedule in Config.Schedules, empty for the top-level schedule
+	Schedule string `yaml:"schedule,omitempty"`
+}
+
+// String returns the namespace and the name or the selector of the workloads, e.g. "team-a/web"
+func (spec WorkloadSpec) String() string {
+	if spec.Name != "" {
+		return spec.Namespace + "/" + spec.Name
+	}
+	return spec.Namespace + "/" + spec.Selector
+}
+
+// GitOpsConfig contains the GitOps resources managing workloads, which are kept from undoing their scale downs
+// during off time
+type GitOpsConfig struct {
+	// ArgoCDApplications have their automated sync turned off during off time and restored at work time
+	ArgoCDApplications []GitOpsResource `yaml:"argoCDApplications,omitempty"`
+	// FluxResources are Flux Kustomizations and HelmReleases suspended during off time and resumed at work time
+	FluxResources []GitOpsResource `yaml:"fluxResources,omitempty"`
+}
+
+// Flux resource kinds
+const (
+	FluxKindKustomization = "Kustomization"
+	FluxKindHelmRelease   = "HelmRelease"
+)
+
+// GitOpsResource is a GitOps resource managing workloads of a schedule
+type GitOpsResource struct {
+	// Kind of a Flux resource, "Kustomization" or "HelmRelease"
+	Kind string `yaml:"kind,omitempty"`
+	// Namespace and Name of the resource
+	Namespace string `yaml:"namespace"`
+	Name      string `yaml:"name"`
+	// Schedule is the name of a schedule in Config.Schedules, empty for the top-level schedule
+	Schedule string `yaml:"schedule,omitempty"`
+}
+
+// DatabaseSpec represents a managed database stopped during off time and started before the node pools of its
+// schedule are restored
+type DatabaseSpec struct {
+	// Provider of the database, "cloudsql" for Cloud SQL instances or "rds" for RDS DB instances
+	Provider string `yaml:"provider"`
+	// Name of the Cloud SQL instance or the identifier of the RDS DB instance
+	Name string `yaml:"name"`
+	// Project of the Cloud SQL instance (default: the project of the cluster)
+	Project string `yaml:"project,omitempty"`
+	// Region of the RDS DB instance (default: the region of the AWS configuration)
+	Region string `yaml:"region,omitempty"`
+	// Schedule is the name of a schedule in Config.Schedules, empty for the top-level schedule
+	Schedule string `yaml:"schedule,omitempty"`
+}
+
+// String returns the provider and the name of the database, e.g. "rds/orders"
+func (spec DatabaseSpec) String() string {
+	return spec.Provider + "/" + spec.Name
+}
+
+// SleepPageSpec represents an Ingress pointed at a static page while the workloads of its schedule sleep
+type SleepPageSpec struct {
+	// Namespace and Ingress name
+	Namespace string `yaml:"namespace"`
+	Ingress   string `yaml:"ingress"`
+	// Message is a Go template of the message on the page, .BackAt is when work time starts again
+	Message string `yaml:"message,omitempty" default:"This environment is sleeping{{if .BackAt}}, back at {{.BackAt}}{{end}}."`
+	// HTML is a Go template of the whole page, replacing the default page with the message
+	HTML string `yaml:"html,omitempty"`
+	// Image serving the page (default: nginx:1.27-alpine)
+	Image string `yaml:"image,omitempty" default:"nginx:1.27-alpine"`
+	// Schedule is the name of a schedule in Config.Schedules, empty for the top-level schedule
+	Schedule string `yaml:"schedule,omitempty"`
 }
 
 // NodeSpec represents the configuration for a node pool.
@@ -62,11 +423,529 @@ type NodeSpec struct {
 	OffTimeCount  int32  `yaml:"offTimeCount"`  // Number of nodes to maintain during off-hours
 	NodePoolName  string `yaml:"nodePoolName"`  // Name of the node pool to manage
 	CloudProvider string `yaml:"cloudProvider"` // "gke", "aws", or "azure"
+	// TierCounts maps capacity tier names to the number of nodes to maintain during the tier,
+	// tiers without a count use OffTimeCount
+	TierCounts map[string]int32 `yaml:"tierCounts,omitempty"`
+	// Schedule is the name of a schedule in Config.Schedules, empty for the top-level schedule
+	Schedule string `yaml:"schedule,omitempty"`
+	// Paused leaves the node pool untouched, it can also be paused by annotating its control ConfigMap
+	Paused bool `yaml:"paused,omitempty"`
+	// IgnorePodDisruptionBudgets scales the node pool down even if evicting its pods violates PodDisruptionBudgets,
+	// by default the scale down is blocked until the budgets allow it
+	IgnorePodDisruptionBudgets bool `yaml:"ignorePodDisruptionBudgets,omitempty"`
+	// Cooldown is the least time between scaling the node pool down and restoring it, overriding Config.Cooldown
+	Cooldown string `yaml:"cooldown,omitempty"`
+	// HourlyNodeCost is the hourly price of a node of the node pool, used to track the realized savings
+	HourlyNodeCost float64 `yaml:"hourlyNodeCost,omitempty"`
+	// NodePower is the average power draw of a node of the node pool in kW, used to estimate the saved CO2 emissions
+	NodePower float64 `yaml:"nodePower,omitempty"`
+	// DriftPolicy is how resizes of the scaled down node pool outside of bmw-saver are handled, overriding Config.DriftPolicy
+	DriftPolicy string `yaml:"driftPolicy,omitempty"`
+	// RequireApproval holds scale downs of the node pool until they are approved with the
+	// bmw-saver.io/approve-scale-down annotation on its control ConfigMap
+	RequireApproval bool `yaml:"requireApproval,omitempty"`
+	// SavingsModeCount is the number of nodes to maintain during work time while in savings mode after a budget
+	// alert, the node pool is restored as usual if not set
+	SavingsModeCount *int32 `yaml:"savingsModeCount,omitempty"`
+	// IdleScaleDown scales the node pool down to its off-time count during work time once it was idle, and
+	// restores it when pods can't be scheduled, disabled if not configured
+	IdleScaleDown *IdleScaleDownConfig `yaml:"idleScaleDown,omitempty"`
+	// UsageBasedRestore restores the node pool to a percentile of its sizes observed during work time instead
+	// of its saved size, if smaller, restored to its saved size if not configured
+	UsageBasedRestore *UsageBasedRestoreConfig `yaml:"usageBasedRestore,omitempty"`
+	// Drain contains settings for draining the nodes of the node pool while scaling it down
+	Drain *DrainConfig `yaml:"drain,omitempty"`
+}
+
+// DrainConfig contains settings for draining the nodes of a node pool
+type DrainConfig struct {
+	// GracePeriod is how long evicted pods are given to terminate, overriding their terminationGracePeriodSeconds
+	GracePeriod string `yaml:"gracePeriod,omitempty"`
+	// Timeout is how long evictions refused by PodDisruptionBudgets or failing otherwise are retried, with a
+	// backoff, before the drain fails (default: 1m)
+	Timeout string `yaml:"timeout,omitempty"`
+	// Force deletes the pods whose eviction is still refused by PodDisruptionBudgets after the timeout, instead of
+	// failing the drain
+	Force bool `yaml:"force,omitempty"`
+	// ProtectEmptyDir fails the drain of nodes running pods with emptyDir volumes, whose data would be lost,
+	// like kubectl drain does without --delete-emptydir-data
+	ProtectEmptyDir bool `yaml:"protectEmptyDir,omitempty"`
+	// TerminationTimeout is how long the evicted pods are waited for to terminate and the volumes of the nodes to
+	// detach before the nodes are removed (default: 2m)
+	TerminationTimeout string `yaml:"terminationTimeout,omitempty"`
+	// Parallelism is how many nodes of the node pool are drained at a time (default: 1)
+	Parallelism int `yaml:"parallelism,omitempty"`
+	// NodeSelection is which nodes are drained first when the node pool is scaled down to fewer nodes:
+	// emptiest-first, oldest-first or newest-first, in the order they are listed if empty
+	NodeSelection string `yaml:"nodeSelection,omitempty"`
+}
+
+// Strategies for selecting the nodes drained when a node pool is scaled down to fewer nodes
+const (
+	// NodeSelectionEmptiestFirst drains the nodes running the fewest pods first, except DaemonSet pods
+	NodeSelectionEmptiestFirst = "emptiest-first"
+	// NodeSelectionOldestFirst drains the nodes with the earliest creation time first
+	NodeSelectionOldestFirst = "oldest-first"
+	// NodeSelectionNewestFirst drains the nodes with the latest creation time first
+	NodeSelectionNewestFirst = "newest-first"
+)
+
+// RightSizingConfig contains settings for recommending node pool sizes from their utilization during work time
+type RightSizingConfig struct {
+	// Interval is how often recommendations are made (default: 24h)
+	Interval string `yaml:"interval,omitempty" default:"24h"`
+	// Workdays is over how many of the last workdays the utilization is analyzed (default: 5)
+	Workdays int `yaml:"workdays,omitempty"`
+	// CPUTarget is the CPU utilization of node pools, as a fraction of their allocatable CPU, that
+	// recommendations size them for (default: 0.6)
+	CPUTarget float64 `yaml:"cpuTarget,omitempty"`
 }
 
+// UsageBasedRestoreConfig contains settings for restoring node pools to their observed sizes
+type UsageBasedRestoreConfig struct {
+	// Workdays is over how many of the last workdays the sizes are observed (default: 5), the node pool
+	// is restored to its saved size until they were observed on as many
+	Workdays int `yaml:"workdays,omitempty"`
+	// Percentile of the observed sizes to restore to (default: 95)
+	Percentile float64 `yaml:"percentile,omitempty"`
+}
+
+// IdleScaleDownConfig contains settings for scaling idle node pools down during work time
+type IdleScaleDownConfig struct {
+	// IdleFor is how long the node pool must be idle before it's scaled down (default: 2h)
+	IdleFor string `yaml:"idleFor,omitempty" default:"2h"`
+	// CPUThreshold is the average CPU usage of its nodes below which the node pool is idle, if metrics-server
+	// is installed (default: 200m). The node pool is only idle without pods besides DaemonSets and kube-system.
+	CPUThreshold string `yaml:"cpuThreshold,omitempty" default:"200m"`
+	// ExcludedNamespaces are namespaces whose pods don't keep the node pool busy, in addition to kube-system
+	ExcludedNamespaces []string `yaml:"excludedNamespaces,omitempty"`
+}
+
+// Drift policies for node pools resized outside of bmw-saver while scaled down
+const (
+	// DriftPolicyEnforce scales the node pool back to its count in the next reconciliation
+	DriftPolicyEnforce = "enforce"
+	// DriftPolicyRespect leaves the node pool until the next transition, e.g. back to work time
+	DriftPolicyRespect = "respect"
+	// DriftPolicyAlertOnly leaves the node pool until the next transition like respect, and sends a notification
+	DriftPolicyAlertOnly = "alert-only"
+)
+
 // Config represents the overall configuration for the BMW Saver.
 // It contains both scheduling and node pool specifications.
 type Config struct {
-	Schedule  WorkSchedule `yaml:"schedule"`
-	NodeSpecs []NodeSpec   `yaml:"nodeSpecs"`
+	Schedule WorkSchedule `yaml:"schedule"`
+	// Schedules are named schedules defined once and referenced by node specs,
+	// e.g. when many node pools share a few schedules
+	Schedules map[string]WorkSchedule `yaml:"schedules,omitempty"`
+	NodeSpecs []NodeSpec              `yaml:"nodeSpecs"`
+	// WorkloadSpecs are workloads scaled during off time along with the node pools
+	WorkloadSpecs []WorkloadSpec `yaml:"workloadSpecs,omitempty"`
+	// GitOps are the GitOps resources managing the workloads, kept from undoing their scale downs
+	GitOps *GitOpsConfig `yaml:"gitOps,omitempty"`
+	// SleepPages are Ingresses serving a static page during off time instead of errors
+	SleepPages []SleepPageSpec `yaml:"sleepPages,omitempty"`
+	// Databases are managed databases stopped during off time along with the node pools
+	Databases []DatabaseSpec `yaml:"databases,omitempty"`
+	// DatabaseStartTimeout is how long the node pools wait for their databases to become available at work time,
+	// after which they are restored anyway (default: 15m)
+	DatabaseStartTimeout string `yaml:"databaseStartTimeout,omitempty"`
+	// Blackouts are windows during which no scaling changes are made in either direction
+	Blackouts []BlackoutWindow `yaml:"blackouts,omitempty"`
+	// MaintenanceWindows are windows during which node pools aren't scaled down, but may still be restored,
+	// so that scale downs don't interfere with cluster upgrades or node pool maintenance
+	MaintenanceWindows []MaintenanceWindow `yaml:"maintenanceWindows,omitempty"`
+	// SafetyPollInterval is the longest time between reconciliations (default: 5m). Node pools are
+	// reconciled at schedule transitions, the safety poll picks up calendar and override changes.
+	SafetyPollInterval string `yaml:"safetyPollInterval,omitempty"`
+	// ReconcileJitter delays every reconciliation by a random duration up to this long, so that instances
+	// sharing a cloud project or account don't all call its API at the same time. Disabled if empty.
+	ReconcileJitter string `yaml:"reconcileJitter,omitempty"`
+	// MaxBackoff is the longest time a node pool is backed off after scaling it failed (default: 30m).
+	// Failed node pools are retried after 1m, doubling with every further failure.
+	MaxBackoff string `yaml:"maxBackoff,omitempty"`
+	// CircuitBreaker suspends scaling node pools that keep failing, disabled if not configured
+	CircuitBreaker *CircuitBreakerConfig `yaml:"circuitBreaker,omitempty"`
+	// Concurrency is how many node pools are scaled in parallel (default: 4)
+	Concurrency int `yaml:"concurrency,omitempty"`
+	// PoolTimeout is how long scaling a node pool may take, including its hooks, before it is aborted (default: 10m)
+	PoolTimeout string `yaml:"poolTimeout,omitempty"`
+	// Notifications are sent when node pools are scaled or scaling keeps failing
+	Notifications *NotificationsConfig `yaml:"notifications,omitempty"`
+	// Hooks are run before node pools are scaled down and after they are restored
+	Hooks *HooksConfig `yaml:"hooks,omitempty"`
+	// Cooldown is the least time between scaling a node pool down and restoring it, so that it isn't
+	// flipped back and forth while schedule providers flap, e.g. "15m". Disabled if empty.
+	Cooldown string `yaml:"cooldown,omitempty"`
+	// DriftPolicy is how resizes of scaled down node pools outside of bmw-saver, e.g. in an emergency, are handled:
+	// "enforce", "respect" or "alert-only" (default: enforce)
+	DriftPolicy string `yaml:"driftPolicy,omitempty"`
+	// ScaleDownGuard defers scaling down node pools while workloads are active on their nodes
+	ScaleDownGuard *ScaleDownGuardConfig `yaml:"scaleDownGuard,omitempty"`
+	// Savings tracks the node hours and cost saved by scaling node pools down
+	Savings *SavingsConfig `yaml:"savings,omitempty"`
+	// UsageStore persists the sizes of node pools observed for usage-based restores and right-sizing (default: configmap)
+	UsageStore *CacheStoreConfig `yaml:"usageStore,omitempty"`
+	// RightSizing periodically recommends sizes of node pools from their utilization during work time
+	RightSizing *RightSizingConfig `yaml:"rightSizing,omitempty"`
+	// Audit records every scaling decision on node pools and its outcome
+	Audit *AuditConfig `yaml:"audit,omitempty"`
+	// RestoreVerification checks that restored node pools become ready, disabled if not configured
+	RestoreVerification *RestoreVerificationConfig `yaml:"restoreVerification,omitempty"`
+	// PrepareToSleep calls the "prepare to sleep" webhooks of workloads before draining their nodes, disabled if
+	// not configured
+	PrepareToSleep *PrepareToSleepConfig `yaml:"prepareToSleep,omitempty"`
+	// Rebalance spreads pods onto the nodes of restored node pools once they are ready, disabled if not configured
+	Rebalance *RebalanceConfig `yaml:"rebalance,omitempty"`
+	// Approval contains settings for scale downs of node pools requiring approval
+	Approval *ApprovalConfig `yaml:"approval,omitempty"`
+	// KeepAlive contains settings for postponing scale downs with the bmw-saver.io/keep-alive-until annotation
+	KeepAlive *KeepAliveConfig `yaml:"keepAlive,omitempty"`
+	// Boost enables the /boost endpoint forcing work time for a node pool, disabled if not configured
+	Boost *BoostConfig `yaml:"boost,omitempty"`
+	// BudgetAlerts enables the /budget/alerts endpoint entering savings mode on cloud budget alerts, disabled if not configured
+	BudgetAlerts *BudgetAlertsConfig `yaml:"budgetAlerts,omitempty"`
+	// SavedState removes the saved state of node pools no longer configured and guards restoring from stale
+	// saved state, disabled if not configured
+	SavedState *SavedStateConfig `yaml:"savedState,omitempty"`
+}
+
+// SavedStateConfig contains settings for the saved state of scaled down node pools
+type SavedStateConfig struct {
+	// GCInterval is how often the saved state of node pools no longer in the configuration is removed (default: 24h)
+	GCInterval string `yaml:"gcInterval,omitempty" default:"24h"`
+	// MaxAge is how old saved state may be to be restored without confirmation, e.g. "168h", saved state of
+	// any age is restored if empty
+	MaxAge string `yaml:"maxAge,omitempty"`
+}
+
+// BudgetAlertsConfig contains settings for the savings mode entered on GCP Billing Budgets and AWS Budgets alerts
+type BudgetAlertsConfig struct {
+	// Token authenticates alerts as bearer token or token query parameter, read from the BUDGET_ALERTS_TOKEN
+	// environment variable if empty
+	Token string `yaml:"token,omitempty"`
+	// Threshold is the least fraction of a GCP budget whose alert threshold must be exceeded, e.g. 0.9,
+	// any exceeded threshold enters savings mode if 0
+	Threshold float64 `yaml:"threshold,omitempty"`
+	// Duration is how long savings mode lasts after the last alert (default: 24h)
+	Duration string `yaml:"duration,omitempty" default:"24h"`
+	// ScaleDownEarlier is how much earlier work time ends in savings mode, e.g. "2h", the scale down delay
+	// is skipped in savings mode as well
+	ScaleDownEarlier string `yaml:"scaleDownEarlier,omitempty"`
+}
+
+// BoostConfig contains settings for the /boost endpoint
+type BoostConfig struct {
+	// Token authenticates requests as bearer token, read from the BOOST_TOKEN environment variable if empty
+	Token string `yaml:"token,omitempty"`
+	// MaxDuration is the longest boost that can be requested (default: 12h)
+	MaxDuration string `yaml:"maxDuration,omitempty" default:"12h"`
+}
+
+// KeepAliveConfig contains settings for keep-alive annotations
+type KeepAliveConfig struct {
+	// MaxExtension is how long keep-alives may postpone a scale down, the node pool is scaled down anyway
+	// afterwards, "0s" ignores keep-alives (default: 4h)
+	MaxExtension string `yaml:"maxExtension,omitempty"`
+}
+
+// ApprovalConfig contains settings for scale downs of node pools requiring approval
+type ApprovalConfig struct {
+	// Timeout is how long a scale down waits for approval before it's approved anyway, e.g. "2h".
+	// Scale downs wait until they are approved if empty.
+	Timeout string `yaml:"timeout,omitempty"`
+	// SnoozeFor is how long a scale down is postponed when it's snoozed from Slack (default: 1h)
+	SnoozeFor string `yaml:"snoozeFor,omitempty" default:"1h"`
+}
+
+// RestoreVerificationConfig contains settings for verifying that restored node pools become ready.
+// A restored node pool is ready once as many nodes are Ready as it had before it was scaled down,
+// or all of its nodes if that count isn't known, e.g. after a restart.
+type RestoreVerificationConfig struct {
+	// Timeout is how long a restored node pool may take to become ready before an alert (default: 15m)
+	Timeout string `yaml:"timeout,omitempty" default:"15m"`
+}
+
+// PrepareToSleepConfig contains settings for the "prepare to sleep" webhooks annotated on Deployments,
+// StatefulSets and Services with bmw-saver.io/prepare-to-sleep-url
+type PrepareToSleepConfig struct {
+	// Timeout is how long the webhooks of the pods on a node are waited for before draining it anyway (default: 2m)
+	Timeout string `yaml:"timeout,omitempty" default:"2m"`
+}
+
+// RebalanceConfig contains settings for rebalancing pods after restores. Once a restored node pool is ready,
+// pods packed onto the nodes that stayed up during off time are evicted to be rescheduled onto the restored
+// nodes, or a descheduler CronJob is run instead.
+type RebalanceConfig struct {
+	// MaxEvictions is the most pods evicted per restored node pool (default: 20)
+	MaxEvictions int `yaml:"maxEvictions,omitempty"`
+	// MaxPriority is the highest priority of evicted pods, pods with a higher priority stay where they are (default: 0)
+	MaxPriority int32 `yaml:"maxPriority,omitempty"`
+	// DeschedulerCronJob is the "namespace/name" of a descheduler CronJob run once instead of evicting pods
+	DeschedulerCronJob string `yaml:"deschedulerCronJob,omitempty"`
+	// Timeout is how long to wait for a restored node pool to become ready before rebalancing is skipped
+	// (default: 30m)
+	Timeout string `yaml:"timeout,omitempty" default:"30m"`
+}
+
+// AuditConfig contains settings for the audit log of scaling decisions
+type AuditConfig struct {
+	// Type is where the audit log is kept: "configmap", a ring buffer in the bmw-saver-audit ConfigMap,
+	// or "file", appended to Path (default: configmap)
+	Type string `yaml:"type,omitempty" default:"configmap"`
+	// Path is the file for the "file" type, e.g. on a persistent volume
+	Path string `yaml:"path,omitempty"`
+	// MaxEntries is how many entries the "configmap" type keeps, the oldest are dropped (default: 500)
+	MaxEntries int `yaml:"maxEntries,omitempty"`
+}
+
+// SavingsConfig contains settings for tracking the realized savings. The saved cost of a node pool
+// is the node hours it was scaled down multiplied by its hourlyNodeCost.
+type SavingsConfig struct {
+	// Currency of the hourly node costs, used as the metrics label (default: USD)
+	Currency string `yaml:"currency,omitempty" default:"USD"`
+	// CarbonIntensity is the average carbon intensity of the power grid in gCO2eq/kWh, the saved CO2 emissions
+	// of a node pool are estimated from the node hours it was scaled down, its nodePower and the intensity
+	CarbonIntensity float64 `yaml:"carbonIntensity,omitempty"`
+	// Store persists the savings across restarts (default: a ConfigMap)
+	Store *CacheStoreConfig `yaml:"store,omitempty"`
+}
+
+// CircuitBreakerConfig contains settings for suspending scaling of node pools that keep failing.
+// Once suspended, a single attempt is made after the period, another failure suspends the node pool again.
+type CircuitBreakerConfig struct {
+	// Failures is how many reconciliations in a row may fail before the node pool is suspended (default: 5)
+	Failures int `yaml:"failures,omitempty"`
+	// SuspendFor is how long no action is taken on the node pool, neither scaling down nor restoring (default: 1h)
+	SuspendFor string `yaml:"suspendFor,omitempty" default:"1h"`
+}
+
+// ScaleDownGuardConfig contains settings for deferring scale downs while workloads are active.
+// Pods of Jobs, pods annotated with bmw-saver.io/block-scale-down: "true" and recently started pods
+// are active workloads, pods of DaemonSets and in kube-system are ignored.
+type ScaleDownGuardConfig struct {
+	// RecentPodAge is how long after starting a pod counts as active, "0s" disables the check (default: 10m)
+	RecentPodAge string `yaml:"recentPodAge,omitempty" default:"10m"`
+	// MaxDeferral is how long a scale down may be deferred, the node pool is scaled down anyway afterwards (default: 4h)
+	MaxDeferral string `yaml:"maxDeferral,omitempty" default:"4h"`
+	// ExcludedNamespaces are not checked for active workloads, e.g. monitoring agents
+	ExcludedNamespaces []string `yaml:"excludedNamespaces,omitempty"`
+	// CIPipelines defers scale downs of CI runner node pools while jobs are queued or running for their runners
+	CIPipelines []CIPipelinesConfig `yaml:"ciPipelines,omitempty"`
+	// GPU defers scale downs of node pools while pods requesting GPUs run on them, disabled if not configured
+	GPU *GPUGuardConfig `yaml:"gpu,omitempty"`
+	// BatchFrameworks are the frameworks whose batch work running on node pools defers their scale downs,
+	// "job", "kubeflow", "ray" or "spark"
+	BatchFrameworks []string `yaml:"batchFrameworks,omitempty"`
+}
+
+// Batch frameworks whose running work defers scale downs
+const (
+	BatchFrameworkJob      = "job"
+	BatchFrameworkKubeflow = "kubeflow"
+	BatchFrameworkRay      = "ray"
+	BatchFrameworkSpark    = "spark"
+)
+
+// GPUGuardConfig contains settings for deferring scale downs while GPU workloads, e.g. training runs, are running.
+// Running pods requesting any of the GPU resources are GPU workloads, pods of DaemonSets are ignored.
+type GPUGuardConfig struct {
+	// ResourceNames are the extended resources of GPUs (default: nvidia.com/gpu and amd.com/gpu)
+	ResourceNames []string `yaml:"resourceNames,omitempty"`
+	// MaxDeferral is how long a scale down may be deferred for GPU workloads, the node pool is scaled down anyway
+	// afterwards (default: 12h)
+	MaxDeferral string `yaml:"maxDeferral,omitempty" default:"12h"`
+}
+
+// CIPipelinesConfig contains the CI systems whose jobs run on the runners of node pools
+type CIPipelinesConfig struct {
+	// NodePools are the node pools of the runners
+	NodePools []string `yaml:"nodePools"`
+	// GitHub checks GitHub Actions workflow jobs
+	GitHub *GitHubActionsConfig `yaml:"github,omitempty"`
+	// GitLab checks GitLab CI jobs
+	GitLab *GitLabCIConfig `yaml:"gitlab,omitempty"`
+	// Jenkins checks the queue and executors of a Jenkins controller
+	Jenkins *JenkinsConfig `yaml:"jenkins,omitempty"`
+}
+
+// JenkinsConfig contains settings for checking a Jenkins controller
+type JenkinsConfig struct {
+	// URL is the Jenkins controller
+	URL string `yaml:"url"`
+	// User is read from the JENKINS_USER environment variable if empty,
+	// its API token from the JENKINS_API_TOKEN environment variable
+	User string `yaml:"user,omitempty"`
+	// Labels are the labels of the agents on the node pools, builds running on agents with any of them count,
+	// on all agents if empty. Queued builds always count.
+	Labels []string `yaml:"labels,omitempty"`
+	// IdleFor is how long Jenkins must have been idle before the node pools are scaled down (default: 10m)
+	IdleFor string `yaml:"idleFor,omitempty" default:"10m"`
+}
+
+// GitHubActionsConfig contains settings for checking GitHub Actions
+type GitHubActionsConfig struct {
+	// APIURL is the GitHub API, e.g. of a GitHub Enterprise Server (default: https://api.github.com)
+	APIURL string `yaml:"apiUrl,omitempty" default:"https://api.github.com"`
+	// Token is read from the GITHUB_TOKEN environment variable if empty
+	Token string `yaml:"token,omitempty"`
+	// Repositories are the "owner/name" repositories whose workflows run on the runners
+	Repositories []string `yaml:"repositories"`
+	// Labels are the labels of the runners, jobs with any of them count, all jobs if empty
+	Labels []string `yaml:"labels,omitempty"`
+}
+
+// GitLabCIConfig contains settings for checking GitLab CI
+type GitLabCIConfig struct {
+	// URL is the GitLab instance (default: https://gitlab.com)
+	URL string `yaml:"url,omitempty" default:"https://gitlab.com"`
+	// Token is read from the GITLAB_TOKEN environment variable if empty
+	Token string `yaml:"token,omitempty"`
+	// Projects are the IDs or paths, e.g. "group/project", of the projects whose jobs run on the runners
+	Projects []string `yaml:"projects"`
+	// Tags are the tags of the runners, jobs with any of them count, all jobs if empty
+	Tags []string `yaml:"tags,omitempty"`
+}
+
+// HooksConfig contains the hooks run around scaling node pools, one after another
+type HooksConfig struct {
+	// PreScaleDown hooks run before a node pool is scaled down, e.g. to flush caches
+	PreScaleDown []HookConfig `yaml:"preScaleDown,omitempty"`
+	// PostRestore hooks run after a node pool is restored, e.g. to warm up services
+	PostRestore []HookConfig `yaml:"postRestore,omitempty"`
+}
+
+// HookConfig is an HTTP call, a Job or a Velero Backup, exactly one of them must be set
+type HookConfig struct {
+	// Name identifies the hook in logs and names its Jobs
+	Name string `yaml:"name"`
+	// HTTP calls an endpoint with the scaling event as JSON body
+	HTTP *HTTPHookConfig `yaml:"http,omitempty"`
+	// Job runs a Kubernetes Job and waits for it to complete
+	Job *JobHookConfig `yaml:"job,omitempty"`
+	// Velero creates a Velero Backup and waits for it to complete
+	Velero *VeleroHookConfig `yaml:"velero,omitempty"`
+	// Timeout is how long the hook may run (default: 5m)
+	Timeout string `yaml:"timeout,omitempty" default:"5m"`
+	// BlockOnFailure skips the scale down if the hook fails or times out, it is retried in the next reconciliation.
+	// Only applies to preScaleDown hooks.
+	BlockOnFailure bool `yaml:"blockOnFailure,omitempty"`
+	// NodePools limits the hook to these node pools, all if empty
+	NodePools []string `yaml:"nodePools,omitempty"`
+}
+
+// HTTPHookConfig contains settings for calling an HTTP endpoint
+type HTTPHookConfig struct {
+	URL string `yaml:"url"`
+	// Method is the HTTP method (default: POST)
+	Method string `yaml:"method,omitempty"`
+	// Headers are added to the request, e.g. for authorization
+	Headers map[string]string `yaml:"headers,omitempty"`
+}
+
+// JobHookConfig contains settings for running a Kubernetes Job
+type JobHookConfig struct {
+	// Namespace of the Job (default: the controller namespace)
+	Namespace string `yaml:"namespace,omitempty"`
+	// Spec is the Job spec, the restart policy defaults to Never
+	Spec batchv1.JobSpec `yaml:"spec"`
+}
+
+// VeleroHookConfig contains settings for creating a Velero Backup
+type VeleroHookConfig struct {
+	// Namespace of Velero, where the Backup is created (default: velero)
+	Namespace string `yaml:"namespace,omitempty" default:"velero"`
+	// IncludedNamespaces are the namespaces backed up
+	IncludedNamespaces []string `yaml:"includedNamespaces"`
+	// StorageLocation is the backup storage location, empty for the default location of Velero
+	StorageLocation string `yaml:"storageLocation,omitempty"`
+	// TTL is how long the Backup is kept, empty for the default TTL of Velero
+	TTL string `yaml:"ttl,omitempty"`
+}
+
+// NotificationsConfig contains the sinks of scaling notifications
+type NotificationsConfig struct {
+	// FailureThreshold is how many reconciliations in a row scaling a node pool may fail
+	// before a failure notification is sent (default: 3)
+	FailureThreshold int `yaml:"failureThreshold,omitempty"`
+	// Slack posts notifications to an incoming webhook
+	Slack *SlackConfig `yaml:"slack,omitempty"`
+	// Webhook posts notifications to any HTTP endpoint
+	Webhook *WebhookConfig `yaml:"webhook,omitempty"`
+	// Teams posts notifications to a Microsoft Teams Workflows webhook
+	Teams *TeamsConfig `yaml:"teams,omitempty"`
+	// Discord posts notifications to a Discord webhook
+	Discord *DiscordConfig `yaml:"discord,omitempty"`
+	// PagerDuty triggers an incident on failures and resolves it on recovery
+	PagerDuty *PagerDutyConfig `yaml:"pagerDuty,omitempty"`
+	// Opsgenie creates an alert on failures and closes it on recovery
+	Opsgenie *OpsgenieConfig `yaml:"opsgenie,omitempty"`
+}
+
+// SlackConfig contains settings for Slack notifications
+type SlackConfig struct {
+	// WebhookURL is the incoming webhook URL, read from the SLACK_WEBHOOK_URL environment variable if empty
+	WebhookURL string `yaml:"webhookUrl,omitempty"`
+	// Interactive adds Approve and Snooze buttons to the messages of scale downs waiting for approval,
+	// the webhook has to belong to a Slack app whose interactivity request URL is /slack/interactions
+	Interactive bool `yaml:"interactive,omitempty"`
+	// SigningSecret verifies the interactions of the Slack app,
+	// read from the SLACK_SIGNING_SECRET environment variable if empty
+	SigningSecret string `yaml:"signingSecret,omitempty"`
+}
+
+// WebhookConfig contains settings for generic webhook notifications
+type WebhookConfig struct {
+	// URL is the endpoint, read from the NOTIFICATION_WEBHOOK_URL environment variable if empty
+	URL string `yaml:"url,omitempty"`
+	// Body is a Go template of the JSON body, e.g. {"message": {{ json .Text }}}.
+	// The notification is posted as JSON if empty.
+	Body string `yaml:"body,omitempty"`
+}
+
+// TeamsConfig contains settings for Microsoft Teams notifications
+type TeamsConfig struct {
+	// WebhookURL is the Workflows webhook URL, read from the TEAMS_WEBHOOK_URL environment variable if empty
+	WebhookURL string `yaml:"webhookUrl,omitempty"`
+}
+
+// DiscordConfig contains settings for Discord notifications
+type DiscordConfig struct {
+	// WebhookURL is the webhook URL, read from the DISCORD_WEBHOOK_URL environment variable if empty
+	WebhookURL string `yaml:"webhookUrl,omitempty"`
+}
+
+// PagerDutyConfig contains settings for PagerDuty incidents, the integration routing key
+// is read from the PAGERDUTY_ROUTING_KEY environment variable
+type PagerDutyConfig struct{}
+
+// OpsgenieConfig contains settings for Opsgenie alerts, the API integration key
+// is read from the OPSGENIE_API_KEY environment variable
+type OpsgenieConfig struct {
+	// APIURL is the Opsgenie API, e.g. "https://api.eu.opsgenie.com" in the EU (default: https://api.opsgenie.com)
+	APIURL string `yaml:"apiUrl,omitempty"`
+}
+
+// BlackoutWindow is a time window during which node pools are left untouched,
+// e.g. end-of-quarter or migrations
+type BlackoutWindow struct {
+	Start  string `yaml:"start"`            // RFC 3339, e.g. "2024-06-28T00:00:00Z"
+	End    string `yaml:"end"`              // RFC 3339, exclusive
+	Reason string `yaml:"reason,omitempty"` // Shown in the logs
+}
+
+// MaintenanceWindow is a one-off window from Start to End, or a weekly window from StartTime to EndTime
+// on Days. Weekly windows ending before they start end on the next day.
+type MaintenanceWindow struct {
+	Start string `yaml:"start,omitempty"` // RFC 3339, e.g. "2024-06-28T00:00:00Z"
+	End   string `yaml:"end,omitempty"`   // RFC 3339, exclusive
+	// StartTime and EndTime are in the format "HH:MM", in TimeZone (default: UTC)
+	StartTime string `yaml:"startTime,omitempty"`
+	EndTime   string `yaml:"endTime,omitempty"`
+	TimeZone  string `yaml:"timeZone,omitempty" default:"UTC"`
+	// Days are lowercase weekday names the window starts on (default: every day)
+	Days []string `yaml:"days,omitempty"`
+	// NodePools are the node pools under maintenance (default: all node pools)
+	NodePools []string `yaml:"nodePools,omitempty"`
+	Reason    string   `yaml:"reason,omitempty"` // Shown in the logs and Events
 }
@@ -0,0 +1,212 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+
+	"github.com/kezhenxu94/bmw-saver/pkg/audit"
+	"github.com/kezhenxu94/bmw-saver/pkg/config"
+	"github.com/kezhenxu94/bmw-saver/pkg/notify"
+)
+
+const (
+	// ApproveScaleDownAnnotation approves the pending scale down of a node pool requiring approval when set to "true"
+	// on its control ConfigMap. The approval is removed once the node pool is scaled down or restored.
+	ApproveScaleDownAnnotation = "bmw-saver.io/approve-scale-down"
+	// SnoozeScaleDownAnnotation postpones the pending scale down of a node pool requiring approval until the
+	// RFC 3339 time on its control ConfigMap, approval is asked for again afterwards.
+	SnoozeScaleDownAnnotation = "bmw-saver.io/snooze-scale-down-until"
+)
+
+// approval is the approval of the scale down of a node pool on its control ConfigMap
+type approval struct {
+	approved     bool
+	snoozedUntil time.Time
+}
+
+// initApproval initializes the approval timeout and snooze duration based on configuration
+func (sc *ScalingController) initApproval(cfg config.Config) {
+	sc.approvalTimeout, sc.snoozeFor = 0, 0
+	if cfg.Approval != nil {
+		sc.approvalTimeout, _ = time.ParseDuration(cfg.Approval.Timeout)
+		sc.snoozeFor, _ = time.ParseDuration(cfg.Approval.SnoozeFor)
+	}
+}
+
+// awaitingApproval returns true if the scale down of the node pool to count requires approval and isn't approved yet.
+// The scale down is recorded as pending, with an Event and a notification when it starts pending and when
+// its snooze ends. It's approved anyway once it pended for the approval timeout.
+func (sc *ScalingController) awaitingApproval(ctx context.Context, now time.Time, pool *poolState, spec config.NodeSpec,
+	notification notify.Notification, action string, count int32, nodes int) bool {
+	if !spec.RequireApproval {
+		return false
+	}
+	approval := sc.getApproval(ctx, spec)
+	if approval.approved {
+		pool.pendingApproval = ""
+		return false
+	}
+
+	if pool.pendingApproval != action {
+		pool.pendingApproval = action
+		pool.pendingSince = now
+		pool.snoozedUntil = time.Time{}
+		sc.askApproval(ctx, spec, notification, count)
+	}
+
+	if approval.snoozedUntil.After(now) {
+		pool.snoozedUntil = approval.snoozedUntil
+		sc.recordAudit(ctx, now, pool, spec, notification.Reason, action, nodes, audit.OutcomeBlocked,
+			"snoozed until "+approval.snoozedUntil.Format(time.RFC3339))
+		return true
+	}
+	if !pool.snoozedUntil.IsZero() {
+		// The snooze ended, the approval timeout starts over
+		pool.snoozedUntil = time.Time{}
+		pool.pendingSince = now
+		sc.askApproval(ctx, spec, notification, count)
+	}
+
+	if sc.approvalTimeout > 0 && !now.Before(pool.pendingSince.Add(sc.approvalTimeout)) {
+		slog.Info("Scale down of node pool approved after approval timeout", "node_pool", spec.NodePoolName, "timeout", sc.approvalTimeout)
+		sc.recordEvent(corev1.EventTypeNormal, eventReasonScaleDownApproved,
+			"Scale down of node pool %s approved after waiting %s for approval", spec.NodePoolName, sc.approvalTimeout)
+		pool.pendingApproval = ""
+		return false
+	}
+
+	sc.recordAudit(ctx, now, pool, spec, notification.Reason, action, nodes, audit.OutcomeBlocked, "waiting for approval")
+	return true
+}
+
+// askApproval records an Event and sends a notification that the scale down of the node pool is waiting for approval
+func (sc *ScalingController) askApproval(ctx context.Context, spec config.NodeSpec, notification notify.Notification, count int32) {
+	slog.Info("Scale down of node pool waiting for approval", "node_pool", spec.NodePoolName, "count", count)
+	sc.recordEvent(corev1.EventTypeNormal, eventReasonScaleDownPendingApproval,
+		"Scale down of node pool %s to %d nodes is waiting for approval, annotate configmap/%s%s with %s=true",
+		spec.NodePoolName, count, PoolConfigMapNamePrefix, spec.NodePoolName, ApproveScaleDownAnnotation)
+	notification.Kind = notify.KindApprovalPending
+	sc.notifier.Notify(ctx, notification)
+}
+
+// getApproval returns the approval of the scale down of the node pool from the annotations on its control ConfigMap
+func (sc *ScalingController) getApproval(ctx context.Context, spec config.NodeSpec) approval {
+	if sc.client == nil {
+		return approval{}
+	}
+
+	name := PoolConfigMapNamePrefix + spec.NodePoolName
+	cm, err := sc.client.CoreV1().ConfigMaps(os.Getenv("NAMESPACE")).Get(ctx, name, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		return approval{}
+	}
+	if err != nil {
+		slog.Warn("Failed to check if scale down of node pool is approved", "node_pool", spec.NodePoolName, "config_map", name, "error", err)
+		return approval{}
+	}
+	return parseApproval(cm.Annotations)
+}
+
+// parseApproval returns the approval from the annotations of a control ConfigMap, ignoring invalid values
+func parseApproval(annotations map[string]string) approval {
+	var a approval
+	a.approved, _ = strconv.ParseBool(annotations[ApproveScaleDownAnnotation])
+	if value, ok := annotations[SnoozeScaleDownAnnotation]; ok {
+		until, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			slog.Warn("Invalid snooze annotation", "annotation", SnoozeScaleDownAnnotation, "value", value)
+		}
+		a.snoozedUntil = until
+	}
+	return a
+}
+
+// consumeApproval removes the approval and snooze of the node pool, so that its next scale down has to be approved again
+func (sc *ScalingController) consumeApproval(ctx context.Context, spec config.NodeSpec) {
+	if !spec.RequireApproval || sc.client == nil {
+		return
+	}
+	if err := sc.annotatePoolConfigMap(ctx, spec, nil, ApproveScaleDownAnnotation, SnoozeScaleDownAnnotation); err != nil {
+		slog.Warn("Failed to remove approval of node pool", "node_pool", spec.NodePoolName, "error", err)
+	}
+}
+
+// annotatePoolConfigMap sets the annotations on the control ConfigMap of the node pool and removes the keys.
+// The ConfigMap is created if annotations are set and it doesn't exist.
+func (sc *ScalingController) annotatePoolConfigMap(ctx context.Context, spec config.NodeSpec, annotations map[string]string, remove ...string) error {
+	return sc.annotateConfigMap(ctx, PoolConfigMapNamePrefix+spec.NodePoolName, annotations, remove...)
+}
+
+// annotateConfigMap sets the annotations on the ConfigMap in the controller namespace and removes the keys.
+// The ConfigMap is created if annotations are set and it doesn't exist.
+func (sc *ScalingController) annotateConfigMap(ctx context.Context, name string, annotations map[string]string, remove ...string) error {
+	configMaps := sc.client.CoreV1().ConfigMaps(os.Getenv("NAMESPACE"))
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cm, err := configMaps.Get(ctx, name, metav1.GetOptions{})
+		if k8serrors.IsNotFound(err) {
+			if len(annotations) == 0 {
+				return nil
+			}
+			cm = &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: name, Annotations: annotations}}
+			_, err = configMaps.Create(ctx, cm, metav1.CreateOptions{})
+			return err
+		}
+		if err != nil {
+			return err
+		}
+
+		changed := false
+		for _, key := range remove {
+			if _, ok := cm.Annotations[key]; ok {
+				delete(cm.Annotations, key)
+				changed = true
+			}
+		}
+		for key, value := range annotations {
+			if cm.Annotations == nil {
+				cm.Annotations = make(map[string]string)
+			}
+			if cm.Annotations[key] != value {
+				cm.Annotations[key] = value
+				changed = true
+			}
+		}
+		if !changed {
+			return nil
+		}
+		_, err = configMaps.Update(ctx, cm, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to annotate configmap %s: %v", name, err)
+	}
+	return nil
+}
+
+// nextApprovalDeadline returns when the earliest snooze or approval timeout of the pending scale downs
+// of the schedule ends, zero if none does
+func (sc *ScalingController) nextApprovalDeadline(now time.Time, state *scheduleState) time.Time {
+	var next time.Time
+	for _, pool := range state.pools {
+		if pool.pendingApproval == "" {
+			continue
+		}
+		end := pool.snoozedUntil
+		if end.IsZero() && sc.approvalTimeout > 0 {
+			end = pool.pendingSince.Add(sc.approvalTimeout)
+		}
+		if end.After(now) && (next.IsZero() || end.Before(next)) {
+			next = end
+		}
+	}
+	return next
+}
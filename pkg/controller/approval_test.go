@@ -0,0 +1,85 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kezhenxu94/bmw-saver/pkg/config"
+	"github.com/kezhenxu94/bmw-saver/pkg/notify"
+)
+
+func TestAwaitingApproval(t *testing.T) {
+	spec := config.NodeSpec{NodePoolName: "default-pool", RequireApproval: true}
+	pool := &poolState{}
+	sc := &ScalingController{
+		config:    config.Config{NodeSpecs: []config.NodeSpec{spec}},
+		schedules: map[string]*scheduleState{defaultScheduleName: {pools: map[string]*poolState{spec.NodePoolName: pool}}},
+	}
+	now := time.Date(2024, time.June, 4, 18, 0, 0, 0, time.UTC)
+	notification := notify.Notification{NodePool: "default-pool"}
+
+	if sc.awaitingApproval(context.Background(), now, pool, config.NodeSpec{NodePoolName: "default-pool"}, notification, "0", 0, 3) {
+		t.Errorf("awaitingApproval() = true for a node pool not requiring approval")
+	}
+
+	if !sc.awaitingApproval(context.Background(), now, pool, spec, notification, "0", 0, 3) {
+		t.Fatalf("awaitingApproval() = false without approval")
+	}
+	if pool.pendingApproval != "0" {
+		t.Errorf("pendingApproval = %q, want 0", pool.pendingApproval)
+	}
+	status := sc.Status(context.Background(), now, now)
+	if got := status.NodePools[spec.NodePoolName].PendingApproval; got != "scale down to 0 nodes" {
+		t.Errorf("PendingApproval = %q, want scale down to 0 nodes", got)
+	}
+}
+
+func TestAwaitingApproval_Timeout(t *testing.T) {
+	spec := config.NodeSpec{NodePoolName: "default-pool", RequireApproval: true}
+	pool := &poolState{}
+	state := &scheduleState{pools: map[string]*poolState{spec.NodePoolName: pool}}
+	sc := &ScalingController{approvalTimeout: time.Hour}
+	now := time.Date(2024, time.June, 4, 18, 0, 0, 0, time.UTC)
+	notification := notify.Notification{NodePool: "default-pool"}
+
+	if !sc.awaitingApproval(context.Background(), now, pool, spec, notification, "0", 0, 3) {
+		t.Fatalf("awaitingApproval() = false without approval")
+	}
+	if got := sc.nextApprovalDeadline(now, state); !got.Equal(now.Add(time.Hour)) {
+		t.Errorf("nextApprovalDeadline() = %v, want %v", got, now.Add(time.Hour))
+	}
+	if !sc.awaitingApproval(context.Background(), now.Add(59*time.Minute), pool, spec, notification, "0", 0, 3) {
+		t.Errorf("awaitingApproval() = false before the approval timeout")
+	}
+	if sc.awaitingApproval(context.Background(), now.Add(time.Hour), pool, spec, notification, "0", 0, 3) {
+		t.Errorf("awaitingApproval() = true after the approval timeout")
+	}
+	if pool.pendingApproval != "" {
+		t.Errorf("pendingApproval = %q after the approval timeout, want none", pool.pendingApproval)
+	}
+}
+
+func TestParseApproval(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		want        approval
+	}{
+		{name: "None", annotations: nil, want: approval{}},
+		{name: "Approved", annotations: map[string]string{ApproveScaleDownAnnotation: "true"}, want: approval{approved: true}},
+		{
+			name:        "Snoozed",
+			annotations: map[string]string{SnoozeScaleDownAnnotation: "2024-06-04T19:00:00Z"},
+			want:        approval{snoozedUntil: time.Date(2024, time.June, 4, 19, 0, 0, 0, time.UTC)},
+		},
+		{name: "Invalid Snooze", annotations: map[string]string{SnoozeScaleDownAnnotation: "tomorrow"}, want: approval{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseApproval(tt.annotations); got.approved != tt.want.approved || !got.snoozedUntil.Equal(tt.want.snoozedUntil) {
+				t.Errorf("parseApproval() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
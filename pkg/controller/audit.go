@@ -0,0 +1,72 @@
+package controller
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/kezhenxu94/bmw-saver/pkg/audit"
+	"github.com/kezhenxu94/bmw-saver/pkg/config"
+)
+
+// defaultAuditMaxEntries is how many entries the audit ConfigMap keeps if not configured
+const defaultAuditMaxEntries = 500
+
+// initAudit initializes the audit log sink based on configuration
+func (sc *ScalingController) initAudit(cfg config.Config) {
+	sc.auditSink = nil
+	if cfg.Audit == nil {
+		return
+	}
+
+	switch cfg.Audit.Type {
+	case "file":
+		sc.auditSink = audit.NewFileSink(cfg.Audit.Path)
+	default:
+		if sc.client == nil {
+			return
+		}
+		maxEntries := defaultAuditMaxEntries
+		if cfg.Audit.MaxEntries > 0 {
+			maxEntries = cfg.Audit.MaxEntries
+		}
+		sc.auditSink = audit.NewConfigMapSink(sc.client, os.Getenv("NAMESPACE"), maxEntries)
+	}
+}
+
+// recordAudit records a scaling decision on the node pool in the audit log, unless it is the same as the last
+// one recorded, e.g. while a scale down stays blocked. nodes is the node count before the action, -1 if unknown.
+// Failures are only logged.
+func (sc *ScalingController) recordAudit(ctx context.Context, now time.Time, pool *poolState, spec config.NodeSpec,
+	reason, action string, nodes int, outcome audit.Outcome, detail string) {
+	if sc.auditSink == nil {
+		return
+	}
+	key := action + "/" + string(outcome) + "/" + detail
+	if pool.lastAudit == key {
+		return
+	}
+	pool.lastAudit = key
+
+	entry := audit.Entry{
+		Time:     now,
+		NodePool: spec.NodePoolName,
+		Schedule: scheduleLabel(spec.Schedule),
+		Action:   actionLabel(action),
+		Reason:   reason,
+		Outcome:  outcome,
+		Detail:   detail,
+	}
+	if nodes >= 0 {
+		entry.From = &nodes
+	}
+	if count, err := strconv.ParseInt(action, 10, 32); err == nil {
+		to := int32(count)
+		entry.To = &to
+	}
+	if err := sc.auditSink.Record(ctx, entry); err != nil {
+		slog.Warn("Failed to record audit log entry", "node_pool", spec.NodePoolName, "error", err)
+	}
+}
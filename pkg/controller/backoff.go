@@ -0,0 +1,130 @@
+package controller
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/kezhenxu94/bmw-saver/pkg/config"
+	"github.com/kezhenxu94/bmw-saver/pkg/notify"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// initialBackoff is how long a node pool is backed off after its first failure, doubling with every further one
+	initialBackoff = time.Minute
+	// defaultMaxBackoff is the longest backoff of a failing node pool if not configured
+	defaultMaxBackoff = 30 * time.Minute
+	// defaultBreakerFailures is how many failures in a row suspend a node pool if the circuit breaker doesn't configure it
+	defaultBreakerFailures = 5
+)
+
+// circuitBreaker suspends scaling node pools after too many failures in a row
+type circuitBreaker struct {
+	failures   int
+	suspendFor time.Duration
+}
+
+// initCircuitBreaker initializes the circuit breaker based on configuration, the duration is validated when reading it
+func (sc *ScalingController) initCircuitBreaker(cfg config.Config) {
+	sc.circuitBreaker = nil
+	if cfg.CircuitBreaker == nil {
+		return
+	}
+
+	failures := defaultBreakerFailures
+	if cfg.CircuitBreaker.Failures > 0 {
+		failures = cfg.CircuitBreaker.Failures
+	}
+	suspendFor, _ := time.ParseDuration(cfg.CircuitBreaker.SuspendFor)
+	sc.circuitBreaker = &circuitBreaker{failures: failures, suspendFor: suspendFor}
+}
+
+// retryBackoff is when a failed scaling action of a node pool is retried
+type retryBackoff struct {
+	// action is "restore" or the scaled down count, like poolState.applied
+	action string
+	until  time.Time
+}
+
+// getMaxBackoff parses the max backoff, falling back to the default
+func getMaxBackoff(maxBackoff string) time.Duration {
+	if d, err := time.ParseDuration(maxBackoff); err == nil && d > 0 {
+		return d
+	}
+	return defaultMaxBackoff
+}
+
+// backoffDelay returns how long to wait before retrying after the number of failures in a row,
+// doubling from the initial backoff up to the max backoff
+func backoffDelay(failures int, maxBackoff time.Duration) time.Duration {
+	delay := initialBackoff
+	for i := 1; i < failures && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, maxBackoff)
+}
+
+// backOff delays retrying the failed action of the node pool according to its failures in a row. Once they
+// reach the circuit breaker failures, no action at all is taken on the node pool until it is suspended for.
+func (sc *ScalingController) backOff(ctx context.Context, now time.Time, pool *poolState, notification notify.Notification, action string) {
+	if breaker := sc.circuitBreaker; breaker != nil && pool.failures >= breaker.failures {
+		pool.backoff = nil
+		pool.suspendedUntil = now.Add(breaker.suspendFor)
+		slog.Warn("Suspending node pool after too many failures",
+			"node_pool", notification.NodePool,
+			"failures", pool.failures,
+			"suspended_until", pool.suspendedUntil,
+		)
+		sc.recordEvent(corev1.EventTypeWarning, eventReasonScalingSuspended,
+			"Scaling node pool %s failed %d times in a row, suspended until %s: %s",
+			notification.NodePool, pool.failures, pool.suspendedUntil.Format(time.RFC3339), pool.lastError)
+		// Notify once, not after every failed attempt of the suspended node pool
+		if pool.failures == breaker.failures {
+			notification.Kind = notify.KindSuspended
+			notification.Error = pool.lastError
+			notification.Failures = pool.failures
+			notification.SuspendedUntil = &pool.suspendedUntil
+			sc.notifier.Notify(ctx, notification)
+		}
+		return
+	}
+
+	delay := backoffDelay(pool.failures, sc.maxBackoff)
+	pool.backoff = &retryBackoff{action: action, until: now.Add(delay)}
+	slog.Info("Backing off node pool", "node_pool", notification.NodePool, "failures", pool.failures, "retry_at", pool.backoff.until)
+}
+
+// suspended returns true if the circuit breaker suspended scaling the node pool, for any action
+func (pool *poolState) suspended(now time.Time, spec config.NodeSpec) bool {
+	if !now.Before(pool.suspendedUntil) {
+		return false
+	}
+	slog.Debug("Node pool suspended after failures", "node_pool", spec.NodePoolName, "suspended_until", pool.suspendedUntil)
+	return true
+}
+
+// backingOff returns true if the action of the node pool failed before and is not retried yet.
+// Other actions, e.g. restoring after a failed scale down, are taken right away.
+func (pool *poolState) backingOff(now time.Time, spec config.NodeSpec, action string) bool {
+	if pool.backoff == nil || pool.backoff.action != action || !now.Before(pool.backoff.until) {
+		return false
+	}
+	slog.Debug("Node pool backing off after failures", "node_pool", spec.NodePoolName, "retry_at", pool.backoff.until)
+	return true
+}
+
+// nextRetry returns when the earliest backed off or suspended node pool of the schedule is retried, zero if none is
+func (state *scheduleState) nextRetry(now time.Time) time.Time {
+	var next time.Time
+	for _, pool := range state.pools {
+		if pool.backoff != nil && pool.backoff.until.After(now) && (next.IsZero() || pool.backoff.until.Before(next)) {
+			next = pool.backoff.until
+		}
+		if pool.suspendedUntil.After(now) && (next.IsZero() || pool.suspendedUntil.Before(next)) {
+			next = pool.suspendedUntil
+		}
+	}
+	return next
+}
@@ -0,0 +1,61 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kezhenxu94/bmw-saver/pkg/config"
+	"github.com/kezhenxu94/bmw-saver/pkg/notify"
+)
+
+func TestBackoffDelay(t *testing.T) {
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{failures: 1, want: time.Minute},
+		{failures: 2, want: 2 * time.Minute},
+		{failures: 4, want: 8 * time.Minute},
+		{failures: 6, want: 30 * time.Minute},
+		{failures: 100, want: 30 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		if got := backoffDelay(tt.failures, 30*time.Minute); got != tt.want {
+			t.Errorf("backoffDelay(%d) = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	sc := &ScalingController{
+		maxBackoff:     30 * time.Minute,
+		circuitBreaker: &circuitBreaker{failures: 3, suspendFor: time.Hour},
+	}
+	spec := config.NodeSpec{NodePoolName: "default-pool"}
+	notification := notify.Notification{NodePool: spec.NodePoolName}
+	now := time.Date(2024, time.June, 4, 18, 0, 0, 0, time.UTC)
+	pool := &poolState{}
+
+	for i := 0; i < 2; i++ {
+		pool.failures++
+		sc.backOff(context.Background(), now, pool, notification, "1")
+	}
+	if pool.suspended(now, spec) || pool.backoff == nil {
+		t.Fatalf("Node pool suspended after 2 failures, want backing off")
+	}
+
+	pool.failures++
+	sc.backOff(context.Background(), now, pool, notification, "1")
+	if !pool.suspended(now.Add(59*time.Minute), spec) {
+		t.Errorf("Node pool not suspended after 3 failures")
+	}
+	// A different action is suspended as well, unlike backing off
+	if !pool.suspended(now, spec) || pool.backingOff(now, spec, "restore") {
+		t.Errorf("Restore not suspended after 3 failures")
+	}
+	if pool.suspended(now.Add(time.Hour), spec) {
+		t.Errorf("Node pool still suspended after the suspend duration")
+	}
+}
@@ -0,0 +1,164 @@
+package controller
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kezhenxu94/bmw-saver/pkg/config"
+	"github.com/kezhenxu94/bmw-saver/pkg/schedule"
+)
+
+// BoostAnnotation forces work time for a node pool until the RFC 3339 time on its control ConfigMap
+const BoostAnnotation = "bmw-saver.io/boost-until"
+
+// boostRequest is the body of a boost request
+type boostRequest struct {
+	// Pool is the node pool to boost
+	Pool string `json:"pool"`
+	// Duration is how long the node pool is boosted, e.g. "2h", "0s" ends the boost
+	Duration string `json:"duration"`
+}
+
+// boostResponse is the body of a boost response
+type boostResponse struct {
+	Pool  string    `json:"pool"`
+	Until time.Time `json:"until"`
+}
+
+// initBoost initializes the boost endpoint based on configuration, the durations are validated when reading it
+func (sc *ScalingController) initBoost(cfg config.Config) {
+	sc.boostToken, sc.maxBoost = "", 0
+	if cfg.Boost == nil {
+		return
+	}
+	sc.boostToken = getEnvDefault(cfg.Boost.Token, "BOOST_TOKEN")
+	sc.maxBoost, _ = time.ParseDuration(cfg.Boost.MaxDuration)
+}
+
+// boost returns the decision forcing work time if the node pool is boosted, and when the boost ends.
+// Failed checks don't boost the node pool.
+func (sc *ScalingController) boost(ctx context.Context, now time.Time, spec config.NodeSpec) (schedule.Decision, time.Time) {
+	if sc.client == nil {
+		return schedule.Decision{}, time.Time{}
+	}
+
+	name := PoolConfigMapNamePrefix + spec.NodePoolName
+	cm, err := sc.client.CoreV1().ConfigMaps(os.Getenv("NAMESPACE")).Get(ctx, name, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		return schedule.Decision{}, time.Time{}
+	}
+	if err != nil {
+		slog.Warn("Failed to check if node pool is boosted", "node_pool", spec.NodePoolName, "config_map", name, "error", err)
+		return schedule.Decision{}, time.Time{}
+	}
+
+	value, ok := cm.Annotations[BoostAnnotation]
+	if !ok {
+		return schedule.Decision{}, time.Time{}
+	}
+	until, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		slog.Warn("Invalid boost annotation", "node_pool", spec.NodePoolName, "annotation", BoostAnnotation, "value", value)
+		return schedule.Decision{}, time.Time{}
+	}
+	if !until.After(now) {
+		return schedule.Decision{}, time.Time{}
+	}
+	return schedule.Decision{
+		IsWorkTime: true,
+		Provider:   "boost",
+		Reason:     "boosted until " + until.Format(time.RFC3339),
+	}, until
+}
+
+// nextBoostEnd returns when the earliest boost of the node pools of the schedule ends, zero if none is boosted
+func (state *scheduleState) nextBoostEnd(now time.Time) time.Time {
+	var next time.Time
+	for _, pool := range state.pools {
+		if end := pool.boostedUntil; end.After(now) && (next.IsZero() || end.Before(next)) {
+			next = end
+		}
+	}
+	return next
+}
+
+// BoostHandler serves POST requests with a JSON body {"pool": "...", "duration": "2h"} forcing work time for the
+// node pool for the duration, e.g. for an urgent deploy off hours. Requests are authenticated with the boost token
+// as bearer token. The boost is set on the control ConfigMap of the node pool, so any shard can serve it.
+func (sc *ScalingController) BoostHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		sc.mu.RLock()
+		token, maxBoost := sc.boostToken, sc.maxBoost
+		specs := sc.config.NodeSpecs
+		sc.mu.RUnlock()
+		if token == "" || sc.client == nil {
+			http.Error(w, "boost isn't enabled", http.StatusNotFound)
+			return
+		}
+		bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(bearer), []byte(token)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var req boostRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		duration, err := time.ParseDuration(req.Duration)
+		if err != nil || duration < 0 || duration > maxBoost {
+			http.Error(w, fmt.Sprintf("invalid duration: %q, must be at most %s", req.Duration, maxBoost), http.StatusBadRequest)
+			return
+		}
+		if req.Pool == "" || (sc.shard.Owns(req.Pool) && !hasNodePool(specs, req.Pool)) {
+			http.Error(w, "unknown node pool: "+req.Pool, http.StatusNotFound)
+			return
+		}
+
+		spec := config.NodeSpec{NodePoolName: req.Pool}
+		until := time.Now().Add(duration).UTC().Truncate(time.Second)
+		if duration == 0 {
+			err = sc.annotatePoolConfigMap(r.Context(), spec, nil, BoostAnnotation)
+		} else {
+			err = sc.annotatePoolConfigMap(r.Context(), spec, map[string]string{BoostAnnotation: until.Format(time.RFC3339)})
+		}
+		if err != nil {
+			slog.Error("Failed to boost node pool", "node_pool", req.Pool, "error", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		slog.Info("Node pool boosted", "node_pool", req.Pool, "until", until)
+		sc.triggerReconcile()
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(boostResponse{Pool: req.Pool, Until: until}); err != nil {
+			slog.Error("Failed to write boost response", "error", err)
+		}
+	})
+}
+
+// hasNodePool returns true if a node spec of the node pool is configured
+func hasNodePool(specs []config.NodeSpec, nodePoolName string) bool {
+	for _, spec := range specs {
+		if spec.NodePoolName == nodePoolName {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,65 @@
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	"github.com/kezhenxu94/bmw-saver/pkg/config"
+)
+
+func TestBoostHandler_Rejects(t *testing.T) {
+	sc := &ScalingController{
+		// Rejected requests never reach the API server
+		client:     kubernetes.NewForConfigOrDie(&rest.Config{Host: "http://127.0.0.1:1"}),
+		config:     config.Config{NodeSpecs: []config.NodeSpec{{NodePoolName: "default-pool"}}},
+		boostToken: "secret",
+		maxBoost:   12 * time.Hour,
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		token      string
+		body       string
+		wantStatus int
+	}{
+		{name: "Method", method: http.MethodGet, token: "secret", wantStatus: http.StatusMethodNotAllowed},
+		{name: "No Token", method: http.MethodPost, body: `{"pool": "default-pool", "duration": "2h"}`, wantStatus: http.StatusUnauthorized},
+		{name: "Wrong Token", method: http.MethodPost, token: "guess", body: `{"pool": "default-pool", "duration": "2h"}`, wantStatus: http.StatusUnauthorized},
+		{name: "Invalid Body", method: http.MethodPost, token: "secret", body: `pool=default-pool`, wantStatus: http.StatusBadRequest},
+		{name: "Too Long", method: http.MethodPost, token: "secret", body: `{"pool": "default-pool", "duration": "24h"}`, wantStatus: http.StatusBadRequest},
+		{name: "Unknown Pool", method: http.MethodPost, token: "secret", body: `{"pool": "other-pool", "duration": "2h"}`, wantStatus: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/boost", strings.NewReader(tt.body))
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rec := httptest.NewRecorder()
+			sc.BoostHandler().ServeHTTP(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("BoostHandler() status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+		})
+	}
+}
+
+func TestNextBoostEnd(t *testing.T) {
+	now := time.Date(2024, time.June, 4, 20, 0, 0, 0, time.UTC)
+	state := &scheduleState{pools: map[string]*poolState{
+		"expired":   {boostedUntil: now.Add(-time.Hour)},
+		"boosted":   {boostedUntil: now.Add(2 * time.Hour)},
+		"soonest":   {boostedUntil: now.Add(time.Hour)},
+		"unboosted": {},
+	}}
+	if got, want := state.nextBoostEnd(now), now.Add(time.Hour); !got.Equal(want) {
+		t.Errorf("nextBoostEnd() = %v, want %v", got, want)
+	}
+}
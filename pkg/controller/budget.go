@@ -0,0 +1,226 @@
+package controller
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kezhenxu94/bmw-saver/pkg/budget"
+	"github.com/kezhenxu94/bmw-saver/pkg/config"
+	"github.com/kezhenxu94/bmw-saver/pkg/schedule"
+)
+
+const (
+	// BudgetConfigMapName is the ConfigMap in the controller namespace holding the savings mode, shared by all shards
+	BudgetConfigMapName = "bmw-saver-budget"
+	// SavingsModeAnnotation is when savings mode ends, in RFC 3339, on the budget ConfigMap
+	SavingsModeAnnotation = "bmw-saver.io/savings-mode-until"
+	// SavingsModeReasonAnnotation is the budget alert that entered savings mode, on the budget ConfigMap
+	SavingsModeReasonAnnotation = "bmw-saver.io/savings-mode-reason"
+	// savingsModeProvider is the provider of the decisions made by savings mode
+	savingsModeProvider = "savings-mode"
+)
+
+// savingsMode is the savings mode entered after a budget alert
+type savingsMode struct {
+	// until is when savings mode ends, and reason the alert that entered it
+	until  time.Time
+	reason string
+	// scaleDownEarlier is how much earlier work time ends, 0 if it ends as scheduled
+	scaleDownEarlier time.Duration
+}
+
+// initBudgetAlerts initializes the budget alerts endpoint based on configuration, the durations are validated when reading it
+func (sc *ScalingController) initBudgetAlerts(cfg config.Config) {
+	sc.budgetAlertsToken, sc.budgetThreshold, sc.savingsModeFor, sc.scaleDownEarlier = "", 0, 0, 0
+	alerts := cfg.BudgetAlerts
+	if alerts == nil {
+		return
+	}
+	sc.budgetAlertsToken = getEnvDefault(alerts.Token, "BUDGET_ALERTS_TOKEN")
+	sc.budgetThreshold = alerts.Threshold
+	sc.savingsModeFor, _ = time.ParseDuration(alerts.Duration)
+	if alerts.ScaleDownEarlier != "" {
+		sc.scaleDownEarlier, _ = time.ParseDuration(alerts.ScaleDownEarlier)
+	}
+}
+
+// savingsMode returns the savings mode the cluster is in, nil if it isn't or budget alerts aren't enabled.
+// Failed checks don't enter savings mode.
+func (sc *ScalingController) savingsMode(ctx context.Context, now time.Time) *savingsMode {
+	if sc.client == nil || sc.savingsModeFor <= 0 {
+		return nil
+	}
+
+	cm, err := sc.client.CoreV1().ConfigMaps(os.Getenv("NAMESPACE")).Get(ctx, BudgetConfigMapName, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		slog.Warn("Failed to check savings mode", "config_map", BudgetConfigMapName, "error", err)
+		return nil
+	}
+
+	value, ok := cm.Annotations[SavingsModeAnnotation]
+	if !ok {
+		return nil
+	}
+	until, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		slog.Warn("Invalid savings mode annotation", "annotation", SavingsModeAnnotation, "value", value)
+		return nil
+	}
+	if !until.After(now) {
+		return nil
+	}
+	return &savingsMode{until: until, reason: cm.Annotations[SavingsModeReasonAnnotation], scaleDownEarlier: sc.scaleDownEarlier}
+}
+
+// endWorkEarlier returns the decision ending work time of the schedule if it ends within the scale down earlier
+// duration, otherwise the decision of the schedule
+func (mode *savingsMode) endWorkEarlier(ctx context.Context, state *scheduleState, now time.Time, decision schedule.Decision) (schedule.Decision, error) {
+	if mode == nil || mode.scaleDownEarlier <= 0 || !decision.IsWorkTime {
+		return decision, nil
+	}
+	later, err := schedule.Explain(ctx, state.scheduler, now.Add(mode.scaleDownEarlier))
+	if err != nil || later.IsWorkTime {
+		return decision, err
+	}
+	return schedule.Decision{
+		Provider: savingsModeProvider,
+		Reason:   fmt.Sprintf("work time ends within %v in savings mode: %s", mode.scaleDownEarlier, mode.reason),
+	}, nil
+}
+
+// nextEarlierEnd returns when work time of the schedule ends earlier in savings mode, zero if it doesn't before the deadline
+func (mode *savingsMode) nextEarlierEnd(ctx context.Context, state *scheduleState, now, deadline time.Time) time.Time {
+	if mode == nil || mode.scaleDownEarlier <= 0 || state.scheduler == nil {
+		return time.Time{}
+	}
+	change, ok, err := schedule.NextChange(ctx, state.scheduler, now.Add(mode.scaleDownEarlier), deadline.Sub(now))
+	if err != nil {
+		slog.Error("Error checking next schedule transition", "error", err)
+		return now.Add(time.Minute)
+	}
+	if !ok || !change.Add(-mode.scaleDownEarlier).After(now) {
+		return time.Time{}
+	}
+	return change.Add(-mode.scaleDownEarlier)
+}
+
+// keptSmall returns the decision keeping a node pool at its savings mode count during work time
+func (mode *savingsMode) keptSmall() schedule.Decision {
+	return schedule.Decision{
+		Provider: savingsModeProvider,
+		Reason:   "work time in savings mode: " + mode.reason,
+	}
+}
+
+// enterSavingsMode enters or extends savings mode for the alert, and returns when it ends
+func (sc *ScalingController) enterSavingsMode(ctx context.Context, now time.Time, alert budget.Alert, duration time.Duration) (time.Time, error) {
+	active := sc.savingsMode(ctx, now)
+	until := now.Add(duration).UTC().Truncate(time.Second)
+	if err := sc.annotateConfigMap(ctx, BudgetConfigMapName, map[string]string{
+		SavingsModeAnnotation:       until.Format(time.RFC3339),
+		SavingsModeReasonAnnotation: alert.String(),
+	}); err != nil {
+		return time.Time{}, err
+	}
+
+	if active != nil {
+		slog.Debug("Savings mode extended", "alert", alert.String(), "until", until)
+		return until, nil
+	}
+	slog.Info("Entering savings mode", "alert", alert.String(), "until", until)
+	sc.recordEvent(corev1.EventTypeWarning, eventReasonSavingsModeEntered,
+		"Entered savings mode until %s: %s", until.Format(time.RFC3339), alert)
+	sc.triggerReconcile()
+	return until, nil
+}
+
+// BudgetAlertsHandler serves the GCP Billing Budgets notifications of a Pub/Sub push subscription, and the
+// AWS Budgets notifications of an SNS HTTPS subscription, whose subscription it confirms. Alerts exceeding
+// a threshold enter savings mode, which is set on the budget ConfigMap so that all shards pick it up.
+// Requests are authenticated with the budget alerts token as bearer token or token query parameter,
+// as neither Pub/Sub nor SNS can set headers.
+func (sc *ScalingController) BudgetAlertsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		sc.mu.RLock()
+		token, threshold, duration := sc.budgetAlertsToken, sc.budgetThreshold, sc.savingsModeFor
+		sc.mu.RUnlock()
+		if token == "" || sc.client == nil {
+			http.Error(w, "budget alerts aren't enabled", http.StatusNotFound)
+			return
+		}
+		provided, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok {
+			provided = r.URL.Query().Get("token")
+		}
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+		if err != nil {
+			http.Error(w, "failed to read request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var alert budget.Alert
+		if r.Header.Get("x-amz-sns-message-type") != "" {
+			message, err := budget.ParseSNS(body)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			switch message.Type {
+			case budget.SNSSubscriptionConfirmation:
+				if err := budget.ConfirmSNS(r.Context(), message); err != nil {
+					slog.Error("Failed to confirm SNS subscription", "topic", message.TopicArn, "error", err)
+					http.Error(w, err.Error(), http.StatusBadGateway)
+					return
+				}
+				slog.Info("Confirmed SNS subscription", "topic", message.TopicArn)
+				w.WriteHeader(http.StatusNoContent)
+				return
+			case budget.SNSNotification:
+				alert = message.Alert()
+			default:
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+		} else if alert, err = budget.ParsePubSub(body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// Pub/Sub redelivers unacknowledged messages, so alerts not entering savings mode are acknowledged as well
+		if !alert.Exceeded || (alert.Source == budget.SourceGCP && alert.Threshold < threshold) {
+			slog.Debug("Budget alert below threshold", "alert", alert.String(), "threshold", threshold)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		if _, err := sc.enterSavingsMode(r.Context(), time.Now(), alert, duration); err != nil {
+			slog.Error("Failed to enter savings mode", "alert", alert.String(), "error", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
@@ -0,0 +1,94 @@
+package controller
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	"github.com/kezhenxu94/bmw-saver/pkg/schedule"
+)
+
+func TestBudgetAlertsHandler(t *testing.T) {
+	sc := &ScalingController{
+		// None of the requests enter savings mode, so they never reach the API server
+		client:            kubernetes.NewForConfigOrDie(&rest.Config{Host: "http://127.0.0.1:1"}),
+		budgetAlertsToken: "secret",
+		budgetThreshold:   0.9,
+		savingsModeFor:    24 * time.Hour,
+	}
+	pubSub := func(data string) string {
+		return `{"message": {"data": "` + base64.StdEncoding.EncodeToString([]byte(data)) + `"}}`
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		header     string
+		body       string
+		wantStatus int
+	}{
+		{name: "Method", method: http.MethodGet, target: "/budget/alerts?token=secret", wantStatus: http.StatusMethodNotAllowed},
+		{name: "No Token", method: http.MethodPost, target: "/budget/alerts", body: pubSub(`{"budgetDisplayName": "team-a"}`), wantStatus: http.StatusUnauthorized},
+		{name: "Wrong Token", method: http.MethodPost, target: "/budget/alerts?token=guess", body: pubSub(`{"budgetDisplayName": "team-a"}`), wantStatus: http.StatusUnauthorized},
+		{name: "Invalid Body", method: http.MethodPost, target: "/budget/alerts?token=secret", body: `{"message": {"data": "!"}}`, wantStatus: http.StatusBadRequest},
+		{name: "Not Exceeded", method: http.MethodPost, target: "/budget/alerts?token=secret", body: pubSub(`{"budgetDisplayName": "team-a", "costAmount": 10}`), wantStatus: http.StatusNoContent},
+		{name: "Below Threshold", method: http.MethodPost, target: "/budget/alerts?token=secret", body: pubSub(`{"budgetDisplayName": "team-a", "alertThresholdExceeded": 0.5}`), wantStatus: http.StatusNoContent},
+		{name: "Unsubscribe", method: http.MethodPost, target: "/budget/alerts?token=secret", header: "UnsubscribeConfirmation", body: `{"Type": "UnsubscribeConfirmation"}`, wantStatus: http.StatusNoContent},
+		{name: "Foreign Subscribe URL", method: http.MethodPost, target: "/budget/alerts?token=secret", header: "SubscriptionConfirmation", body: `{"Type": "SubscriptionConfirmation", "SubscribeURL": "https://example.com/confirm"}`, wantStatus: http.StatusBadGateway},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			if tt.header != "" {
+				req.Header.Set("x-amz-sns-message-type", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			sc.BudgetAlertsHandler().ServeHTTP(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("BudgetAlertsHandler() status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+		})
+	}
+}
+
+func TestSavingsMode_EndWorkEarlier(t *testing.T) {
+	workDays := map[time.Weekday]bool{time.Tuesday: true}
+	state := &scheduleState{scheduler: schedule.NewStaticProvider("09:00", "17:00", "UTC", workDays)}
+	mode := &savingsMode{until: time.Date(2024, time.June, 5, 0, 0, 0, 0, time.UTC), reason: "gcp budget team-a exceeded 90% threshold", scaleDownEarlier: 2 * time.Hour}
+	work := schedule.Decision{IsWorkTime: true, Provider: "static"}
+
+	tests := []struct {
+		name         string
+		mode         *savingsMode
+		now          time.Time
+		wantWorkTime bool
+	}{
+		{name: "Not In Savings Mode", now: time.Date(2024, time.June, 4, 16, 0, 0, 0, time.UTC), wantWorkTime: true},
+		{name: "Before Earlier End", mode: mode, now: time.Date(2024, time.June, 4, 14, 59, 0, 0, time.UTC), wantWorkTime: true},
+		{name: "After Earlier End", mode: mode, now: time.Date(2024, time.June, 4, 15, 0, 0, 0, time.UTC), wantWorkTime: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision, err := tt.mode.endWorkEarlier(context.Background(), state, tt.now, work)
+			if err != nil {
+				t.Fatalf("endWorkEarlier() error = %v", err)
+			}
+			if decision.IsWorkTime != tt.wantWorkTime {
+				t.Errorf("endWorkEarlier() is work time = %v, want %v", decision.IsWorkTime, tt.wantWorkTime)
+			}
+		})
+	}
+
+	now := time.Date(2024, time.June, 4, 12, 0, 0, 0, time.UTC)
+	if got, want := mode.nextEarlierEnd(context.Background(), state, now, now.Add(6*time.Hour)), time.Date(2024, time.June, 4, 15, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("nextEarlierEnd() = %v, want %v", got, want)
+	}
+}
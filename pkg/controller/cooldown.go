@@ -0,0 +1,79 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/kezhenxu94/bmw-saver/pkg/audit"
+	"github.com/kezhenxu94/bmw-saver/pkg/config"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// skippedAction is a scaling action of a node pool that was skipped during its cooldown
+type skippedAction struct {
+	// action is "restore" or the scaled down count, like poolState.applied
+	action string
+	// until is when the cooldown ends, the action is taken then if it is still due
+	until time.Time
+}
+
+// getCooldown returns the cooldown of the node pool, falling back to the configured default
+func (sc *ScalingController) getCooldown(spec config.NodeSpec) time.Duration {
+	cooldown := spec.Cooldown
+	if cooldown == "" {
+		cooldown = sc.config.Cooldown
+	}
+	d, _ := time.ParseDuration(cooldown)
+	return d
+}
+
+// inCooldown returns true if the node pool was scaled too recently to take the action, so that it isn't
+// flipped back and forth while schedule providers flap. The skipped action is kept in the state, and an
+// Event is recorded when it is skipped first.
+func (sc *ScalingController) inCooldown(ctx context.Context, now time.Time, pool *poolState, spec config.NodeSpec, action string) bool {
+	lastScaled := pool.lastScaled
+	cooldown := sc.getCooldown(spec)
+	if pool.applied == action || lastScaled.IsZero() || cooldown <= 0 || now.Sub(lastScaled) >= cooldown {
+		pool.skipped = nil
+		return false
+	}
+
+	skipped := skippedAction{action: action, until: lastScaled.Add(cooldown)}
+	if pool.skipped == nil || *pool.skipped != skipped {
+		slog.Info("Node pool in cooldown, skipping scaling",
+			"node_pool", spec.NodePoolName,
+			"action", actionLabel(action),
+			"last_scaled", lastScaled,
+			"until", skipped.until,
+		)
+		sc.recordEvent(corev1.EventTypeNormal, eventReasonScalingSkipped,
+			"Skipped %s of node pool %s, it was scaled at %s and is in cooldown until %s",
+			actionLabel(action), spec.NodePoolName, lastScaled.Format(time.RFC3339), skipped.until.Format(time.RFC3339))
+		sc.recordAudit(ctx, now, pool, spec, "", action, -1, audit.OutcomeSkipped,
+			fmt.Sprintf("in cooldown until %s", skipped.until.Format(time.RFC3339)))
+		pool.skipped = &skipped
+	}
+	return true
+}
+
+// nextCooldownEnd returns when the earliest cooldown with a skipped action of the schedule ends, zero if none does
+func (state *scheduleState) nextCooldownEnd(now time.Time) time.Time {
+	var next time.Time
+	for _, pool := range state.pools {
+		if pool.skipped != nil && pool.skipped.until.After(now) && (next.IsZero() || pool.skipped.until.Before(next)) {
+			next = pool.skipped.until
+		}
+	}
+	return next
+}
+
+// actionLabel describes an action of poolState.applied
+func actionLabel(action string) string {
+	if action == "restore" {
+		return "restore"
+	}
+	return "scale down to " + action + " nodes"
+}
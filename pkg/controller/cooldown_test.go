@@ -0,0 +1,64 @@
+package controller
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/tools/record"
+
+	"github.com/kezhenxu94/bmw-saver/pkg/config"
+)
+
+func TestInCooldown(t *testing.T) {
+	now := time.Date(2024, time.June, 4, 8, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		cooldown   string
+		applied    string
+		lastScaled time.Time
+		action     string
+		want       bool
+	}{
+		{name: "same action", applied: "restore", lastScaled: now.Add(-5 * time.Minute), action: "restore", want: false},
+		{name: "opposite action within cooldown", applied: "0", lastScaled: now.Add(-5 * time.Minute), action: "restore", want: true},
+		{name: "other count within cooldown", applied: "0", lastScaled: now.Add(-5 * time.Minute), action: "1", want: true},
+		{name: "cooldown expired", applied: "0", lastScaled: now.Add(-30 * time.Minute), action: "restore", want: false},
+		{name: "never scaled", applied: "", action: "0", want: false},
+		{name: "longer per-spec cooldown", cooldown: "1h", applied: "0", lastScaled: now.Add(-40 * time.Minute), action: "restore", want: true},
+		{name: "shorter per-spec cooldown", cooldown: "10m", applied: "0", lastScaled: now.Add(-15 * time.Minute), action: "restore", want: false},
+		{name: "cooldown disabled per spec", cooldown: "0s", applied: "0", lastScaled: now.Add(-5 * time.Minute), action: "restore", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := record.NewFakeRecorder(10)
+			sc := &ScalingController{config: config.Config{Cooldown: "30m"}, events: recorder}
+			spec := config.NodeSpec{NodePoolName: "default-pool", Cooldown: tt.cooldown}
+			pool := &poolState{applied: tt.applied, lastScaled: tt.lastScaled}
+
+			// The skipped action is only recorded once however often it is skipped
+			for i := 0; i < 2; i++ {
+				if got := sc.inCooldown(context.Background(), now.Add(time.Duration(i)*time.Minute), pool, spec, tt.action); got != tt.want {
+					t.Fatalf("inCooldown() = %v, want %v", got, tt.want)
+				}
+			}
+			if !tt.want {
+				if pool.skipped != nil || len(recorder.Events) != 0 {
+					t.Errorf("skipped = %+v with %d Events, want no skipped action", pool.skipped, len(recorder.Events))
+				}
+				return
+			}
+			if pool.skipped == nil || pool.skipped.action != tt.action || !pool.skipped.until.Equal(tt.lastScaled.Add(sc.getCooldown(spec))) {
+				t.Errorf("skipped = %+v, want %s until the end of the cooldown", pool.skipped, tt.action)
+			}
+			if len(recorder.Events) != 1 {
+				t.Fatalf("%d Events recorded, want 1", len(recorder.Events))
+			}
+			if event := <-recorder.Events; !strings.HasPrefix(event, "Normal "+eventReasonScalingSkipped+" Skipped "+actionLabel(tt.action)) {
+				t.Errorf("Event = %q, want a %s Event", event, eventReasonScalingSkipped)
+			}
+		})
+	}
+}
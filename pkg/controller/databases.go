@@ -0,0 +1,130 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/kezhenxu94/bmw-saver/pkg/config"
+	"github.com/kezhenxu94/bmw-saver/pkg/providers"
+	"github.com/kezhenxu94/bmw-saver/pkg/schedule"
+)
+
+const (
+	// defaultDatabaseStartTimeout is how long node pools wait for their databases if not configured
+	defaultDatabaseStartTimeout = 15 * time.Minute
+	// databaseCheckInterval is how often starting databases are checked for their availability
+	databaseCheckInterval = 30 * time.Second
+)
+
+// initDatabases initializes the clients of the databases based on configuration, the timeout is validated when
+// reading it
+func (sc *ScalingController) initDatabases(cfg config.Config, opts initOptions) error {
+	sc.databases = make(map[string]providers.Database)
+	sc.databaseStartTimeout = defaultDatabaseStartTimeout
+	if cfg.DatabaseStartTimeout != "" {
+		sc.databaseStartTimeout, _ = time.ParseDuration(cfg.DatabaseStartTimeout)
+	}
+
+	for _, spec := range cfg.Databases {
+		database, err := providers.NewDatabase(context.Background(), spec.Provider, spec.Name, spec.Project, spec.Region)
+		if err != nil {
+			if opts.logErrors {
+				slog.Error("Failed to create client for database", "database", spec.String(), "error", err)
+				continue
+			}
+			return fmt.Errorf("failed to create client for database %s: %v", spec.String(), err)
+		}
+		sc.databases[spec.String()] = database
+	}
+	return nil
+}
+
+// stopDatabases stops the available databases of a schedule during off time. Databases still starting or
+// stopping are stopped in a later reconciliation.
+func (sc *ScalingController) stopDatabases(ctx context.Context, name string, specs []config.DatabaseSpec, decision schedule.Decision) {
+	for _, spec := range specs {
+		database, ok := sc.databases[spec.String()]
+		if !ok {
+			continue
+		}
+		status, err := database.Status(ctx)
+		if err != nil {
+			slog.Error("Error checking database", "database", spec.String(), "error", err)
+			continue
+		}
+		if status != providers.DatabaseAvailable {
+			continue
+		}
+		if err := database.Stop(ctx); err != nil {
+			slog.Error("Error stopping database", "database", spec.String(), "error", err)
+			sc.recordEvent(corev1.EventTypeWarning, eventReasonScaleDownFailed,
+				"Failed to stop database %s: %v", spec.String(), err)
+			continue
+		}
+		slog.Info("Stopped database", "database", spec.String(), "schedule", scheduleLabel(name))
+		sc.recordEvent(corev1.EventTypeNormal, eventReasonScaledDown,
+			"Stopped database %s, %s", spec.String(), decisionSummary(decision))
+	}
+}
+
+// startDatabases starts the stopped databases of a schedule at work time, and returns whether they are all
+// available, so that the workloads and node pools depending on them are restored. After the start timeout,
+// they are restored anyway.
+func (sc *ScalingController) startDatabases(ctx context.Context, now time.Time, name string, state *scheduleState, specs []config.DatabaseSpec, decision schedule.Decision) bool {
+	available := true
+	for _, spec := range specs {
+		database, ok := sc.databases[spec.String()]
+		if !ok {
+			continue
+		}
+		status, err := database.Status(ctx)
+		if err != nil {
+			slog.Error("Error checking database", "database", spec.String(), "error", err)
+			available = false
+			continue
+		}
+		switch status {
+		case providers.DatabaseAvailable:
+			continue
+		case providers.DatabaseStopped:
+			if err := database.Start(ctx); err != nil {
+				slog.Error("Error starting database", "database", spec.String(), "error", err)
+				sc.recordEvent(corev1.EventTypeWarning, eventReasonRestoreFailed,
+					"Failed to start database %s: %v", spec.String(), err)
+			} else {
+				slog.Info("Started database", "database", spec.String(), "schedule", scheduleLabel(name))
+				sc.recordEvent(corev1.EventTypeNormal, eventReasonRestored,
+					"Started database %s, %s", spec.String(), decisionSummary(decision))
+			}
+		}
+		available = false
+	}
+
+	if available {
+		state.databasesStartingSince = time.Time{}
+		return true
+	}
+	if state.databasesStartingSince.IsZero() {
+		state.databasesStartingSince = now
+	}
+	if waited := now.Sub(state.databasesStartingSince); waited >= sc.databaseStartTimeout {
+		slog.Warn("Databases not available in time, restoring anyway", "schedule", scheduleLabel(name), "waited", waited)
+		return true
+	}
+	slog.Info("Waiting for databases to become available", "schedule", scheduleLabel(name), "since", state.databasesStartingSince)
+	return false
+}
+
+// nextDatabaseCheck returns when the starting databases of the schedule are checked again, zero if none are
+// waited for
+func (sc *ScalingController) nextDatabaseCheck(now time.Time, state *scheduleState) time.Time {
+	since := state.databasesStartingSince
+	if since.IsZero() || now.Sub(since) >= sc.databaseStartTimeout {
+		return time.Time{}
+	}
+	return now.Add(databaseCheckInterval)
+}
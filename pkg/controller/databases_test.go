@@ -0,0 +1,84 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kezhenxu94/bmw-saver/pkg/config"
+	"github.com/kezhenxu94/bmw-saver/pkg/providers"
+	"github.com/kezhenxu94/bmw-saver/pkg/schedule"
+)
+
+// fakeDatabase is a database stopping right away and starting until its status is set
+type fakeDatabase struct {
+	status        providers.DatabaseStatus
+	starts, stops int
+}
+
+func (d *fakeDatabase) Status(ctx context.Context) (providers.DatabaseStatus, error) {
+	return d.status, nil
+}
+
+func (d *fakeDatabase) Stop(ctx context.Context) error {
+	d.stops++
+	d.status = providers.DatabaseStopped
+	return nil
+}
+
+func (d *fakeDatabase) Start(ctx context.Context) error {
+	d.starts++
+	d.status = providers.DatabasePending
+	return nil
+}
+
+func TestStartDatabases(t *testing.T) {
+	now := time.Date(2024, time.June, 4, 9, 0, 0, 0, time.UTC)
+	orders := &fakeDatabase{status: providers.DatabaseAvailable}
+	sc := &ScalingController{
+		databases:            map[string]providers.Database{"rds/orders": orders},
+		databaseStartTimeout: 10 * time.Minute,
+	}
+	specs := []config.DatabaseSpec{{Provider: "rds", Name: "orders"}}
+	state := &scheduleState{}
+	offTime, workTime := schedule.Decision{}, schedule.Decision{IsWorkTime: true}
+
+	sc.stopDatabases(context.Background(), "", specs, offTime)
+	sc.stopDatabases(context.Background(), "", specs, offTime)
+	if orders.stops != 1 || orders.status != providers.DatabaseStopped {
+		t.Fatalf("stops = %d, status = %s, want stopped once", orders.stops, orders.status)
+	}
+
+	if sc.startDatabases(context.Background(), now, "", state, specs, workTime) {
+		t.Errorf("startDatabases() = true, want waiting for the started database")
+	}
+	if orders.starts != 1 {
+		t.Errorf("starts = %d, want 1", orders.starts)
+	}
+	if got := sc.nextDatabaseCheck(now, state); !got.Equal(now.Add(databaseCheckInterval)) {
+		t.Errorf("nextDatabaseCheck() = %v, want in %v", got, databaseCheckInterval)
+	}
+	if sc.startDatabases(context.Background(), now.Add(time.Minute), "", state, specs, workTime) {
+		t.Errorf("startDatabases() = true, want still waiting")
+	}
+	if orders.starts != 1 {
+		t.Errorf("starts = %d, want the starting database not started again", orders.starts)
+	}
+
+	// Node pools are restored anyway after the timeout
+	later := now.Add(10 * time.Minute)
+	if !sc.startDatabases(context.Background(), later, "", state, specs, workTime) {
+		t.Errorf("startDatabases() = false, want restoring after the timeout")
+	}
+	if got := sc.nextDatabaseCheck(later, state); !got.IsZero() {
+		t.Errorf("nextDatabaseCheck() = %v after the timeout, want zero", got)
+	}
+
+	orders.status = providers.DatabaseAvailable
+	if !sc.startDatabases(context.Background(), later, "", state, specs, workTime) {
+		t.Errorf("startDatabases() = false, want the available database used")
+	}
+	if !state.databasesStartingSince.IsZero() {
+		t.Errorf("databasesStartingSince = %v, want reset once available", state.databasesStartingSince)
+	}
+}
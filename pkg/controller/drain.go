@@ -0,0 +1,179 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/kezhenxu94/bmw-saver/pkg/config"
+	pkgk8s "github.com/kezhenxu94/bmw-saver/pkg/kubernetes"
+)
+
+// initDrain initializes the settings for draining nodes based on configuration, the timeout is validated when
+// reading it
+func (sc *ScalingController) initDrain(cfg config.Config) {
+	sc.prepareToSleepTimeout = 0
+	if cfg.PrepareToSleep != nil {
+		sc.prepareToSleepTimeout, _ = time.ParseDuration(cfg.PrepareToSleep.Timeout)
+	}
+}
+
+// drainOptions returns the options for draining the nodes of the node pool, its drain durations are validated when
+// reading the configuration
+func (sc *ScalingController) drainOptions(spec config.NodeSpec) pkgk8s.DrainOptions {
+	opts := pkgk8s.DrainOptions{
+		IgnorePodDisruptionBudgets: spec.IgnorePodDisruptionBudgets,
+		PrepareToSleepTimeout:      sc.prepareToSleepTimeout,
+	}
+	if drain := spec.Drain; drain != nil {
+		opts.GracePeriod, _ = time.ParseDuration(drain.GracePeriod)
+		opts.Timeout, _ = time.ParseDuration(drain.Timeout)
+		opts.Force = drain.Force
+		opts.ProtectEmptyDir = drain.ProtectEmptyDir
+		opts.TerminationTimeout, _ = time.ParseDuration(drain.TerminationTimeout)
+		opts.Parallelism = drain.Parallelism
+		opts.NodeSelection = drain.NodeSelection
+	}
+	opts.Progress = func(progress pkgk8s.DrainProgress) { sc.reportDrainProgress(spec, progress) }
+	return opts
+}
+
+// releaseNodes uncordons and untaints the nodes of the restored node pool that were tainted as shutting down but
+// survived its scale down, e.g. as it was restored before the cloud provider removed them, so that they don't stay
+// unschedulable
+func (sc *ScalingController) releaseNodes(ctx context.Context, spec config.NodeSpec) {
+	if sc.client == nil || sc.nodes == nil {
+		return
+	}
+	nodes, err := sc.nodes.NodePoolNodes(ctx, spec.CloudProvider, spec.NodePoolName)
+	if err != nil {
+		slog.Warn("Failed to list nodes of restored node pool", "node_pool", spec.NodePoolName, "error", err)
+		return
+	}
+	var shuttingDown []corev1.Node
+	for i := range nodes {
+		if pkgk8s.HasShuttingDownTaint(&nodes[i]) {
+			shuttingDown = append(shuttingDown, nodes[i])
+		}
+	}
+	if len(shuttingDown) == 0 {
+		return
+	}
+	slog.Info("Releasing nodes of restored node pool tainted as shutting down", "node_pool", spec.NodePoolName, "nodes", len(shuttingDown))
+	pkgk8s.ReleaseNodes(ctx, sc.client, shuttingDown)
+}
+
+// drainDurationBuckets are the upper bounds of the buckets of the drain duration histogram, in seconds
+var drainDurationBuckets = []float64{10, 30, 60, 120, 300, 600, 1800}
+
+// drainStats are the pods evicted and blocked when draining the nodes of a node pool, and the histogram of the
+// durations of the drains
+type drainStats struct {
+	evicted int
+	blocked int
+	// buckets counts the drains at most as long as the bucket bound, count all of them and sum their seconds
+	buckets []int
+	count   int
+	sum     float64
+}
+
+// reportDrainProgress records an Event with the progress of draining a node of the node pool, while its evictions
+// are retried and once it's drained, and accumulates the drain statistics of the node pool once it's drained
+func (sc *ScalingController) reportDrainProgress(spec config.NodeSpec, progress pkgk8s.DrainProgress) {
+	duration := progress.Duration.Round(time.Second)
+	switch {
+	case !progress.Done:
+		sc.recordEvent(corev1.EventTypeWarning, eventReasonDrainBlocked,
+			"Draining node %s of node pool %s retries evictions of %d pods after %d pods evicted: %s",
+			progress.Node, spec.NodePoolName, len(progress.Blocked), progress.Evicted, strings.Join(progress.Blocked, ", "))
+		return
+	case progress.Err != nil:
+		sc.recordEvent(corev1.EventTypeWarning, eventReasonDrainFailed,
+			"Failed to drain node %s of node pool %s after %s, %d pods evicted: %v",
+			progress.Node, spec.NodePoolName, duration, progress.Evicted, progress.Err)
+	default:
+		sc.recordEvent(corev1.EventTypeNormal, eventReasonNodeDrained,
+			"Drained node %s of node pool %s in %s, %d pods evicted", progress.Node, spec.NodePoolName, duration, progress.Evicted)
+	}
+
+	sc.metricsMu.Lock()
+	defer sc.metricsMu.Unlock()
+	if sc.drainStats == nil {
+		sc.drainStats = make(map[string]*drainStats)
+	}
+	stats := sc.drainStats[spec.NodePoolName]
+	if stats == nil {
+		stats = &drainStats{buckets: make([]int, len(drainDurationBuckets))}
+		sc.drainStats[spec.NodePoolName] = stats
+	}
+	stats.evicted += progress.Evicted
+	stats.blocked += len(progress.Blocked)
+	seconds := progress.Duration.Seconds()
+	for i, bound := range drainDurationBuckets {
+		if seconds <= bound {
+			stats.buckets[i]++
+		}
+	}
+	stats.count++
+	stats.sum += seconds
+}
+
+// writeDrainMetrics writes the pods evicted and blocked and the drain durations of the nodes of the node pools in
+// the Prometheus text format
+func (sc *ScalingController) writeDrainMetrics(w io.Writer) error {
+	sc.metricsMu.Lock()
+	defer sc.metricsMu.Unlock()
+	if len(sc.drainStats) == 0 {
+		return nil
+	}
+
+	pools := make([]string, 0, len(sc.drainStats))
+	for pool := range sc.drainStats {
+		pools = append(pools, pool)
+	}
+	sort.Strings(pools)
+
+	counters := []struct {
+		name, help string
+		value      func(*drainStats) int
+	}{
+		{"bmw_saver_drain_evicted_pods_total", "Pods evicted or deleted when draining the nodes of the node pool.",
+			func(stats *drainStats) int { return stats.evicted }},
+		{"bmw_saver_drain_blocked_pods_total", "Pods whose eviction was still blocked or failing when draining a node of the node pool ended.",
+			func(stats *drainStats) int { return stats.blocked }},
+	}
+	for _, counter := range counters {
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", counter.name, counter.help, counter.name); err != nil {
+			return err
+		}
+		for _, pool := range pools {
+			if _, err := fmt.Fprintf(w, "%s{node_pool=%q} %d\n", counter.name, pool, counter.value(sc.drainStats[pool])); err != nil {
+				return err
+			}
+		}
+	}
+
+	const name = "bmw_saver_node_drain_duration_seconds"
+	if _, err := fmt.Fprintf(w, "# HELP %s Seconds draining a node of the node pool took.\n# TYPE %s histogram\n", name, name); err != nil {
+		return err
+	}
+	for _, pool := range pools {
+		stats := sc.drainStats[pool]
+		for i, bound := range drainDurationBuckets {
+			if _, err := fmt.Fprintf(w, "%s_bucket{node_pool=%q,le=\"%g\"} %d\n", name, pool, bound, stats.buckets[i]); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintf(w, "%s_bucket{node_pool=%q,le=\"+Inf\"} %d\n%s_sum{node_pool=%q} %g\n%s_count{node_pool=%q} %d\n",
+			name, pool, stats.count, name, pool, stats.sum, name, pool, stats.count); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/kezhenxu94/bmw-saver/pkg/config"
+	pkgk8s "github.com/kezhenxu94/bmw-saver/pkg/kubernetes"
+)
+
+func TestReleaseNodes(t *testing.T) {
+	node := func(name, pool string, taints ...corev1.Taint) *corev1.Node {
+		return &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"cloud.google.com/gke-nodepool": pool}},
+			Spec:       corev1.NodeSpec{Unschedulable: true, Taints: taints},
+		}
+	}
+	shuttingDown := corev1.Taint{Key: pkgk8s.ShuttingDownTaint, Effect: corev1.TaintEffectNoSchedule}
+	client := fake.NewSimpleClientset(
+		node("survivor", "default-pool", shuttingDown),
+		node("maintenance", "default-pool"),
+		node("other", "other-pool", shuttingDown),
+	)
+	sc := &ScalingController{client: client, nodes: pkgk8s.NewNodeLister(client)}
+	ctx := context.Background()
+
+	sc.releaseNodes(ctx, config.NodeSpec{NodePoolName: "default-pool", CloudProvider: "gke"})
+	for name, want := range map[string]bool{"survivor": false, "maintenance": true, "other": true} {
+		got, err := client.CoreV1().Nodes().Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("failed to get node %s: %v", name, err)
+		}
+		if got.Spec.Unschedulable != want {
+			t.Errorf("node %s unschedulable = %v, want %v", name, got.Spec.Unschedulable, want)
+		}
+		if tainted := name == "other"; pkgk8s.HasShuttingDownTaint(got) != tainted {
+			t.Errorf("node %s has taints %v, want tainted as shutting down %v", name, got.Spec.Taints, tainted)
+		}
+	}
+}
+
+func TestReportDrainProgress(t *testing.T) {
+	sc := &ScalingController{}
+	spec := config.NodeSpec{NodePoolName: "default-pool"}
+
+	sc.reportDrainProgress(spec, pkgk8s.DrainProgress{Node: "node-1", Evicted: 1, Blocked: []string{"team-a/db-0"}, Duration: 5 * time.Second})
+	var metrics strings.Builder
+	if err := sc.writeDrainMetrics(&metrics); err != nil || metrics.Len() != 0 {
+		t.Fatalf("writeDrainMetrics() = %q, %v while draining, want none", metrics.String(), err)
+	}
+
+	sc.reportDrainProgress(spec, pkgk8s.DrainProgress{Node: "node-1", Evicted: 3, Duration: 45 * time.Second, Done: true})
+	sc.reportDrainProgress(spec, pkgk8s.DrainProgress{Node: "node-2", Evicted: 2, Blocked: []string{"team-a/db-0"},
+		Duration: 5 * time.Minute, Done: true, Err: errors.New("eviction of pods blocked by PodDisruptionBudgets: team-a/db-0")})
+	if err := sc.writeDrainMetrics(&metrics); err != nil {
+		t.Fatalf("writeDrainMetrics() error = %v", err)
+	}
+	for _, want := range []string{
+		`bmw_saver_drain_evicted_pods_total{node_pool="default-pool"} 5`,
+		`bmw_saver_drain_blocked_pods_total{node_pool="default-pool"} 1`,
+		`bmw_saver_node_drain_duration_seconds_bucket{node_pool="default-pool",le="30"} 0`,
+		`bmw_saver_node_drain_duration_seconds_bucket{node_pool="default-pool",le="60"} 1`,
+		`bmw_saver_node_drain_duration_seconds_bucket{node_pool="default-pool",le="300"} 2`,
+		`bmw_saver_node_drain_duration_seconds_bucket{node_pool="default-pool",le="+Inf"} 2`,
+		`bmw_saver_node_drain_duration_seconds_sum{node_pool="default-pool"} 345`,
+		`bmw_saver_node_drain_duration_seconds_count{node_pool="default-pool"} 2`,
+	} {
+		if !strings.Contains(metrics.String(), want+"\n") {
+			t.Errorf("metrics = %s, want %s", metrics.String(), want)
+		}
+	}
+}
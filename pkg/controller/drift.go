@@ -0,0 +1,92 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/kezhenxu94/bmw-saver/pkg/audit"
+	"github.com/kezhenxu94/bmw-saver/pkg/config"
+	"github.com/kezhenxu94/bmw-saver/pkg/notify"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// driftState is a resize of a scaled down node pool outside of bmw-saver
+type driftState struct {
+	// action is the scaled down count the node pool drifted from, like poolState.applied
+	action string
+	// count is the node count the node pool was resized to, and since when the drift was detected
+	count int
+	since time.Time
+}
+
+// getDriftPolicy returns the drift policy of the node pool, falling back to the configured default
+func (sc *ScalingController) getDriftPolicy(spec config.NodeSpec) string {
+	switch {
+	case spec.DriftPolicy != "":
+		return spec.DriftPolicy
+	case sc.config.DriftPolicy != "":
+		return sc.config.DriftPolicy
+	default:
+		return config.DriftPolicyEnforce
+	}
+}
+
+// observeNodes records the node count of the node pool scaled down by action, and returns true if it drifted,
+// i.e. the node pool reached the scaled down count before and was resized since. Until it reached the count
+// it is still being scaled down.
+func (pool *poolState) observeNodes(action string, count int32, nodes int) bool {
+	if nodes == int(count) {
+		pool.converged = action
+		return false
+	}
+	return pool.converged == action
+}
+
+// drifted returns true if the scaled down node pool was resized outside of bmw-saver and is left alone until
+// the next transition, according to its drift policy. With the enforce policy drifts aren't checked, the node
+// pool is scaled back to its count like in every reconciliation.
+func (sc *ScalingController) drifted(ctx context.Context, now time.Time, pool *poolState, spec config.NodeSpec,
+	notification notify.Notification, action string, count int32) bool {
+	if pool.drift != nil && pool.drift.action != action {
+		pool.drift = nil
+	}
+	policy := sc.getDriftPolicy(spec)
+	if policy == config.DriftPolicyEnforce || pool.applied != action || sc.nodes == nil {
+		return false
+	}
+	if pool.drift != nil {
+		return true
+	}
+
+	nodes, err := sc.nodes.NodePoolNodes(ctx, spec.CloudProvider, spec.NodePoolName)
+	if err != nil {
+		slog.Warn("Failed to count nodes of node pool, not checking drift", "node_pool", spec.NodePoolName, "error", err)
+		return false
+	}
+	if !pool.observeNodes(action, count, len(nodes)) {
+		return false
+	}
+
+	pool.drift = &driftState{action: action, count: len(nodes), since: now}
+	slog.Warn("Node pool resized outside of bmw-saver, leaving it until the next transition",
+		"node_pool", spec.NodePoolName,
+		"count", count,
+		"actual_count", len(nodes),
+		"drift_policy", policy,
+	)
+	detail := fmt.Sprintf("resized to %d nodes outside of bmw-saver", len(nodes))
+	sc.recordEvent(corev1.EventTypeWarning, eventReasonDriftDetected,
+		"Node pool %s was %s, leaving it until the next transition", spec.NodePoolName, detail)
+	sc.recordAudit(ctx, now, pool, spec, notification.Reason, action, len(nodes), audit.OutcomeDrift, detail)
+	// The resized nodes aren't saved anymore
+	sc.savings.ScaledDown(spec.NodePoolName, int(count)-len(nodes), spec.HourlyNodeCost, sc.hourlyNodeCO2(spec), now)
+	if policy == config.DriftPolicyAlertOnly {
+		notification.Kind = notify.KindDrift
+		notification.Count = int32(len(nodes))
+		sc.notifier.Notify(ctx, notification)
+	}
+	return true
+}
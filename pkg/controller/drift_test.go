@@ -0,0 +1,35 @@
+package controller
+
+import "testing"
+
+func TestObserveNodes(t *testing.T) {
+	tests := []struct {
+		name  string
+		nodes []int
+		want  bool
+	}{
+		{name: "still scaling down", nodes: []int{3, 2}, want: false},
+		{name: "scaled down", nodes: []int{3, 1}, want: false},
+		{name: "resized after scaling down", nodes: []int{3, 1, 4}, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool := &poolState{}
+			var got bool
+			for _, nodes := range tt.nodes {
+				got = pool.observeNodes("1", 1, nodes)
+			}
+			if got != tt.want {
+				t.Errorf("observeNodes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// A different scaled down count has to be reached again
+	pool := &poolState{}
+	pool.observeNodes("1", 1, 1)
+	if pool.observeNodes("0", 0, 1) {
+		t.Errorf("observeNodes() = true for a new action, want false")
+	}
+}
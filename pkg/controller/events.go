@@ -0,0 +1,91 @@
+package controller
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/kezhenxu94/bmw-saver/pkg/schedule"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+// Reasons of the Kubernetes Events recorded for node pools
+const (
+	eventReasonScaledDown               = "ScaledDown"
+	eventReasonScaleDownFailed          = "ScaleDownFailed"
+	eventReasonScaleDownBlocked         = "ScaleDownBlocked"
+	eventReasonScaleDownDeferred        = "ScaleDownDeferred"
+	eventReasonRestored                 = "Restored"
+	eventReasonRestoreFailed            = "RestoreFailed"
+	eventReasonScalingSkipped           = "ScalingSkipped"
+	eventReasonDriftDetected            = "DriftDetected"
+	eventReasonScalingSuspended         = "ScalingSuspended"
+	eventReasonRestoreVerified          = "RestoreVerified"
+	eventReasonRestoreNotReady          = "RestoreNotReady"
+	eventReasonScaleDownPendingApproval = "ScaleDownPendingApproval"
+	eventReasonScaleDownApproved        = "ScaleDownApproved"
+	eventReasonSavingsModeEntered       = "SavingsModeEntered"
+	eventReasonRightSizingRecommended   = "RightSizingRecommended"
+	eventReasonGitOpsSuspended          = "GitOpsSuspended"
+	eventReasonGitOpsResumed            = "GitOpsResumed"
+	eventReasonSleepPageShown           = "SleepPageShown"
+	eventReasonSleepPageHidden          = "SleepPageHidden"
+	eventReasonRebalanced               = "Rebalanced"
+	eventReasonStaleStatePending        = "StaleStatePendingConfirmation"
+	eventReasonSavedStateRemoved        = "SavedStateRemoved"
+	eventReasonNodeDrained              = "NodeDrained"
+	eventReasonDrainBlocked             = "DrainBlocked"
+	eventReasonDrainFailed              = "DrainFailed"
+)
+
+// eventConfigMapName is the ConfigMap of the controller configuration, the Events are recorded on it
+const eventConfigMapName = "bmw-saver-config"
+
+// newEventRecorder creates a recorder of Events in the controller namespace, so that
+// `kubectl get events` shows what the controller did and why
+func newEventRecorder(client kubernetes.Interface) (record.EventRecorder, record.EventBroadcaster, *corev1.ObjectReference) {
+	namespace := os.Getenv("NAMESPACE")
+	if namespace == "" {
+		namespace = corev1.NamespaceDefault
+	}
+
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: client.CoreV1().Events(namespace)})
+	recorder := broadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: "bmw-saver"})
+
+	ref := &corev1.ObjectReference{
+		APIVersion: "v1",
+		Kind:       "ConfigMap",
+		Namespace:  namespace,
+		Name:       eventConfigMapName,
+	}
+	return recorder, broadcaster, ref
+}
+
+// recordEvent records an Event, if an event recorder is set up
+func (sc *ScalingController) recordEvent(eventType, reason, message string, args ...interface{}) {
+	if sc.events == nil {
+		return
+	}
+	sc.events.Eventf(sc.eventRef, eventType, reason, message, args...)
+}
+
+// decisionSummary describes why the decision was made, e.g. "off time: outside Tuesday work hours 09:00-18:00"
+func decisionSummary(decision schedule.Decision) string {
+	kind := "off time"
+	if decision.IsWorkTime {
+		kind = "work time"
+	}
+	switch {
+	case decision.Detail != "":
+		return fmt.Sprintf("%s: %s", kind, decision.Detail)
+	case decision.Reason != "":
+		return fmt.Sprintf("%s: %s", kind, decision.Reason)
+	default:
+		return fmt.Sprintf("%s by %s", kind, decision.Provider)
+	}
+}
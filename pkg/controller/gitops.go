@@ -0,0 +1,76 @@
+package controller
+
+import (
+	"context"
+	"log/slog"
+	"os"
+
+	corev1 "k8s.io/api/core/v1"
+
+	pkgk8s "github.com/kezhenxu94/bmw-saver/pkg/kubernetes"
+	"github.com/kezhenxu94/bmw-saver/pkg/schedule"
+)
+
+// suspendGitOps turns off the automated sync of the ArgoCD Applications of the workloads and suspends their Flux
+// resources during off time
+func (sc *ScalingController) suspendGitOps(ctx context.Context, name string, workloads workloadSet, decision schedule.Decision) {
+	namespace := os.Getenv("NAMESPACE")
+	for _, app := range workloads.argoCDApplications {
+		resource := app.Namespace + "/" + app.Name
+		suspended, err := pkgk8s.SuspendApplication(ctx, sc.client, namespace, app.Namespace, app.Name)
+		if err != nil {
+			slog.Error("Error suspending ArgoCD application", "application", resource, "error", err)
+			sc.recordEvent(corev1.EventTypeWarning, eventReasonScaleDownFailed,
+				"Failed to turn off automated sync of ArgoCD application %s: %v", resource, err)
+		} else if suspended {
+			slog.Info("Turned off automated sync of ArgoCD application", "application", resource, "schedule", scheduleLabel(name))
+			sc.recordEvent(corev1.EventTypeNormal, eventReasonGitOpsSuspended,
+				"Turned off automated sync of ArgoCD application %s, %s", resource, decisionSummary(decision))
+		}
+	}
+	for _, flux := range workloads.fluxResources {
+		resource := flux.Namespace + "/" + flux.Name
+		suspended, err := pkgk8s.SuspendFluxResource(ctx, sc.client, namespace, flux.Kind, flux.Namespace, flux.Name)
+		if err != nil {
+			slog.Error("Error suspending Flux resource", "kind", flux.Kind, "resource", resource, "error", err)
+			sc.recordEvent(corev1.EventTypeWarning, eventReasonScaleDownFailed,
+				"Failed to suspend Flux %s %s: %v", flux.Kind, resource, err)
+		} else if suspended {
+			slog.Info("Suspended Flux resource", "kind", flux.Kind, "resource", resource, "schedule", scheduleLabel(name))
+			sc.recordEvent(corev1.EventTypeNormal, eventReasonGitOpsSuspended,
+				"Suspended Flux %s %s, %s", flux.Kind, resource, decisionSummary(decision))
+		}
+	}
+}
+
+// resumeGitOps restores the automated sync of the ArgoCD Applications of the workloads and resumes their Flux
+// resources at work time
+func (sc *ScalingController) resumeGitOps(ctx context.Context, name string, workloads workloadSet, decision schedule.Decision) {
+	namespace := os.Getenv("NAMESPACE")
+	for _, app := range workloads.argoCDApplications {
+		resource := app.Namespace + "/" + app.Name
+		resumed, err := pkgk8s.ResumeApplication(ctx, sc.client, namespace, app.Namespace, app.Name)
+		if err != nil {
+			slog.Error("Error resuming ArgoCD application", "application", resource, "error", err)
+			sc.recordEvent(corev1.EventTypeWarning, eventReasonRestoreFailed,
+				"Failed to restore automated sync of ArgoCD application %s: %v", resource, err)
+		} else if resumed {
+			slog.Info("Restored automated sync of ArgoCD application", "application", resource, "schedule", scheduleLabel(name))
+			sc.recordEvent(corev1.EventTypeNormal, eventReasonGitOpsResumed,
+				"Restored automated sync of ArgoCD application %s, %s", resource, decisionSummary(decision))
+		}
+	}
+	for _, flux := range workloads.fluxResources {
+		resource := flux.Namespace + "/" + flux.Name
+		resumed, err := pkgk8s.ResumeFluxResource(ctx, sc.client, namespace, flux.Kind, flux.Namespace, flux.Name)
+		if err != nil {
+			slog.Error("Error resuming Flux resource", "kind", flux.Kind, "resource", resource, "error", err)
+			sc.recordEvent(corev1.EventTypeWarning, eventReasonRestoreFailed,
+				"Failed to resume Flux %s %s: %v", flux.Kind, resource, err)
+		} else if resumed {
+			slog.Info("Resumed Flux resource", "kind", flux.Kind, "resource", resource, "schedule", scheduleLabel(name))
+			sc.recordEvent(corev1.EventTypeNormal, eventReasonGitOpsResumed,
+				"Resumed Flux %s %s, %s", flux.Kind, resource, decisionSummary(decision))
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package controller
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/kezhenxu94/bmw-saver/pkg/ci"
+	"github.com/kezhenxu94/bmw-saver/pkg/config"
+	pkgk8s "github.com/kezhenxu94/bmw-saver/pkg/kubernetes"
+	"github.com/kezhenxu94/bmw-saver/pkg/notify"
+)
+
+// scaleDownGuard defers scaling down node pools while workloads are active on their nodes,
+// or CI jobs are in flight for their runners
+type scaleDownGuard struct {
+	// maxDeferral is how long a scale down may be deferred
+	maxDeferral time.Duration
+	options     pkgk8s.WorkloadOptions
+	// pipelines maps node pools to the CI systems whose jobs run on them
+	pipelines map[string][]ci.Source
+	// gpuResourceNames are the GPU resources of GPU workloads, none if GPU workloads aren't checked, and
+	// gpuMaxDeferral how long a scale down may be deferred for them
+	gpuResourceNames []string
+	gpuMaxDeferral   time.Duration
+	// batchFrameworks are the frameworks whose running batch work defers scale downs
+	batchFrameworks []string
+}
+
+// initScaleDownGuard initializes the scale down guard based on configuration, the durations are validated when reading it
+func (sc *ScalingController) initScaleDownGuard(cfg config.Config) {
+	sc.scaleDownGuard = nil
+	if cfg.ScaleDownGuard == nil {
+		return
+	}
+
+	recentPodAge, _ := time.ParseDuration(cfg.ScaleDownGuard.RecentPodAge)
+	maxDeferral, _ := time.ParseDuration(cfg.ScaleDownGuard.MaxDeferral)
+	sc.scaleDownGuard = &scaleDownGuard{
+		maxDeferral: maxDeferral,
+		options: pkgk8s.WorkloadOptions{
+			RecentPodAge:       recentPodAge,
+			ExcludedNamespaces: toSet(cfg.ScaleDownGuard.ExcludedNamespaces),
+		},
+		pipelines:       make(map[string][]ci.Source),
+		batchFrameworks: cfg.ScaleDownGuard.BatchFrameworks,
+	}
+	if gpu := cfg.ScaleDownGuard.GPU; gpu != nil {
+		sc.scaleDownGuard.gpuResourceNames = gpu.ResourceNames
+		sc.scaleDownGuard.gpuMaxDeferral, _ = time.ParseDuration(gpu.MaxDeferral)
+	}
+
+	for _, pipelines := range cfg.ScaleDownGuard.CIPipelines {
+		sources := ciSources(pipelines)
+		for _, nodePool := range pipelines.NodePools {
+			sc.scaleDownGuard.pipelines[nodePool] = append(sc.scaleDownGuard.pipelines[nodePool], sources...)
+		}
+	}
+}
+
+// ciSources creates the CI systems of the configuration, the configuration is validated when reading it
+func ciSources(cfg config.CIPipelinesConfig) []ci.Source {
+	var sources []ci.Source
+	if github := cfg.GitHub; github != nil {
+		source, err := ci.NewGitHubActions(github.APIURL, getEnvDefault(github.Token, "GITHUB_TOKEN"), github.Repositories, github.Labels)
+		if err != nil {
+			slog.Error("Failed to create CI system", "ci", "GitHub Actions", "error", err)
+		} else {
+			sources = append(sources, source)
+		}
+	}
+	if gitlab := cfg.GitLab; gitlab != nil {
+		source, err := ci.NewGitLabCI(gitlab.URL, getEnvDefault(gitlab.Token, "GITLAB_TOKEN"), gitlab.Projects, gitlab.Tags)
+		if err != nil {
+			slog.Error("Failed to create CI system", "ci", "GitLab CI", "error", err)
+		} else {
+			sources = append(sources, source)
+		}
+	}
+	if jenkins := cfg.Jenkins; jenkins != nil {
+		idleFor, _ := time.ParseDuration(jenkins.IdleFor)
+		source, err := ci.NewJenkins(jenkins.URL, getEnvDefault(jenkins.User, "JENKINS_USER"), os.Getenv("JENKINS_API_TOKEN"), jenkins.Labels, idleFor)
+		if err != nil {
+			slog.Error("Failed to create CI system", "ci", "Jenkins", "error", err)
+		} else {
+			sources = append(sources, source)
+		}
+	}
+	return sources
+}
+
+// deferScaleDown returns the active workloads and running batch work on the nodes of the node pool and the CI jobs
+// in flight for its runners, for which its scale down is deferred. The deferral starts when active workloads are
+// found first, once it took longer than the max deferral the node pool is scaled down anyway. Failed checks don't
+// defer the scale down.
+func (sc *ScalingController) deferScaleDown(ctx context.Context, now time.Time, pool *poolState, spec config.NodeSpec) []string {
+	if sc.scaleDownGuard == nil {
+		return nil
+	}
+
+	deferredSince, deferred := pool.deferredSince, !pool.deferredSince.IsZero()
+	if deferred && now.Sub(deferredSince) >= sc.scaleDownGuard.maxDeferral {
+		slog.Warn("Scale down deferred for too long, scaling down despite active workloads",
+			"node_pool", spec.NodePoolName,
+			"deferred_since", deferredSince,
+			"max_deferral", sc.scaleDownGuard.maxDeferral,
+		)
+		return nil
+	}
+
+	var active []string
+	if sc.client != nil {
+		options := sc.scaleDownGuard.options
+		options.Now = now
+		workloads, err := pkgk8s.ActiveWorkloads(ctx, sc.client, spec.CloudProvider, spec.NodePoolName, options)
+		if err != nil {
+			slog.Warn("Failed to check active workloads", "node_pool", spec.NodePoolName, "error", err)
+		}
+		active = workloads
+		if len(sc.scaleDownGuard.batchFrameworks) > 0 {
+			work, err := pkgk8s.RunningBatchWork(ctx, sc.client, spec.CloudProvider, spec.NodePoolName, sc.scaleDownGuard.batchFrameworks)
+			if err != nil {
+				slog.Warn("Failed to check running batch work", "node_pool", spec.NodePoolName, "error", err)
+			}
+			active = append(active, work...)
+		}
+	}
+	for _, source := range sc.scaleDownGuard.pipelines[spec.NodePoolName] {
+		jobs, err := source.InFlight(ctx)
+		if err != nil {
+			slog.Warn("Failed to check CI jobs in flight", "node_pool", spec.NodePoolName, "ci", source, "error", err)
+			continue
+		}
+		active = append(active, jobs...)
+	}
+	if len(active) == 0 {
+		return nil
+	}
+
+	if !deferred {
+		pool.deferredSince = now
+	}
+	return active
+}
+
+// deferForGPUWorkloads returns the GPU workloads running on the nodes of the node pool, for which its scale down is
+// deferred, e.g. training runs that are far more expensive to restart than the node hours saved. A notification
+// is sent when the deferral starts, once it took longer than the GPU max deferral the node pool is scaled down
+// anyway. Failed checks don't defer the scale down.
+func (sc *ScalingController) deferForGPUWorkloads(ctx context.Context, now time.Time, pool *poolState, spec config.NodeSpec, notification notify.Notification) []string {
+	if sc.scaleDownGuard == nil || len(sc.scaleDownGuard.gpuResourceNames) == 0 || sc.client == nil {
+		return nil
+	}
+
+	deferredSince, deferred := pool.gpuDeferredSince, !pool.gpuDeferredSince.IsZero()
+	if deferred && now.Sub(deferredSince) >= sc.scaleDownGuard.gpuMaxDeferral {
+		slog.Warn("Scale down deferred for too long, scaling down despite GPU workloads",
+			"node_pool", spec.NodePoolName,
+			"deferred_since", deferredSince,
+			"max_deferral", sc.scaleDownGuard.gpuMaxDeferral,
+		)
+		return nil
+	}
+
+	workloads, err := pkgk8s.GPUWorkloads(ctx, sc.client, spec.CloudProvider, spec.NodePoolName, sc.scaleDownGuard.gpuResourceNames)
+	if err != nil {
+		slog.Warn("Failed to check GPU workloads", "node_pool", spec.NodePoolName, "error", err)
+		return nil
+	}
+	if len(workloads) == 0 {
+		return nil
+	}
+
+	if !deferred {
+		pool.gpuDeferredSince = now
+		until := now.Add(sc.scaleDownGuard.gpuMaxDeferral)
+		notification.Kind = notify.KindDeferred
+		notification.Workloads, notification.DeferredUntil = workloads, &until
+		sc.notifier.Notify(ctx, notification)
+	}
+	return workloads
+}
+
+// nextDeferralEnd returns when the earliest deferred scale down of the schedule is due, zero if none is
+func (sc *ScalingController) nextDeferralEnd(now time.Time, state *scheduleState) time.Time {
+	var next time.Time
+	if sc.scaleDownGuard == nil {
+		return next
+	}
+	for _, pool := range state.pools {
+		for _, end := range []time.Time{
+			deferralEnd(pool.deferredSince, sc.scaleDownGuard.maxDeferral),
+			deferralEnd(pool.gpuDeferredSince, sc.scaleDownGuard.gpuMaxDeferral),
+		} {
+			if end.After(now) && (next.IsZero() || end.Before(next)) {
+				next = end
+			}
+		}
+	}
+	return next
+}
+
+// deferralEnd returns when a deferral since the time ends, zero if there is none
+func deferralEnd(since time.Time, maxDeferral time.Duration) time.Time {
+	if since.IsZero() {
+		return time.Time{}
+	}
+	return since.Add(maxDeferral)
+}
+
+// blockingDisruptionBudgets returns the PodDisruptionBudgets that block scaling down the node pool, none if they
+// are ignored for it. Failed checks don't block the scale down, the budgets are still respected when draining nodes.
+func (sc *ScalingController) blockingDisruptionBudgets(ctx context.Context, spec config.NodeSpec) []string {
+	if spec.IgnorePodDisruptionBudgets || sc.client == nil {
+		return nil
+	}
+
+	blocking, err := pkgk8s.BlockingDisruptionBudgets(ctx, sc.client, spec.CloudProvider, spec.NodePoolName)
+	if err != nil {
+		slog.Warn("Failed to check pod disruption budgets", "node_pool", spec.NodePoolName, "error", err)
+		return nil
+	}
+	return blocking
+}
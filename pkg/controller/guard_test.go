@@ -0,0 +1,73 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	"github.com/kezhenxu94/bmw-saver/pkg/config"
+	"github.com/kezhenxu94/bmw-saver/pkg/notify"
+)
+
+func TestDeferForGPUWorkloads(t *testing.T) {
+	now := time.Date(2024, time.June, 3, 18, 0, 0, 0, time.UTC)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v1/nodes":
+			_ = json.NewEncoder(w).Encode(corev1.NodeList{Items: []corev1.Node{{ObjectMeta: metav1.ObjectMeta{Name: "gpu-1"}}}})
+		case "/api/v1/pods":
+			_ = json.NewEncoder(w).Encode(corev1.PodList{Items: []corev1.Pod{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "ml", Name: "train"},
+				Spec: corev1.PodSpec{NodeName: "gpu-1", Containers: []corev1.Container{{
+					Name:      "main",
+					Resources: corev1.ResourceRequirements{Limits: corev1.ResourceList{"nvidia.com/gpu": resource.MustParse("1")}},
+				}}},
+				Status: corev1.PodStatus{Phase: corev1.PodRunning},
+			}}})
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	sc := &ScalingController{
+		client: kubernetes.NewForConfigOrDie(&rest.Config{Host: server.URL, ContentConfig: rest.ContentConfig{ContentType: "application/json"}}),
+		scaleDownGuard: &scaleDownGuard{
+			maxDeferral:      4 * time.Hour,
+			gpuResourceNames: []string{"nvidia.com/gpu"},
+			gpuMaxDeferral:   12 * time.Hour,
+		},
+	}
+	spec := config.NodeSpec{NodePoolName: "gpu-pool", CloudProvider: "gke"}
+	pool := &poolState{}
+	state := &scheduleState{pools: map[string]*poolState{"gpu-pool": pool}}
+	ctx := context.Background()
+
+	if got := sc.deferForGPUWorkloads(ctx, now, pool, spec, notify.Notification{}); len(got) != 1 {
+		t.Fatalf("deferForGPUWorkloads() = %q, want the training run", got)
+	}
+	if !pool.gpuDeferredSince.Equal(now) {
+		t.Errorf("gpuDeferredSince = %v, want %v", pool.gpuDeferredSince, now)
+	}
+	if got, want := sc.nextDeferralEnd(now, state), now.Add(12*time.Hour); !got.Equal(want) {
+		t.Errorf("nextDeferralEnd() = %v, want %v", got, want)
+	}
+
+	later := now.Add(time.Hour)
+	if got := sc.deferForGPUWorkloads(ctx, later, pool, spec, notify.Notification{}); len(got) != 1 || !pool.gpuDeferredSince.Equal(now) {
+		t.Errorf("deferForGPUWorkloads() = %q since %v, want still deferred since %v", got, pool.gpuDeferredSince, now)
+	}
+	if got := sc.deferForGPUWorkloads(ctx, now.Add(12*time.Hour), pool, spec, notify.Notification{}); len(got) != 0 {
+		t.Errorf("deferForGPUWorkloads() = %q after the max deferral, want scaled down anyway", got)
+	}
+}
@@ -0,0 +1,141 @@
+package controller
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	pkgk8s "github.com/kezhenxu94/bmw-saver/pkg/kubernetes"
+	"github.com/kezhenxu94/bmw-saver/pkg/schedule"
+)
+
+// PreviewHandler serves the upcoming work/off transitions as JSON.
+// The optional "count" and "from" (RFC 3339) query parameters limit the preview,
+// "schedule" previews a named schedule instead of the default one.
+func (sc *ScalingController) PreviewHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		from := time.Now()
+		if value := r.URL.Query().Get("from"); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				http.Error(w, "invalid from: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			from = t
+		}
+
+		var opts schedule.PreviewOptions
+		if value := r.URL.Query().Get("count"); value != "" {
+			count, err := strconv.Atoi(value)
+			if err != nil || count <= 0 {
+				http.Error(w, "invalid count: "+value, http.StatusBadRequest)
+				return
+			}
+			opts.Count = count
+		}
+
+		transitions, err := sc.Preview(r.Context(), r.URL.Query().Get("schedule"), from, opts)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(transitions); err != nil {
+			slog.Error("Failed to write schedule preview", "error", err)
+		}
+	})
+}
+
+// ExplainHandler serves the schedule decision with the provider that made it and what matched as JSON.
+// The optional "at" (RFC 3339) query parameter explains another time than now,
+// "schedule" explains a named schedule instead of the default one.
+func (sc *ScalingController) ExplainHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var at time.Time
+		if value := r.URL.Query().Get("at"); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				http.Error(w, "invalid at: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			at = t
+		}
+
+		decision, err := sc.Explain(r.Context(), r.URL.Query().Get("schedule"), at)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(decision); err != nil {
+			slog.Error("Failed to write schedule explanation", "error", err)
+		}
+	})
+}
+
+// StateHandler serves the saved state of the node pools and workloads not restored yet as JSON
+func (sc *ScalingController) StateHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		states, err := pkgk8s.NewStateStore(sc.client, os.Getenv("NAMESPACE")).List(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(states); err != nil {
+			slog.Error("Failed to write saved state", "error", err)
+		}
+	})
+}
+
+// HealthzHandler serves the liveness of the controller, it fails if the reconciliation loop is stuck
+func (sc *ScalingController) HealthzHandler() http.Handler {
+	return probeHandler(sc.Live)
+}
+
+// ReadyzHandler serves the readiness of the controller, it fails until the first reconciliation completed
+func (sc *ScalingController) ReadyzHandler() http.Handler {
+	return probeHandler(sc.Ready)
+}
+
+// MetricsHandler serves the realized savings, the restore times and the drain statistics of node pools in the
+// Prometheus text format
+func (sc *ScalingController) MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sc.mu.RLock()
+		tracker, currency := sc.savings, sc.currency
+		sc.mu.RUnlock()
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		if err := tracker.WriteMetrics(w, currency); err != nil {
+			slog.Error("Failed to write metrics", "error", err)
+			return
+		}
+		if err := sc.writeRestoreMetrics(w); err != nil {
+			slog.Error("Failed to write metrics", "error", err)
+			return
+		}
+		if err := sc.writeDrainMetrics(w); err != nil {
+			slog.Error("Failed to write metrics", "error", err)
+		}
+	})
+}
+
+// probeHandler serves "ok" if check succeeds, and the error with status 503 otherwise
+func probeHandler(check func() error) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := check(); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		if _, err := w.Write([]byte("ok")); err != nil {
+			slog.Error("Failed to write probe response", "error", err)
+		}
+	})
+}
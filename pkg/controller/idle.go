@@ -0,0 +1,122 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/kezhenxu94/bmw-saver/pkg/config"
+	pkgk8s "github.com/kezhenxu94/bmw-saver/pkg/kubernetes"
+	"github.com/kezhenxu94/bmw-saver/pkg/schedule"
+)
+
+// idleScaleDown returns the decision scaling the node pool down during work time, ok is true once it was idle
+// for long enough and until pods wait for it. Failed checks don't scale the node pool down, nor restore it.
+func (sc *ScalingController) idleScaleDown(ctx context.Context, now time.Time, pool *poolState, spec config.NodeSpec) (schedule.Decision, bool) {
+	idle := spec.IdleScaleDown
+	if idle == nil || sc.client == nil {
+		pool.resetIdle()
+		return schedule.Decision{}, false
+	}
+	// The durations and quantities are validated when reading the configuration
+	idleFor, _ := time.ParseDuration(idle.IdleFor)
+	excluded := toSet(idle.ExcludedNamespaces)
+
+	if pool.idleScaledDown {
+		waiting, err := pkgk8s.UnschedulablePods(ctx, sc.client, pool.idleTemplate, excluded)
+		if err == nil && len(waiting) == 0 {
+			// Pods may fit on the nodes left by the off-time count
+			waiting, err = pkgk8s.UserPods(ctx, sc.client, spec.CloudProvider, spec.NodePoolName, excluded)
+		}
+		if err != nil {
+			slog.Warn("Failed to check if pods wait for idle node pool", "node_pool", spec.NodePoolName, "error", err)
+		} else if len(waiting) > 0 {
+			slog.Info("Pods wait for idle node pool, restoring it", "node_pool", spec.NodePoolName, "pods", waiting)
+			pool.resetIdle()
+			return schedule.Decision{}, false
+		}
+		return idleDecision(pool.idleSince), true
+	}
+
+	reason, err := sc.busyReason(ctx, spec, excluded)
+	if err != nil {
+		slog.Warn("Failed to check if node pool is idle", "node_pool", spec.NodePoolName, "error", err)
+		pool.resetIdle()
+		return schedule.Decision{}, false
+	}
+	if reason != "" {
+		slog.Debug("Node pool is busy", "node_pool", spec.NodePoolName, "reason", reason)
+		pool.resetIdle()
+		return schedule.Decision{}, false
+	}
+
+	if pool.idleSince.IsZero() {
+		pool.idleSince, pool.idleAt = now, now.Add(idleFor)
+	}
+	if now.Before(pool.idleAt) {
+		slog.Debug("Node pool is idle", "node_pool", spec.NodePoolName, "idle_since", pool.idleSince)
+		return schedule.Decision{}, false
+	}
+	// Its nodes are gone once it's scaled down, keep what pods waiting for it need to match
+	template, err := pkgk8s.NodePoolTemplate(ctx, sc.client, spec.CloudProvider, spec.NodePoolName)
+	if err != nil {
+		slog.Warn("Failed to read nodes of idle node pool", "node_pool", spec.NodePoolName, "error", err)
+		return schedule.Decision{}, false
+	}
+	slog.Info("Node pool idle, scaling it down during work time", "node_pool", spec.NodePoolName, "idle_since", pool.idleSince)
+	pool.idleScaledDown, pool.idleTemplate = true, template
+	return idleDecision(pool.idleSince), true
+}
+
+// busyReason returns why the node pool isn't idle, empty if it is. The CPU usage is only checked if
+// metrics-server is installed.
+func (sc *ScalingController) busyReason(ctx context.Context, spec config.NodeSpec, excluded map[string]bool) (string, error) {
+	used, err := pkgk8s.UserPods(ctx, sc.client, spec.CloudProvider, spec.NodePoolName, excluded)
+	if err != nil {
+		return "", err
+	}
+	if len(used) > 0 {
+		return fmt.Sprintf("%s runs on it", used[0]), nil
+	}
+
+	threshold := resource.MustParse(spec.IdleScaleDown.CPUThreshold)
+	usage, nodes, err := pkgk8s.NodePoolCPUUsage(ctx, sc.client, spec.CloudProvider, spec.NodePoolName)
+	if err != nil {
+		slog.Debug("Failed to get node metrics, skipping CPU usage check", "node_pool", spec.NodePoolName, "error", err)
+		return "", nil
+	}
+	if nodes > 0 && usage.Cmp(threshold) >= 0 {
+		return fmt.Sprintf("CPU usage %s per node is above threshold %s", usage.String(), threshold.String()), nil
+	}
+	return "", nil
+}
+
+// idleDecision returns the decision scaling a node pool idle since the given time down
+func idleDecision(idleSince time.Time) schedule.Decision {
+	return schedule.Decision{
+		Provider: "idle",
+		Reason:   "node pool idle since " + idleSince.Format(time.RFC3339),
+	}
+}
+
+// resetIdle forgets that the node pool was idle
+func (pool *poolState) resetIdle() {
+	pool.idleSince, pool.idleAt, pool.idleScaledDown, pool.idleTemplate = time.Time{}, time.Time{}, false, nil
+}
+
+// nextIdleScaleDown returns when the earliest idle node pool of the schedule is scaled down, zero if none is idle
+func (state *scheduleState) nextIdleScaleDown(now time.Time) time.Time {
+	var next time.Time
+	for _, pool := range state.pools {
+		if pool.idleScaledDown {
+			continue
+		}
+		if at := pool.idleAt; at.After(now) && (next.IsZero() || at.Before(next)) {
+			next = at
+		}
+	}
+	return next
+}
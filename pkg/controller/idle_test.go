@@ -0,0 +1,25 @@
+package controller
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNextIdleScaleDown(t *testing.T) {
+	now := time.Date(2024, time.June, 4, 11, 0, 0, 0, time.UTC)
+	state := &scheduleState{pools: map[string]*poolState{
+		"scaled":  {idleSince: now.Add(-3 * time.Hour), idleAt: now.Add(-time.Hour), idleScaledDown: true},
+		"idle":    {idleSince: now.Add(-time.Hour), idleAt: now.Add(time.Hour)},
+		"soonest": {idleSince: now.Add(-90 * time.Minute), idleAt: now.Add(30 * time.Minute)},
+		"busy":    {},
+	}}
+	if got, want := state.nextIdleScaleDown(now), now.Add(30*time.Minute); !got.Equal(want) {
+		t.Errorf("nextIdleScaleDown() = %v, want %v", got, want)
+	}
+
+	pool := state.pools["scaled"]
+	pool.resetIdle()
+	if !pool.idleSince.IsZero() || !pool.idleAt.IsZero() || pool.idleScaledDown {
+		t.Errorf("resetIdle() left %+v", pool)
+	}
+}
@@ -0,0 +1,76 @@
+package controller
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/kezhenxu94/bmw-saver/pkg/config"
+	pkgk8s "github.com/kezhenxu94/bmw-saver/pkg/kubernetes"
+)
+
+// defaultMaxKeepAlive is how long keep-alives may postpone a scale down if not configured
+const defaultMaxKeepAlive = 4 * time.Hour
+
+// initKeepAlive initializes the max extension of keep-alives based on configuration, it's validated when reading it
+func (sc *ScalingController) initKeepAlive(cfg config.Config) {
+	sc.maxKeepAlive = defaultMaxKeepAlive
+	if cfg.KeepAlive != nil && cfg.KeepAlive.MaxExtension != "" {
+		sc.maxKeepAlive, _ = time.ParseDuration(cfg.KeepAlive.MaxExtension)
+	}
+}
+
+// keptAlive returns the unexpired keep-alives for which the scale down of the node pool is postponed.
+// The postponement starts when keep-alives are found first, once it took longer than the max extension
+// the node pool is scaled down anyway. Failed checks don't postpone the scale down.
+func (sc *ScalingController) keptAlive(ctx context.Context, now time.Time, pool *poolState, spec config.NodeSpec) []pkgk8s.KeepAlive {
+	if sc.client == nil || sc.maxKeepAlive <= 0 {
+		return nil
+	}
+
+	keptAliveSince, keptAlive := pool.keptAliveSince, !pool.keptAliveSince.IsZero()
+	if keptAlive && now.Sub(keptAliveSince) >= sc.maxKeepAlive {
+		if !pool.keptAliveUntil.IsZero() {
+			slog.Warn("Scale down kept alive for too long, scaling down despite keep-alives",
+				"node_pool", spec.NodePoolName,
+				"kept_alive_since", keptAliveSince,
+				"max_extension", sc.maxKeepAlive,
+			)
+			pool.keptAliveUntil = time.Time{}
+		}
+		return nil
+	}
+
+	keepAlives, err := pkgk8s.KeepAlives(ctx, sc.client, os.Getenv("NAMESPACE"), PoolConfigMapNamePrefix+spec.NodePoolName,
+		spec.CloudProvider, spec.NodePoolName, now)
+	if err != nil {
+		slog.Warn("Failed to check keep-alives, not postponing scale down", "node_pool", spec.NodePoolName, "error", err)
+		return nil
+	}
+	if len(keepAlives) == 0 {
+		pool.keptAliveUntil = time.Time{}
+		return nil
+	}
+
+	if !keptAlive {
+		pool.keptAliveSince = now
+	}
+	// Check again when the soonest keep-alive expires, capped by the max extension
+	pool.keptAliveUntil = keepAlives[0].Until
+	if end := pool.keptAliveSince.Add(sc.maxKeepAlive); end.Before(pool.keptAliveUntil) {
+		pool.keptAliveUntil = end
+	}
+	return keepAlives
+}
+
+// nextKeepAliveEnd returns when the earliest keep-alive of the schedule expires, zero if none is kept alive
+func (state *scheduleState) nextKeepAliveEnd(now time.Time) time.Time {
+	var next time.Time
+	for _, pool := range state.pools {
+		if end := pool.keptAliveUntil; end.After(now) && (next.IsZero() || end.Before(next)) {
+			next = end
+		}
+	}
+	return next
+}
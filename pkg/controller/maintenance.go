@@ -0,0 +1,82 @@
+package controller
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/kezhenxu94/bmw-saver/pkg/config"
+)
+
+// activeMaintenance returns the maintenance window of the node pool containing now and when it ends, if any
+func (sc *ScalingController) activeMaintenance(now time.Time, nodePool string) (*config.MaintenanceWindow, time.Time) {
+	for i, window := range sc.config.MaintenanceWindows {
+		if len(window.NodePools) > 0 && !slices.Contains(window.NodePools, nodePool) {
+			continue
+		}
+		if end, ok := maintenanceEnd(window, now); ok {
+			return &sc.config.MaintenanceWindows[i], end
+		}
+	}
+	return nil, time.Time{}
+}
+
+// maintenanceEnd returns when the maintenance window ends if it contains now. Weekly windows
+// are checked from today and from yesterday, as they may go past midnight.
+func maintenanceEnd(window config.MaintenanceWindow, now time.Time) (time.Time, bool) {
+	if window.Start != "" {
+		start, err := time.Parse(time.RFC3339, window.Start)
+		if err != nil {
+			return time.Time{}, false
+		}
+		end, err := time.Parse(time.RFC3339, window.End)
+		if err != nil {
+			return time.Time{}, false
+		}
+		return end, !now.Before(start) && now.Before(end)
+	}
+
+	location, err := time.LoadLocation(window.TimeZone)
+	if err != nil {
+		return time.Time{}, false
+	}
+	startTime, err := time.Parse("15:04", window.StartTime)
+	if err != nil {
+		return time.Time{}, false
+	}
+	endTime, err := time.Parse("15:04", window.EndTime)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	local := now.In(location)
+	for _, offset := range []int{0, -1} {
+		day := local.AddDate(0, 0, offset)
+		if len(window.Days) > 0 && !slices.ContainsFunc(window.Days, func(d string) bool {
+			return config.Weekdays[strings.ToLower(d)] == day.Weekday()
+		}) {
+			continue
+		}
+		start := time.Date(day.Year(), day.Month(), day.Day(), startTime.Hour(), startTime.Minute(), 0, 0, location)
+		end := time.Date(day.Year(), day.Month(), day.Day(), endTime.Hour(), endTime.Minute(), 0, 0, location)
+		if !end.After(start) {
+			end = end.AddDate(0, 0, 1)
+		}
+		if !now.Before(start) && now.Before(end) {
+			return end, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// nextMaintenanceEnd returns when the earliest active maintenance window ends, zero if none is active,
+// so that node pools are scaled down right after it
+func (sc *ScalingController) nextMaintenanceEnd(now time.Time) time.Time {
+	var next time.Time
+	for _, window := range sc.config.MaintenanceWindows {
+		if end, ok := maintenanceEnd(window, now); ok && (next.IsZero() || end.Before(next)) {
+			next = end
+		}
+	}
+	return next
+}
@@ -0,0 +1,62 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kezhenxu94/bmw-saver/pkg/config"
+)
+
+func TestMaintenanceEnd(t *testing.T) {
+	weekly := config.MaintenanceWindow{StartTime: "22:00", EndTime: "04:00", TimeZone: "UTC", Days: []string{"saturday"}}
+	oneOff := config.MaintenanceWindow{Start: "2024-06-28T00:00:00Z", End: "2024-06-29T00:00:00Z"}
+
+	tests := []struct {
+		name    string
+		window  config.MaintenanceWindow
+		now     time.Time
+		wantEnd time.Time
+		wantOK  bool
+	}{
+		{
+			name:    "weekly window on its day",
+			window:  weekly,
+			now:     time.Date(2024, time.June, 8, 23, 0, 0, 0, time.UTC),
+			wantEnd: time.Date(2024, time.June, 9, 4, 0, 0, 0, time.UTC),
+			wantOK:  true,
+		},
+		{
+			name:    "weekly window past midnight",
+			window:  weekly,
+			now:     time.Date(2024, time.June, 9, 3, 0, 0, 0, time.UTC),
+			wantEnd: time.Date(2024, time.June, 9, 4, 0, 0, 0, time.UTC),
+			wantOK:  true,
+		},
+		{
+			name:   "weekly window on another day",
+			window: weekly,
+			now:    time.Date(2024, time.June, 9, 23, 0, 0, 0, time.UTC),
+		},
+		{
+			name:    "one-off window",
+			window:  oneOff,
+			now:     time.Date(2024, time.June, 28, 12, 0, 0, 0, time.UTC),
+			wantEnd: time.Date(2024, time.June, 29, 0, 0, 0, 0, time.UTC),
+			wantOK:  true,
+		},
+		{
+			name:   "after one-off window",
+			window: oneOff,
+			now:    time.Date(2024, time.June, 29, 0, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			end, ok := maintenanceEnd(tt.window, tt.now)
+			if ok != tt.wantOK || !end.Equal(tt.wantEnd) && tt.wantOK {
+				t.Errorf("maintenanceEnd() = %v, %v, want %v, %v", end, ok, tt.wantEnd, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,21 @@
+package controller
+
+import (
+	"context"
+	"log/slog"
+	"os"
+
+	"github.com/kezhenxu94/bmw-saver/pkg/config"
+	pkgk8s "github.com/kezhenxu94/bmw-saver/pkg/kubernetes"
+)
+
+// recordNodePoolStatus writes the outcome of scaling the node pool to the status of its NodePoolState, failures
+// are only logged as the status is informational
+func (sc *ScalingController) recordNodePoolStatus(ctx context.Context, spec config.NodeSpec, scaling pkgk8s.NodePoolScaling) {
+	if sc.client == nil {
+		return
+	}
+	if err := pkgk8s.UpdateNodePoolStatus(ctx, sc.client, os.Getenv("NAMESPACE"), spec.NodePoolName, scaling); err != nil {
+		slog.Warn("Failed to update status of NodePoolState", "node_pool", spec.NodePoolName, "error", err)
+	}
+}
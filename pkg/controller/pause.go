@@ -0,0 +1,44 @@
+package controller
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strconv"
+
+	"github.com/kezhenxu94/bmw-saver/pkg/config"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// PausedAnnotation pauses a node pool when set to "true" on its control ConfigMap
+	PausedAnnotation = "bmw-saver.io/paused"
+	// PoolConfigMapNamePrefix is the prefix of the control ConfigMap of a node pool, followed by its name
+	PoolConfigMapNamePrefix = "bmw-saver-pool-"
+)
+
+// isPaused returns true if the node pool is paused in its node spec, or through the annotation on
+// its control ConfigMap. Paused node pools are left untouched until they are unpaused.
+func (sc *ScalingController) isPaused(ctx context.Context, spec config.NodeSpec) bool {
+	if spec.Paused {
+		return true
+	}
+	if sc.client == nil {
+		return false
+	}
+
+	name := PoolConfigMapNamePrefix + spec.NodePoolName
+	cm, err := sc.client.CoreV1().ConfigMaps(os.Getenv("NAMESPACE")).Get(ctx, name, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		return false
+	}
+	if err != nil {
+		slog.Warn("Failed to check if node pool is paused", "node_pool", spec.NodePoolName, "config_map", name, "error", err)
+		return false
+	}
+
+	paused, _ := strconv.ParseBool(cm.Annotations[PausedAnnotation])
+	return paused
+}
@@ -0,0 +1,99 @@
+package controller
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/kezhenxu94/bmw-saver/pkg/config"
+	pkgk8s "github.com/kezhenxu94/bmw-saver/pkg/kubernetes"
+)
+
+// defaultRebalanceMaxEvictions is how many pods are evicted per restored node pool if not configured
+const defaultRebalanceMaxEvictions = 20
+
+// initRebalance initializes the rebalancing after restores based on configuration, the timeout is validated when
+// reading it
+func (sc *ScalingController) initRebalance(cfg config.Config) {
+	sc.rebalance = cfg.Rebalance
+	sc.rebalanceTimeout = 0
+	if cfg.Rebalance != nil {
+		sc.rebalanceTimeout, _ = time.ParseDuration(cfg.Rebalance.Timeout)
+	}
+}
+
+// startRebalance starts waiting for the node pool restored at now to become ready, to rebalance its pods then
+func (sc *ScalingController) startRebalance(now time.Time, pool *poolState) {
+	if sc.rebalance == nil {
+		return
+	}
+	pool.rebalanceSince = now
+}
+
+// rebalanceRestored rebalances the pods of the restored node pool once it's ready, by evicting the pods packed
+// onto the nodes that stayed up during off time or by running the descheduler. It's skipped if the node pool
+// doesn't become ready within the timeout.
+func (sc *ScalingController) rebalanceRestored(ctx context.Context, now time.Time, pool *poolState, spec config.NodeSpec) {
+	rebalance := sc.rebalance
+	if pool.rebalanceSince.IsZero() || rebalance == nil || sc.client == nil || sc.nodes == nil {
+		return
+	}
+
+	ready, want, err := sc.readyNodes(ctx, spec, pool.scaledDownFrom)
+	if err != nil {
+		slog.Warn("Failed to get nodes of node pool, not rebalancing yet", "node_pool", spec.NodePoolName, "error", err)
+		return
+	}
+	if ready == 0 || ready < want {
+		if now.Sub(pool.rebalanceSince) >= sc.rebalanceTimeout {
+			pool.rebalanceSince = time.Time{}
+			slog.Warn("Restored node pool isn't ready, not rebalancing", "node_pool", spec.NodePoolName, "ready_nodes", ready, "nodes", want)
+		}
+		return
+	}
+	pool.rebalanceSince = time.Time{}
+
+	if cronJob := rebalance.DeschedulerCronJob; cronJob != "" {
+		namespace, name, _ := strings.Cut(cronJob, "/")
+		job, err := pkgk8s.RunCronJob(ctx, sc.client, namespace, name)
+		if err != nil {
+			slog.Error("Failed to run descheduler", "node_pool", spec.NodePoolName, "cronjob", cronJob, "error", err)
+			return
+		}
+		slog.Info("Ran descheduler after restore", "node_pool", spec.NodePoolName, "job", job, "namespace", namespace)
+		sc.recordEvent(corev1.EventTypeNormal, eventReasonRebalanced,
+			"Ran descheduler %s to rebalance restored node pool %s", cronJob, spec.NodePoolName)
+		return
+	}
+
+	maxEvictions := defaultRebalanceMaxEvictions
+	if rebalance.MaxEvictions > 0 {
+		maxEvictions = rebalance.MaxEvictions
+	}
+	evicted, err := pkgk8s.RebalanceNodePool(ctx, sc.client, spec.CloudProvider, spec.NodePoolName, rebalance.MaxPriority, maxEvictions)
+	if err != nil {
+		slog.Error("Failed to rebalance restored node pool", "node_pool", spec.NodePoolName, "evicted", evicted, "error", err)
+		return
+	}
+	if len(evicted) == 0 {
+		slog.Debug("Restored node pool is balanced", "node_pool", spec.NodePoolName)
+		return
+	}
+	slog.Info("Rebalanced restored node pool", "node_pool", spec.NodePoolName, "evicted", evicted)
+	sc.recordEvent(corev1.EventTypeNormal, eventReasonRebalanced,
+		"Evicted %d pods to rebalance restored node pool %s: %s", len(evicted), spec.NodePoolName, strings.Join(evicted, ", "))
+}
+
+// nextRebalance returns when to check the restored node pools of the schedule waiting to be rebalanced again,
+// zero if none are waiting
+func (state *scheduleState) nextRebalance(now time.Time) time.Time {
+	for _, pool := range state.pools {
+		if !pool.rebalanceSince.IsZero() {
+			return now.Add(verifyInterval)
+		}
+	}
+	return time.Time{}
+}
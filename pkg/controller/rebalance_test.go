@@ -0,0 +1,77 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	"github.com/kezhenxu94/bmw-saver/pkg/config"
+	pkgk8s "github.com/kezhenxu94/bmw-saver/pkg/kubernetes"
+)
+
+func TestRebalanceRestored(t *testing.T) {
+	restoredAt := time.Date(2024, time.June, 4, 8, 0, 0, 0, time.UTC)
+	ready := corev1.NodeStatus{Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}}}
+	nodes := []corev1.Node{{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}, Status: ready}, {ObjectMeta: metav1.ObjectMeta{Name: "node-2"}}}
+	jobs := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/api/v1/nodes":
+			_ = json.NewEncoder(w).Encode(corev1.NodeList{Items: nodes})
+		case r.URL.Path == "/apis/batch/v1/namespaces/kube-system/cronjobs/descheduler":
+			_ = json.NewEncoder(w).Encode(batchv1.CronJob{ObjectMeta: metav1.ObjectMeta{Namespace: "kube-system", Name: "descheduler"}})
+		case r.Method == http.MethodPost && r.URL.Path == "/apis/batch/v1/namespaces/kube-system/jobs":
+			jobs++
+			_ = json.NewEncoder(w).Encode(batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "descheduler-bmw-saver-abcde"}})
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	client := kubernetes.NewForConfigOrDie(&rest.Config{Host: server.URL, ContentConfig: rest.ContentConfig{ContentType: "application/json"}})
+	sc := &ScalingController{client: client, nodes: pkgk8s.NewNodeLister(client)}
+	sc.initRebalance(config.Config{Rebalance: &config.RebalanceConfig{DeschedulerCronJob: "kube-system/descheduler", Timeout: "30m"}})
+	spec := config.NodeSpec{NodePoolName: "default-pool", CloudProvider: "gke"}
+	pool := &poolState{scaledDownFrom: 2}
+	state := &scheduleState{pools: map[string]*poolState{"default-pool": pool}}
+	ctx := context.Background()
+
+	sc.startRebalance(restoredAt, pool)
+	if got, want := state.nextRebalance(restoredAt), restoredAt.Add(verifyInterval); !got.Equal(want) {
+		t.Errorf("nextRebalance() = %v, want %v", got, want)
+	}
+
+	// Waits for all nodes to be ready
+	sc.rebalanceRestored(ctx, restoredAt.Add(time.Minute), pool, spec)
+	if jobs != 0 || pool.rebalanceSince.IsZero() {
+		t.Fatalf("rebalanced with a node not ready, jobs = %d", jobs)
+	}
+
+	nodes[1].Status = ready
+	sc.rebalanceRestored(ctx, restoredAt.Add(2*time.Minute), pool, spec)
+	if jobs != 1 || !pool.rebalanceSince.IsZero() {
+		t.Errorf("jobs = %d, waiting since %v, want the descheduler run once ready", jobs, pool.rebalanceSince)
+	}
+	if got := state.nextRebalance(restoredAt); !got.IsZero() {
+		t.Errorf("nextRebalance() = %v after rebalancing, want zero", got)
+	}
+
+	// Gives up on node pools not ready within the timeout
+	nodes[1].Status = corev1.NodeStatus{}
+	sc.startRebalance(restoredAt, pool)
+	sc.rebalanceRestored(ctx, restoredAt.Add(30*time.Minute), pool, spec)
+	if jobs != 1 || !pool.rebalanceSince.IsZero() {
+		t.Errorf("jobs = %d, waiting since %v, want skipped after the timeout", jobs, pool.rebalanceSince)
+	}
+}
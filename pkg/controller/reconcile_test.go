@@ -0,0 +1,449 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/kezhenxu94/bmw-saver/pkg/config"
+	pkgk8s "github.com/kezhenxu94/bmw-saver/pkg/kubernetes"
+	"github.com/kezhenxu94/bmw-saver/pkg/providers"
+	"github.com/kezhenxu94/bmw-saver/pkg/schedule"
+)
+
+// fakeSchedule is work time whenever isWorkTime returns true, fails with err if set, and is unhealthy with the
+// healthy error
+type fakeSchedule struct {
+	isWorkTime func(t time.Time) bool
+	err        error
+	healthy    error
+}
+
+func (s *fakeSchedule) IsWorkTime(ctx context.Context, t time.Time) (bool, error) {
+	if s.err != nil {
+		return false, s.err
+	}
+	return s.isWorkTime(t), nil
+}
+
+func (s *fakeSchedule) LastSync() time.Time { return time.Time{} }
+
+func (s *fakeSchedule) Healthy() error { return s.healthy }
+
+// workHours returns a schedule with work time from the start hour until the end hour every day
+func workHours(start, end int) *fakeSchedule {
+	return &fakeSchedule{isWorkTime: func(t time.Time) bool {
+		return t.Hour() >= start && t.Hour() < end
+	}}
+}
+
+// inFlight counts the calls in progress of the fake cloud providers sharing it
+type inFlight struct {
+	mu sync.Mutex
+	// running and max are how many calls are in progress, and at most at once
+	running int
+	max     int
+}
+
+func (f *inFlight) start() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.running++
+	if f.running > f.max {
+		f.max = f.running
+	}
+}
+
+func (f *inFlight) done() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.running--
+}
+
+func (f *inFlight) maxRunning() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.max
+}
+
+// fakeCloudProvider records the scaling calls of its node pools, each taking delay or until the context is done
+type fakeCloudProvider struct {
+	delay    time.Duration
+	inFlight *inFlight
+
+	mu      sync.Mutex
+	scaled  map[string][]int32
+	restore map[string]int
+}
+
+func newFakeCloudProvider(delay time.Duration) *fakeCloudProvider {
+	return &fakeCloudProvider{delay: delay, inFlight: &inFlight{}, scaled: make(map[string][]int32), restore: make(map[string]int)}
+}
+
+func (p *fakeCloudProvider) call(ctx context.Context, record func()) error {
+	p.inFlight.start()
+	defer p.inFlight.done()
+
+	select {
+	case <-time.After(p.delay):
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	record()
+	return nil
+}
+
+func (p *fakeCloudProvider) ScaleNodePool(ctx context.Context, nodePoolName string, count int32) error {
+	return p.call(ctx, func() { p.scaled[nodePoolName] = append(p.scaled[nodePoolName], count) })
+}
+
+func (p *fakeCloudProvider) RestoreNodePool(ctx context.Context, nodePoolName string) error {
+	return p.call(ctx, func() { p.restore[nodePoolName]++ })
+}
+
+func (p *fakeCloudProvider) scaledTo(nodePoolName string) []int32 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]int32(nil), p.scaled[nodePoolName]...)
+}
+
+func (p *fakeCloudProvider) restored(nodePoolName string) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.restore[nodePoolName]
+}
+
+// newTestController returns a controller scaling the node pools of the default schedule with the provider
+func newTestController(scheduler schedule.Provider, provider providers.CloudProvider, specs ...config.NodeSpec) *ScalingController {
+	sc := &ScalingController{
+		config:             config.Config{NodeSpecs: specs},
+		providers:          make(map[string]providers.CloudProvider),
+		schedules:          map[string]*scheduleState{defaultScheduleName: {scheduler: scheduler, pools: make(map[string]*poolState)}},
+		safetyPollInterval: time.Hour,
+		concurrency:        defaultConcurrency,
+		poolTimeout:        defaultPoolTimeout,
+	}
+	for _, spec := range specs {
+		sc.providers[spec.NodePoolName] = provider
+	}
+	return sc
+}
+
+// reconcileAt reconciles the default schedule at now, like reconcile does, and waits for its node pools
+func reconcileAt(sc *ScalingController, now time.Time) {
+	var workers errgroup.Group
+	workers.SetLimit(sc.concurrency)
+	ctx := context.Background()
+	sc.reconcileSchedule(ctx, ctx, now, defaultScheduleName, sc.schedules[defaultScheduleName], sc.config.NodeSpecs, workloadSet{}, nil, &workers)
+	_ = workers.Wait()
+}
+
+func TestReconcileRecordsNodeCountBeforeScaleDown(t *testing.T) {
+	workStart := time.Date(2024, time.June, 10, 8, 0, 0, 0, time.UTC)
+	workEnd := workStart.Add(10 * time.Hour)
+	client := fake.NewSimpleClientset()
+	for i := 0; i < 3; i++ {
+		node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{
+			Name:   fmt.Sprintf("node-%d", i),
+			Labels: map[string]string{"cloud.google.com/gke-nodepool": "default-pool"},
+		}}
+		if _, err := client.CoreV1().Nodes().Create(context.Background(), node, metav1.CreateOptions{}); err != nil {
+			t.Fatalf("Failed to create node: %v", err)
+		}
+	}
+	provider := newFakeCloudProvider(0)
+	sc := newTestController(workHours(8, 18), provider,
+		config.NodeSpec{NodePoolName: "default-pool", CloudProvider: "gke", OffTimeCount: 0})
+	sc.nodes = pkgk8s.NewNodeLister(client)
+	sc.verifyTimeout = 15 * time.Minute
+	pool := sc.schedules[defaultScheduleName].pool("default-pool")
+
+	reconcileAt(sc, workStart.Add(time.Hour))
+	if provider.restored("default-pool") != 1 || pool.applied != "restore" {
+		t.Fatalf("restored %d times, applied %q, want restored once", provider.restored("default-pool"), pool.applied)
+	}
+
+	reconcileAt(sc, workEnd.Add(time.Minute))
+	if got := provider.scaledTo("default-pool"); len(got) != 1 || got[0] != 0 {
+		t.Fatalf("scaled to %v, want [0]", got)
+	}
+	if pool.scaledDownFrom != 3 {
+		t.Errorf("scaledDownFrom = %d after scale down from work time, want 3", pool.scaledDownFrom)
+	}
+
+	// Scaling between off time counts doesn't tell the size of the node pool at work time
+	sc.config.NodeSpecs[0].OffTimeCount = 1
+	reconcileAt(sc, workEnd.Add(time.Hour))
+	if got := provider.scaledTo("default-pool"); len(got) != 2 || got[1] != 1 {
+		t.Fatalf("scaled to %v, want [0 1]", got)
+	}
+	if pool.scaledDownFrom != 3 {
+		t.Errorf("scaledDownFrom = %d after scale down between off time counts, want 3", pool.scaledDownFrom)
+	}
+
+	reconcileAt(sc, workStart.Add(24*time.Hour))
+	if pool.verification == nil || pool.verification.nodes != 3 {
+		t.Errorf("verification = %+v after restore, want 3 nodes", pool.verification)
+	}
+}
+
+func TestReconcileScalesNodePoolsInParallel(t *testing.T) {
+	gauge := &inFlight{}
+	stuck := newFakeCloudProvider(time.Hour)
+	stuck.inFlight = gauge
+	fast := newFakeCloudProvider(20 * time.Millisecond)
+	fast.inFlight = gauge
+	specs := []config.NodeSpec{{NodePoolName: "stuck-pool"}}
+	for i := 0; i < 6; i++ {
+		specs = append(specs, config.NodeSpec{NodePoolName: fmt.Sprintf("pool-%d", i)})
+	}
+	sc := newTestController(&fakeSchedule{isWorkTime: func(time.Time) bool { return false }}, fast, specs...)
+	sc.providers["stuck-pool"] = stuck
+	sc.concurrency = 2
+	sc.poolTimeout = 500 * time.Millisecond
+
+	start := time.Now()
+	sc.reconcile(context.Background(), context.Background())
+	elapsed := time.Since(start)
+
+	if got := gauge.maxRunning(); got != sc.concurrency {
+		t.Errorf("%d node pools scaled at once, want %d", got, sc.concurrency)
+	}
+	// The fast node pools are scaled by the other worker while the stuck one times out
+	for _, spec := range specs[1:] {
+		if got := fast.scaledTo(spec.NodePoolName); len(got) != 1 {
+			t.Errorf("%s scaled to %v, want scaled once", spec.NodePoolName, got)
+		}
+	}
+	if elapsed < sc.poolTimeout || elapsed > 2*sc.poolTimeout {
+		t.Errorf("reconcile took %v, want the pool timeout %v", elapsed, sc.poolTimeout)
+	}
+	pool := sc.schedules[defaultScheduleName].pools["stuck-pool"]
+	if len(stuck.scaledTo("stuck-pool")) != 0 || pool.failures != 1 || !strings.Contains(pool.lastError, context.DeadlineExceeded.Error()) {
+		t.Errorf("stuck node pool failed %d times with %q, want timed out once", pool.failures, pool.lastError)
+	}
+	if pool.backoff == nil {
+		t.Errorf("stuck node pool isn't backing off")
+	}
+}
+
+func TestProbes(t *testing.T) {
+	scheduler := &fakeSchedule{isWorkTime: func(time.Time) bool { return false }}
+	fast := newFakeCloudProvider(20 * time.Millisecond)
+	sc := newTestController(scheduler, fast, config.NodeSpec{NodePoolName: "pool-1"}, config.NodeSpec{NodePoolName: "pool-2"})
+
+	if err := sc.Ready(); err == nil {
+		t.Errorf("Ready() = nil before the first reconciliation, want error")
+	}
+	sc.ready.Store(true)
+	if err := sc.Ready(); err != nil {
+		t.Errorf("Ready() error = %v", err)
+	}
+	scheduler.healthy = errors.New("sync failed")
+	sc.recordHealth()
+	// Ready doesn't wait for reconciliations or configuration updates holding the lock
+	sc.mu.Lock()
+	err := sc.Ready()
+	sc.mu.Unlock()
+	if err == nil {
+		t.Errorf("Ready() = nil with an unhealthy schedule, want error")
+	}
+
+	// Scaling node pools is progress, even if the reconciliation as a whole takes long
+	sc.stuckAt.Store(time.Now().Add(-time.Minute).UnixNano())
+	if err := sc.Live(); err == nil {
+		t.Errorf("Live() = nil when overdue, want error")
+	}
+	reconcileAt(sc, time.Date(2024, time.June, 10, 20, 0, 0, 0, time.UTC))
+	if err := sc.Live(); err != nil {
+		t.Errorf("Live() error = %v after node pools were scaled", err)
+	}
+}
+
+func TestReconcileScaleDownDelay(t *testing.T) {
+	start := time.Date(2024, time.June, 10, 17, 0, 0, 0, time.UTC)
+	type step struct {
+		after      time.Duration
+		isWorkTime bool
+	}
+	tests := []struct {
+		name  string
+		steps []step
+		// want are the counts the node pool was scaled to, and wantNext when reconciled next for the delay
+		want     []int32
+		wantNext time.Duration
+	}{
+		{
+			name:     "off time within the delay",
+			steps:    []step{{0, true}, {time.Hour, false}, {time.Hour + 20*time.Minute, false}},
+			wantNext: time.Hour + 30*time.Minute,
+		},
+		{
+			name:  "off time longer than the delay",
+			steps: []step{{0, true}, {time.Hour, false}, {time.Hour + 30*time.Minute, false}},
+			want:  []int32{0},
+		},
+		{
+			name:  "work time again within the delay",
+			steps: []step{{0, true}, {time.Hour, false}, {time.Hour + 20*time.Minute, true}, {time.Hour + 35*time.Minute, false}, {2 * time.Hour, false}},
+			// The delay starts over once off time starts again
+			wantNext: 2*time.Hour + 5*time.Minute,
+		},
+		{
+			name:  "off time again longer than the delay",
+			steps: []step{{0, true}, {time.Hour, false}, {time.Hour + 20*time.Minute, true}, {time.Hour + 35*time.Minute, false}, {2*time.Hour + 5*time.Minute, false}},
+			want:  []int32{0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			isWorkTime := true
+			provider := newFakeCloudProvider(0)
+			sc := newTestController(&fakeSchedule{isWorkTime: func(time.Time) bool { return isWorkTime }}, provider,
+				config.NodeSpec{NodePoolName: "default-pool"})
+			state := sc.schedules[defaultScheduleName]
+			state.scaleDownDelay = 30 * time.Minute
+
+			var now time.Time
+			for _, step := range tt.steps {
+				now, isWorkTime = start.Add(step.after), step.isWorkTime
+				reconcileAt(sc, now)
+			}
+			if got := provider.scaledTo("default-pool"); len(got) != len(tt.want) || (len(got) > 0 && got[0] != tt.want[0]) {
+				t.Errorf("scaled to %v, want %v", got, tt.want)
+			}
+			if tt.wantNext > 0 {
+				if got := state.nextReconcile(context.Background(), now, now.Add(time.Hour)); !got.Equal(start.Add(tt.wantNext)) {
+					t.Errorf("nextReconcile() = %v, want %v", got, start.Add(tt.wantNext))
+				}
+			}
+		})
+	}
+}
+
+func TestReconcileRestoreLeadTime(t *testing.T) {
+	day := time.Date(2024, time.June, 10, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		// at is when the node pool is reconciled, work time starts at 08:00
+		at          time.Duration
+		wantRestore bool
+		// wantNext is when the node pool is reconciled next, at the transitions of the schedule or the lead time
+		// before them, whichever comes first
+		wantNext time.Duration
+	}{
+		{name: "work time starts after the lead time", at: 7 * time.Hour, wantNext: 7*time.Hour + 45*time.Minute},
+		{name: "work time starts within the lead time", at: 7*time.Hour + 50*time.Minute, wantRestore: true, wantNext: 8 * time.Hour},
+		{name: "work time", at: 9 * time.Hour, wantRestore: true, wantNext: 17*time.Hour + 45*time.Minute},
+		{name: "off time until the next day", at: 19 * time.Hour, wantNext: 24*time.Hour + 7*time.Hour + 45*time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := newFakeCloudProvider(0)
+			sc := newTestController(workHours(8, 18), provider, config.NodeSpec{NodePoolName: "default-pool"})
+			state := sc.schedules[defaultScheduleName]
+			state.restoreLeadTime = 15 * time.Minute
+			now := day.Add(tt.at)
+
+			reconcileAt(sc, now)
+			if restored := provider.restored("default-pool") == 1; restored != tt.wantRestore {
+				t.Errorf("restored = %v, want %v", restored, tt.wantRestore)
+			}
+			if scaled := len(provider.scaledTo("default-pool")) == 1; scaled == tt.wantRestore {
+				t.Errorf("scaled down = %v, want %v", scaled, !tt.wantRestore)
+			}
+			if got := state.nextReconcile(context.Background(), now, now.Add(24*time.Hour)); !got.Equal(day.Add(tt.wantNext)) {
+				t.Errorf("nextReconcile() = %v, want %v", got, day.Add(tt.wantNext))
+			}
+		})
+	}
+}
+
+func TestReconcileRestoreLeadTimeFailingSchedule(t *testing.T) {
+	provider := newFakeCloudProvider(0)
+	scheduler := &fakeSchedule{isWorkTime: func(time.Time) bool { return false }, err: errors.New("calendar unavailable")}
+	sc := newTestController(scheduler, provider, config.NodeSpec{NodePoolName: "default-pool"})
+	state := sc.schedules[defaultScheduleName]
+	state.restoreLeadTime = 15 * time.Minute
+	now := time.Date(2024, time.June, 10, 12, 0, 0, 0, time.UTC)
+
+	if _, err := state.decide(context.Background(), now); err == nil {
+		t.Errorf("decide() error = nil, want the schedule error")
+	}
+	// Failed decisions don't scale node pools down
+	reconcileAt(sc, now)
+	if got := provider.scaledTo("default-pool"); len(got) != 0 {
+		t.Errorf("scaled to %v with a failing schedule, want no scale down", got)
+	}
+}
+
+func TestExplainLeavesObserversAlone(t *testing.T) {
+	idle, err := schedule.NewClusterIdleProvider(fake.NewSimpleClientset(), time.Hour, "200m", nil)
+	if err != nil {
+		t.Fatalf("NewClusterIdleProvider() error = %v", err)
+	}
+	// The idle cluster keeps work time for an hour past the work hours
+	scheduler := schedule.NewCompositeProvider(workHours(8, 18))
+	scheduler.AddOverride(idle, 10)
+	sc := newTestController(scheduler, newFakeCloudProvider(0), config.NodeSpec{NodePoolName: "default-pool"})
+	state := sc.schedules[defaultScheduleName]
+	ctx := context.Background()
+	day := time.Date(2024, time.June, 10, 0, 0, 0, 0, time.UTC)
+
+	if decision, err := state.decide(ctx, day.Add(19*time.Hour)); err != nil || !decision.IsWorkTime {
+		t.Fatalf("decide() = %+v, %v, want work time until the cluster is idle for an hour", decision, err)
+	}
+	// Explaining an earlier time doesn't make the cluster idle since then
+	decision, err := sc.Explain(ctx, "", day.Add(17*time.Hour))
+	if err != nil {
+		t.Fatalf("Explain() error = %v", err)
+	}
+	if !decision.IsWorkTime {
+		t.Errorf("Explain() = %+v, want work time within work hours", decision)
+	}
+	if decision, err := state.decide(ctx, day.Add(19*time.Hour+30*time.Minute)); err != nil || !decision.IsWorkTime {
+		t.Errorf("decide() after Explain() = %+v, %v, want work time while the cluster is idle for 30m", decision, err)
+	}
+}
+
+func TestReconcileBlackout(t *testing.T) {
+	provider := newFakeCloudProvider(0)
+	sc := newTestController(&fakeSchedule{isWorkTime: func(time.Time) bool { return false }}, provider,
+		config.NodeSpec{NodePoolName: "default-pool"})
+	// reconcile runs at the current time, the blackout window is around it
+	now := time.Now().Truncate(time.Second)
+	end := now.Add(10 * time.Minute)
+	sc.config.Blackouts = []config.BlackoutWindow{{
+		Start:  now.Add(-time.Hour).Format(time.RFC3339),
+		End:    end.Format(time.RFC3339),
+		Reason: "release freeze",
+	}}
+
+	next := sc.reconcile(context.Background(), context.Background())
+	if got := provider.scaledTo("default-pool"); len(got) != 0 {
+		t.Errorf("scaled to %v in blackout window, want no scale down", got)
+	}
+	if !next.Equal(end) {
+		t.Errorf("next reconciliation at %v in blackout window, want its end %v", next, end)
+	}
+
+	// Once the window ended, the node pool is scaled down at the next reconciliation
+	sc.config.Blackouts[0].End = now.Add(-time.Minute).Format(time.RFC3339)
+	sc.reconcile(context.Background(), context.Background())
+	if got := provider.scaledTo("default-pool"); len(got) != 1 || got[0] != 0 {
+		t.Errorf("scaled to %v after blackout window, want [0]", got)
+	}
+}
@@ -0,0 +1,112 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/kezhenxu94/bmw-saver/pkg/config"
+	pkgk8s "github.com/kezhenxu94/bmw-saver/pkg/kubernetes"
+	"github.com/kezhenxu94/bmw-saver/pkg/notify"
+	"github.com/kezhenxu94/bmw-saver/pkg/usage"
+)
+
+// initRightSizing initializes the right-sizing recommendations based on configuration, the interval is
+// validated when reading it. The first recommendations are made one interval after they are enabled.
+func (sc *ScalingController) initRightSizing(cfg config.Config) {
+	rightSizing := cfg.RightSizing
+	if rightSizing == nil {
+		sc.rightSizingInterval, sc.nextRightSizing = 0, time.Time{}
+		return
+	}
+	sc.rightSizingInterval, _ = time.ParseDuration(rightSizing.Interval)
+	sc.rightSizingWorkdays = rightSizing.Workdays
+	sc.rightSizingCPUTarget = rightSizing.CPUTarget
+	if sc.nextRightSizing.IsZero() {
+		sc.nextRightSizing = time.Now().Add(sc.rightSizingInterval)
+	}
+}
+
+// observeCPU records the CPU utilization of the node pool for right-sizing, if metrics-server is installed
+func (sc *ScalingController) observeCPU(ctx context.Context, now time.Time, spec config.NodeSpec, nodes []corev1.Node) {
+	if sc.rightSizingInterval <= 0 || sc.client == nil || len(nodes) == 0 {
+		return
+	}
+	cpuUsage, reported, err := pkgk8s.NodePoolCPUUsage(ctx, sc.client, spec.CloudProvider, spec.NodePoolName)
+	if err != nil || reported == 0 {
+		slog.Debug("Failed to get node metrics, not observing CPU utilization", "node_pool", spec.NodePoolName, "error", err)
+		return
+	}
+	var allocatable int64
+	for _, node := range nodes {
+		allocatable += node.Status.Allocatable.Cpu().MilliValue()
+	}
+	if allocatable <= 0 {
+		return
+	}
+	sc.usage.ObserveCPU(spec.NodePoolName, float64(cpuUsage.MilliValue())*float64(len(nodes))/float64(allocatable), now)
+}
+
+// reportRightSizing makes the right-sizing recommendations of the node pools once per interval, shows them
+// in the status and sends them to the notification sinks
+func (sc *ScalingController) reportRightSizing(ctx context.Context, now time.Time, specs []config.NodeSpec) {
+	if sc.rightSizingInterval <= 0 || now.Before(sc.nextRightSizing) {
+		return
+	}
+	sc.nextRightSizing = now.Add(sc.rightSizingInterval)
+
+	for _, spec := range specs {
+		state := sc.schedules[spec.Schedule]
+		if state == nil {
+			continue
+		}
+		pool, ok := state.pools[spec.NodePoolName]
+		if !ok {
+			continue
+		}
+		pool.recommendations = recommendSizes(sc.usage, spec, pool.scaledDownFrom, sc.rightSizingWorkdays, sc.rightSizingCPUTarget)
+		for _, recommendation := range pool.recommendations {
+			slog.Info("Right-sizing recommendation", "node_pool", spec.NodePoolName, "recommendation", recommendation)
+			sc.recordEvent(corev1.EventTypeNormal, eventReasonRightSizingRecommended,
+				"Right-sizing node pool %s: %s", spec.NodePoolName, recommendation)
+			sc.notifier.Notify(ctx, notify.Notification{
+				Kind:     notify.KindRecommendation,
+				NodePool: spec.NodePoolName,
+				Schedule: scheduleLabel(spec.Schedule),
+				Reason:   recommendation,
+			})
+		}
+	}
+}
+
+// recommendSizes returns the recommendations for the node pool from its sizes and CPU utilizations observed on
+// the last workdays, restoredTo is how many nodes it's restored to, 0 if not known. There are none until the
+// node pool was observed on as many workdays.
+func recommendSizes(tracker *usage.Tracker, spec config.NodeSpec, restoredTo, workdays int, cpuTarget float64) []string {
+	var recommendations []string
+	maxSize, ok := tracker.Percentile(spec.NodePoolName, workdays, 100)
+	if !ok {
+		return nil
+	}
+	if restoredTo > maxSize && spec.UsageBasedRestore == nil {
+		recommendations = append(recommendations, fmt.Sprintf(
+			"never above %d nodes during work time on the last %d workdays but restored to %d nodes, consider lowering its restore size or usageBasedRestore",
+			maxSize, workdays, restoredTo))
+	}
+
+	size, _ := tracker.Percentile(spec.NodePoolName, workdays, 95)
+	utilization, ok := tracker.CPUPercentile(spec.NodePoolName, workdays, 95)
+	if ok && cpuTarget > 0 && size > 1 {
+		needed := max(int(math.Ceil(float64(size)*utilization/cpuTarget)), 1)
+		if needed < size {
+			recommendations = append(recommendations, fmt.Sprintf(
+				"95th percentile CPU utilization of %.0f%% on %d nodes during work time on the last %d workdays, %d nodes would run at %.0f%%, consider fewer or smaller nodes",
+				utilization*100, size, workdays, needed, float64(size)*utilization/float64(needed)*100))
+		}
+	}
+	return recommendations
+}
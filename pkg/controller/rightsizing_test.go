@@ -0,0 +1,112 @@
+package controller
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kezhenxu94/bmw-saver/pkg/config"
+	"github.com/kezhenxu94/bmw-saver/pkg/usage"
+)
+
+func TestRecommendSizes(t *testing.T) {
+	tracker, err := usage.NewTracker(context.Background(), nil, "usage")
+	if err != nil {
+		t.Fatalf("NewTracker() error = %v", err)
+	}
+	monday := time.Date(2024, time.June, 3, 12, 0, 0, 0, time.UTC)
+	for day := 0; day < 2; day++ {
+		at := monday.AddDate(0, 0, day)
+		tracker.Observe("oversized-pool", 4, at)
+		tracker.Observe("busy-pool", 4, at)
+		tracker.ObserveCPU("busy-pool", 0.8, at)
+		tracker.Observe("idle-pool", 6, at)
+		tracker.ObserveCPU("idle-pool", 0.15, at)
+	}
+
+	tests := []struct {
+		name       string
+		spec       config.NodeSpec
+		restoredTo int
+		workdays   int
+		want       []string
+	}{
+		{
+			name:       "not enough workdays",
+			spec:       config.NodeSpec{NodePoolName: "oversized-pool"},
+			restoredTo: 8,
+			workdays:   3,
+		},
+		{
+			name:       "restored to more than observed",
+			spec:       config.NodeSpec{NodePoolName: "oversized-pool"},
+			restoredTo: 8,
+			workdays:   2,
+			want:       []string{"never above 4 nodes during work time on the last 2 workdays but restored to 8 nodes"},
+		},
+		{
+			name:       "restored to observed sizes already",
+			spec:       config.NodeSpec{NodePoolName: "oversized-pool", UsageBasedRestore: &config.UsageBasedRestoreConfig{Workdays: 2}},
+			restoredTo: 8,
+			workdays:   2,
+		},
+		{
+			name:       "busy",
+			spec:       config.NodeSpec{NodePoolName: "busy-pool"},
+			restoredTo: 4,
+			workdays:   2,
+		},
+		{
+			name:       "low CPU utilization",
+			spec:       config.NodeSpec{NodePoolName: "idle-pool"},
+			restoredTo: 6,
+			workdays:   2,
+			want:       []string{"95th percentile CPU utilization of 15% on 6 nodes during work time on the last 2 workdays, 2 nodes would run at 45%"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := recommendSizes(tracker, tt.spec, tt.restoredTo, tt.workdays, 0.6)
+			if len(got) != len(tt.want) {
+				t.Fatalf("recommendSizes() = %q, want %d recommendations", got, len(tt.want))
+			}
+			for i, want := range tt.want {
+				if !strings.HasPrefix(got[i], want) {
+					t.Errorf("recommendSizes()[%d] = %q, want prefix %q", i, got[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestReportRightSizing(t *testing.T) {
+	tracker, err := usage.NewTracker(context.Background(), nil, "usage")
+	if err != nil {
+		t.Fatalf("NewTracker() error = %v", err)
+	}
+	now := time.Date(2024, time.June, 4, 12, 0, 0, 0, time.UTC)
+	tracker.Observe("default-pool", 2, now)
+
+	pool := &poolState{scaledDownFrom: 5}
+	sc := &ScalingController{
+		usage:               tracker,
+		rightSizingInterval: 24 * time.Hour,
+		rightSizingWorkdays: 1,
+		nextRightSizing:     now.Add(time.Hour),
+		schedules:           map[string]*scheduleState{"": {pools: map[string]*poolState{"default-pool": pool}}},
+	}
+	specs := []config.NodeSpec{{NodePoolName: "default-pool"}}
+
+	sc.reportRightSizing(context.Background(), now, specs)
+	if len(pool.recommendations) != 0 {
+		t.Errorf("recommendations = %q before the interval, want none", pool.recommendations)
+	}
+	sc.reportRightSizing(context.Background(), now.Add(time.Hour), specs)
+	if len(pool.recommendations) != 1 {
+		t.Errorf("recommendations = %q, want 1", pool.recommendations)
+	}
+	if want := now.Add(25 * time.Hour); !sc.nextRightSizing.Equal(want) {
+		t.Errorf("nextRightSizing = %v, want %v", sc.nextRightSizing, want)
+	}
+}
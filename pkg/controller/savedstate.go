@@ -0,0 +1,134 @@
+package controller
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kezhenxu94/bmw-saver/pkg/audit"
+	"github.com/kezhenxu94/bmw-saver/pkg/config"
+	pkgk8s "github.com/kezhenxu94/bmw-saver/pkg/kubernetes"
+	"github.com/kezhenxu94/bmw-saver/pkg/notify"
+)
+
+// ConfirmStaleRestoreAnnotation confirms restoring a node pool from saved state older than the max age when set
+// to "true" on its control ConfigMap. The confirmation is removed once the node pool is restored.
+const ConfirmStaleRestoreAnnotation = "bmw-saver.io/confirm-stale-restore"
+
+// initSavedState initializes the garbage collection and max age of saved state based on configuration,
+// the durations are validated when reading it
+func (sc *ScalingController) initSavedState(cfg config.Config) {
+	sc.stateGCInterval, sc.maxStateAge = 0, 0
+	if cfg.SavedState == nil {
+		sc.nextStateGC = time.Time{}
+		return
+	}
+	sc.stateGCInterval, _ = time.ParseDuration(cfg.SavedState.GCInterval)
+	if cfg.SavedState.MaxAge != "" {
+		sc.maxStateAge, _ = time.ParseDuration(cfg.SavedState.MaxAge)
+	}
+}
+
+// collectSavedState removes the saved state of the node pools of the shard that are no longer configured,
+// once every GC interval
+func (sc *ScalingController) collectSavedState(ctx context.Context, now time.Time, specs []config.NodeSpec) {
+	if sc.stateGCInterval <= 0 || sc.client == nil || now.Before(sc.nextStateGC) {
+		return
+	}
+	sc.nextStateGC = now.Add(sc.stateGCInterval)
+
+	configured := make(map[string]bool, len(specs))
+	for _, spec := range specs {
+		configured[spec.NodePoolName] = true
+	}
+	store := pkgk8s.NewStateStore(sc.client, os.Getenv("NAMESPACE"))
+	states, err := store.List(ctx)
+	if err != nil {
+		slog.Warn("Failed to list saved state for garbage collection", "error", err)
+		return
+	}
+	for _, state := range states {
+		// Other shards collect the saved state of their node pools
+		if state.Kind != pkgk8s.StateKindNodePool || configured[state.Name] || !sc.shard.Owns(state.Name) {
+			continue
+		}
+		if err := store.Delete(ctx, state.StateKey); err != nil {
+			slog.Warn("Failed to remove saved state of node pool", "node_pool", state.Name, "error", err)
+			continue
+		}
+		slog.Info("Removed saved state of node pool no longer configured", "node_pool", state.Name, "saved_at", state.SavedAt)
+		sc.recordEvent(corev1.EventTypeNormal, eventReasonSavedStateRemoved,
+			"Removed saved state of node pool %s, it's no longer configured", state.Name)
+	}
+}
+
+// awaitingRestoreConfirmation returns true if the node pool would be restored from saved state older than the
+// max age and restoring it isn't confirmed yet. The restore is recorded as blocked, with an Event once per saved
+// state. State saved before versioning has no save time and is restored regardless of its age.
+func (sc *ScalingController) awaitingRestoreConfirmation(ctx context.Context, now time.Time, pool *poolState,
+	spec config.NodeSpec, notification notify.Notification, nodes int) bool {
+	if sc.maxStateAge <= 0 || sc.client == nil || pool.applied == "restore" {
+		return false
+	}
+
+	key := pkgk8s.StateKey{Kind: pkgk8s.StateKindNodePool, Name: spec.NodePoolName}
+	state, ok, err := pkgk8s.NewStateStore(sc.client, os.Getenv("NAMESPACE")).Get(ctx, key)
+	if err != nil {
+		slog.Warn("Failed to check age of saved state of node pool", "node_pool", spec.NodePoolName, "error", err)
+		return false
+	}
+	if !ok || state.SavedAt.IsZero() || !state.RestoredAt.IsZero() || now.Sub(state.SavedAt) <= sc.maxStateAge {
+		pool.staleStateSavedAt = time.Time{}
+		return false
+	}
+	if sc.restoreConfirmed(ctx, spec) {
+		slog.Info("Restore of node pool from stale saved state confirmed", "node_pool", spec.NodePoolName, "saved_at", state.SavedAt)
+		pool.staleStateSavedAt = time.Time{}
+		return false
+	}
+
+	age := now.Sub(state.SavedAt).Truncate(time.Minute)
+	if !pool.staleStateSavedAt.Equal(state.SavedAt) {
+		pool.staleStateSavedAt = state.SavedAt
+		slog.Warn("Restore of node pool waiting for confirmation, its saved state is stale",
+			"node_pool", spec.NodePoolName, "saved_at", state.SavedAt, "max_age", sc.maxStateAge)
+		sc.recordEvent(corev1.EventTypeWarning, eventReasonStaleStatePending,
+			"Restore of node pool %s is waiting for confirmation, its saved state is %s old, annotate configmap/%s%s with %s=true",
+			spec.NodePoolName, age, PoolConfigMapNamePrefix, spec.NodePoolName, ConfirmStaleRestoreAnnotation)
+	}
+	sc.recordAudit(ctx, now, pool, spec, notification.Reason, "restore", nodes, audit.OutcomeBlocked,
+		"saved state is "+age.String()+" old, waiting for confirmation")
+	return true
+}
+
+// restoreConfirmed returns whether restoring the node pool from stale saved state is confirmed on its control ConfigMap
+func (sc *ScalingController) restoreConfirmed(ctx context.Context, spec config.NodeSpec) bool {
+	name := PoolConfigMapNamePrefix + spec.NodePoolName
+	cm, err := sc.client.CoreV1().ConfigMaps(os.Getenv("NAMESPACE")).Get(ctx, name, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		return false
+	}
+	if err != nil {
+		slog.Warn("Failed to check if restore of node pool is confirmed", "node_pool", spec.NodePoolName, "config_map", name, "error", err)
+		return false
+	}
+	confirmed, _ := strconv.ParseBool(cm.Annotations[ConfirmStaleRestoreAnnotation])
+	return confirmed
+}
+
+// consumeRestoreConfirmation removes the confirmation of the node pool, so that restoring it from stale saved
+// state has to be confirmed again
+func (sc *ScalingController) consumeRestoreConfirmation(ctx context.Context, spec config.NodeSpec) {
+	if sc.maxStateAge <= 0 || sc.client == nil {
+		return
+	}
+	if err := sc.annotatePoolConfigMap(ctx, spec, nil, ConfirmStaleRestoreAnnotation); err != nil {
+		slog.Warn("Failed to remove restore confirmation of node pool", "node_pool", spec.NodePoolName, "error", err)
+	}
+}
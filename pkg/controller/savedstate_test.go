@@ -0,0 +1,133 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	"github.com/kezhenxu94/bmw-saver/pkg/config"
+	pkgk8s "github.com/kezhenxu94/bmw-saver/pkg/kubernetes"
+	"github.com/kezhenxu94/bmw-saver/pkg/notify"
+)
+
+const nodePoolStatesPath = "/apis/" + pkgk8s.NodePoolStateGroupVersion + "/namespaces/bmw-saver/nodepoolstates"
+
+func nodePoolState(name string, savedAt time.Time) pkgk8s.NodePoolState {
+	return pkgk8s.NodePoolState{
+		APIVersion: pkgk8s.NodePoolStateGroupVersion,
+		Kind:       pkgk8s.NodePoolStateKind,
+		Metadata:   metav1.ObjectMeta{Name: name, Namespace: "bmw-saver"},
+		Spec: pkgk8s.NodePoolStateSpec{
+			NodePool: name,
+			Version:  pkgk8s.StateVersion,
+			SavedAt:  &metav1.Time{Time: savedAt},
+			Config:   json.RawMessage(`{"nodeCount":3}`),
+		},
+	}
+}
+
+func TestAwaitingRestoreConfirmation(t *testing.T) {
+	t.Setenv("NAMESPACE", "bmw-saver")
+	now := time.Date(2024, time.June, 10, 8, 0, 0, 0, time.UTC)
+	savedAt := now.Add(-72 * time.Hour)
+	tests := []struct {
+		name        string
+		maxAge      time.Duration
+		annotations map[string]string
+		applied     string
+		want        bool
+	}{
+		{name: "no max age", want: false},
+		{name: "saved within max age", maxAge: 96 * time.Hour, want: false},
+		{name: "stale", maxAge: 24 * time.Hour, want: true},
+		{name: "stale and confirmed", maxAge: 24 * time.Hour, annotations: map[string]string{ConfirmStaleRestoreAnnotation: "true"}, want: false},
+		{name: "already restored", maxAge: 24 * time.Hour, applied: "restore", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				switch r.URL.Path {
+				case nodePoolStatesPath + "/default-pool":
+					_ = json.NewEncoder(w).Encode(nodePoolState("default-pool", savedAt))
+				case "/api/v1/namespaces/bmw-saver/configmaps/" + PoolConfigMapNamePrefix + "default-pool":
+					_ = json.NewEncoder(w).Encode(corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+						Name:        PoolConfigMapNamePrefix + "default-pool",
+						Annotations: tt.annotations,
+					}})
+				default:
+					t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			defer server.Close()
+			sc := &ScalingController{
+				client:      kubernetes.NewForConfigOrDie(&rest.Config{Host: server.URL, ContentConfig: rest.ContentConfig{ContentType: "application/json"}}),
+				maxStateAge: tt.maxAge,
+			}
+			spec := config.NodeSpec{NodePoolName: "default-pool"}
+			pool := &poolState{applied: tt.applied}
+
+			if got := sc.awaitingRestoreConfirmation(context.Background(), now, pool, spec, notify.Notification{}, 0); got != tt.want {
+				t.Errorf("awaitingRestoreConfirmation() = %v, want %v", got, tt.want)
+			}
+			if tt.want && !pool.staleStateSavedAt.Equal(savedAt) {
+				t.Errorf("staleStateSavedAt = %v, want %v", pool.staleStateSavedAt, savedAt)
+			}
+		})
+	}
+}
+
+func TestCollectSavedState(t *testing.T) {
+	t.Setenv("NAMESPACE", "bmw-saver")
+	now := time.Date(2024, time.June, 10, 8, 0, 0, 0, time.UTC)
+	var deleted []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v1/namespaces/bmw-saver/configmaps":
+			_ = json.NewEncoder(w).Encode(corev1.ConfigMapList{})
+		case r.Method == http.MethodGet && r.URL.Path == nodePoolStatesPath:
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"items": []pkgk8s.NodePoolState{
+				nodePoolState("default-pool", now.Add(-time.Hour)),
+				nodePoolState("removed-pool", now.Add(-time.Hour)),
+			}})
+		case r.Method == http.MethodDelete && r.URL.Path == nodePoolStatesPath+"/removed-pool":
+			deleted = append(deleted, "removed-pool")
+			_ = json.NewEncoder(w).Encode(metav1.Status{Status: metav1.StatusSuccess})
+		case r.Method == http.MethodDelete:
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	sc := &ScalingController{
+		client:          kubernetes.NewForConfigOrDie(&rest.Config{Host: server.URL, ContentConfig: rest.ContentConfig{ContentType: "application/json"}}),
+		stateGCInterval: 24 * time.Hour,
+	}
+	specs := []config.NodeSpec{{NodePoolName: "default-pool"}}
+
+	sc.collectSavedState(context.Background(), now, specs)
+	if len(deleted) != 1 || deleted[0] != "removed-pool" {
+		t.Errorf("deleted = %v, want [removed-pool]", deleted)
+	}
+	if !sc.nextStateGC.Equal(now.Add(24 * time.Hour)) {
+		t.Errorf("nextStateGC = %v, want %v", sc.nextStateGC, now.Add(24*time.Hour))
+	}
+
+	deleted = nil
+	sc.collectSavedState(context.Background(), now.Add(time.Hour), specs)
+	if len(deleted) != 0 {
+		t.Errorf("deleted = %v before the GC interval, want none", deleted)
+	}
+}
@@ -0,0 +1,70 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/kezhenxu94/bmw-saver/pkg/config"
+	"github.com/kezhenxu94/bmw-saver/pkg/savings"
+)
+
+// initSavings initializes the savings tracker based on configuration. The tracker is kept across
+// configuration changes, so that the savings of node pools scaled down at the time aren't lost.
+func (sc *ScalingController) initSavings(cfg config.Config, opts initOptions) error {
+	if cfg.Savings == nil {
+		sc.savings = nil
+		return nil
+	}
+	sc.currency = cfg.Savings.Currency
+	sc.carbonIntensity = cfg.Savings.CarbonIntensity
+	if sc.savings != nil {
+		return nil
+	}
+
+	store, err := sc.getCacheStore(cfg.Savings.Store)
+	if err == nil {
+		sc.savings, err = savings.NewTracker(context.Background(), store, sc.shard.Name("savings"))
+	}
+	if err != nil {
+		if !opts.logErrors {
+			return fmt.Errorf("failed to create savings tracker: %v", err)
+		}
+		slog.Error("Failed to create savings tracker", "error", err)
+	}
+	return nil
+}
+
+// hourlyNodeCO2 returns the estimated hourly CO2 emissions of a node of the node pool in kg, 0 if not known
+func (sc *ScalingController) hourlyNodeCO2(spec config.NodeSpec) float64 {
+	return spec.NodePower * sc.carbonIntensity / 1000
+}
+
+// countNodes returns the number of nodes of the node pool before it is scaled, -1 if neither savings, the audit
+// log, restore verification, rebalancing nor right-sizing need it or the nodes couldn't be counted
+func (sc *ScalingController) countNodes(ctx context.Context, spec config.NodeSpec) int {
+	if (sc.savings == nil && sc.auditSink == nil && sc.verifyTimeout <= 0 && sc.rebalance == nil && sc.rightSizingInterval <= 0) || sc.nodes == nil {
+		return -1
+	}
+	nodes, err := sc.nodes.NodePoolNodes(ctx, spec.CloudProvider, spec.NodePoolName)
+	if err != nil {
+		slog.Warn("Failed to count nodes of node pool", "node_pool", spec.NodePoolName, "error", err)
+		return -1
+	}
+	return len(nodes)
+}
+
+// saveSavings accumulates the savings until now and persists them, failures are only logged
+func (sc *ScalingController) saveSavings(ctx context.Context, now time.Time) {
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+
+	if sc.savings == nil {
+		return
+	}
+	sc.savings.Update(now)
+	if err := sc.savings.Save(ctx); err != nil {
+		slog.Warn("Failed to save savings", "error", err)
+	}
+}
@@ -3,17 +3,34 @@ package controller
 import (
 	"context"
 	"fmt"
+	"maps"
+	"math/rand/v2"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/kezhenxu94/bmw-saver/pkg/audit"
 	"github.com/kezhenxu94/bmw-saver/pkg/config"
+	"github.com/kezhenxu94/bmw-saver/pkg/hooks"
+	pkgk8s "github.com/kezhenxu94/bmw-saver/pkg/kubernetes"
+	"github.com/kezhenxu94/bmw-saver/pkg/notify"
 	"github.com/kezhenxu94/bmw-saver/pkg/providers"
+	"github.com/kezhenxu94/bmw-saver/pkg/savings"
 	"github.com/kezhenxu94/bmw-saver/pkg/schedule"
+	"github.com/kezhenxu94/bmw-saver/pkg/usage"
 
 	"log/slog"
 
-	"k8s.io/apimachinery/pkg/util/wait"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/sync/errgroup"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
 )
 
 // initOptions contains options for initializing providers
@@ -22,22 +39,281 @@ type initOptions struct {
 	logErrors bool
 }
 
+// defaultManualOverridePriority puts manual overrides above any configured provider priority
+const defaultManualOverridePriority = 1000
+
+// scheduleOverride is a schedule provider taking precedence over the combined providers
+type scheduleOverride struct {
+	provider schedule.Provider
+	priority int
+}
+
+// shutdownGracePeriod is how long in-flight scaling operations may take to finish on shutdown,
+// within the default termination grace period of pods
+const shutdownGracePeriod = 20 * time.Second
+
+// stuckReconcileTimeout is how long a reconciliation may go without progress, or be overdue, before the loop is
+// considered stuck. Scaling a node pool counts as progress, as node pools may be scaled one after another.
+const stuckReconcileTimeout = 15 * time.Minute
+
+// stuckReconcileSlack is how long the rest of a reconciliation may take on top of scaling a node pool, for pool
+// timeouts longer than the stuck reconcile timeout
+const stuckReconcileSlack = 5 * time.Minute
+
+// defaultSafetyPollInterval is the longest time between reconciliations if not configured
+const defaultSafetyPollInterval = 5 * time.Minute
+
+// defaultConcurrency is how many node pools are scaled in parallel if not configured
+const defaultConcurrency = 4
+
+// defaultPoolTimeout is how long scaling a node pool may take if not configured, shorter than
+// the stuck reconciliation timeout so that a hanging cloud API doesn't fail the liveness probe
+const defaultPoolTimeout = 10 * time.Minute
+
+// defaultScheduleName is the name of the top-level schedule, used by node pools without a schedule
+const defaultScheduleName = ""
+
+// scheduleState is a configured schedule with its scaling state
+type scheduleState struct {
+	scheduler schedule.Provider
+	// tiers picks the capacity tier outside work time, nil if no tiers are configured
+	tiers schedule.TierProvider
+	// maxStaleness is how old the schedule data may be before failing safe to work time, 0 disables the check
+	maxStaleness time.Duration
+	// restoreLeadTime is how long before work time the node pools are restored
+	restoreLeadTime time.Duration
+	// scaleDownDelay is how long off time must persist before scaling down
+	scaleDownDelay time.Duration
+	// offSince is when off time started, zero during work time. It is only accessed by reconcile.
+	offSince time.Time
+	// lastDecision is the last logged schedule decision. It is only accessed by reconcile.
+	lastDecision schedule.Decision
+	// restoringWorkloads is whether StatefulSets are still being restored one replica at a time. It is only
+	// accessed by reconcile.
+	restoringWorkloads bool
+	// databasesStartingSince is when the node pools started waiting for their databases to become available,
+	// zero if they don't wait. It is only accessed by reconcile.
+	databasesStartingSince time.Time
+	// sleepPageBackAt is when work time starts again as shown on the sleep pages, computed once per off time.
+	// It is only accessed by reconcile.
+	sleepPageBackAt string
+	// pools are the scaling states of the node pools. It is only accessed by reconcile, which adds
+	// the node pools before they are reconciled in parallel.
+	pools map[string]*poolState
+}
+
+// poolState is the scaling state of a node pool, it is only accessed by the reconciliation of the node pool
+type poolState struct {
+	// desired is the action the schedule asks for, "restore" or the scaled down count
+	desired string
+	// paused is whether the node pool was paused in the last reconciliation
+	paused bool
+	// applied is the last successful action, "restore" or the scaled down count, so that Events are only
+	// recorded when it changes
+	applied string
+	// failures is how many reconciliations in a row failed to scale the node pool, and lastError the last error
+	failures  int
+	lastError string
+	// deferredSince is when the scale down was first deferred for active workloads, zero if it isn't deferred,
+	// and gpuDeferredSince for GPU workloads
+	deferredSince    time.Time
+	gpuDeferredSince time.Time
+	// keptAliveSince is when the scale down was first postponed by keep-alives, zero if it isn't,
+	// and keptAliveUntil when they are checked again
+	keptAliveSince time.Time
+	keptAliveUntil time.Time
+	// boostedUntil is when the boost forcing work time for the node pool ends, zero if it isn't boosted
+	boostedUntil time.Time
+	// idleSince is when the node pool became idle during work time, zero if it isn't, idleAt when it's scaled
+	// down for it, and idleScaledDown whether it's scaled down until pods wait for it. idleTemplate stands for its
+	// nodes while it's scaled down.
+	idleSince      time.Time
+	idleAt         time.Time
+	idleScaledDown bool
+	idleTemplate   *corev1.Node
+	// recommendations are the last right-sizing recommendations for the node pool
+	recommendations []string
+	// lastScaled is when the node pool was last scaled down or restored
+	lastScaled time.Time
+	// converged is the scaled down count the node pool last reached, and drift its resize outside of bmw-saver
+	// since, nil if it didn't drift
+	converged string
+	drift     *driftState
+	// lastAudit identifies the last audit log entry, so that repeated decisions are recorded once
+	lastAudit string
+	// skipped is the action skipped because the node pool is in cooldown, nil if none is
+	skipped *skippedAction
+	// backoff is when the failed action is retried, nil if the last action succeeded
+	backoff *retryBackoff
+	// suspendedUntil is when the node pool is tried again after the circuit breaker suspended it
+	suspendedUntil time.Time
+	// scaledDownFrom is how many nodes the node pool had before it was last scaled down from work time,
+	// 0 if not known, and verification the restore being verified, nil if none is
+	scaledDownFrom int
+	verification   *restoreVerification
+	// rebalanceSince is when the node pool was restored while it waits to be rebalanced, zero if it doesn't
+	rebalanceSince time.Time
+	// pendingApproval is the scale down waiting for approval since pendingSince, empty if none is,
+	// and snoozedUntil when its snooze ends, zero if it isn't snoozed
+	pendingApproval string
+	pendingSince    time.Time
+	snoozedUntil    time.Time
+	// staleStateSavedAt is when the stale saved state was saved whose restore waits for confirmation, zero if none does
+	staleStateSavedAt time.Time
+}
+
+// close stops the background syncs of the schedule providers and capacity tiers, and persists their state
+func (state *scheduleState) close(ctx context.Context) {
+	schedule.Close(ctx, state.scheduler)
+	if closer, ok := state.tiers.(schedule.Closer); ok {
+		closer.Close(ctx)
+	}
+}
+
+// pool returns the state of the node pool, adding it if it doesn't exist yet
+func (state *scheduleState) pool(name string) *poolState {
+	pool, ok := state.pools[name]
+	if !ok {
+		pool = &poolState{}
+		state.pools[name] = pool
+	}
+	return pool
+}
+
 // ScalingController manages node pool scaling based on work hours.
 type ScalingController struct {
-	client    *kubernetes.Clientset
-	config    config.Config
+	client kubernetes.Interface
+	// nodes looks up nodes in the cache of a shared informer, nil without a client
+	nodes  *pkgk8s.NodeLister
+	config config.Config
+	// shard is the share of the node pools scaled by this replica
+	shard     Shard
 	providers map[string]providers.CloudProvider
-	scheduler schedule.Provider
-	mu        sync.RWMutex
+	// databases are the databases stopped during off time, keyed by provider and name
+	databases map[string]providers.Database
+	// databaseStartTimeout is how long node pools wait for their databases to become available
+	databaseStartTimeout time.Duration
+	// schedules are the default schedule and the named schedules, keyed by name
+	schedules map[string]*scheduleState
+	// safetyPollInterval is the longest time between reconciliations
+	safetyPollInterval time.Duration
+	// reconcileJitter is the longest random delay of reconciliations, 0 if disabled
+	reconcileJitter time.Duration
+	// maxBackoff is the longest time a failing node pool is backed off
+	maxBackoff time.Duration
+	// circuitBreaker suspends node pools failing too often, nil if not configured
+	circuitBreaker *circuitBreaker
+	// concurrency is how many node pools are scaled in parallel, and poolTimeout how long scaling each one may take
+	concurrency int
+	poolTimeout time.Duration
+	// wakeUp triggers a reconciliation before the next scheduled one, e.g. after a config change
+	wakeUp chan struct{}
+	// scaledDown are the keys of the scaled down node pools, whose node changes trigger a reconciliation
+	scaledDown atomic.Pointer[map[string]bool]
+	// events records Kubernetes Events about scaling actions on eventRef
+	events           record.EventRecorder
+	eventBroadcaster record.EventBroadcaster
+	eventRef         *corev1.ObjectReference
+	// notifier sends scaling notifications, nil if none are configured
+	notifier *notify.Notifier
+	// failureThreshold is how many reconciliations in a row may fail before a failure notification
+	failureThreshold int
+	// preScaleDownHooks and postRestoreHooks are run around scaling node pools
+	preScaleDownHooks []hooks.Configured
+	postRestoreHooks  []hooks.Configured
+	// scaleDownGuard defers scale downs while workloads are active, nil if not configured
+	scaleDownGuard *scaleDownGuard
+	// savings tracks the realized savings of node pools in currency, nil if not configured
+	savings  *savings.Tracker
+	currency string
+	// carbonIntensity is the carbon intensity of the power grid in gCO2eq/kWh estimating the saved CO2 emissions
+	carbonIntensity float64
+	// usage tracks the sizes of node pools restored to their observed sizes and the utilization for right-sizing,
+	// nil if neither is enabled
+	usage *usage.Tracker
+	// rightSizingInterval is how often right-sizing recommendations are made, 0 if they aren't, nextRightSizing
+	// when they are made next, rightSizingWorkdays over how many workdays and rightSizingCPUTarget for which
+	// CPU utilization
+	rightSizingInterval  time.Duration
+	nextRightSizing      time.Time
+	rightSizingWorkdays  int
+	rightSizingCPUTarget float64
+	// auditSink records scaling decisions in the audit log, nil if not configured
+	auditSink audit.Sink
+	// verifyTimeout is how long restored node pools may take to become ready, 0 if not verified
+	verifyTimeout time.Duration
+	// prepareToSleepTimeout is how long "prepare to sleep" webhooks are waited for before draining nodes, 0 if
+	// they aren't called
+	prepareToSleepTimeout time.Duration
+	// rebalance contains the settings for rebalancing pods after restores, nil if disabled, and rebalanceTimeout
+	// how long restored node pools are waited for
+	rebalance        *config.RebalanceConfig
+	rebalanceTimeout time.Duration
+	// timeToReady is how long node pools took to become ready after their last restore, guarded by metricsMu
+	timeToReady map[string]time.Duration
+	// drainStats are the pods evicted and blocked and the drain durations of the nodes of each node pool, guarded
+	// by metricsMu
+	drainStats map[string]*drainStats
+	metricsMu  sync.Mutex
+	// approvalTimeout is how long scale downs wait for approval, 0 if they wait until approved,
+	// and snoozeFor how long they are postponed when snoozed
+	approvalTimeout time.Duration
+	snoozeFor       time.Duration
+	// maxKeepAlive is how long keep-alive annotations may postpone a scale down, 0 if they are ignored
+	maxKeepAlive time.Duration
+	// boostToken authenticates boost requests, empty if boosts aren't enabled, and maxBoost is the longest boost
+	boostToken string
+	maxBoost   time.Duration
+	// budgetAlertsToken authenticates budget alerts, empty if they aren't enabled, budgetThreshold is the least
+	// exceeded fraction of a GCP budget entering savings mode, savingsModeFor how long it lasts after an alert,
+	// and scaleDownEarlier how much earlier work time ends in it
+	budgetAlertsToken string
+	budgetThreshold   float64
+	savingsModeFor    time.Duration
+	scaleDownEarlier  time.Duration
+	// stateGCInterval is how often the saved state of node pools no longer configured is removed, 0 if it isn't,
+	// nextStateGC when it's removed next, and maxStateAge how old saved state is restored without confirmation,
+	// 0 if of any age
+	stateGCInterval time.Duration
+	nextStateGC     time.Time
+	maxStateAge     time.Duration
+	// slackSigningSecret verifies Slack interactions, empty if Slack messages aren't interactive
+	slackSigningSecret string
+	// ready is set once the first reconciliation completed, and cleared on shutdown
+	ready atomic.Bool
+	// health is why the schedules were unhealthy at the end of the last reconciliation, nil if they were healthy.
+	// Ready reads it instead of asking the schedules, as waiting for sc.mu would block during reconciliations.
+	health atomic.Pointer[error]
+	// stuckAt is when the reconciliation loop is considered stuck if it didn't make progress, in Unix nanoseconds,
+	// and stuckTimeout how long it may go without progress
+	stuckAt      atomic.Int64
+	stuckTimeout atomic.Int64
+	mu           sync.RWMutex
+}
+
+// Options contains the settings of a controller replica, which aren't part of the configuration
+type Options struct {
+	// Shard is the share of the node pools scaled by the replica, the zero value scales all node pools
+	Shard Shard
 }
 
 // NewScalingController creates a new scaling controller with the provided configuration.
-// It initializes cloud providers for each node pool specification.
-func NewScalingController(client *kubernetes.Clientset, cfg config.Config) (*ScalingController, error) {
+// It initializes cloud providers for each node pool specification in the shard of the replica.
+func NewScalingController(client kubernetes.Interface, cfg config.Config, opts Options) (*ScalingController, error) {
+	cfg = opts.Shard.filter(cfg)
 	sc := &ScalingController{
 		client:    client,
 		config:    cfg,
+		shard:     opts.Shard,
 		providers: make(map[string]providers.CloudProvider),
+		wakeUp:    make(chan struct{}, 1),
+	}
+	sc.events, sc.eventBroadcaster, sc.eventRef = newEventRecorder(client)
+	if client != nil {
+		sc.nodes = pkgk8s.NewNodeLister(client)
+		if err := sc.nodes.OnChange(sc.nodesChanged); err != nil {
+			return nil, err
+		}
 	}
 
 	if err := sc.initScheduleProviders(cfg, initOptions{logErrors: false}); err != nil {
@@ -48,90 +324,528 @@ func NewScalingController(client *kubernetes.Clientset, cfg config.Config) (*Sca
 		return nil, err
 	}
 
+	if err := sc.initNotifier(cfg, initOptions{logErrors: false}); err != nil {
+		return nil, err
+	}
+
+	if err := sc.initHooks(cfg, initOptions{logErrors: false}); err != nil {
+		return nil, err
+	}
+
+	sc.initScaleDownGuard(cfg)
+
+	if err := sc.initSavings(cfg, initOptions{logErrors: false}); err != nil {
+		return nil, err
+	}
+	if err := sc.initUsage(cfg, initOptions{logErrors: false}); err != nil {
+		return nil, err
+	}
+	sc.initRightSizing(cfg)
+
+	sc.initAudit(cfg)
+	sc.initRestoreVerification(cfg)
+	sc.initDrain(cfg)
+	sc.initRebalance(cfg)
+	sc.initApproval(cfg)
+	sc.initKeepAlive(cfg)
+	sc.initBoost(cfg)
+	sc.initBudgetAlerts(cfg)
+	sc.initSavedState(cfg)
+	if err := sc.initDatabases(cfg, initOptions{logErrors: false}); err != nil {
+		return nil, err
+	}
+
 	return sc, nil
 }
 
-// initScheduleProviders initializes all schedule providers based on configuration
+// initScheduleProviders initializes the providers of the default and the named schedules based on configuration.
+// The scaling state of schedules that still exist is kept.
 func (sc *ScalingController) initScheduleProviders(cfg config.Config, opts initOptions) error {
+	workSchedules := map[string]config.WorkSchedule{defaultScheduleName: cfg.Schedule}
+	for name, workSchedule := range cfg.Schedules {
+		workSchedules[name] = workSchedule
+	}
+
+	nodePools := make(map[string][]string)
+	for _, spec := range cfg.NodeSpecs {
+		nodePools[spec.Schedule] = append(nodePools[spec.Schedule], spec.NodePoolName)
+	}
+
+	schedules := make(map[string]*scheduleState, len(workSchedules))
+	for name, workSchedule := range workSchedules {
+		state, err := sc.newScheduleState(workSchedule, nodePools[name], opts)
+		if err != nil {
+			// Stop the background syncs of the schedules created so far, the previous ones are kept
+			for created, createdState := range schedules {
+				if sc.schedules[created] != createdState {
+					createdState.close(context.Background())
+				}
+			}
+			if name == defaultScheduleName {
+				return err
+			}
+			return fmt.Errorf("invalid schedule %s: %v", name, err)
+		}
+
+		previous := sc.schedules[name]
+		if state == nil {
+			// Keep the previous providers when the new configuration has none
+			state = previous
+		} else if previous != nil {
+			state.offSince = previous.offSince
+			state.lastDecision = previous.lastDecision
+			state.pools = previous.pools
+		}
+		if state != nil {
+			schedules[name] = state
+		}
+	}
+	// Stop the background syncs of replaced providers
+	for name, previous := range sc.schedules {
+		if schedules[name] != previous {
+			previous.close(context.Background())
+		}
+	}
+	sc.schedules = schedules
+	sc.safetyPollInterval = getSafetyPollInterval(cfg.SafetyPollInterval)
+	sc.reconcileJitter, _ = time.ParseDuration(cfg.ReconcileJitter)
+	sc.maxBackoff = getMaxBackoff(cfg.MaxBackoff)
+	sc.initCircuitBreaker(cfg)
+	sc.concurrency = defaultConcurrency
+	if cfg.Concurrency > 0 {
+		sc.concurrency = cfg.Concurrency
+	}
+	sc.poolTimeout = defaultPoolTimeout
+	if d, err := time.ParseDuration(cfg.PoolTimeout); err == nil && d > 0 {
+		sc.poolTimeout = d
+	}
+	sc.stuckTimeout.Store(int64(max(stuckReconcileTimeout, sc.poolTimeout+stuckReconcileSlack)))
+	return nil
+}
+
+// jitter returns a random delay of the next reconciliation up to the reconcile jitter, so that the
+// transitions of instances with the same schedule are spread out
+func (sc *ScalingController) jitter() time.Duration {
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+
+	if sc.reconcileJitter <= 0 {
+		return 0
+	}
+	return rand.N(sc.reconcileJitter)
+}
+
+// getSafetyPollInterval parses the safety poll interval, falling back to the default
+func getSafetyPollInterval(interval string) time.Duration {
+	if d, err := time.ParseDuration(interval); err == nil && d > 0 {
+		return d
+	}
+	return defaultSafetyPollInterval
+}
+
+// newScheduleState creates the schedule providers of a schedule configuration of the node pools. If it fails, the providers
+// created so far are closed, so that their background syncs don't keep running.
+func (sc *ScalingController) newScheduleState(cfg config.WorkSchedule, nodePools []string, opts initOptions) (state *scheduleState, err error) {
 	var scheduleProviders []schedule.Provider
+	var overrides []scheduleOverride
+	defer func() {
+		if err == nil {
+			return
+		}
+		for _, provider := range scheduleProviders {
+			schedule.Close(context.Background(), provider)
+		}
+		for _, override := range overrides {
+			schedule.Close(context.Background(), override.provider)
+		}
+	}()
+
+	// Providers with a priority take precedence over the combined providers
+	addProvider := func(provider schedule.Provider, priority int) {
+		if priority > 0 {
+			overrides = append(overrides, scheduleOverride{provider: provider, priority: priority})
+			return
+		}
+		scheduleProviders = append(scheduleProviders, provider)
+	}
+
+	cacheStore, err := sc.getCacheStore(cfg.CacheStore)
+	if err != nil {
+		return nil, err
+	}
+
+	var maxStaleness time.Duration
+	if cfg.MaxStaleness != "" {
+		if maxStaleness, err = time.ParseDuration(cfg.MaxStaleness); err != nil {
+			return nil, fmt.Errorf("invalid max staleness: %v", err)
+		}
+	}
+
+	var restoreLeadTime time.Duration
+	if cfg.RestoreLeadTime != "" {
+		if restoreLeadTime, err = time.ParseDuration(cfg.RestoreLeadTime); err != nil {
+			return nil, fmt.Errorf("invalid restore lead time: %v", err)
+		}
+	}
+
+	var scaleDownDelay time.Duration
+	if cfg.ScaleDownDelay != "" {
+		if scaleDownDelay, err = time.ParseDuration(cfg.ScaleDownDelay); err != nil {
+			return nil, fmt.Errorf("invalid scale down delay: %v", err)
+		}
+	}
 
 	// Always add static provider if configured
-	if cfg.Schedule.StartTime != "" && cfg.Schedule.EndTime != "" && cfg.Schedule.TimeZone != "" {
-		workDays := sc.getWorkDays(cfg.Schedule.WorkDays)
-		scheduleProviders = append(scheduleProviders, schedule.NewStaticProvider(
-			cfg.Schedule.StartTime,
-			cfg.Schedule.EndTime,
-			cfg.Schedule.TimeZone,
+	if cfg.StartTime != "" && cfg.EndTime != "" && cfg.TimeZone != "" {
+		workDays := sc.getWorkDays(cfg.WorkDays)
+		staticProvider := schedule.NewStaticProvider(
+			cfg.StartTime,
+			cfg.EndTime,
+			cfg.TimeZone,
 			workDays,
-		))
+		)
+		staticProvider.DayHours = sc.getDayHours(cfg.DayHours)
+		if exceptions := cfg.Exceptions; exceptions != nil {
+			staticProvider.OffDates = toSet(exceptions.OffDates)
+			staticProvider.WorkDates = toSet(exceptions.WorkDates)
+		}
+		scheduleProviders = append(scheduleProviders, staticProvider)
 	}
 
 	// Add Google Calendar provider if configured
-	if cfg.Schedule.GoogleCalendar != nil {
+	if cfg.GoogleCalendar != nil {
 		slog.Info("Using Google Calendar provider")
 
-		syncInterval, err := sc.getSyncInterval(cfg.Schedule.GoogleCalendar.SyncInterval)
+		syncInterval, err := sc.getSyncInterval(cfg.GoogleCalendar.SyncInterval)
 		if err != nil {
 			if opts.logErrors {
 				slog.Error("Invalid sync interval, using default", "error", err)
 			} else {
-				return fmt.Errorf("invalid sync interval: %v", err)
+				return nil, fmt.Errorf("invalid sync interval: %v", err)
 			}
 		}
 
-		cacheDays := sc.getCacheDays(cfg.Schedule.GoogleCalendar.CacheDays)
+		cacheDays := sc.getCacheDays(cfg.GoogleCalendar.CacheDays)
 
-		gcalProvider, err := schedule.NewGoogleCalendarProvider(
-			cfg.Schedule.GoogleCalendar.CredentialsPath,
-			cfg.Schedule.GoogleCalendar.CalendarID,
-			cfg.Schedule.GoogleCalendar.OffTimeEvents,
-			syncInterval,
-			cacheDays,
-		)
+		gcalProvider, err := schedule.NewGoogleCalendarProvider(schedule.GoogleCalendarOptions{
+			CredentialsPath:         cfg.GoogleCalendar.CredentialsPath,
+			Subject:                 cfg.GoogleCalendar.Subject,
+			ServiceAccount:          cfg.GoogleCalendar.ServiceAccount,
+			CalendarID:              cfg.GoogleCalendar.CalendarID,
+			OffTimeEvents:           cfg.GoogleCalendar.OffTimeEvents,
+			SyncInterval:            syncInterval,
+			CacheDays:               cacheDays,
+			OutOfOfficeAttendees:    cfg.GoogleCalendar.OutOfOfficeAttendees,
+			OffTimeWorkingLocations: cfg.GoogleCalendar.OffTimeWorkingLocations,
+			CacheStore:              cacheStore,
+		})
 		if err != nil {
 			if opts.logErrors {
 				slog.Error("Failed to create Google Calendar provider", "error", err)
 			} else {
-				return fmt.Errorf("failed to create Google Calendar provider: %v", err)
+				return nil, fmt.Errorf("failed to create Google Calendar provider: %v", err)
 			}
 		} else {
-			scheduleProviders = append(scheduleProviders, gcalProvider)
+			addProvider(gcalProvider, cfg.GoogleCalendar.Priority)
 		}
 	}
 
-	if cfg.Schedule.ICSCalendar != nil {
+	if cfg.ICSCalendar != nil {
 		syncInterval := 1 * time.Hour
-		if cfg.Schedule.ICSCalendar.SyncInterval != "" {
-			d, err := time.ParseDuration(cfg.Schedule.ICSCalendar.SyncInterval)
+		if cfg.ICSCalendar.SyncInterval != "" {
+			d, err := time.ParseDuration(cfg.ICSCalendar.SyncInterval)
 			if err != nil {
-				return fmt.Errorf("invalid sync interval: %v", err)
+				return nil, fmt.Errorf("invalid sync interval: %v", err)
 			}
 			syncInterval = d
 		}
 
-		icsProvider, err := schedule.NewICSCalendarProvider(
-			cfg.Schedule.ICSCalendar.URL,
-			syncInterval,
-			cfg.Schedule.ICSCalendar.WorkDayPatterns,
-			cfg.Schedule.ICSCalendar.HolidayPatterns,
+		timeZone := cfg.ICSCalendar.TimeZone
+		if timeZone == "" {
+			timeZone = cfg.TimeZone
+		}
+		location, err := time.LoadLocation(timeZone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ICS calendar time zone: %v", err)
+		}
+
+		urls := cfg.ICSCalendar.URLs
+		if cfg.ICSCalendar.URL != "" {
+			urls = append([]string{cfg.ICSCalendar.URL}, urls...)
+		}
+
+		icsProvider, err := schedule.NewICSCalendarProvider(schedule.ICSCalendarOptions{
+			URLs:            urls,
+			SyncInterval:    syncInterval,
+			WorkDayPatterns: cfg.ICSCalendar.WorkDayPatterns,
+			HolidayPatterns: cfg.ICSCalendar.HolidayPatterns,
+			Location:        location,
+			CacheStore:      cacheStore,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create ICS Calendar provider: %v", err)
+		}
+		addProvider(icsProvider, cfg.ICSCalendar.Priority)
+	}
+
+	if cfg.TeamsShifts != nil {
+		slog.Info("Using Microsoft Teams Shifts provider")
+
+		syncInterval, err := sc.getSyncInterval(cfg.TeamsShifts.SyncInterval)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sync interval: %v", err)
+		}
+
+		tenantID := cfg.TeamsShifts.TenantID
+		if tenantID == "" {
+			tenantID = os.Getenv("AZURE_TENANT_ID")
+		}
+		clientID := cfg.TeamsShifts.ClientID
+		if clientID == "" {
+			clientID = os.Getenv("AZURE_CLIENT_ID")
+		}
+
+		shiftsProvider, err := schedule.NewTeamsShiftsProvider(schedule.TeamsShiftsOptions{
+			TenantID:           tenantID,
+			ClientID:           clientID,
+			ClientSecret:       os.Getenv("AZURE_CLIENT_SECRET"),
+			TeamID:             cfg.TeamsShifts.TeamID,
+			SchedulingGroupIDs: cfg.TeamsShifts.SchedulingGroupIDs,
+			SyncInterval:       syncInterval,
+			CacheDays:          sc.getCacheDays(cfg.TeamsShifts.CacheDays),
+			CacheStore:         cacheStore,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Teams Shifts provider: %v", err)
+		}
+		addProvider(shiftsProvider, cfg.TeamsShifts.Priority)
+	}
+
+	if cfg.TeamCalendars != nil {
+		slog.Info("Using Team Calendars provider")
+
+		syncInterval, err := sc.getSyncInterval(cfg.TeamCalendars.SyncInterval)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sync interval: %v", err)
+		}
+
+		timeZone := cfg.TeamCalendars.TimeZone
+		if timeZone == "" {
+			timeZone = cfg.TimeZone
+		}
+		location, err := time.LoadLocation(timeZone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Team Calendars time zone: %v", err)
+		}
+
+		teamCalendarsProvider, err := schedule.NewTeamCalendarsProvider(schedule.TeamCalendarsOptions{
+			BaseURL:        cfg.TeamCalendars.BaseURL,
+			Username:       cfg.TeamCalendars.Username,
+			Token:          os.Getenv("CONFLUENCE_API_TOKEN"),
+			SubCalendarIDs: cfg.TeamCalendars.SubCalendarIDs,
+			EventTypes:     cfg.TeamCalendars.EventTypes,
+			Location:       location,
+			SyncInterval:   syncInterval,
+			CacheDays:      sc.getCacheDays(cfg.TeamCalendars.CacheDays),
+			CacheStore:     cacheStore,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Team Calendars provider: %v", err)
+		}
+		addProvider(teamCalendarsProvider, cfg.TeamCalendars.Priority)
+	}
+
+	if cfg.HRTimeOff != nil {
+		slog.Info("Using HR time off provider", "system", cfg.HRTimeOff.System)
+
+		syncInterval, err := sc.getSyncInterval(cfg.HRTimeOff.SyncInterval)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sync interval: %v", err)
+		}
+
+		timeZone := cfg.HRTimeOff.TimeZone
+		if timeZone == "" {
+			timeZone = cfg.TimeZone
+		}
+		location, err := time.LoadLocation(timeZone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid HR time off time zone: %v", err)
+		}
+
+		timeOffProvider, err := schedule.NewHRTimeOffProvider(schedule.HRTimeOffOptions{
+			System:        cfg.HRTimeOff.System,
+			CompanyDomain: cfg.HRTimeOff.CompanyDomain,
+			APIKey:        os.Getenv("BAMBOOHR_API_KEY"),
+			ClientID:      os.Getenv("PERSONIO_CLIENT_ID"),
+			ClientSecret:  os.Getenv("PERSONIO_CLIENT_SECRET"),
+			Employees:     cfg.HRTimeOff.Employees,
+			Location:      location,
+			SyncInterval:  syncInterval,
+			CacheDays:     sc.getCacheDays(cfg.HRTimeOff.CacheDays),
+			CacheStore:    cacheStore,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create HR time off provider: %v", err)
+		}
+		addProvider(timeOffProvider, cfg.HRTimeOff.Priority)
+	}
+
+	if cfg.Prometheus != nil {
+		duration, err := time.ParseDuration(cfg.Prometheus.Duration)
+		if err != nil {
+			return nil, fmt.Errorf("invalid prometheus duration: %v", err)
+		}
+
+		promProvider, err := schedule.NewPrometheusProvider(
+			cfg.Prometheus.URL,
+			cfg.Prometheus.Query,
+			cfg.Prometheus.Threshold,
+			duration,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Prometheus provider: %v", err)
+		}
+		addProvider(promProvider, cfg.Prometheus.Priority)
+	}
+
+	if cfg.ClusterIdle != nil {
+		duration, err := time.ParseDuration(cfg.ClusterIdle.Duration)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cluster idle duration: %v", err)
+		}
+
+		// Never count bmw-saver itself as user activity
+		excluded := append([]string{os.Getenv("NAMESPACE")}, cfg.ClusterIdle.ExcludedNamespaces...)
+
+		idleProvider, err := schedule.NewClusterIdleProvider(
+			sc.client,
+			duration,
+			cfg.ClusterIdle.CPUThreshold,
+			excluded,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create cluster idle provider: %v", err)
+		}
+		addProvider(idleProvider, cfg.ClusterIdle.Priority)
+	}
+
+	if cfg.Daylight != nil {
+		daylightProvider, err := schedule.NewDaylightProvider(
+			cfg.Daylight.Latitude,
+			cfg.Daylight.Longitude,
+			schedule.Twilight(cfg.Daylight.Twilight),
 		)
 		if err != nil {
-			return fmt.Errorf("failed to create ICS Calendar provider: %v", err)
+			return nil, fmt.Errorf("failed to create daylight provider: %v", err)
 		}
-		scheduleProviders = append(scheduleProviders, icsProvider)
+		addProvider(daylightProvider, cfg.Daylight.Priority)
 	}
 
-	if len(scheduleProviders) == 0 {
+	if cfg.CarbonAware != nil {
+		slog.Info("Using carbon-aware provider", "source", cfg.CarbonAware.Source, "zone", cfg.CarbonAware.Zone)
+
+		syncInterval, err := sc.getSyncInterval(cfg.CarbonAware.SyncInterval)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sync interval: %v", err)
+		}
+
+		timeZone := cfg.CarbonAware.TimeZone
+		if timeZone == "" {
+			timeZone = cfg.TimeZone
+		}
+		location, err := time.LoadLocation(timeZone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid carbon-aware time zone: %v", err)
+		}
+
+		carbonAwareProvider, err := schedule.NewCarbonAwareProvider(schedule.CarbonAwareOptions{
+			Source:       cfg.CarbonAware.Source,
+			Zone:         cfg.CarbonAware.Zone,
+			APIToken:     os.Getenv("ELECTRICITYMAPS_API_TOKEN"),
+			Username:     os.Getenv("WATTTIME_USERNAME"),
+			Password:     os.Getenv("WATTTIME_PASSWORD"),
+			HoursPerDay:  cfg.CarbonAware.HoursPerDay,
+			Location:     location,
+			SyncInterval: syncInterval,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create carbon-aware provider: %v", err)
+		}
+		addProvider(carbonAwareProvider, cfg.CarbonAware.Priority)
+	}
+
+	if cfg.ScheduleCRD != nil {
+		slog.Info("Using Schedule custom resources provider")
+		crdProvider, err := schedule.NewCRDProvider(sc.client, cfg.ScheduleCRD.Namespace, nodePools, sc.triggerReconcile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Schedule custom resources provider: %v", err)
+		}
+		addProvider(crdProvider, cfg.ScheduleCRD.Priority)
+	}
+
+	if cfg.ManualOverride != nil {
+		overrideProvider, err := schedule.NewManualOverrideProvider(
+			sc.client,
+			os.Getenv("NAMESPACE"),
+			cfg.ManualOverride.ConfigMapName,
+			sc.triggerReconcile,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create manual override provider: %v", err)
+		}
+
+		// Manual overrides take precedence over everything else unless configured otherwise
+		priority := cfg.ManualOverride.Priority
+		if priority <= 0 {
+			priority = defaultManualOverridePriority
+		}
+		addProvider(overrideProvider, priority)
+	}
+
+	if len(scheduleProviders) == 0 && len(overrides) == 0 {
 		if opts.logErrors {
 			slog.Error("No schedule providers configured")
-			return nil
+			return nil, nil
 		}
-		return fmt.Errorf("no schedule providers configured")
+		return nil, fmt.Errorf("no schedule providers configured")
+	}
+
+	if cfg.CompositeMode == string(schedule.CompositeModeQuorum) && cfg.Quorum > len(scheduleProviders) {
+		if !opts.logErrors {
+			return nil, fmt.Errorf("quorum %d exceeds the number of schedule providers %d", cfg.Quorum, len(scheduleProviders))
+		}
+		slog.Error("Quorum exceeds the number of schedule providers, it is never work time",
+			"quorum", cfg.Quorum,
+			"providers", len(scheduleProviders),
+		)
+	}
+
+	var tiers schedule.TierProvider
+	if len(cfg.Tiers) > 0 {
+		tierSchedule, err := sc.getTiers(cfg)
+		if err != nil {
+			return nil, err
+		}
+		tiers = tierSchedule
 	}
 
 	// Create composite provider from all configured providers
-	sc.scheduler = schedule.NewCompositeProvider(scheduleProviders...)
-	return nil
+	composite := schedule.NewCompositeProvider(scheduleProviders...)
+	if cfg.CompositeMode != "" {
+		composite.Mode = schedule.CompositeMode(cfg.CompositeMode)
+	}
+	composite.Quorum = cfg.Quorum
+	for _, override := range overrides {
+		composite.AddOverride(override.provider, override.priority)
+	}
+	return &scheduleState{
+		scheduler:       composite,
+		tiers:           tiers,
+		maxStaleness:    maxStaleness,
+		restoreLeadTime: restoreLeadTime,
+		scaleDownDelay:  scaleDownDelay,
+		pools:           make(map[string]*poolState),
+	}, nil
 }
 
 // initCloudProviders initializes cloud providers for each node pool
@@ -141,7 +855,7 @@ func (sc *ScalingController) initCloudProviders(cfg config.Config, opts initOpti
 
 	// Initialize cloud providers
 	for _, spec := range cfg.NodeSpecs {
-		provider, err := providers.NewCloudProvider(spec.CloudProvider)
+		provider, err := providers.NewCloudProvider(spec.CloudProvider, sc.client, sc.nodes)
 		if err != nil {
 			if opts.logErrors {
 				slog.Error("Failed to create provider for node pool",
@@ -157,6 +871,145 @@ func (sc *ScalingController) initCloudProviders(cfg config.Config, opts initOpti
 	return nil
 }
 
+// defaultFailureThreshold is how many reconciliations in a row may fail before a failure notification if not configured
+const defaultFailureThreshold = 3
+
+// initNotifier initializes the notification sinks based on configuration
+func (sc *ScalingController) initNotifier(cfg config.Config, opts initOptions) error {
+	sc.notifier = nil
+	sc.failureThreshold = defaultFailureThreshold
+	sc.slackSigningSecret = ""
+	if cfg.Notifications == nil {
+		return nil
+	}
+	if cfg.Notifications.FailureThreshold > 0 {
+		sc.failureThreshold = cfg.Notifications.FailureThreshold
+	}
+
+	var sinks []notify.Sink
+	addSink := func(name string, sink notify.Sink, err error) error {
+		if err != nil {
+			if !opts.logErrors {
+				return fmt.Errorf("failed to create %s notifications: %v", name, err)
+			}
+			slog.Error("Failed to create notifications", "sink", name, "error", err)
+			return nil
+		}
+		sinks = append(sinks, sink)
+		return nil
+	}
+
+	if slack := cfg.Notifications.Slack; slack != nil {
+		sink, err := notify.NewSlackSink(getEnvDefault(slack.WebhookURL, "SLACK_WEBHOOK_URL"), slack.Interactive)
+		if slack.Interactive && err == nil {
+			sc.slackSigningSecret = getEnvDefault(slack.SigningSecret, "SLACK_SIGNING_SECRET")
+			if sc.slackSigningSecret == "" {
+				err = fmt.Errorf("slack signing secret is required for interactive messages")
+			}
+		}
+		if err := addSink("Slack", sink, err); err != nil {
+			return err
+		}
+	}
+	if webhook := cfg.Notifications.Webhook; webhook != nil {
+		sink, err := notify.NewWebhookSink(getEnvDefault(webhook.URL, "NOTIFICATION_WEBHOOK_URL"), webhook.Body)
+		if err := addSink("webhook", sink, err); err != nil {
+			return err
+		}
+	}
+	if teams := cfg.Notifications.Teams; teams != nil {
+		sink, err := notify.NewTeamsSink(getEnvDefault(teams.WebhookURL, "TEAMS_WEBHOOK_URL"))
+		if err := addSink("Teams", sink, err); err != nil {
+			return err
+		}
+	}
+	if discord := cfg.Notifications.Discord; discord != nil {
+		sink, err := notify.NewDiscordSink(getEnvDefault(discord.WebhookURL, "DISCORD_WEBHOOK_URL"))
+		if err := addSink("Discord", sink, err); err != nil {
+			return err
+		}
+	}
+	if cfg.Notifications.PagerDuty != nil {
+		sink, err := notify.NewPagerDutySink(os.Getenv("PAGERDUTY_ROUTING_KEY"))
+		if err := addSink("PagerDuty", sink, err); err != nil {
+			return err
+		}
+	}
+	if opsgenie := cfg.Notifications.Opsgenie; opsgenie != nil {
+		sink, err := notify.NewOpsgenieSink(os.Getenv("OPSGENIE_API_KEY"), opsgenie.APIURL)
+		if err := addSink("Opsgenie", sink, err); err != nil {
+			return err
+		}
+	}
+
+	if len(sinks) > 0 {
+		sc.notifier = notify.NewNotifier(sinks...)
+	}
+	return nil
+}
+
+// initHooks initializes the hooks run around scaling node pools based on configuration
+func (sc *ScalingController) initHooks(cfg config.Config, opts initOptions) error {
+	sc.preScaleDownHooks, sc.postRestoreHooks = nil, nil
+	if cfg.Hooks == nil {
+		return nil
+	}
+
+	newHooks := func(hookConfigs []config.HookConfig) ([]hooks.Configured, error) {
+		var configured []hooks.Configured
+		for _, hookConfig := range hookConfigs {
+			hook, err := sc.newHook(hookConfig)
+			if err != nil {
+				if !opts.logErrors {
+					return nil, fmt.Errorf("failed to create hook %s: %v", hookConfig.Name, err)
+				}
+				slog.Error("Failed to create hook", "hook", hookConfig.Name, "error", err)
+				continue
+			}
+			timeout, _ := time.ParseDuration(hookConfig.Timeout)
+			configured = append(configured, hooks.Configured{
+				Name:           hookConfig.Name,
+				Hook:           hook,
+				Timeout:        timeout,
+				BlockOnFailure: hookConfig.BlockOnFailure,
+				NodePools:      toSet(hookConfig.NodePools),
+			})
+		}
+		return configured, nil
+	}
+
+	var err error
+	if sc.preScaleDownHooks, err = newHooks(cfg.Hooks.PreScaleDown); err != nil {
+		return err
+	}
+	if sc.postRestoreHooks, err = newHooks(cfg.Hooks.PostRestore); err != nil {
+		return err
+	}
+	return nil
+}
+
+// newHook creates an HTTP, Job or Velero hook
+func (sc *ScalingController) newHook(cfg config.HookConfig) (hooks.Hook, error) {
+	if cfg.HTTP != nil {
+		return hooks.NewHTTPHook(cfg.HTTP.URL, cfg.HTTP.Method, cfg.HTTP.Headers)
+	}
+	if cfg.Job != nil {
+		return hooks.NewJobHook(sc.client, cfg.Name, getEnvDefault(cfg.Job.Namespace, "NAMESPACE"), cfg.Job.Spec)
+	}
+	if velero := cfg.Velero; velero != nil {
+		return hooks.NewVeleroHook(sc.client, cfg.Name, velero.Namespace, velero.IncludedNamespaces, velero.StorageLocation, velero.TTL)
+	}
+	return nil, fmt.Errorf("no http, job or velero configured")
+}
+
+// getEnvDefault returns the value, or the environment variable if it is empty
+func getEnvDefault(value, env string) string {
+	if value == "" {
+		return os.Getenv(env)
+	}
+	return value
+}
+
 // getWorkDays converts WorkDays config to a map
 func (sc *ScalingController) getWorkDays(workDays *config.WorkDays) map[time.Weekday]bool {
 	if workDays == nil {
@@ -179,6 +1032,74 @@ func (sc *ScalingController) getWorkDays(workDays *config.WorkDays) map[time.Wee
 	}
 }
 
+// getDayHours converts per-day hours config to a map keyed by weekday
+func (sc *ScalingController) getDayHours(dayHours map[string]config.TimeRange) map[time.Weekday]schedule.TimeRange {
+	result := make(map[time.Weekday]schedule.TimeRange, len(dayHours))
+	for day, hours := range dayHours {
+		weekday, ok := config.Weekdays[strings.ToLower(day)]
+		if !ok {
+			slog.Warn("Ignoring hours for unknown weekday", "day", day)
+			continue
+		}
+		result[weekday] = schedule.TimeRange{
+			StartTime: hours.StartTime,
+			EndTime:   hours.EndTime,
+		}
+	}
+	return result
+}
+
+// getTiers converts the capacity tiers config to a tier schedule in the schedule time zone.
+// If an on-call tier can't be created, the on-call tiers created so far are closed.
+func (sc *ScalingController) getTiers(cfg config.WorkSchedule) (*schedule.TierSchedule, error) {
+	tiers := make([]schedule.Tier, 0, len(cfg.Tiers))
+	for _, tier := range cfg.Tiers {
+		if tier.OnCall != nil {
+			provider, err := sc.newOnCallProvider(*tier.OnCall)
+			if err != nil {
+				schedule.NewTierSchedule(tiers...).Close(context.Background())
+				return nil, fmt.Errorf("failed to create on-call provider of tier %s: %v", tier.Name, err)
+			}
+			tiers = append(tiers, schedule.Tier{Name: tier.Name, Schedule: provider})
+			continue
+		}
+
+		var days map[time.Weekday]bool
+		if len(tier.Days) > 0 {
+			days = make(map[time.Weekday]bool, len(tier.Days))
+			for _, day := range tier.Days {
+				if weekday, ok := config.Weekdays[strings.ToLower(day)]; ok {
+					days[weekday] = true
+				}
+			}
+		}
+		tiers = append(tiers, schedule.Tier{
+			Name:     tier.Name,
+			Schedule: schedule.NewStaticProvider(tier.StartTime, tier.EndTime, cfg.TimeZone, days),
+		})
+	}
+	return schedule.NewTierSchedule(tiers...), nil
+}
+
+// newOnCallProvider creates the provider of an on-call tier, with the API key from the environment
+func (sc *ScalingController) newOnCallProvider(cfg config.OnCallConfig) (*schedule.OnCallProvider, error) {
+	syncInterval, err := sc.getSyncInterval(cfg.SyncInterval)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sync interval: %v", err)
+	}
+	apiKey := os.Getenv("PAGERDUTY_API_KEY")
+	if cfg.System == "opsgenie" {
+		apiKey = os.Getenv("OPSGENIE_API_KEY")
+	}
+	return schedule.NewOnCallProvider(schedule.OnCallOptions{
+		System:       cfg.System,
+		ScheduleID:   cfg.ScheduleID,
+		APIKey:       apiKey,
+		SyncInterval: syncInterval,
+		CacheDays:    sc.getCacheDays(cfg.CacheDays),
+	})
+}
+
 // getSyncInterval parses and validates the sync interval
 func (sc *ScalingController) getSyncInterval(interval string) (time.Duration, error) {
 	if interval == "" {
@@ -195,20 +1116,153 @@ func (sc *ScalingController) getCacheDays(days int) int {
 	return days
 }
 
+// toSet converts a list of strings to a set
+func toSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, value := range values {
+		set[value] = true
+	}
+	return set
+}
+
+// getCacheStore creates the store for persisting calendar caches, nil if not configured
+func (sc *ScalingController) getCacheStore(cfg *config.CacheStoreConfig) (schedule.CacheStore, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+
+	switch cfg.Type {
+	case "file":
+		store, err := schedule.NewFileCacheStore(cfg.Path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create cache store: %v", err)
+		}
+		return store, nil
+	default:
+		return schedule.NewConfigMapCacheStore(sc.client, os.Getenv("NAMESPACE")), nil
+	}
+}
+
 // Run starts the controller's reconciliation loop.
-// It runs indefinitely until an error occurs.
-func (sc *ScalingController) Run() error {
+// Node pools are reconciled right at the next schedule transition, or after the safety poll interval at the latest.
+// It runs until the context is canceled, then lets in-flight scaling operations finish within a grace period,
+// stops the schedule providers and persists their state.
+func (sc *ScalingController) Run(ctx context.Context) error {
 	slog.Info("Starting scaling controller")
-	wait.Forever(sc.reconcile, time.Minute)
+
+	// Nodes are looked up from the API server until the cache is synced
+	if sc.nodes != nil {
+		go sc.nodes.Start(ctx)
+	}
+
+	// Scaling operations outlive the context by the grace period, so that node pools aren't left half-scaled
+	opCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancel()
+	go func() {
+		<-ctx.Done()
+		timer := time.NewTimer(shutdownGracePeriod)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+			slog.Warn("Shutdown grace period exceeded, aborting in-flight scaling operations")
+			cancel()
+		case <-opCtx.Done():
+		}
+	}()
+
+	for {
+		sc.progressed(time.Now())
+		now := time.Now()
+		next := sc.reconcile(ctx, opCtx).Add(sc.jitter())
+		sc.saveSavings(opCtx, time.Now())
+		sc.saveUsage(opCtx)
+		sc.writeStatus(opCtx, sc.Status(opCtx, now, next))
+		sc.progressed(next)
+		sc.ready.Store(true)
+		slog.Debug("Next reconciliation", "time", next)
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			sc.ready.Store(false)
+			sc.saveSavings(opCtx, time.Now())
+			sc.saveUsage(opCtx)
+			sc.shutdown(opCtx)
+			return nil
+		case <-timer.C:
+		case <-sc.wakeUp:
+			timer.Stop()
+		}
+	}
+}
+
+// shutdown stops the schedule providers and persists their state
+func (sc *ScalingController) shutdown(ctx context.Context) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	slog.Info("Shutting down scaling controller")
+	for _, state := range sc.schedules {
+		state.close(ctx)
+	}
+	// Flush the recorded Events
+	sc.eventBroadcaster.Shutdown()
+}
+
+// Ready returns an error until the configuration is loaded and the first reconciliation completed, and while the
+// schedule providers couldn't refresh their data at the last reconciliation, e.g. until their initial sync
+func (sc *ScalingController) Ready() error {
+	if !sc.ready.Load() {
+		return fmt.Errorf("first reconciliation not completed")
+	}
+
+	if health := sc.health.Load(); health != nil {
+		return *health
+	}
 	return nil
 }
 
+// recordHealth records the health of the schedules for Ready, sc.mu must be held
+func (sc *ScalingController) recordHealth() {
+	var unhealthy error
+	for _, name := range slices.Sorted(maps.Keys(sc.schedules)) {
+		if err := sc.schedules[name].scheduler.Healthy(); err != nil {
+			unhealthy = fmt.Errorf("schedule %s is unhealthy: %v", scheduleLabel(name), err)
+			break
+		}
+	}
+	sc.health.Store(&unhealthy)
+}
+
+// Live returns an error if the reconciliation loop is stuck, i.e. a reconciliation didn't make progress, or the
+// next one is overdue, for longer than the stuck timeout
+func (sc *ScalingController) Live() error {
+	stuckAt := sc.stuckAt.Load()
+	if stuckAt != 0 && time.Now().UnixNano() > stuckAt {
+		return fmt.Errorf("reconciliation loop stuck since %s", time.Unix(0, stuckAt-sc.stuckTimeout.Load()).Format(time.RFC3339))
+	}
+	return nil
+}
+
+// progressed records that the reconciliation loop made progress, or is expected to at the given time, e.g. when the
+// next reconciliation is due
+func (sc *ScalingController) progressed(at time.Time) {
+	timeout := time.Duration(sc.stuckTimeout.Load())
+	if timeout == 0 {
+		timeout = stuckReconcileTimeout
+	}
+	sc.stuckAt.Store(at.Add(timeout).UnixNano())
+}
+
 // UpdateConfig updates the controller's configuration and reinitializes providers.
 // It safely handles concurrent access to shared resources.
 func (sc *ScalingController) UpdateConfig(cfg config.Config) {
 	sc.mu.Lock()
 	defer sc.mu.Unlock()
 
+	cfg = sc.shard.filter(cfg)
+
 	// Initialize providers with error logging
 	if err := sc.initScheduleProviders(cfg, initOptions{logErrors: true}); err != nil {
 		return
@@ -216,61 +1270,731 @@ func (sc *ScalingController) UpdateConfig(cfg config.Config) {
 	if err := sc.initCloudProviders(cfg, initOptions{logErrors: true}); err != nil {
 		return
 	}
+	if err := sc.initNotifier(cfg, initOptions{logErrors: true}); err != nil {
+		return
+	}
+	if err := sc.initHooks(cfg, initOptions{logErrors: true}); err != nil {
+		return
+	}
+	sc.initScaleDownGuard(cfg)
+	if err := sc.initSavings(cfg, initOptions{logErrors: true}); err != nil {
+		return
+	}
+	if err := sc.initUsage(cfg, initOptions{logErrors: true}); err != nil {
+		return
+	}
+	sc.initRightSizing(cfg)
+	sc.initAudit(cfg)
+	sc.initRestoreVerification(cfg)
+	sc.initDrain(cfg)
+	sc.initRebalance(cfg)
+	sc.initApproval(cfg)
+	sc.initKeepAlive(cfg)
+	sc.initBoost(cfg)
+	sc.initBudgetAlerts(cfg)
+	sc.initSavedState(cfg)
+	if err := sc.initDatabases(cfg, initOptions{logErrors: true}); err != nil {
+		return
+	}
 
 	sc.config = cfg
 	slog.Info("Controller configuration updated")
+
+	// Reconcile with the new configuration right away
+	sc.triggerReconcile()
 }
 
-func (sc *ScalingController) reconcile() {
+// reconcile scales the node pools according to their schedules and returns when to reconcile next.
+// No scaling operations are started once ctx is canceled, the started ones run with opCtx.
+func (sc *ScalingController) reconcile(ctx, opCtx context.Context) time.Time {
 	sc.mu.RLock()
 	defer sc.mu.RUnlock()
+	defer sc.recordHealth()
 
-	ctx := context.Background()
 	now := time.Now()
+	next := now.Add(sc.safetyPollInterval)
 
 	slog.Debug("Starting reconciliation loop", "time", now)
+	ctx, opCtx, span := startSpan(ctx, opCtx, "reconcile")
+	defer span.End()
 
-	isWorkTime, err := sc.isWorkTime(now)
-	if err != nil {
-		slog.Error("Error checking work time", "error", err)
-		return
+	if blackout := sc.activeBlackout(now); blackout != nil {
+		span.SetAttributes(attribute.String("blackout", blackout.Reason))
+		slog.Info("In blackout window, skipping scaling",
+			"start", blackout.Start,
+			"end", blackout.End,
+			"reason", blackout.Reason,
+		)
+		if end, err := time.Parse(time.RFC3339, blackout.End); err == nil && end.Before(next) {
+			next = end
+		}
+		return next
 	}
 
-	slog.Debug("Work time check", "is_work_time", isWorkTime)
+	// Savings mode is checked once, so that all node pools see the same
+	mode := sc.savingsMode(ctx, now)
+	if mode != nil {
+		span.SetAttributes(attribute.String("savings_mode", mode.reason))
+		if mode.until.Before(next) {
+			next = mode.until
+		}
+	}
 
+	// Node pools and workloads sharing a schedule are scaled together from a single decision
+	var names []string
+	specsBySchedule := make(map[string][]config.NodeSpec)
+	workloadsBySchedule := make(map[string]workloadSet)
 	for _, spec := range sc.config.NodeSpecs {
-		provider := sc.providers[spec.NodePoolName]
-		if provider == nil {
-			slog.Warn("No provider found for node pool", "node_pool", spec.NodePoolName)
+		if _, ok := specsBySchedule[spec.Schedule]; !ok {
+			names = append(names, spec.Schedule)
+		}
+		specsBySchedule[spec.Schedule] = append(specsBySchedule[spec.Schedule], spec)
+	}
+	addWorkloads := func(schedule string, add func(*workloadSet)) {
+		_, hasNodePools := specsBySchedule[schedule]
+		workloads, ok := workloadsBySchedule[schedule]
+		if !ok && !hasNodePools {
+			names = append(names, schedule)
+		}
+		add(&workloads)
+		workloadsBySchedule[schedule] = workloads
+	}
+	for _, spec := range sc.config.WorkloadSpecs {
+		addWorkloads(spec.Schedule, func(w *workloadSet) { w.specs = append(w.specs, spec) })
+	}
+	for _, page := range sc.config.SleepPages {
+		addWorkloads(page.Schedule, func(w *workloadSet) { w.sleepPages = append(w.sleepPages, page) })
+	}
+	for _, database := range sc.config.Databases {
+		addWorkloads(database.Schedule, func(w *workloadSet) { w.databases = append(w.databases, database) })
+	}
+	if sc.config.GitOps != nil {
+		for _, app := range sc.config.GitOps.ArgoCDApplications {
+			addWorkloads(app.Schedule, func(w *workloadSet) { w.argoCDApplications = append(w.argoCDApplications, app) })
+		}
+		for _, resource := range sc.config.GitOps.FluxResources {
+			addWorkloads(resource.Schedule, func(w *workloadSet) { w.fluxResources = append(w.fluxResources, resource) })
+		}
+	}
+
+	// Node pools are scaled in parallel by the workers, their schedules are only checked once the node pools are done
+	var workers errgroup.Group
+	workers.SetLimit(sc.concurrency)
+	var states []*scheduleState
+	for _, name := range names {
+		state := sc.schedules[name]
+		if state == nil {
+			slog.Warn("No schedule found for node pools", "schedule", scheduleLabel(name))
 			continue
 		}
+		sc.reconcileSchedule(ctx, opCtx, now, name, state, specsBySchedule[name], workloadsBySchedule[name], mode, &workers)
+		states = append(states, state)
+	}
+	_ = workers.Wait()
+	sc.watchScaledDown(specsBySchedule)
+	sc.reportRightSizing(opCtx, now, sc.config.NodeSpecs)
+	sc.collectSavedState(opCtx, now, sc.config.NodeSpecs)
 
-		if isWorkTime {
-			// During work hours, restore from saved config
-			if err := provider.RestoreNodePool(ctx, spec.NodePoolName); err != nil {
-				if providers.IsNoSavedStateError(err) {
-					slog.Warn("No saved state found for node pool", "node_pool", spec.NodePoolName)
-				} else {
-					slog.Error("Error restoring node pool",
-						"node_pool", spec.NodePoolName,
-						"error", err,
-					)
-				}
+	for _, state := range states {
+		if t := state.nextReconcile(ctx, now, next); t.Before(next) {
+			next = t
+		}
+		for _, t := range []time.Time{sc.nextDeferralEnd(now, state), state.nextCooldownEnd(now), state.nextRetry(now), state.nextVerification(now), state.nextRebalance(now), sc.nextApprovalDeadline(now, state), state.nextKeepAliveEnd(now), state.nextBoostEnd(now), state.nextIdleScaleDown(now), state.nextWorkloadRestore(now), sc.nextDatabaseCheck(now, state), mode.nextEarlierEnd(ctx, state, now, next)} {
+			if !t.IsZero() && t.Before(next) {
+				next = t
 			}
+		}
+	}
+	if t := sc.nextMaintenanceEnd(now); !t.IsZero() && t.Before(next) {
+		next = t
+	}
+	return next
+}
+
+// reconcileSchedule scales the workloads of a schedule, then its node pools according to its decision, each one
+// by a worker. mode is the savings mode the cluster is in, nil if it isn't.
+func (sc *ScalingController) reconcileSchedule(ctx, opCtx context.Context, now time.Time, name string, state *scheduleState, specs []config.NodeSpec, workloads workloadSet, mode *savingsMode, workers *errgroup.Group) {
+	decideCtx, span := tracer.Start(ctx, "decide", trace.WithAttributes(attribute.String("schedule", scheduleLabel(name))))
+	decision, err := state.decide(decideCtx, now)
+	if err == nil {
+		decision, err = mode.endWorkEarlier(decideCtx, state, now, decision)
+	}
+	span.SetAttributes(attribute.Bool("is_work_time", decision.IsWorkTime), attribute.String("provider", decision.Provider))
+	endSpan(span, err)
+	if err != nil {
+		slog.Error("Error checking work time", "schedule", scheduleLabel(name), "error", err)
+		return
+	}
+	isWorkTime := decision.IsWorkTime
+
+	if decision != state.lastDecision {
+		slog.Info("Schedule decision changed",
+			"schedule", scheduleLabel(name),
+			"is_work_time", decision.IsWorkTime,
+			"provider", decision.Provider,
+			"reason", decision.Reason,
+			"detail", decision.Detail,
+		)
+		state.lastDecision = decision
+	} else {
+		slog.Debug("Work time check", "schedule", scheduleLabel(name), "is_work_time", isWorkTime)
+	}
+
+	var tier string
+	if !isWorkTime && state.tiers != nil {
+		if tier, err = state.tiers.Tier(ctx, now); err != nil {
+			slog.Error("Error checking capacity tier", "schedule", scheduleLabel(name), "error", err)
+			return
+		}
+	}
+
+	if isWorkTime {
+		state.offSince, state.sleepPageBackAt = time.Time{}, ""
+	} else {
+		state.restoringWorkloads, state.databasesStartingSince = false, time.Time{}
+		if state.offSince.IsZero() {
+			state.offSince = now
+		}
+		// Wait until off time persisted for the whole delay, e.g. for someone working late, except in savings mode
+		if offFor := now.Sub(state.offSince); offFor < state.scaleDownDelay && mode == nil {
+			slog.Info("Off time started, waiting for scale down delay",
+				"schedule", scheduleLabel(name),
+				"off_since", state.offSince,
+				"scale_down_delay", state.scaleDownDelay,
+			)
+			return
+		}
+	}
+
+	if !workloads.empty() && ctx.Err() == nil {
+		// Databases are started before the workloads and node pools using them are restored, and stopped after
+		// the workloads are scaled down. Sleep pages are shown while the workloads are scaled down.
+		if isWorkTime && !sc.startDatabases(opCtx, now, name, state, workloads.databases, decision) {
+			return
+		}
+		state.restoringWorkloads = sc.reconcileWorkloads(opCtx, name, workloads, decision)
+		if !isWorkTime {
+			sc.stopDatabases(opCtx, name, workloads.databases, decision)
+			sc.showSleepPages(opCtx, now, name, state, workloads.sleepPages, decision)
+		} else if !state.restoringWorkloads {
+			sc.hideSleepPages(opCtx, name, workloads.sleepPages, decision)
+		}
+		sc.progressed(time.Now())
+	}
+
+	for _, spec := range specs {
+		// The node pool states are added before the workers start, as they only access their own
+		pool := state.pool(spec.NodePoolName)
+		workers.Go(func() error {
+			if ctx.Err() != nil {
+				slog.Info("Shutting down, skipping node pool", "node_pool", spec.NodePoolName)
+				return nil
+			}
+			poolCtx, cancel := context.WithTimeout(opCtx, sc.poolTimeout)
+			defer cancel()
+			sc.reconcileNodePool(ctx, poolCtx, now, name, pool, spec, decision, tier, mode)
+			sc.progressed(time.Now())
+			return nil
+		})
+	}
+}
+
+// reconcileNodePool scales a node pool according to the decision of its schedule.
+// No scaling operations are started once ctx is canceled, the started ones run with opCtx.
+func (sc *ScalingController) reconcileNodePool(ctx, opCtx context.Context, now time.Time, name string, pool *poolState, spec config.NodeSpec, decision schedule.Decision, tier string, mode *savingsMode) {
+	ctx, opCtx, span := startSpan(ctx, opCtx, "reconcileNodePool", trace.WithAttributes(
+		attribute.String("node_pool", spec.NodePoolName),
+		attribute.String("cloud_provider", spec.CloudProvider),
+		attribute.String("schedule", scheduleLabel(name)),
+		attribute.Bool("is_work_time", decision.IsWorkTime),
+	))
+	defer span.End()
+
+	pool.paused = sc.isPaused(ctx, spec)
+	if pool.paused {
+		slog.Info("Node pool is paused, skipping", "node_pool", spec.NodePoolName)
+		return
+	}
+
+	// Boosted node pools have work time whatever their schedule says
+	boost, boostedUntil := sc.boost(ctx, now, spec)
+	pool.boostedUntil = boostedUntil
+	if !boostedUntil.IsZero() && !decision.IsWorkTime {
+		slog.Info("Node pool is boosted, forcing work time", "node_pool", spec.NodePoolName, "until", boostedUntil)
+		decision = boost
+	}
+
+	// In savings mode, node pools with a savings mode count are kept at it instead of being restored, unless boosted
+	var savingsModeCount *int32
+	if mode != nil && decision.IsWorkTime && boostedUntil.IsZero() && spec.SavingsModeCount != nil {
+		slog.Info("In savings mode, keeping node pool at savings mode count", "node_pool", spec.NodePoolName, "count", *spec.SavingsModeCount)
+		savingsModeCount = spec.SavingsModeCount
+		decision = mode.keptSmall()
+	}
+
+	// Idle node pools are scaled down during work time as well, until pods wait for them
+	if decision.IsWorkTime && boostedUntil.IsZero() {
+		if idle, ok := sc.idleScaleDown(ctx, now, pool, spec); ok {
+			decision = idle
+		}
+	} else {
+		pool.resetIdle()
+	}
+
+	provider := sc.providers[spec.NodePoolName]
+	if provider == nil {
+		slog.Warn("No provider found for node pool", "node_pool", spec.NodePoolName)
+		return
+	}
+
+	notification := notify.Notification{
+		NodePool: spec.NodePoolName,
+		Schedule: scheduleLabel(name),
+		Reason:   decisionSummary(decision),
+	}
+
+	if decision.IsWorkTime {
+		pool.desired = "restore"
+		pool.deferredSince, pool.gpuDeferredSince = time.Time{}, time.Time{}
+		pool.keptAliveSince, pool.keptAliveUntil = time.Time{}, time.Time{}
+		pool.drift = nil
+		pool.pendingApproval = ""
+		if pool.suspended(now, spec) || pool.backingOff(now, spec, "restore") || sc.inCooldown(ctx, now, pool, spec, "restore") {
+			return
+		}
+
+		nodes := -1
+		if pool.applied != "restore" {
+			nodes = sc.countNodes(ctx, spec)
+		}
+		if sc.awaitingRestoreConfirmation(opCtx, now, pool, spec, notification, nodes) {
+			return
+		}
+
+		// During work hours, restore from saved config, to the observed size of the node pool if smaller
+		restoreCtx, restoreSpan := tracer.Start(opCtx, "RestoreNodePool")
+		var err error
+		restored := "its saved configuration"
+		count, limited := sc.restoreCount(spec)
+		if sized, ok := provider.(providers.SizedRestorer); ok && limited {
+			restoreSpan.SetAttributes(attribute.Int("count", int(count)))
+			restored = fmt.Sprintf("its saved configuration with at most %d nodes", count)
+			err = sized.RestoreNodePoolTo(restoreCtx, spec.NodePoolName, count)
 		} else {
-			// During off hours, scale down to specified count
-			if err := provider.ScaleNodePool(ctx, spec.NodePoolName, spec.OffTimeCount); err != nil {
-				slog.Error("Error scaling node pool",
+			err = provider.RestoreNodePool(restoreCtx, spec.NodePoolName)
+		}
+		endSpan(restoreSpan, err)
+		if err != nil {
+			if providers.IsNoSavedStateError(err) {
+				slog.Warn("No saved state found for node pool", "node_pool", spec.NodePoolName)
+			} else {
+				slog.Error("Error restoring node pool",
 					"node_pool", spec.NodePoolName,
-					"desired_count", spec.OffTimeCount,
 					"error", err,
 				)
+				sc.recordEvent(corev1.EventTypeWarning, eventReasonRestoreFailed,
+					"Failed to restore node pool %s: %v", spec.NodePoolName, err)
+				sc.recordAudit(opCtx, now, pool, spec, notification.Reason, "restore", nodes, audit.OutcomeFailed, err.Error())
+				sc.recordNodePoolStatus(opCtx, spec, pkgk8s.NodePoolScaling{Time: now, Restore: true, Err: err})
+				sc.reportFailure(opCtx, pool, notification, err)
+				sc.backOff(opCtx, now, pool, notification, "restore")
+			}
+			return
+		}
+		sc.reportSuccess(opCtx, pool, notification)
+		if pool.applied != "restore" {
+			pool.applied = "restore"
+			pool.lastScaled = now
+			sc.savings.Restored(spec.NodePoolName, now)
+			sc.recordAudit(opCtx, now, pool, spec, notification.Reason, "restore", nodes, audit.OutcomeSucceeded, "")
+			sc.recordNodePoolStatus(opCtx, spec, pkgk8s.NodePoolScaling{Time: now, Restore: true})
+			sc.recordEvent(corev1.EventTypeNormal, eventReasonRestored,
+				"Restored node pool %s to %s, %s", spec.NodePoolName, restored, notification.Reason)
+			sc.startVerification(now, pool)
+			sc.startRebalance(now, pool)
+			sc.consumeApproval(opCtx, spec)
+			sc.consumeRestoreConfirmation(opCtx, spec)
+			sc.releaseNodes(opCtx, spec)
+			restored := notification
+			restored.Kind = notify.KindRestored
+			sc.notifier.Notify(opCtx, restored)
+			// Failures of post restore hooks are only logged, the node pool is restored already
+			_ = hooks.Run(opCtx, sc.postRestoreHooks, hooks.Event{
+				Phase:    hooks.PhasePostRestore,
+				NodePool: spec.NodePoolName,
+				Schedule: notification.Schedule,
+				Reason:   notification.Reason,
+			})
+		}
+		sc.verifyRestore(opCtx, now, pool, spec, notification)
+		sc.rebalanceRestored(opCtx, now, pool, spec)
+		sc.observeUsage(ctx, now, pool, spec)
+		return
+	}
+
+	// During off hours, scale down to the count of the current tier, or the off-time count
+	count := spec.OffTimeCount
+	if tierCount, ok := spec.TierCounts[tier]; ok {
+		count = tierCount
+	}
+	if savingsModeCount != nil {
+		count = *savingsModeCount
+	}
+	notification.Count = count
+	notification.Tier = tier
+	applied := strconv.Itoa(int(count))
+	pool.desired = applied
+	pool.verification = nil
+	pool.rebalanceSince = time.Time{}
+	if sc.drifted(opCtx, now, pool, spec, notification, applied, count) {
+		return
+	}
+	if pool.suspended(now, spec) || pool.backingOff(now, spec, applied) || sc.inCooldown(ctx, now, pool, spec, applied) {
+		return
+	}
+
+	// Node pools under maintenance aren't touched until the window ends, not even to reapply the count
+	if window, end := sc.activeMaintenance(now, spec.NodePoolName); window != nil {
+		if pool.applied != applied {
+			slog.Info("Node pool in maintenance window, postponing scale down",
+				"node_pool", spec.NodePoolName,
+				"until", end,
+				"reason", window.Reason,
+			)
+			detail := fmt.Sprintf("in maintenance window until %s", end.Format(time.RFC3339))
+			if window.Reason != "" {
+				detail += ": " + window.Reason
 			}
+			sc.recordEvent(corev1.EventTypeNormal, eventReasonScaleDownBlocked,
+				"Scale down of node pool %s blocked: %s", spec.NodePoolName, detail)
+			sc.recordAudit(opCtx, now, pool, spec, notification.Reason, applied, -1, audit.OutcomeBlocked, detail)
 		}
+		return
+	}
+
+	// Guards and hooks only run when the node pool is about to be scaled, not on every reconciliation
+	nodes := -1
+	if pool.applied != applied {
+		nodes = sc.countNodes(ctx, spec)
+		if keepAlives := sc.keptAlive(ctx, now, pool, spec); len(keepAlives) > 0 {
+			kept := make([]string, 0, len(keepAlives))
+			for _, keepAlive := range keepAlives {
+				kept = append(kept, keepAlive.String())
+			}
+			slog.Info("Node pool kept alive, postponing scale down",
+				"node_pool", spec.NodePoolName,
+				"until", pool.keptAliveUntil,
+				"keep_alives", kept,
+			)
+			if pool.keptAliveSince.Equal(now) {
+				sc.recordEvent(corev1.EventTypeNormal, eventReasonScaleDownDeferred,
+					"Scale down of node pool %s postponed by keep-alives: %s", spec.NodePoolName, strings.Join(kept, ", "))
+				sc.recordAudit(opCtx, now, pool, spec, notification.Reason, applied, nodes, audit.OutcomeDeferred,
+					"kept alive: "+strings.Join(kept, ", "))
+			}
+			return
+		}
+		if gpuWorkloads := sc.deferForGPUWorkloads(ctx, now, pool, spec, notification); len(gpuWorkloads) > 0 {
+			slog.Info("GPU workloads on node pool, deferring scale down",
+				"node_pool", spec.NodePoolName,
+				"deferred_since", pool.gpuDeferredSince,
+				"workloads", gpuWorkloads,
+			)
+			if pool.gpuDeferredSince.Equal(now) {
+				sc.recordEvent(corev1.EventTypeNormal, eventReasonScaleDownDeferred,
+					"Scale down of node pool %s deferred for GPU workloads: %s", spec.NodePoolName, strings.Join(gpuWorkloads, ", "))
+				sc.recordAudit(opCtx, now, pool, spec, notification.Reason, applied, nodes, audit.OutcomeDeferred,
+					"GPU workloads: "+strings.Join(gpuWorkloads, ", "))
+			}
+			return
+		}
+		if active := sc.deferScaleDown(ctx, now, pool, spec); len(active) > 0 {
+			slog.Info("Active workloads on node pool, deferring scale down",
+				"node_pool", spec.NodePoolName,
+				"deferred_since", pool.deferredSince,
+				"workloads", active,
+			)
+			if pool.deferredSince.Equal(now) {
+				sc.recordEvent(corev1.EventTypeNormal, eventReasonScaleDownDeferred,
+					"Scale down of node pool %s deferred for active workloads: %s", spec.NodePoolName, strings.Join(active, ", "))
+				sc.recordAudit(opCtx, now, pool, spec, notification.Reason, applied, nodes, audit.OutcomeDeferred,
+					"active workloads: "+strings.Join(active, ", "))
+			}
+			return
+		}
+
+		if blocking := sc.blockingDisruptionBudgets(ctx, spec); len(blocking) > 0 {
+			slog.Warn("Scale down blocked by pod disruption budgets", "node_pool", spec.NodePoolName, "pod_disruption_budgets", blocking)
+			sc.recordEvent(corev1.EventTypeWarning, eventReasonScaleDownBlocked,
+				"Scale down of node pool %s blocked: %s", spec.NodePoolName, strings.Join(blocking, ", "))
+			sc.recordAudit(opCtx, now, pool, spec, notification.Reason, applied, nodes, audit.OutcomeBlocked, strings.Join(blocking, ", "))
+			return
+		}
+
+		if sc.awaitingApproval(opCtx, now, pool, spec, notification, applied, count, nodes) {
+			return
+		}
+
+		err := hooks.Run(opCtx, sc.preScaleDownHooks, hooks.Event{
+			Phase:    hooks.PhasePreScaleDown,
+			NodePool: spec.NodePoolName,
+			Schedule: notification.Schedule,
+			Count:    count,
+			Reason:   notification.Reason,
+		})
+		if err != nil {
+			slog.Warn("Scale down blocked by hook", "node_pool", spec.NodePoolName, "error", err)
+			sc.recordEvent(corev1.EventTypeWarning, eventReasonScaleDownBlocked,
+				"Scale down of node pool %s blocked: %v", spec.NodePoolName, err)
+			sc.recordAudit(opCtx, now, pool, spec, notification.Reason, applied, nodes, audit.OutcomeBlocked, err.Error())
+			return
+		}
+	}
+
+	drainCtx := pkgk8s.WithDrainOptions(opCtx, sc.drainOptions(spec))
+	drainCtx, scaleSpan := tracer.Start(drainCtx, "ScaleNodePool", trace.WithAttributes(attribute.Int("count", int(count))))
+	err := provider.ScaleNodePool(drainCtx, spec.NodePoolName, count)
+	endSpan(scaleSpan, err)
+	if err != nil {
+		slog.Error("Error scaling node pool",
+			"node_pool", spec.NodePoolName,
+			"desired_count", count,
+			"tier", tier,
+			"error", err,
+		)
+		sc.recordEvent(corev1.EventTypeWarning, eventReasonScaleDownFailed,
+			"Failed to scale node pool %s to %d nodes: %v", spec.NodePoolName, count, err)
+		sc.recordAudit(opCtx, now, pool, spec, notification.Reason, applied, nodes, audit.OutcomeFailed, err.Error())
+		sc.recordNodePoolStatus(opCtx, spec, pkgk8s.NodePoolScaling{Time: now, Count: count, Err: err})
+		sc.reportFailure(opCtx, pool, notification, err)
+		sc.backOff(opCtx, now, pool, notification, applied)
+		return
+	}
+	sc.reportSuccess(opCtx, pool, notification)
+	pool.deferredSince, pool.gpuDeferredSince = time.Time{}, time.Time{}
+	pool.keptAliveSince, pool.keptAliveUntil = time.Time{}, time.Time{}
+	if pool.applied != applied {
+		// Only a scale down from work time tells the size of the node pool at work time, not one between tiers
+		if pool.applied == "restore" && nodes >= 0 {
+			pool.scaledDownFrom = nodes
+		}
+		pool.applied = applied
+		pool.lastScaled = now
+		if nodes >= 0 {
+			sc.savings.ScaledDown(spec.NodePoolName, nodes-int(count), spec.HourlyNodeCost, sc.hourlyNodeCO2(spec), now)
+		}
+		sc.consumeApproval(opCtx, spec)
+		sc.recordAudit(opCtx, now, pool, spec, notification.Reason, applied, nodes, audit.OutcomeSucceeded, "")
+		sc.recordNodePoolStatus(opCtx, spec, pkgk8s.NodePoolScaling{Time: now, Count: count})
+		message := "Scaled node pool %s to %d nodes, %s"
+		args := []interface{}{spec.NodePoolName, count, notification.Reason}
+		if tier != "" {
+			message += " (tier %s)"
+			args = append(args, tier)
+		}
+		sc.recordEvent(corev1.EventTypeNormal, eventReasonScaledDown, message, args...)
+		notification.Kind = notify.KindScaledDown
+		sc.notifier.Notify(opCtx, notification)
+	}
+}
+
+// reportFailure counts a failed scaling of a node pool, and sends a failure notification
+// once it failed in more reconciliations in a row than the failure threshold
+func (sc *ScalingController) reportFailure(ctx context.Context, pool *poolState, notification notify.Notification, err error) {
+	pool.failures++
+	pool.lastError = err.Error()
+	if pool.failures == sc.failureThreshold+1 {
+		notification.Kind = notify.KindFailure
+		notification.Error = err.Error()
+		notification.Failures = pool.failures
+		sc.notifier.Notify(ctx, notification)
+	}
+}
+
+// reportSuccess resets the failures of a node pool, and sends a recovery notification
+// if a failure notification was sent before, e.g. to resolve an incident
+func (sc *ScalingController) reportSuccess(ctx context.Context, pool *poolState, notification notify.Notification) {
+	failures, suspended := pool.failures, !pool.suspendedUntil.IsZero()
+	pool.failures = 0
+	pool.lastError = ""
+	pool.backoff = nil
+	pool.suspendedUntil = time.Time{}
+	if failures > sc.failureThreshold || suspended {
+		notification.Kind = notify.KindRecovered
+		notification.Failures = failures
+		sc.notifier.Notify(ctx, notification)
 	}
 }
 
-func (sc *ScalingController) isWorkTime(now time.Time) (bool, error) {
-	ctx := context.Background()
-	return sc.scheduler.IsWorkTime(ctx, now)
+// nextReconcile returns when the schedule may need to scale its node pools next, no later than deadline:
+// at its next transition, ahead of it by the restore lead time, when the scale down delay is over,
+// when the capacity tier changes or when its data becomes stale. Failed checks are retried after a minute.
+func (state *scheduleState) nextReconcile(ctx context.Context, now, deadline time.Time) time.Time {
+	next := deadline
+	earlier := func(t time.Time) {
+		if t.After(now) && t.Before(next) {
+			next = t
+		}
+	}
+	horizon := deadline.Sub(now)
+
+	if state.scheduler == nil {
+		return next
+	}
+
+	change, ok, err := schedule.NextChange(ctx, state.scheduler, now, horizon)
+	if err != nil {
+		slog.Error("Error checking next schedule transition", "error", err)
+		earlier(now.Add(time.Minute))
+	} else if ok {
+		earlier(change)
+	}
+
+	if state.restoreLeadTime > 0 {
+		change, ok, err := schedule.NextChange(ctx, state.scheduler, now.Add(state.restoreLeadTime), horizon)
+		if err != nil {
+			slog.Error("Error checking next schedule transition", "error", err)
+			earlier(now.Add(time.Minute))
+		} else if ok {
+			earlier(change.Add(-state.restoreLeadTime))
+		}
+	}
+
+	if !state.offSince.IsZero() {
+		earlier(state.offSince.Add(state.scaleDownDelay))
+	}
+
+	if state.tiers != nil {
+		change, ok, err := state.tiers.NextChange(ctx, now, horizon)
+		if err != nil {
+			slog.Error("Error checking next capacity tier change", "error", err)
+			earlier(now.Add(time.Minute))
+		} else if ok {
+			earlier(change)
+		}
+	}
+
+	if lastSync := state.scheduler.LastSync(); state.maxStaleness > 0 && !lastSync.IsZero() {
+		earlier(lastSync.Add(state.maxStaleness))
+	}
+
+	return next
+}
+
+// scheduleLabel returns the name of a schedule for logs, "default" for the top-level schedule
+func scheduleLabel(name string) string {
+	if name == defaultScheduleName {
+		return "default"
+	}
+	return name
+}
+
+// Explain returns the decision of the named schedule at the given time, with the provider that made it and
+// what matched. An empty name is the default schedule, the zero time is now. Like reconcile, it fails safe to work
+// time on stale data and takes the restore lead time into account. Observers only decide now, they are left out at
+// other times.
+func (sc *ScalingController) Explain(ctx context.Context, name string, t time.Time) (schedule.Decision, error) {
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+
+	state := sc.schedules[name]
+	if state == nil {
+		return schedule.Decision{}, fmt.Errorf("schedule %s not found", scheduleLabel(name))
+	}
+	if t.IsZero() {
+		return state.decide(ctx, time.Now())
+	}
+	return state.explain(ctx, t, false)
+}
+
+// Preview returns the upcoming work/off transitions of the named schedule after from,
+// an empty name is the default schedule
+func (sc *ScalingController) Preview(ctx context.Context, name string, from time.Time, opts schedule.PreviewOptions) ([]schedule.Transition, error) {
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+
+	state := sc.schedules[name]
+	if state == nil {
+		return nil, fmt.Errorf("schedule %s not found", scheduleLabel(name))
+	}
+	return schedule.Preview(ctx, state.scheduler, from, opts)
+}
+
+// activeBlackout returns the blackout window containing now, if any
+func (sc *ScalingController) activeBlackout(now time.Time) *config.BlackoutWindow {
+	for i, blackout := range sc.config.Blackouts {
+		start, err := time.Parse(time.RFC3339, blackout.Start)
+		if err != nil {
+			continue
+		}
+		end, err := time.Parse(time.RFC3339, blackout.End)
+		if err != nil {
+			continue
+		}
+		if !now.Before(start) && now.Before(end) {
+			return &sc.config.Blackouts[i]
+		}
+	}
+	return nil
+}
+
+// decide returns the schedule decision at the given time and why it was made
+func (state *scheduleState) decide(ctx context.Context, now time.Time) (schedule.Decision, error) {
+	return state.explain(ctx, now, true)
+}
+
+// explain is decide at now, unless it isn't current. Observers are left out of decisions at other times than now,
+// asking them would disturb what they track, e.g. since when the cluster is idle.
+func (state *scheduleState) explain(ctx context.Context, now time.Time, current bool) (schedule.Decision, error) {
+	if err := state.scheduler.Healthy(); err != nil {
+		slog.Warn("Schedule provider is unhealthy", "error", err)
+	}
+
+	// Never make scaling decisions off stale calendar data, keep the cluster running instead
+	if lastSync := state.scheduler.LastSync(); state.maxStaleness > 0 && !lastSync.IsZero() && now.Sub(lastSync) > state.maxStaleness {
+		slog.Error("Schedule data is stale, failing safe to work time",
+			"last_sync", lastSync,
+			"max_staleness", state.maxStaleness,
+		)
+		return schedule.Decision{
+			IsWorkTime: true,
+			Reason:     fmt.Sprintf("schedule data last synced at %s is older than %v", lastSync.Format(time.RFC3339), state.maxStaleness),
+		}, nil
+	}
+
+	if state.restoreLeadTime <= 0 {
+		if !current {
+			return schedule.ExplainUnobserved(ctx, state.scheduler, now)
+		}
+		return schedule.Explain(ctx, state.scheduler, now)
+	}
+
+	// Restore ahead of work time so nodes are Ready when it starts,
+	// scale-down stays aligned to the real end of work time
+	decision, soon, err := state.explainAhead(ctx, now, now.Add(state.restoreLeadTime), current)
+	if err != nil {
+		return schedule.Decision{}, err
+	}
+	if decision.IsWorkTime || !soon.IsWorkTime {
+		return decision, nil
+	}
+	slog.Debug("Work time starts within restore lead time", "restore_lead_time", state.restoreLeadTime)
+	soon.Reason = fmt.Sprintf("work time starts within restore lead time %v: %s", state.restoreLeadTime, soon.Reason)
+	return soon, nil
+}
+
+// explainAhead returns the decisions at now and ahead, leaving out observers unless now is current
+func (state *scheduleState) explainAhead(ctx context.Context, now, ahead time.Time, current bool) (schedule.Decision, schedule.Decision, error) {
+	if current {
+		return schedule.ExplainAhead(ctx, state.scheduler, now, ahead)
+	}
+	decision, err := schedule.ExplainUnobserved(ctx, state.scheduler, now)
+	if err != nil {
+		return schedule.Decision{}, schedule.Decision{}, err
+	}
+	soon, err := schedule.ExplainUnobserved(ctx, state.scheduler, ahead)
+	if err != nil {
+		return schedule.Decision{}, schedule.Decision{}, err
+	}
+	return decision, soon, nil
 }
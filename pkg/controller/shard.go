@@ -0,0 +1,99 @@
+package controller
+
+import (
+	"fmt"
+	"hash/fnv"
+
+	"github.com/kezhenxu94/bmw-saver/pkg/config"
+)
+
+// Shard is the share of the node pools scaled by a controller replica, by the hash of the node pool names
+type Shard struct {
+	// Index is the shard of the replica, from 0 to Count-1
+	Index int
+	// Count is the number of shards, a single shard has all node pools if it's 0 or 1
+	Count int
+}
+
+// Validate returns an error if the index isn't one of the shards
+func (s Shard) Validate() error {
+	if s.Count < 0 {
+		return fmt.Errorf("shard count must not be negative: %d", s.Count)
+	}
+	if s.Index < 0 || (s.Count > 1 && s.Index >= s.Count) || (s.Count <= 1 && s.Index != 0) {
+		return fmt.Errorf("shard index %d out of range for %d shards", s.Index, s.Count)
+	}
+	return nil
+}
+
+// Owns returns whether the node pool is in the shard
+func (s Shard) Owns(nodePoolName string) bool {
+	if s.Count <= 1 {
+		return true
+	}
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(nodePoolName))
+	return int(hash.Sum32()%uint32(s.Count)) == s.Index
+}
+
+// Name returns name suffixed with the shard index, so that the shards don't share resources by the name,
+// e.g. the status ConfigMap. The name is unchanged with a single shard.
+func (s Shard) Name(name string) string {
+	if s.Count <= 1 {
+		return name
+	}
+	return fmt.Sprintf("%s-shard-%d", name, s.Index)
+}
+
+// filter returns the configuration with the node specs of the node pools in the shard only, and the workload
+// specs, GitOps resources, databases and sleep pages in the shard by their names
+func (s Shard) filter(cfg config.Config) config.Config {
+	if s.Count <= 1 {
+		return cfg
+	}
+	specs := make([]config.NodeSpec, 0, len(cfg.NodeSpecs))
+	for _, spec := range cfg.NodeSpecs {
+		if s.Owns(spec.NodePoolName) {
+			specs = append(specs, spec)
+		}
+	}
+	cfg.NodeSpecs = specs
+	workloads := make([]config.WorkloadSpec, 0, len(cfg.WorkloadSpecs))
+	for _, spec := range cfg.WorkloadSpecs {
+		if s.Owns(spec.String()) {
+			workloads = append(workloads, spec)
+		}
+	}
+	cfg.WorkloadSpecs = workloads
+	databases := make([]config.DatabaseSpec, 0, len(cfg.Databases))
+	for _, database := range cfg.Databases {
+		if s.Owns(database.String()) {
+			databases = append(databases, database)
+		}
+	}
+	cfg.Databases = databases
+	pages := make([]config.SleepPageSpec, 0, len(cfg.SleepPages))
+	for _, page := range cfg.SleepPages {
+		if s.Owns(page.Namespace + "/" + page.Ingress) {
+			pages = append(pages, page)
+		}
+	}
+	cfg.SleepPages = pages
+	if cfg.GitOps != nil {
+		gitOps := *cfg.GitOps
+		gitOps.ArgoCDApplications = nil
+		for _, app := range cfg.GitOps.ArgoCDApplications {
+			if s.Owns(app.Namespace + "/" + app.Name) {
+				gitOps.ArgoCDApplications = append(gitOps.ArgoCDApplications, app)
+			}
+		}
+		gitOps.FluxResources = nil
+		for _, resource := range cfg.GitOps.FluxResources {
+			if s.Owns(resource.Namespace + "/" + resource.Name) {
+				gitOps.FluxResources = append(gitOps.FluxResources, resource)
+			}
+		}
+		cfg.GitOps = &gitOps
+	}
+	return cfg
+}
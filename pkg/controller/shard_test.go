@@ -0,0 +1,68 @@
+package controller
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/kezhenxu94/bmw-saver/pkg/config"
+)
+
+func TestShard(t *testing.T) {
+	var specs []config.NodeSpec
+	for i := 0; i < 100; i++ {
+		specs = append(specs, config.NodeSpec{NodePoolName: fmt.Sprintf("pool-%d", i)})
+	}
+	cfg := config.Config{NodeSpecs: specs}
+
+	// Every node pool is in exactly one of the shards
+	owners := make(map[string]int)
+	for index := 0; index < 3; index++ {
+		shard := Shard{Index: index, Count: 3}
+		filtered := shard.filter(cfg)
+		if len(filtered.NodeSpecs) == 0 {
+			t.Errorf("Shard %d has no node pools", index)
+		}
+		for _, spec := range filtered.NodeSpecs {
+			owners[spec.NodePoolName]++
+		}
+	}
+	for _, spec := range specs {
+		if owners[spec.NodePoolName] != 1 {
+			t.Errorf("Node pool %s is in %d shards, want 1", spec.NodePoolName, owners[spec.NodePoolName])
+		}
+	}
+	if len(cfg.NodeSpecs) != 100 {
+		t.Errorf("Filtering modified the configuration")
+	}
+
+	if got := (Shard{}).filter(cfg); len(got.NodeSpecs) != 100 {
+		t.Errorf("Single shard has %d node pools, want 100", len(got.NodeSpecs))
+	}
+	if got := (Shard{}).Name("bmw-saver-status"); got != "bmw-saver-status" {
+		t.Errorf("Name() = %s, want bmw-saver-status", got)
+	}
+	if got := (Shard{Index: 1, Count: 3}).Name("bmw-saver-status"); got != "bmw-saver-status-shard-1" {
+		t.Errorf("Name() = %s, want bmw-saver-status-shard-1", got)
+	}
+}
+
+func TestShardValidate(t *testing.T) {
+	tests := []struct {
+		shard   Shard
+		wantErr bool
+	}{
+		{shard: Shard{}},
+		{shard: Shard{Index: 0, Count: 1}},
+		{shard: Shard{Index: 2, Count: 3}},
+		{shard: Shard{Index: 3, Count: 3}, wantErr: true},
+		{shard: Shard{Index: -1, Count: 3}, wantErr: true},
+		{shard: Shard{Index: 1, Count: 1}, wantErr: true},
+		{shard: Shard{Index: 0, Count: -1}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		if err := tt.shard.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("Validate(%+v) error = %v, wantErr %v", tt.shard, err, tt.wantErr)
+		}
+	}
+}
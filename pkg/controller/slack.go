@@ -0,0 +1,103 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/kezhenxu94/bmw-saver/pkg/config"
+	"github.com/kezhenxu94/bmw-saver/pkg/notify"
+)
+
+const (
+	// slackInteractionMaxAge is how long the buttons of a Slack message waiting for approval can be clicked
+	slackInteractionMaxAge = 24 * time.Hour
+	// slackResponseTimeout is how long updating the Slack message of an interaction may take
+	slackResponseTimeout = 10 * time.Second
+)
+
+// SlackInteractionsHandler serves the clicks on the Approve and Snooze buttons of the Slack messages of
+// scale downs waiting for approval. The scale down is approved or snoozed with the annotations on the
+// control ConfigMap of the node pool, and the message is replaced with who approved or snoozed it.
+func (sc *ScalingController) SlackInteractionsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		sc.mu.RLock()
+		secret, snoozeFor := sc.slackSigningSecret, sc.snoozeFor
+		sc.mu.RUnlock()
+		if secret == "" || sc.client == nil {
+			http.Error(w, "slack interactions aren't enabled", http.StatusNotFound)
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+		if err != nil {
+			http.Error(w, "failed to read request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		now := time.Now()
+		if err := notify.VerifySlackRequest(secret, r.Header, body, now); err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		interaction, err := notify.ParseSlackInteraction(body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		text, err := sc.handleSlackInteraction(r.Context(), now, interaction, snoozeFor)
+		if err != nil {
+			slog.Error("Failed to handle Slack interaction", "node_pool", interaction.NodePool, "action", interaction.Action, "error", err)
+			text = fmt.Sprintf(":warning: Failed to %s scale down of node pool %s: %v", interaction.Action, interaction.NodePool, err)
+		}
+		w.WriteHeader(http.StatusOK)
+
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), slackResponseTimeout)
+			defer cancel()
+			if err := notify.RespondSlack(ctx, interaction.ResponseURL, text); err != nil {
+				slog.Error("Failed to respond to Slack interaction", "node_pool", interaction.NodePool, "error", err)
+			}
+		}()
+	})
+}
+
+// handleSlackInteraction approves or snoozes the scale down of the node pool of the interaction, and returns
+// the text replacing the Slack message. The node pool may be scaled by another shard, it picks the annotations
+// up in its next reconciliation.
+func (sc *ScalingController) handleSlackInteraction(ctx context.Context, now time.Time, interaction notify.SlackInteraction, snoozeFor time.Duration) (string, error) {
+	if now.Sub(interaction.AskedAt) > slackInteractionMaxAge {
+		return fmt.Sprintf("Approval of scale down of node pool %s expired, approve it with `kubectl annotate configmap %s%s %s=true`",
+			interaction.NodePool, PoolConfigMapNamePrefix, interaction.NodePool, ApproveScaleDownAnnotation), nil
+	}
+
+	spec := config.NodeSpec{NodePoolName: interaction.NodePool}
+	var text string
+	switch interaction.Action {
+	case notify.SlackActionApprove:
+		if err := sc.annotatePoolConfigMap(ctx, spec, map[string]string{ApproveScaleDownAnnotation: "true"}, SnoozeScaleDownAnnotation); err != nil {
+			return "", err
+		}
+		text = fmt.Sprintf(":white_check_mark: Scale down of node pool %s approved by %s", interaction.NodePool, interaction.User)
+	case notify.SlackActionSnooze:
+		until := now.Add(snoozeFor).UTC()
+		if err := sc.annotatePoolConfigMap(ctx, spec, map[string]string{SnoozeScaleDownAnnotation: until.Format(time.RFC3339)}); err != nil {
+			return "", err
+		}
+		text = fmt.Sprintf(":zzz: Scale down of node pool %s snoozed until %s by %s", interaction.NodePool, until.Format(time.RFC3339), interaction.User)
+	default:
+		return "", fmt.Errorf("unsupported action: %s", interaction.Action)
+	}
+
+	slog.Info("Scale down of node pool approved or snoozed from Slack", "node_pool", interaction.NodePool, "action", interaction.Action, "user", interaction.User)
+	sc.triggerReconcile()
+	return text, nil
+}
@@ -0,0 +1,118 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"html/template"
+	"log/slog"
+	"os"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/kezhenxu94/bmw-saver/pkg/config"
+	pkgk8s "github.com/kezhenxu94/bmw-saver/pkg/kubernetes"
+	"github.com/kezhenxu94/bmw-saver/pkg/schedule"
+)
+
+// sleepPageTemplate is the page showing the message of a sleep page, unless the page is configured
+var sleepPageTemplate = template.Must(template.New("sleep-page").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Sleeping</title>
+<style>body{font-family:sans-serif;display:flex;align-items:center;justify-content:center;height:100vh;margin:0;color:#333}</style>
+</head>
+<body><p>{{.Message}}</p></body>
+</html>
+`))
+
+// sleepPageData is passed to the templates of sleep pages
+type sleepPageData struct {
+	// BackAt is when work time starts again, empty if unknown
+	BackAt string
+	// Message is the rendered message, only for the page template
+	Message template.HTML
+}
+
+// showSleepPages points the Ingresses of a schedule at their sleep pages during off time, once the workloads
+// behind them are scaled down
+func (sc *ScalingController) showSleepPages(ctx context.Context, now time.Time, name string, state *scheduleState, pages []config.SleepPageSpec, decision schedule.Decision) {
+	if sc.client == nil || len(pages) == 0 {
+		return
+	}
+	if state.sleepPageBackAt == "" {
+		state.sleepPageBackAt = sleepPageBackAt(ctx, state.scheduler, now)
+	}
+	namespace := os.Getenv("NAMESPACE")
+	for _, page := range pages {
+		ingress := page.Namespace + "/" + page.Ingress
+		html, err := renderSleepPage(page, state.sleepPageBackAt)
+		if err != nil {
+			slog.Error("Error rendering sleep page", "ingress", ingress, "error", err)
+			continue
+		}
+		shown, err := pkgk8s.ShowSleepPage(ctx, sc.client, namespace, page.Namespace, page.Ingress, page.Image, html)
+		if err != nil {
+			slog.Error("Error showing sleep page", "ingress", ingress, "error", err)
+			sc.recordEvent(corev1.EventTypeWarning, eventReasonScaleDownFailed,
+				"Failed to show sleep page of ingress %s: %v", ingress, err)
+		} else if shown {
+			slog.Info("Showing sleep page", "ingress", ingress, "schedule", scheduleLabel(name))
+			sc.recordEvent(corev1.EventTypeNormal, eventReasonSleepPageShown,
+				"Showing sleep page of ingress %s, %s", ingress, decisionSummary(decision))
+		}
+	}
+}
+
+// hideSleepPages points the Ingresses of a schedule back at their Services at work time, once the workloads
+// behind them are restored
+func (sc *ScalingController) hideSleepPages(ctx context.Context, name string, pages []config.SleepPageSpec, decision schedule.Decision) {
+	if sc.client == nil {
+		return
+	}
+	namespace := os.Getenv("NAMESPACE")
+	for _, page := range pages {
+		ingress := page.Namespace + "/" + page.Ingress
+		hidden, err := pkgk8s.HideSleepPage(ctx, sc.client, namespace, page.Namespace, page.Ingress)
+		if err != nil {
+			slog.Error("Error hiding sleep page", "ingress", ingress, "error", err)
+			sc.recordEvent(corev1.EventTypeWarning, eventReasonRestoreFailed,
+				"Failed to hide sleep page of ingress %s: %v", ingress, err)
+		} else if hidden {
+			slog.Info("Hid sleep page", "ingress", ingress, "schedule", scheduleLabel(name))
+			sc.recordEvent(corev1.EventTypeNormal, eventReasonSleepPageHidden,
+				"Hid sleep page of ingress %s, %s", ingress, decisionSummary(decision))
+		}
+	}
+}
+
+// sleepPageBackAt returns when work time starts again, empty if the schedule can't be previewed
+func sleepPageBackAt(ctx context.Context, scheduler schedule.Provider, now time.Time) string {
+	transitions, err := schedule.Preview(ctx, scheduler, now, schedule.PreviewOptions{Count: 1})
+	if err != nil || len(transitions) == 0 {
+		slog.Debug("Unknown end of off time for sleep pages", "error", err)
+		return ""
+	}
+	return transitions[0].Time.Format("Mon 15:04 MST")
+}
+
+// renderSleepPage renders the page of the sleep page spec, the templates are validated when reading the config
+func renderSleepPage(page config.SleepPageSpec, backAt string) (string, error) {
+	data := sleepPageData{BackAt: backAt}
+	var message bytes.Buffer
+	if err := template.Must(template.New("message").Parse(page.Message)).Execute(&message, data); err != nil {
+		return "", err
+	}
+	data.Message = template.HTML(message.String())
+
+	tmpl := sleepPageTemplate
+	if page.HTML != "" {
+		tmpl = template.Must(template.New("page").Parse(page.HTML))
+	}
+	var html bytes.Buffer
+	if err := tmpl.Execute(&html, data); err != nil {
+		return "", err
+	}
+	return html.String(), nil
+}
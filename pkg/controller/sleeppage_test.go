@@ -0,0 +1,53 @@
+package controller
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kezhenxu94/bmw-saver/pkg/config"
+)
+
+func TestRenderSleepPage(t *testing.T) {
+	message := "This environment is sleeping{{if .BackAt}}, back at {{.BackAt}}{{end}}."
+	tests := []struct {
+		name   string
+		page   config.SleepPageSpec
+		backAt string
+		want   string
+	}{
+		{
+			name:   "default page with back at",
+			page:   config.SleepPageSpec{Message: message},
+			backAt: "Tue 09:00 CET",
+			want:   "<p>This environment is sleeping, back at Tue 09:00 CET.</p>",
+		},
+		{
+			name: "default page without back at",
+			page: config.SleepPageSpec{Message: message},
+			want: "<p>This environment is sleeping.</p>",
+		},
+		{
+			name:   "escaped message",
+			page:   config.SleepPageSpec{Message: "Back at {{.BackAt}}"},
+			backAt: "<script>",
+			want:   "<p>Back at &lt;script&gt;</p>",
+		},
+		{
+			name:   "custom page",
+			page:   config.SleepPageSpec{Message: "Zzz", HTML: "<h1>{{.Message}}</h1><i>{{.BackAt}}</i>"},
+			backAt: "Mon 08:00 UTC",
+			want:   "<h1>Zzz</h1><i>Mon 08:00 UTC</i>",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := renderSleepPage(tt.page, tt.backAt)
+			if err != nil {
+				t.Fatalf("renderSleepPage() error = %v", err)
+			}
+			if !strings.Contains(got, tt.want) {
+				t.Errorf("renderSleepPage() = %s, want it to contain %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,230 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/kezhenxu94/bmw-saver/pkg/schedule"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// StatusConfigMapName is the ConfigMap the controller writes its status to after every reconciliation
+	StatusConfigMapName = "bmw-saver-status"
+	// statusDataKey is the key of the status in the status ConfigMap
+	statusDataKey = "status.yaml"
+)
+
+// Status is the state of the controller, written to the status ConfigMap
+type Status struct {
+	// LastReconcile is when the node pools were last reconciled, and NextReconcile when they are reconciled next
+	LastReconcile time.Time `json:"lastReconcile"`
+	NextReconcile time.Time `json:"nextReconcile"`
+	// Blackout is the reason of the active blackout window, if any
+	Blackout string `json:"blackout,omitempty"`
+	// Schedules are the current decisions of the schedules
+	Schedules map[string]schedule.Decision `json:"schedules"`
+	NodePools map[string]NodePoolStatus    `json:"nodePools"`
+	// Savings are the realized savings of all node pools, nil if savings aren't tracked
+	Savings *SavingsStatus `json:"savings,omitempty"`
+}
+
+// SavingsStatus are realized savings, the node hours saved by scaling down and their cost
+type SavingsStatus struct {
+	NodeHours float64 `json:"nodeHours"`
+	Cost      float64 `json:"cost"`
+	Currency  string  `json:"currency,omitempty"`
+	// CO2 is the estimated CO2 emissions saved in kg
+	CO2 float64 `json:"co2,omitempty"`
+}
+
+// NodePoolStatus is the scaling state of a node pool
+type NodePoolStatus struct {
+	Schedule string `json:"schedule"`
+	Paused   bool   `json:"paused,omitempty"`
+	// Desired is the action the schedule asks for, "restore" or "scale down to <count> nodes"
+	Desired string `json:"desired,omitempty"`
+	// ActualCount is the number of nodes in the node pool, nil if they couldn't be counted
+	ActualCount *int `json:"actualCount,omitempty"`
+	// LastAction is the last successful action, and LastScaled when it was taken
+	LastAction string    `json:"lastAction,omitempty"`
+	LastScaled time.Time `json:"lastScaled,omitempty"`
+	// LastError is the error of the last failed action, until it succeeds
+	LastError string `json:"lastError,omitempty"`
+	Failures  int    `json:"failures,omitempty"`
+	// RetryAt is when a failed action is retried after backing off
+	RetryAt *time.Time `json:"retryAt,omitempty"`
+	// SuspendedUntil is when the node pool is tried again after the circuit breaker suspended it
+	SuspendedUntil *time.Time `json:"suspendedUntil,omitempty"`
+	// Skipped is the action skipped during the cooldown, until the time in SkippedUntil
+	Skipped      string     `json:"skipped,omitempty"`
+	SkippedUntil *time.Time `json:"skippedUntil,omitempty"`
+	// DeferredSince is when the scale down was deferred for active workloads
+	DeferredSince *time.Time `json:"deferredSince,omitempty"`
+	// GPUDeferredSince is when the scale down was deferred for GPU workloads
+	GPUDeferredSince *time.Time `json:"gpuDeferredSince,omitempty"`
+	// BoostedUntil is when the boost forcing work time for the node pool ends
+	BoostedUntil *time.Time `json:"boostedUntil,omitempty"`
+	// IdleSince is when the node pool became idle during work time, it's scaled down once idle for long enough
+	IdleSince *time.Time `json:"idleSince,omitempty"`
+	// KeptAliveUntil is when the keep-alives postponing the scale down are checked again
+	KeptAliveUntil *time.Time `json:"keptAliveUntil,omitempty"`
+	// PendingApproval is the scale down waiting for approval, e.g. "scale down to 0 nodes"
+	PendingApproval string `json:"pendingApproval,omitempty"`
+	// Drift is the resize of the scaled down node pool outside of bmw-saver, it is left alone until the next transition
+	Drift string `json:"drift,omitempty"`
+	// Recommendations are the last right-sizing recommendations for the node pool
+	Recommendations []string `json:"recommendations,omitempty"`
+	// MaintenanceUntil is when the maintenance window of the node pool ends, scale downs are postponed until then
+	MaintenanceUntil *time.Time `json:"maintenanceUntil,omitempty"`
+	// Savings are the realized savings of the node pool
+	Savings *SavingsStatus `json:"savings,omitempty"`
+}
+
+// Status returns the state of the controller as of the last reconciliation
+func (sc *ScalingController) Status(ctx context.Context, lastReconcile, nextReconcile time.Time) Status {
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+
+	status := Status{
+		LastReconcile: lastReconcile,
+		NextReconcile: nextReconcile,
+		Schedules:     make(map[string]schedule.Decision),
+		NodePools:     make(map[string]NodePoolStatus),
+	}
+	if blackout := sc.activeBlackout(lastReconcile); blackout != nil {
+		status.Blackout = blackout.Reason
+	}
+
+	for name, state := range sc.schedules {
+		status.Schedules[scheduleLabel(name)] = state.lastDecision
+	}
+
+	for _, spec := range sc.config.NodeSpecs {
+		poolStatus := NodePoolStatus{Schedule: scheduleLabel(spec.Schedule)}
+		if state := sc.schedules[spec.Schedule]; state != nil {
+			if pool, ok := state.pools[spec.NodePoolName]; ok {
+				poolStatus.Paused = pool.paused
+				poolStatus.LastError = pool.lastError
+				poolStatus.Failures = pool.failures
+				poolStatus.LastScaled = pool.lastScaled
+				if pool.desired != "" {
+					poolStatus.Desired = actionLabel(pool.desired)
+				}
+				if pool.applied != "" {
+					poolStatus.LastAction = actionLabel(pool.applied)
+				}
+				if pool.backoff != nil {
+					poolStatus.RetryAt = &pool.backoff.until
+				}
+				if pool.suspendedUntil.After(lastReconcile) {
+					poolStatus.SuspendedUntil = &pool.suspendedUntil
+				}
+				if pool.skipped != nil {
+					poolStatus.Skipped = actionLabel(pool.skipped.action)
+					poolStatus.SkippedUntil = &pool.skipped.until
+				}
+				if !pool.deferredSince.IsZero() {
+					poolStatus.DeferredSince = &pool.deferredSince
+				}
+				if !pool.gpuDeferredSince.IsZero() {
+					poolStatus.GPUDeferredSince = &pool.gpuDeferredSince
+				}
+				if pool.boostedUntil.After(lastReconcile) {
+					poolStatus.BoostedUntil = &pool.boostedUntil
+				}
+				if !pool.idleSince.IsZero() {
+					poolStatus.IdleSince = &pool.idleSince
+				}
+				if pool.keptAliveUntil.After(lastReconcile) {
+					poolStatus.KeptAliveUntil = &pool.keptAliveUntil
+				}
+				if pool.pendingApproval != "" {
+					poolStatus.PendingApproval = actionLabel(pool.pendingApproval)
+				}
+				poolStatus.Recommendations = pool.recommendations
+				if pool.drift != nil {
+					poolStatus.Drift = fmt.Sprintf("resized to %d nodes at %s", pool.drift.count, pool.drift.since.Format(time.RFC3339))
+				}
+			}
+		}
+
+		if window, end := sc.activeMaintenance(lastReconcile, spec.NodePoolName); window != nil {
+			poolStatus.MaintenanceUntil = &end
+		}
+
+		if sc.nodes != nil {
+			nodes, err := sc.nodes.NodePoolNodes(ctx, spec.CloudProvider, spec.NodePoolName)
+			if err != nil {
+				slog.Debug("Failed to count nodes of node pool", "node_pool", spec.NodePoolName, "error", err)
+			} else {
+				count := len(nodes)
+				poolStatus.ActualCount = &count
+			}
+		}
+		status.NodePools[spec.NodePoolName] = poolStatus
+	}
+
+	if sc.savings != nil {
+		status.Savings = &SavingsStatus{Currency: sc.currency}
+		for name, saved := range sc.savings.Savings() {
+			status.Savings.NodeHours += saved.NodeHours
+			status.Savings.Cost += saved.Cost
+			status.Savings.CO2 += saved.CO2
+			if poolStatus, ok := status.NodePools[name]; ok {
+				poolStatus.Savings = &SavingsStatus{NodeHours: saved.NodeHours, Cost: saved.Cost, CO2: saved.CO2}
+				status.NodePools[name] = poolStatus
+			}
+		}
+	}
+	return status
+}
+
+// writeStatus writes the status to the status ConfigMap, so that `kubectl get configmap bmw-saver-status -o yaml`
+// shows what the controller decided and did. Failures are only logged.
+func (sc *ScalingController) writeStatus(ctx context.Context, status Status) {
+	if sc.client == nil {
+		return
+	}
+	if err := sc.saveStatus(ctx, status); err != nil {
+		slog.Warn("Failed to write status", "config_map", sc.shard.Name(StatusConfigMapName), "error", err)
+	}
+}
+
+// saveStatus creates or updates the status ConfigMap
+func (sc *ScalingController) saveStatus(ctx context.Context, status Status) error {
+	data, err := yaml.Marshal(status)
+	if err != nil {
+		return fmt.Errorf("failed to marshal status: %v", err)
+	}
+
+	namespace := os.Getenv("NAMESPACE")
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      sc.shard.Name(StatusConfigMapName),
+			Namespace: namespace,
+			Labels: map[string]string{
+				"app.kubernetes.io/managed-by": "bmw-saver",
+			},
+		},
+		Data: map[string]string{
+			statusDataKey: string(data),
+		},
+	}
+
+	_, err = sc.client.CoreV1().ConfigMaps(namespace).Update(ctx, cm, metav1.UpdateOptions{})
+	if k8serrors.IsNotFound(err) {
+		_, err = sc.client.CoreV1().ConfigMaps(namespace).Create(ctx, cm, metav1.CreateOptions{})
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save status ConfigMap: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package controller
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer creates the spans of reconciliations, they are only exported if a tracer provider is set up
+var tracer = otel.Tracer("github.com/kezhenxu94/bmw-saver/pkg/controller")
+
+// startSpan starts a span in opCtx, and adds it to ctx as well, so that both contexts of a reconciliation
+// create child spans
+func startSpan(ctx, opCtx context.Context, name string, options ...trace.SpanStartOption) (context.Context, context.Context, trace.Span) {
+	opCtx, span := tracer.Start(opCtx, name, options...)
+	return trace.ContextWithSpan(ctx, span), opCtx, span
+}
+
+// endSpan records the error on the span, if any, and ends it
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,82 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/kezhenxu94/bmw-saver/pkg/config"
+	"github.com/kezhenxu94/bmw-saver/pkg/usage"
+)
+
+// usageSettleTime is how long after a restore the size of a node pool isn't observed, while its nodes join
+const usageSettleTime = 15 * time.Minute
+
+// initUsage initializes tracking the sizes of node pools restored to their observed sizes, and the utilization
+// of node pools for right-sizing, persisted in the usage store. The tracker is kept across configuration changes, so that the sizes aren't lost.
+func (sc *ScalingController) initUsage(cfg config.Config, opts initOptions) error {
+	if cfg.RightSizing == nil && !slices.ContainsFunc(cfg.NodeSpecs, func(spec config.NodeSpec) bool { return spec.UsageBasedRestore != nil }) {
+		sc.usage = nil
+		return nil
+	}
+	if sc.usage != nil {
+		return nil
+	}
+
+	storeConfig := cfg.UsageStore
+	if storeConfig == nil {
+		storeConfig = &config.CacheStoreConfig{}
+	}
+	store, err := sc.getCacheStore(storeConfig)
+	if err == nil {
+		sc.usage, err = usage.NewTracker(context.Background(), store, sc.shard.Name("usage"))
+	}
+	if err != nil {
+		if !opts.logErrors {
+			return fmt.Errorf("failed to create usage tracker: %v", err)
+		}
+		slog.Error("Failed to create usage tracker", "error", err)
+	}
+	return nil
+}
+
+// observeUsage records the size of the restored node pool, and its CPU utilization for right-sizing, once its
+// nodes had time to join
+func (sc *ScalingController) observeUsage(ctx context.Context, now time.Time, pool *poolState, spec config.NodeSpec) {
+	if (spec.UsageBasedRestore == nil && sc.rightSizingInterval <= 0) || sc.usage == nil || sc.nodes == nil {
+		return
+	}
+	if pool.verification != nil || now.Sub(pool.lastScaled) < usageSettleTime {
+		return
+	}
+	nodes, err := sc.nodes.NodePoolNodes(ctx, spec.CloudProvider, spec.NodePoolName)
+	if err != nil {
+		slog.Warn("Failed to observe size of node pool", "node_pool", spec.NodePoolName, "error", err)
+		return
+	}
+	sc.usage.Observe(spec.NodePoolName, len(nodes), now)
+	sc.observeCPU(ctx, now, spec, nodes)
+}
+
+// restoreCount returns the percentile of the sizes of the node pool observed on its last workdays, at least
+// 1 node. ok is false if it isn't restored to its observed sizes, or they weren't observed on enough workdays yet.
+func (sc *ScalingController) restoreCount(spec config.NodeSpec) (int32, bool) {
+	restore := spec.UsageBasedRestore
+	if restore == nil || sc.usage == nil {
+		return 0, false
+	}
+	size, ok := sc.usage.Percentile(spec.NodePoolName, restore.Workdays, restore.Percentile)
+	return int32(max(size, 1)), ok
+}
+
+// saveUsage persists the observed sizes of node pools, failures are only logged
+func (sc *ScalingController) saveUsage(ctx context.Context) {
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+
+	if err := sc.usage.Save(ctx); err != nil {
+		slog.Warn("Failed to save observed node pool sizes", "error", err)
+	}
+}
@@ -0,0 +1,57 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kezhenxu94/bmw-saver/pkg/config"
+	"github.com/kezhenxu94/bmw-saver/pkg/usage"
+)
+
+func TestRestoreCount(t *testing.T) {
+	tracker, err := usage.NewTracker(context.Background(), nil, "usage")
+	if err != nil {
+		t.Fatalf("NewTracker() error = %v", err)
+	}
+	monday := time.Date(2024, time.June, 3, 12, 0, 0, 0, time.UTC)
+	for day := 0; day < 2; day++ {
+		tracker.Observe("shrunk-pool", 3, monday.AddDate(0, 0, day))
+		tracker.Observe("empty-pool", 0, monday.AddDate(0, 0, day))
+	}
+	sc := &ScalingController{usage: tracker}
+
+	tests := []struct {
+		name   string
+		spec   config.NodeSpec
+		want   int32
+		wantOK bool
+	}{
+		{
+			name: "not usage-based",
+			spec: config.NodeSpec{NodePoolName: "shrunk-pool"},
+		},
+		{
+			name: "observed sizes",
+			spec: config.NodeSpec{NodePoolName: "shrunk-pool", UsageBasedRestore: &config.UsageBasedRestoreConfig{Workdays: 2, Percentile: 95}},
+			want: 3, wantOK: true,
+		},
+		{
+			name: "not enough workdays",
+			spec: config.NodeSpec{NodePoolName: "shrunk-pool", UsageBasedRestore: &config.UsageBasedRestoreConfig{Workdays: 5, Percentile: 95}},
+		},
+		{
+			name: "at least one node",
+			spec: config.NodeSpec{NodePoolName: "empty-pool", UsageBasedRestore: &config.UsageBasedRestoreConfig{Workdays: 2, Percentile: 95}},
+			want: 1, wantOK: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := sc.restoreCount(tt.spec)
+			if ok != tt.wantOK || (ok && got != tt.want) {
+				t.Errorf("restoreCount() = %d, %v, want %d, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/kezhenxu94/bmw-saver/pkg/audit"
+	"github.com/kezhenxu94/bmw-saver/pkg/config"
+	"github.com/kezhenxu94/bmw-saver/pkg/notify"
+)
+
+// verifyInterval is how often the nodes of restored node pools are checked until they are ready
+const verifyInterval = 30 * time.Second
+
+// restoreVerification is a restored node pool that isn't ready yet
+type restoreVerification struct {
+	// since is when the node pool was restored
+	since time.Time
+	// nodes is how many nodes the node pool had before it was scaled down, 0 if not known
+	nodes int
+}
+
+// initRestoreVerification initializes the restore verification timeout based on configuration
+func (sc *ScalingController) initRestoreVerification(cfg config.Config) {
+	sc.verifyTimeout = 0
+	if cfg.RestoreVerification != nil {
+		sc.verifyTimeout, _ = time.ParseDuration(cfg.RestoreVerification.Timeout)
+	}
+}
+
+// startVerification starts verifying that the node pool restored at now becomes ready
+func (sc *ScalingController) startVerification(now time.Time, pool *poolState) {
+	if sc.verifyTimeout <= 0 {
+		return
+	}
+	pool.verification = &restoreVerification{since: now, nodes: pool.scaledDownFrom}
+}
+
+// verifyRestore checks whether the restored node pool is ready, recording its time to ready once it is,
+// and alerting once if it isn't within the timeout
+func (sc *ScalingController) verifyRestore(ctx context.Context, now time.Time, pool *poolState, spec config.NodeSpec, notification notify.Notification) {
+	verification := pool.verification
+	if verification == nil || sc.nodes == nil {
+		return
+	}
+
+	ready, want, err := sc.readyNodes(ctx, spec, verification.nodes)
+	if err != nil {
+		slog.Warn("Failed to get nodes of node pool, not verifying restore", "node_pool", spec.NodePoolName, "error", err)
+		return
+	}
+
+	elapsed := now.Sub(verification.since)
+	if ready > 0 && ready >= want {
+		pool.verification = nil
+		sc.recordTimeToReady(spec.NodePoolName, elapsed)
+		slog.Info("Restored node pool is ready", "node_pool", spec.NodePoolName, "nodes", ready, "time_to_ready", elapsed)
+		sc.recordEvent(corev1.EventTypeNormal, eventReasonRestoreVerified,
+			"Restored node pool %s is ready with %d nodes after %s", spec.NodePoolName, ready, elapsed.Round(time.Second))
+		return
+	}
+	if elapsed < sc.verifyTimeout {
+		return
+	}
+
+	pool.verification = nil
+	detail := fmt.Sprintf("%d of %d nodes ready %s after restore", ready, want, sc.verifyTimeout)
+	slog.Warn("Restored node pool isn't ready", "node_pool", spec.NodePoolName, "ready_nodes", ready, "nodes", want, "timeout", sc.verifyTimeout)
+	sc.recordEvent(corev1.EventTypeWarning, eventReasonRestoreNotReady,
+		"Restored node pool %s isn't ready, %s", spec.NodePoolName, detail)
+	sc.recordAudit(ctx, now, pool, spec, notification.Reason, "restore", ready, audit.OutcomeFailed, detail)
+	notification.Kind = notify.KindNotReady
+	notification.Error = detail
+	sc.notifier.Notify(ctx, notification)
+}
+
+// readyNodes returns how many nodes of the node pool are Ready, and how many are wanted for it to be ready:
+// nodes, or all of its nodes if nodes isn't known
+func (sc *ScalingController) readyNodes(ctx context.Context, spec config.NodeSpec, nodes int) (int, int, error) {
+	list, err := sc.nodes.NodePoolNodes(ctx, spec.CloudProvider, spec.NodePoolName)
+	if err != nil {
+		return 0, 0, err
+	}
+	ready := 0
+	for _, node := range list {
+		if isNodeReady(&node) {
+			ready++
+		}
+	}
+	if nodes <= 0 {
+		nodes = len(list)
+	}
+	return ready, nodes, nil
+}
+
+// isNodeReady returns whether the node has the Ready condition
+func isNodeReady(node *corev1.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == corev1.NodeReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// nextVerification returns when to check the restored node pools of the schedule again, zero if none are verified
+func (state *scheduleState) nextVerification(now time.Time) time.Time {
+	for _, pool := range state.pools {
+		if pool.verification != nil {
+			return now.Add(verifyInterval)
+		}
+	}
+	return time.Time{}
+}
+
+// recordTimeToReady records how long the restored node pool took to become ready
+func (sc *ScalingController) recordTimeToReady(nodePool string, d time.Duration) {
+	sc.metricsMu.Lock()
+	defer sc.metricsMu.Unlock()
+	if sc.timeToReady == nil {
+		sc.timeToReady = make(map[string]time.Duration)
+	}
+	sc.timeToReady[nodePool] = d
+}
+
+// writeRestoreMetrics writes the last time to ready of the restored node pools in the Prometheus text format
+func (sc *ScalingController) writeRestoreMetrics(w io.Writer) error {
+	sc.metricsMu.Lock()
+	defer sc.metricsMu.Unlock()
+	if len(sc.timeToReady) == 0 {
+		return nil
+	}
+
+	pools := make([]string, 0, len(sc.timeToReady))
+	for pool := range sc.timeToReady {
+		pools = append(pools, pool)
+	}
+	sort.Strings(pools)
+
+	const name = "bmw_saver_restore_time_to_ready_seconds"
+	if _, err := fmt.Fprintf(w, "# HELP %s Seconds the node pool took to become ready after its last restore.\n# TYPE %s gauge\n", name, name); err != nil {
+		return err
+	}
+	for _, pool := range pools {
+		if _, err := fmt.Fprintf(w, "%s{node_pool=%q} %g\n", name, pool, sc.timeToReady[pool].Seconds()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
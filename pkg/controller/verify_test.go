@@ -0,0 +1,89 @@
+package controller
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/kezhenxu94/bmw-saver/pkg/config"
+	pkgk8s "github.com/kezhenxu94/bmw-saver/pkg/kubernetes"
+	"github.com/kezhenxu94/bmw-saver/pkg/notify"
+)
+
+func TestVerifyRestore(t *testing.T) {
+	node := func(name string, ready corev1.ConditionStatus) *corev1.Node {
+		return &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"cloud.google.com/gke-nodepool": "default-pool"}},
+			Status:     corev1.NodeStatus{Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: ready}}},
+		}
+	}
+	restoredAt := time.Date(2024, time.June, 4, 8, 0, 0, 0, time.UTC)
+	spec := config.NodeSpec{NodePoolName: "default-pool", CloudProvider: "gke"}
+
+	tests := []struct {
+		name           string
+		nodes          []*corev1.Node
+		scaledDownFrom int
+		after          time.Duration
+		wantVerifying  bool
+		wantReady      bool
+	}{
+		{
+			name:           "all nodes ready",
+			nodes:          []*corev1.Node{node("node-1", corev1.ConditionTrue), node("node-2", corev1.ConditionTrue)},
+			scaledDownFrom: 2,
+			after:          3 * time.Minute,
+			wantReady:      true,
+		},
+		{
+			name:           "fewer nodes than before the scale down",
+			nodes:          []*corev1.Node{node("node-1", corev1.ConditionTrue)},
+			scaledDownFrom: 2,
+			after:          3 * time.Minute,
+			wantVerifying:  true,
+		},
+		{
+			name:          "unknown size with a node not ready",
+			nodes:         []*corev1.Node{node("node-1", corev1.ConditionTrue), node("node-2", corev1.ConditionFalse)},
+			after:         3 * time.Minute,
+			wantVerifying: true,
+		},
+		{
+			name:  "not ready within the timeout",
+			nodes: []*corev1.Node{node("node-1", corev1.ConditionFalse)},
+			after: 15 * time.Minute,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := fake.NewSimpleClientset()
+			for _, n := range tt.nodes {
+				if _, err := client.CoreV1().Nodes().Create(context.Background(), n, metav1.CreateOptions{}); err != nil {
+					t.Fatalf("Failed to create node: %v", err)
+				}
+			}
+			sc := &ScalingController{nodes: pkgk8s.NewNodeLister(client), verifyTimeout: 15 * time.Minute}
+			pool := &poolState{scaledDownFrom: tt.scaledDownFrom}
+			sc.startVerification(restoredAt, pool)
+
+			sc.verifyRestore(context.Background(), restoredAt.Add(tt.after), pool, spec, notify.Notification{NodePool: spec.NodePoolName})
+			if got := pool.verification != nil; got != tt.wantVerifying {
+				t.Errorf("Verifying = %v, want %v", got, tt.wantVerifying)
+			}
+			var metrics strings.Builder
+			if err := sc.writeRestoreMetrics(&metrics); err != nil {
+				t.Fatalf("writeRestoreMetrics() error = %v", err)
+			}
+			want := `bmw_saver_restore_time_to_ready_seconds{node_pool="default-pool"} 180`
+			if got := strings.Contains(metrics.String(), want); got != tt.wantReady {
+				t.Errorf("Metrics = %q, want time to ready recorded %v", metrics.String(), tt.wantReady)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package controller
+
+import (
+	"fmt"
+	"log/slog"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/kezhenxu94/bmw-saver/pkg/config"
+	pkgk8s "github.com/kezhenxu94/bmw-saver/pkg/kubernetes"
+)
+
+// nodePoolKey identifies the nodes of a node pool by the node pool label of its cloud provider
+func nodePoolKey(label, nodePoolName string) string {
+	return fmt.Sprintf("%s=%s", label, nodePoolName)
+}
+
+// watchScaledDown updates the scaled down node pools watched for node changes, from the states of the reconciled
+// node pools by schedule
+func (sc *ScalingController) watchScaledDown(specsBySchedule map[string][]config.NodeSpec) {
+	scaledDown := make(map[string]bool)
+	for name, specs := range specsBySchedule {
+		state := sc.schedules[name]
+		if state == nil {
+			continue
+		}
+		for _, spec := range specs {
+			pool := state.pools[spec.NodePoolName]
+			if pool == nil || pool.paused || pool.applied == "" || pool.applied == "restore" {
+				continue
+			}
+			if label, ok := pkgk8s.NodePoolLabels[spec.CloudProvider]; ok {
+				scaledDown[nodePoolKey(label, spec.NodePoolName)] = true
+			}
+		}
+	}
+	sc.scaledDown.Store(&scaledDown)
+}
+
+// nodesChanged reconciles right away if a node is added to or deleted from a scaled down node pool, e.g. by the
+// cluster autoscaler or by hand during off time, instead of waiting for the next reconciliation
+func (sc *ScalingController) nodesChanged(node *corev1.Node) {
+	scaledDown := sc.scaledDown.Load()
+	if scaledDown == nil {
+		return
+	}
+	for _, label := range pkgk8s.NodePoolLabels {
+		nodePoolName, ok := node.Labels[label]
+		if !ok || !(*scaledDown)[nodePoolKey(label, nodePoolName)] {
+			continue
+		}
+		slog.Info("Nodes of scaled down node pool changed, reconciling", "node_pool", nodePoolName, "node", node.Name)
+		sc.triggerReconcile()
+		return
+	}
+}
+
+// triggerReconcile reconciles before the next scheduled reconciliation, triggers are coalesced until it starts
+func (sc *ScalingController) triggerReconcile() {
+	select {
+	case sc.wakeUp <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,61 @@
+package controller
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kezhenxu94/bmw-saver/pkg/config"
+)
+
+func TestNodesChanged(t *testing.T) {
+	sc := &ScalingController{
+		wakeUp: make(chan struct{}, 1),
+		schedules: map[string]*scheduleState{
+			defaultScheduleName: {pools: map[string]*poolState{
+				"scaled-down": {applied: "0"},
+				"restored":    {applied: "restore"},
+				"paused":      {applied: "0", paused: true},
+			}},
+		},
+	}
+	sc.watchScaledDown(map[string][]config.NodeSpec{defaultScheduleName: {
+		{NodePoolName: "scaled-down", CloudProvider: "gke"},
+		{NodePoolName: "restored", CloudProvider: "gke"},
+		{NodePoolName: "paused", CloudProvider: "gke"},
+		{NodePoolName: "new", CloudProvider: "gke"},
+	}})
+
+	node := func(label, nodePool string) *corev1.Node {
+		return &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1", Labels: map[string]string{label: nodePool}}}
+	}
+	tests := []struct {
+		name string
+		node *corev1.Node
+		want bool
+	}{
+		{name: "scaled down node pool", node: node("cloud.google.com/gke-nodepool", "scaled-down"), want: true},
+		{name: "restored node pool", node: node("cloud.google.com/gke-nodepool", "restored")},
+		{name: "paused node pool", node: node("cloud.google.com/gke-nodepool", "paused")},
+		{name: "unreconciled node pool", node: node("cloud.google.com/gke-nodepool", "new")},
+		{name: "other cloud provider", node: node("eks.amazonaws.com/nodegroup", "scaled-down")},
+		{name: "unmanaged node", node: &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc.nodesChanged(tt.node)
+			select {
+			case <-sc.wakeUp:
+				if !tt.want {
+					t.Errorf("nodesChanged() triggered a reconciliation")
+				}
+			default:
+				if tt.want {
+					t.Errorf("nodesChanged() didn't trigger a reconciliation")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package controller
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/kezhenxu94/bmw-saver/pkg/config"
+	pkgk8s "github.com/kezhenxu94/bmw-saver/pkg/kubernetes"
+	"github.com/kezhenxu94/bmw-saver/pkg/schedule"
+)
+
+// workloadRestoreInterval is how often StatefulSets being restored are checked for their next replica
+const workloadRestoreInterval = 15 * time.Second
+
+// workloadSet are the workloads of a schedule, the GitOps resources managing them, the databases they use and
+// the Ingresses serving sleep pages in their place
+type workloadSet struct {
+	specs              []config.WorkloadSpec
+	argoCDApplications []config.GitOpsResource
+	fluxResources      []config.GitOpsResource
+	databases          []config.DatabaseSpec
+	sleepPages         []config.SleepPageSpec
+}
+
+// empty returns whether there are no workloads, GitOps resources, databases nor sleep pages
+func (w workloadSet) empty() bool {
+	return len(w.specs) == 0 && len(w.argoCDApplications) == 0 && len(w.fluxResources) == 0 &&
+		len(w.databases) == 0 && len(w.sleepPages) == 0
+}
+
+// reconcileWorkloads scales the workloads of a schedule according to its decision, and returns whether
+// StatefulSets are still being restored. They are scaled before the node pools, so that their pods don't keep
+// the node pools from consolidating. GitOps resources are suspended before the workloads are scaled down and
+// resumed once they are restored, so that they don't undo the scale downs. Failures are only logged, the
+// workloads are scaled again in the next reconciliation.
+func (sc *ScalingController) reconcileWorkloads(ctx context.Context, name string, workloads workloadSet, decision schedule.Decision) bool {
+	if sc.client == nil {
+		return false
+	}
+	if !decision.IsWorkTime {
+		sc.suspendGitOps(ctx, name, workloads, decision)
+	}
+	restoring := false
+	for _, spec := range workloads.specs {
+		switch spec.Kind {
+		case config.WorkloadKindScaledObject:
+			sc.reconcileScaledObjects(ctx, name, spec, decision)
+		case config.WorkloadKindStatefulSet:
+			restoring = sc.reconcileStatefulSets(ctx, name, spec, decision) || restoring
+		default:
+			sc.reconcileDeployments(ctx, name, spec, decision)
+		}
+	}
+	if decision.IsWorkTime && !restoring {
+		sc.resumeGitOps(ctx, name, workloads, decision)
+	}
+	return restoring
+}
+
+// nextWorkloadRestore returns when the StatefulSets being restored are checked again, zero if there are none
+func (state *scheduleState) nextWorkloadRestore(now time.Time) time.Time {
+	if !state.restoringWorkloads {
+		return time.Time{}
+	}
+	return now.Add(workloadRestoreInterval)
+}
+
+// reconcileDeployments scales the Deployments of the workload spec down, or restores them to their saved replicas
+func (sc *ScalingController) reconcileDeployments(ctx context.Context, name string, spec config.WorkloadSpec, decision schedule.Decision) {
+	namespace := os.Getenv("NAMESPACE")
+	deployments, err := pkgk8s.Deployments(ctx, sc.client, spec.Namespace, spec.Name, spec.Selector)
+	if err != nil {
+		slog.Error("Error getting deployments of workload", "workload", spec.String(), "error", err)
+		return
+	}
+
+	for _, deployment := range deployments {
+		workload := deployment.Namespace + "/" + deployment.Name
+		if decision.IsWorkTime {
+			replicas, err := pkgk8s.RestoreDeployment(ctx, sc.client, namespace, deployment)
+			if err != nil {
+				slog.Error("Error restoring deployment", "deployment", workload, "error", err)
+				sc.recordEvent(corev1.EventTypeWarning, eventReasonRestoreFailed,
+					"Failed to restore deployment %s: %v", workload, err)
+			} else if replicas >= 0 {
+				slog.Info("Restored deployment", "deployment", workload, "replicas", replicas, "schedule", scheduleLabel(name))
+				sc.recordEvent(corev1.EventTypeNormal, eventReasonRestored,
+					"Restored deployment %s to %d replicas, %s", workload, replicas, decisionSummary(decision))
+			}
+			continue
+		}
+
+		scaled, err := pkgk8s.ScaleDeployment(ctx, sc.client, namespace, deployment, spec.OffTimeReplicas)
+		if err != nil {
+			slog.Error("Error scaling deployment", "deployment", workload, "replicas", spec.OffTimeReplicas, "error", err)
+			sc.recordEvent(corev1.EventTypeWarning, eventReasonScaleDownFailed,
+				"Failed to scale deployment %s to %d replicas: %v", workload, spec.OffTimeReplicas, err)
+		} else if scaled {
+			slog.Info("Scaled deployment down", "deployment", workload, "replicas", spec.OffTimeReplicas, "schedule", scheduleLabel(name))
+			sc.recordEvent(corev1.EventTypeNormal, eventReasonScaledDown,
+				"Scaled deployment %s down to %d replicas, %s", workload, spec.OffTimeReplicas, decisionSummary(decision))
+		}
+	}
+}
+
+// reconcileStatefulSets scales the StatefulSets of the workload spec down, or restores them one replica at a time,
+// and returns whether any is still being restored
+func (sc *ScalingController) reconcileStatefulSets(ctx context.Context, name string, spec config.WorkloadSpec, decision schedule.Decision) bool {
+	namespace := os.Getenv("NAMESPACE")
+	statefulSets, err := pkgk8s.StatefulSets(ctx, sc.client, spec.Namespace, spec.Name, spec.Selector)
+	if err != nil {
+		slog.Error("Error getting statefulsets of workload", "workload", spec.String(), "error", err)
+		return false
+	}
+
+	restoring := false
+	for _, statefulSet := range statefulSets {
+		workload := statefulSet.Namespace + "/" + statefulSet.Name
+		if decision.IsWorkTime {
+			replicas, done, err := pkgk8s.RestoreStatefulSet(ctx, sc.client, namespace, statefulSet)
+			switch {
+			case err != nil:
+				slog.Error("Error restoring statefulset", "statefulset", workload, "error", err)
+				sc.recordEvent(corev1.EventTypeWarning, eventReasonRestoreFailed,
+					"Failed to restore statefulset %s: %v", workload, err)
+			case !done:
+				slog.Info("Restoring statefulset", "statefulset", workload, "replicas", replicas, "schedule", scheduleLabel(name))
+				restoring = true
+			case replicas >= 0:
+				slog.Info("Restored statefulset", "statefulset", workload, "replicas", replicas, "schedule", scheduleLabel(name))
+				sc.recordEvent(corev1.EventTypeNormal, eventReasonRestored,
+					"Restored statefulset %s to %d replicas, %s", workload, replicas, decisionSummary(decision))
+			}
+			continue
+		}
+
+		scaled, err := pkgk8s.ScaleStatefulSet(ctx, sc.client, namespace, statefulSet, spec.OffTimeReplicas)
+		if err != nil {
+			slog.Error("Error scaling statefulset", "statefulset", workload, "replicas", spec.OffTimeReplicas, "error", err)
+			sc.recordEvent(corev1.EventTypeWarning, eventReasonScaleDownFailed,
+				"Failed to scale statefulset %s to %d replicas: %v", workload, spec.OffTimeReplicas, err)
+		} else if scaled {
+			slog.Info("Scaled statefulset down", "statefulset", workload, "replicas", spec.OffTimeReplicas, "schedule", scheduleLabel(name))
+			sc.recordEvent(corev1.EventTypeNormal, eventReasonScaledDown,
+				"Scaled statefulset %s down to %d replicas, %s", workload, spec.OffTimeReplicas, decisionSummary(decision))
+		}
+	}
+	return restoring
+}
+
+// reconcileScaledObjects pauses the KEDA ScaledObjects of the workload spec at the off-time replicas, or resumes them,
+// so that KEDA scales their targets instead of scaling them back up
+func (sc *ScalingController) reconcileScaledObjects(ctx context.Context, name string, spec config.WorkloadSpec, decision schedule.Decision) {
+	scaledObjects, err := pkgk8s.ScaledObjects(ctx, sc.client, spec.Namespace, spec.Name, spec.Selector)
+	if err != nil {
+		slog.Error("Error getting ScaledObjects of workload", "workload", spec.String(), "error", err)
+		return
+	}
+
+	for _, scaledObject := range scaledObjects {
+		workload := scaledObject.Metadata.Namespace + "/" + scaledObject.Metadata.Name
+		if decision.IsWorkTime {
+			resumed, err := pkgk8s.ResumeScaledObject(ctx, sc.client, scaledObject)
+			if err != nil {
+				slog.Error("Error resuming ScaledObject", "scaled_object", workload, "error", err)
+				sc.recordEvent(corev1.EventTypeWarning, eventReasonRestoreFailed,
+					"Failed to resume ScaledObject %s: %v", workload, err)
+			} else if resumed {
+				slog.Info("Resumed ScaledObject", "scaled_object", workload, "schedule", scheduleLabel(name))
+				sc.recordEvent(corev1.EventTypeNormal, eventReasonRestored,
+					"Resumed ScaledObject %s, %s", workload, decisionSummary(decision))
+			}
+			continue
+		}
+
+		paused, err := pkgk8s.PauseScaledObject(ctx, sc.client, scaledObject, spec.OffTimeReplicas)
+		if err != nil {
+			slog.Error("Error pausing ScaledObject", "scaled_object", workload, "replicas", spec.OffTimeReplicas, "error", err)
+			sc.recordEvent(corev1.EventTypeWarning, eventReasonScaleDownFailed,
+				"Failed to pause ScaledObject %s at %d replicas: %v", workload, spec.OffTimeReplicas, err)
+		} else if paused {
+			slog.Info("Paused ScaledObject", "scaled_object", workload, "replicas", spec.OffTimeReplicas, "schedule", scheduleLabel(name))
+			sc.recordEvent(corev1.EventTypeNormal, eventReasonScaledDown,
+				"Paused ScaledObject %s at %d replicas, %s", workload, spec.OffTimeReplicas, decisionSummary(decision))
+		}
+	}
+}
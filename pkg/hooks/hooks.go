@@ -0,0 +1,87 @@
+package hooks
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// Phase is when a hook runs
+type Phase string
+
+const (
+	// PhasePreScaleDown hooks run before a node pool is scaled down, e.g. to flush caches
+	PhasePreScaleDown Phase = "preScaleDown"
+	// PhasePostRestore hooks run after a node pool is restored, e.g. to warm up services
+	PhasePostRestore Phase = "postRestore"
+)
+
+// defaultTimeout is how long a hook may run if not configured
+const defaultTimeout = 5 * time.Minute
+
+// Event is passed to hooks, describing the scaling action
+type Event struct {
+	Phase Phase `json:"phase"`
+	// NodePool is the name of the node pool
+	NodePool string `json:"nodePool"`
+	// Schedule is the name of the schedule of the node pool, "default" for the top-level schedule
+	Schedule string `json:"schedule"`
+	// Count is the node count the node pool is scaled down to, only set before scale downs
+	Count int32 `json:"count,omitempty"`
+	// Reason is why the schedule decided
+	Reason string `json:"reason"`
+}
+
+// Hook is an action run around scaling a node pool
+type Hook interface {
+	// Run runs the hook for the event, it returns once the hook finished
+	Run(ctx context.Context, event Event) error
+}
+
+// Configured is a hook with its settings
+type Configured struct {
+	// Name identifies the hook in logs
+	Name string
+	Hook Hook
+	// Timeout is how long the hook may run (default: 5m)
+	Timeout time.Duration
+	// BlockOnFailure skips the scale down if the hook fails, only applies to pre scale down hooks
+	BlockOnFailure bool
+	// NodePools limits the hook to these node pools, all if empty
+	NodePools map[string]bool
+}
+
+// Run runs the hooks applying to the node pool of the event one after another. Failures are logged,
+// an error is returned if a hook with BlockOnFailure failed before a scale down.
+func Run(ctx context.Context, hooks []Configured, event Event) error {
+	var blocked error
+	for _, hook := range hooks {
+		if len(hook.NodePools) > 0 && !hook.NodePools[event.NodePool] {
+			continue
+		}
+
+		timeout := hook.Timeout
+		if timeout <= 0 {
+			timeout = defaultTimeout
+		}
+		hookCtx, cancel := context.WithTimeout(ctx, timeout)
+		slog.Info("Running hook", "hook", hook.Name, "phase", event.Phase, "node_pool", event.NodePool)
+		err := hook.Hook.Run(hookCtx, event)
+		cancel()
+		if err == nil {
+			continue
+		}
+
+		slog.Error("Hook failed",
+			"hook", hook.Name,
+			"phase", event.Phase,
+			"node_pool", event.NodePool,
+			"error", err,
+		)
+		if hook.BlockOnFailure && event.Phase == PhasePreScaleDown && blocked == nil {
+			blocked = fmt.Errorf("hook %s failed: %v", hook.Name, err)
+		}
+	}
+	return blocked
+}
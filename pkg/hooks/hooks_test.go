@@ -0,0 +1,218 @@
+package hooks
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestRun(t *testing.T) {
+	var got []Event
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event Event
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			t.Errorf("Failed to decode event: %v", err)
+		}
+		got = append(got, event)
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	newHook := func(path string) Hook {
+		hook, err := NewHTTPHook(server.URL+path, "", nil)
+		if err != nil {
+			t.Fatalf("Failed to create hook: %v", err)
+		}
+		return hook
+	}
+
+	tests := []struct {
+		name      string
+		hooks     []Configured
+		phase     Phase
+		wantCalls int
+		wantErr   bool
+	}{
+		{
+			name:      "Success",
+			hooks:     []Configured{{Name: "flush", Hook: newHook("/ok")}},
+			phase:     PhasePreScaleDown,
+			wantCalls: 1,
+		},
+		{
+			name:      "Failure Without Blocking",
+			hooks:     []Configured{{Name: "flush", Hook: newHook("/fail")}, {Name: "status", Hook: newHook("/ok")}},
+			phase:     PhasePreScaleDown,
+			wantCalls: 2,
+		},
+		{
+			name:      "Blocking Failure",
+			hooks:     []Configured{{Name: "flush", Hook: newHook("/fail"), BlockOnFailure: true}, {Name: "status", Hook: newHook("/ok")}},
+			phase:     PhasePreScaleDown,
+			wantCalls: 2,
+			wantErr:   true,
+		},
+		{
+			name:      "Blocking Failure After Restore",
+			hooks:     []Configured{{Name: "warm-up", Hook: newHook("/fail"), BlockOnFailure: true}},
+			phase:     PhasePostRestore,
+			wantCalls: 1,
+		},
+		{
+			name:  "Other Node Pool",
+			hooks: []Configured{{Name: "flush", Hook: newHook("/ok"), NodePools: map[string]bool{"batch-pool": true}}},
+			phase: PhasePreScaleDown,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got = nil
+			event := Event{Phase: tt.phase, NodePool: "default-pool", Schedule: "default", Reason: "off time"}
+			err := Run(context.Background(), tt.hooks, event)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(got) != tt.wantCalls {
+				t.Fatalf("Hooks called %d times, want %d", len(got), tt.wantCalls)
+			}
+			for _, e := range got {
+				if e != event {
+					t.Errorf("Hook got event %+v, want %+v", e, event)
+				}
+			}
+		})
+	}
+}
+
+func TestJobHook_Run(t *testing.T) {
+	tests := []struct {
+		name      string
+		condition batchv1.JobConditionType
+		wantErr   bool
+	}{
+		{name: "Complete", condition: batchv1.JobComplete},
+		{name: "Failed", condition: batchv1.JobFailed, wantErr: true},
+		{name: "Timeout", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := fake.NewSimpleClientset()
+			// The fake client doesn't generate names, and Jobs finish as soon as they are created
+			client.PrependReactor("create", "jobs", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				job := action.(k8stesting.CreateAction).GetObject().(*batchv1.Job)
+				job.Name = job.GenerateName + "test"
+				if tt.condition != "" {
+					job.Status.Conditions = []batchv1.JobCondition{{Type: tt.condition, Status: corev1.ConditionTrue}}
+				}
+				return false, nil, nil
+			})
+
+			hook, err := NewJobHook(client, "flush", "bmw-saver", batchv1.JobSpec{
+				Template: corev1.PodTemplateSpec{
+					Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "flush", Image: "busybox"}}},
+				},
+			})
+			if err != nil {
+				t.Fatalf("Failed to create hook: %v", err)
+			}
+			hook.pollInterval = 10 * time.Millisecond
+
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+			err = hook.Run(ctx, Event{Phase: PhasePreScaleDown, NodePool: "default-pool", Count: 1})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			job, err := client.BatchV1().Jobs("bmw-saver").Get(context.Background(), "bmw-saver-flush-test", metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("Job not created: %v", err)
+			}
+			if policy := job.Spec.Template.Spec.RestartPolicy; policy != corev1.RestartPolicyNever {
+				t.Errorf("RestartPolicy = %s, want Never", policy)
+			}
+			env := map[string]string{}
+			for _, e := range job.Spec.Template.Spec.Containers[0].Env {
+				env[e.Name] = e.Value
+			}
+			if env["BMW_SAVER_NODE_POOL"] != "default-pool" || env["BMW_SAVER_COUNT"] != "1" {
+				t.Errorf("Job env = %v, want the event", env)
+			}
+		})
+	}
+}
+
+func TestVeleroHook_Run(t *testing.T) {
+	tests := []struct {
+		name    string
+		phases  []string
+		wantErr bool
+	}{
+		{name: "Completed", phases: []string{"New", "InProgress", "Completed"}},
+		{name: "PartiallyFailed", phases: []string{"InProgress", "PartiallyFailed"}, wantErr: true},
+		{name: "Timeout", phases: []string{"InProgress"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var created veleroBackup
+			polls := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				switch {
+				case r.Method == http.MethodPost && r.URL.Path == "/apis/velero.io/v1/namespaces/velero/backups":
+					if err := json.NewDecoder(r.Body).Decode(&created); err != nil {
+						t.Errorf("Failed to decode backup: %v", err)
+					}
+					created.Metadata.Name = created.Metadata.GenerateName + "test"
+				case r.Method == http.MethodGet && r.URL.Path == "/apis/velero.io/v1/namespaces/velero/backups/bmw-saver-nightly-test":
+					polls++
+				default:
+					t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				backup := created
+				backup.Status.Phase = tt.phases[min(polls, len(tt.phases)-1)]
+				_ = json.NewEncoder(w).Encode(backup)
+			}))
+			defer server.Close()
+			client := kubernetes.NewForConfigOrDie(&rest.Config{Host: server.URL, ContentConfig: rest.ContentConfig{ContentType: "application/json"}})
+
+			hook, err := NewVeleroHook(client, "nightly", "velero", []string{"team-a"}, "", "168h")
+			if err != nil {
+				t.Fatalf("Failed to create hook: %v", err)
+			}
+			hook.pollInterval = 10 * time.Millisecond
+
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+			err = hook.Run(ctx, Event{Phase: PhasePreScaleDown, NodePool: "default-pool"})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := created.Spec.IncludedNamespaces; len(got) != 1 || got[0] != "team-a" || created.Spec.TTL != "168h" {
+				t.Errorf("Backup spec = %+v, want team-a kept for 168h", created.Spec)
+			}
+			if got := created.Metadata.Annotations["bmw-saver.io/node-pool"]; got != "default-pool" {
+				t.Errorf("Backup node pool = %q, want default-pool", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+)
+
+// HTTPHook calls an HTTP endpoint with the event as JSON body
+type HTTPHook struct {
+	url    string
+	method string
+	header http.Header
+	client *http.Client
+}
+
+// NewHTTPHook creates a new HTTP hook, method defaults to POST
+func NewHTTPHook(url, method string, header map[string]string) (*HTTPHook, error) {
+	if url == "" {
+		return nil, fmt.Errorf("hook URL is required")
+	}
+	if method == "" {
+		method = http.MethodPost
+	}
+
+	h := http.Header{}
+	for key, value := range header {
+		h.Set(key, value)
+	}
+	return &HTTPHook{
+		url:    url,
+		method: method,
+		header: h,
+		// The timeout is set by the context of each run
+		client: &http.Client{},
+	}, nil
+}
+
+// Run calls the endpoint and fails on any non-2xx response
+func (h *HTTPHook) Run(ctx context.Context, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, h.method, h.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	for key, values := range h.header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call hook: %v", err)
+	}
+	defer func() {
+		if e := resp.Body.Close(); e != nil {
+			slog.Error("Failed to close response body", "error", e)
+		}
+	}()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, respBody)
+	}
+	return nil
+}
+
+// String returns a string representation of the HTTPHook without the possibly secret URL
+func (h *HTTPHook) String() string {
+	return fmt.Sprintf("HTTPHook{method: %s}", h.method)
+}
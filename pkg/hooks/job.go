@@ -0,0 +1,121 @@
+package hooks
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// jobPollInterval is how often the status of a hook Job is checked
+	jobPollInterval = 5 * time.Second
+	// defaultJobTTL is how long finished hook Jobs are kept, so that their logs can be checked
+	defaultJobTTL int32 = 24 * 60 * 60
+)
+
+// JobHook runs a Kubernetes Job and waits for it to complete. The event is passed to all containers
+// as the BMW_SAVER_PHASE, BMW_SAVER_NODE_POOL, BMW_SAVER_SCHEDULE and BMW_SAVER_COUNT environment variables.
+type JobHook struct {
+	client       kubernetes.Interface
+	name         string
+	namespace    string
+	spec         batchv1.JobSpec
+	pollInterval time.Duration
+}
+
+// NewJobHook creates a new Job hook, Jobs are created from the spec in the namespace
+func NewJobHook(client kubernetes.Interface, name, namespace string, spec batchv1.JobSpec) (*JobHook, error) {
+	if len(spec.Template.Spec.Containers) == 0 {
+		return nil, fmt.Errorf("hook job requires at least one container")
+	}
+	if spec.Template.Spec.RestartPolicy == "" {
+		spec.Template.Spec.RestartPolicy = corev1.RestartPolicyNever
+	}
+	if spec.TTLSecondsAfterFinished == nil {
+		ttl := defaultJobTTL
+		spec.TTLSecondsAfterFinished = &ttl
+	}
+	return &JobHook{
+		client:       client,
+		name:         name,
+		namespace:    namespace,
+		spec:         spec,
+		pollInterval: jobPollInterval,
+	}, nil
+}
+
+// Run creates the Job and waits until it completed, it fails if the Job failed or didn't finish in time
+func (h *JobHook) Run(ctx context.Context, event Event) error {
+	spec := *h.spec.DeepCopy()
+	env := []corev1.EnvVar{
+		{Name: "BMW_SAVER_PHASE", Value: string(event.Phase)},
+		{Name: "BMW_SAVER_NODE_POOL", Value: event.NodePool},
+		{Name: "BMW_SAVER_SCHEDULE", Value: event.Schedule},
+		{Name: "BMW_SAVER_COUNT", Value: strconv.Itoa(int(event.Count))},
+	}
+	for i := range spec.Template.Spec.Containers {
+		spec.Template.Spec.Containers[i].Env = append(spec.Template.Spec.Containers[i].Env, env...)
+	}
+
+	job, err := h.client.BatchV1().Jobs(h.namespace).Create(ctx, &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: fmt.Sprintf("bmw-saver-%s-", h.name),
+			Labels: map[string]string{
+				"app.kubernetes.io/managed-by": "bmw-saver",
+				"bmw-saver/hook":               h.name,
+			},
+		},
+		Spec: spec,
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to create job: %v", err)
+	}
+	slog.Debug("Hook job created", "job", job.Name, "namespace", h.namespace)
+
+	ticker := time.NewTicker(h.pollInterval)
+	defer ticker.Stop()
+	for {
+		if done, err := jobFinished(job); done {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("job %s did not finish in time: %v", job.Name, ctx.Err())
+		case <-ticker.C:
+		}
+
+		job, err = h.client.BatchV1().Jobs(h.namespace).Get(ctx, job.Name, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to get job: %v", err)
+		}
+	}
+}
+
+// jobFinished returns true if the Job completed or failed, with an error if it failed
+func jobFinished(job *batchv1.Job) (bool, error) {
+	for _, condition := range job.Status.Conditions {
+		if condition.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch condition.Type {
+		case batchv1.JobComplete:
+			return true, nil
+		case batchv1.JobFailed:
+			return true, fmt.Errorf("job %s failed: %s", job.Name, condition.Message)
+		}
+	}
+	return false, nil
+}
+
+// String returns a string representation of the JobHook
+func (h *JobHook) String() string {
+	return fmt.Sprintf("JobHook{name: %s, namespace: %s}", h.name, h.namespace)
+}
@@ -0,0 +1,134 @@
+package hooks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// veleroPollInterval is how often the phase of a hook Backup is checked
+	veleroPollInterval = 10 * time.Second
+	// veleroBackupsPath is the API path of the Velero Backups, followed by their namespace
+	veleroBackupsPath = "/apis/velero.io/v1/namespaces/%s/backups"
+)
+
+// veleroBackup is the subset of a Velero Backup we need
+type veleroBackup struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Metadata   struct {
+		Name         string            `json:"name,omitempty"`
+		GenerateName string            `json:"generateName,omitempty"`
+		Labels       map[string]string `json:"labels,omitempty"`
+		Annotations  map[string]string `json:"annotations,omitempty"`
+	} `json:"metadata"`
+	Spec struct {
+		IncludedNamespaces []string `json:"includedNamespaces"`
+		StorageLocation    string   `json:"storageLocation,omitempty"`
+		TTL                string   `json:"ttl,omitempty"`
+	} `json:"spec"`
+	Status struct {
+		Phase string `json:"phase,omitempty"`
+	} `json:"status,omitempty"`
+}
+
+// VeleroHook creates a Velero Backup of namespaces and waits for it to complete, so that environments sleeping
+// every night have a fresh restore point
+type VeleroHook struct {
+	client             kubernetes.Interface
+	name               string
+	namespace          string
+	includedNamespaces []string
+	storageLocation    string
+	ttl                string
+	pollInterval       time.Duration
+}
+
+// NewVeleroHook creates a new Velero hook, Backups of the included namespaces are created in the Velero namespace.
+// The storage location and TTL default to the ones of Velero if empty.
+func NewVeleroHook(client kubernetes.Interface, name, namespace string, includedNamespaces []string, storageLocation, ttl string) (*VeleroHook, error) {
+	if len(includedNamespaces) == 0 {
+		return nil, fmt.Errorf("hook backup requires at least one namespace")
+	}
+	return &VeleroHook{
+		client:             client,
+		name:               name,
+		namespace:          namespace,
+		includedNamespaces: includedNamespaces,
+		storageLocation:    storageLocation,
+		ttl:                ttl,
+		pollInterval:       veleroPollInterval,
+	}, nil
+}
+
+// Run creates the Backup and waits until it completed, it fails if the Backup failed, partially failed or didn't
+// finish in time
+func (h *VeleroHook) Run(ctx context.Context, event Event) error {
+	restClient := h.client.Discovery().RESTClient()
+	if restClient == nil {
+		return fmt.Errorf("kubernetes API is not available")
+	}
+	path := fmt.Sprintf(veleroBackupsPath, h.namespace)
+
+	backup := veleroBackup{APIVersion: "velero.io/v1", Kind: "Backup"}
+	backup.Metadata.GenerateName = fmt.Sprintf("bmw-saver-%s-", h.name)
+	backup.Metadata.Labels = map[string]string{
+		"app.kubernetes.io/managed-by": "bmw-saver",
+		"bmw-saver/hook":               h.name,
+	}
+	backup.Metadata.Annotations = map[string]string{
+		"bmw-saver.io/phase":     string(event.Phase),
+		"bmw-saver.io/node-pool": event.NodePool,
+		"bmw-saver.io/schedule":  event.Schedule,
+	}
+	backup.Spec.IncludedNamespaces = h.includedNamespaces
+	backup.Spec.StorageLocation = h.storageLocation
+	backup.Spec.TTL = h.ttl
+	body, err := json.Marshal(backup)
+	if err != nil {
+		return fmt.Errorf("failed to marshal backup: %v", err)
+	}
+	data, err := restClient.Post().AbsPath(path).Body(body).DoRaw(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create backup: %v", err)
+	}
+	if err := json.Unmarshal(data, &backup); err != nil {
+		return fmt.Errorf("failed to parse backup: %v", err)
+	}
+	slog.Debug("Hook backup created", "backup", backup.Metadata.Name, "namespace", h.namespace)
+
+	ticker := time.NewTicker(h.pollInterval)
+	defer ticker.Stop()
+	for {
+		switch phase := backup.Status.Phase; phase {
+		case "Completed":
+			return nil
+		case "Failed", "PartiallyFailed", "FailedValidation":
+			return fmt.Errorf("backup %s %s", backup.Metadata.Name, phase)
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("backup %s did not finish in time: %v", backup.Metadata.Name, ctx.Err())
+		case <-ticker.C:
+		}
+
+		data, err := restClient.Get().AbsPath(path, backup.Metadata.Name).DoRaw(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get backup: %v", err)
+		}
+		if err := json.Unmarshal(data, &backup); err != nil {
+			return fmt.Errorf("failed to parse backup: %v", err)
+		}
+	}
+}
+
+// String returns a string representation of the VeleroHook
+func (h *VeleroHook) String() string {
+	return fmt.Sprintf("VeleroHook{name: %s, namespace: %s}", h.name, h.namespace)
+}
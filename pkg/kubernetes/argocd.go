@@ -0,0 +1,92 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+// ApplicationGroupVersion is the API group and version of ArgoCD Applications
+const ApplicationGroupVersion = "argoproj.io/v1alpha1"
+
+// application is the subset of an ArgoCD Application we need
+type application struct {
+	Spec struct {
+		SyncPolicy struct {
+			Automated json.RawMessage `json:"automated"`
+		} `json:"syncPolicy"`
+	} `json:"spec"`
+}
+
+// SuspendApplication turns off the automated sync of the ArgoCD Application, including self-healing, so that it
+// doesn't undo scale downs, and returns whether it was on. The automated sync policy is saved in a ConfigMap of
+// the state namespace first, unless it's saved already by an earlier suspension.
+func SuspendApplication(ctx context.Context, client kubernetes.Interface, stateNamespace, namespace, name string) (bool, error) {
+	restClient := client.Discovery().RESTClient()
+	if restClient == nil {
+		return false, fmt.Errorf("kubernetes API is not available")
+	}
+	data, err := restClient.Get().AbsPath(applicationPath(namespace), name).DoRaw(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to get Application %s/%s: %v", namespace, name, err)
+	}
+	var app application
+	if err := json.Unmarshal(data, &app); err != nil {
+		return false, fmt.Errorf("failed to parse Application %s/%s: %v", namespace, name, err)
+	}
+	automated := app.Spec.SyncPolicy.Automated
+	if len(automated) == 0 || string(automated) == "null" {
+		return false, nil
+	}
+
+	key := StateKey{Kind: StateKindApplication, Namespace: namespace, Name: name}
+	if err := NewStateStore(client, stateNamespace).Save(ctx, key, workloadConfig{Automated: automated}); err != nil {
+		return false, err
+	}
+	if err := patchApplication(ctx, client, namespace, name, nil); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ResumeApplication restores the saved automated sync policy of the ArgoCD Application, and returns whether it
+// was suspended. The saved policy is deleted afterwards.
+func ResumeApplication(ctx context.Context, client kubernetes.Interface, stateNamespace, namespace, name string) (bool, error) {
+	store := NewStateStore(client, stateNamespace)
+	key := StateKey{Kind: StateKindApplication, Namespace: namespace, Name: name}
+	var saved workloadConfig
+	if ok, err := store.Load(ctx, key, &saved); err != nil || !ok {
+		return false, err
+	}
+	if err := patchApplication(ctx, client, namespace, name, saved.Automated); err != nil {
+		return false, err
+	}
+	if err := store.Delete(ctx, key); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// patchApplication sets the automated sync policy of the Application, nil turns it off
+func patchApplication(ctx context.Context, client kubernetes.Interface, namespace, name string, automated json.RawMessage) error {
+	restClient := client.Discovery().RESTClient()
+	if restClient == nil {
+		return fmt.Errorf("kubernetes API is not available")
+	}
+	if automated == nil {
+		automated = json.RawMessage("null")
+	}
+	patch := fmt.Sprintf(`{"spec":{"syncPolicy":{"automated":%s}}}`, automated)
+	if _, err := restClient.Patch(types.MergePatchType).AbsPath(applicationPath(namespace), name).Body([]byte(patch)).DoRaw(ctx); err != nil {
+		return fmt.Errorf("failed to patch Application %s/%s: %v", namespace, name, err)
+	}
+	return nil
+}
+
+// applicationPath returns the API path of the Applications in the namespace
+func applicationPath(namespace string) string {
+	return "/apis/" + ApplicationGroupVersion + "/namespaces/" + namespace + "/applications"
+}
@@ -0,0 +1,79 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+func TestSuspendAndResumeApplication(t *testing.T) {
+	automated := `{"prune":true,"selfHeal":true}`
+	var configMap []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/apis/argoproj.io/v1alpha1/namespaces/argocd/applications/shop"):
+			if r.Method == http.MethodPatch {
+				var patch application
+				if err := json.Unmarshal(body, &patch); err != nil {
+					t.Errorf("failed to parse patch: %v", err)
+				}
+				automated = string(patch.Spec.SyncPolicy.Automated)
+			}
+			_, _ = w.Write([]byte(`{"spec": {"syncPolicy": {"automated": ` + automated + `}}}`))
+		case r.URL.Path == "/api/v1/namespaces/bmw-saver/configmaps" && r.Method == http.MethodPost:
+			configMap = body
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write(body)
+		case r.URL.Path == "/api/v1/namespaces/bmw-saver/configmaps/bmw-saver-workload-argocd.shop.application":
+			if configMap == nil {
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte(`{"kind": "Status", "apiVersion": "v1", "status": "Failure", "reason": "NotFound", "code": 404}`))
+				return
+			}
+			if r.Method == http.MethodDelete {
+				configMap = nil
+				_, _ = w.Write([]byte(`{"kind": "Status", "apiVersion": "v1", "status": "Success"}`))
+				return
+			}
+			_, _ = w.Write(configMap)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	client := kubernetes.NewForConfigOrDie(&rest.Config{Host: server.URL, ContentConfig: rest.ContentConfig{ContentType: "application/json"}})
+	ctx := context.Background()
+
+	if resumed, err := ResumeApplication(ctx, client, "bmw-saver", "argocd", "shop"); err != nil || resumed {
+		t.Errorf("ResumeApplication() = %v, %v, want not resumed before suspending", resumed, err)
+	}
+	if suspended, err := SuspendApplication(ctx, client, "bmw-saver", "argocd", "shop"); err != nil || !suspended {
+		t.Fatalf("SuspendApplication() = %v, %v, want suspended", suspended, err)
+	}
+	if automated != "null" {
+		t.Errorf("automated = %s after suspending, want null", automated)
+	}
+	if suspended, err := SuspendApplication(ctx, client, "bmw-saver", "argocd", "shop"); err != nil || suspended {
+		t.Errorf("SuspendApplication() = %v, %v, want suspended already", suspended, err)
+	}
+
+	if resumed, err := ResumeApplication(ctx, client, "bmw-saver", "argocd", "shop"); err != nil || !resumed {
+		t.Fatalf("ResumeApplication() = %v, %v, want resumed", resumed, err)
+	}
+	if automated != `{"prune":true,"selfHeal":true}` {
+		t.Errorf("automated = %s after resuming, want the saved policy", automated)
+	}
+	if configMap != nil {
+		t.Errorf("saved policy = %s, want deleted after resuming", configMap)
+	}
+}
@@ -0,0 +1,197 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// batchCheck returns the batch work of a framework the pod runs that is still running, empty if the pod doesn't
+// run any or it finished
+type batchCheck func(ctx context.Context, client kubernetes.Interface, pod *corev1.Pod) (string, error)
+
+// batchChecks are the checks for running batch work, by framework
+var batchChecks = map[string]batchCheck{
+	"job":      runningJob,
+	"kubeflow": runningKubeflowRun,
+	"ray":      runningRayJob,
+	"spark":    runningSparkApplication,
+}
+
+// RunningBatchWork returns the batch work of the frameworks still running on the nodes of the node pool, e.g.
+// Kubeflow pipeline runs, Ray jobs or Spark applications. Each work is only returned once, regardless of how many
+// of its pods run on the node pool. Finished pods are ignored.
+func RunningBatchWork(ctx context.Context, client kubernetes.Interface, cloudProvider, nodePoolName string, frameworks []string) ([]string, error) {
+	pods, err := nodePoolPods(ctx, client, cloudProvider, nodePoolName)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	var running []string
+	for _, pod := range pods {
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		for _, framework := range frameworks {
+			check, ok := batchChecks[framework]
+			if !ok {
+				return nil, fmt.Errorf("unsupported batch framework: %s", framework)
+			}
+			work, err := check(ctx, client, &pod)
+			if err != nil {
+				return nil, err
+			}
+			if work != "" && !seen[work] {
+				seen[work] = true
+				running = append(running, work)
+			}
+		}
+	}
+	return running, nil
+}
+
+// runningJob returns the Job owning the pod, unless it completed or failed
+func runningJob(ctx context.Context, client kubernetes.Interface, pod *corev1.Pod) (string, error) {
+	for _, owner := range pod.OwnerReferences {
+		if owner.Kind != "Job" {
+			continue
+		}
+		job, err := client.BatchV1().Jobs(pod.Namespace).Get(ctx, owner.Name, metav1.GetOptions{})
+		if k8serrors.IsNotFound(err) {
+			return "", nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to get job %s/%s: %v", pod.Namespace, owner.Name, err)
+		}
+		for _, condition := range job.Status.Conditions {
+			if (condition.Type == batchv1.JobComplete || condition.Type == batchv1.JobFailed) && condition.Status == corev1.ConditionTrue {
+				return "", nil
+			}
+		}
+		return fmt.Sprintf("Job %s/%s", pod.Namespace, owner.Name), nil
+	}
+	return "", nil
+}
+
+// runningKubeflowRun returns the Kubeflow pipeline run of the pod, by the phase of the Argo Workflow running it
+func runningKubeflowRun(ctx context.Context, client kubernetes.Interface, pod *corev1.Pod) (string, error) {
+	workflow := pod.Labels["workflows.argoproj.io/workflow"]
+	if workflow == "" {
+		return "", nil
+	}
+	var resource struct {
+		Status struct {
+			Phase string `json:"phase"`
+		} `json:"status"`
+	}
+	path := "/apis/argoproj.io/v1alpha1/namespaces/" + pod.Namespace + "/workflows"
+	if ok, err := getCustomResource(ctx, client, path, workflow, &resource); err != nil || !ok {
+		return "", err
+	}
+	if phase := resource.Status.Phase; phase != "" && phase != "Pending" && phase != "Running" {
+		return "", nil
+	}
+	if runID := pod.Labels["pipeline/runid"]; runID != "" {
+		return fmt.Sprintf("Kubeflow pipeline run %s", runID), nil
+	}
+	return fmt.Sprintf("Argo Workflow %s/%s", pod.Namespace, workflow), nil
+}
+
+// runningRayJob returns the Ray job running on the Ray cluster of the pod, unless it finished
+func runningRayJob(ctx context.Context, client kubernetes.Interface, pod *corev1.Pod) (string, error) {
+	cluster := pod.Labels["ray.io/cluster"]
+	if cluster == "" {
+		return "", nil
+	}
+	restClient := client.Discovery().RESTClient()
+	if restClient == nil {
+		return "", fmt.Errorf("kubernetes API is not available")
+	}
+	result := restClient.Get().AbsPath("/apis/ray.io/v1/namespaces/" + pod.Namespace + "/rayjobs").Do(ctx)
+	var statusCode int
+	if result.StatusCode(&statusCode); statusCode == http.StatusNotFound {
+		return "", nil
+	}
+	data, err := result.Raw()
+	if err != nil {
+		return "", fmt.Errorf("failed to list RayJobs in %s: %v", pod.Namespace, err)
+	}
+	var list struct {
+		Items []struct {
+			Metadata struct {
+				Name string `json:"name"`
+			} `json:"metadata"`
+			Status struct {
+				RayClusterName string `json:"rayClusterName"`
+				JobStatus      string `json:"jobStatus"`
+			} `json:"status"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(data, &list); err != nil {
+		return "", fmt.Errorf("failed to parse RayJobs: %v", err)
+	}
+	for _, job := range list.Items {
+		if job.Status.RayClusterName != cluster {
+			continue
+		}
+		switch job.Status.JobStatus {
+		case "SUCCEEDED", "FAILED", "STOPPED":
+			continue
+		}
+		return fmt.Sprintf("RayJob %s/%s", pod.Namespace, job.Metadata.Name), nil
+	}
+	return "", nil
+}
+
+// runningSparkApplication returns the Spark application of the driver or executor pod, unless it finished
+func runningSparkApplication(ctx context.Context, client kubernetes.Interface, pod *corev1.Pod) (string, error) {
+	name := pod.Labels["sparkoperator.k8s.io/app-name"]
+	if name == "" {
+		return "", nil
+	}
+	var resource struct {
+		Status struct {
+			ApplicationState struct {
+				State string `json:"state"`
+			} `json:"applicationState"`
+		} `json:"status"`
+	}
+	path := "/apis/sparkoperator.k8s.io/v1beta2/namespaces/" + pod.Namespace + "/sparkapplications"
+	if ok, err := getCustomResource(ctx, client, path, name, &resource); err != nil || !ok {
+		return "", err
+	}
+	switch resource.Status.ApplicationState.State {
+	case "COMPLETED", "FAILED":
+		return "", nil
+	}
+	return fmt.Sprintf("SparkApplication %s/%s", pod.Namespace, name), nil
+}
+
+// getCustomResource gets the custom resource at the path into v, and returns whether it exists
+func getCustomResource(ctx context.Context, client kubernetes.Interface, path, name string, v interface{}) (bool, error) {
+	restClient := client.Discovery().RESTClient()
+	if restClient == nil {
+		return false, fmt.Errorf("kubernetes API is not available")
+	}
+	result := restClient.Get().AbsPath(path, name).Do(ctx)
+	var statusCode int
+	if result.StatusCode(&statusCode); statusCode == http.StatusNotFound {
+		return false, nil
+	}
+	data, err := result.Raw()
+	if err != nil {
+		return false, fmt.Errorf("failed to get %s/%s: %v", path, name, err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return false, fmt.Errorf("failed to parse %s/%s: %v", path, name, err)
+	}
+	return true, nil
+}
@@ -0,0 +1,109 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+func TestRunningBatchWork(t *testing.T) {
+	pod := func(name string, labels map[string]string, owner string) corev1.Pod {
+		p := corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ml", Name: name, Labels: labels},
+			Spec:       corev1.PodSpec{NodeName: "node-1"},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+		}
+		if owner != "" {
+			p.OwnerReferences = []metav1.OwnerReference{{Kind: "Job", Name: owner}}
+		}
+		return p
+	}
+	finished := pod("old-driver", map[string]string{"sparkoperator.k8s.io/app-name": "etl"}, "")
+	finished.Status.Phase = corev1.PodSucceeded
+	pods := []corev1.Pod{
+		pod("train-abc", nil, "train"),
+		pod("report-abc", nil, "report"),
+		pod("step-1", map[string]string{"workflows.argoproj.io/workflow": "pipeline-x", "pipeline/runid": "run-1"}, ""),
+		pod("step-2", map[string]string{"workflows.argoproj.io/workflow": "pipeline-x", "pipeline/runid": "run-1"}, ""),
+		pod("done-step", map[string]string{"workflows.argoproj.io/workflow": "pipeline-y"}, ""),
+		pod("ray-head", map[string]string{"ray.io/cluster": "tune"}, ""),
+		pod("ray-idle", map[string]string{"ray.io/cluster": "idle"}, ""),
+		pod("etl-driver", map[string]string{"sparkoperator.k8s.io/app-name": "etl"}, ""),
+		pod("gone-driver", map[string]string{"sparkoperator.k8s.io/app-name": "gone"}, ""),
+		finished,
+		pod("web", nil, ""),
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v1/nodes":
+			_ = json.NewEncoder(w).Encode(corev1.NodeList{Items: []corev1.Node{{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}}}})
+		case "/api/v1/pods":
+			_ = json.NewEncoder(w).Encode(corev1.PodList{Items: pods})
+		case "/apis/batch/v1/namespaces/ml/jobs/train":
+			_ = json.NewEncoder(w).Encode(batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "train"}})
+		case "/apis/batch/v1/namespaces/ml/jobs/report":
+			_ = json.NewEncoder(w).Encode(batchv1.Job{
+				ObjectMeta: metav1.ObjectMeta{Name: "report"},
+				Status:     batchv1.JobStatus{Conditions: []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}}},
+			})
+		case "/apis/argoproj.io/v1alpha1/namespaces/ml/workflows/pipeline-x":
+			_, _ = w.Write([]byte(`{"status": {"phase": "Running"}}`))
+		case "/apis/argoproj.io/v1alpha1/namespaces/ml/workflows/pipeline-y":
+			_, _ = w.Write([]byte(`{"status": {"phase": "Succeeded"}}`))
+		case "/apis/ray.io/v1/namespaces/ml/rayjobs":
+			_, _ = w.Write([]byte(`{"items": [
+				{"metadata": {"name": "tune-job"}, "status": {"rayClusterName": "tune", "jobStatus": "RUNNING"}},
+				{"metadata": {"name": "idle-job"}, "status": {"rayClusterName": "idle", "jobStatus": "SUCCEEDED"}}
+			]}`))
+		case "/apis/sparkoperator.k8s.io/v1beta2/namespaces/ml/sparkapplications/etl":
+			_, _ = w.Write([]byte(`{"status": {"applicationState": {"state": "RUNNING"}}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"kind": "Status", "apiVersion": "v1", "status": "Failure", "reason": "NotFound", "code": 404}`))
+		}
+	}))
+	defer server.Close()
+	client := kubernetes.NewForConfigOrDie(&rest.Config{Host: server.URL, ContentConfig: rest.ContentConfig{ContentType: "application/json"}})
+
+	tests := []struct {
+		name       string
+		frameworks []string
+		want       []string
+	}{
+		{
+			name:       "all frameworks",
+			frameworks: []string{"job", "kubeflow", "ray", "spark"},
+			want:       []string{"Job ml/train", "Kubeflow pipeline run run-1", "RayJob ml/tune-job", "SparkApplication ml/etl"},
+		},
+		{
+			name:       "some frameworks",
+			frameworks: []string{"spark"},
+			want:       []string{"SparkApplication ml/etl"},
+		},
+		{
+			name: "no frameworks",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RunningBatchWork(context.Background(), client, "gke", "batch-pool", tt.frameworks)
+			if err != nil {
+				t.Fatalf("RunningBatchWork() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("RunningBatchWork() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,31 +0,0 @@
-package kubernetes
-
-import (
-	"context"
-	"fmt"
-
-	corev1 "k8s.io/api/core/v1"
-	k8serrors "k8s.io/apimachinery/pkg/api/errors"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/rest"
-)
-
-// CreateConfigMap creates a ConfigMap in the specified namespace.
-// If the ConfigMap already exists, no action is taken.
-func CreateConfigMap(ctx context.Context, kubeConfig *rest.Config, configMap *corev1.ConfigMap) error {
-	clientset, err := kubernetes.NewForConfig(kubeConfig)
-	if err != nil {
-		return fmt.Errorf("failed to create kubernetes client: %v", err)
-	}
-
-	_, err = clientset.CoreV1().ConfigMaps(configMap.Namespace).Create(ctx, configMap, metav1.CreateOptions{})
-	if err != nil {
-		if k8serrors.IsAlreadyExists(err) {
-			return nil
-		}
-		return fmt.Errorf("failed to create ConfigMap: %v", err)
-	}
-
-	return nil
-}
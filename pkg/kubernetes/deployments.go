@@ -0,0 +1,102 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+// workloadConfig is the saved state of a scaled down workload
+type workloadConfig struct {
+	Replicas int32 `json:"replicas"`
+	// Automated is the automated sync policy of a suspended ArgoCD Application
+	Automated json.RawMessage `json:"automated,omitempty"`
+	// Ingress are the backends of an Ingress pointed at its sleep page
+	Ingress json.RawMessage `json:"ingress,omitempty"`
+}
+
+// Deployments returns the Deployments of the namespace by name, or by label selector if name is empty.
+// A missing Deployment isn't an error, there are none.
+func Deployments(ctx context.Context, client kubernetes.Interface, namespace, name, selector string) ([]appsv1.Deployment, error) {
+	if name != "" {
+		deployment, err := client.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+		if k8serrors.IsNotFound(err) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get deployment %s/%s: %v", namespace, name, err)
+		}
+		return []appsv1.Deployment{*deployment}, nil
+	}
+
+	deployments, err := client.AppsV1().Deployments(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deployments in %s: %v", namespace, err)
+	}
+	return deployments.Items, nil
+}
+
+// ScaleDeployment scales the Deployment to the replicas, and returns whether it had other replicas. Its replicas
+// are saved in a ConfigMap of the state namespace first, unless they are saved already by an earlier scale down.
+func ScaleDeployment(ctx context.Context, client kubernetes.Interface, stateNamespace string, deployment appsv1.Deployment, replicas int32) (bool, error) {
+	current := int32(1)
+	if deployment.Spec.Replicas != nil {
+		current = *deployment.Spec.Replicas
+	}
+	if current == replicas {
+		return false, nil
+	}
+
+	key := StateKey{Kind: StateKindDeployment, Namespace: deployment.Namespace, Name: deployment.Name}
+	if err := NewStateStore(client, stateNamespace).Save(ctx, key, workloadConfig{Replicas: current}); err != nil {
+		return false, err
+	}
+	if err := patchReplicas(ctx, client, "deployment", deployment.Namespace, deployment.Name, replicas); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// RestoreDeployment restores the Deployment to its saved replicas, and returns them, -1 if none were saved.
+// The saved replicas are deleted afterwards, so that replicas changed during work time are saved at the next
+// scale down.
+func RestoreDeployment(ctx context.Context, client kubernetes.Interface, stateNamespace string, deployment appsv1.Deployment) (int32, error) {
+	store := NewStateStore(client, stateNamespace)
+	key := StateKey{Kind: StateKindDeployment, Namespace: deployment.Namespace, Name: deployment.Name}
+	var config workloadConfig
+	if ok, err := store.Load(ctx, key, &config); err != nil || !ok {
+		return -1, err
+	}
+	saved := config.Replicas
+
+	if deployment.Spec.Replicas == nil || *deployment.Spec.Replicas != saved {
+		if err := patchReplicas(ctx, client, "deployment", deployment.Namespace, deployment.Name, saved); err != nil {
+			return -1, err
+		}
+	}
+	if err := store.Delete(ctx, key); err != nil {
+		return -1, err
+	}
+	return saved, nil
+}
+
+// patchReplicas sets the replicas of the Deployment or StatefulSet
+func patchReplicas(ctx context.Context, client kubernetes.Interface, kind, namespace, name string, replicas int32) error {
+	patch := []byte(fmt.Sprintf(`{"spec":{"replicas":%d}}`, replicas))
+	var err error
+	if kind == "statefulset" {
+		_, err = client.AppsV1().StatefulSets(namespace).Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{})
+	} else {
+		_, err = client.AppsV1().Deployments(namespace).Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{})
+	}
+	if err != nil {
+		return fmt.Errorf("failed to scale %s %s/%s: %v", kind, namespace, name, err)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestScaleAndRestoreDeployment(t *testing.T) {
+	replicas := int32(3)
+	client := fake.NewSimpleClientset(
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "web", Labels: map[string]string{"tier": "frontend"}},
+			Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+		},
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "worker", Labels: map[string]string{"tier": "backend"}},
+		},
+	)
+	ctx := context.Background()
+
+	deployments, err := Deployments(ctx, client, "team-a", "", "tier=frontend")
+	if err != nil || len(deployments) != 1 || deployments[0].Name != "web" {
+		t.Fatalf("Deployments() = %v, %v, want web", deployments, err)
+	}
+	if missing, err := Deployments(ctx, client, "team-a", "missing", ""); err != nil || len(missing) != 0 {
+		t.Errorf("Deployments() = %v, %v, want none", missing, err)
+	}
+
+	scaled, err := ScaleDeployment(ctx, client, "bmw-saver", deployments[0], 0)
+	if err != nil || !scaled {
+		t.Fatalf("ScaleDeployment() = %v, %v, want scaled", scaled, err)
+	}
+	if got := getReplicas(t, client, "web"); got != 0 {
+		t.Errorf("replicas = %d after scale down, want 0", got)
+	}
+
+	// Scaling down again keeps the replicas saved by the first scale down
+	deployments, _ = Deployments(ctx, client, "team-a", "web", "")
+	if scaled, err := ScaleDeployment(ctx, client, "bmw-saver", deployments[0], 0); err != nil || scaled {
+		t.Errorf("ScaleDeployment() = %v, %v, want not scaled", scaled, err)
+	}
+
+	restored, err := RestoreDeployment(ctx, client, "bmw-saver", deployments[0])
+	if err != nil || restored != 3 {
+		t.Fatalf("RestoreDeployment() = %d, %v, want 3", restored, err)
+	}
+	if got := getReplicas(t, client, "web"); got != 3 {
+		t.Errorf("replicas = %d after restore, want 3", got)
+	}
+	_, err = client.CoreV1().ConfigMaps("bmw-saver").Get(ctx, WorkloadConfigMapNamePrefix+"team-a.web", metav1.GetOptions{})
+	if !k8serrors.IsNotFound(err) {
+		t.Errorf("saved workload config error = %v, want deleted after restore", err)
+	}
+
+	// Nothing is restored without saved replicas
+	deployments, _ = Deployments(ctx, client, "team-a", "worker", "")
+	if restored, err := RestoreDeployment(ctx, client, "bmw-saver", deployments[0]); err != nil || restored != -1 {
+		t.Errorf("RestoreDeployment() = %d, %v, want -1", restored, err)
+	}
+}
+
+func getReplicas(t *testing.T, client *fake.Clientset, name string) int32 {
+	t.Helper()
+	deployment, err := client.AppsV1().Deployments("team-a").Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("failed to get deployment: %v", err)
+	}
+	return *deployment.Spec.Replicas
+}
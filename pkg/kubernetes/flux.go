@@ -0,0 +1,87 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+// fluxPaths are the API paths of the Flux resources, by kind
+var fluxPaths = map[string]string{
+	"Kustomization": "/apis/kustomize.toolkit.fluxcd.io/v1/namespaces/%s/kustomizations",
+	"HelmRelease":   "/apis/helm.toolkit.fluxcd.io/v2/namespaces/%s/helmreleases",
+}
+
+// fluxResource is the subset of a Flux Kustomization or HelmRelease we need
+type fluxResource struct {
+	Spec struct {
+		Suspend bool `json:"suspend"`
+	} `json:"spec"`
+}
+
+// SuspendFluxResource suspends the reconciliation of the Flux Kustomization or HelmRelease, so that it doesn't
+// undo scale downs, and returns whether it wasn't suspended. Resources suspended by others are left alone, a
+// ConfigMap of the state namespace records the resources suspended by bmw-saver.
+func SuspendFluxResource(ctx context.Context, client kubernetes.Interface, stateNamespace, kind, namespace, name string) (bool, error) {
+	restClient := client.Discovery().RESTClient()
+	if restClient == nil {
+		return false, fmt.Errorf("kubernetes API is not available")
+	}
+	path, ok := fluxPaths[kind]
+	if !ok {
+		return false, fmt.Errorf("unsupported Flux kind: %s", kind)
+	}
+	data, err := restClient.Get().AbsPath(fmt.Sprintf(path, namespace), name).DoRaw(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to get %s %s/%s: %v", kind, namespace, name, err)
+	}
+	var resource fluxResource
+	if err := json.Unmarshal(data, &resource); err != nil {
+		return false, fmt.Errorf("failed to parse %s %s/%s: %v", kind, namespace, name, err)
+	}
+	if resource.Spec.Suspend {
+		return false, nil
+	}
+
+	key := StateKey{Kind: kind, Namespace: namespace, Name: name}
+	if err := NewStateStore(client, stateNamespace).Save(ctx, key, workloadConfig{}); err != nil {
+		return false, err
+	}
+	if err := patchFluxResource(ctx, client, kind, namespace, name, true); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ResumeFluxResource resumes the reconciliation of the Flux Kustomization or HelmRelease, and returns whether it
+// was suspended by bmw-saver
+func ResumeFluxResource(ctx context.Context, client kubernetes.Interface, stateNamespace, kind, namespace, name string) (bool, error) {
+	store := NewStateStore(client, stateNamespace)
+	key := StateKey{Kind: kind, Namespace: namespace, Name: name}
+	if ok, err := store.Load(ctx, key, &workloadConfig{}); err != nil || !ok {
+		return false, err
+	}
+	if err := patchFluxResource(ctx, client, kind, namespace, name, false); err != nil {
+		return false, err
+	}
+	if err := store.Delete(ctx, key); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// patchFluxResource sets whether the Flux resource is suspended
+func patchFluxResource(ctx context.Context, client kubernetes.Interface, kind, namespace, name string, suspend bool) error {
+	restClient := client.Discovery().RESTClient()
+	if restClient == nil {
+		return fmt.Errorf("kubernetes API is not available")
+	}
+	patch := fmt.Sprintf(`{"spec":{"suspend":%t}}`, suspend)
+	if _, err := restClient.Patch(types.MergePatchType).AbsPath(fmt.Sprintf(fluxPaths[kind], namespace), name).Body([]byte(patch)).DoRaw(ctx); err != nil {
+		return fmt.Errorf("failed to patch %s %s/%s: %v", kind, namespace, name, err)
+	}
+	return nil
+}
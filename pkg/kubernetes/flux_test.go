@@ -0,0 +1,78 @@
+package kubernetes
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+func TestSuspendAndResumeFluxResource(t *testing.T) {
+	suspended := map[string]string{"apps": "false", "manual": "true"}
+	var configMap []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/apis/kustomize.toolkit.fluxcd.io/v1/namespaces/flux-system/kustomizations/"):
+			name := strings.TrimPrefix(r.URL.Path, "/apis/kustomize.toolkit.fluxcd.io/v1/namespaces/flux-system/kustomizations/")
+			if r.Method == http.MethodPatch {
+				suspended[name] = strings.TrimSuffix(strings.TrimPrefix(string(body), `{"spec":{"suspend":`), "}}")
+			}
+			_, _ = w.Write([]byte(`{"spec": {"suspend": ` + suspended[name] + `}}`))
+		case r.URL.Path == "/api/v1/namespaces/bmw-saver/configmaps" && r.Method == http.MethodPost:
+			configMap = body
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write(body)
+		case strings.HasPrefix(r.URL.Path, "/api/v1/namespaces/bmw-saver/configmaps/"):
+			if configMap == nil || r.URL.Path != "/api/v1/namespaces/bmw-saver/configmaps/bmw-saver-workload-flux-system.apps.kustomization" {
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte(`{"kind": "Status", "apiVersion": "v1", "status": "Failure", "reason": "NotFound", "code": 404}`))
+				return
+			}
+			if r.Method == http.MethodDelete {
+				configMap = nil
+				_, _ = w.Write([]byte(`{"kind": "Status", "apiVersion": "v1", "status": "Success"}`))
+				return
+			}
+			_, _ = w.Write(configMap)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	client := kubernetes.NewForConfigOrDie(&rest.Config{Host: server.URL, ContentConfig: rest.ContentConfig{ContentType: "application/json"}})
+	ctx := context.Background()
+
+	if ok, err := SuspendFluxResource(ctx, client, "bmw-saver", "Kustomization", "flux-system", "apps"); err != nil || !ok {
+		t.Fatalf("SuspendFluxResource() = %v, %v, want suspended", ok, err)
+	}
+	if suspended["apps"] != "true" {
+		t.Errorf("suspend = %s, want true", suspended["apps"])
+	}
+	if ok, err := SuspendFluxResource(ctx, client, "bmw-saver", "Kustomization", "flux-system", "apps"); err != nil || ok {
+		t.Errorf("SuspendFluxResource() = %v, %v, want suspended already", ok, err)
+	}
+	if ok, err := SuspendFluxResource(ctx, client, "bmw-saver", "Kustomization", "flux-system", "manual"); err != nil || ok {
+		t.Errorf("SuspendFluxResource() = %v, %v, want suspended by others", ok, err)
+	}
+
+	if ok, err := ResumeFluxResource(ctx, client, "bmw-saver", "Kustomization", "flux-system", "apps"); err != nil || !ok {
+		t.Fatalf("ResumeFluxResource() = %v, %v, want resumed", ok, err)
+	}
+	if suspended["apps"] != "false" || configMap != nil {
+		t.Errorf("suspend = %s, saved = %s, want resumed and deleted", suspended["apps"], configMap)
+	}
+	if ok, err := ResumeFluxResource(ctx, client, "bmw-saver", "Kustomization", "flux-system", "manual"); err != nil || ok {
+		t.Errorf("ResumeFluxResource() = %v, %v, want the suspension of others kept", ok, err)
+	}
+	if suspended["manual"] != "true" {
+		t.Errorf("suspend = %s, want true", suspended["manual"])
+	}
+}
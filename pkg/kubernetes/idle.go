@@ -0,0 +1,260 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/client-go/kubernetes"
+)
+
+// mirrorPodAnnotation is set on the API server's mirrors of static pods
+const mirrorPodAnnotation = "kubernetes.io/config.mirror"
+
+// nodeMetricsList is the subset of the metrics.k8s.io NodeMetricsList we need
+type nodeMetricsList struct {
+	Items []struct {
+		Usage map[string]resource.Quantity `json:"usage"`
+	} `json:"items"`
+}
+
+// UserPods returns the pods on the nodes of the node pool that use it: unfinished pods outside of kube-system
+// and the excluded namespaces, besides the pods of DaemonSets and static pods, which run on every node anyway
+func UserPods(ctx context.Context, client kubernetes.Interface, cloudProvider, nodePoolName string, excluded map[string]bool) ([]string, error) {
+	pods, err := nodePoolPods(ctx, client, cloudProvider, nodePoolName)
+	if err != nil {
+		return nil, err
+	}
+
+	var used []string
+	for _, pod := range pods {
+		if isUserPod(&pod, excluded) {
+			used = append(used, fmt.Sprintf("pod %s/%s", pod.Namespace, pod.Name))
+		}
+	}
+	return used, nil
+}
+
+// isUserPod returns true if the pod is an unfinished pod of a user workload
+func isUserPod(pod *corev1.Pod, excluded map[string]bool) bool {
+	if pod.Namespace == "kube-system" || excluded[pod.Namespace] {
+		return false
+	}
+	if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+		return false
+	}
+	if _, ok := pod.Annotations[mirrorPodAnnotation]; ok {
+		return false
+	}
+	for _, owner := range pod.OwnerReferences {
+		if owner.Kind == "DaemonSet" {
+			return false
+		}
+	}
+	return true
+}
+
+// NodePoolTemplate returns a node standing for the nodes of the node pool, with the labels, taints and allocatable
+// resources of one of its nodes, so that pods waiting for the node pool can be told apart once it has no nodes.
+// Taints of the node's conditions or of it being removed are left out. Without nodes, it only has the node pool
+// label.
+func NodePoolTemplate(ctx context.Context, client kubernetes.Interface, cloudProvider, nodePoolName string) (*corev1.Node, error) {
+	label, ok := NodePoolLabels[cloudProvider]
+	if !ok {
+		return nil, fmt.Errorf("unsupported cloud provider: %s", cloudProvider)
+	}
+	nodes, err := NodePoolNodes(ctx, client, cloudProvider, nodePoolName)
+	if err != nil {
+		return nil, err
+	}
+
+	template := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{label: nodePoolName}}}
+	if len(nodes) == 0 {
+		return template, nil
+	}
+	for key, value := range nodes[0].Labels {
+		template.Labels[key] = value
+	}
+	for _, taint := range nodes[0].Spec.Taints {
+		if taint.Key == ShuttingDownTaint || strings.HasPrefix(taint.Key, "node.kubernetes.io/") || taint.Key == toBeDeletedTaint {
+			continue
+		}
+		template.Spec.Taints = append(template.Spec.Taints, taint)
+	}
+	template.Status.Allocatable = nodes[0].Status.Allocatable.DeepCopy()
+	return template, nil
+}
+
+// toBeDeletedTaint is set by the cluster autoscaler on nodes it removes
+const toBeDeletedTaint = "ToBeDeletedByClusterAutoscaler"
+
+// UnschedulablePods returns the pods that can't be scheduled and may be scheduled on the nodes of the node pool,
+// as the template stands for them: their node selector and required node affinity match it, they tolerate its
+// taints and fit onto it. Pods that can't be scheduled for other reasons, e.g. unbound volume claims, and pods in
+// kube-system and the excluded namespaces are ignored.
+func UnschedulablePods(ctx context.Context, client kubernetes.Interface, template *corev1.Node, excluded map[string]bool) ([]string, error) {
+	pods, err := client.CoreV1().Pods("").List(ctx, metav1.ListOptions{
+		FieldSelector: "status.phase=Pending,spec.nodeName=",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pending pods: %v", err)
+	}
+
+	var unschedulable []string
+	for _, pod := range pods.Items {
+		if pod.Spec.NodeName != "" || !isUserPod(&pod, excluded) || !isUnschedulable(&pod) {
+			continue
+		}
+		if !schedulableOn(&pod, template) {
+			continue
+		}
+		unschedulable = append(unschedulable, fmt.Sprintf("pod %s/%s", pod.Namespace, pod.Name))
+	}
+	return unschedulable, nil
+}
+
+// schedulableOn returns true if the pod could be scheduled on the node, by its node selector, required node
+// affinity, tolerations and resource requests, and doesn't wait for unbound volume claims
+func schedulableOn(pod *corev1.Pod, node *corev1.Node) bool {
+	nodeLabels := labels.Set(node.Labels)
+	if !labels.SelectorFromSet(pod.Spec.NodeSelector).Matches(nodeLabels) {
+		return false
+	}
+	if affinity := pod.Spec.Affinity; affinity != nil && affinity.NodeAffinity != nil {
+		if required := affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution; required != nil && !matchesNodeSelector(required, nodeLabels) {
+			return false
+		}
+	}
+	for i := range node.Spec.Taints {
+		taint := &node.Spec.Taints[i]
+		if taint.Effect == corev1.TaintEffectPreferNoSchedule {
+			continue
+		}
+		tolerated := false
+		for j := range pod.Spec.Tolerations {
+			if pod.Spec.Tolerations[j].ToleratesTaint(taint) {
+				tolerated = true
+				break
+			}
+		}
+		if !tolerated {
+			return false
+		}
+	}
+	for name, requested := range podRequests(pod) {
+		if allocatable, ok := node.Status.Allocatable[name]; ok && requested.Cmp(allocatable) > 0 {
+			return false
+		}
+	}
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodScheduled && strings.Contains(condition.Message, "unbound immediate PersistentVolumeClaims") {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesNodeSelector returns true if any term of the node selector matches the labels, terms matching fields
+// never match as the node is a template
+func matchesNodeSelector(selector *corev1.NodeSelector, nodeLabels labels.Set) bool {
+	operators := map[corev1.NodeSelectorOperator]selection.Operator{
+		corev1.NodeSelectorOpIn:           selection.In,
+		corev1.NodeSelectorOpNotIn:        selection.NotIn,
+		corev1.NodeSelectorOpExists:       selection.Exists,
+		corev1.NodeSelectorOpDoesNotExist: selection.DoesNotExist,
+		corev1.NodeSelectorOpGt:           selection.GreaterThan,
+		corev1.NodeSelectorOpLt:           selection.LessThan,
+	}
+	for _, term := range selector.NodeSelectorTerms {
+		if len(term.MatchFields) > 0 || len(term.MatchExpressions) == 0 {
+			continue
+		}
+		matches := true
+		for _, expression := range term.MatchExpressions {
+			operator, ok := operators[expression.Operator]
+			requirement, err := labels.NewRequirement(expression.Key, operator, expression.Values)
+			if !ok || err != nil || !requirement.Matches(nodeLabels) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return true
+		}
+	}
+	return false
+}
+
+// podRequests returns the resources requested by the pod, the larger of the sum of its containers and any of its
+// init containers
+func podRequests(pod *corev1.Pod) corev1.ResourceList {
+	requests := corev1.ResourceList{}
+	for _, container := range pod.Spec.Containers {
+		for name, quantity := range container.Resources.Requests {
+			total := requests[name]
+			total.Add(quantity)
+			requests[name] = total
+		}
+	}
+	for _, container := range pod.Spec.InitContainers {
+		for name, quantity := range container.Resources.Requests {
+			if quantity.Cmp(requests[name]) > 0 {
+				requests[name] = quantity.DeepCopy()
+			}
+		}
+	}
+	return requests
+}
+
+// isUnschedulable returns true if the scheduler couldn't find a node for the pod
+func isUnschedulable(pod *corev1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodScheduled && condition.Status == corev1.ConditionFalse {
+			return condition.Reason == corev1.PodReasonUnschedulable
+		}
+	}
+	return false
+}
+
+// NodePoolCPUUsage returns the average CPU usage of the nodes of the node pool reported by metrics-server,
+// and how many nodes reported it
+func NodePoolCPUUsage(ctx context.Context, client kubernetes.Interface, cloudProvider, nodePoolName string) (resource.Quantity, int, error) {
+	label, ok := NodePoolLabels[cloudProvider]
+	if !ok {
+		return resource.Quantity{}, 0, fmt.Errorf("unsupported cloud provider: %s", cloudProvider)
+	}
+
+	restClient := client.Discovery().RESTClient()
+	if restClient == nil {
+		return resource.Quantity{}, 0, fmt.Errorf("metrics API is not available")
+	}
+	data, err := restClient.Get().
+		AbsPath("/apis/metrics.k8s.io/v1beta1/nodes").
+		Param("labelSelector", fmt.Sprintf("%s=%s", label, nodePoolName)).
+		DoRaw(ctx)
+	if err != nil {
+		return resource.Quantity{}, 0, fmt.Errorf("failed to query metrics API: %v", err)
+	}
+
+	var metrics nodeMetricsList
+	if err := json.Unmarshal(data, &metrics); err != nil {
+		return resource.Quantity{}, 0, fmt.Errorf("failed to parse node metrics: %v", err)
+	}
+	if len(metrics.Items) == 0 {
+		return resource.Quantity{}, 0, nil
+	}
+
+	var total int64
+	for _, item := range metrics.Items {
+		if cpu, ok := item.Usage["cpu"]; ok {
+			total += cpu.MilliValue()
+		}
+	}
+	return *resource.NewMilliQuantity(total/int64(len(metrics.Items)), resource.DecimalSI), len(metrics.Items), nil
+}
@@ -0,0 +1,126 @@
+package kubernetes
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestUserPods(t *testing.T) {
+	pod := func(namespace, name, node string, mutate func(*corev1.Pod)) *corev1.Pod {
+		p := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+			Spec:       corev1.PodSpec{NodeName: node},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+		}
+		if mutate != nil {
+			mutate(p)
+		}
+		return p
+	}
+	unschedulable := func(p *corev1.Pod) {
+		p.Status.Phase = corev1.PodPending
+		p.Status.Conditions = []corev1.PodCondition{{
+			Type:   corev1.PodScheduled,
+			Status: corev1.ConditionFalse,
+			Reason: corev1.PodReasonUnschedulable,
+		}}
+		p.Spec.Tolerations = []corev1.Toleration{{Key: "dedicated", Operator: corev1.TolerationOpEqual, Value: "batch"}}
+	}
+	requiring := func(operator corev1.NodeSelectorOperator, key string, values ...string) *corev1.Affinity {
+		return &corev1.Affinity{NodeAffinity: &corev1.NodeAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{NodeSelectorTerms: []corev1.NodeSelectorTerm{{
+				MatchExpressions: []corev1.NodeSelectorRequirement{{Key: key, Operator: operator, Values: values}},
+			}}},
+		}}
+	}
+
+	client := newFakeClientset(
+		&corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "node-1", Labels: map[string]string{"cloud.google.com/gke-nodepool": "default-pool", "zone": "a"}},
+			Spec: corev1.NodeSpec{Taints: []corev1.Taint{
+				{Key: "dedicated", Value: "batch", Effect: corev1.TaintEffectNoSchedule},
+				{Key: ShuttingDownTaint, Effect: corev1.TaintEffectNoSchedule},
+			}},
+			Status: corev1.NodeStatus{Allocatable: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("2")}},
+		},
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-2", Labels: map[string]string{"cloud.google.com/gke-nodepool": "other-pool"}}},
+		pod("default", "agent", "node-1", func(p *corev1.Pod) {
+			p.OwnerReferences = []metav1.OwnerReference{{Kind: "DaemonSet", Name: "agent"}}
+		}),
+		pod("default", "static", "node-1", func(p *corev1.Pod) {
+			p.Annotations = map[string]string{mirrorPodAnnotation: "abc"}
+		}),
+		pod("default", "done", "node-1", func(p *corev1.Pod) { p.Status.Phase = corev1.PodSucceeded }),
+		pod("kube-system", "dns", "node-1", nil),
+		pod("monitoring", "prometheus", "node-1", nil),
+		pod("default", "web", "node-2", nil),
+		pod("default", "pending", "", unschedulable),
+		pod("default", "pending-elsewhere", "", func(p *corev1.Pod) {
+			unschedulable(p)
+			p.Spec.NodeSelector = map[string]string{"cloud.google.com/gke-nodepool": "other-pool"}
+		}),
+		pod("default", "pending-here", "", func(p *corev1.Pod) {
+			unschedulable(p)
+			p.Spec.NodeSelector = map[string]string{"cloud.google.com/gke-nodepool": "default-pool"}
+		}),
+		pod("default", "pending-affinity", "", func(p *corev1.Pod) {
+			unschedulable(p)
+			p.Spec.Affinity = requiring(corev1.NodeSelectorOpIn, "zone", "a", "b")
+		}),
+		pod("default", "pending-other-zone", "", func(p *corev1.Pod) {
+			unschedulable(p)
+			p.Spec.Affinity = requiring(corev1.NodeSelectorOpNotIn, "zone", "a")
+		}),
+		pod("default", "pending-untolerated", "", func(p *corev1.Pod) {
+			unschedulable(p)
+			p.Spec.Tolerations = nil
+		}),
+		pod("default", "pending-too-large", "", func(p *corev1.Pod) {
+			unschedulable(p)
+			p.Spec.Containers = []corev1.Container{{Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("3")},
+			}}}
+		}),
+		pod("default", "pending-volume", "", func(p *corev1.Pod) {
+			unschedulable(p)
+			p.Status.Conditions[0].Message = "0/3 nodes are available: pod has unbound immediate PersistentVolumeClaims."
+		}),
+		pod("default", "scheduling", "", func(p *corev1.Pod) { p.Status.Phase = corev1.PodPending }),
+	)
+	excluded := map[string]bool{"monitoring": true}
+
+	used, err := UserPods(context.Background(), client, "gke", "default-pool", excluded)
+	if err != nil {
+		t.Fatalf("UserPods() error = %v", err)
+	}
+	if len(used) != 0 {
+		t.Errorf("UserPods() = %v, want none", used)
+	}
+	used, err = UserPods(context.Background(), client, "gke", "other-pool", excluded)
+	if err != nil {
+		t.Fatalf("UserPods() error = %v", err)
+	}
+	if want := []string{"pod default/web"}; !reflect.DeepEqual(used, want) {
+		t.Errorf("UserPods() = %v, want %v", used, want)
+	}
+
+	template, err := NodePoolTemplate(context.Background(), client, "gke", "default-pool")
+	if err != nil {
+		t.Fatalf("NodePoolTemplate() error = %v", err)
+	}
+	if len(template.Spec.Taints) != 1 {
+		t.Errorf("NodePoolTemplate() taints = %v, want only the dedicated taint", template.Spec.Taints)
+	}
+	waiting, err := UnschedulablePods(context.Background(), client, template, excluded)
+	if err != nil {
+		t.Fatalf("UnschedulablePods() error = %v", err)
+	}
+	if want := []string{"pod default/pending", "pod default/pending-affinity", "pod default/pending-here"}; !reflect.DeepEqual(waiting, want) {
+		t.Errorf("UnschedulablePods() = %v, want %v", waiting, want)
+	}
+}
@@ -0,0 +1,103 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// nodeResyncPeriod is how often the cached nodes are resynced
+const nodeResyncPeriod = 10 * time.Minute
+
+// NodeLister looks up nodes in the cache of a shared node informer, so that looking up the nodes of
+// every node pool in every reconciliation doesn't list them from the API server.
+// Until the cache is synced, nodes are looked up from the API server.
+type NodeLister struct {
+	client   kubernetes.Interface
+	factory  informers.SharedInformerFactory
+	informer cache.SharedIndexInformer
+	lister   corelisters.NodeLister
+}
+
+// NewNodeLister creates a node lister, the informer isn't started until Start is called
+func NewNodeLister(client kubernetes.Interface) *NodeLister {
+	factory := informers.NewSharedInformerFactory(client, nodeResyncPeriod)
+	nodes := factory.Core().V1().Nodes()
+	return &NodeLister{
+		client:   client,
+		factory:  factory,
+		informer: nodes.Informer(),
+		lister:   nodes.Lister(),
+	}
+}
+
+// Start starts the informer and waits until its cache is synced.
+// The informer stops with the context, it returns false if the context is done before the cache is synced.
+func (l *NodeLister) Start(ctx context.Context) bool {
+	l.factory.Start(ctx.Done())
+	return cache.WaitForCacheSync(ctx.Done(), l.informer.HasSynced)
+}
+
+// NodePoolNodes returns the nodes of the node pool, by the node pool label of the cloud provider.
+// The nodes are shared with the cache and must not be modified.
+func (l *NodeLister) NodePoolNodes(ctx context.Context, cloudProvider, nodePoolName string) ([]corev1.Node, error) {
+	if !l.informer.HasSynced() {
+		return NodePoolNodes(ctx, l.client, cloudProvider, nodePoolName)
+	}
+
+	label, ok := NodePoolLabels[cloudProvider]
+	if !ok {
+		return nil, fmt.Errorf("unsupported cloud provider: %s", cloudProvider)
+	}
+
+	cached, err := l.lister.List(labels.SelectorFromSet(labels.Set{label: nodePoolName}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %v", err)
+	}
+	nodes := make([]corev1.Node, 0, len(cached))
+	for _, node := range cached {
+		nodes = append(nodes, *node)
+	}
+	return nodes, nil
+}
+
+// Node returns the node by name.
+// The node is shared with the cache and must not be modified.
+func (l *NodeLister) Node(ctx context.Context, name string) (*corev1.Node, error) {
+	if !l.informer.HasSynced() {
+		return l.client.CoreV1().Nodes().Get(ctx, name, metav1.GetOptions{})
+	}
+	return l.lister.Get(name)
+}
+
+// OnChange calls handler with the nodes added or deleted after the initial list, e.g. by the cluster autoscaler.
+// The nodes are shared with the cache and must not be modified.
+func (l *NodeLister) OnChange(handler func(node *corev1.Node)) error {
+	_, err := l.informer.AddEventHandler(cache.ResourceEventHandlerDetailedFuncs{
+		AddFunc: func(obj interface{}, isInInitialList bool) {
+			if node, ok := obj.(*corev1.Node); ok && !isInInitialList {
+				handler(node)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if node, ok := obj.(*corev1.Node); ok {
+				handler(node)
+			}
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to add node event handler: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package kubernetes
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestNodeLister(t *testing.T) {
+	node := func(name, nodePool string) *corev1.Node {
+		return &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"cloud.google.com/gke-nodepool": nodePool}}}
+	}
+	client := fake.NewSimpleClientset(node("node-1", "default-pool"), node("node-2", "other-pool"))
+	lister := NewNodeLister(client)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	names := func() []string {
+		t.Helper()
+		nodes, err := lister.NodePoolNodes(ctx, "gke", "default-pool")
+		if err != nil {
+			t.Fatalf("NodePoolNodes() error = %v", err)
+		}
+		var names []string
+		for _, node := range nodes {
+			names = append(names, node.Name)
+		}
+		sort.Strings(names)
+		return names
+	}
+
+	// Nodes are listed from the API server until the informer is started
+	if got := names(); len(got) != 1 || got[0] != "node-1" {
+		t.Errorf("NodePoolNodes() before sync = %v, want [node-1]", got)
+	}
+
+	changed := make(chan string, 10)
+	if err := lister.OnChange(func(node *corev1.Node) { changed <- node.Name }); err != nil {
+		t.Fatalf("OnChange() error = %v", err)
+	}
+
+	if !lister.Start(ctx) {
+		t.Fatalf("Start() = false, want cache synced")
+	}
+	listed := len(client.Actions())
+	if got := names(); len(got) != 1 || got[0] != "node-1" {
+		t.Errorf("NodePoolNodes() = %v, want [node-1]", got)
+	}
+	if _, err := lister.Node(ctx, "node-2"); err != nil {
+		t.Errorf("Node() error = %v", err)
+	}
+	if len(client.Actions()) != listed {
+		t.Errorf("Nodes looked up from the API server after the cache is synced: %v", client.Actions()[listed:])
+	}
+
+	if _, err := client.CoreV1().Nodes().Create(ctx, node("node-3", "default-pool"), metav1.CreateOptions{}); err != nil {
+		t.Fatalf("Failed to create node: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for len(names()) != 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("NodePoolNodes() = %v, want the created node", names())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// The initially listed nodes aren't changes
+	if got := <-changed; got != "node-3" {
+		t.Errorf("OnChange() called with %s, want node-3", got)
+	}
+
+	if _, err := lister.NodePoolNodes(ctx, "unknown", "default-pool"); err == nil {
+		t.Errorf("NodePoolNodes() of an unsupported cloud provider succeeded")
+	}
+}
@@ -0,0 +1,126 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// ScaledObjectGroupVersion is the API group and version of KEDA ScaledObjects
+	ScaledObjectGroupVersion = "keda.sh/v1alpha1"
+	// PausedReplicasAnnotation on a ScaledObject makes KEDA scale its target to the replicas and stop autoscaling it
+	PausedReplicasAnnotation = "autoscaling.keda.sh/paused-replicas"
+	// PausedByAnnotation marks ScaledObjects paused by bmw-saver, so that ScaledObjects paused by others are left alone
+	PausedByAnnotation = "bmw-saver.io/paused"
+)
+
+// ScaledObject is the subset of a KEDA ScaledObject we need
+type ScaledObject struct {
+	Metadata struct {
+		Name        string            `json:"name"`
+		Namespace   string            `json:"namespace"`
+		Annotations map[string]string `json:"annotations"`
+	} `json:"metadata"`
+}
+
+// scaledObjectList is a list of KEDA ScaledObjects
+type scaledObjectList struct {
+	Items []ScaledObject `json:"items"`
+}
+
+// ScaledObjects returns the KEDA ScaledObjects of the namespace by name, or by label selector if name is empty.
+// A missing ScaledObject isn't an error, there are none.
+func ScaledObjects(ctx context.Context, client kubernetes.Interface, namespace, name, selector string) ([]ScaledObject, error) {
+	restClient := client.Discovery().RESTClient()
+	if restClient == nil {
+		return nil, fmt.Errorf("kubernetes API is not available")
+	}
+	path := "/apis/" + ScaledObjectGroupVersion + "/namespaces/" + namespace + "/scaledobjects"
+
+	if name != "" {
+		result := restClient.Get().AbsPath(path, name).Do(ctx)
+		var statusCode int
+		if result.StatusCode(&statusCode); statusCode == http.StatusNotFound {
+			return nil, nil
+		}
+		data, err := result.Raw()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get ScaledObject %s/%s: %v", namespace, name, err)
+		}
+		var scaledObject ScaledObject
+		if err := json.Unmarshal(data, &scaledObject); err != nil {
+			return nil, fmt.Errorf("failed to parse ScaledObject %s/%s: %v", namespace, name, err)
+		}
+		return []ScaledObject{scaledObject}, nil
+	}
+
+	data, err := restClient.Get().AbsPath(path).Param("labelSelector", selector).DoRaw(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ScaledObjects in %s: %v", namespace, err)
+	}
+	var list scaledObjectList
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse ScaledObjects: %v", err)
+	}
+	return list.Items, nil
+}
+
+// PauseScaledObject pauses the ScaledObject at the replicas, and returns whether it wasn't paused at them already.
+// ScaledObjects paused by others aren't changed.
+func PauseScaledObject(ctx context.Context, client kubernetes.Interface, scaledObject ScaledObject, replicas int32) (bool, error) {
+	annotations := scaledObject.Metadata.Annotations
+	paused, isPaused := annotations[PausedReplicasAnnotation]
+	if isPaused && annotations[PausedByAnnotation] != "true" {
+		return false, nil
+	}
+	if paused == strconv.Itoa(int(replicas)) {
+		return false, nil
+	}
+	if err := patchScaledObjectAnnotations(ctx, client, scaledObject, map[string]interface{}{
+		PausedReplicasAnnotation: strconv.Itoa(int(replicas)),
+		PausedByAnnotation:       "true",
+	}); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ResumeScaledObject removes the pause of the ScaledObject, and returns whether it was paused by bmw-saver
+func ResumeScaledObject(ctx context.Context, client kubernetes.Interface, scaledObject ScaledObject) (bool, error) {
+	if scaledObject.Metadata.Annotations[PausedByAnnotation] != "true" {
+		return false, nil
+	}
+	// Null values remove the annotations with a merge patch
+	if err := patchScaledObjectAnnotations(ctx, client, scaledObject, map[string]interface{}{
+		PausedReplicasAnnotation: nil,
+		PausedByAnnotation:       nil,
+	}); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// patchScaledObjectAnnotations merges the annotations into the ScaledObject
+func patchScaledObjectAnnotations(ctx context.Context, client kubernetes.Interface, scaledObject ScaledObject, annotations map[string]interface{}) error {
+	restClient := client.Discovery().RESTClient()
+	if restClient == nil {
+		return fmt.Errorf("kubernetes API is not available")
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{"annotations": annotations},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal patch: %v", err)
+	}
+	path := "/apis/" + ScaledObjectGroupVersion + "/namespaces/" + scaledObject.Metadata.Namespace + "/scaledobjects"
+	if _, err := restClient.Patch(types.MergePatchType).AbsPath(path, scaledObject.Metadata.Name).Body(patch).DoRaw(ctx); err != nil {
+		return fmt.Errorf("failed to patch ScaledObject %s/%s: %v", scaledObject.Metadata.Namespace, scaledObject.Metadata.Name, err)
+	}
+	return nil
+}
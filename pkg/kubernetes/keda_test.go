@@ -0,0 +1,98 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+func TestPauseAndResumeScaledObject(t *testing.T) {
+	var patches []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPatch:
+			body, _ := io.ReadAll(r.Body)
+			patches = append(patches, r.URL.Path+" "+string(body))
+			_, _ = w.Write([]byte(`{}`))
+		case strings.HasSuffix(r.URL.Path, "/scaledobjects/missing"):
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"kind": "Status", "apiVersion": "v1", "code": 404}`))
+		case strings.HasSuffix(r.URL.Path, "/scaledobjects/consumer"):
+			_, _ = w.Write([]byte(`{"metadata": {"name": "consumer", "namespace": "team-a"}}`))
+		default:
+			if got := r.URL.Query().Get("labelSelector"); got != "tier=batch" {
+				t.Errorf("labelSelector = %q, want tier=batch", got)
+			}
+			_, _ = w.Write([]byte(`{"items": [
+				{"metadata": {"name": "ours", "namespace": "team-a", "annotations": {"autoscaling.keda.sh/paused-replicas": "0", "bmw-saver.io/paused": "true"}}},
+				{"metadata": {"name": "theirs", "namespace": "team-a", "annotations": {"autoscaling.keda.sh/paused-replicas": "2"}}}
+			]}`))
+		}
+	}))
+	defer server.Close()
+	client := kubernetes.NewForConfigOrDie(&rest.Config{Host: server.URL})
+	ctx := context.Background()
+
+	if missing, err := ScaledObjects(ctx, client, "team-a", "missing", ""); err != nil || len(missing) != 0 {
+		t.Errorf("ScaledObjects() = %v, %v, want none", missing, err)
+	}
+
+	scaledObjects, err := ScaledObjects(ctx, client, "team-a", "consumer", "")
+	if err != nil || len(scaledObjects) != 1 {
+		t.Fatalf("ScaledObjects() = %v, %v, want consumer", scaledObjects, err)
+	}
+	if paused, err := PauseScaledObject(ctx, client, scaledObjects[0], 0); err != nil || !paused {
+		t.Fatalf("PauseScaledObject() = %v, %v, want paused", paused, err)
+	}
+	if resumed, err := ResumeScaledObject(ctx, client, scaledObjects[0]); err != nil || resumed {
+		t.Errorf("ResumeScaledObject() = %v, %v, want not resumed without the pause of bmw-saver", resumed, err)
+	}
+
+	scaledObjects, err = ScaledObjects(ctx, client, "team-a", "", "tier=batch")
+	if err != nil || len(scaledObjects) != 2 {
+		t.Fatalf("ScaledObjects() = %v, %v, want ours and theirs", scaledObjects, err)
+	}
+	// Already paused at the replicas, or paused by others
+	for _, scaledObject := range scaledObjects {
+		if paused, err := PauseScaledObject(ctx, client, scaledObject, 0); err != nil || paused {
+			t.Errorf("PauseScaledObject(%s) = %v, %v, want not paused", scaledObject.Metadata.Name, paused, err)
+		}
+	}
+	if resumed, err := ResumeScaledObject(ctx, client, scaledObjects[0]); err != nil || !resumed {
+		t.Errorf("ResumeScaledObject() = %v, %v, want resumed", resumed, err)
+	}
+	if resumed, err := ResumeScaledObject(ctx, client, scaledObjects[1]); err != nil || resumed {
+		t.Errorf("ResumeScaledObject() = %v, %v, want the pause of others kept", resumed, err)
+	}
+
+	if len(patches) != 2 {
+		t.Fatalf("patches = %v, want 2", patches)
+	}
+	for i, want := range []map[string]interface{}{
+		{PausedReplicasAnnotation: "0", PausedByAnnotation: "true"},
+		{PausedReplicasAnnotation: nil, PausedByAnnotation: nil},
+	} {
+		path, body, _ := strings.Cut(patches[i], " ")
+		var patch struct {
+			Metadata struct {
+				Annotations map[string]interface{} `json:"annotations"`
+			} `json:"metadata"`
+		}
+		if err := json.Unmarshal([]byte(body), &patch); err != nil {
+			t.Fatalf("failed to parse patch: %v", err)
+		}
+		if len(patch.Metadata.Annotations) != 2 || patch.Metadata.Annotations[PausedReplicasAnnotation] != want[PausedReplicasAnnotation] {
+			t.Errorf("patch %d = %s, want annotations %v", i, body, want)
+		}
+		if !strings.HasPrefix(path, "/apis/keda.sh/v1alpha1/namespaces/team-a/scaledobjects/") {
+			t.Errorf("patch %d path = %s", i, path)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// KeepAliveAnnotation postpones scaling down node pools until the RFC 3339 time, on the control ConfigMap
+	// of a node pool or on a namespace with pods on the node pool
+	KeepAliveAnnotation = "bmw-saver.io/keep-alive-until"
+	// KeepAliveByAnnotation names who set the keep-alive, the field manager that set it is used if it's not set
+	KeepAliveByAnnotation = "bmw-saver.io/keep-alive-by"
+)
+
+// KeepAlive is an unexpired keep-alive annotation
+type KeepAlive struct {
+	// Object is the annotated object, e.g. "namespace/team-a"
+	Object string
+	// Until is when the keep-alive expires
+	Until time.Time
+	// By is who set the keep-alive, empty if not known
+	By string
+}
+
+// String returns the keep-alive for logs and Events
+func (k KeepAlive) String() string {
+	s := fmt.Sprintf("%s until %s", k.Object, k.Until.Format(time.RFC3339))
+	if k.By != "" {
+		s += " by " + k.By
+	}
+	return s
+}
+
+// KeepAlives returns the unexpired keep-alives of the node pool, on its control ConfigMap and on the namespaces
+// with pods on its nodes, soonest expiring first. Invalid annotations are ignored.
+func KeepAlives(ctx context.Context, client kubernetes.Interface, configMapNamespace, configMapName, cloudProvider, nodePoolName string, now time.Time) ([]KeepAlive, error) {
+	var keepAlives []KeepAlive
+	add := func(object string, meta metav1.ObjectMeta) {
+		if keepAlive, ok := keepAliveOf(object, meta, now); ok {
+			keepAlives = append(keepAlives, keepAlive)
+		}
+	}
+
+	cm, err := client.CoreV1().ConfigMaps(configMapNamespace).Get(ctx, configMapName, metav1.GetOptions{})
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return nil, fmt.Errorf("failed to get configmap %s: %v", configMapName, err)
+	}
+	if err == nil {
+		add("configmap/"+configMapName, cm.ObjectMeta)
+	}
+
+	pods, err := nodePoolPods(ctx, client, cloudProvider, nodePoolName)
+	if err != nil {
+		return nil, err
+	}
+	namespaces := make(map[string]bool)
+	for _, pod := range pods {
+		if namespaces[pod.Namespace] {
+			continue
+		}
+		namespaces[pod.Namespace] = true
+		namespace, err := client.CoreV1().Namespaces().Get(ctx, pod.Namespace, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get namespace %s: %v", pod.Namespace, err)
+		}
+		add("namespace/"+pod.Namespace, namespace.ObjectMeta)
+	}
+
+	sort.Slice(keepAlives, func(i, j int) bool { return keepAlives[i].Until.Before(keepAlives[j].Until) })
+	return keepAlives, nil
+}
+
+// keepAliveOf returns the keep-alive annotation of the object, false if it has none or it expired
+func keepAliveOf(object string, meta metav1.ObjectMeta, now time.Time) (KeepAlive, bool) {
+	value, ok := meta.Annotations[KeepAliveAnnotation]
+	if !ok {
+		return KeepAlive{}, false
+	}
+	until, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		slog.Warn("Invalid keep-alive annotation", "object", object, "annotation", KeepAliveAnnotation, "value", value)
+		return KeepAlive{}, false
+	}
+	if !until.After(now) {
+		return KeepAlive{}, false
+	}
+
+	by := meta.Annotations[KeepAliveByAnnotation]
+	if by == "" {
+		by = keepAliveManager(meta.ManagedFields)
+	}
+	return KeepAlive{Object: object, Until: until, By: by}, true
+}
+
+// keepAliveManager returns the field manager that last set the keep-alive annotation, e.g. "kubectl-annotate"
+func keepAliveManager(managedFields []metav1.ManagedFieldsEntry) string {
+	var manager string
+	var at time.Time
+	for _, entry := range managedFields {
+		if entry.FieldsV1 == nil || !strings.Contains(string(entry.FieldsV1.Raw), `"f:`+KeepAliveAnnotation+`"`) {
+			continue
+		}
+		if entry.Time == nil || manager == "" || entry.Time.After(at) {
+			manager = entry.Manager
+			if entry.Time != nil {
+				at = entry.Time.Time
+			}
+		}
+	}
+	return manager
+}
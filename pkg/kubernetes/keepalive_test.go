@@ -0,0 +1,60 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestKeepAlives(t *testing.T) {
+	now := time.Date(2024, time.June, 4, 18, 0, 0, 0, time.UTC)
+	annotated := func(until, by string) map[string]string {
+		annotations := map[string]string{KeepAliveAnnotation: until}
+		if by != "" {
+			annotations[KeepAliveByAnnotation] = by
+		}
+		return annotations
+	}
+	client := fake.NewSimpleClientset(
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "bmw-saver",
+			Name:        "bmw-saver-pool-default-pool",
+			Annotations: annotated("2024-06-04T20:00:00Z", ""),
+			ManagedFields: []metav1.ManagedFieldsEntry{
+				{Manager: "kubectl-create", FieldsV1: &metav1.FieldsV1{Raw: []byte(`{"f:data":{}}`)}},
+				{Manager: "kubectl-annotate", FieldsV1: &metav1.FieldsV1{Raw: []byte(`{"f:metadata":{"f:annotations":{"f:bmw-saver.io/keep-alive-until":{}}}}`)}},
+			},
+		}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a", Annotations: annotated("2024-06-04T19:00:00Z", "alice")}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-b", Annotations: annotated("2024-06-04T17:00:00Z", "bob")}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-c", Annotations: annotated("tonight", "carol")}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-d", Annotations: annotated("2024-06-04T21:00:00Z", "dave")}},
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1", Labels: map[string]string{"cloud.google.com/gke-nodepool": "default-pool"}}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "app"}, Spec: corev1.PodSpec{NodeName: "node-1"}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "team-b", Name: "app"}, Spec: corev1.PodSpec{NodeName: "node-1"}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "team-c", Name: "app"}, Spec: corev1.PodSpec{NodeName: "node-1"}},
+	)
+
+	keepAlives, err := KeepAlives(context.Background(), client, "bmw-saver", "bmw-saver-pool-default-pool", "gke", "default-pool", now)
+	if err != nil {
+		t.Fatalf("KeepAlives() error = %v", err)
+	}
+
+	// Expired and invalid keep-alives, and namespaces without pods on the node pool, are ignored
+	want := []string{
+		"namespace/team-a until 2024-06-04T19:00:00Z by alice",
+		"configmap/bmw-saver-pool-default-pool until 2024-06-04T20:00:00Z by kubectl-annotate",
+	}
+	if len(keepAlives) != len(want) {
+		t.Fatalf("KeepAlives() = %v, want %v", keepAlives, want)
+	}
+	for i, keepAlive := range keepAlives {
+		if got := keepAlive.String(); got != want[i] {
+			t.Errorf("KeepAlives()[%d] = %s, want %s", i, got, want[i])
+		}
+	}
+}
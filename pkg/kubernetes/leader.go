@@ -0,0 +1,85 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+// LeaderElectionOptions contains the settings of the leader election
+type LeaderElectionOptions struct {
+	// Namespace and Name of the Lease held by the leader
+	Namespace string
+	Name      string
+	// Identity of this replica in the Lease, e.g. the pod name
+	Identity string
+	// LeaseDuration is how long other replicas wait before taking over the Lease of a leader that stopped renewing it
+	LeaseDuration time.Duration
+	// RenewDeadline is how long the leader retries renewing the Lease before it gives up leading
+	RenewDeadline time.Duration
+	// RetryPeriod is how often the Lease is tried to be acquired or renewed
+	RetryPeriod time.Duration
+}
+
+// RunLeaderElected runs run once this replica holds the Lease, so that only one replica runs it at a time.
+// The context of run is cancelled when ctx is done or the Lease is lost, it returns an error if the Lease was lost.
+func RunLeaderElected(ctx context.Context, client kubernetes.Interface, opts LeaderElectionOptions, run func(context.Context) error) error {
+	leading := make(chan context.Context, 1)
+	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock: &resourcelock.LeaseLock{
+			LeaseMeta:  metav1.ObjectMeta{Namespace: opts.Namespace, Name: opts.Name},
+			Client:     client.CoordinationV1(),
+			LockConfig: resourcelock.ResourceLockConfig{Identity: opts.Identity},
+		},
+		LeaseDuration:   opts.LeaseDuration,
+		RenewDeadline:   opts.RenewDeadline,
+		RetryPeriod:     opts.RetryPeriod,
+		ReleaseOnCancel: true,
+		Name:            opts.Name,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(ctx context.Context) {
+				slog.Info("Started leading", "lease", opts.Name, "identity", opts.Identity)
+				leading <- ctx
+			},
+			OnStoppedLeading: func() {},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create leader elector: %v", err)
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		elector.Run(ctx)
+	}()
+
+	slog.Info("Waiting for leadership", "lease", opts.Name, "identity", opts.Identity)
+	var leaderCtx context.Context
+	select {
+	case leaderCtx = <-leading:
+	case <-stopped:
+		// Stopped before leading, or leading started just now and is already over
+		select {
+		case leaderCtx = <-leading:
+		default:
+		}
+	}
+	if leaderCtx != nil && leaderCtx.Err() == nil {
+		err = run(leaderCtx)
+	}
+	<-stopped
+	if err != nil {
+		return err
+	}
+	if leaderCtx != nil && ctx.Err() == nil {
+		return fmt.Errorf("lost leadership of lease %s", opts.Name)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package kubernetes
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestRunLeaderElected(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	options := func(identity string) LeaderElectionOptions {
+		return LeaderElectionOptions{
+			Namespace:     "bmw-saver",
+			Name:          "bmw-saver",
+			Identity:      identity,
+			LeaseDuration: time.Second,
+			RenewDeadline: 500 * time.Millisecond,
+			RetryPeriod:   100 * time.Millisecond,
+		}
+	}
+
+	var running atomic.Int32
+	started := make(chan string, 2)
+	run := func(identity string) func(context.Context) error {
+		return func(ctx context.Context) error {
+			if running.Add(1) > 1 {
+				t.Errorf("%s runs while another replica is running", identity)
+			}
+			started <- identity
+			<-ctx.Done()
+			running.Add(-1)
+			return nil
+		}
+	}
+
+	ctx1, cancel1 := context.WithCancel(context.Background())
+	done1 := make(chan error, 1)
+	go func() { done1 <- RunLeaderElected(ctx1, client, options("replica-1"), run("replica-1")) }()
+	if got := <-started; got != "replica-1" {
+		t.Fatalf("Started %s, want replica-1", got)
+	}
+
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	defer cancel2()
+	done2 := make(chan error, 1)
+	go func() { done2 <- RunLeaderElected(ctx2, client, options("replica-2"), run("replica-2")) }()
+	select {
+	case got := <-started:
+		t.Fatalf("Started %s while replica-1 leads", got)
+	case <-time.After(300 * time.Millisecond):
+	}
+
+	// The stopped leader releases the lease, so the other replica takes over
+	cancel1()
+	if err := <-done1; err != nil {
+		t.Errorf("RunLeaderElected() error = %v", err)
+	}
+	select {
+	case got := <-started:
+		if got != "replica-2" {
+			t.Errorf("Started %s, want replica-2", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("replica-2 didn't take over the lease")
+	}
+
+	cancel2()
+	if err := <-done2; err != nil {
+		t.Errorf("RunLeaderElected() error = %v", err)
+	}
+}
@@ -2,22 +2,138 @@ package kubernetes
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/sync/errgroup"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/retry"
 )
 
-// DrainNode safely drains a node by evicting all pods and marking it as unschedulable.
-// It returns an error if the draining process fails.
-func DrainNode(ctx context.Context, config *rest.Config, nodeName string) error {
+// Evictions refused with 429 Too Many Requests by PodDisruptionBudgets or failing otherwise are retried after
+// evictionRetryInterval, doubling up to evictionMaxRetryInterval, until evictionRetryTimeout
+var (
+	evictionRetryInterval    = 5 * time.Second
+	evictionMaxRetryInterval = time.Minute
+	evictionRetryTimeout     = time.Minute
+)
+
+// Evicted pods are checked for termination every podTerminationInterval until podTerminationTimeout, if not
+// configured in the drain options
+var (
+	podTerminationInterval = 2 * time.Second
+	podTerminationTimeout  = 2 * time.Minute
+)
+
+// DrainOptions contains settings for draining nodes
+type DrainOptions struct {
+	// IgnorePodDisruptionBudgets deletes pods instead of evicting them, so PodDisruptionBudgets can't block the drain
+	IgnorePodDisruptionBudgets bool
+	// PrepareToSleepTimeout is how long the "prepare to sleep" webhooks of the pods are waited for before evicting
+	// them, 0 doesn't call them
+	PrepareToSleepTimeout time.Duration
+	// GracePeriod is how long evicted pods are given to terminate, 0 uses their own termination grace period
+	GracePeriod time.Duration
+	// Timeout is how long evictions refused by PodDisruptionBudgets or failing otherwise are retried, 0 uses
+	// evictionRetryTimeout
+	Timeout time.Duration
+	// Force deletes the pods whose eviction is still refused after the timeout instead of failing the drain
+	Force bool
+	// ProtectEmptyDir fails the drain if pods with emptyDir volumes run on the node, as their data would be lost
+	ProtectEmptyDir bool
+	// TerminationTimeout is how long evicted pods are waited for to terminate and their volumes to detach, 0 uses
+	// podTerminationTimeout
+	TerminationTimeout time.Duration
+	// Parallelism is how many nodes are drained at a time, 0 drains them one after the other
+	Parallelism int
+	// Progress is called with the progress of draining each node, when its evictions are first retried and once
+	// it's drained, nil if the progress isn't reported. It's called concurrently when nodes are drained in parallel.
+	Progress func(DrainProgress)
+	// NodeSelection is which nodes are drained first when a node pool is scaled down to fewer nodes, one of the
+	// NodeSelection strategies, the nodes aren't reordered if empty
+	NodeSelection string
+}
+
+// Strategies for selecting the nodes drained when a node pool is scaled down to fewer nodes
+const (
+	// NodeSelectionEmptiestFirst drains the nodes running the fewest evictable pods first, to disrupt the fewest pods
+	NodeSelectionEmptiestFirst = "emptiest-first"
+	// NodeSelectionOldestFirst drains the nodes by their creation time, oldest first
+	NodeSelectionOldestFirst = "oldest-first"
+	// NodeSelectionNewestFirst drains the nodes by their creation time, newest first
+	NodeSelectionNewestFirst = "newest-first"
+)
+
+// DrainProgress is the progress of draining a node
+type DrainProgress struct {
+	// Node is the name of the node
+	Node string
+	// Evicted is how many pods were evicted or deleted so far
+	Evicted int
+	// Blocked are the pods whose eviction was refused by PodDisruptionBudgets or failed in the last attempt, as
+	// namespace/name
+	Blocked []string
+	// Duration is how long the node has been drained for
+	Duration time.Duration
+	// Done is whether draining the node finished, with Err if it failed
+	Done bool
+	Err  error
+}
+
+type drainOptionsKey struct{}
+
+// WithDrainOptions returns a context with the options for draining the nodes of a node pool,
+// as the nodes are drained by the cloud providers while scaling the node pool
+func WithDrainOptions(ctx context.Context, opts DrainOptions) context.Context {
+	return context.WithValue(ctx, drainOptionsKey{}, opts)
+}
+
+// drainOptionsFrom returns the drain options of the context, the defaults if none are set
+func drainOptionsFrom(ctx context.Context) DrainOptions {
+	opts, _ := ctx.Value(drainOptionsKey{}).(DrainOptions)
+	return opts
+}
+
+// DrainNode cordons a node and evicts its pods according to the drain options of the context, then waits for
+// them to terminate. It returns an error if pods are protected, or still can't be evicted at the drain timeout.
+func DrainNode(ctx context.Context, clientset kubernetes.Interface, nodeName string) (err error) {
 	slog.Info("Draining node", "node", nodeName)
+	ctx, span := otel.Tracer("github.com/kezhenxu94/bmw-saver/pkg/kubernetes").Start(ctx, "DrainNode",
+		trace.WithAttributes(attribute.String("node", nodeName)))
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
 
-	clientset, err := kubernetes.NewForConfig(config)
-	if err != nil {
-		return fmt.Errorf("failed to create kubernetes client: %v", err)
+	opts := drainOptionsFrom(ctx)
+	start := time.Now()
+	progress := DrainProgress{Node: nodeName}
+	if opts.Progress != nil {
+		defer func() {
+			progress.Duration, progress.Done, progress.Err = time.Since(start), true, err
+			opts.Progress(progress)
+		}()
+	}
+
+	if err := CordonNode(ctx, clientset, nodeName); err != nil {
+		return err
 	}
 
 	pods, err := clientset.CoreV1().Pods("").List(ctx, metav1.ListOptions{
@@ -27,17 +143,395 @@ func DrainNode(ctx context.Context, config *rest.Config, nodeName string) error
 		return fmt.Errorf("failed to list pods: %v", err)
 	}
 
+	if opts.PrepareToSleepTimeout > 0 {
+		prepareCtx, cancel := context.WithTimeout(ctx, opts.PrepareToSleepTimeout)
+		prepareToSleep(prepareCtx, clientset, nodeName, pods.Items)
+		cancel()
+	}
+
+	var pending []corev1.Pod
+	for _, pod := range pods.Items {
+		if reason := skipDrain(&pod); reason != "" {
+			slog.Debug("Not evicting pod", "pod", pod.Name, "namespace", pod.Namespace, "reason", reason)
+			continue
+		}
+		pending = append(pending, pod)
+	}
+	if opts.ProtectEmptyDir {
+		if names := podsWithEmptyDir(pending); len(names) > 0 {
+			return fmt.Errorf("pods with emptyDir volumes on node %s: %s", nodeName, strings.Join(names, ", "))
+		}
+	}
+
+	deleteOptions := metav1.DeleteOptions{}
+	if opts.GracePeriod > 0 {
+		gracePeriodSeconds := int64(opts.GracePeriod.Seconds())
+		deleteOptions.GracePeriodSeconds = &gracePeriodSeconds
+	}
+	timeout := evictionRetryTimeout
+	if opts.Timeout > 0 {
+		timeout = opts.Timeout
+	}
+
+	// Evictions refused by PodDisruptionBudgets are retried together, as evicted pods being rescheduled elsewhere
+	// make room in the budgets, and so are failed evictions, with a backoff until the deadline
+	deadline := time.Now().Add(timeout)
+	interval := evictionRetryInterval
+	retrying := false
+	var failed []string
+	for {
+		var blocked, retried []corev1.Pod
+		failed = nil
+		for _, pod := range pending {
+			if opts.IgnorePodDisruptionBudgets {
+				err = clientset.CoreV1().Pods(pod.Namespace).Delete(ctx, pod.Name, deleteOptions)
+			} else {
+				err = clientset.PolicyV1().Evictions(pod.Namespace).Evict(ctx, &policyv1.Eviction{
+					ObjectMeta:    metav1.ObjectMeta{Name: pod.Name, Namespace: pod.Namespace},
+					DeleteOptions: &deleteOptions,
+				})
+			}
+			if k8serrors.IsNotFound(err) {
+				continue
+			}
+			if k8serrors.IsTooManyRequests(err) {
+				// The API server refuses evictions that would violate a PodDisruptionBudget
+				slog.Debug("Pod eviction blocked by PodDisruptionBudget", "pod", pod.Name, "namespace", pod.Namespace, "error", err)
+				blocked = append(blocked, pod)
+				continue
+			}
+			if err != nil {
+				slog.Warn("Failed to evict pod", "pod", pod.Name, "namespace", pod.Namespace, "error", err)
+				retried = append(retried, pod)
+				failed = append(failed, fmt.Sprintf("%s/%s: %v", pod.Namespace, pod.Name, err))
+				continue
+			}
+			slog.Info("Pod evicted successfully", "pod", pod.Name, "namespace", pod.Namespace)
+			progress.Evicted++
+		}
+		pending = append(blocked, retried...)
+		progress.Blocked = make([]string, 0, len(pending))
+		for _, pod := range pending {
+			progress.Blocked = append(progress.Blocked, pod.Namespace+"/"+pod.Name)
+		}
+		wait := time.Until(deadline)
+		if len(pending) == 0 || wait <= 0 {
+			pending = blocked
+			break
+		}
+		wait = min(wait, interval)
+		if !retrying && opts.Progress != nil {
+			progress.Duration = time.Since(start)
+			opts.Progress(progress)
+		}
+		retrying = true
+		interval = min(2*interval, evictionMaxRetryInterval)
+		slog.Info("Pod evictions blocked or failed, retrying", "node", nodeName, "blocked", len(blocked), "failed", len(retried), "retry_in", wait)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("draining node %s interrupted: %v", nodeName, ctx.Err())
+		case <-time.After(wait):
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to evict pods: %s", strings.Join(failed, ", "))
+	}
+	if len(pending) > 0 && !opts.Force {
+		names := make([]string, 0, len(pending))
+		for _, pod := range pending {
+			slog.Warn("Pod eviction blocked by PodDisruptionBudget", "pod", pod.Name, "namespace", pod.Namespace)
+			names = append(names, pod.Namespace+"/"+pod.Name)
+		}
+		return fmt.Errorf("eviction of pods blocked by PodDisruptionBudgets: %s", strings.Join(names, ", "))
+	}
+	for _, pod := range pending {
+		slog.Warn("Pod eviction blocked by PodDisruptionBudget after timeout, deleting pod", "pod", pod.Name, "namespace", pod.Namespace)
+		err := clientset.CoreV1().Pods(pod.Namespace).Delete(ctx, pod.Name, deleteOptions)
+		if err != nil && !k8serrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete pod %s/%s: %v", pod.Namespace, pod.Name, err)
+		}
+		progress.Evicted++
+	}
+
+	terminationTimeout := podTerminationTimeout
+	if opts.TerminationTimeout > 0 {
+		terminationTimeout = opts.TerminationTimeout
+	}
+	return waitForTermination(ctx, clientset, nodeName, terminationTimeout)
+}
+
+// DrainNodes drains the nodes in parallel, at most as many at a time as the parallelism of the drain options of the
+// context, and logs the progress as each node is drained. All nodes are drained even if some fail, it returns an
+// error listing the nodes that failed.
+func DrainNodes(ctx context.Context, clientset kubernetes.Interface, nodeNames []string) error {
+	parallelism := drainOptionsFrom(ctx).Parallelism
+	if parallelism <= 0 {
+		parallelism = 1
+	}
+
+	var (
+		mu      sync.Mutex
+		drained int
+		failed  []string
+		workers errgroup.Group
+	)
+	workers.SetLimit(parallelism)
+	for _, nodeName := range nodeNames {
+		workers.Go(func() error {
+			start := time.Now()
+			err := DrainNode(ctx, clientset, nodeName)
+
+			mu.Lock()
+			defer mu.Unlock()
+			drained++
+			progress := fmt.Sprintf("%d/%d", drained, len(nodeNames))
+			if err != nil {
+				slog.Error("Failed to drain node", "node", nodeName, "progress", progress, "duration", time.Since(start), "error", err)
+				failed = append(failed, fmt.Sprintf("%s: %v", nodeName, err))
+				return nil
+			}
+			slog.Info("Drained node", "node", nodeName, "progress", progress, "duration", time.Since(start))
+			return nil
+		})
+	}
+	_ = workers.Wait()
+
+	if len(failed) > 0 {
+		sort.Strings(failed)
+		return fmt.Errorf("failed to drain %d of %d nodes: %s", len(failed), len(nodeNames), strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// waitForTermination waits until the evicted pods on the node terminated and the volumes attached to it detached,
+// so that the node isn't removed by the cloud provider while its pods still shut down or write to their volumes.
+// Once the timeout passes, the drain goes on regardless, as only the graceful shutdown is at stake then.
+func waitForTermination(ctx context.Context, client kubernetes.Interface, nodeName string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		pods, volumes, err := nodeTermination(ctx, client, nodeName)
+		if err != nil {
+			slog.Warn("Failed to check termination of evicted pods", "node", nodeName, "error", err)
+		} else if len(pods) == 0 && len(volumes) == 0 {
+			slog.Info("Node drained", "node", nodeName)
+			return nil
+		}
+
+		if time.Now().Add(podTerminationInterval).After(deadline) {
+			slog.Warn("Evicted pods didn't terminate in time, removing node anyway", "node", nodeName,
+				"pods", pods, "volumes", volumes, "timeout", timeout)
+			return nil
+		}
+		slog.Debug("Waiting for evicted pods to terminate", "node", nodeName, "pods", pods, "volumes", volumes)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for pods on node %s to terminate interrupted: %v", nodeName, ctx.Err())
+		case <-time.After(podTerminationInterval):
+		}
+	}
+}
+
+// nodeTermination returns the pods still running or terminating on the node besides the ones not evicted when
+// draining it, as "namespace/name", and the volumes still attached to it
+func nodeTermination(ctx context.Context, client kubernetes.Interface, nodeName string) ([]string, []string, error) {
+	pods, err := client.CoreV1().Pods("").List(ctx, metav1.ListOptions{
+		FieldSelector: fmt.Sprintf("spec.nodeName=%s", nodeName),
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list pods: %v", err)
+	}
+	var running []string
 	for _, pod := range pods.Items {
-		if pod.Namespace == "kube-system" {
+		if reason := skipDrain(&pod); reason == "" || reason == "terminating pod" {
+			running = append(running, pod.Namespace+"/"+pod.Name)
+		}
+	}
+
+	node, err := client.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get node: %v", err)
+	}
+	var volumes []string
+	for _, volume := range node.Status.VolumesAttached {
+		volumes = append(volumes, string(volume.Name))
+	}
+	return running, volumes, nil
+}
+
+// skipDrain returns why the pod isn't evicted when draining its node, like kubectl drain does, empty if it's
+// evicted: DaemonSet pods would be recreated on the node right away, mirror pods of static pods are managed by the
+// kubelet and can't be evicted, and finished or terminating pods don't run anymore
+func skipDrain(pod *corev1.Pod) string {
+	switch {
+	case ownedByDaemonSet(pod):
+		return "DaemonSet pod"
+	case isMirrorPod(pod):
+		return "mirror pod"
+	case pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed:
+		return "finished pod"
+	case pod.DeletionTimestamp != nil:
+		return "terminating pod"
+	}
+	return ""
+}
+
+// podsWithEmptyDir returns the pods with emptyDir volumes as "namespace/name"
+func podsWithEmptyDir(pods []corev1.Pod) []string {
+	var names []string
+	for _, pod := range pods {
+		for _, volume := range pod.Spec.Volumes {
+			if volume.EmptyDir != nil {
+				names = append(names, pod.Namespace+"/"+pod.Name)
+				break
+			}
+		}
+	}
+	return names
+}
+
+// isMirrorPod returns whether the pod is the mirror pod of a static pod
+func isMirrorPod(pod *corev1.Pod) bool {
+	_, ok := pod.Annotations[corev1.MirrorPodAnnotationKey]
+	return ok
+}
+
+// SortNodesForDrain sorts the nodes of a node pool in the order they are drained when it's scaled down, by the node
+// selection strategy of the drain options of the context. Nodes cordoned already come first, as they were about to
+// be removed anyway, and the order is kept otherwise without a strategy or between equal nodes.
+func SortNodesForDrain(ctx context.Context, client kubernetes.Interface, nodes []corev1.Node) error {
+	var less func(a, b *corev1.Node) bool
+	switch drainOptionsFrom(ctx).NodeSelection {
+	case NodeSelectionEmptiestFirst:
+		pods, err := client.CoreV1().Pods("").List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to list pods: %v", err)
+		}
+		counts := make(map[string]int)
+		for i := range pods.Items {
+			if skipDrain(&pods.Items[i]) == "" {
+				counts[pods.Items[i].Spec.NodeName]++
+			}
+		}
+		less = func(a, b *corev1.Node) bool { return counts[a.Name] < counts[b.Name] }
+	case NodeSelectionOldestFirst:
+		less = func(a, b *corev1.Node) bool { return a.CreationTimestamp.Before(&b.CreationTimestamp) }
+	case NodeSelectionNewestFirst:
+		less = func(a, b *corev1.Node) bool { return b.CreationTimestamp.Before(&a.CreationTimestamp) }
+	default:
+		less = func(a, b *corev1.Node) bool { return false }
+	}
+	sort.SliceStable(nodes, func(i, j int) bool {
+		if nodes[i].Spec.Unschedulable != nodes[j].Spec.Unschedulable {
+			return nodes[i].Spec.Unschedulable
+		}
+		return less(&nodes[i], &nodes[j])
+	})
+	return nil
+}
+
+// ShuttingDownTaint keeps new pods off the nodes of a node pool being scaled down, between draining them and the
+// cloud provider removing them
+const ShuttingDownTaint = "bmw-saver.io/shutting-down"
+
+// TaintNodes taints the nodes with the shutting down taint before they are drained, so that no new pods are
+// scheduled onto them, including pods tolerating unschedulable nodes
+func TaintNodes(ctx context.Context, client kubernetes.Interface, nodes []corev1.Node) error {
+	for _, node := range nodes {
+		err := updateTaints(ctx, client, node.Name, func(taints []corev1.Taint) []corev1.Taint {
+			for _, taint := range taints {
+				if taint.Key == ShuttingDownTaint && taint.Effect == corev1.TaintEffectNoSchedule {
+					return nil
+				}
+			}
+			return append(taints, corev1.Taint{Key: ShuttingDownTaint, Effect: corev1.TaintEffectNoSchedule})
+		}, false)
+		if err != nil {
+			return fmt.Errorf("failed to taint node %s: %v", node.Name, err)
+		}
+		slog.Info("Tainted node", "node", node.Name, "taint", ShuttingDownTaint)
+	}
+	return nil
+}
+
+// ReleaseNodes removes the shutting down taint from the nodes and uncordons them, when their scale down is aborted
+// or they survived it, e.g. as their node pool was restored before the cloud provider removed them. Nodes without
+// the taint are left alone, as they weren't cordoned for shutting down. Failures are logged only, as the nodes are
+// released again once their node pool is restored.
+func ReleaseNodes(ctx context.Context, client kubernetes.Interface, nodes []corev1.Node) {
+	for _, node := range nodes {
+		released := false
+		err := updateTaints(ctx, client, node.Name, func(taints []corev1.Taint) []corev1.Taint {
+			kept := make([]corev1.Taint, 0, len(taints))
+			for _, taint := range taints {
+				if taint.Key != ShuttingDownTaint {
+					kept = append(kept, taint)
+				}
+			}
+			if len(kept) == len(taints) {
+				return nil
+			}
+			released = true
+			return kept
+		}, true)
+		if k8serrors.IsNotFound(err) {
 			continue
 		}
-		err = clientset.CoreV1().Pods(pod.Namespace).Delete(ctx, pod.Name, metav1.DeleteOptions{})
 		if err != nil {
-			slog.Warn("Failed to delete pod", "pod", pod.Name, "namespace", pod.Namespace, "error", err)
+			slog.Warn("Failed to release node from shutting down", "node", node.Name, "error", err)
 			continue
 		}
-		slog.Info("Pod deleted successfully", "pod", pod.Name, "namespace", pod.Namespace)
+		if released {
+			slog.Info("Removed shutting down taint from node and uncordoned it", "node", node.Name)
+		}
 	}
+}
 
+// HasShuttingDownTaint returns whether the node is tainted as shutting down
+func HasShuttingDownTaint(node *corev1.Node) bool {
+	for _, taint := range node.Spec.Taints {
+		if taint.Key == ShuttingDownTaint {
+			return true
+		}
+	}
+	return false
+}
+
+// updateTaints patches the taints of the node to the ones returned by update, unless it returns nil, and uncordons
+// it along if uncordon is set. The patch is retried if the node changed since it was read, as taints are replaced
+// as a whole.
+func updateTaints(ctx context.Context, client kubernetes.Interface, nodeName string, update func([]corev1.Taint) []corev1.Taint, uncordon bool) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		node, err := client.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		taints := update(node.Spec.Taints)
+		if taints == nil {
+			return nil
+		}
+		spec := map[string]interface{}{"taints": taints}
+		if uncordon {
+			spec["unschedulable"] = false
+		}
+		patch, err := json.Marshal(map[string]interface{}{
+			"metadata": map[string]string{"resourceVersion": node.ResourceVersion},
+			"spec":     spec,
+		})
+		if err != nil {
+			return err
+		}
+		_, err = client.CoreV1().Nodes().Patch(ctx, nodeName, types.MergePatchType, patch, metav1.PatchOptions{})
+		return err
+	})
+}
+
+// CordonNode marks the node as unschedulable, so that no new pods are scheduled onto it
+func CordonNode(ctx context.Context, client kubernetes.Interface, nodeName string) error {
+	patch := []byte(`{"spec":{"unschedulable":true}}`)
+	if _, err := client.CoreV1().Nodes().Patch(ctx, nodeName, types.StrategicMergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		return fmt.Errorf("failed to cordon node %s: %v", nodeName, err)
+	}
+	slog.Info("Cordoned node", "node", nodeName)
 	return nil
 }
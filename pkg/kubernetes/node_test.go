@@ -0,0 +1,377 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+)
+
+func TestDrainNode(t *testing.T) {
+	evictionRetryInterval, evictionRetryTimeout = 10*time.Millisecond, 100*time.Millisecond
+	podTerminationInterval = 10 * time.Millisecond
+	defer func() {
+		evictionRetryInterval, evictionRetryTimeout = 5*time.Second, time.Minute
+		podTerminationInterval = 2 * time.Second
+	}()
+
+	pod := func(namespace, name string) corev1.Pod {
+		return corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+			Spec:       corev1.PodSpec{NodeName: "node-1"},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+		}
+	}
+	daemonSetPod := pod("monitoring", "node-exporter")
+	daemonSetPod.OwnerReferences = []metav1.OwnerReference{{Kind: "DaemonSet", Name: "node-exporter"}}
+	mirrorPod := pod("kube-system", "kube-proxy")
+	mirrorPod.Annotations = map[string]string{corev1.MirrorPodAnnotationKey: "hash"}
+	finishedPod := pod("team-a", "migrate")
+	finishedPod.Status.Phase = corev1.PodSucceeded
+	web1WithEmptyDir := pod("team-a", "web-1")
+	web1WithEmptyDir.Spec.Volumes = []corev1.Volume{{Name: "cache", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}}}
+	tests := []struct {
+		name        string
+		opts        DrainOptions
+		web1        corev1.Pod
+		refusals    map[string]int
+		failures    map[string]int
+		attached    int
+		wantEvicted []string
+		wantDeleted []string
+		wantErr     string
+	}{
+		{
+			name:        "evicted",
+			wantEvicted: []string{"web-1", "web-2", "coredns"},
+		},
+		{
+			name:        "evicted once the budget allows it",
+			refusals:    map[string]int{"web-2": 2},
+			wantEvicted: []string{"web-1", "coredns", "web-2"},
+		},
+		{
+			name:        "still blocked",
+			refusals:    map[string]int{"web-2": 100},
+			wantEvicted: []string{"web-1", "coredns"},
+			wantErr:     "team-a/web-2",
+		},
+		{
+			name:        "evicted once failures are retried",
+			failures:    map[string]int{"web-2": 2},
+			wantEvicted: []string{"web-1", "coredns", "web-2"},
+		},
+		{
+			name:        "still failing",
+			failures:    map[string]int{"web-2": 100},
+			wantEvicted: []string{"web-1", "coredns"},
+			wantErr:     "failed to evict pods: team-a/web-2",
+		},
+		{
+			name:        "deleted when forced after the timeout",
+			opts:        DrainOptions{Timeout: 50 * time.Millisecond, Force: true},
+			refusals:    map[string]int{"web-2": 100},
+			wantEvicted: []string{"web-1", "coredns"},
+			wantDeleted: []string{"web-2"},
+		},
+		{
+			name:        "evicted with the grace period",
+			opts:        DrainOptions{GracePeriod: 30 * time.Second},
+			wantEvicted: []string{"web-1", "web-2", "coredns"},
+		},
+		{
+			name:        "evicted with emptyDir volumes",
+			web1:        web1WithEmptyDir,
+			wantEvicted: []string{"web-1", "web-2", "coredns"},
+		},
+		{
+			name:        "waits for volumes to detach",
+			attached:    2,
+			wantEvicted: []string{"web-1", "web-2", "coredns"},
+		},
+		{
+			name:        "removed when volumes don't detach in time",
+			opts:        DrainOptions{TerminationTimeout: 50 * time.Millisecond},
+			attached:    100,
+			wantEvicted: []string{"web-1", "web-2", "coredns"},
+		},
+		{
+			name:    "emptyDir volumes protected",
+			opts:    DrainOptions{ProtectEmptyDir: true},
+			web1:    web1WithEmptyDir,
+			wantErr: "team-a/web-1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var evicted, deleted []string
+			cordoned := false
+			nodeGets := 0
+			web1 := tt.web1
+			if web1.Name == "" {
+				web1 = pod("team-a", "web-1")
+			}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				switch {
+				case r.Method == http.MethodPatch && r.URL.Path == "/api/v1/nodes/node-1":
+					cordoned = true
+					_ = json.NewEncoder(w).Encode(corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}, Spec: corev1.NodeSpec{Unschedulable: true}})
+				case r.Method == http.MethodGet && r.URL.Path == "/api/v1/nodes/node-1":
+					node := corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}}
+					if nodeGets < tt.attached {
+						node.Status.VolumesAttached = []corev1.AttachedVolume{{Name: "kubernetes.io/csi/pd.csi.storage.gke.io^disk-1"}}
+					}
+					nodeGets++
+					_ = json.NewEncoder(w).Encode(node)
+				case r.URL.Path == "/api/v1/pods":
+					if !cordoned {
+						t.Error("pods listed before the node was cordoned")
+					}
+					mu.Lock()
+					defer mu.Unlock()
+					// Evicted and deleted pods are gone
+					gone := strings.Join(append(evicted, deleted...), ",") + ","
+					var pods []corev1.Pod
+					for _, pod := range []corev1.Pod{web1, pod("team-a", "web-2"), pod("kube-system", "coredns"), daemonSetPod, mirrorPod, finishedPod} {
+						if !strings.Contains(gone, pod.Name+",") {
+							pods = append(pods, pod)
+						}
+					}
+					_ = json.NewEncoder(w).Encode(corev1.PodList{Items: pods})
+				case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/eviction"):
+					name := strings.Split(r.URL.Path, "/")[6]
+					var eviction policyv1.Eviction
+					_ = json.NewDecoder(r.Body).Decode(&eviction)
+					if want := int64(tt.opts.GracePeriod.Seconds()); want > 0 &&
+						(eviction.DeleteOptions == nil || eviction.DeleteOptions.GracePeriodSeconds == nil || *eviction.DeleteOptions.GracePeriodSeconds != want) {
+						t.Errorf("eviction of %s delete options = %+v, want grace period %ds", name, eviction.DeleteOptions, want)
+					}
+					mu.Lock()
+					defer mu.Unlock()
+					if tt.refusals[name] > 0 {
+						tt.refusals[name]--
+						w.WriteHeader(http.StatusTooManyRequests)
+						_, _ = w.Write([]byte(`{"kind": "Status", "apiVersion": "v1", "status": "Failure", "reason": "TooManyRequests", "code": 429}`))
+						return
+					}
+					if tt.failures[name] > 0 {
+						tt.failures[name]--
+						w.WriteHeader(http.StatusInternalServerError)
+						_, _ = w.Write([]byte(`{"kind": "Status", "apiVersion": "v1", "status": "Failure", "reason": "InternalError", "code": 500}`))
+						return
+					}
+					evicted = append(evicted, name)
+					w.WriteHeader(http.StatusCreated)
+					_, _ = w.Write([]byte(`{"kind": "Status", "apiVersion": "v1", "status": "Success"}`))
+				case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, "/api/v1/namespaces/"):
+					mu.Lock()
+					defer mu.Unlock()
+					deleted = append(deleted, strings.Split(r.URL.Path, "/")[6])
+					_ = json.NewEncoder(w).Encode(corev1.Pod{})
+				default:
+					t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			defer server.Close()
+
+			client := kubernetes.NewForConfigOrDie(&rest.Config{Host: server.URL})
+			var reports []DrainProgress
+			opts := tt.opts
+			opts.Progress = func(progress DrainProgress) { reports = append(reports, progress) }
+			err := DrainNode(WithDrainOptions(context.Background(), opts), client, "node-1")
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("DrainNode() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("DrainNode() error = %v, want error with %s", err, tt.wantErr)
+			}
+			if strings.Join(evicted, ",") != strings.Join(tt.wantEvicted, ",") {
+				t.Errorf("evicted = %v, want %v", evicted, tt.wantEvicted)
+			}
+			if strings.Join(deleted, ",") != strings.Join(tt.wantDeleted, ",") {
+				t.Errorf("deleted = %v, want %v", deleted, tt.wantDeleted)
+			}
+			if len(reports) == 0 || !reports[len(reports)-1].Done || reports[len(reports)-1].Err != err {
+				t.Fatalf("progress = %+v, want done with error %v last", reports, err)
+			}
+			if got, want := reports[len(reports)-1].Evicted, len(tt.wantEvicted)+len(tt.wantDeleted); got != want {
+				t.Errorf("progress evicted %d pods, want %d", got, want)
+			}
+			if len(tt.refusals)+len(tt.failures) > 0 && (len(reports) != 2 || strings.Join(reports[0].Blocked, ",") != "team-a/web-2") {
+				t.Errorf("progress = %+v, want team-a/web-2 blocked while retrying", reports)
+			}
+			if tt.wantErr == "" && tt.opts.TerminationTimeout == 0 && nodeGets != tt.attached+1 {
+				t.Errorf("node checked %d times, want until its volumes detached after %d", nodeGets, tt.attached)
+			}
+		})
+	}
+}
+
+func TestDrainNodes(t *testing.T) {
+	var mu sync.Mutex
+	active, maxActive := 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		name := strings.TrimPrefix(r.URL.Path, "/api/v1/nodes/")
+		switch {
+		case name == "removed":
+			w.WriteHeader(http.StatusNotFound)
+		case r.Method == http.MethodPatch:
+			mu.Lock()
+			active++
+			maxActive = max(maxActive, active)
+			mu.Unlock()
+			time.Sleep(20 * time.Millisecond)
+			mu.Lock()
+			active--
+			mu.Unlock()
+			_ = json.NewEncoder(w).Encode(corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name}})
+		case r.URL.Path == "/api/v1/pods":
+			_ = json.NewEncoder(w).Encode(corev1.PodList{})
+		case r.Method == http.MethodGet:
+			_ = json.NewEncoder(w).Encode(corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name}})
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	client := kubernetes.NewForConfigOrDie(&rest.Config{Host: server.URL, QPS: 1000, Burst: 1000})
+	var names []string
+	for i := 0; i < 6; i++ {
+		names = append(names, fmt.Sprintf("node-%d", i))
+	}
+
+	ctx := WithDrainOptions(context.Background(), DrainOptions{Parallelism: 3})
+	if err := DrainNodes(ctx, client, names); err != nil {
+		t.Fatalf("DrainNodes() error = %v", err)
+	}
+	if maxActive != 3 {
+		t.Errorf("drained %d nodes at a time, want 3", maxActive)
+	}
+
+	err := DrainNodes(ctx, client, []string{"node-0", "removed"})
+	if err == nil || !strings.Contains(err.Error(), "failed to drain 1 of 2 nodes: removed:") {
+		t.Errorf("DrainNodes() error = %v, want the removed node failed", err)
+	}
+}
+
+func TestSortNodesForDrain(t *testing.T) {
+	created := time.Date(2024, time.June, 3, 8, 0, 0, 0, time.UTC)
+	node := func(name string, age time.Duration, cordoned bool) corev1.Node {
+		return corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name, CreationTimestamp: metav1.NewTime(created.Add(-age))},
+			Spec:       corev1.NodeSpec{Unschedulable: cordoned},
+		}
+	}
+	pod := func(name, nodeName string, daemonSet bool) *corev1.Pod {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+			Spec:       corev1.PodSpec{NodeName: nodeName},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+		}
+		if daemonSet {
+			pod.OwnerReferences = []metav1.OwnerReference{{Kind: "DaemonSet", Name: "agent"}}
+		}
+		return pod
+	}
+	client := fake.NewSimpleClientset(
+		pod("web-1", "node-a", false), pod("web-2", "node-a", false), pod("web-3", "node-b", false),
+		pod("agent-a", "node-a", true), pod("agent-b", "node-b", true), pod("agent-c", "node-c", true),
+	)
+
+	tests := []struct {
+		selection string
+		want      string
+	}{
+		{selection: "", want: "node-d,node-a,node-b,node-c"},
+		{selection: NodeSelectionEmptiestFirst, want: "node-d,node-c,node-b,node-a"},
+		{selection: NodeSelectionOldestFirst, want: "node-d,node-b,node-a,node-c"},
+		{selection: NodeSelectionNewestFirst, want: "node-d,node-c,node-a,node-b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.selection, func(t *testing.T) {
+			nodes := []corev1.Node{
+				node("node-a", 2*time.Hour, false),
+				node("node-b", 3*time.Hour, false),
+				node("node-c", time.Hour, false),
+				node("node-d", time.Minute, true),
+			}
+			ctx := WithDrainOptions(context.Background(), DrainOptions{NodeSelection: tt.selection})
+			if err := SortNodesForDrain(ctx, client, nodes); err != nil {
+				t.Fatalf("SortNodesForDrain() error = %v", err)
+			}
+			var names []string
+			for _, node := range nodes {
+				names = append(names, node.Name)
+			}
+			if got := strings.Join(names, ","); got != tt.want {
+				t.Errorf("SortNodesForDrain() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTaintNodes(t *testing.T) {
+	other := corev1.Taint{Key: "dedicated", Value: "batch", Effect: corev1.TaintEffectNoSchedule}
+	nodes := []corev1.Node{
+		{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "node-2"}, Spec: corev1.NodeSpec{Taints: []corev1.Taint{other}}},
+	}
+	// node-3 is cordoned by someone else, it's left alone
+	maintained := corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-3"}, Spec: corev1.NodeSpec{Unschedulable: true}}
+	client := fake.NewSimpleClientset(&nodes[0], &nodes[1], &maintained)
+	ctx := context.Background()
+	get := func(name string) *corev1.Node {
+		node, err := client.CoreV1().Nodes().Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("failed to get node %s: %v", name, err)
+		}
+		return node
+	}
+
+	// Tainting twice doesn't add the taint again
+	for i := 0; i < 2; i++ {
+		if err := TaintNodes(ctx, client, nodes); err != nil {
+			t.Fatalf("TaintNodes() error = %v", err)
+		}
+	}
+	shuttingDown := corev1.Taint{Key: ShuttingDownTaint, Effect: corev1.TaintEffectNoSchedule}
+	if got := get("node-1").Spec.Taints; len(got) != 1 || got[0] != shuttingDown {
+		t.Errorf("taints of node-1 = %v, want %v", got, shuttingDown)
+	}
+	if got := get("node-2").Spec.Taints; len(got) != 2 || got[0] != other || got[1] != shuttingDown {
+		t.Errorf("taints of node-2 = %v, want %v and %v", got, other, shuttingDown)
+	}
+	for _, node := range nodes {
+		if err := CordonNode(ctx, client, node.Name); err != nil {
+			t.Fatalf("CordonNode() error = %v", err)
+		}
+	}
+
+	ReleaseNodes(ctx, client, append(nodes, maintained, corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "removed"}}))
+	if got := get("node-1"); len(got.Spec.Taints) != 0 || got.Spec.Unschedulable {
+		t.Errorf("node-1 has taints %v and unschedulable %v after releasing, want none", got.Spec.Taints, got.Spec.Unschedulable)
+	}
+	if got := get("node-2"); len(got.Spec.Taints) != 1 || got.Spec.Taints[0] != other || got.Spec.Unschedulable {
+		t.Errorf("node-2 has taints %v and unschedulable %v after releasing, want %v", got.Spec.Taints, got.Spec.Unschedulable, other)
+	}
+	if !get("node-3").Spec.Unschedulable {
+		t.Errorf("node-3 uncordoned, want it left cordoned without the taint")
+	}
+}
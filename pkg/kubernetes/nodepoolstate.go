@@ -0,0 +1,357 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+const (
+	// NodePoolStateGroupVersion is the API group and version of the NodePoolState custom resource
+	NodePoolStateGroupVersion = "bmw-saver.kezhenxu94.github.io/v1alpha1"
+	// NodePoolStateKind is the kind of the NodePoolState custom resource
+	NodePoolStateKind = "NodePoolState"
+)
+
+// NodePoolState is a NodePoolState custom resource in the state namespace, named after its node pool. Its spec is
+// the configuration saved before the node pool was scaled down, its status the outcome of the last scalings.
+type NodePoolState struct {
+	APIVersion string              `json:"apiVersion"`
+	Kind       string              `json:"kind"`
+	Metadata   metav1.ObjectMeta   `json:"metadata"`
+	Spec       NodePoolStateSpec   `json:"spec"`
+	Status     NodePoolStateStatus `json:"status,omitempty"`
+}
+
+// NodePoolStateSpec is the saved configuration of a node pool, empty until it's scaled down
+type NodePoolStateSpec struct {
+	// NodePool is the name of the node pool
+	NodePool string `json:"nodePool"`
+	// Version is the version of the format the configuration was saved in
+	Version int `json:"version,omitempty"`
+	// SavedAt is when the configuration was saved
+	SavedAt *metav1.Time `json:"savedAt,omitempty"`
+	// Config is the configuration of the node pool specific to its cloud provider
+	Config json.RawMessage `json:"config,omitempty"`
+	// RestoredAt is when the node pool was restored to its full size, the configuration is refreshed by the next
+	// scale down then
+	RestoredAt *metav1.Time `json:"restoredAt,omitempty"`
+}
+
+// NodePoolStateStatus is the outcome of the last scalings of a node pool
+type NodePoolStateStatus struct {
+	// LastScaleDown is when the node pool was last scaled down
+	LastScaleDown *metav1.Time `json:"lastScaleDown,omitempty"`
+	// LastScaleDownCount is the node count the node pool was last scaled down to
+	LastScaleDownCount *int32 `json:"lastScaleDownCount,omitempty"`
+	// LastRestore is when the node pool was last restored
+	LastRestore *metav1.Time `json:"lastRestore,omitempty"`
+	// LastError is the error of the last scaling if it failed
+	LastError string `json:"lastError,omitempty"`
+	// LastErrorTime is when the last scaling failed
+	LastErrorTime *metav1.Time `json:"lastErrorTime,omitempty"`
+}
+
+// NodePoolScaling is the outcome of scaling a node pool, written to the status of its NodePoolState
+type NodePoolScaling struct {
+	// Time is when the node pool was scaled
+	Time time.Time
+	// Restore is whether the node pool was restored, or scaled down to Count nodes otherwise
+	Restore bool
+	Count   int32
+	// Err is the error of the scaling if it failed
+	Err error
+}
+
+// UpdateNodePoolStatus writes the outcome of scaling the node pool to the status of its NodePoolState, which is
+// created without saved configuration if the node pool wasn't scaled down before
+func UpdateNodePoolStatus(ctx context.Context, client kubernetes.Interface, namespace, nodePool string, scaling NodePoolScaling) error {
+	restClient, err := nodePoolStateClient(client)
+	if err != nil {
+		return err
+	}
+	at := metav1.NewTime(scaling.Time)
+	status := map[string]interface{}{"lastError": nil, "lastErrorTime": nil}
+	switch {
+	case scaling.Err != nil:
+		status = map[string]interface{}{"lastError": scaling.Err.Error(), "lastErrorTime": at}
+	case scaling.Restore:
+		status["lastRestore"] = at
+	default:
+		status["lastScaleDown"] = at
+		status["lastScaleDownCount"] = scaling.Count
+	}
+	patch, err := json.Marshal(map[string]interface{}{"status": status})
+	if err != nil {
+		return fmt.Errorf("failed to marshal status of NodePoolState %s: %v", nodePool, err)
+	}
+
+	path := nodePoolStatePath(namespace)
+	_, err = restClient.Patch(types.MergePatchType).AbsPath(path, nodePool, "status").Body(patch).DoRaw(ctx)
+	if k8serrors.IsNotFound(err) {
+		if err := createNodePoolState(ctx, restClient, namespace, NodePoolStateSpec{NodePool: nodePool}); err != nil {
+			return err
+		}
+		_, err = restClient.Patch(types.MergePatchType).AbsPath(path, nodePool, "status").Body(patch).DoRaw(ctx)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update status of NodePoolState %s: %v", nodePool, err)
+	}
+	return nil
+}
+
+// saveNodePoolState saves the configuration of the node pool in its NodePoolState, unless it's saved already and
+// the node pool wasn't restored since, and returns whether it was saved. Configuration saved in a ConfigMap by
+// earlier versions is moved to the NodePoolState instead, unless it was restored since. It isn't saved if the NodePoolState CRD isn't installed.
+func (s *StateStore) saveNodePoolState(ctx context.Context, key StateKey, data []byte) (bool, error) {
+	restClient, err := nodePoolStateClient(s.client)
+	if err != nil {
+		return false, err
+	}
+	state, err := getNodePoolState(ctx, restClient, s.namespace, key.Name)
+	if err != nil {
+		return false, err
+	}
+	now := metav1.Now()
+	spec := NodePoolStateSpec{NodePool: key.Name, Version: StateVersion, SavedAt: &now, Config: data}
+	if state != nil && len(state.Spec.Config) > 0 {
+		if state.Spec.RestoredAt == nil {
+			return true, nil
+		}
+		if err := patchNodePoolStateSpec(ctx, restClient, s.namespace, spec); err != nil {
+			return false, err
+		}
+		slog.Info("Refreshed saved state", "target", key.String())
+		return true, nil
+	}
+
+	legacy, err := s.client.CoreV1().ConfigMaps(s.namespace).Get(ctx, key.configMapName(), metav1.GetOptions{})
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return false, fmt.Errorf("failed to get saved state of %s: %v", key, err)
+	}
+	migrated := err == nil
+	if migrated {
+		saved, err := parseSavedState(key, legacy)
+		if err != nil {
+			return false, err
+		}
+		// Configuration restored since it was saved is stale, the configuration of this scale down is saved instead
+		if saved.RestoredAt.IsZero() {
+			spec.Version, spec.Config = saved.Version, saved.Config
+			spec.SavedAt = nil
+			if !saved.SavedAt.IsZero() {
+				savedAt := metav1.NewTime(saved.SavedAt)
+				spec.SavedAt = &savedAt
+			}
+		}
+	}
+
+	if state == nil {
+		err = createNodePoolState(ctx, restClient, s.namespace, spec)
+		if k8serrors.IsNotFound(err) {
+			slog.Warn("NodePoolState CRD isn't installed, saving node pool state in a ConfigMap", "node_pool", key.Name)
+			return false, nil
+		}
+	} else {
+		// The NodePoolState was created for its status before the node pool was scaled down
+		err = patchNodePoolStateSpec(ctx, restClient, s.namespace, spec)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if migrated {
+		if err := s.client.CoreV1().ConfigMaps(s.namespace).Delete(ctx, legacy.Name, metav1.DeleteOptions{}); err != nil && !k8serrors.IsNotFound(err) {
+			slog.Warn("Failed to delete saved state moved to NodePoolState", "node_pool", key.Name, "configmap", legacy.Name, "error", err)
+		} else {
+			slog.Info("Moved saved node pool state to NodePoolState", "node_pool", key.Name, "configmap", legacy.Name)
+		}
+	}
+	return true, nil
+}
+
+// markNodePoolStateRestored marks the saved configuration of the NodePoolState of the node pool as restored, and
+// returns whether it has saved configuration
+func (s *StateStore) markNodePoolStateRestored(ctx context.Context, key StateKey) (bool, error) {
+	restClient, err := nodePoolStateClient(s.client)
+	if err != nil {
+		return false, err
+	}
+	state, err := getNodePoolState(ctx, restClient, s.namespace, key.Name)
+	if err != nil || state == nil || len(state.Spec.Config) == 0 {
+		return false, err
+	}
+	if state.Spec.RestoredAt != nil {
+		return true, nil
+	}
+	patch, err := json.Marshal(map[string]interface{}{"spec": map[string]interface{}{"restoredAt": metav1.Now()}})
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal NodePoolState %s: %v", key.Name, err)
+	}
+	if _, err := restClient.Patch(types.MergePatchType).AbsPath(nodePoolStatePath(s.namespace), key.Name).Body(patch).DoRaw(ctx); err != nil {
+		return false, fmt.Errorf("failed to mark NodePoolState %s as restored: %v", key.Name, err)
+	}
+	return true, nil
+}
+
+// loadNodePoolState returns the saved state of the node pool from its NodePoolState, ok is false if it has no
+// saved configuration or the NodePoolState CRD isn't installed
+func (s *StateStore) loadNodePoolState(ctx context.Context, key StateKey) (SavedState, bool, error) {
+	restClient, err := nodePoolStateClient(s.client)
+	if err != nil {
+		return SavedState{}, false, err
+	}
+	state, err := getNodePoolState(ctx, restClient, s.namespace, key.Name)
+	if err != nil || state == nil || len(state.Spec.Config) == 0 {
+		return SavedState{}, false, err
+	}
+	saved, err := state.savedState()
+	return saved, err == nil, err
+}
+
+// listNodePoolStates returns the saved state of the node pools with saved configuration, none if the
+// NodePoolState CRD isn't installed
+func (s *StateStore) listNodePoolStates(ctx context.Context) ([]SavedState, error) {
+	restClient, err := nodePoolStateClient(s.client)
+	if err != nil {
+		return nil, err
+	}
+	data, err := restClient.Get().AbsPath(nodePoolStatePath(s.namespace)).DoRaw(ctx)
+	if k8serrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list NodePoolStates: %v", err)
+	}
+	var list struct {
+		Items []NodePoolState `json:"items"`
+	}
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse NodePoolStates: %v", err)
+	}
+	var states []SavedState
+	for _, state := range list.Items {
+		if len(state.Spec.Config) == 0 {
+			continue
+		}
+		saved, err := state.savedState()
+		if err != nil {
+			return nil, err
+		}
+		states = append(states, saved)
+	}
+	return states, nil
+}
+
+// deleteNodePoolState deletes the NodePoolState of the node pool, including its status
+func (s *StateStore) deleteNodePoolState(ctx context.Context, key StateKey) error {
+	restClient, err := nodePoolStateClient(s.client)
+	if err != nil {
+		return err
+	}
+	if _, err := restClient.Delete().AbsPath(nodePoolStatePath(s.namespace), key.Name).DoRaw(ctx); err != nil && !k8serrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete NodePoolState %s: %v", key.Name, err)
+	}
+	return nil
+}
+
+// savedState returns the saved state of the NodePoolState, and refuses state saved by a newer version of bmw-saver
+func (state *NodePoolState) savedState() (SavedState, error) {
+	key := StateKey{Kind: StateKindNodePool, Name: state.Metadata.Name}
+	saved := SavedState{StateKey: key, Version: state.Spec.Version, Config: state.Spec.Config}
+	if state.Spec.SavedAt != nil {
+		saved.SavedAt = state.Spec.SavedAt.UTC()
+	}
+	if state.Spec.RestoredAt != nil {
+		saved.RestoredAt = state.Spec.RestoredAt.UTC()
+	}
+	if saved.Version > StateVersion {
+		return saved, fmt.Errorf("state of %s was saved in version %d, newer than supported version %d", key, saved.Version, StateVersion)
+	}
+	return saved, nil
+}
+
+// getNodePoolState returns the NodePoolState of the node pool, nil if there is none
+func getNodePoolState(ctx context.Context, restClient rest.Interface, namespace, nodePool string) (*NodePoolState, error) {
+	data, err := restClient.Get().AbsPath(nodePoolStatePath(namespace), nodePool).DoRaw(ctx)
+	if k8serrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get NodePoolState %s: %v", nodePool, err)
+	}
+	var state NodePoolState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse NodePoolState %s: %v", nodePool, err)
+	}
+	return &state, nil
+}
+
+// createNodePoolState creates the NodePoolState of the node pool with the spec, the error is NotFound if the
+// NodePoolState CRD isn't installed
+func createNodePoolState(ctx context.Context, restClient rest.Interface, namespace string, spec NodePoolStateSpec) error {
+	body, err := json.Marshal(NodePoolState{
+		APIVersion: NodePoolStateGroupVersion,
+		Kind:       NodePoolStateKind,
+		Metadata: metav1.ObjectMeta{
+			Name:      spec.NodePool,
+			Namespace: namespace,
+			Labels:    map[string]string{"app.kubernetes.io/managed-by": "bmw-saver"},
+		},
+		Spec: spec,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal NodePoolState %s: %v", spec.NodePool, err)
+	}
+	_, err = restClient.Post().AbsPath(nodePoolStatePath(namespace)).Body(body).DoRaw(ctx)
+	if k8serrors.IsNotFound(err) {
+		return err
+	}
+	if err != nil && !k8serrors.IsAlreadyExists(err) {
+		return fmt.Errorf("failed to create NodePoolState %s: %v", spec.NodePool, err)
+	}
+	return nil
+}
+
+// patchNodePoolStateSpec sets the spec of the existing NodePoolState of the node pool, it's not restored anymore
+func patchNodePoolStateSpec(ctx context.Context, restClient rest.Interface, namespace string, spec NodePoolStateSpec) error {
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return fmt.Errorf("failed to marshal NodePoolState %s: %v", spec.NodePool, err)
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return fmt.Errorf("failed to marshal NodePoolState %s: %v", spec.NodePool, err)
+	}
+	fields["restoredAt"] = nil
+	patch, err := json.Marshal(map[string]interface{}{"spec": fields})
+	if err != nil {
+		return fmt.Errorf("failed to marshal NodePoolState %s: %v", spec.NodePool, err)
+	}
+	if _, err := restClient.Patch(types.MergePatchType).AbsPath(nodePoolStatePath(namespace), spec.NodePool).Body(patch).DoRaw(ctx); err != nil {
+		return fmt.Errorf("failed to save NodePoolState %s: %v", spec.NodePool, err)
+	}
+	return nil
+}
+
+// nodePoolStateClient returns the REST client for NodePoolStates
+func nodePoolStateClient(client kubernetes.Interface) (rest.Interface, error) {
+	restClient := client.Discovery().RESTClient()
+	if restClient == nil {
+		return nil, fmt.Errorf("kubernetes API is not available")
+	}
+	return restClient, nil
+}
+
+// nodePoolStatePath returns the API path of the NodePoolStates in the namespace
+func nodePoolStatePath(namespace string) string {
+	return "/apis/" + NodePoolStateGroupVersion + "/namespaces/" + namespace + "/nodepoolstates"
+}
@@ -0,0 +1,58 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+)
+
+// BlockingDisruptionBudgets returns the PodDisruptionBudgets that would be violated by evicting their pods
+// on the nodes of the node pool, i.e. that allow fewer disruptions than they have pods on the node pool.
+// Pods in kube-system and finished pods are ignored, as they aren't evicted when draining nodes.
+func BlockingDisruptionBudgets(ctx context.Context, client kubernetes.Interface, cloudProvider, nodePoolName string) ([]string, error) {
+	pods, err := nodePoolPods(ctx, client, cloudProvider, nodePoolName)
+	if err != nil {
+		return nil, err
+	}
+
+	podsByNamespace := make(map[string][]corev1.Pod)
+	for _, pod := range pods {
+		if pod.Namespace == "kube-system" || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		podsByNamespace[pod.Namespace] = append(podsByNamespace[pod.Namespace], pod)
+	}
+
+	var blocking []string
+	for namespace, namespacePods := range podsByNamespace {
+		pdbs, err := client.PolicyV1().PodDisruptionBudgets(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list pod disruption budgets in namespace %s: %v", namespace, err)
+		}
+
+		for _, pdb := range pdbs.Items {
+			selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+			if err != nil {
+				return nil, fmt.Errorf("invalid selector of pod disruption budget %s/%s: %v", namespace, pdb.Name, err)
+			}
+
+			matching := 0
+			for _, pod := range namespacePods {
+				if selector.Matches(labels.Set(pod.Labels)) {
+					matching++
+				}
+			}
+			if matching > int(pdb.Status.DisruptionsAllowed) {
+				blocking = append(blocking, fmt.Sprintf("PodDisruptionBudget %s/%s allows %d disruptions, %d pods are on the node pool",
+					namespace, pdb.Name, pdb.Status.DisruptionsAllowed, matching))
+			}
+		}
+	}
+	sort.Strings(blocking)
+	return blocking, nil
+}
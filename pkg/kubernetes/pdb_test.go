@@ -0,0 +1,55 @@
+package kubernetes
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestBlockingDisruptionBudgets(t *testing.T) {
+	pod := func(namespace, name, node, app string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, Labels: map[string]string{"app": app}},
+			Spec:       corev1.PodSpec{NodeName: node},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+		}
+	}
+	pdb := func(namespace, name, app string, disruptionsAllowed int32) *policyv1.PodDisruptionBudget {
+		return &policyv1.PodDisruptionBudget{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+			Spec:       policyv1.PodDisruptionBudgetSpec{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": app}}},
+			Status:     policyv1.PodDisruptionBudgetStatus{DisruptionsAllowed: disruptionsAllowed},
+		}
+	}
+
+	client := newFakeClientset(
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1", Labels: map[string]string{"eks.amazonaws.com/nodegroup": "default-group"}}},
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-2", Labels: map[string]string{"eks.amazonaws.com/nodegroup": "default-group"}}},
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-3", Labels: map[string]string{"eks.amazonaws.com/nodegroup": "other-group"}}},
+		pod("default", "db-0", "node-1", "db"),
+		pod("default", "db-1", "node-2", "db"),
+		pod("default", "db-2", "node-3", "db"),
+		pod("default", "web-0", "node-1", "web"),
+		pod("default", "web-1", "node-2", "web"),
+		pod("default", "cache-0", "node-3", "cache"),
+		pod("kube-system", "dns-0", "node-1", "dns"),
+		pdb("default", "db", "db", 1),
+		pdb("default", "web", "web", 2),
+		pdb("default", "cache", "cache", 0),
+		pdb("kube-system", "dns", "dns", 0),
+	)
+
+	got, err := BlockingDisruptionBudgets(context.Background(), client, "aws", "default-group")
+	if err != nil {
+		t.Fatalf("BlockingDisruptionBudgets() error = %v", err)
+	}
+
+	want := []string{"PodDisruptionBudget default/db allows 1 disruptions, 2 pods are on the node pool"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("BlockingDisruptionBudgets() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,150 @@
+package kubernetes
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+)
+
+// PrepareToSleepAnnotation on a Deployment, StatefulSet or Service is the URL of its "prepare to sleep" webhook,
+// called before the nodes running its pods are drained
+const PrepareToSleepAnnotation = "bmw-saver.io/prepare-to-sleep-url"
+
+// PrepareToSleepRequest is posted as JSON to "prepare to sleep" webhooks
+type PrepareToSleepRequest struct {
+	// Node is the name of the node about to be drained
+	Node string `json:"node"`
+	// Workload is the annotated workload or Service, e.g. "Deployment team-a/web"
+	Workload string `json:"workload"`
+	// Pods are the names of its pods on the node
+	Pods []string `json:"pods"`
+}
+
+// prepareToSleep calls the "prepare to sleep" webhooks of the workloads and Services of the pods in parallel, and
+// waits until they acknowledged with a 2xx response, so that stateful apps can flush or checkpoint before their
+// pods are evicted. Failed or timed out webhooks are logged, they don't stop the drain.
+func prepareToSleep(ctx context.Context, client kubernetes.Interface, nodeName string, pods []corev1.Pod) {
+	// webhook is a webhook to call with its request
+	type webhook struct {
+		url     string
+		request *PrepareToSleepRequest
+	}
+	var webhooks []*webhook
+	byWorkload := make(map[string]*webhook)
+	add := func(workload, url string, pod *corev1.Pod) {
+		hook, ok := byWorkload[workload]
+		if !ok {
+			hook = &webhook{url: url, request: &PrepareToSleepRequest{Node: nodeName, Workload: workload}}
+			byWorkload[workload] = hook
+			webhooks = append(webhooks, hook)
+		}
+		hook.request.Pods = append(hook.request.Pods, pod.Name)
+	}
+
+	services := make(map[string][]corev1.Service)
+	for i := range pods {
+		pod := &pods[i]
+		if pod.Namespace == "kube-system" || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		if workload, url := annotatedOwner(ctx, client, pod); url != "" {
+			add(workload, url, pod)
+		}
+		if _, ok := services[pod.Namespace]; !ok {
+			list, err := client.CoreV1().Services(pod.Namespace).List(ctx, metav1.ListOptions{})
+			if err != nil {
+				slog.Warn("Failed to list services for prepare to sleep webhooks", "namespace", pod.Namespace, "error", err)
+			} else {
+				services[pod.Namespace] = list.Items
+			}
+		}
+		for _, service := range services[pod.Namespace] {
+			url := service.Annotations[PrepareToSleepAnnotation]
+			if url == "" || len(service.Spec.Selector) == 0 || !labels.SelectorFromSet(service.Spec.Selector).Matches(labels.Set(pod.Labels)) {
+				continue
+			}
+			add(fmt.Sprintf("Service %s/%s", service.Namespace, service.Name), url, pod)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for _, hook := range webhooks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slog.Info("Calling prepare to sleep webhook", "node", nodeName, "workload", hook.request.Workload, "url", hook.url)
+			if err := callPrepareToSleep(ctx, hook.url, hook.request); err != nil {
+				slog.Warn("Prepare to sleep webhook failed, draining anyway", "node", nodeName, "workload", hook.request.Workload, "error", err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// annotatedOwner returns the Deployment or StatefulSet of the pod and its "prepare to sleep" webhook URL, empty if
+// it has none
+func annotatedOwner(ctx context.Context, client kubernetes.Interface, pod *corev1.Pod) (string, string) {
+	for _, owner := range pod.OwnerReferences {
+		switch owner.Kind {
+		case "StatefulSet":
+			statefulSet, err := client.AppsV1().StatefulSets(pod.Namespace).Get(ctx, owner.Name, metav1.GetOptions{})
+			if err != nil {
+				slog.Warn("Failed to get statefulset for prepare to sleep webhook", "statefulset", owner.Name, "namespace", pod.Namespace, "error", err)
+				return "", ""
+			}
+			return fmt.Sprintf("StatefulSet %s/%s", pod.Namespace, owner.Name), statefulSet.Annotations[PrepareToSleepAnnotation]
+		case "ReplicaSet":
+			replicaSet, err := client.AppsV1().ReplicaSets(pod.Namespace).Get(ctx, owner.Name, metav1.GetOptions{})
+			if err != nil {
+				slog.Warn("Failed to get replicaset for prepare to sleep webhook", "replicaset", owner.Name, "namespace", pod.Namespace, "error", err)
+				return "", ""
+			}
+			for _, rsOwner := range replicaSet.OwnerReferences {
+				if rsOwner.Kind != "Deployment" {
+					continue
+				}
+				deployment, err := client.AppsV1().Deployments(pod.Namespace).Get(ctx, rsOwner.Name, metav1.GetOptions{})
+				if err != nil {
+					slog.Warn("Failed to get deployment for prepare to sleep webhook", "deployment", rsOwner.Name, "namespace", pod.Namespace, "error", err)
+					return "", ""
+				}
+				return fmt.Sprintf("Deployment %s/%s", pod.Namespace, rsOwner.Name), deployment.Annotations[PrepareToSleepAnnotation]
+			}
+		}
+	}
+	return "", ""
+}
+
+// callPrepareToSleep posts the request to the webhook and fails on any non-2xx response
+func callPrepareToSleep(ctx context.Context, url string, request *PrepareToSleepRequest) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %v", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call webhook: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("webhook responded with status %d: %s", resp.StatusCode, respBody)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestPrepareToSleep(t *testing.T) {
+	var mu sync.Mutex
+	var got []PrepareToSleepRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request PrepareToSleepRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+		}
+		mu.Lock()
+		got = append(got, request)
+		mu.Unlock()
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	annotated := func(url string) map[string]string {
+		return map[string]string{PrepareToSleepAnnotation: server.URL + url}
+	}
+	pod := func(name string, labels map[string]string, kind, owner string) corev1.Pod {
+		return corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:       "team-a",
+				Name:            name,
+				Labels:          labels,
+				OwnerReferences: []metav1.OwnerReference{{Kind: kind, Name: owner}},
+			},
+			Status: corev1.PodStatus{Phase: corev1.PodRunning},
+		}
+	}
+	pods := []corev1.Pod{
+		pod("web-abc-1", map[string]string{"app": "web"}, "ReplicaSet", "web-abc"),
+		pod("web-abc-2", map[string]string{"app": "web"}, "ReplicaSet", "web-abc"),
+		pod("db-0", map[string]string{"app": "db"}, "StatefulSet", "db"),
+		pod("cache-0", map[string]string{"app": "cache"}, "StatefulSet", "cache"),
+	}
+	client := fake.NewSimpleClientset(
+		&appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{
+			Namespace:       "team-a",
+			Name:            "web-abc",
+			OwnerReferences: []metav1.OwnerReference{{Kind: "Deployment", Name: "web"}},
+		}},
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "web", Annotations: annotated("/web")}},
+		&appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "db", Annotations: annotated("/fail")}},
+		&appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "cache"}},
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "cache", Annotations: annotated("/cache")},
+			Spec:       corev1.ServiceSpec{Selector: map[string]string{"app": "cache"}},
+		},
+	)
+
+	prepareToSleep(context.Background(), client, "node-1", pods)
+
+	sort.Slice(got, func(i, j int) bool { return got[i].Workload < got[j].Workload })
+	want := []PrepareToSleepRequest{
+		{Node: "node-1", Workload: "Deployment team-a/web", Pods: []string{"web-abc-1", "web-abc-2"}},
+		{Node: "node-1", Workload: "Service team-a/cache", Pods: []string{"cache-0"}},
+		{Node: "node-1", Workload: "StatefulSet team-a/db", Pods: []string{"db-0"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("prepareToSleep() requests = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,143 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// RebalanceNodePool evicts pods from the nodes of the node pool running more pods than their share, so that they
+// are rescheduled onto the nodes with fewer pods, e.g. after pods were packed onto the nodes that stayed up during
+// off time. Only pods of ReplicaSets and StatefulSets with at most maxPriority are evicted, lowest priority first,
+// and at most maxEvictions of them. Evictions refused by PodDisruptionBudgets are skipped. It returns the evicted
+// pods as "namespace/name".
+func RebalanceNodePool(ctx context.Context, client kubernetes.Interface, cloudProvider, nodePoolName string, maxPriority int32, maxEvictions int) ([]string, error) {
+	nodes, err := NodePoolNodes(ctx, client, cloudProvider, nodePoolName)
+	if err != nil {
+		return nil, err
+	}
+
+	// The pods counting towards the share of each schedulable node, and the ones that can be moved
+	schedulable, total := 0, 0
+	counts := make(map[string]int)
+	movable := make(map[string][]corev1.Pod)
+	for _, node := range nodes {
+		if node.Spec.Unschedulable {
+			continue
+		}
+		schedulable++
+		pods, err := client.CoreV1().Pods("").List(ctx, metav1.ListOptions{
+			FieldSelector: fmt.Sprintf("spec.nodeName=%s", node.Name),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list pods on node %s: %v", node.Name, err)
+		}
+		for _, pod := range pods.Items {
+			if ownedByDaemonSet(&pod) || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+				continue
+			}
+			counts[node.Name]++
+			total++
+			if rebalanceable(&pod, maxPriority) {
+				movable[node.Name] = append(movable[node.Name], pod)
+			}
+		}
+	}
+	if schedulable < 2 {
+		return nil, nil
+	}
+	share := (total + schedulable - 1) / schedulable
+
+	// Nodes running the most pods are relieved first
+	names := make([]string, 0, len(movable))
+	for name := range movable {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if counts[names[i]] != counts[names[j]] {
+			return counts[names[i]] > counts[names[j]]
+		}
+		return names[i] < names[j]
+	})
+
+	var evicted []string
+	for _, name := range names {
+		pods := movable[name]
+		sort.SliceStable(pods, func(i, j int) bool { return podPriority(&pods[i]) < podPriority(&pods[j]) })
+		for i := 0; i < len(pods) && counts[name] > share && len(evicted) < maxEvictions; i++ {
+			pod := &pods[i]
+			err := client.PolicyV1().Evictions(pod.Namespace).Evict(ctx, &policyv1.Eviction{
+				ObjectMeta: metav1.ObjectMeta{Name: pod.Name, Namespace: pod.Namespace},
+			})
+			if k8serrors.IsTooManyRequests(err) {
+				slog.Info("Pod eviction for rebalance blocked by PodDisruptionBudget", "pod", pod.Name, "namespace", pod.Namespace)
+				continue
+			}
+			if err != nil && !k8serrors.IsNotFound(err) {
+				return evicted, fmt.Errorf("failed to evict pod %s/%s: %v", pod.Namespace, pod.Name, err)
+			}
+			counts[name]--
+			evicted = append(evicted, pod.Namespace+"/"+pod.Name)
+		}
+	}
+	return evicted, nil
+}
+
+// rebalanceable returns whether the pod may be evicted to rebalance it: it's rescheduled by its ReplicaSet or
+// StatefulSet, isn't a system or mirror pod, and its priority is at most maxPriority
+func rebalanceable(pod *corev1.Pod, maxPriority int32) bool {
+	if pod.Namespace == "kube-system" || pod.DeletionTimestamp != nil || isMirrorPod(pod) || podPriority(pod) > maxPriority {
+		return false
+	}
+	for _, owner := range pod.OwnerReferences {
+		if owner.Kind == "ReplicaSet" || owner.Kind == "StatefulSet" {
+			return true
+		}
+	}
+	return false
+}
+
+// podPriority returns the priority of the pod, 0 if it has none
+func podPriority(pod *corev1.Pod) int32 {
+	if pod.Spec.Priority == nil {
+		return 0
+	}
+	return *pod.Spec.Priority
+}
+
+// RunCronJob creates a Job from the job template of the CronJob like "kubectl create job --from=cronjob" does,
+// without waiting for it, and returns its name
+func RunCronJob(ctx context.Context, client kubernetes.Interface, namespace, name string) (string, error) {
+	cronJob, err := client.BatchV1().CronJobs(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to get cronjob %s/%s: %v", namespace, name, err)
+	}
+
+	annotations := map[string]string{"cronjob.kubernetes.io/instantiate": "manual"}
+	for key, value := range cronJob.Spec.JobTemplate.Annotations {
+		annotations[key] = value
+	}
+	job, err := client.BatchV1().Jobs(namespace).Create(ctx, &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: name + "-bmw-saver-",
+			Labels:       cronJob.Spec.JobTemplate.Labels,
+			Annotations:  annotations,
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(cronJob, batchv1.SchemeGroupVersion.WithKind("CronJob")),
+			},
+		},
+		Spec: cronJob.Spec.JobTemplate.Spec,
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to create job from cronjob %s/%s: %v", namespace, name, err)
+	}
+	return job.Name, nil
+}
@@ -0,0 +1,112 @@
+package kubernetes
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestRebalanceNodePool(t *testing.T) {
+	node := func(name string) *corev1.Node {
+		return &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{NodePoolLabels["gke"]: "default-pool"}}}
+	}
+	pod := func(name, nodeName, owner string, priority int32) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:       "team-a",
+				Name:            name,
+				OwnerReferences: []metav1.OwnerReference{{Kind: owner, Name: "owner"}},
+			},
+			Spec:   corev1.PodSpec{NodeName: nodeName, Priority: &priority},
+			Status: corev1.PodStatus{Phase: corev1.PodRunning},
+		}
+	}
+
+	tests := []struct {
+		name         string
+		maxEvictions int
+		want         []string
+	}{
+		{
+			name:         "down to the share of each node",
+			maxEvictions: 20,
+			want:         []string{"team-a/low", "team-a/web-1"},
+		},
+		{
+			name:         "at most max evictions",
+			maxEvictions: 1,
+			want:         []string{"team-a/low"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 6 pods on 3 nodes, node-1 runs 4 of them
+			client := newFakeClientset(
+				node("node-1"), node("node-2"), node("node-3"),
+				pod("web-1", "node-1", "ReplicaSet", 0),
+				pod("low", "node-1", "ReplicaSet", -10),
+				pod("critical", "node-1", "ReplicaSet", 1000),
+				pod("blocked", "node-1", "ReplicaSet", 0),
+				pod("logs", "node-1", "DaemonSet", 0),
+				pod("db-0", "node-2", "StatefulSet", 0),
+				pod("web-2", "node-3", "ReplicaSet", 0),
+			)
+			var evicted []string
+			client.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				if action.GetSubresource() != "eviction" {
+					return false, nil, nil
+				}
+				eviction := action.(k8stesting.CreateAction).GetObject().(*policyv1.Eviction)
+				if eviction.Name == "blocked" {
+					return true, nil, k8serrors.NewTooManyRequests("disruption budget", 0)
+				}
+				evicted = append(evicted, eviction.Namespace+"/"+eviction.Name)
+				return true, nil, nil
+			})
+
+			got, err := RebalanceNodePool(context.Background(), client, "gke", "default-pool", 0, tt.maxEvictions)
+			if err != nil {
+				t.Fatalf("RebalanceNodePool() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) || !reflect.DeepEqual(evicted, tt.want) {
+				t.Errorf("RebalanceNodePool() = %v, evicted %v, want %v", got, evicted, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunCronJob(t *testing.T) {
+	client := newFakeClientset(&batchv1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "kube-system", Name: "descheduler"},
+		Spec: batchv1.CronJobSpec{JobTemplate: batchv1.JobTemplateSpec{
+			ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "descheduler"}},
+			Spec:       batchv1.JobSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "descheduler"}}}}},
+		}},
+	})
+	ctx := context.Background()
+
+	if _, err := RunCronJob(ctx, client, "kube-system", "descheduler"); err != nil {
+		t.Fatalf("RunCronJob() error = %v", err)
+	}
+	jobs, err := client.BatchV1().Jobs("kube-system").List(ctx, metav1.ListOptions{})
+	if err != nil || len(jobs.Items) != 1 {
+		t.Fatalf("jobs = %v, %v, want one", jobs, err)
+	}
+	job := jobs.Items[0]
+	if job.Labels["app"] != "descheduler" || job.Annotations["cronjob.kubernetes.io/instantiate"] != "manual" ||
+		job.OwnerReferences[0].Kind != "CronJob" || len(job.Spec.Template.Spec.Containers) != 1 {
+		t.Errorf("job = %+v, want created from the cronjob", job)
+	}
+
+	if _, err := RunCronJob(ctx, client, "kube-system", "missing"); err == nil {
+		t.Error("RunCronJob() of missing cronjob error = nil, want error")
+	}
+}
@@ -0,0 +1,175 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// SleepPageNamePrefix is the prefix of the ConfigMap, Deployment and Service serving the sleep page of an
+	// Ingress, followed by its name
+	SleepPageNamePrefix = "bmw-saver-sleep-page-"
+	// SleepPageAnnotation marks Ingresses pointed at their sleep page
+	SleepPageAnnotation = "bmw-saver.io/sleep-page"
+)
+
+// savedIngress is the saved spec of an Ingress pointed at its sleep page
+type savedIngress struct {
+	DefaultBackend *networkingv1.IngressBackend `json:"defaultBackend,omitempty"`
+	Rules          []networkingv1.IngressRule   `json:"rules,omitempty"`
+}
+
+// ShowSleepPage serves the HTML page with the image in the namespace of the Ingress and points all of its
+// backends at it, and returns whether it wasn't pointed at it already. The backends are saved in a ConfigMap
+// of the state namespace first, unless they are saved already. The page is updated if it was shown already.
+func ShowSleepPage(ctx context.Context, client kubernetes.Interface, stateNamespace, namespace, name, image, html string) (bool, error) {
+	ingress, err := client.NetworkingV1().Ingresses(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return false, fmt.Errorf("failed to get ingress %s/%s: %v", namespace, name, err)
+	}
+	if err := applySleepPage(ctx, client, namespace, SleepPageNamePrefix+name, image, html); err != nil {
+		return false, err
+	}
+	if ingress.Annotations[SleepPageAnnotation] == "true" {
+		return false, nil
+	}
+
+	data, err := json.Marshal(savedIngress{DefaultBackend: ingress.Spec.DefaultBackend, Rules: ingress.Spec.Rules})
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal ingress: %v", err)
+	}
+	key := StateKey{Kind: StateKindIngress, Namespace: namespace, Name: name}
+	if err := NewStateStore(client, stateNamespace).Save(ctx, key, workloadConfig{Ingress: data}); err != nil {
+		return false, err
+	}
+
+	backend := &networkingv1.IngressBackend{Service: &networkingv1.IngressServiceBackend{
+		Name: SleepPageNamePrefix + name,
+		Port: networkingv1.ServiceBackendPort{Number: 80},
+	}}
+	if ingress.Spec.DefaultBackend != nil || len(ingress.Spec.Rules) == 0 {
+		ingress.Spec.DefaultBackend = backend
+	}
+	for i := range ingress.Spec.Rules {
+		if http := ingress.Spec.Rules[i].HTTP; http != nil {
+			for j := range http.Paths {
+				http.Paths[j].Backend = *backend
+			}
+		}
+	}
+	if ingress.Annotations == nil {
+		ingress.Annotations = make(map[string]string)
+	}
+	ingress.Annotations[SleepPageAnnotation] = "true"
+	if _, err := client.NetworkingV1().Ingresses(namespace).Update(ctx, ingress, metav1.UpdateOptions{}); err != nil {
+		return false, fmt.Errorf("failed to update ingress %s/%s: %v", namespace, name, err)
+	}
+	return true, nil
+}
+
+// HideSleepPage points the backends of the Ingress back at their saved Services and deletes its sleep page, and
+// returns whether the sleep page was shown
+func HideSleepPage(ctx context.Context, client kubernetes.Interface, stateNamespace, namespace, name string) (bool, error) {
+	store := NewStateStore(client, stateNamespace)
+	key := StateKey{Kind: StateKindIngress, Namespace: namespace, Name: name}
+	var saved workloadConfig
+	if ok, err := store.Load(ctx, key, &saved); err != nil || !ok {
+		return false, err
+	}
+	var spec savedIngress
+	if err := json.Unmarshal(saved.Ingress, &spec); err != nil {
+		return false, fmt.Errorf("failed to parse saved ingress: %v", err)
+	}
+
+	ingress, err := client.NetworkingV1().Ingresses(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return false, fmt.Errorf("failed to get ingress %s/%s: %v", namespace, name, err)
+	}
+	ingress.Spec.DefaultBackend, ingress.Spec.Rules = spec.DefaultBackend, spec.Rules
+	delete(ingress.Annotations, SleepPageAnnotation)
+	if _, err := client.NetworkingV1().Ingresses(namespace).Update(ctx, ingress, metav1.UpdateOptions{}); err != nil {
+		return false, fmt.Errorf("failed to update ingress %s/%s: %v", namespace, name, err)
+	}
+	if err := store.Delete(ctx, key); err != nil {
+		return false, err
+	}
+
+	pageName := SleepPageNamePrefix + name
+	if err := client.CoreV1().Services(namespace).Delete(ctx, pageName, metav1.DeleteOptions{}); err != nil && !k8serrors.IsNotFound(err) {
+		return false, fmt.Errorf("failed to delete sleep page service %s/%s: %v", namespace, pageName, err)
+	}
+	if err := client.AppsV1().Deployments(namespace).Delete(ctx, pageName, metav1.DeleteOptions{}); err != nil && !k8serrors.IsNotFound(err) {
+		return false, fmt.Errorf("failed to delete sleep page deployment %s/%s: %v", namespace, pageName, err)
+	}
+	if err := client.CoreV1().ConfigMaps(namespace).Delete(ctx, pageName, metav1.DeleteOptions{}); err != nil && !k8serrors.IsNotFound(err) {
+		return false, fmt.Errorf("failed to delete sleep page %s/%s: %v", namespace, pageName, err)
+	}
+	return true, nil
+}
+
+// applySleepPage creates the ConfigMap with the HTML page, the Deployment serving it and its Service, or updates
+// the page if they exist
+func applySleepPage(ctx context.Context, client kubernetes.Interface, namespace, name, image, html string) error {
+	labels := map[string]string{"app.kubernetes.io/name": name, "app.kubernetes.io/managed-by": "bmw-saver"}
+	meta := metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: labels}
+
+	configMap := &corev1.ConfigMap{ObjectMeta: meta, Data: map[string]string{"index.html": html}}
+	if _, err := client.CoreV1().ConfigMaps(namespace).Create(ctx, configMap, metav1.CreateOptions{}); k8serrors.IsAlreadyExists(err) {
+		_, err = client.CoreV1().ConfigMaps(namespace).Update(ctx, configMap, metav1.UpdateOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to update sleep page %s/%s: %v", namespace, name, err)
+		}
+	} else if err != nil {
+		return fmt.Errorf("failed to create sleep page %s/%s: %v", namespace, name, err)
+	}
+
+	replicas := int32(1)
+	deployment := &appsv1.Deployment{
+		ObjectMeta: meta,
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: labels},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{
+						Name:         "sleep-page",
+						Image:        image,
+						Ports:        []corev1.ContainerPort{{Name: "http", ContainerPort: 80}},
+						VolumeMounts: []corev1.VolumeMount{{Name: "page", MountPath: "/usr/share/nginx/html", ReadOnly: true}},
+					}},
+					Volumes: []corev1.Volume{{
+						Name: "page",
+						VolumeSource: corev1.VolumeSource{
+							ConfigMap: &corev1.ConfigMapVolumeSource{LocalObjectReference: corev1.LocalObjectReference{Name: name}},
+						},
+					}},
+				},
+			},
+		},
+	}
+	if _, err := client.AppsV1().Deployments(namespace).Create(ctx, deployment, metav1.CreateOptions{}); err != nil && !k8serrors.IsAlreadyExists(err) {
+		return fmt.Errorf("failed to create sleep page deployment %s/%s: %v", namespace, name, err)
+	}
+
+	service := &corev1.Service{
+		ObjectMeta: meta,
+		Spec: corev1.ServiceSpec{
+			Selector: labels,
+			Ports:    []corev1.ServicePort{{Name: "http", Port: 80, TargetPort: intstr.FromString("http")}},
+		},
+	}
+	if _, err := client.CoreV1().Services(namespace).Create(ctx, service, metav1.CreateOptions{}); err != nil && !k8serrors.IsAlreadyExists(err) {
+		return fmt.Errorf("failed to create sleep page service %s/%s: %v", namespace, name, err)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestShowAndHideSleepPage(t *testing.T) {
+	prefix := networkingv1.PathTypePrefix
+	backend := networkingv1.IngressBackend{Service: &networkingv1.IngressServiceBackend{
+		Name: "web",
+		Port: networkingv1.ServiceBackendPort{Number: 8080},
+	}}
+	client := fake.NewSimpleClientset(&networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "shop"},
+		Spec: networkingv1.IngressSpec{Rules: []networkingv1.IngressRule{{
+			Host: "shop.example.com",
+			IngressRuleValue: networkingv1.IngressRuleValue{HTTP: &networkingv1.HTTPIngressRuleValue{
+				Paths: []networkingv1.HTTPIngressPath{{Path: "/", PathType: &prefix, Backend: backend}},
+			}},
+		}}},
+	})
+	ctx := context.Background()
+
+	if hidden, err := HideSleepPage(ctx, client, "bmw-saver", "team-a", "shop"); err != nil || hidden {
+		t.Errorf("HideSleepPage() = %v, %v, want not hidden before showing", hidden, err)
+	}
+	if shown, err := ShowSleepPage(ctx, client, "bmw-saver", "team-a", "shop", "nginx", "sleeping"); err != nil || !shown {
+		t.Fatalf("ShowSleepPage() = %v, %v, want shown", shown, err)
+	}
+	if got := getIngressBackend(t, client); got != SleepPageNamePrefix+"shop" {
+		t.Errorf("backend = %s after showing, want the sleep page", got)
+	}
+	if shown, err := ShowSleepPage(ctx, client, "bmw-saver", "team-a", "shop", "nginx", "back soon"); err != nil || shown {
+		t.Errorf("ShowSleepPage() = %v, %v, want shown already", shown, err)
+	}
+	page, err := client.CoreV1().ConfigMaps("team-a").Get(ctx, SleepPageNamePrefix+"shop", metav1.GetOptions{})
+	if err != nil || page.Data["index.html"] != "back soon" {
+		t.Errorf("page = %v, %v, want the updated page", page, err)
+	}
+	if _, err := client.CoreV1().Services("team-a").Get(ctx, SleepPageNamePrefix+"shop", metav1.GetOptions{}); err != nil {
+		t.Errorf("failed to get sleep page service: %v", err)
+	}
+
+	if hidden, err := HideSleepPage(ctx, client, "bmw-saver", "team-a", "shop"); err != nil || !hidden {
+		t.Fatalf("HideSleepPage() = %v, %v, want hidden", hidden, err)
+	}
+	if got := getIngressBackend(t, client); got != "web" {
+		t.Errorf("backend = %s after hiding, want web", got)
+	}
+	if _, err := client.AppsV1().Deployments("team-a").Get(ctx, SleepPageNamePrefix+"shop", metav1.GetOptions{}); err == nil {
+		t.Errorf("sleep page deployment exists after hiding, want deleted")
+	}
+	if _, err := client.CoreV1().ConfigMaps("bmw-saver").Get(ctx, WorkloadConfigMapNamePrefix+"team-a.shop.ingress", metav1.GetOptions{}); err == nil {
+		t.Errorf("saved ingress exists after hiding, want deleted")
+	}
+}
+
+func getIngressBackend(t *testing.T, client *fake.Clientset) string {
+	t.Helper()
+	ingress, err := client.NetworkingV1().Ingresses("team-a").Get(context.Background(), "shop", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("failed to get ingress: %v", err)
+	}
+	return ingress.Spec.Rules[0].HTTP.Paths[0].Backend.Service.Name
+}
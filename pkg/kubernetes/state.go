@@ -0,0 +1,282 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// StateVersion is the version of the saved state format written by this version of bmw-saver. State saved by a
+	// newer version is refused, state saved before versioning is version 0 and still restored.
+	StateVersion = 1
+
+	// StateKindLabel labels the ConfigMaps of the state store with the kind of their target
+	StateKindLabel = "bmw-saver.io/state-kind"
+
+	// NodePoolStateNamePrefix is the prefix of the ConfigMaps saving the configuration of scaled down node pools,
+	// followed by their name
+	NodePoolStateNamePrefix = "bmw-saver-nodepool-"
+	// WorkloadConfigMapNamePrefix is the prefix of the ConfigMaps saving the state of scaled down workloads,
+	// followed by their namespace and name, and the lower case kind unless it's a Deployment
+	WorkloadConfigMapNamePrefix = "bmw-saver-workload-"
+)
+
+// Kinds of the targets whose state is saved, Flux resources are saved with their own kind
+const (
+	StateKindNodePool    = "NodePool"
+	StateKindDeployment  = "Deployment"
+	StateKindStatefulSet = "StatefulSet"
+	StateKindApplication = "Application"
+	StateKindIngress     = "Ingress"
+)
+
+// StateKey identifies the saved state of a scaled down target by its kind and name, node pools have no namespace
+type StateKey struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+}
+
+// String returns the key as "kind namespace/name", or "kind name" without namespace
+func (k StateKey) String() string {
+	if k.Namespace == "" {
+		return k.Kind + " " + k.Name
+	}
+	return k.Kind + " " + k.Namespace + "/" + k.Name
+}
+
+// configMapName returns the name of the ConfigMap saving the state, the same as before the state store so that
+// state saved by earlier versions is restored
+func (k StateKey) configMapName() string {
+	switch k.Kind {
+	case StateKindNodePool:
+		return NodePoolStateNamePrefix + k.Name
+	case StateKindDeployment:
+		return fmt.Sprintf("%s%s.%s", WorkloadConfigMapNamePrefix, k.Namespace, k.Name)
+	default:
+		return fmt.Sprintf("%s%s.%s.%s", WorkloadConfigMapNamePrefix, k.Namespace, k.Name, strings.ToLower(k.Kind))
+	}
+}
+
+// SavedState is the state of a scaled down target saved in the state store
+type SavedState struct {
+	StateKey
+	// Version is the version of the format the state was saved in
+	Version int `json:"version"`
+	// SavedAt is when the state was saved, zero if it was saved before versioning
+	SavedAt time.Time `json:"savedAt,omitempty"`
+	// RestoredAt is when the target was restored to its full size, zero while it's still scaled down
+	RestoredAt time.Time `json:"restoredAt,omitempty"`
+	// Config is the saved configuration of the target, e.g. the replicas of a workload
+	Config json.RawMessage `json:"config"`
+}
+
+// StateStore saves the state of scaled down node pools and workloads in the state namespace until they are
+// restored, keyed by the kind and name of the target. Node pools are saved in NodePoolState custom resources, or in
+// ConfigMaps like workloads if the NodePoolState CRD isn't installed.
+type StateStore struct {
+	client    kubernetes.Interface
+	namespace string
+}
+
+// NewStateStore creates a state store saving in the namespace
+func NewStateStore(client kubernetes.Interface, namespace string) *StateStore {
+	return &StateStore{client: client, namespace: namespace}
+}
+
+// Save saves the configuration of the target. State saved by an earlier scale down is kept while the target is
+// still scaled down, and refreshed once the target was restored to its full size, so that changes made to the
+// target while it was up are restored the next time.
+func (s *StateStore) Save(ctx context.Context, key StateKey, config interface{}) error {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("failed to marshal state of %s: %v", key, err)
+	}
+	if key.Kind == StateKindNodePool {
+		if saved, err := s.saveNodePoolState(ctx, key, data); saved || err != nil {
+			return err
+		}
+	}
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      key.configMapName(),
+			Namespace: s.namespace,
+			Labels: map[string]string{
+				"app.kubernetes.io/managed-by": "bmw-saver",
+				StateKindLabel:                 key.Kind,
+			},
+		},
+		Data: map[string]string{
+			"kind":      key.Kind,
+			"namespace": key.Namespace,
+			"name":      key.Name,
+			"version":   strconv.Itoa(StateVersion),
+			"savedAt":   time.Now().UTC().Format(time.RFC3339),
+			"config":    string(data),
+		},
+	}
+	configMaps := s.client.CoreV1().ConfigMaps(s.namespace)
+	_, err = configMaps.Create(ctx, configMap, metav1.CreateOptions{})
+	if !k8serrors.IsAlreadyExists(err) {
+		if err != nil {
+			return fmt.Errorf("failed to save state of %s: %v", key, err)
+		}
+		return nil
+	}
+
+	existing, err := configMaps.Get(ctx, configMap.Name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get saved state of %s: %v", key, err)
+	}
+	if existing.Data["restoredAt"] == "" {
+		return nil
+	}
+	existing.Labels, existing.Data = configMap.Labels, configMap.Data
+	if _, err := configMaps.Update(ctx, existing, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to refresh saved state of %s: %v", key, err)
+	}
+	slog.Info("Refreshed saved state", "target", key.String())
+	return nil
+}
+
+// MarkRestored marks the saved state of the target as restored to its full size, so that it's refreshed by the
+// next Save. It does nothing if there is no saved state, or it's marked already.
+func (s *StateStore) MarkRestored(ctx context.Context, key StateKey) error {
+	if key.Kind == StateKindNodePool {
+		if ok, err := s.markNodePoolStateRestored(ctx, key); ok || err != nil {
+			return err
+		}
+	}
+	configMaps := s.client.CoreV1().ConfigMaps(s.namespace)
+	configMap, err := configMaps.Get(ctx, key.configMapName(), metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get saved state of %s: %v", key, err)
+	}
+	if configMap.Data["restoredAt"] != "" {
+		return nil
+	}
+	if configMap.Data == nil {
+		configMap.Data = make(map[string]string)
+	}
+	configMap.Data["restoredAt"] = time.Now().UTC().Format(time.RFC3339)
+	if _, err := configMaps.Update(ctx, configMap, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to mark saved state of %s as restored: %v", key, err)
+	}
+	return nil
+}
+
+// Load loads the saved configuration of the target into config, and returns whether it was saved
+func (s *StateStore) Load(ctx context.Context, key StateKey, config interface{}) (bool, error) {
+	state, ok, err := s.Get(ctx, key)
+	if err != nil || !ok {
+		return false, err
+	}
+	if err := json.Unmarshal(state.Config, config); err != nil {
+		return false, fmt.Errorf("failed to parse saved state of %s: %v", key, err)
+	}
+	return true, nil
+}
+
+// Get returns the saved state of the target, and whether it was saved. Node pools saved in ConfigMaps by earlier
+// versions or without the NodePoolState CRD are loaded from them.
+func (s *StateStore) Get(ctx context.Context, key StateKey) (SavedState, bool, error) {
+	if key.Kind == StateKindNodePool {
+		if state, ok, err := s.loadNodePoolState(ctx, key); ok || err != nil {
+			return state, ok, err
+		}
+	}
+	configMap, err := s.client.CoreV1().ConfigMaps(s.namespace).Get(ctx, key.configMapName(), metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		return SavedState{}, false, nil
+	}
+	if err != nil {
+		return SavedState{}, false, fmt.Errorf("failed to get saved state of %s: %v", key, err)
+	}
+	state, err := parseSavedState(key, configMap)
+	return state, err == nil, err
+}
+
+// Delete deletes the saved state of a restored target, node pools are deleted with the status of their
+// NodePoolState
+func (s *StateStore) Delete(ctx context.Context, key StateKey) error {
+	if key.Kind == StateKindNodePool {
+		if err := s.deleteNodePoolState(ctx, key); err != nil {
+			return err
+		}
+	}
+	if err := s.client.CoreV1().ConfigMaps(s.namespace).Delete(ctx, key.configMapName(), metav1.DeleteOptions{}); err != nil && !k8serrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete saved state of %s: %v", key, err)
+	}
+	return nil
+}
+
+// List returns the saved state of all targets not restored yet, sorted by kind and name. State saved before
+// versioning isn't labeled and not listed.
+func (s *StateStore) List(ctx context.Context) ([]SavedState, error) {
+	configMaps, err := s.client.CoreV1().ConfigMaps(s.namespace).List(ctx, metav1.ListOptions{LabelSelector: StateKindLabel})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list saved state: %v", err)
+	}
+	states := make([]SavedState, 0, len(configMaps.Items))
+	for i := range configMaps.Items {
+		data := configMaps.Items[i].Data
+		key := StateKey{Kind: data["kind"], Namespace: data["namespace"], Name: data["name"]}
+		state, err := parseSavedState(key, &configMaps.Items[i])
+		if err != nil {
+			return nil, err
+		}
+		states = append(states, state)
+	}
+	nodePools, err := s.listNodePoolStates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	states = append(states, nodePools...)
+	sort.Slice(states, func(i, j int) bool { return states[i].StateKey.String() < states[j].StateKey.String() })
+	return states, nil
+}
+
+// parseSavedState parses the saved state of the target from its ConfigMap, and refuses state saved by a newer
+// version of bmw-saver
+func parseSavedState(key StateKey, configMap *corev1.ConfigMap) (SavedState, error) {
+	state := SavedState{StateKey: key, Config: json.RawMessage(configMap.Data["config"])}
+	if value := configMap.Data["version"]; value != "" {
+		version, err := strconv.Atoi(value)
+		if err != nil {
+			return state, fmt.Errorf("invalid version of saved state of %s: %s", key, value)
+		}
+		state.Version = version
+	}
+	if state.Version > StateVersion {
+		return state, fmt.Errorf("state of %s was saved in version %d, newer than supported version %d", key, state.Version, StateVersion)
+	}
+	if value := configMap.Data["savedAt"]; value != "" {
+		savedAt, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return state, fmt.Errorf("invalid save time of saved state of %s: %s", key, value)
+		}
+		state.SavedAt = savedAt
+	}
+	if value := configMap.Data["restoredAt"]; value != "" {
+		restoredAt, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return state, fmt.Errorf("invalid restore time of saved state of %s: %s", key, value)
+		}
+		state.RestoredAt = restoredAt
+	}
+	return state, nil
+}
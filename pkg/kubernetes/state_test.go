@@ -0,0 +1,339 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// newStateServer returns a client of an API server keeping the ConfigMaps and NodePoolStates of the bmw-saver
+// namespace in objects by path, the NodePoolState CRD is only installed if crd is true
+func newStateServer(t *testing.T, crd bool, objects map[string]map[string]interface{}) kubernetes.Interface {
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		failure := func(code int, reason string) {
+			w.WriteHeader(code)
+			_, _ = fmt.Fprintf(w, `{"kind": "Status", "apiVersion": "v1", "status": "Failure", "reason": %q, "code": %d}`, reason, code)
+		}
+
+		collections := []string{"/api/v1/namespaces/bmw-saver/configmaps"}
+		if crd {
+			collections = append(collections, nodePoolStatePath("bmw-saver"))
+		}
+		path, isStatus := strings.CutSuffix(r.URL.Path, "/status")
+		collection := ""
+		for _, c := range collections {
+			if path == c || strings.HasPrefix(path, c+"/") {
+				collection = c
+			}
+		}
+		if collection == "" {
+			failure(http.StatusNotFound, "NotFound")
+			return
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		object, ok := objects[path]
+		switch {
+		case path == collection && r.Method == http.MethodGet:
+			var paths []string
+			for p := range objects {
+				if strings.HasPrefix(p, collection+"/") {
+					paths = append(paths, p)
+				}
+			}
+			sort.Strings(paths)
+			selector := r.URL.Query().Get("labelSelector")
+			items := []interface{}{}
+			for _, p := range paths {
+				labels, _ := objects[p]["metadata"].(map[string]interface{})["labels"].(map[string]interface{})
+				if _, ok := labels[selector]; selector == "" || ok {
+					items = append(items, objects[p])
+				}
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"items": items})
+		case path == collection && r.Method == http.MethodPost:
+			var created map[string]interface{}
+			_ = json.Unmarshal(body, &created)
+			name := created["metadata"].(map[string]interface{})["name"].(string)
+			if _, ok := objects[collection+"/"+name]; ok {
+				failure(http.StatusConflict, "AlreadyExists")
+				return
+			}
+			delete(created, "status")
+			objects[collection+"/"+name] = created
+			w.WriteHeader(http.StatusCreated)
+			_ = json.NewEncoder(w).Encode(created)
+		case !ok:
+			failure(http.StatusNotFound, "NotFound")
+		case r.Method == http.MethodGet:
+			_ = json.NewEncoder(w).Encode(object)
+		case r.Method == http.MethodDelete:
+			delete(objects, path)
+			_, _ = w.Write([]byte(`{"kind": "Status", "apiVersion": "v1", "status": "Success"}`))
+		case r.Method == http.MethodPut:
+			var updated map[string]interface{}
+			_ = json.Unmarshal(body, &updated)
+			objects[path] = updated
+			_ = json.NewEncoder(w).Encode(updated)
+		case r.Method == http.MethodPatch:
+			var patch map[string]interface{}
+			_ = json.Unmarshal(body, &patch)
+			// Only the status subresource changes the status
+			if isStatus {
+				patch = map[string]interface{}{"status": patch["status"]}
+			} else {
+				delete(patch, "status")
+			}
+			mergePatch(object, patch)
+			_ = json.NewEncoder(w).Encode(object)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			failure(http.StatusMethodNotAllowed, "MethodNotAllowed")
+		}
+	}))
+	t.Cleanup(server.Close)
+	return kubernetes.NewForConfigOrDie(&rest.Config{
+		Host:          server.URL,
+		ContentConfig: rest.ContentConfig{ContentType: "application/json"},
+		QPS:           1000,
+		Burst:         1000,
+	})
+}
+
+// mergePatch applies the JSON merge patch to the object
+func mergePatch(object, patch map[string]interface{}) {
+	for key, value := range patch {
+		nested, isMap := value.(map[string]interface{})
+		switch {
+		case value == nil:
+			delete(object, key)
+		case isMap:
+			current, ok := object[key].(map[string]interface{})
+			if !ok {
+				current = map[string]interface{}{}
+				object[key] = current
+			}
+			mergePatch(current, nested)
+		default:
+			object[key] = value
+		}
+	}
+}
+
+// configMapObject returns a ConfigMap of the bmw-saver namespace with the data as stored by newStateServer
+func configMapObject(name string, data map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"metadata": map[string]interface{}{"name": name, "namespace": "bmw-saver"},
+		"data":     data,
+	}
+}
+
+func TestStateStore(t *testing.T) {
+	objects := map[string]map[string]interface{}{
+		// Saved before the state store
+		"/api/v1/namespaces/bmw-saver/configmaps/bmw-saver-workload-team-a.legacy.statefulset": configMapObject(
+			"bmw-saver-workload-team-a.legacy.statefulset", map[string]interface{}{"config": `{"replicas":2}`}),
+		// Saved by a newer version
+		"/api/v1/namespaces/bmw-saver/configmaps/bmw-saver-workload-team-a.future.statefulset": configMapObject(
+			"bmw-saver-workload-team-a.future.statefulset", map[string]interface{}{"version": "2", "config": `{"replicas":2}`}),
+	}
+	store := NewStateStore(newStateServer(t, true, objects), "bmw-saver")
+	ctx := context.Background()
+
+	statefulSet := StateKey{Kind: StateKindStatefulSet, Namespace: "team-a", Name: "db"}
+	deployment := StateKey{Kind: StateKindDeployment, Namespace: "team-a", Name: "web"}
+	if err := store.Save(ctx, statefulSet, workloadConfig{Replicas: 3}); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	// Saving again keeps the state saved first
+	if err := store.Save(ctx, statefulSet, workloadConfig{Replicas: 1}); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if err := store.Save(ctx, deployment, workloadConfig{Replicas: 2}); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	var saved workloadConfig
+	if ok, err := store.Load(ctx, statefulSet, &saved); err != nil || !ok || saved.Replicas != 3 {
+		t.Errorf("Load() = %+v, %v, %v, want 3 replicas", saved, ok, err)
+	}
+	if ok, err := store.Load(ctx, StateKey{Kind: StateKindStatefulSet, Namespace: "team-a", Name: "legacy"}, &saved); err != nil || !ok || saved.Replicas != 2 {
+		t.Errorf("Load() legacy = %+v, %v, %v, want 2 replicas", saved, ok, err)
+	}
+	if _, err := store.Load(ctx, StateKey{Kind: StateKindStatefulSet, Namespace: "team-a", Name: "future"}, &saved); err == nil || !strings.Contains(err.Error(), "newer") {
+		t.Errorf("Load() newer version error = %v, want refused", err)
+	}
+	if ok, err := store.Load(ctx, StateKey{Kind: StateKindStatefulSet, Namespace: "team-a", Name: "missing"}, &saved); err != nil || ok {
+		t.Errorf("Load() missing = %v, %v, want not saved", ok, err)
+	}
+
+	states, err := store.List(ctx)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(states) != 2 || states[0].StateKey != deployment || states[1].StateKey != statefulSet {
+		t.Fatalf("List() = %+v, want the deployment and statefulset", states)
+	}
+	if states[1].Version != StateVersion || states[1].SavedAt.IsZero() || string(states[1].Config) != `{"replicas":3}` {
+		t.Errorf("List() statefulset = %+v, want version, save time and config", states[1])
+	}
+
+	// Saved state is refreshed once restored
+	if err := store.MarkRestored(ctx, statefulSet); err != nil {
+		t.Fatalf("MarkRestored() error = %v", err)
+	}
+	if state, ok, err := store.Get(ctx, statefulSet); err != nil || !ok || state.RestoredAt.IsZero() {
+		t.Errorf("Get() = %+v, %v, %v, want marked as restored", state, ok, err)
+	}
+	if err := store.Save(ctx, statefulSet, workloadConfig{Replicas: 4}); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if err := store.Save(ctx, statefulSet, workloadConfig{Replicas: 1}); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if state, ok, err := store.Get(ctx, statefulSet); err != nil || !ok || !state.RestoredAt.IsZero() || string(state.Config) != `{"replicas":4}` {
+		t.Errorf("Get() = %+v, %v, %v, want refreshed to 4 replicas", state, ok, err)
+	}
+	if err := store.MarkRestored(ctx, StateKey{Kind: StateKindStatefulSet, Namespace: "team-a", Name: "missing"}); err != nil {
+		t.Errorf("MarkRestored() missing error = %v, want nothing to mark", err)
+	}
+
+	if err := store.Delete(ctx, statefulSet); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if ok, err := store.Load(ctx, statefulSet, &saved); err != nil || ok {
+		t.Errorf("Load() after Delete() = %v, %v, want not saved", ok, err)
+	}
+}
+
+func TestStateStoreNodePools(t *testing.T) {
+	type nodePoolConfig struct {
+		NodeCount int `json:"nodeCount"`
+	}
+	legacyPath := "/api/v1/namespaces/bmw-saver/configmaps/bmw-saver-nodepool-legacy-pool"
+	restoredPath := "/api/v1/namespaces/bmw-saver/configmaps/bmw-saver-nodepool-restored-pool"
+	for _, crd := range []bool{true, false} {
+		t.Run(fmt.Sprintf("crd installed %v", crd), func(t *testing.T) {
+			objects := map[string]map[string]interface{}{
+				// Saved in a ConfigMap by an earlier version
+				legacyPath: configMapObject("bmw-saver-nodepool-legacy-pool", map[string]interface{}{"config": `{"nodeCount":2}`}),
+				// Saved in a ConfigMap by an earlier version, and restored since
+				restoredPath: configMapObject("bmw-saver-nodepool-restored-pool", map[string]interface{}{
+					"config":     `{"nodeCount":2}`,
+					"restoredAt": "2024-01-01T00:00:00Z",
+				}),
+			}
+			client := newStateServer(t, crd, objects)
+			store := NewStateStore(client, "bmw-saver")
+			ctx := context.Background()
+
+			pool := StateKey{Kind: StateKindNodePool, Name: "default-pool"}
+			legacy := StateKey{Kind: StateKindNodePool, Name: "legacy-pool"}
+			err := UpdateNodePoolStatus(ctx, client, "bmw-saver", "default-pool", NodePoolScaling{Time: time.Now(), Err: errors.New("quota exceeded")})
+			if crd && err != nil {
+				t.Fatalf("UpdateNodePoolStatus() error = %v", err)
+			}
+			if !crd && err == nil {
+				t.Errorf("UpdateNodePoolStatus() without CRD error = nil, want error")
+			}
+			for _, count := range []int{3, 1} {
+				if err := store.Save(ctx, pool, nodePoolConfig{NodeCount: count}); err != nil {
+					t.Fatalf("Save() error = %v", err)
+				}
+			}
+			if err := store.Save(ctx, legacy, nodePoolConfig{NodeCount: 5}); err != nil {
+				t.Fatalf("Save() legacy error = %v", err)
+			}
+
+			var saved nodePoolConfig
+			if ok, err := store.Load(ctx, pool, &saved); err != nil || !ok || saved.NodeCount != 3 {
+				t.Errorf("Load() = %+v, %v, %v, want the 3 nodes saved first", saved, ok, err)
+			}
+			if ok, err := store.Load(ctx, legacy, &saved); err != nil || !ok || saved.NodeCount != 2 {
+				t.Errorf("Load() legacy = %+v, %v, %v, want 2 nodes saved by the earlier version", saved, ok, err)
+			}
+			_, inConfigMap := objects["/api/v1/namespaces/bmw-saver/configmaps/bmw-saver-nodepool-default-pool"]
+			_, inNodePoolState := objects[nodePoolStatePath("bmw-saver")+"/default-pool"]
+			if inConfigMap == crd || inNodePoolState != crd {
+				t.Errorf("saved in ConfigMap %v, NodePoolState %v, want NodePoolState only if the CRD is installed", inConfigMap, inNodePoolState)
+			}
+			if _, ok := objects[legacyPath]; ok == crd {
+				t.Errorf("legacy ConfigMap kept %v, want moved to the NodePoolState if the CRD is installed", ok)
+			}
+
+			// State saved before the state store is only listed once moved to a NodePoolState
+			states, err := store.List(ctx)
+			if err != nil || len(states) == 0 || states[0].StateKey != pool || states[0].Version != StateVersion || states[0].SavedAt.IsZero() {
+				t.Fatalf("List() = %+v, %v, want the node pool with version and save time", states, err)
+			}
+			if !crd {
+				return
+			}
+			if len(states) != 2 || states[1].StateKey != legacy || states[1].Version != 0 {
+				t.Fatalf("List() = %+v, want the moved legacy node pool without version", states)
+			}
+
+			// Legacy state restored since it was saved is replaced by the configuration of the scale down
+			restored := StateKey{Kind: StateKindNodePool, Name: "restored-pool"}
+			if err := store.Save(ctx, restored, nodePoolConfig{NodeCount: 6}); err != nil {
+				t.Fatalf("Save() restored error = %v", err)
+			}
+			got, ok, err := store.Get(ctx, restored)
+			if err != nil || !ok || string(got.Config) != `{"nodeCount":6}` || got.Version != StateVersion || got.SavedAt.IsZero() || !got.RestoredAt.IsZero() {
+				t.Errorf("Get() restored = %+v, %v, %v, want the 6 nodes of the scale down", got, ok, err)
+			}
+			if _, ok := objects[restoredPath]; ok {
+				t.Errorf("restored legacy ConfigMap kept, want moved to the NodePoolState")
+			}
+
+			state := objects[nodePoolStatePath("bmw-saver")+"/default-pool"]
+			if status, _ := state["status"].(map[string]interface{}); status["lastError"] != "quota exceeded" {
+				t.Errorf("status = %v, want the error kept while saving", state["status"])
+			}
+			if err := UpdateNodePoolStatus(ctx, client, "bmw-saver", "default-pool", NodePoolScaling{Time: time.Now(), Count: 1}); err != nil {
+				t.Fatalf("UpdateNodePoolStatus() error = %v", err)
+			}
+			status, _ := state["status"].(map[string]interface{})
+			if status["lastError"] != nil || status["lastScaleDown"] == nil || status["lastScaleDownCount"] != float64(1) {
+				t.Errorf("status = %v, want scaled down to 1 node without error", status)
+			}
+
+			// Saved state is refreshed once restored
+			if err := store.MarkRestored(ctx, pool); err != nil {
+				t.Fatalf("MarkRestored() error = %v", err)
+			}
+			if err := store.Save(ctx, pool, nodePoolConfig{NodeCount: 4}); err != nil {
+				t.Fatalf("Save() error = %v", err)
+			}
+			if err := store.Save(ctx, pool, nodePoolConfig{NodeCount: 1}); err != nil {
+				t.Fatalf("Save() error = %v", err)
+			}
+			if saved, ok, err := store.Get(ctx, pool); err != nil || !ok || !saved.RestoredAt.IsZero() || string(saved.Config) != `{"nodeCount":4}` {
+				t.Errorf("Get() = %+v, %v, %v, want refreshed to 4 nodes", saved, ok, err)
+			}
+
+			if err := store.Delete(ctx, pool); err != nil {
+				t.Fatalf("Delete() error = %v", err)
+			}
+			if ok, err := store.Load(ctx, pool, &saved); err != nil || ok {
+				t.Errorf("Load() after Delete() = %v, %v, want not saved", ok, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// StatefulSets returns the StatefulSets of the namespace by name, or by label selector if name is empty.
+// A missing StatefulSet isn't an error, there are none.
+func StatefulSets(ctx context.Context, client kubernetes.Interface, namespace, name, selector string) ([]appsv1.StatefulSet, error) {
+	if name != "" {
+		statefulSet, err := client.AppsV1().StatefulSets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if k8serrors.IsNotFound(err) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get statefulset %s/%s: %v", namespace, name, err)
+		}
+		return []appsv1.StatefulSet{*statefulSet}, nil
+	}
+
+	statefulSets, err := client.AppsV1().StatefulSets(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list statefulsets in %s: %v", namespace, err)
+	}
+	return statefulSets.Items, nil
+}
+
+// ScaleStatefulSet scales the StatefulSet to the replicas, and returns whether it had other replicas. Its replicas
+// are saved in a ConfigMap of the state namespace first, unless they are saved already by an earlier scale down.
+func ScaleStatefulSet(ctx context.Context, client kubernetes.Interface, stateNamespace string, statefulSet appsv1.StatefulSet, replicas int32) (bool, error) {
+	current := int32(1)
+	if statefulSet.Spec.Replicas != nil {
+		current = *statefulSet.Spec.Replicas
+	}
+	if current == replicas {
+		return false, nil
+	}
+
+	key := StateKey{Kind: StateKindStatefulSet, Namespace: statefulSet.Namespace, Name: statefulSet.Name}
+	if err := NewStateStore(client, stateNamespace).Save(ctx, key, workloadConfig{Replicas: current}); err != nil {
+		return false, err
+	}
+	if err := patchReplicas(ctx, client, "statefulset", statefulSet.Namespace, statefulSet.Name, replicas); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// RestoreStatefulSet restores the StatefulSet towards its saved replicas one replica at a time, adding the next
+// replica only once all of its replicas are ready, so that databases and queues ramp up in order. It returns the
+// replicas it's scaled to, -1 if none were saved, and whether it reached the saved replicas, after which they are
+// deleted like for Deployments.
+func RestoreStatefulSet(ctx context.Context, client kubernetes.Interface, stateNamespace string, statefulSet appsv1.StatefulSet) (int32, bool, error) {
+	store := NewStateStore(client, stateNamespace)
+	key := StateKey{Kind: StateKindStatefulSet, Namespace: statefulSet.Namespace, Name: statefulSet.Name}
+	var config workloadConfig
+	if ok, err := store.Load(ctx, key, &config); err != nil || !ok {
+		return -1, true, err
+	}
+	saved := config.Replicas
+
+	current := int32(1)
+	if statefulSet.Spec.Replicas != nil {
+		current = *statefulSet.Spec.Replicas
+	}
+	if current < saved {
+		// Wait for the replicas added last, the status may still be from before they were added
+		if statefulSet.Status.ObservedGeneration < statefulSet.Generation || statefulSet.Status.ReadyReplicas < current {
+			return current, false, nil
+		}
+		if err := patchReplicas(ctx, client, "statefulset", statefulSet.Namespace, statefulSet.Name, current+1); err != nil {
+			return -1, false, err
+		}
+		if current+1 < saved {
+			return current + 1, false, nil
+		}
+	}
+	// Replicas added during off time are kept
+	if err := store.Delete(ctx, key); err != nil {
+		return -1, false, err
+	}
+	return max(current, saved), true, nil
+}
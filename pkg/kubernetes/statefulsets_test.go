@@ -0,0 +1,68 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestScaleAndRestoreStatefulSet(t *testing.T) {
+	replicas := int32(3)
+	client := fake.NewSimpleClientset(&appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "db", Labels: map[string]string{"tier": "data"}},
+		Spec:       appsv1.StatefulSetSpec{Replicas: &replicas},
+	})
+	ctx := context.Background()
+
+	statefulSets, err := StatefulSets(ctx, client, "team-a", "", "tier=data")
+	if err != nil || len(statefulSets) != 1 {
+		t.Fatalf("StatefulSets() = %v, %v, want db", statefulSets, err)
+	}
+	if scaled, err := ScaleStatefulSet(ctx, client, "bmw-saver", statefulSets[0], 0); err != nil || !scaled {
+		t.Fatalf("ScaleStatefulSet() = %v, %v, want scaled", scaled, err)
+	}
+
+	// Each replica is added once the replicas before it are ready
+	for _, step := range []struct {
+		ready    int32
+		want     int32
+		wantDone bool
+	}{
+		{ready: 0, want: 1},
+		{ready: 0, want: 1},
+		{ready: 1, want: 2},
+		{ready: 1, want: 2},
+		{ready: 2, want: 3, wantDone: true},
+	} {
+		statefulSet := getStatefulSet(t, client)
+		statefulSet.Status.ReadyReplicas = step.ready
+		got, done, err := RestoreStatefulSet(ctx, client, "bmw-saver", statefulSet)
+		if err != nil || got != step.want || done != step.wantDone {
+			t.Fatalf("RestoreStatefulSet() with %d ready = %d, %v, %v, want %d, %v", step.ready, got, done, err, step.want, step.wantDone)
+		}
+		if replicas := *getStatefulSet(t, client).Spec.Replicas; replicas != step.want {
+			t.Errorf("replicas = %d, want %d", replicas, step.want)
+		}
+	}
+
+	_, err = client.CoreV1().ConfigMaps("bmw-saver").Get(ctx, WorkloadConfigMapNamePrefix+"team-a.db.statefulset", metav1.GetOptions{})
+	if !k8serrors.IsNotFound(err) {
+		t.Errorf("saved workload config error = %v, want deleted after restore", err)
+	}
+	if got, done, err := RestoreStatefulSet(ctx, client, "bmw-saver", getStatefulSet(t, client)); err != nil || got != -1 || !done {
+		t.Errorf("RestoreStatefulSet() = %d, %v, %v, want -1, true", got, done, err)
+	}
+}
+
+func getStatefulSet(t *testing.T, client *fake.Clientset) appsv1.StatefulSet {
+	t.Helper()
+	statefulSet, err := client.AppsV1().StatefulSets("team-a").Get(context.Background(), "db", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("failed to get statefulset: %v", err)
+	}
+	return *statefulSet
+}
@@ -0,0 +1,163 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// BlockScaleDownAnnotation on a pod defers scaling down its node pool while the pod runs
+const BlockScaleDownAnnotation = "bmw-saver.io/block-scale-down"
+
+// NodePoolLabels are the node labels naming the node pool, by cloud provider
+var NodePoolLabels = map[string]string{
+	"gke":   "cloud.google.com/gke-nodepool",
+	"aws":   "eks.amazonaws.com/nodegroup",
+	"azure": "kubernetes.azure.com/agentpool",
+}
+
+// WorkloadOptions contains the settings for finding active workloads
+type WorkloadOptions struct {
+	// RecentPodAge is how long after starting a pod counts as active, 0 disables the check
+	RecentPodAge time.Duration
+	// ExcludedNamespaces are never checked, in addition to kube-system
+	ExcludedNamespaces map[string]bool
+	// Now is the current time, used for the pod age
+	Now time.Time
+}
+
+// ActiveWorkloads returns the active workloads on the nodes of the node pool that would be disrupted by
+// scaling it down: pods of Jobs, pods with the block scale down annotation, and recently started pods.
+// Pods of DaemonSets and finished pods are ignored.
+func ActiveWorkloads(ctx context.Context, client kubernetes.Interface, cloudProvider, nodePoolName string, opts WorkloadOptions) ([]string, error) {
+	pods, err := nodePoolPods(ctx, client, cloudProvider, nodePoolName)
+	if err != nil {
+		return nil, err
+	}
+
+	var active []string
+	for _, pod := range pods {
+		if reason := activeReason(&pod, opts); reason != "" {
+			active = append(active, fmt.Sprintf("pod %s/%s %s", pod.Namespace, pod.Name, reason))
+		}
+	}
+	return active, nil
+}
+
+// GPUWorkloads returns the running pods on the nodes of the node pool requesting any of the GPU resources, e.g.
+// training runs. Pods of DaemonSets, e.g. GPU device plugins and exporters, and finished pods are ignored.
+func GPUWorkloads(ctx context.Context, client kubernetes.Interface, cloudProvider, nodePoolName string, resourceNames []string) ([]string, error) {
+	pods, err := nodePoolPods(ctx, client, cloudProvider, nodePoolName)
+	if err != nil {
+		return nil, err
+	}
+
+	var workloads []string
+	for _, pod := range pods {
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed || ownedByDaemonSet(&pod) {
+			continue
+		}
+		for _, name := range resourceNames {
+			if gpus := podRequest(&pod, corev1.ResourceName(name)); gpus > 0 {
+				workloads = append(workloads, fmt.Sprintf("pod %s/%s requests %d %s", pod.Namespace, pod.Name, gpus, name))
+				break
+			}
+		}
+	}
+	return workloads, nil
+}
+
+// podRequest returns how many of the resource the containers of the pod request, extended resources are
+// requested by their limits if requests are omitted
+func podRequest(pod *corev1.Pod, name corev1.ResourceName) int64 {
+	var total int64
+	for _, container := range pod.Spec.Containers {
+		quantity, ok := container.Resources.Requests[name]
+		if !ok {
+			quantity = container.Resources.Limits[name]
+		}
+		total += quantity.Value()
+	}
+	return total
+}
+
+// ownedByDaemonSet returns whether the pod belongs to a DaemonSet
+func ownedByDaemonSet(pod *corev1.Pod) bool {
+	for _, owner := range pod.OwnerReferences {
+		if owner.Kind == "DaemonSet" {
+			return true
+		}
+	}
+	return false
+}
+
+// NodePoolNodes returns the nodes of the node pool, by the node pool label of the cloud provider
+func NodePoolNodes(ctx context.Context, client kubernetes.Interface, cloudProvider, nodePoolName string) ([]corev1.Node, error) {
+	label, ok := NodePoolLabels[cloudProvider]
+	if !ok {
+		return nil, fmt.Errorf("unsupported cloud provider: %s", cloudProvider)
+	}
+
+	nodes, err := client.CoreV1().Nodes().List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s", label, nodePoolName),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %v", err)
+	}
+	return nodes.Items, nil
+}
+
+// nodePoolPods returns the pods on the nodes of the node pool
+func nodePoolPods(ctx context.Context, client kubernetes.Interface, cloudProvider, nodePoolName string) ([]corev1.Pod, error) {
+	nodes, err := NodePoolNodes(ctx, client, cloudProvider, nodePoolName)
+	if err != nil {
+		return nil, err
+	}
+
+	var pods []corev1.Pod
+	for _, node := range nodes {
+		nodePods, err := client.CoreV1().Pods("").List(ctx, metav1.ListOptions{
+			FieldSelector: fmt.Sprintf("spec.nodeName=%s", node.Name),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list pods on node %s: %v", node.Name, err)
+		}
+		pods = append(pods, nodePods.Items...)
+	}
+	return pods, nil
+}
+
+// activeReason returns why the pod is an active workload, empty if it isn't
+func activeReason(pod *corev1.Pod, opts WorkloadOptions) string {
+	if pod.Namespace == "kube-system" || opts.ExcludedNamespaces[pod.Namespace] {
+		return ""
+	}
+	if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+		return ""
+	}
+
+	for _, owner := range pod.OwnerReferences {
+		switch owner.Kind {
+		case "DaemonSet":
+			return ""
+		case "Job":
+			return fmt.Sprintf("runs Job %s", owner.Name)
+		}
+	}
+
+	if block, _ := strconv.ParseBool(pod.Annotations[BlockScaleDownAnnotation]); block {
+		return "blocks scale down"
+	}
+
+	if opts.RecentPodAge > 0 && pod.Status.StartTime != nil {
+		if age := opts.Now.Sub(pod.Status.StartTime.Time); age < opts.RecentPodAge {
+			return fmt.Sprintf("started %v ago", age.Round(time.Second))
+		}
+	}
+	return ""
+}
@@ -0,0 +1,138 @@
+package kubernetes
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestActiveWorkloads(t *testing.T) {
+	now := time.Date(2024, time.June, 3, 18, 1, 0, 0, time.UTC)
+	longAgo := metav1.NewTime(now.Add(-24 * time.Hour))
+	recently := metav1.NewTime(now.Add(-2 * time.Minute))
+
+	pod := func(namespace, name, node string, startTime metav1.Time, mutate func(*corev1.Pod)) *corev1.Pod {
+		p := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+			Spec:       corev1.PodSpec{NodeName: node},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning, StartTime: &startTime},
+		}
+		if mutate != nil {
+			mutate(p)
+		}
+		return p
+	}
+	ownedBy := func(kind, name string) func(*corev1.Pod) {
+		return func(p *corev1.Pod) {
+			p.OwnerReferences = []metav1.OwnerReference{{Kind: kind, Name: name}}
+		}
+	}
+
+	client := newFakeClientset(
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1", Labels: map[string]string{"cloud.google.com/gke-nodepool": "default-pool"}}},
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-2", Labels: map[string]string{"cloud.google.com/gke-nodepool": "other-pool"}}},
+		pod("default", "web", "node-1", longAgo, nil),
+		pod("default", "report-abc", "node-1", longAgo, ownedBy("Job", "report")),
+		pod("default", "done-abc", "node-1", longAgo, func(p *corev1.Pod) {
+			ownedBy("Job", "done")(p)
+			p.Status.Phase = corev1.PodSucceeded
+		}),
+		pod("default", "agent", "node-1", recently, ownedBy("DaemonSet", "agent")),
+		pod("default", "notebook", "node-1", longAgo, func(p *corev1.Pod) {
+			p.Annotations = map[string]string{BlockScaleDownAnnotation: "true"}
+		}),
+		pod("default", "deploy-xyz", "node-1", recently, nil),
+		pod("kube-system", "dns", "node-1", recently, nil),
+		pod("monitoring", "prometheus", "node-1", recently, nil),
+		pod("default", "batch-abc", "node-2", longAgo, ownedBy("Job", "batch")),
+	)
+
+	got, err := ActiveWorkloads(context.Background(), client, "gke", "default-pool", WorkloadOptions{
+		RecentPodAge:       10 * time.Minute,
+		ExcludedNamespaces: map[string]bool{"monitoring": true},
+		Now:                now,
+	})
+	if err != nil {
+		t.Fatalf("ActiveWorkloads() error = %v", err)
+	}
+
+	want := []string{
+		"pod default/deploy-xyz started 2m0s ago",
+		"pod default/notebook blocks scale down",
+		"pod default/report-abc runs Job report",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ActiveWorkloads() = %q, want %q", got, want)
+	}
+}
+
+func TestGPUWorkloads(t *testing.T) {
+	pod := func(name, node string, gpu corev1.ResourceName, limitsOnly bool, mutate func(*corev1.Pod)) *corev1.Pod {
+		resources := corev1.ResourceRequirements{Limits: corev1.ResourceList{gpu: resource.MustParse("2")}}
+		if !limitsOnly {
+			resources.Requests = resources.Limits
+		}
+		p := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ml", Name: name},
+			Spec:       corev1.PodSpec{NodeName: node, Containers: []corev1.Container{{Name: "main", Resources: resources}}},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+		}
+		if mutate != nil {
+			mutate(p)
+		}
+		return p
+	}
+
+	client := newFakeClientset(
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "gpu-1", Labels: map[string]string{"cloud.google.com/gke-nodepool": "gpu-pool"}}},
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "gpu-2", Labels: map[string]string{"cloud.google.com/gke-nodepool": "other-pool"}}},
+		pod("train", "gpu-1", "nvidia.com/gpu", false, nil),
+		pod("infer", "gpu-1", "amd.com/gpu", true, nil),
+		pod("web", "gpu-1", corev1.ResourceCPU, false, nil),
+		pod("done", "gpu-1", "nvidia.com/gpu", false, func(p *corev1.Pod) { p.Status.Phase = corev1.PodSucceeded }),
+		pod("exporter", "gpu-1", "nvidia.com/gpu", false, func(p *corev1.Pod) {
+			p.OwnerReferences = []metav1.OwnerReference{{Kind: "DaemonSet", Name: "exporter"}}
+		}),
+		pod("other", "gpu-2", "nvidia.com/gpu", false, nil),
+	)
+
+	got, err := GPUWorkloads(context.Background(), client, "gke", "gpu-pool", []string{"nvidia.com/gpu", "amd.com/gpu"})
+	if err != nil {
+		t.Fatalf("GPUWorkloads() error = %v", err)
+	}
+	want := []string{
+		"pod ml/infer requests 2 amd.com/gpu",
+		"pod ml/train requests 2 nvidia.com/gpu",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GPUWorkloads() = %q, want %q", got, want)
+	}
+}
+
+// newFakeClientset creates a fake clientset listing pods by node name, as the fake clientset doesn't support field selectors
+func newFakeClientset(objects ...runtime.Object) *fake.Clientset {
+	client := fake.NewSimpleClientset(objects...)
+	client.PrependReactor("list", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		node, _ := action.(k8stesting.ListAction).GetListRestrictions().Fields.RequiresExactMatch("spec.nodeName")
+		objects, err := client.Tracker().List(corev1.SchemeGroupVersion.WithResource("pods"), corev1.SchemeGroupVersion.WithKind("Pod"), "")
+		if err != nil {
+			return true, nil, err
+		}
+		list := &corev1.PodList{}
+		for _, p := range objects.(*corev1.PodList).Items {
+			if p.Spec.NodeName == node {
+				list.Items = append(list.Items, p)
+			}
+		}
+		return true, list, nil
+	})
+	return client
+}
@@ -0,0 +1,96 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// recordedRequest is a request received by the fake alerting API
+type recordedRequest struct {
+	path          string
+	authorization string
+	body          map[string]interface{}
+}
+
+func newAlertingServer(t *testing.T, requests *[]recordedRequest) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request := recordedRequest{path: r.URL.RequestURI(), authorization: r.Header.Get("Authorization")}
+		if err := json.NewDecoder(r.Body).Decode(&request.body); err != nil {
+			t.Errorf("Failed to decode body: %v", err)
+		}
+		*requests = append(*requests, request)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+}
+
+var (
+	scaledDown = Notification{Kind: KindScaledDown, NodePool: "default-pool"}
+	failure    = Notification{Kind: KindFailure, NodePool: "default-pool", Error: "quota exceeded", Failures: 4}
+	recovered  = Notification{Kind: KindRecovered, NodePool: "default-pool", Failures: 5}
+)
+
+func TestPagerDutySink_Notify(t *testing.T) {
+	var requests []recordedRequest
+	server := newAlertingServer(t, &requests)
+	defer server.Close()
+
+	sink, err := NewPagerDutySink("routing-key")
+	if err != nil {
+		t.Fatalf("Failed to create sink: %v", err)
+	}
+	sink.url = server.URL
+
+	for _, n := range []Notification{scaledDown, failure, recovered} {
+		if err := sink.Notify(context.Background(), n); err != nil {
+			t.Fatalf("Notify(%s) error = %v", n.Kind, err)
+		}
+	}
+
+	if len(requests) != 2 {
+		t.Fatalf("Got %d events, want trigger and resolve", len(requests))
+	}
+	for i, want := range []string{"trigger", "resolve"} {
+		body := requests[i].body
+		if body["event_action"] != want || body["dedup_key"] != "bmw-saver-default-pool" || body["routing_key"] != "routing-key" {
+			t.Errorf("Event %d = %v, want %s of bmw-saver-default-pool", i, body, want)
+		}
+	}
+	if payload, ok := requests[0].body["payload"].(map[string]interface{}); !ok || payload["summary"] != failure.Text() {
+		t.Errorf("Trigger payload = %v, want summary %q", requests[0].body["payload"], failure.Text())
+	}
+}
+
+func TestOpsgenieSink_Notify(t *testing.T) {
+	var requests []recordedRequest
+	server := newAlertingServer(t, &requests)
+	defer server.Close()
+
+	sink, err := NewOpsgenieSink("api-key", server.URL+"/")
+	if err != nil {
+		t.Fatalf("Failed to create sink: %v", err)
+	}
+
+	for _, n := range []Notification{scaledDown, failure, recovered} {
+		if err := sink.Notify(context.Background(), n); err != nil {
+			t.Fatalf("Notify(%s) error = %v", n.Kind, err)
+		}
+	}
+
+	if len(requests) != 2 {
+		t.Fatalf("Got %d requests, want create and close", len(requests))
+	}
+	for i, want := range []string{"/v2/alerts", "/v2/alerts/bmw-saver-default-pool/close?identifierType=alias"} {
+		if requests[i].path != want {
+			t.Errorf("Request %d path = %s, want %s", i, requests[i].path, want)
+		}
+		if requests[i].authorization != "GenieKey api-key" {
+			t.Errorf("Request %d authorization = %q, want GenieKey", i, requests[i].authorization)
+		}
+	}
+	if alias := requests[0].body["alias"]; alias != "bmw-saver-default-pool" {
+		t.Errorf("Alert alias = %v, want bmw-saver-default-pool", alias)
+	}
+}
@@ -0,0 +1,51 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// discordMaxContentLength is the longest message content Discord accepts
+const discordMaxContentLength = 2000
+
+// DiscordSink posts notifications to a Discord webhook
+type DiscordSink struct {
+	webhookURL string
+	client     *http.Client
+}
+
+// discordMessage is the payload of a Discord webhook
+type discordMessage struct {
+	Content string `json:"content"`
+}
+
+// NewDiscordSink creates a new Discord sink posting to the webhook URL
+func NewDiscordSink(webhookURL string) (*DiscordSink, error) {
+	if webhookURL == "" {
+		return nil, fmt.Errorf("discord webhook URL is required")
+	}
+	return &DiscordSink{
+		webhookURL: webhookURL,
+		client: &http.Client{
+			Timeout: defaultTimeout,
+		},
+	}, nil
+}
+
+// Notify posts the notification as a Discord message, truncated to the content length limit
+func (s *DiscordSink) Notify(ctx context.Context, n Notification) error {
+	content := n.Text()
+	if n.Kind == KindFailure {
+		content = ":warning: " + content
+	}
+	if runes := []rune(content); len(runes) > discordMaxContentLength {
+		content = string(runes[:discordMaxContentLength-1]) + "…"
+	}
+	return postJSON(ctx, s.client, s.webhookURL, discordMessage{Content: content})
+}
+
+// String returns a string representation of the DiscordSink without the secret webhook URL
+func (s *DiscordSink) String() string {
+	return "DiscordSink"
+}
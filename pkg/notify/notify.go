@@ -0,0 +1,171 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// defaultTimeout is how long sinks wait for a webhook to respond
+const defaultTimeout = 10 * time.Second
+
+// Kind is what happened to a node pool
+type Kind string
+
+const (
+	// KindScaledDown is sent when a node pool was scaled down for off time
+	KindScaledDown Kind = "scaled_down"
+	// KindRestored is sent when a node pool was restored for work time
+	KindRestored Kind = "restored"
+	// KindFailure is sent when scaling a node pool failed in several reconciliations in a row
+	KindFailure Kind = "failure"
+	// KindRecovered is sent when scaling a node pool succeeded again after a failure was sent
+	KindRecovered Kind = "recovered"
+	// KindSuspended is sent when scaling a node pool is suspended by the circuit breaker after failing too often
+	KindSuspended Kind = "suspended"
+	// KindDrift is sent when a scaled down node pool was resized outside of bmw-saver, with the alert-only drift policy
+	KindDrift Kind = "drift"
+	// KindNotReady is sent when a restored node pool didn't become ready within the restore verification timeout
+	KindNotReady Kind = "not_ready"
+	// KindApprovalPending is sent when the scale down of a node pool requiring approval waits for it
+	KindApprovalPending Kind = "approval_pending"
+	// KindRecommendation is sent when right-sizing recommends another size for a node pool
+	KindRecommendation Kind = "recommendation"
+	// KindDeferred is sent when the scale down of a node pool is deferred for GPU workloads running on it
+	KindDeferred Kind = "deferred"
+)
+
+// Notification describes a scaling action or a persistent failure on a node pool
+type Notification struct {
+	Kind Kind `json:"kind"`
+	// NodePool is the name of the node pool
+	NodePool string `json:"nodePool"`
+	// Schedule is the name of the schedule of the node pool, "default" for the top-level schedule
+	Schedule string `json:"schedule"`
+	// Count is the node count the node pool was scaled to, only set for scale downs and their failures,
+	// or the node count it was resized to for drifts
+	Count int32 `json:"count,omitempty"`
+	// Tier is the capacity tier the node pool was scaled for, if any
+	Tier string `json:"tier,omitempty"`
+	// Reason is why the schedule decided, e.g. "off time: outside Tuesday work hours 09:00-18:00"
+	Reason string `json:"reason"`
+	// Error is the last error of a failure
+	Error string `json:"error,omitempty"`
+	// Failures is how many reconciliations in a row failed, before recovering for recoveries
+	Failures int `json:"failures,omitempty"`
+	// SuspendedUntil is when a suspended node pool is tried again
+	SuspendedUntil *time.Time `json:"suspendedUntil,omitempty"`
+	// Workloads are the workloads a scale down is deferred for, and DeferredUntil when it's scaled down anyway
+	Workloads     []string   `json:"workloads,omitempty"`
+	DeferredUntil *time.Time `json:"deferredUntil,omitempty"`
+}
+
+// Text returns a human readable summary of the notification
+func (n Notification) Text() string {
+	switch n.Kind {
+	case KindScaledDown:
+		text := fmt.Sprintf("Scaled node pool %s down to %d nodes, %s", n.NodePool, n.Count, n.Reason)
+		if n.Tier != "" {
+			text += fmt.Sprintf(" (tier %s)", n.Tier)
+		}
+		return text
+	case KindRestored:
+		return fmt.Sprintf("Restored node pool %s, %s", n.NodePool, n.Reason)
+	case KindFailure:
+		return fmt.Sprintf("Scaling node pool %s failed %d times in a row, %s: %s", n.NodePool, n.Failures, n.Reason, n.Error)
+	case KindRecovered:
+		return fmt.Sprintf("Scaling node pool %s recovered after %d failures, %s", n.NodePool, n.Failures, n.Reason)
+	case KindSuspended:
+		return fmt.Sprintf("Scaling node pool %s failed %d times in a row, suspended until %s: %s",
+			n.NodePool, n.Failures, n.SuspendedUntil.Format(time.RFC3339), n.Error)
+	case KindDrift:
+		return fmt.Sprintf("Node pool %s was resized to %d nodes outside of bmw-saver, leaving it until the next transition", n.NodePool, n.Count)
+	case KindApprovalPending:
+		return fmt.Sprintf("Scale down of node pool %s to %d nodes is waiting for approval, %s", n.NodePool, n.Count, n.Reason)
+	case KindNotReady:
+		return fmt.Sprintf("Restored node pool %s isn't ready, %s", n.NodePool, n.Error)
+	case KindRecommendation:
+		return fmt.Sprintf("Right-sizing node pool %s: %s", n.NodePool, n.Reason)
+	case KindDeferred:
+		return fmt.Sprintf("Scale down of node pool %s deferred until %s for GPU workloads: %s",
+			n.NodePool, n.DeferredUntil.Format(time.RFC3339), strings.Join(n.Workloads, ", "))
+	default:
+		return fmt.Sprintf("Node pool %s: %s", n.NodePool, n.Kind)
+	}
+}
+
+// Sink delivers notifications, e.g. to a chat channel
+type Sink interface {
+	// Notify delivers the notification
+	Notify(ctx context.Context, n Notification) error
+}
+
+// Notifier sends notifications to all of its sinks
+type Notifier struct {
+	sinks []Sink
+}
+
+// NewNotifier creates a new notifier sending to the given sinks
+func NewNotifier(sinks ...Sink) *Notifier {
+	return &Notifier{sinks: sinks}
+}
+
+// Notify sends the notification to all sinks, failures are logged and don't stop the other sinks
+func (n *Notifier) Notify(ctx context.Context, notification Notification) {
+	if n == nil {
+		return
+	}
+	for _, sink := range n.sinks {
+		if err := sink.Notify(ctx, notification); err != nil {
+			slog.Error("Failed to send notification",
+				"sink", fmt.Sprint(sink),
+				"node_pool", notification.NodePool,
+				"kind", notification.Kind,
+				"error", err,
+			)
+		}
+	}
+}
+
+// postJSON posts the payload as JSON and fails on any non-2xx response
+func postJSON(ctx context.Context, client *http.Client, url string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %v", err)
+	}
+	return post(ctx, client, url, body, nil)
+}
+
+// post posts the JSON body with the additional header and fails on any non-2xx response
+func post(ctx context.Context, client *http.Client, url string, body []byte, header http.Header) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post notification: %v", err)
+	}
+	defer func() {
+		if e := resp.Body.Close(); e != nil {
+			slog.Error("Failed to close response body", "error", e)
+		}
+	}()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, respBody)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// defaultOpsgenieURL is the Opsgenie API in the US region, "https://api.eu.opsgenie.com" in the EU
+const defaultOpsgenieURL = "https://api.opsgenie.com"
+
+// OpsgenieSink creates an Opsgenie alert for persistent failures and closes it on recovery,
+// scaling actions are ignored
+type OpsgenieSink struct {
+	apiKey string
+	url    string
+	client *http.Client
+}
+
+// opsgenieAlert is a request of the Opsgenie Alert API
+type opsgenieAlert struct {
+	Message     string            `json:"message"`
+	Alias       string            `json:"alias"`
+	Description string            `json:"description,omitempty"`
+	Priority    string            `json:"priority,omitempty"`
+	Source      string            `json:"source"`
+	Details     map[string]string `json:"details,omitempty"`
+}
+
+// opsgenieClose is a request closing an Opsgenie alert
+type opsgenieClose struct {
+	Source string `json:"source"`
+	Note   string `json:"note,omitempty"`
+}
+
+// NewOpsgenieSink creates a new Opsgenie sink with the API key of an API integration,
+// apiURL defaults to the US region
+func NewOpsgenieSink(apiKey, apiURL string) (*OpsgenieSink, error) {
+	if apiKey == "" {
+		return nil, fmt.Errorf("opsgenie API key is required")
+	}
+	if apiURL == "" {
+		apiURL = defaultOpsgenieURL
+	}
+	return &OpsgenieSink{
+		apiKey: apiKey,
+		url:    strings.TrimSuffix(apiURL, "/"),
+		client: &http.Client{
+			Timeout: defaultTimeout,
+		},
+	}, nil
+}
+
+// Notify creates an alert per node pool on failures and closes it on recovery
+func (s *OpsgenieSink) Notify(ctx context.Context, n Notification) error {
+	switch n.Kind {
+	case KindFailure:
+		return s.post(ctx, "/v2/alerts", opsgenieAlert{
+			Message:     fmt.Sprintf("Scaling node pool %s keeps failing", n.NodePool),
+			Alias:       alertKey(n),
+			Description: n.Text(),
+			Priority:    "P2",
+			Source:      "bmw-saver",
+			Details: map[string]string{
+				"nodePool": n.NodePool,
+				"schedule": n.Schedule,
+				"reason":   n.Reason,
+				"error":    n.Error,
+			},
+		})
+	case KindRecovered:
+		path := fmt.Sprintf("/v2/alerts/%s/close?identifierType=alias", url.PathEscape(alertKey(n)))
+		return s.post(ctx, path, opsgenieClose{Source: "bmw-saver", Note: n.Text()})
+	default:
+		return nil
+	}
+}
+
+// post posts the request to the Opsgenie API, which accepts requests asynchronously with 202
+func (s *OpsgenieSink) post(ctx context.Context, path string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %v", err)
+	}
+	header := http.Header{}
+	header.Set("Authorization", "GenieKey "+s.apiKey)
+	return post(ctx, s.client, s.url+path, body, header)
+}
+
+// String returns a string representation of the OpsgenieSink without the secret API key
+func (s *OpsgenieSink) String() string {
+	return "OpsgenieSink"
+}
@@ -0,0 +1,86 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// pagerDutyEventsURL is the PagerDuty Events API v2 endpoint
+const pagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+
+// PagerDutySink triggers a PagerDuty incident for persistent failures and resolves it on recovery,
+// scaling actions are ignored
+type PagerDutySink struct {
+	routingKey string
+	url        string
+	client     *http.Client
+}
+
+// pagerDutyEvent is an event of the PagerDuty Events API v2
+type pagerDutyEvent struct {
+	RoutingKey  string            `json:"routing_key"`
+	EventAction string            `json:"event_action"`
+	DedupKey    string            `json:"dedup_key"`
+	Payload     *pagerDutyPayload `json:"payload,omitempty"`
+}
+
+type pagerDutyPayload struct {
+	Summary       string            `json:"summary"`
+	Source        string            `json:"source"`
+	Severity      string            `json:"severity"`
+	Component     string            `json:"component"`
+	CustomDetails map[string]string `json:"custom_details,omitempty"`
+}
+
+// NewPagerDutySink creates a new PagerDuty sink sending events to the integration with the routing key
+func NewPagerDutySink(routingKey string) (*PagerDutySink, error) {
+	if routingKey == "" {
+		return nil, fmt.Errorf("pagerduty routing key is required")
+	}
+	return &PagerDutySink{
+		routingKey: routingKey,
+		url:        pagerDutyEventsURL,
+		client: &http.Client{
+			Timeout: defaultTimeout,
+		},
+	}, nil
+}
+
+// Notify triggers an incident per node pool on failures and resolves it on recovery
+func (s *PagerDutySink) Notify(ctx context.Context, n Notification) error {
+	event := pagerDutyEvent{
+		RoutingKey: s.routingKey,
+		DedupKey:   alertKey(n),
+	}
+	switch n.Kind {
+	case KindFailure:
+		event.EventAction = "trigger"
+		event.Payload = &pagerDutyPayload{
+			Summary:   n.Text(),
+			Source:    "bmw-saver",
+			Severity:  "error",
+			Component: n.NodePool,
+			CustomDetails: map[string]string{
+				"schedule": n.Schedule,
+				"reason":   n.Reason,
+				"error":    n.Error,
+			},
+		}
+	case KindRecovered:
+		event.EventAction = "resolve"
+	default:
+		return nil
+	}
+	return postJSON(ctx, s.client, s.url, event)
+}
+
+// String returns a string representation of the PagerDutySink without the secret routing key
+func (s *PagerDutySink) String() string {
+	return "PagerDutySink"
+}
+
+// alertKey identifies the alert of a node pool, so that repeated failures don't open several incidents
+func alertKey(n Notification) string {
+	return "bmw-saver-" + n.NodePool
+}
@@ -0,0 +1,55 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// SlackSink posts notifications to a Slack incoming webhook
+type SlackSink struct {
+	webhookURL string
+	// interactive adds Approve and Snooze buttons to pending approvals
+	interactive bool
+	client      *http.Client
+}
+
+// slackMessage is the payload of a Slack incoming webhook
+type slackMessage struct {
+	Text   string       `json:"text"`
+	Blocks []slackBlock `json:"blocks,omitempty"`
+}
+
+// NewSlackSink creates a new Slack sink posting to the incoming webhook URL. If interactive, pending approvals
+// have Approve and Snooze buttons, the webhook has to belong to a Slack app with interactivity enabled.
+func NewSlackSink(webhookURL string, interactive bool) (*SlackSink, error) {
+	if webhookURL == "" {
+		return nil, fmt.Errorf("slack webhook URL is required")
+	}
+	return &SlackSink{
+		webhookURL:  webhookURL,
+		interactive: interactive,
+		client: &http.Client{
+			Timeout: defaultTimeout,
+		},
+	}, nil
+}
+
+// Notify posts the notification as a Slack message
+func (s *SlackSink) Notify(ctx context.Context, n Notification) error {
+	text := n.Text()
+	if n.Kind == KindFailure {
+		text = ":warning: " + text
+	}
+	message := slackMessage{Text: text}
+	if n.Kind == KindApprovalPending && s.interactive {
+		message.Blocks = approvalBlocks(text, n.NodePool, time.Now())
+	}
+	return postJSON(ctx, s.client, s.webhookURL, message)
+}
+
+// String returns a string representation of the SlackSink without the secret webhook URL
+func (s *SlackSink) String() string {
+	return "SlackSink"
+}
@@ -0,0 +1,146 @@
+package notify
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// SlackActionApprove approves the pending scale down of a node pool
+	SlackActionApprove = "approve"
+	// SlackActionSnooze postpones the pending scale down of a node pool
+	SlackActionSnooze = "snooze"
+
+	// slackRequestMaxAge is how old a signed Slack request may be, against replay attacks
+	slackRequestMaxAge = 5 * time.Minute
+)
+
+// slackBlock is a Block Kit block of a Slack message
+type slackBlock struct {
+	Type     string         `json:"type"`
+	BlockID  string         `json:"block_id,omitempty"`
+	Text     *slackText     `json:"text,omitempty"`
+	Elements []slackElement `json:"elements,omitempty"`
+}
+
+// slackText is a text object of a Slack message
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// slackElement is a button of a Slack message
+type slackElement struct {
+	Type     string    `json:"type"`
+	Text     slackText `json:"text"`
+	ActionID string    `json:"action_id"`
+	Value    string    `json:"value"`
+	Style    string    `json:"style,omitempty"`
+}
+
+// approvalBlocks returns the blocks of a pending approval message, with Approve and Snooze buttons.
+// The button values identify the node pool and when the approval was asked for.
+func approvalBlocks(text, nodePool string, now time.Time) []slackBlock {
+	value := fmt.Sprintf("%s|%d", nodePool, now.Unix())
+	return []slackBlock{
+		{Type: "section", Text: &slackText{Type: "mrkdwn", Text: text}},
+		{Type: "actions", BlockID: "approval", Elements: []slackElement{
+			{Type: "button", Text: slackText{Type: "plain_text", Text: "Approve"}, ActionID: SlackActionApprove, Value: value, Style: "primary"},
+			{Type: "button", Text: slackText{Type: "plain_text", Text: "Snooze"}, ActionID: SlackActionSnooze, Value: value},
+		}},
+	}
+}
+
+// SlackInteraction is a click on a button of a pending approval message
+type SlackInteraction struct {
+	// Action is SlackActionApprove or SlackActionSnooze
+	Action string
+	// NodePool is the node pool whose scale down is approved or snoozed
+	NodePool string
+	// AskedAt is when the approval was asked for
+	AskedAt time.Time
+	// User is the name of the Slack user who clicked
+	User string
+	// ResponseURL updates the message, e.g. to replace the buttons with who approved
+	ResponseURL string
+}
+
+// slackInteractionPayload is the payload of a Slack block action
+type slackInteractionPayload struct {
+	Type string `json:"type"`
+	User struct {
+		Name string `json:"name"`
+	} `json:"user"`
+	ResponseURL string `json:"response_url"`
+	Actions     []struct {
+		ActionID string `json:"action_id"`
+		Value    string `json:"value"`
+	} `json:"actions"`
+}
+
+// VerifySlackRequest verifies the signature of a request from Slack with the signing secret of the Slack app
+func VerifySlackRequest(signingSecret string, header http.Header, body []byte, now time.Time) error {
+	timestamp := header.Get("X-Slack-Request-Timestamp")
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid request timestamp: %q", timestamp)
+	}
+	if age := now.Sub(time.Unix(seconds, 0)); age > slackRequestMaxAge || age < -slackRequestMaxAge {
+		return fmt.Errorf("request timestamp too old: %s", timestamp)
+	}
+
+	mac := hmac.New(sha256.New, []byte(signingSecret))
+	fmt.Fprintf(mac, "v0:%s:%s", timestamp, body)
+	want := "v0=" + hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(header.Get("X-Slack-Signature")), []byte(want)) {
+		return fmt.Errorf("invalid request signature")
+	}
+	return nil
+}
+
+// ParseSlackInteraction parses the form encoded body of a Slack interaction request
+func ParseSlackInteraction(body []byte) (SlackInteraction, error) {
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		return SlackInteraction{}, fmt.Errorf("failed to parse interaction: %v", err)
+	}
+	var payload slackInteractionPayload
+	if err := json.Unmarshal([]byte(form.Get("payload")), &payload); err != nil {
+		return SlackInteraction{}, fmt.Errorf("failed to parse interaction payload: %v", err)
+	}
+	if payload.Type != "block_actions" || len(payload.Actions) == 0 {
+		return SlackInteraction{}, fmt.Errorf("unsupported interaction: %s", payload.Type)
+	}
+
+	action := payload.Actions[0]
+	nodePool, askedAt, ok := strings.Cut(action.Value, "|")
+	seconds, err := strconv.ParseInt(askedAt, 10, 64)
+	if !ok || err != nil {
+		return SlackInteraction{}, fmt.Errorf("invalid action value: %q", action.Value)
+	}
+	return SlackInteraction{
+		Action:      action.ActionID,
+		NodePool:    nodePool,
+		AskedAt:     time.Unix(seconds, 0),
+		User:        payload.User.Name,
+		ResponseURL: payload.ResponseURL,
+	}, nil
+}
+
+// RespondSlack replaces the message of the interaction with the text, removing its buttons
+func RespondSlack(ctx context.Context, responseURL, text string) error {
+	client := &http.Client{Timeout: defaultTimeout}
+	return postJSON(ctx, client, responseURL, map[string]interface{}{
+		"replace_original": true,
+		"text":             text,
+	})
+}
@@ -0,0 +1,100 @@
+package notify
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestSlackSink_NotifyInteractive(t *testing.T) {
+	var got slackMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("Failed to decode message: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	sink, err := NewSlackSink(server.URL, true)
+	if err != nil {
+		t.Fatalf("Failed to create sink: %v", err)
+	}
+	if err := sink.Notify(context.Background(), Notification{Kind: KindApprovalPending, NodePool: "default-pool"}); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+	if len(got.Blocks) != 2 || len(got.Blocks[1].Elements) != 2 {
+		t.Fatalf("Notify() blocks = %+v, want a section and Approve and Snooze buttons", got.Blocks)
+	}
+	for i, action := range []string{SlackActionApprove, SlackActionSnooze} {
+		button := got.Blocks[1].Elements[i]
+		if button.ActionID != action {
+			t.Errorf("button %d action = %q, want %q", i, button.ActionID, action)
+		}
+		interaction, err := ParseSlackInteraction([]byte(interactionBody(action, button.Value)))
+		if err != nil {
+			t.Fatalf("ParseSlackInteraction() error = %v", err)
+		}
+		if interaction.Action != action || interaction.NodePool != "default-pool" || interaction.User != "alice" {
+			t.Errorf("ParseSlackInteraction() = %+v", interaction)
+		}
+	}
+
+	got = slackMessage{}
+	if err := sink.Notify(context.Background(), Notification{Kind: KindRestored, NodePool: "default-pool"}); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+	if len(got.Blocks) != 0 {
+		t.Errorf("Notify() blocks = %+v, want none for other kinds", got.Blocks)
+	}
+}
+
+func TestVerifySlackRequest(t *testing.T) {
+	now := time.Unix(1717524000, 0)
+	body := []byte(interactionBody(SlackActionApprove, "default-pool|1717524000"))
+	sign := func(secret string, timestamp int64) http.Header {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte("v0:" + strconv.FormatInt(timestamp, 10) + ":" + string(body)))
+		header := http.Header{}
+		header.Set("X-Slack-Request-Timestamp", strconv.FormatInt(timestamp, 10))
+		header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(mac.Sum(nil)))
+		return header
+	}
+
+	tests := []struct {
+		name    string
+		header  http.Header
+		wantErr bool
+	}{
+		{name: "Valid", header: sign("secret", now.Unix())},
+		{name: "Wrong Secret", header: sign("other", now.Unix()), wantErr: true},
+		{name: "Too Old", header: sign("secret", now.Add(-10*time.Minute).Unix()), wantErr: true},
+		{name: "Unsigned", header: http.Header{}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifySlackRequest("secret", tt.header, body, now)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("VerifySlackRequest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// interactionBody returns the form encoded body of a click on the button with the action and value
+func interactionBody(action, value string) string {
+	payload, _ := json.Marshal(map[string]interface{}{
+		"type":         "block_actions",
+		"user":         map[string]string{"name": "alice"},
+		"response_url": "https://hooks.slack.com/actions/response",
+		"actions":      []map[string]string{{"action_id": action, "value": value}},
+	})
+	return url.Values{"payload": {string(payload)}}.Encode()
+}
@@ -0,0 +1,90 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSlackSink_Notify(t *testing.T) {
+	tests := []struct {
+		name         string
+		notification Notification
+		status       int
+		wantText     string
+		wantErr      bool
+	}{
+		{
+			name: "Scaled Down",
+			notification: Notification{
+				Kind:     KindScaledDown,
+				NodePool: "default-pool",
+				Schedule: "default",
+				Count:    1,
+				Tier:     "evening",
+				Reason:   "off time: outside Tuesday work hours 09:00-18:00",
+			},
+			status:   http.StatusOK,
+			wantText: "Scaled node pool default-pool down to 1 nodes, off time: outside Tuesday work hours 09:00-18:00 (tier evening)",
+		},
+		{
+			name: "Restored",
+			notification: Notification{
+				Kind:     KindRestored,
+				NodePool: "default-pool",
+				Reason:   "work time: within Tuesday work hours 09:00-18:00",
+			},
+			status:   http.StatusOK,
+			wantText: "Restored node pool default-pool, work time: within Tuesday work hours 09:00-18:00",
+		},
+		{
+			name: "Failure",
+			notification: Notification{
+				Kind:     KindFailure,
+				NodePool: "default-pool",
+				Reason:   "off time: Saturday is not a work day",
+				Error:    "quota exceeded",
+				Failures: 4,
+			},
+			status:   http.StatusOK,
+			wantText: ":warning: Scaling node pool default-pool failed 4 times in a row, off time: Saturday is not a work day: quota exceeded",
+		},
+		{
+			name:         "Webhook Error",
+			notification: Notification{Kind: KindRestored, NodePool: "default-pool"},
+			status:       http.StatusForbidden,
+			wantErr:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got slackMessage
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+					t.Errorf("Content-Type = %q, want application/json", ct)
+				}
+				if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+					t.Errorf("Failed to decode message: %v", err)
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			sink, err := NewSlackSink(server.URL, false)
+			if err != nil {
+				t.Fatalf("Failed to create sink: %v", err)
+			}
+
+			err = sink.Notify(context.Background(), tt.notification)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Notify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got.Text != tt.wantText {
+				t.Errorf("Notify() text = %q, want %q", got.Text, tt.wantText)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// TeamsSink posts notifications as Adaptive Cards to a Microsoft Teams Workflows webhook
+type TeamsSink struct {
+	webhookURL string
+	client     *http.Client
+}
+
+// teamsMessage is the payload of a Teams Workflows webhook, a message with an Adaptive Card attachment
+type teamsMessage struct {
+	Type        string            `json:"type"`
+	Attachments []teamsAttachment `json:"attachments"`
+}
+
+type teamsAttachment struct {
+	ContentType string    `json:"contentType"`
+	Content     teamsCard `json:"content"`
+}
+
+type teamsCard struct {
+	Schema  string           `json:"$schema"`
+	Type    string           `json:"type"`
+	Version string           `json:"version"`
+	Body    []teamsTextBlock `json:"body"`
+}
+
+type teamsTextBlock struct {
+	Type   string `json:"type"`
+	Text   string `json:"text"`
+	Wrap   bool   `json:"wrap"`
+	Color  string `json:"color,omitempty"`
+	Weight string `json:"weight,omitempty"`
+}
+
+// NewTeamsSink creates a new Teams sink posting to the Workflows webhook URL
+func NewTeamsSink(webhookURL string) (*TeamsSink, error) {
+	if webhookURL == "" {
+		return nil, fmt.Errorf("teams webhook URL is required")
+	}
+	return &TeamsSink{
+		webhookURL: webhookURL,
+		client: &http.Client{
+			Timeout: defaultTimeout,
+		},
+	}, nil
+}
+
+// Notify posts the notification as an Adaptive Card, failures are highlighted
+func (s *TeamsSink) Notify(ctx context.Context, n Notification) error {
+	title := teamsTextBlock{Type: "TextBlock", Text: "BMW-Saver", Weight: "Bolder", Wrap: true}
+	if n.Kind == KindFailure {
+		title.Color = "Attention"
+	}
+
+	return postJSON(ctx, s.client, s.webhookURL, teamsMessage{
+		Type: "message",
+		Attachments: []teamsAttachment{{
+			ContentType: "application/vnd.microsoft.card.adaptive",
+			Content: teamsCard{
+				Schema:  "http://adaptivecards.io/schemas/adaptive-card.json",
+				Type:    "AdaptiveCard",
+				Version: "1.4",
+				Body: []teamsTextBlock{
+					title,
+					{Type: "TextBlock", Text: n.Text(), Wrap: true},
+				},
+			},
+		}},
+	})
+}
+
+// String returns a string representation of the TeamsSink without the secret webhook URL
+func (s *TeamsSink) String() string {
+	return "TeamsSink"
+}
@@ -0,0 +1,78 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"text/template"
+)
+
+// WebhookSink posts notifications to any HTTP endpoint, as the notification JSON or a templated body
+type WebhookSink struct {
+	url      string
+	template *template.Template
+	client   *http.Client
+}
+
+// webhookData is what the body template is executed with
+type webhookData struct {
+	Notification
+	// Text is the human readable summary of the notification
+	Text string
+}
+
+// NewWebhookSink creates a new webhook sink posting to the URL. The body is the notification as JSON,
+// or the result of the Go template if set, executed with the notification fields and .Text.
+// The json function quotes a value for use in the template, e.g. {"message": {{ json .Text }}}.
+func NewWebhookSink(url, body string) (*WebhookSink, error) {
+	if url == "" {
+		return nil, fmt.Errorf("webhook URL is required")
+	}
+
+	sink := &WebhookSink{
+		url: url,
+		client: &http.Client{
+			Timeout: defaultTimeout,
+		},
+	}
+	if body != "" {
+		tmpl, err := template.New("webhook").Funcs(template.FuncMap{"json": toJSON}).Parse(body)
+		if err != nil {
+			return nil, fmt.Errorf("invalid webhook template: %v", err)
+		}
+		sink.template = tmpl
+	}
+	return sink, nil
+}
+
+// Notify posts the notification to the webhook
+func (s *WebhookSink) Notify(ctx context.Context, n Notification) error {
+	if s.template == nil {
+		return postJSON(ctx, s.client, s.url, n)
+	}
+
+	var body bytes.Buffer
+	if err := s.template.Execute(&body, webhookData{Notification: n, Text: n.Text()}); err != nil {
+		return fmt.Errorf("failed to execute webhook template: %v", err)
+	}
+	if !json.Valid(body.Bytes()) {
+		return fmt.Errorf("webhook template produced invalid JSON: %s", body.String())
+	}
+	return post(ctx, s.client, s.url, body.Bytes(), nil)
+}
+
+// String returns a string representation of the WebhookSink without the possibly secret URL
+func (s *WebhookSink) String() string {
+	return "WebhookSink"
+}
+
+// toJSON returns the value as JSON, e.g. a quoted and escaped string
+func toJSON(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
@@ -0,0 +1,111 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSinks_Notify(t *testing.T) {
+	notification := Notification{
+		Kind:     KindScaledDown,
+		NodePool: "default-pool",
+		Schedule: "default",
+		Count:    0,
+		Reason:   `off time: ICS event "Holiday"`,
+	}
+	text := `Scaled node pool default-pool down to 0 nodes, off time: ICS event "Holiday"`
+
+	tests := []struct {
+		name    string
+		newSink func(url string) (Sink, error)
+		want    string
+	}{
+		{
+			name:    "Webhook",
+			newSink: func(url string) (Sink, error) { return NewWebhookSink(url, "") },
+			want:    `{"kind":"scaled_down","nodePool":"default-pool","schedule":"default","reason":"off time: ICS event \"Holiday\""}`,
+		},
+		{
+			name: "Webhook Template",
+			newSink: func(url string) (Sink, error) {
+				return NewWebhookSink(url, `{"pool": "{{ .NodePool }}", "count": {{ .Count }}, "message": {{ json .Text }}}`)
+			},
+			want: `{"pool":"default-pool","count":0,"message":` + mustJSON(t, text) + `}`,
+		},
+		{
+			name:    "Discord",
+			newSink: func(url string) (Sink, error) { return NewDiscordSink(url) },
+			want:    `{"content":` + mustJSON(t, text) + `}`,
+		},
+		{
+			name:    "Teams",
+			newSink: func(url string) (Sink, error) { return NewTeamsSink(url) },
+			want: `{"type":"message","attachments":[{"contentType":"application/vnd.microsoft.card.adaptive","content":{` +
+				`"$schema":"http://adaptivecards.io/schemas/adaptive-card.json","type":"AdaptiveCard","version":"1.4","body":[` +
+				`{"type":"TextBlock","text":"BMW-Saver","wrap":true,"weight":"Bolder"},` +
+				`{"type":"TextBlock","text":` + mustJSON(t, text) + `,"wrap":true}]}}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, err := io.ReadAll(r.Body)
+				if err != nil {
+					t.Errorf("Failed to read body: %v", err)
+				}
+				got = string(body)
+			}))
+			defer server.Close()
+
+			sink, err := tt.newSink(server.URL)
+			if err != nil {
+				t.Fatalf("Failed to create sink: %v", err)
+			}
+			if err := sink.Notify(context.Background(), notification); err != nil {
+				t.Fatalf("Notify() error = %v", err)
+			}
+			if compact(t, got) != compact(t, tt.want) {
+				t.Errorf("Notify() body = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWebhookSink_InvalidTemplate(t *testing.T) {
+	if _, err := NewWebhookSink("http://localhost", `{"pool": {{ .NodePool }`); err == nil {
+		t.Error("NewWebhookSink() error = nil, want error for an unparsable template")
+	}
+
+	sink, err := NewWebhookSink("http://localhost", `{"pool": {{ .NodePool }}}`)
+	if err != nil {
+		t.Fatalf("Failed to create sink: %v", err)
+	}
+	err = sink.Notify(context.Background(), Notification{NodePool: "default-pool"})
+	if err == nil || !strings.Contains(err.Error(), "invalid JSON") {
+		t.Errorf("Notify() error = %v, want invalid JSON error", err)
+	}
+}
+
+func mustJSON(t *testing.T, v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("Failed to marshal: %v", err)
+	}
+	return string(data)
+}
+
+// compact removes insignificant whitespace from JSON so that bodies can be compared
+func compact(t *testing.T, s string) string {
+	var v interface{}
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		t.Fatalf("Invalid JSON %s: %v", s, err)
+	}
+	return mustJSON(t, v)
+}
@@ -6,85 +6,51 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
-	"sync"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/autoscaling"
 	"github.com/aws/aws-sdk-go-v2/service/eks"
 	"github.com/aws/aws-sdk-go-v2/service/eks/types"
 	corev1 "k8s.io/api/core/v1"
-	k8serrors "k8s.io/apimachinery/pkg/api/errors"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/rest"
 
 	pkgk8s "github.com/kezhenxu94/bmw-saver/pkg/kubernetes"
 )
 
 // AWSProvider implements the CloudProvider interface for AWS EKS
 type AWSProvider struct {
-	awsConfig   aws.Config
 	clusterName string
-	kubeConfig  *rest.Config
-	eksClients  map[string]*eks.Client // region -> client
-	clientMu    sync.RWMutex
+	client      kubernetes.Interface
+	state       *pkgk8s.StateStore
+	nodes       *pkgk8s.NodeLister
+	// eks and autoscaling call the APIs in the region of the cluster
+	eks         eksAPI
+	autoscaling autoScalingAPI
 }
 
-// NodeGroupConfig represents the configuration for an EKS node group
-type NodeGroupConfig struct {
-	DesiredSize int32                         `json:"desiredSize"`
-	Autoscaling *types.NodegroupScalingConfig `json:"autoscaling,omitempty"`
+// eksAPI is the subset of the EKS API we use
+type eksAPI interface {
+	eks.DescribeNodegroupAPIClient
+	UpdateNodegroupConfig(ctx context.Context, params *eks.UpdateNodegroupConfigInput, optFns ...func(*eks.Options)) (*eks.UpdateNodegroupConfigOutput, error)
 }
 
-// getEKSClient returns an EKS client for the given region, creating it if necessary
-func (p *AWSProvider) getEKSClient(region string) (*eks.Client, error) {
-	p.clientMu.RLock()
-	client, ok := p.eksClients[region]
-	p.clientMu.RUnlock()
-	if ok {
-		return client, nil
-	}
-
-	p.clientMu.Lock()
-	defer p.clientMu.Unlock()
-
-	// Check again in case another goroutine created it
-	if client, ok = p.eksClients[region]; ok {
-		return client, nil
-	}
-
-	// Create new client for this region
-	cfg := p.awsConfig.Copy()
-	cfg.Region = region
-	client = eks.NewFromConfig(cfg)
-	p.eksClients[region] = client
-
-	return client, nil
+// autoScalingAPI is the subset of the Auto Scaling API we use
+type autoScalingAPI interface {
+	TerminateInstanceInAutoScalingGroup(ctx context.Context, params *autoscaling.TerminateInstanceInAutoScalingGroupInput, optFns ...func(*autoscaling.Options)) (*autoscaling.TerminateInstanceInAutoScalingGroupOutput, error)
 }
 
-// getNodeRegion gets the region from a node's labels
-func (p *AWSProvider) getNodeRegion(ctx context.Context, nodeName string) (string, error) {
-	clientset, err := kubernetes.NewForConfig(p.kubeConfig)
-	if err != nil {
-		return "", fmt.Errorf("failed to create kubernetes client: %v", err)
-	}
-
-	node, err := clientset.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
-	if err != nil {
-		return "", fmt.Errorf("failed to get node %s: %v", nodeName, err)
-	}
-
-	region := node.Labels["topology.kubernetes.io/region"]
-	if region == "" {
-		return "", fmt.Errorf("region label not found on node %s", nodeName)
-	}
-
-	return region, nil
+// NodeGroupConfig represents the configuration for an EKS node group
+type NodeGroupConfig struct {
+	DesiredSize int32                         `json:"desiredSize"`
+	Autoscaling *types.NodegroupScalingConfig `json:"autoscaling,omitempty"`
 }
 
-// NewAWSProvider creates a new AWS provider instance
-func NewAWSProvider() (*AWSProvider, error) {
+// NewAWSProvider creates a new AWS provider instance.
+// Nodes are looked up with the shared node lister, or from the API server with the client if nil.
+func NewAWSProvider(client kubernetes.Interface, nodes *pkgk8s.NodeLister) (*AWSProvider, error) {
 	ctx := context.Background()
 
 	// Load AWS configuration
@@ -98,51 +64,29 @@ func NewAWSProvider() (*AWSProvider, error) {
 	if clusterName == "" {
 		return nil, fmt.Errorf("EKS_CLUSTER_NAME environment variable is required")
 	}
+	// The region isn't read from the nodes, node groups scaled down to zero have none
+	if cfg.Region == "" {
+		return nil, fmt.Errorf("AWS region of the cluster is required, e.g. with the AWS_REGION environment variable")
+	}
 
-	// Get kubeconfig
-	kubeConfig, err := rest.InClusterConfig()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get kubeconfig: %v", err)
+	if nodes == nil {
+		nodes = pkgk8s.NewNodeLister(client)
 	}
 
 	return &AWSProvider{
-		awsConfig:   cfg,
 		clusterName: clusterName,
-		kubeConfig:  kubeConfig,
-		eksClients:  make(map[string]*eks.Client),
+		client:      client,
+		state:       pkgk8s.NewStateStore(client, os.Getenv("NAMESPACE")),
+		nodes:       nodes,
+		eks:         eks.NewFromConfig(cfg),
+		autoscaling: autoscaling.NewFromConfig(cfg),
 	}, nil
 }
 
 // ScaleNodePool scales an EKS node group to the specified count
 func (p *AWSProvider) ScaleNodePool(ctx context.Context, nodeGroupName string, count int32) error {
-	// Get nodes in the node group to find region
-	nodes, err := p.getNodesInNodeGroup(ctx, nodeGroupName)
-	if err != nil {
-		return fmt.Errorf("failed to get nodes: %v", err)
-	}
-	if len(nodes) == 0 {
-		return fmt.Errorf("no nodes found in node group %s", nodeGroupName)
-	}
-
-	// Get region from first node
-	region, err := p.getNodeRegion(ctx, nodes[0].Name)
-	if err != nil {
-		return fmt.Errorf("failed to get region: %v", err)
-	}
-
-	// Get EKS client for this region
-	eksClient, err := p.getEKSClient(region)
-	if err != nil {
-		return fmt.Errorf("failed to get EKS client: %v", err)
-	}
-
-	// Save current configuration before scaling
-	if err = p.saveNodeGroupConfig(ctx, nodeGroupName); err != nil {
-		return fmt.Errorf("failed to save node group config: %v", err)
-	}
-
 	// Check and log current node group status
-	nodeGroup, err := eksClient.DescribeNodegroup(ctx, &eks.DescribeNodegroupInput{
+	nodeGroup, err := p.eks.DescribeNodegroup(ctx, &eks.DescribeNodegroupInput{
 		ClusterName:   &p.clusterName,
 		NodegroupName: &nodeGroupName,
 	})
@@ -156,15 +100,28 @@ func (p *AWSProvider) ScaleNodePool(ctx context.Context, nodeGroupName string, c
 		"health", nodeGroup.Nodegroup.Health,
 	)
 
-	// Disable autoscaling if enabled
-	if nodeGroup.Nodegroup.ScalingConfig != nil && nodeGroup.Nodegroup.ScalingConfig.MinSize != nil {
-		_, err = eksClient.UpdateNodegroupConfig(ctx, &eks.UpdateNodegroupConfigInput{
+	// Node groups scaled down already are left alone, scaled down to zero they have no nodes to drain
+	if scaledTo(nodeGroup.Nodegroup.ScalingConfig, count) {
+		slog.Debug("Node group already at desired size", "node_group", nodeGroupName, "size", count)
+		return nil
+	}
+
+	// Save current configuration before scaling
+	if err = p.saveNodeGroupConfig(ctx, nodeGroupName); err != nil {
+		return fmt.Errorf("failed to save node group config: %v", err)
+	}
+
+	// Disable autoscaling by capping the node group at its current size, it's only lowered once the excess nodes
+	// are drained, lowering it now would terminate nodes picked by the Auto Scaling group while still running pods.
+	// The minimum size is lowered already so that the drained nodes can be terminated.
+	if config := nodeGroup.Nodegroup.ScalingConfig; config != nil && config.DesiredSize != nil && *config.DesiredSize > 0 {
+		minSize := min(count, *config.DesiredSize)
+		_, err = p.eks.UpdateNodegroupConfig(ctx, &eks.UpdateNodegroupConfigInput{
 			ClusterName:   &p.clusterName,
 			NodegroupName: &nodeGroupName,
 			ScalingConfig: &types.NodegroupScalingConfig{
-				MinSize:     &count,
-				MaxSize:     &count,
-				DesiredSize: &count,
+				MinSize: &minSize,
+				MaxSize: config.DesiredSize,
 			},
 		})
 		if err != nil {
@@ -179,19 +136,30 @@ func (p *AWSProvider) ScaleNodePool(ctx context.Context, nodeGroupName string, c
 		return fmt.Errorf("failed to get nodes: %v", err)
 	}
 
-	// Drain excess nodes
-	nodesToDrain := len(nodesInGroup) - int(count)
-	if nodesToDrain > 0 {
-		for i := 0; i < nodesToDrain && i < len(nodesInGroup); i++ {
-			if err = pkgk8s.DrainNode(ctx, p.kubeConfig, nodesInGroup[i].Name); err != nil {
-				slog.Error("Failed to drain node", "node", nodesInGroup[i].Name, "error", err)
-				continue
-			}
+	// Drain excess nodes, picked by the node selection strategy and tainted first so that no pods are scheduled
+	// onto them until the node group is resized, they are released again if the scale down is aborted
+	var draining []corev1.Node
+	if nodesToDrain := len(nodesInGroup) - int(count); nodesToDrain > 0 {
+		if err = pkgk8s.SortNodesForDrain(ctx, p.client, nodesInGroup); err != nil {
+			return fmt.Errorf("failed to select nodes to drain: %v", err)
+		}
+		draining = nodesInGroup[:nodesToDrain]
+	}
+	resized := false
+	defer func() {
+		if !resized {
+			pkgk8s.ReleaseNodes(context.WithoutCancel(ctx), p.client, draining)
 		}
+	}()
+	if err = pkgk8s.TaintNodes(ctx, p.client, draining); err != nil {
+		return err
+	}
+	if err = pkgk8s.DrainNodes(ctx, p.client, nodeNames(draining)); err != nil {
+		return err
 	}
 
 	// Wait for node group to be active before updating
-	waiter := eks.NewNodegroupActiveWaiter(eksClient)
+	waiter := eks.NewNodegroupActiveWaiter(p.eks)
 	if err = waiter.Wait(ctx, &eks.DescribeNodegroupInput{
 		ClusterName:   &p.clusterName,
 		NodegroupName: &nodeGroupName,
@@ -200,7 +168,7 @@ func (p *AWSProvider) ScaleNodePool(ctx context.Context, nodeGroupName string, c
 	}
 
 	// Verify status after waiting
-	nodeGroup, err = eksClient.DescribeNodegroup(ctx, &eks.DescribeNodegroupInput{
+	nodeGroup, err = p.eks.DescribeNodegroup(ctx, &eks.DescribeNodegroupInput{
 		ClusterName:   &p.clusterName,
 		NodegroupName: &nodeGroupName,
 	})
@@ -214,11 +182,23 @@ func (p *AWSProvider) ScaleNodePool(ctx context.Context, nodeGroupName string, c
 		"health", nodeGroup.Nodegroup.Health,
 	)
 
-	// Update node group size
-	_, err = eksClient.UpdateNodegroupConfig(ctx, &eks.UpdateNodegroupConfigInput{
+	// Terminate exactly the drained nodes, lowering the desired size alone lets the Auto Scaling group pick the
+	// instances to terminate, which may be undrained nodes still running pods. Terminated nodes aren't released.
+	terminated, err := p.terminateNodeInstances(ctx, draining)
+	draining = draining[terminated:]
+	if err != nil {
+		return fmt.Errorf("failed to terminate drained nodes: %v", err)
+	}
+	resized = true
+
+	// Update node group size, the maximum size must be at least 1
+	maxSize := max(count, 1)
+	_, err = p.eks.UpdateNodegroupConfig(ctx, &eks.UpdateNodegroupConfigInput{
 		ClusterName:   &p.clusterName,
 		NodegroupName: &nodeGroupName,
 		ScalingConfig: &types.NodegroupScalingConfig{
+			MinSize:     &count,
+			MaxSize:     &maxSize,
 			DesiredSize: &count,
 		},
 	})
@@ -230,34 +210,85 @@ func (p *AWSProvider) ScaleNodePool(ctx context.Context, nodeGroupName string, c
 	return nil
 }
 
+// scaledTo returns true if the node group was scaled down to count nodes, with autoscaling disabled
+func scaledTo(config *types.NodegroupScalingConfig, count int32) bool {
+	return config != nil && config.DesiredSize != nil && *config.DesiredSize == count &&
+		config.MaxSize != nil && *config.MaxSize == max(count, 1)
+}
+
+// terminateNodeInstances terminates the instances of the nodes in their Auto Scaling group, decrementing its
+// desired capacity so that they aren't replaced, and returns how many of them, in order, were terminated
+func (p *AWSProvider) terminateNodeInstances(ctx context.Context, nodes []corev1.Node) (int, error) {
+	for i, node := range nodes {
+		instanceID, ok := parseAWSProviderID(node.Spec.ProviderID)
+		if !ok {
+			return i, fmt.Errorf("unexpected provider ID %q of node %s", node.Spec.ProviderID, node.Name)
+		}
+		_, err := p.autoscaling.TerminateInstanceInAutoScalingGroup(ctx, &autoscaling.TerminateInstanceInAutoScalingGroupInput{
+			InstanceId:                     &instanceID,
+			ShouldDecrementDesiredCapacity: aws.Bool(true),
+		})
+		if err != nil {
+			return i, fmt.Errorf("failed to terminate instance %s of node %s: %v", instanceID, node.Name, err)
+		}
+		slog.Info("Terminated drained node", "node", node.Name, "instance", instanceID)
+	}
+	return len(nodes), nil
+}
+
+// parseAWSProviderID returns the instance ID of a node provider ID, e.g. aws:///us-east-1a/i-0123456789abcdef0
+func parseAWSProviderID(providerID string) (string, bool) {
+	if !strings.HasPrefix(providerID, "aws://") {
+		return "", false
+	}
+	instanceID := providerID[strings.LastIndex(providerID, "/")+1:]
+	if !strings.HasPrefix(instanceID, "i-") {
+		return "", false
+	}
+	return instanceID, true
+}
+
 // RestoreNodePool restores an EKS node group to its saved configuration
 func (p *AWSProvider) RestoreNodePool(ctx context.Context, nodeGroupName string) error {
-	// Get saved config from ConfigMap
-	clientset, err := kubernetes.NewForConfig(p.kubeConfig)
-	if err != nil {
-		return fmt.Errorf("failed to create kubernetes client: %v", err)
-	}
+	return p.restoreNodePool(ctx, nodeGroupName, nil)
+}
+
+// RestoreNodePoolTo restores an EKS node group to its saved configuration with a desired size of at most
+// count, but at least its saved minimum size
+func (p *AWSProvider) RestoreNodePoolTo(ctx context.Context, nodeGroupName string, count int32) error {
+	return p.restoreNodePool(ctx, nodeGroupName, &count)
+}
 
-	configMap, err := clientset.CoreV1().ConfigMaps(os.Getenv("NAMESPACE")).Get(ctx,
-		fmt.Sprintf("%s%s", ConfigMapNamePrefix, nodeGroupName), metav1.GetOptions{})
+// restoreNodePool restores an EKS node group to its saved configuration, with at most limit nodes if not nil
+func (p *AWSProvider) restoreNodePool(ctx context.Context, nodeGroupName string, limit *int32) error {
+	// Get saved config from the state store
+	key := pkgk8s.StateKey{Kind: pkgk8s.StateKindNodePool, Name: nodeGroupName}
+	state, ok, err := p.state.Get(ctx, key)
 	if err != nil {
-		if k8serrors.IsNotFound(err) {
-			return &ErrNoSavedState{NodePool: nodeGroupName}
-		}
 		return fmt.Errorf("failed to get saved config: %v", err)
 	}
-
+	if !ok {
+		return &ErrNoSavedState{NodePool: nodeGroupName}
+	}
 	var savedConfig NodeGroupConfig
-	if err = json.Unmarshal([]byte(configMap.Data["config"]), &savedConfig); err != nil {
+	if err := json.Unmarshal(state.Config, &savedConfig); err != nil {
 		return fmt.Errorf("failed to parse saved config: %v", err)
 	}
 
+	desiredSize := savedConfig.DesiredSize
+	if limit != nil && *limit < desiredSize {
+		desiredSize = *limit
+		if savedConfig.Autoscaling != nil && savedConfig.Autoscaling.MinSize != nil {
+			desiredSize = max(desiredSize, *savedConfig.Autoscaling.MinSize)
+		}
+	}
+
 	// Update node group configuration
 	input := &eks.UpdateNodegroupConfigInput{
 		ClusterName:   &p.clusterName,
 		NodegroupName: &nodeGroupName,
 		ScalingConfig: &types.NodegroupScalingConfig{
-			DesiredSize: &savedConfig.DesiredSize,
+			DesiredSize: &desiredSize,
 		},
 	}
 
@@ -266,62 +297,23 @@ func (p *AWSProvider) RestoreNodePool(ctx context.Context, nodeGroupName string)
 		input.ScalingConfig.MaxSize = savedConfig.Autoscaling.MaxSize
 	}
 
-	// Get nodes in the node group to find region
-	nodes, err := p.getNodesInNodeGroup(ctx, nodeGroupName)
-	if err != nil {
-		return fmt.Errorf("failed to get nodes: %v", err)
-	}
-	if len(nodes) == 0 {
-		return fmt.Errorf("no nodes found in node group %s", nodeGroupName)
-	}
-
-	// Get region from first node
-	region, err := p.getNodeRegion(ctx, nodes[0].Name)
-	if err != nil {
-		return fmt.Errorf("failed to get region: %v", err)
-	}
-
-	// Get EKS client for this region
-	eksClient, err := p.getEKSClient(region)
-	if err != nil {
-		return fmt.Errorf("failed to get EKS client: %v", err)
-	}
-
-	_, err = eksClient.UpdateNodegroupConfig(ctx, input)
+	_, err = p.eks.UpdateNodegroupConfig(ctx, input)
 	if err != nil {
 		return fmt.Errorf("failed to restore node group: %v", err)
 	}
 
 	slog.Info("Restored node group configuration",
 		"node_group", nodeGroupName,
-		"desired_size", savedConfig.DesiredSize,
+		"desired_size", desiredSize,
 	)
+	if desiredSize == savedConfig.DesiredSize {
+		markRestored(ctx, p.state, state)
+	}
 	return nil
 }
 
 func (p *AWSProvider) saveNodeGroupConfig(ctx context.Context, nodeGroupName string) error {
-	// Get nodes in the node group to find region
-	nodes, err := p.getNodesInNodeGroup(ctx, nodeGroupName)
-	if err != nil {
-		return fmt.Errorf("failed to get nodes: %v", err)
-	}
-	if len(nodes) == 0 {
-		return fmt.Errorf("no nodes found in node group %s", nodeGroupName)
-	}
-
-	// Get region from first node
-	region, err := p.getNodeRegion(ctx, nodes[0].Name)
-	if err != nil {
-		return fmt.Errorf("failed to get region: %v", err)
-	}
-
-	// Get EKS client for this region
-	eksClient, err := p.getEKSClient(region)
-	if err != nil {
-		return fmt.Errorf("failed to get EKS client: %v", err)
-	}
-
-	nodeGroup, err := eksClient.DescribeNodegroup(ctx, &eks.DescribeNodegroupInput{
+	nodeGroup, err := p.eks.DescribeNodegroup(ctx, &eks.DescribeNodegroupInput{
 		ClusterName:   &p.clusterName,
 		NodegroupName: &nodeGroupName,
 	})
@@ -340,49 +332,15 @@ func (p *AWSProvider) saveNodeGroupConfig(ctx context.Context, nodeGroupName str
 		}
 	}
 
-	configMap := &corev1.ConfigMap{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      fmt.Sprintf("%s%s", ConfigMapNamePrefix, nodeGroupName),
-			Namespace: os.Getenv("NAMESPACE"),
-		},
-		Data: map[string]string{
-			"config": encodeNodeGroupConfig(config),
-		},
-	}
-
-	if err := pkgk8s.CreateConfigMap(ctx, p.kubeConfig, configMap); err != nil {
+	key := pkgk8s.StateKey{Kind: pkgk8s.StateKindNodePool, Name: nodeGroupName}
+	if err := p.state.Save(ctx, key, config); err != nil {
 		return fmt.Errorf("failed to save node group config: %v", err)
 	}
 
-	slog.Info("Saved node group configuration",
-		"node_group", nodeGroupName,
-		"config_map", configMap.Name,
-	)
+	slog.Info("Saved node group configuration", "node_group", nodeGroupName)
 	return nil
 }
 
 func (p *AWSProvider) getNodesInNodeGroup(ctx context.Context, nodeGroupName string) ([]corev1.Node, error) {
-	clientset, err := kubernetes.NewForConfig(p.kubeConfig)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create kubernetes client: %v", err)
-	}
-
-	labelSelector := fmt.Sprintf("eks.amazonaws.com/nodegroup=%s", nodeGroupName)
-	nodes, err := clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{
-		LabelSelector: labelSelector,
-	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to list nodes: %v", err)
-	}
-
-	return nodes.Items, nil
-}
-
-func encodeNodeGroupConfig(config NodeGroupConfig) string {
-	data, err := json.Marshal(config)
-	if err != nil {
-		slog.Error("Failed to marshal node group config", "error", err)
-		return ""
-	}
-	return string(data)
+	return p.nodes.NodePoolNodes(ctx, "aws", nodeGroupName)
 }
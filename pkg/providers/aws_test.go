@@ -0,0 +1,129 @@
+package providers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/autoscaling"
+	"github.com/aws/aws-sdk-go-v2/service/eks"
+	"github.com/aws/aws-sdk-go-v2/service/eks/types"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+
+	pkgk8s "github.com/kezhenxu94/bmw-saver/pkg/kubernetes"
+)
+
+// withoutCRDs is a clientset whose API server has no custom resources, so that node pool state is saved in
+// ConfigMaps of the fake clientset
+type withoutCRDs struct {
+	kubernetes.Interface
+	discovery discovery.DiscoveryInterface
+}
+
+func (c withoutCRDs) Discovery() discovery.DiscoveryInterface {
+	return c.discovery
+}
+
+func newClientWithoutCRDs(t *testing.T, client kubernetes.Interface) kubernetes.Interface {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"kind": "Status", "apiVersion": "v1", "status": "Failure", "reason": "NotFound", "code": 404}`))
+	}))
+	t.Cleanup(server.Close)
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(&rest.Config{Host: server.URL})
+	if err != nil {
+		t.Fatalf("NewDiscoveryClientForConfig() error = %v", err)
+	}
+	return withoutCRDs{Interface: client, discovery: discoveryClient}
+}
+
+// fakeEKS is an EKS API with a single active node group, whose instances are the nodes of the client
+type fakeEKS struct {
+	client  kubernetes.Interface
+	config  types.NodegroupScalingConfig
+	updates int
+}
+
+func (f *fakeEKS) DescribeNodegroup(ctx context.Context, params *eks.DescribeNodegroupInput, optFns ...func(*eks.Options)) (*eks.DescribeNodegroupOutput, error) {
+	config := f.config
+	return &eks.DescribeNodegroupOutput{Nodegroup: &types.Nodegroup{
+		NodegroupName: params.NodegroupName,
+		Status:        types.NodegroupStatusActive,
+		ScalingConfig: &config,
+	}}, nil
+}
+
+func (f *fakeEKS) UpdateNodegroupConfig(ctx context.Context, params *eks.UpdateNodegroupConfigInput, optFns ...func(*eks.Options)) (*eks.UpdateNodegroupConfigOutput, error) {
+	f.updates++
+	if config := params.ScalingConfig; config != nil {
+		if config.MinSize != nil {
+			f.config.MinSize = config.MinSize
+		}
+		if config.MaxSize != nil {
+			f.config.MaxSize = config.MaxSize
+		}
+		if config.DesiredSize != nil {
+			f.config.DesiredSize = config.DesiredSize
+		}
+	}
+	return &eks.UpdateNodegroupConfigOutput{}, nil
+}
+
+// TerminateInstanceInAutoScalingGroup removes the node of the instance and decrements the desired size
+func (f *fakeEKS) TerminateInstanceInAutoScalingGroup(ctx context.Context, params *autoscaling.TerminateInstanceInAutoScalingGroupInput, optFns ...func(*autoscaling.Options)) (*autoscaling.TerminateInstanceInAutoScalingGroupOutput, error) {
+	if err := f.client.CoreV1().Nodes().Delete(ctx, *params.InstanceId, metav1.DeleteOptions{}); err != nil {
+		return nil, err
+	}
+	f.config.DesiredSize = aws.Int32(*f.config.DesiredSize - 1)
+	return &autoscaling.TerminateInstanceInAutoScalingGroupOutput{}, nil
+}
+
+func TestAWSProvider_ScaleToZeroAndRestore(t *testing.T) {
+	ctx := context.Background()
+	client := fake.NewSimpleClientset(&corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "i-0123456789abcdef0", Labels: map[string]string{"eks.amazonaws.com/nodegroup": "default-group"}},
+		Spec:       corev1.NodeSpec{ProviderID: "aws:///us-east-1a/i-0123456789abcdef0"},
+	})
+	api := &fakeEKS{client: client, config: types.NodegroupScalingConfig{
+		MinSize:     aws.Int32(1),
+		MaxSize:     aws.Int32(3),
+		DesiredSize: aws.Int32(1),
+	}}
+	provider := &AWSProvider{
+		clusterName: "cluster",
+		client:      client,
+		state:       pkgk8s.NewStateStore(newClientWithoutCRDs(t, client), "default"),
+		nodes:       pkgk8s.NewNodeLister(client),
+		eks:         api,
+		autoscaling: api,
+	}
+
+	// The node group has no nodes once scaled down, scaling it down again does nothing
+	for i := 0; i < 3; i++ {
+		if err := provider.ScaleNodePool(ctx, "default-group", 0); err != nil {
+			t.Fatalf("ScaleNodePool() #%d error = %v", i+1, err)
+		}
+	}
+	if *api.config.DesiredSize != 0 || *api.config.MaxSize != 1 {
+		t.Errorf("scaling config = %d nodes, max %d, want 0 nodes, max 1", *api.config.DesiredSize, *api.config.MaxSize)
+	}
+	if api.updates != 2 {
+		t.Errorf("node group updated %d times, want only by the first scale down", api.updates)
+	}
+
+	if err := provider.RestoreNodePool(ctx, "default-group"); err != nil {
+		t.Fatalf("RestoreNodePool() error = %v", err)
+	}
+	if *api.config.DesiredSize != 1 || *api.config.MinSize != 1 || *api.config.MaxSize != 3 {
+		t.Errorf("restored scaling config = %d nodes, min %d, max %d, want 1 node, min 1, max 3",
+			*api.config.DesiredSize, *api.config.MinSize, *api.config.MaxSize)
+	}
+}
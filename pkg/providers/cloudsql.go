@@ -0,0 +1,78 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/api/option"
+	sqladmin "google.golang.org/api/sqladmin/v1"
+)
+
+// CloudSQLDatabase implements the Database interface for Cloud SQL instances, which are stopped and started
+// with their activation policy
+type CloudSQLDatabase struct {
+	service  *sqladmin.Service
+	project  string
+	instance string
+}
+
+// NewCloudSQLDatabase creates a Cloud SQL instance of the project, or of the project of the cluster if empty
+func NewCloudSQLDatabase(ctx context.Context, instance, project string, opts ...option.ClientOption) (*CloudSQLDatabase, error) {
+	service, err := sqladmin.NewService(ctx, append([]option.ClientOption{option.WithScopes(sqladmin.SqlserviceAdminScope)}, opts...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Cloud SQL service: %v", err)
+	}
+	if project == "" {
+		if project, err = getProjectID(); err != nil {
+			return nil, fmt.Errorf("failed to get project ID: %v", err)
+		}
+	}
+	return &CloudSQLDatabase{
+		service:  service,
+		project:  project,
+		instance: instance,
+	}, nil
+}
+
+// Status returns the status of the instance, it's pending while operations on it are running
+func (d *CloudSQLDatabase) Status(ctx context.Context) (DatabaseStatus, error) {
+	instance, err := d.service.Instances.Get(d.project, d.instance).Context(ctx).Do()
+	if err != nil {
+		return "", fmt.Errorf("failed to get Cloud SQL instance %s: %v", d.instance, err)
+	}
+	operations, err := d.service.Operations.List(d.project).Instance(d.instance).MaxResults(1).Context(ctx).Do()
+	if err != nil {
+		return "", fmt.Errorf("failed to list operations of Cloud SQL instance %s: %v", d.instance, err)
+	}
+	if len(operations.Items) > 0 && operations.Items[0].Status != "DONE" {
+		return DatabasePending, nil
+	}
+
+	switch {
+	case instance.Settings != nil && instance.Settings.ActivationPolicy == "NEVER":
+		return DatabaseStopped, nil
+	case instance.State == "RUNNABLE":
+		return DatabaseAvailable, nil
+	default:
+		return DatabasePending, nil
+	}
+}
+
+// Stop stops the instance by setting its activation policy to NEVER
+func (d *CloudSQLDatabase) Stop(ctx context.Context) error {
+	return d.setActivationPolicy(ctx, "NEVER")
+}
+
+// Start starts the instance by setting its activation policy to ALWAYS
+func (d *CloudSQLDatabase) Start(ctx context.Context) error {
+	return d.setActivationPolicy(ctx, "ALWAYS")
+}
+
+// setActivationPolicy patches the activation policy of the instance
+func (d *CloudSQLDatabase) setActivationPolicy(ctx context.Context, policy string) error {
+	patch := &sqladmin.DatabaseInstance{Settings: &sqladmin.Settings{ActivationPolicy: policy}}
+	if _, err := d.service.Instances.Patch(d.project, d.instance, patch).Context(ctx).Do(); err != nil {
+		return fmt.Errorf("failed to set activation policy of Cloud SQL instance %s to %s: %v", d.instance, policy, err)
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+)
+
+// DatabaseStatus is the status of a managed database
+type DatabaseStatus string
+
+const (
+	// DatabaseAvailable is a running database accepting connections
+	DatabaseAvailable DatabaseStatus = "available"
+	// DatabaseStopped is a stopped database
+	DatabaseStopped DatabaseStatus = "stopped"
+	// DatabasePending is a database starting, stopping or in maintenance
+	DatabasePending DatabaseStatus = "pending"
+)
+
+// Database defines the interface for stopping and starting managed databases, e.g. Cloud SQL instances or
+// RDS DB instances
+type Database interface {
+	// Status returns the status of the database
+	Status(ctx context.Context) (DatabaseStatus, error)
+
+	// Stop stops the database, it may take minutes to stop afterwards
+	Stop(ctx context.Context) error
+
+	// Start starts the database, it may take minutes to become available afterwards
+	Start(ctx context.Context) error
+}
+
+// NewDatabase creates a database of the provider type, "cloudsql" or "rds".
+// It returns an error if the provider type is not supported.
+func NewDatabase(ctx context.Context, providerType, name, project, region string) (Database, error) {
+	switch providerType {
+	case "cloudsql":
+		return NewCloudSQLDatabase(ctx, name, project)
+	case "rds":
+		return NewRDSDatabase(ctx, name, region)
+	default:
+		return nil, fmt.Errorf("unsupported database provider: %s", providerType)
+	}
+}
@@ -8,34 +8,29 @@ import (
 	"log/slog"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 
+	compute "google.golang.org/api/compute/v1"
 	container "google.golang.org/api/container/v1"
 	"google.golang.org/api/googleapi"
 	"google.golang.org/api/option"
 	corev1 "k8s.io/api/core/v1"
-	k8serrors "k8s.io/apimachinery/pkg/api/errors"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/rest"
 
 	pkgk8s "github.com/kezhenxu94/bmw-saver/pkg/kubernetes"
 )
 
-const (
-	// ConfigMapNamePrefix is the prefix for the ConfigMap name
-	ConfigMapNamePrefix = "bmw-saver-nodepool-"
-	// ConfigMapNamespace is the namespace for the ConfigMap
-	ConfigMapNamespace = "bmw-saver"
-)
-
 // GKEProvider implements the CloudProvider interface for Google Kubernetes Engine.
 type GKEProvider struct {
-	service    *container.Service
-	projectID  string
-	cluster    string
-	location   string
-	kubeConfig *rest.Config
+	service   *container.Service
+	compute   *compute.Service
+	projectID string
+	cluster   string
+	location  string
+	client    kubernetes.Interface
+	state     *pkgk8s.StateStore
+	nodes     *pkgk8s.NodeLister
 }
 
 // NodePoolConfig represents the configuration for a node pool
@@ -46,12 +41,17 @@ type NodePoolConfig struct {
 
 // NewGKEProvider creates a new GKE provider instance.
 // It initializes the GCP client and retrieves cluster information.
-func NewGKEProvider() (*GKEProvider, error) {
+// Nodes are looked up with the shared node lister, or from the API server with the client if nil.
+func NewGKEProvider(client kubernetes.Interface, nodes *pkgk8s.NodeLister) (*GKEProvider, error) {
 	ctx := context.Background()
 	service, err := container.NewService(ctx, option.WithScopes(container.CloudPlatformScope))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create GKE service: %v", err)
 	}
+	computeService, err := compute.NewService(ctx, option.WithScopes(compute.CloudPlatformScope))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Compute Engine service: %v", err)
+	}
 
 	projectID, err := getProjectID()
 	if err != nil {
@@ -68,9 +68,8 @@ func NewGKEProvider() (*GKEProvider, error) {
 		return nil, fmt.Errorf("failed to get cluster location: %v", err)
 	}
 
-	kubeConfig, err := rest.InClusterConfig()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get kubeconfig: %v", err)
+	if nodes == nil {
+		nodes = pkgk8s.NewNodeLister(client)
 	}
 
 	slog.Info("GKE provider initialized",
@@ -80,11 +79,14 @@ func NewGKEProvider() (*GKEProvider, error) {
 	)
 
 	return &GKEProvider{
-		service:    service,
-		projectID:  projectID,
-		cluster:    cluster,
-		location:   location,
-		kubeConfig: kubeConfig,
+		service:   service,
+		compute:   computeService,
+		projectID: projectID,
+		cluster:   cluster,
+		location:  location,
+		client:    client,
+		state:     pkgk8s.NewStateStore(client, os.Getenv("NAMESPACE")),
+		nodes:     nodes,
 	}, nil
 }
 
@@ -179,13 +181,33 @@ func (p *GKEProvider) ScaleNodePool(ctx context.Context, nodePoolName string, co
 				return nil
 			}
 
-			for _, node := range nodes {
-				slog.Debug("Node", "name", node.Name, "status", node.Status)
-				if isNodeCordoned(&node) {
-					if err := pkgk8s.DrainNode(ctx, p.kubeConfig, node.Name); err != nil {
-						return fmt.Errorf("failed to drain node %s: %v", node.Name, err)
-					}
+			// Drain the nodes to remove, cordoning them first, nodes cordoned already are removed first, then the
+			// nodes picked by the node selection strategy
+			if err := pkgk8s.SortNodesForDrain(ctx, p.client, nodes); err != nil {
+				return fmt.Errorf("failed to select nodes to drain: %v", err)
+			}
+			var draining []corev1.Node
+			if excess := len(nodes) - int(count); excess > 0 {
+				draining = nodes[:excess]
+			}
+
+			// Taint the nodes to remove so that no pods are scheduled onto them until the node pool is resized,
+			// they are released again if the scale down is aborted
+			resized := false
+			defer func() {
+				if !resized {
+					pkgk8s.ReleaseNodes(context.WithoutCancel(ctx), p.client, draining)
 				}
+			}()
+			if err := pkgk8s.TaintNodes(ctx, p.client, draining); err != nil {
+				return err
+			}
+
+			for _, node := range draining {
+				slog.Debug("Draining node to remove", "name", node.Name, "cordoned", isNodeCordoned(&node))
+			}
+			if err := pkgk8s.DrainNodes(ctx, p.client, nodeNames(draining)); err != nil {
+				return err
 			}
 
 			if err := p.saveNodePoolConfig(ctx, nodePoolName); err != nil {
@@ -199,6 +221,13 @@ func (p *GKEProvider) ScaleNodePool(ctx context.Context, nodePoolName string, co
 				}
 			}
 
+			// Delete exactly the drained nodes from their instance groups, resizing the node pool alone lets the
+			// instance groups pick the instances to delete, which may be undrained nodes still running pods
+			if err := p.deleteNodeInstances(ctx, nodePool, draining); err != nil {
+				return fmt.Errorf("failed to delete drained nodes: %v", err)
+			}
+			resized = true
+
 			if err := p.updateNodePool(ctx, nodePoolName, count); err != nil {
 				return fmt.Errorf("failed to update node pool: %v", err)
 			}
@@ -234,9 +263,9 @@ func (p *GKEProvider) updateNodePool(ctx context.Context, nodePoolName string, c
 
 	_, err := p.service.Projects.Locations.Clusters.NodePools.SetSize(name, request).Context(ctx).Do()
 	if err != nil {
+		// Fail instead of retrying later, so that the drained nodes are released and the node pool is retried
 		if isClusterBusy(err) {
-			slog.Info("Cluster is busy, will retry in next reconciliation", "node_pool", nodePoolName)
-			return nil
+			return fmt.Errorf("cluster is busy with another operation: %v", err)
 		}
 		return fmt.Errorf("failed to update node pool: %v", err)
 	}
@@ -244,6 +273,69 @@ func (p *GKEProvider) updateNodePool(ctx context.Context, nodePoolName string, c
 	return nil
 }
 
+// deleteNodeInstances deletes the instances of the nodes from the managed instance groups of the node pool, which
+// lowers their target sizes accordingly, and waits for the deletions to complete
+func (p *GKEProvider) deleteNodeInstances(ctx context.Context, nodePool *container.NodePool, nodes []corev1.Node) error {
+	// The node pool has an instance group per zone, the instances of the nodes are deleted from the one in their zone
+	groups := make(map[string]string) // zone -> instance group manager
+	for _, url := range nodePool.InstanceGroupUrls {
+		zone, name, ok := parseInstanceGroupURL(url)
+		if !ok {
+			return fmt.Errorf("unexpected instance group URL %q", url)
+		}
+		groups[zone] = name
+	}
+
+	instances := make(map[string][]string) // zone -> instances
+	for _, node := range nodes {
+		zone, instance, ok := parseGCEProviderID(node.Spec.ProviderID)
+		if !ok {
+			return fmt.Errorf("unexpected provider ID %q of node %s", node.Spec.ProviderID, node.Name)
+		}
+		if _, ok := groups[zone]; !ok {
+			return fmt.Errorf("no instance group of node pool %s in zone %s of node %s", nodePool.Name, zone, node.Name)
+		}
+		instances[zone] = append(instances[zone], fmt.Sprintf("zones/%s/instances/%s", zone, instance))
+	}
+
+	for zone, urls := range instances {
+		request := &compute.InstanceGroupManagersDeleteInstancesRequest{Instances: urls}
+		op, err := p.compute.InstanceGroupManagers.DeleteInstances(p.projectID, zone, groups[zone], request).Context(ctx).Do()
+		if err != nil {
+			return fmt.Errorf("failed to delete instances from instance group %s: %v", groups[zone], err)
+		}
+		for op.Status != "DONE" {
+			if op, err = p.compute.ZoneOperations.Wait(p.projectID, zone, op.Name).Context(ctx).Do(); err != nil {
+				return fmt.Errorf("failed waiting for instances to be deleted from instance group %s: %v", groups[zone], err)
+			}
+		}
+		if op.Error != nil && len(op.Error.Errors) > 0 {
+			return fmt.Errorf("failed to delete instances from instance group %s: %s", groups[zone], op.Error.Errors[0].Message)
+		}
+		slog.Info("Deleted drained nodes from instance group", "node_pool", nodePool.Name, "instance_group", groups[zone], "instances", urls)
+	}
+	return nil
+}
+
+// parseInstanceGroupURL returns the zone and name of the instance group manager of a node pool instance group URL,
+// e.g. https://www.googleapis.com/compute/v1/projects/p/zones/z/instanceGroupManagers/name
+func parseInstanceGroupURL(url string) (string, string, bool) {
+	parts := strings.Split(url, "/")
+	if len(parts) < 4 || parts[len(parts)-4] != "zones" || parts[len(parts)-2] != "instanceGroupManagers" {
+		return "", "", false
+	}
+	return parts[len(parts)-3], parts[len(parts)-1], true
+}
+
+// parseGCEProviderID returns the zone and instance name of a node provider ID, e.g. gce://project/zone/instance
+func parseGCEProviderID(providerID string) (string, string, bool) {
+	parts := strings.Split(strings.TrimPrefix(providerID, "gce://"), "/")
+	if !strings.HasPrefix(providerID, "gce://") || len(parts) != 3 {
+		return "", "", false
+	}
+	return parts[1], parts[2], true
+}
+
 func (p *GKEProvider) disableAutoscaling(ctx context.Context, nodePoolName string) error {
 	name := fmt.Sprintf("projects/%s/locations/%s/clusters/%s/nodePools/%s", p.projectID, p.location, p.cluster, nodePoolName)
 
@@ -256,8 +348,7 @@ func (p *GKEProvider) disableAutoscaling(ctx context.Context, nodePoolName strin
 	_, err := p.service.Projects.Locations.Clusters.NodePools.SetAutoscaling(name, request).Context(ctx).Do()
 	if err != nil {
 		if isClusterBusy(err) {
-			slog.Info("Cluster is busy, will retry in next reconciliation", "node_pool", nodePoolName)
-			return nil
+			return fmt.Errorf("cluster is busy with another operation: %v", err)
 		}
 		return fmt.Errorf("failed to disable autoscaling for node pool: %v", err)
 	}
@@ -279,24 +370,12 @@ func (p *GKEProvider) saveNodePoolConfig(ctx context.Context, nodePoolName strin
 				Autoscaling: nodePool.Autoscaling,
 			}
 
-			configMap := &corev1.ConfigMap{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:      fmt.Sprintf("%s%s", ConfigMapNamePrefix, nodePoolName),
-					Namespace: os.Getenv("NAMESPACE"),
-				},
-				Data: map[string]string{
-					"config": encodeNodePoolConfig(config),
-				},
-			}
-
-			if err := pkgk8s.CreateConfigMap(ctx, p.kubeConfig, configMap); err != nil {
+			key := pkgk8s.StateKey{Kind: pkgk8s.StateKindNodePool, Name: nodePoolName}
+			if err := p.state.Save(ctx, key, config); err != nil {
 				return fmt.Errorf("failed to save node pool config: %v", err)
 			}
 
-			slog.Info("Saved node pool configuration to ConfigMap",
-				"node_pool", nodePoolName,
-				"config_map", configMap.Name,
-			)
+			slog.Info("Saved node pool configuration", "node_pool", nodePoolName)
 			return nil
 		}
 	}
@@ -304,53 +383,35 @@ func (p *GKEProvider) saveNodePoolConfig(ctx context.Context, nodePoolName strin
 	return fmt.Errorf("node pool %s not found", nodePoolName)
 }
 
-func encodeNodePoolConfig(config NodePoolConfig) string {
-	data, err := json.Marshal(config)
-	if err != nil {
-		slog.Error("Failed to marshal node pool config", "error", err)
-		return ""
-	}
-	return string(data)
-}
-
 func (p *GKEProvider) getNodesInNodePool(ctx context.Context, nodePoolName string) ([]corev1.Node, error) {
-	clientset, err := kubernetes.NewForConfig(p.kubeConfig)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create kubernetes client: %v", err)
-	}
-
-	labelSelector := fmt.Sprintf("cloud.google.com/gke-nodepool=%s", nodePoolName)
-	nodes, err := clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{
-		LabelSelector: labelSelector,
-	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to list nodes: %v", err)
-	}
-
-	return nodes.Items, nil
+	return p.nodes.NodePoolNodes(ctx, "gke", nodePoolName)
 }
 
 // RestoreNodePool restores a GKE node pool to its saved configuration.
-// It retrieves the configuration from a ConfigMap and applies it.
+// It retrieves the configuration from the state store and applies it.
 func (p *GKEProvider) RestoreNodePool(ctx context.Context, nodePoolName string) error {
-	// Get saved config from ConfigMap
-	clientset, err := kubernetes.NewForConfig(p.kubeConfig)
-	if err != nil {
-		return fmt.Errorf("failed to create kubernetes client: %v", err)
-	}
+	return p.restoreNodePool(ctx, nodePoolName, nil)
+}
 
-	configMap, err := clientset.CoreV1().ConfigMaps(os.Getenv("NAMESPACE")).Get(ctx,
-		fmt.Sprintf("%s%s", ConfigMapNamePrefix, nodePoolName), metav1.GetOptions{})
+// RestoreNodePoolTo restores a GKE node pool to its saved configuration with at most count nodes.
+// Autoscaled node pools are resized to count before autoscaling is enabled again.
+func (p *GKEProvider) RestoreNodePoolTo(ctx context.Context, nodePoolName string, count int32) error {
+	return p.restoreNodePool(ctx, nodePoolName, &count)
+}
+
+// restoreNodePool restores a GKE node pool to its saved configuration, with at most limit nodes if not nil
+func (p *GKEProvider) restoreNodePool(ctx context.Context, nodePoolName string, limit *int32) error {
+	// Get saved config from the state store
+	key := pkgk8s.StateKey{Kind: pkgk8s.StateKindNodePool, Name: nodePoolName}
+	state, ok, err := p.state.Get(ctx, key)
 	if err != nil {
-		if k8serrors.IsNotFound(err) {
-			return &ErrNoSavedState{NodePool: nodePoolName}
-		}
 		return fmt.Errorf("failed to get saved config: %v", err)
 	}
-
-	configData := configMap.Data["config"]
+	if !ok {
+		return &ErrNoSavedState{NodePool: nodePoolName}
+	}
 	var savedConfig NodePoolConfig
-	if err = json.Unmarshal([]byte(configData), &savedConfig); err != nil {
+	if err := json.Unmarshal(state.Config, &savedConfig); err != nil {
 		return fmt.Errorf("failed to parse saved config: %v", err)
 	}
 
@@ -372,19 +433,29 @@ func (p *GKEProvider) RestoreNodePool(ctx context.Context, nodePoolName string)
 		return fmt.Errorf("node pool %s not found", nodePoolName)
 	}
 
+	nodeCount := savedConfig.NodeCount
+	if limit != nil && int64(*limit) < nodeCount {
+		nodeCount = int64(*limit)
+	}
+
 	// Check if node pool is already at desired state
 	isAutoscalingMatch := (currentPool.Autoscaling == nil && savedConfig.Autoscaling == nil) ||
 		(currentPool.Autoscaling != nil && savedConfig.Autoscaling != nil &&
 			currentPool.Autoscaling.Enabled == savedConfig.Autoscaling.Enabled)
 	isNodeCountMatch := savedConfig.Autoscaling != nil && savedConfig.Autoscaling.Enabled ||
-		currentPool.InitialNodeCount == savedConfig.NodeCount
+		currentPool.InitialNodeCount == nodeCount
+	// Autoscaled node pools get their saved autoscaling back even if they start from fewer nodes
+	isFullSize := savedConfig.Autoscaling != nil && savedConfig.Autoscaling.Enabled || nodeCount == savedConfig.NodeCount
 
 	if isAutoscalingMatch && isNodeCountMatch {
 		slog.Debug("Node pool already at desired state",
 			"node_pool", nodePoolName,
-			"node_count", savedConfig.NodeCount,
+			"node_count", nodeCount,
 			"autoscaling_enabled", savedConfig.Autoscaling != nil && savedConfig.Autoscaling.Enabled,
 		)
+		if isFullSize {
+			markRestored(ctx, p.state, state)
+		}
 		return nil
 	}
 
@@ -395,14 +466,21 @@ func (p *GKEProvider) RestoreNodePool(ctx context.Context, nodePoolName string)
 	if savedConfig.Autoscaling != nil && savedConfig.Autoscaling.Enabled {
 		// Only update autoscaling if it's different from current state
 		if !isAutoscalingMatch {
+			// Limited node pools start from the limit, while autoscaling is still disabled
+			if limit != nil && currentPool.InitialNodeCount != nodeCount {
+				if err := p.updateNodePool(ctx, nodePoolName, int32(nodeCount)); err != nil {
+					return fmt.Errorf("failed to restore node count: %v", err)
+				}
+				slog.Info("Restored node count", "node_pool", nodePoolName, "count", nodeCount)
+			}
 			request := &container.SetNodePoolAutoscalingRequest{
 				Autoscaling: savedConfig.Autoscaling,
 			}
 			_, err = p.service.Projects.Locations.Clusters.NodePools.SetAutoscaling(name, request).Context(ctx).Do()
 			if err != nil {
+				// Fail instead of retrying later, so that the restore backs off and is retried
 				if isClusterBusy(err) {
-					slog.Info("Cluster is busy, will retry in next reconciliation", "node_pool", nodePoolName)
-					return nil
+					return fmt.Errorf("cluster is busy with another operation: %v", err)
 				}
 				return fmt.Errorf("failed to restore autoscaling: %v", err)
 			}
@@ -411,18 +489,20 @@ func (p *GKEProvider) RestoreNodePool(ctx context.Context, nodePoolName string)
 	} else {
 		// Only set node count when autoscaling is disabled
 		request := &container.SetNodePoolSizeRequest{
-			NodeCount: savedConfig.NodeCount,
+			NodeCount: nodeCount,
 		}
 		_, err = p.service.Projects.Locations.Clusters.NodePools.SetSize(name, request).Context(ctx).Do()
 		if err != nil {
 			if isClusterBusy(err) {
-				slog.Info("Cluster is busy, will retry in next reconciliation", "node_pool", nodePoolName)
-				return nil
+				return fmt.Errorf("cluster is busy with another operation: %v", err)
 			}
 			return fmt.Errorf("failed to restore node count: %v", err)
 		}
-		slog.Info("Restored node count", "node_pool", nodePoolName, "count", savedConfig.NodeCount)
+		slog.Info("Restored node count", "node_pool", nodePoolName, "count", nodeCount)
 	}
 
+	if isFullSize {
+		markRestored(ctx, p.state, state)
+	}
 	return nil
 }
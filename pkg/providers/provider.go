@@ -3,6 +3,12 @@ package providers
 import (
 	"context"
 	"fmt"
+	"log/slog"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+
+	pkgk8s "github.com/kezhenxu94/bmw-saver/pkg/kubernetes"
 )
 
 // ErrNoSavedState indicates that there is no saved state to restore for a node pool
@@ -31,14 +37,43 @@ type CloudProvider interface {
 	RestoreNodePool(ctx context.Context, nodePoolName string) error
 }
 
+// SizedRestorer is implemented by cloud providers that can restore a node pool to its saved configuration
+// with fewer nodes than it had when it was saved.
+type SizedRestorer interface {
+	// RestoreNodePoolTo restores a node pool to its saved configuration, with at most count nodes.
+	// Autoscaled node pools start from count nodes, the autoscaler sizes them afterwards.
+	RestoreNodePoolTo(ctx context.Context, nodePoolName string, count int32) error
+}
+
+// markRestored marks the saved state of the node pool as restored once the node pool is restored to its full size,
+// so that its configuration is saved again by the next scale down, failures are only logged
+func markRestored(ctx context.Context, store *pkgk8s.StateStore, state pkgk8s.SavedState) {
+	if !state.RestoredAt.IsZero() {
+		return
+	}
+	if err := store.MarkRestored(ctx, state.StateKey); err != nil {
+		slog.Warn("Failed to mark saved state as restored", "node_pool", state.Name, "error", err)
+	}
+}
+
+// nodeNames returns the names of the nodes
+func nodeNames(nodes []corev1.Node) []string {
+	names := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		names = append(names, node.Name)
+	}
+	return names
+}
+
 // NewCloudProvider creates a new cloud provider based on the provider type.
+// The providers share the Kubernetes client, and look nodes up with the shared node lister if not nil.
 // It returns an error if the provider type is not supported.
-func NewCloudProvider(providerType string) (CloudProvider, error) {
+func NewCloudProvider(providerType string, client kubernetes.Interface, nodes *pkgk8s.NodeLister) (CloudProvider, error) {
 	switch providerType {
 	case "gke":
-		return NewGKEProvider()
+		return NewGKEProvider(client, nodes)
 	case "aws":
-		return NewAWSProvider()
+		return NewAWSProvider(client, nodes)
 	case "azure":
 		return NewAzureProvider()
 	default:
@@ -0,0 +1,131 @@
+package providers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/config"
+)
+
+// rdsAPIVersion is the version of the RDS Query API
+const rdsAPIVersion = "2014-10-31"
+
+// RDSDatabase implements the Database interface for RDS DB instances with the RDS Query API
+type RDSDatabase struct {
+	credentials aws.CredentialsProvider
+	region      string
+	endpoint    string
+	client      *http.Client
+	identifier  string
+}
+
+// NewRDSDatabase creates an RDS DB instance of the region, or of the region of the AWS configuration if empty
+func NewRDSDatabase(ctx context.Context, identifier, region string) (*RDSDatabase, error) {
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %v", err)
+	}
+	if region == "" {
+		region = cfg.Region
+	}
+	if region == "" {
+		return nil, fmt.Errorf("region is required for RDS DB instance %s", identifier)
+	}
+	return &RDSDatabase{
+		credentials: cfg.Credentials,
+		region:      region,
+		endpoint:    fmt.Sprintf("https://rds.%s.amazonaws.com/", region),
+		client:      &http.Client{Timeout: 30 * time.Second},
+		identifier:  identifier,
+	}, nil
+}
+
+// describeDBInstancesResponse is the subset of the DescribeDBInstances response we need
+type describeDBInstancesResponse struct {
+	Instances []struct {
+		Status string `xml:"DBInstanceStatus"`
+	} `xml:"DescribeDBInstancesResult>DBInstances>DBInstance"`
+}
+
+// Status returns the status of the DB instance
+func (d *RDSDatabase) Status(ctx context.Context) (DatabaseStatus, error) {
+	body, err := d.call(ctx, "DescribeDBInstances")
+	if err != nil {
+		return "", err
+	}
+	var response describeDBInstancesResponse
+	if err := xml.Unmarshal(body, &response); err != nil {
+		return "", fmt.Errorf("failed to parse RDS DB instance %s: %v", d.identifier, err)
+	}
+	if len(response.Instances) == 0 {
+		return "", fmt.Errorf("RDS DB instance %s not found", d.identifier)
+	}
+
+	switch response.Instances[0].Status {
+	case "available":
+		return DatabaseAvailable, nil
+	case "stopped":
+		return DatabaseStopped, nil
+	default:
+		return DatabasePending, nil
+	}
+}
+
+// Stop stops the DB instance, RDS starts stopped DB instances again after seven days
+func (d *RDSDatabase) Stop(ctx context.Context) error {
+	_, err := d.call(ctx, "StopDBInstance")
+	return err
+}
+
+// Start starts the DB instance
+func (d *RDSDatabase) Start(ctx context.Context) error {
+	_, err := d.call(ctx, "StartDBInstance")
+	return err
+}
+
+// call calls the action of the RDS Query API on the DB instance, signed with the AWS credentials
+func (d *RDSDatabase) call(ctx context.Context, action string) ([]byte, error) {
+	form := url.Values{
+		"Action":               {action},
+		"Version":              {rdsAPIVersion},
+		"DBInstanceIdentifier": {d.identifier},
+	}.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.endpoint, strings.NewReader(form))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create RDS request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	credentials, err := d.credentials.Retrieve(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve AWS credentials: %v", err)
+	}
+	hash := sha256.Sum256([]byte(form))
+	if err := v4.NewSigner().SignHTTP(ctx, credentials, req, hex.EncodeToString(hash[:]), "rds", d.region, time.Now()); err != nil {
+		return nil, fmt.Errorf("failed to sign RDS request: %v", err)
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call RDS %s for DB instance %s: %v", action, d.identifier, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read RDS response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("RDS %s for DB instance %s failed with status %d: %s", action, d.identifier, resp.StatusCode, body)
+	}
+	return body, nil
+}
@@ -0,0 +1,194 @@
+package savings
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kezhenxu94/bmw-saver/pkg/schedule"
+)
+
+// PoolSavings are the realized savings of a node pool
+type PoolSavings struct {
+	// NodeHours is how many node hours were saved by scaling the node pool down
+	NodeHours float64 `json:"nodeHours"`
+	// Cost is the saved cost, the node hours multiplied by the hourly cost of a node
+	Cost float64 `json:"cost"`
+	// CO2 is the estimated CO2 emissions saved in kg, the node hours multiplied by the hourly emissions of a node
+	CO2 float64 `json:"co2,omitempty"`
+	// ScaledDownNodes is how many nodes are currently removed by scaling down, 0 if the node pool is restored
+	ScaledDownNodes int `json:"scaledDownNodes,omitempty"`
+	// HourlyNodeCost is the hourly cost of a node while scaled down
+	HourlyNodeCost float64 `json:"hourlyNodeCost,omitempty"`
+	// HourlyNodeCO2 is the estimated hourly CO2 emissions of a node in kg while scaled down
+	HourlyNodeCO2 float64 `json:"hourlyNodeCO2,omitempty"`
+	// Since is when the savings were last accumulated
+	Since time.Time `json:"since,omitempty"`
+}
+
+// Tracker accumulates the realized savings of node pools while they are scaled down, and
+// persists them in a store so that they survive restarts. It is safe for concurrent use.
+type Tracker struct {
+	store schedule.CacheStore
+	key   string
+	mu    sync.Mutex
+	pools map[string]*PoolSavings
+}
+
+// NewTracker creates a new tracker and loads the savings from key of the store, which may be nil
+func NewTracker(ctx context.Context, store schedule.CacheStore, key string) (*Tracker, error) {
+	t := &Tracker{
+		store: store,
+		key:   key,
+		pools: make(map[string]*PoolSavings),
+	}
+	if store == nil {
+		return t, nil
+	}
+
+	data, err := store.Load(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load savings: %v", err)
+	}
+	if data != nil {
+		if err := json.Unmarshal(data, &t.pools); err != nil {
+			return nil, fmt.Errorf("failed to parse savings: %v", err)
+		}
+	}
+	return t, nil
+}
+
+// ScaledDown tracks the nodes removed from the node pool in addition to the ones removed before, e.g. when
+// it is scaled from an evening tier to the off-time count. Removed nodes are negative if nodes were added back.
+func (t *Tracker) ScaledDown(nodePool string, removedNodes int, hourlyNodeCost, hourlyNodeCO2 float64, now time.Time) {
+	if t == nil || removedNodes == 0 {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	pool := t.pool(nodePool)
+	pool.accumulate(now)
+	pool.ScaledDownNodes = max(pool.ScaledDownNodes+removedNodes, 0)
+	pool.HourlyNodeCost = hourlyNodeCost
+	pool.HourlyNodeCO2 = hourlyNodeCO2
+}
+
+// Restored stops tracking the node pool until it is scaled down again
+func (t *Tracker) Restored(nodePool string, now time.Time) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	pool, ok := t.pools[nodePool]
+	if !ok {
+		return
+	}
+	pool.accumulate(now)
+	pool.ScaledDownNodes = 0
+}
+
+// Update accumulates the savings of all node pools until now
+func (t *Tracker) Update(now time.Time) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, pool := range t.pools {
+		pool.accumulate(now)
+	}
+}
+
+// Savings returns the savings of the node pools
+func (t *Tracker) Savings() map[string]PoolSavings {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	savings := make(map[string]PoolSavings, len(t.pools))
+	for name, pool := range t.pools {
+		savings[name] = *pool
+	}
+	return savings
+}
+
+// Save persists the savings in the store
+func (t *Tracker) Save(ctx context.Context) error {
+	if t == nil || t.store == nil {
+		return nil
+	}
+	t.mu.Lock()
+	data, err := json.Marshal(t.pools)
+	t.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to marshal savings: %v", err)
+	}
+	return t.store.Save(ctx, t.key, data)
+}
+
+// WriteMetrics writes the savings in the Prometheus text format
+func (t *Tracker) WriteMetrics(w io.Writer, currency string) error {
+	savings := t.Savings()
+	names := make([]string, 0, len(savings))
+	for name := range savings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	metrics := []struct {
+		name, help, kind string
+		value            func(PoolSavings) float64
+		labels           string
+	}{
+		{"bmw_saver_saved_node_hours_total", "Node hours saved by scaling node pools down.", "counter",
+			func(s PoolSavings) float64 { return s.NodeHours }, ""},
+		{"bmw_saver_saved_cost_total", "Cost saved by scaling node pools down.", "counter",
+			func(s PoolSavings) float64 { return s.Cost }, fmt.Sprintf(",currency=%q", currency)},
+		{"bmw_saver_saved_co2_kilograms_total", "Estimated CO2 emissions saved by scaling node pools down.", "counter",
+			func(s PoolSavings) float64 { return s.CO2 }, ""},
+		{"bmw_saver_scaled_down_nodes", "Nodes currently removed by scaling node pools down.", "gauge",
+			func(s PoolSavings) float64 { return float64(s.ScaledDownNodes) }, ""},
+	}
+	for _, metric := range metrics {
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", metric.name, metric.help, metric.name, metric.kind); err != nil {
+			return err
+		}
+		for _, name := range names {
+			if _, err := fmt.Fprintf(w, "%s{node_pool=%q%s} %g\n", metric.name, name, metric.labels, metric.value(savings[name])); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// pool returns the savings of the node pool, adding them if they don't exist yet
+func (t *Tracker) pool(nodePool string) *PoolSavings {
+	pool, ok := t.pools[nodePool]
+	if !ok {
+		pool = &PoolSavings{}
+		t.pools[nodePool] = pool
+	}
+	return pool
+}
+
+// accumulate adds the savings since the last accumulation
+func (s *PoolSavings) accumulate(now time.Time) {
+	if s.ScaledDownNodes > 0 && !s.Since.IsZero() && now.After(s.Since) {
+		nodeHours := now.Sub(s.Since).Hours() * float64(s.ScaledDownNodes)
+		s.NodeHours += nodeHours
+		s.Cost += nodeHours * s.HourlyNodeCost
+		s.CO2 += nodeHours * s.HourlyNodeCO2
+	}
+	s.Since = now
+}
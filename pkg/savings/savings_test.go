@@ -0,0 +1,69 @@
+package savings
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+// memoryStore is a cache store in memory
+type memoryStore map[string][]byte
+
+func (s memoryStore) Load(ctx context.Context, key string) ([]byte, error) {
+	return s[key], nil
+}
+
+func (s memoryStore) Save(ctx context.Context, key string, data []byte) error {
+	s[key] = data
+	return nil
+}
+
+func TestTracker(t *testing.T) {
+	store := memoryStore{}
+	tracker, err := NewTracker(context.Background(), store, "savings")
+	if err != nil {
+		t.Fatalf("NewTracker() error = %v", err)
+	}
+
+	evening := time.Date(2024, time.June, 3, 18, 0, 0, 0, time.UTC)
+	// Scaled from 5 to 3 nodes in the evening, to 1 node at night, and restored in the morning
+	tracker.ScaledDown("default-pool", 2, 0.5, 0.25, evening)
+	tracker.Update(evening.Add(2 * time.Hour))
+	tracker.ScaledDown("default-pool", 2, 0.5, 0.25, evening.Add(4*time.Hour))
+	tracker.Restored("default-pool", evening.Add(14*time.Hour))
+	tracker.Update(evening.Add(20 * time.Hour))
+
+	got := tracker.Savings()["default-pool"]
+	// 2 nodes for 4 hours, then 4 nodes for 10 hours
+	if got.NodeHours != 48 || got.Cost != 24 || got.CO2 != 12 || got.ScaledDownNodes != 0 {
+		t.Errorf("Savings() = %+v, want 48 node hours, a cost of 24 and 12 kg CO2", got)
+	}
+
+	if err := tracker.Save(context.Background()); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	loaded, err := NewTracker(context.Background(), store, "savings")
+	if err != nil {
+		t.Fatalf("NewTracker() error = %v", err)
+	}
+	if loaded := loaded.Savings()["default-pool"]; loaded.NodeHours != 48 {
+		t.Errorf("Loaded savings = %+v, want 48 node hours", loaded)
+	}
+
+	var metrics bytes.Buffer
+	if err := tracker.WriteMetrics(&metrics, "USD"); err != nil {
+		t.Fatalf("WriteMetrics() error = %v", err)
+	}
+	for _, want := range []string{
+		`bmw_saver_saved_node_hours_total{node_pool="default-pool"} 48`,
+		`bmw_saver_saved_cost_total{node_pool="default-pool",currency="USD"} 24`,
+		`bmw_saver_saved_co2_kilograms_total{node_pool="default-pool"} 12`,
+		`bmw_saver_scaled_down_nodes{node_pool="default-pool"} 0`,
+	} {
+		if !strings.Contains(metrics.String(), want+"\n") {
+			t.Errorf("Metrics missing %q:\n%s", want, metrics.String())
+		}
+	}
+}
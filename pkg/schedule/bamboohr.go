@@ -0,0 +1,87 @@
+package schedule
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// bambooHRBaseURL is the base URL of the BambooHR API, followed by the company domain
+const bambooHRBaseURL = "https://api.bamboohr.com/api/gateway.php/"
+
+// bambooHRSource lists approved time off requests from BambooHR
+type bambooHRSource struct {
+	client  httpClient
+	baseURL string
+	apiKey  string
+}
+
+// bambooHRTimeOffRequest is the subset of a BambooHR time off request we need
+type bambooHRTimeOffRequest struct {
+	EmployeeID json.Number `json:"employeeId"`
+	Start      string      `json:"start"`
+	End        string      `json:"end"`
+}
+
+// newBambooHRSource creates a new BambooHR source for the company API base URL
+func newBambooHRSource(baseURL, apiKey string) *bambooHRSource {
+	return &bambooHRSource{
+		client:  &http.Client{Timeout: 30 * time.Second},
+		baseURL: baseURL,
+		apiKey:  apiKey,
+	}
+}
+
+// listTimeOff returns the approved time off requests overlapping the date range
+func (s *bambooHRSource) listTimeOff(ctx context.Context, start, end time.Time) ([]timeOff, error) {
+	params := url.Values{}
+	params.Set("start", start.Format("2006-01-02"))
+	params.Set("end", end.Format("2006-01-02"))
+	params.Set("status", "approved")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.baseURL+"/v1/time_off/requests/?"+params.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create BambooHR request: %v", err)
+	}
+	// BambooHR uses the API key as user name with any password
+	req.SetBasicAuth(s.apiKey, "x")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list BambooHR time off: %v", err)
+	}
+	defer func() {
+		if e := resp.Body.Close(); e != nil {
+			slog.Error("Failed to close BambooHR response body", "error", e)
+		}
+	}()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read BambooHR response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to list BambooHR time off: unexpected status code %d", resp.StatusCode)
+	}
+
+	var requests []bambooHRTimeOffRequest
+	if err := json.Unmarshal(body, &requests); err != nil {
+		return nil, fmt.Errorf("failed to parse BambooHR time off: %v", err)
+	}
+
+	entries := make([]timeOff, 0, len(requests))
+	for _, request := range requests {
+		entries = append(entries, timeOff{
+			Employee:  request.EmployeeID.String(),
+			StartDate: request.Start,
+			EndDate:   request.End,
+		})
+	}
+	return entries, nil
+}
@@ -0,0 +1,125 @@
+package schedule
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// CacheConfigMapNamePrefix is the prefix for the ConfigMaps storing calendar caches
+	CacheConfigMapNamePrefix = "bmw-saver-cache-"
+	cacheDataKey             = "cache"
+)
+
+// CacheStore persists calendar caches so that they survive restarts and calendar outages
+type CacheStore interface {
+	// Load returns the cache stored under the key, or nil if there is none
+	Load(ctx context.Context, key string) ([]byte, error)
+	// Save stores the cache under the key
+	Save(ctx context.Context, key string, data []byte) error
+}
+
+// cacheKey derives a stable, name-safe cache key from the provider kind and its sources
+func cacheKey(kind string, sources ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(sources, "\n")))
+	return kind + "-" + hex.EncodeToString(sum[:])[:16]
+}
+
+// FileCacheStore stores caches as files in a directory, e.g. an emptyDir volume
+type FileCacheStore struct {
+	dir string
+}
+
+// NewFileCacheStore creates a new file cache store in the given absolute directory
+func NewFileCacheStore(dir string) (*FileCacheStore, error) {
+	if !filepath.IsAbs(dir) {
+		return nil, fmt.Errorf("cache directory must be absolute: %s", dir)
+	}
+	if err := os.MkdirAll(filepath.Clean(dir), 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %v", err)
+	}
+	return &FileCacheStore{dir: filepath.Clean(dir)}, nil
+}
+
+// Load reads the cache file for the key
+func (s *FileCacheStore) Load(ctx context.Context, key string) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(s.dir, key+".json"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cache file: %v", err)
+	}
+	return data, nil
+}
+
+// Save atomically writes the cache file for the key
+func (s *FileCacheStore) Save(ctx context.Context, key string, data []byte) error {
+	path := filepath.Join(s.dir, key+".json")
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write cache file: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to replace cache file: %v", err)
+	}
+	return nil
+}
+
+// ConfigMapCacheStore stores each cache in its own ConfigMap
+type ConfigMapCacheStore struct {
+	client    kubernetes.Interface
+	namespace string
+}
+
+// NewConfigMapCacheStore creates a new ConfigMap cache store in the given namespace
+func NewConfigMapCacheStore(client kubernetes.Interface, namespace string) *ConfigMapCacheStore {
+	return &ConfigMapCacheStore{
+		client:    client,
+		namespace: namespace,
+	}
+}
+
+// Load reads the cache ConfigMap for the key
+func (s *ConfigMapCacheStore) Load(ctx context.Context, key string) ([]byte, error) {
+	cm, err := s.client.CoreV1().ConfigMaps(s.namespace).Get(ctx, CacheConfigMapNamePrefix+key, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cache ConfigMap: %v", err)
+	}
+	return []byte(cm.Data[cacheDataKey]), nil
+}
+
+// Save creates or updates the cache ConfigMap for the key
+func (s *ConfigMapCacheStore) Save(ctx context.Context, key string, data []byte) error {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      CacheConfigMapNamePrefix + key,
+			Namespace: s.namespace,
+		},
+		Data: map[string]string{
+			cacheDataKey: string(data),
+		},
+	}
+
+	_, err := s.client.CoreV1().ConfigMaps(s.namespace).Update(ctx, cm, metav1.UpdateOptions{})
+	if k8serrors.IsNotFound(err) {
+		_, err = s.client.CoreV1().ConfigMaps(s.namespace).Create(ctx, cm, metav1.CreateOptions{})
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save cache ConfigMap: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,261 @@
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+)
+
+// carbonIntensityRetention is how long past hourly carbon intensities are kept, so that the lowest hours
+// of the current day are still chosen among all of its hours once the forecast moved on
+const carbonIntensityRetention = 48 * time.Hour
+
+// carbonIntensitySource forecasts the carbon intensity of a power grid
+type carbonIntensitySource interface {
+	// forecast returns the forecast carbon intensities in gCO2eq/kWh, in any granularity
+	forecast(ctx context.Context) ([]carbonIntensity, error)
+}
+
+// carbonIntensity is the carbon intensity of a power grid from a point in time until the next one
+type carbonIntensity struct {
+	At        time.Time
+	Intensity float64
+}
+
+// CarbonAwareProvider is a schedule provider that says work time in the hours of the day with the lowest
+// forecast carbon intensity of the power grid, e.g. so that batch node pools run when power is cleanest
+type CarbonAwareProvider struct {
+	source       string
+	zone         string
+	hoursPerDay  int
+	location     *time.Location
+	forecaster   carbonIntensitySource
+	syncInterval time.Duration
+	// hours are the average carbon intensities of the hours, keyed by their start
+	hours    map[time.Time]float64
+	lastSync time.Time
+	// syncErr is the error of the last sync, nil if it succeeded
+	syncErr error
+	mu      sync.RWMutex
+	// stop stops the background sync
+	stop context.CancelFunc
+}
+
+// CarbonAwareOptions contains the settings for creating a CarbonAwareProvider
+type CarbonAwareOptions struct {
+	// Source is the carbon intensity API: "electricitymaps" or "watttime"
+	Source string
+	// Zone is the ElectricityMaps zone or the WattTime region
+	Zone string
+	// APIToken is the ElectricityMaps API token
+	APIToken string
+	// Username and Password are the WattTime credentials
+	Username string
+	Password string
+	// HoursPerDay is how many hours per day are work time
+	HoursPerDay int
+	// Location is the time zone of the days
+	Location *time.Location
+	// SyncInterval is how often to refresh the forecast
+	SyncInterval time.Duration
+}
+
+// NewCarbonAwareProvider creates a new carbon-aware provider for the configured carbon intensity API
+func NewCarbonAwareProvider(opts CarbonAwareOptions) (*CarbonAwareProvider, error) {
+	if opts.Zone == "" {
+		return nil, fmt.Errorf("zone is required for carbon-aware schedules")
+	}
+
+	var forecaster carbonIntensitySource
+	switch opts.Source {
+	case "electricitymaps":
+		if opts.APIToken == "" {
+			return nil, fmt.Errorf("API token is required for ElectricityMaps")
+		}
+		forecaster = newElectricityMapsSource(electricityMapsAPIURL, opts.APIToken, opts.Zone)
+	case "watttime":
+		if opts.Username == "" || opts.Password == "" {
+			return nil, fmt.Errorf("username and password are required for WattTime")
+		}
+		forecaster = newWattTimeSource(wattTimeAPIURL, opts.Username, opts.Password, opts.Zone)
+	default:
+		return nil, fmt.Errorf("unsupported carbon intensity source: %s", opts.Source)
+	}
+
+	return newCarbonAwareProvider(context.Background(), forecaster, opts)
+}
+
+// newCarbonAwareProvider creates the provider with the given source and syncs the forecast
+func newCarbonAwareProvider(ctx context.Context, forecaster carbonIntensitySource, opts CarbonAwareOptions) (*CarbonAwareProvider, error) {
+	location := opts.Location
+	if location == nil {
+		location = time.UTC
+	}
+	provider := &CarbonAwareProvider{
+		source:       opts.Source,
+		zone:         opts.Zone,
+		hoursPerDay:  opts.HoursPerDay,
+		location:     location,
+		forecaster:   forecaster,
+		syncInterval: opts.SyncInterval,
+		hours:        make(map[time.Time]float64),
+	}
+
+	// Initial sync
+	if err := provider.sync(ctx); err != nil {
+		return nil, fmt.Errorf("failed initial carbon intensity sync: %v", err)
+	}
+
+	// Start background sync
+	syncCtx, stop := context.WithCancel(context.Background())
+	provider.stop = stop
+	go provider.backgroundSync(syncCtx)
+
+	return provider, nil
+}
+
+func (p *CarbonAwareProvider) backgroundSync(ctx context.Context) {
+	ticker := time.NewTicker(p.syncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := p.sync(ctx); err != nil {
+				slog.Error("Failed to sync carbon intensity forecast", "source", p.source, "error", err)
+			}
+		}
+	}
+}
+
+// sync merges the forecast into the hourly carbon intensities and records the result for Healthy.
+// Forecast hours replace the known ones, past hours are kept for the retention.
+func (p *CarbonAwareProvider) sync(ctx context.Context) error {
+	now := time.Now()
+	forecast, err := p.forecaster.forecast(ctx)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.syncErr = err
+	if err != nil {
+		return err
+	}
+	for hour, intensity := range hourlyIntensities(forecast) {
+		p.hours[hour] = intensity
+	}
+	for hour := range p.hours {
+		if now.Sub(hour) > carbonIntensityRetention {
+			delete(p.hours, hour)
+		}
+	}
+	p.lastSync = now
+	slog.Info("Carbon intensity forecast synced successfully", "source", p.source, "zone", p.zone, "hours", len(p.hours))
+	return nil
+}
+
+// hourlyIntensities averages the carbon intensities per hour
+func hourlyIntensities(forecast []carbonIntensity) map[time.Time]float64 {
+	sums := make(map[time.Time]float64)
+	counts := make(map[time.Time]int)
+	for _, point := range forecast {
+		hour := point.At.UTC().Truncate(time.Hour)
+		sums[hour] += point.Intensity
+		counts[hour]++
+	}
+	hours := make(map[time.Time]float64, len(sums))
+	for hour, sum := range sums {
+		hours[hour] = sum / float64(counts[hour])
+	}
+	return hours
+}
+
+// Close stops the background sync
+func (p *CarbonAwareProvider) Close(ctx context.Context) {
+	p.stop()
+}
+
+// IsWorkTime returns true in the hours with the lowest carbon intensity of the day
+func (p *CarbonAwareProvider) IsWorkTime(ctx context.Context, t time.Time) (bool, error) {
+	_, _, ok := p.lowCarbonHour(t)
+	return ok, nil
+}
+
+// Describe returns the carbon intensity of the hour, and its rank in the day if it's among the lowest
+func (p *CarbonAwareProvider) Describe(ctx context.Context, t time.Time) (string, error) {
+	intensity, rank, ok := p.lowCarbonHour(t)
+	if rank == 0 {
+		return fmt.Sprintf("no carbon intensity forecast for %s", t.In(p.location).Format("2006-01-02 15:00")), nil
+	}
+	if !ok {
+		return fmt.Sprintf("carbon intensity %.0f gCO2eq/kWh in %s, not among the %d lowest hours of the day", intensity, p.zone, p.hoursPerDay), nil
+	}
+	return fmt.Sprintf("carbon intensity %.0f gCO2eq/kWh in %s, lowest hour %d of %d of the day", intensity, p.zone, rank, p.hoursPerDay), nil
+}
+
+// lowCarbonHour returns the carbon intensity of the hour containing t, its rank among the hours of its day
+// from the lowest intensity, 0 if it isn't known, and whether it's among the lowest hours per day
+func (p *CarbonAwareProvider) lowCarbonHour(t time.Time) (float64, int, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	hour := t.UTC().Truncate(time.Hour)
+	intensity, ok := p.hours[hour]
+	if !ok {
+		return 0, 0, false
+	}
+
+	year, month, day := t.In(p.location).Date()
+	start := time.Date(year, month, day, 0, 0, 0, 0, p.location)
+	end := start.AddDate(0, 0, 1)
+	var dayHours []time.Time
+	for h := range p.hours {
+		if !h.Before(start) && h.Before(end) {
+			dayHours = append(dayHours, h)
+		}
+	}
+	// Ties go to the earlier hour, so that the chosen hours don't change between evaluations
+	sort.Slice(dayHours, func(i, j int) bool {
+		if p.hours[dayHours[i]] != p.hours[dayHours[j]] {
+			return p.hours[dayHours[i]] < p.hours[dayHours[j]]
+		}
+		return dayHours[i].Before(dayHours[j])
+	})
+	for i, h := range dayHours {
+		if h.Equal(hour) {
+			return intensity, i + 1, i < p.hoursPerDay
+		}
+	}
+	return intensity, 0, false
+}
+
+// LastSync returns when the forecast was last synced successfully
+func (p *CarbonAwareProvider) LastSync() time.Time {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.lastSync
+}
+
+// Healthy returns an error if the last sync failed
+func (p *CarbonAwareProvider) Healthy() error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.syncErr != nil {
+		return fmt.Errorf("carbon intensity sync failed: %v", p.syncErr)
+	}
+	return nil
+}
+
+// String returns a string representation of the CarbonAwareProvider
+func (p *CarbonAwareProvider) String() string {
+	return fmt.Sprintf("CarbonAwareProvider{source: %s, zone: %s, hoursPerDay: %d, location: %s, syncInterval: %v}",
+		p.source,
+		p.zone,
+		p.hoursPerDay,
+		p.location,
+		p.syncInterval)
+}
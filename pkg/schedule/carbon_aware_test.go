@@ -0,0 +1,109 @@
+package schedule
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCarbonAwareProvider_IsWorkTime(t *testing.T) {
+	// Tomorrow is fully forecast, its cleanest hours are 03:00, 04:00 and 13:00
+	day := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 1)
+	intensity := func(hour int) float64 {
+		switch hour {
+		case 3, 4:
+			return 100
+		case 13:
+			return 120
+		default:
+			return 300 + float64(hour)
+		}
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v3/carbon-intensity/forecast":
+			if r.Header.Get("auth-token") != "api-token" {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			if r.URL.Query().Get("zone") != "DE" {
+				t.Errorf("zone = %q, want DE", r.URL.Query().Get("zone"))
+			}
+			type point struct {
+				CarbonIntensity float64   `json:"carbonIntensity"`
+				Datetime        time.Time `json:"datetime"`
+			}
+			var forecast []point
+			for hour := 0; hour < 24; hour++ {
+				forecast = append(forecast, point{CarbonIntensity: intensity(hour), Datetime: day.Add(time.Duration(hour) * time.Hour)})
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"zone": "DE", "forecast": forecast})
+		case "/login":
+			if username, password, _ := r.BasicAuth(); username != "user" || password != "secret" {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			_, _ = w.Write([]byte(`{"token": "wt-token"}`))
+		case "/v3/forecast":
+			if r.Header.Get("Authorization") != "Bearer wt-token" {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			type point struct {
+				PointTime time.Time `json:"point_time"`
+				Value     float64   `json:"value"`
+			}
+			// WattTime forecasts in 5 minute steps, in lbs/MWh
+			var data []point
+			for step := 0; step < 24*12; step++ {
+				data = append(data, point{PointTime: day.Add(time.Duration(step) * 5 * time.Minute), Value: intensity(step/12) / lbsPerMWhInGramsPerKWh})
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	sources := map[string]carbonIntensitySource{
+		"electricitymaps": newElectricityMapsSource(server.URL, "api-token", "DE"),
+		"watttime":        newWattTimeSource(server.URL, "user", "secret", "DE"),
+	}
+	tests := []struct {
+		name string
+		t    time.Time
+		want bool
+	}{
+		{name: "Cleanest Hour", t: day.Add(3*time.Hour + 30*time.Minute), want: true},
+		{name: "Third Cleanest Hour", t: day.Add(13 * time.Hour), want: true},
+		{name: "Dirty Hour", t: day.Add(12 * time.Hour), want: false},
+		{name: "Beyond Forecast", t: day.AddDate(0, 0, 1).Add(3 * time.Hour), want: false},
+	}
+	for name, source := range sources {
+		provider, err := newCarbonAwareProvider(context.Background(), source, CarbonAwareOptions{
+			Source:       name,
+			Zone:         "DE",
+			HoursPerDay:  3,
+			SyncInterval: time.Hour,
+		})
+		if err != nil {
+			t.Fatalf("newCarbonAwareProvider(%s) error = %v", name, err)
+		}
+		for _, tt := range tests {
+			t.Run(name+"/"+tt.name, func(t *testing.T) {
+				got, err := provider.IsWorkTime(context.Background(), tt.t)
+				if err != nil {
+					t.Fatalf("IsWorkTime() error = %v", err)
+				}
+				if got != tt.want {
+					t.Errorf("IsWorkTime(%v) = %v, want %v", tt.t, got, tt.want)
+				}
+			})
+		}
+		provider.Close(context.Background())
+	}
+}
@@ -0,0 +1,227 @@
+package schedule
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// defaultSystemNamespaces are never considered user activity
+var defaultSystemNamespaces = []string{"kube-system", "kube-public", "kube-node-lease"}
+
+// ClusterIdleProvider is a schedule provider that inspects the cluster itself.
+// It reports off time only when there are no pending or recently created pods
+// and non-system CPU usage stayed below the threshold for the idle duration.
+// Pods displaced by the scale down during off time aren't activity.
+type ClusterIdleProvider struct {
+	client       kubernetes.Interface
+	idleDuration time.Duration
+	cpuThreshold resource.Quantity
+	excluded     map[string]bool
+	idleSince    time.Time
+	// offSince is when off time was first reported, zero during work time
+	offSince time.Time
+	mu       sync.Mutex
+}
+
+// podMetricsList is the subset of the metrics.k8s.io PodMetricsList we need
+type podMetricsList struct {
+	Items []struct {
+		Metadata struct {
+			Namespace string `json:"namespace"`
+		} `json:"metadata"`
+		Containers []struct {
+			Usage map[string]resource.Quantity `json:"usage"`
+		} `json:"containers"`
+	} `json:"items"`
+}
+
+// NewClusterIdleProvider creates a new cluster idle-detection provider
+func NewClusterIdleProvider(client kubernetes.Interface, idleDuration time.Duration, cpuThreshold string, excludedNamespaces []string) (*ClusterIdleProvider, error) {
+	if idleDuration <= 0 {
+		return nil, fmt.Errorf("idle duration must be positive")
+	}
+
+	threshold, err := resource.ParseQuantity(cpuThreshold)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cpu threshold %q: %v", cpuThreshold, err)
+	}
+
+	excluded := make(map[string]bool)
+	for _, ns := range defaultSystemNamespaces {
+		excluded[ns] = true
+	}
+	for _, ns := range excludedNamespaces {
+		excluded[ns] = true
+	}
+
+	return &ClusterIdleProvider{
+		client:       client,
+		idleDuration: idleDuration,
+		cpuThreshold: threshold,
+		excluded:     excluded,
+	}, nil
+}
+
+// IsWorkTime returns false only if the cluster has been idle for the configured duration
+func (p *ClusterIdleProvider) IsWorkTime(ctx context.Context, t time.Time) (bool, error) {
+	p.mu.Lock()
+	offSince := p.offSince
+	p.mu.Unlock()
+
+	active, reason, err := p.isActive(ctx, t, offSince)
+	if err != nil {
+		return false, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if active {
+		slog.Debug("Cluster is active", "reason", reason)
+		p.idleSince, p.offSince = time.Time{}, time.Time{}
+		return true, nil
+	}
+
+	if p.idleSince.IsZero() || t.Before(p.idleSince) {
+		p.idleSince = t
+	}
+	idleFor := t.Sub(p.idleSince)
+	slog.Debug("Cluster is idle", "idle_for", idleFor)
+	if idleFor < p.idleDuration {
+		return true, nil
+	}
+	if p.offSince.IsZero() {
+		p.offSince = t
+	}
+	return false, nil
+}
+
+// Override returns work time while the cluster isn't idle for the configured duration, and has no opinion once it
+// is, so that with a priority it keeps the cluster up past the work hours of the other providers while it's in use
+func (p *ClusterIdleProvider) Override(ctx context.Context, t time.Time) (bool, bool, error) {
+	isWork, err := p.IsWorkTime(ctx, t)
+	if err != nil || !isWork {
+		return false, false, err
+	}
+	return true, true, nil
+}
+
+// isActive checks the cluster for signs of user activity, offSince is when off time started, zero during work time
+func (p *ClusterIdleProvider) isActive(ctx context.Context, t, offSince time.Time) (bool, string, error) {
+	pods, err := p.client.CoreV1().Pods("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return false, "", fmt.Errorf("failed to list pods: %v", err)
+	}
+
+	for _, pod := range pods.Items {
+		if p.excluded[pod.Namespace] {
+			continue
+		}
+		// Pods evicted by the scale down are recreated and wait for the node pools, which would end off time again
+		if displaced(&pod, offSince) {
+			continue
+		}
+		if pod.Status.Phase == corev1.PodPending {
+			return true, fmt.Sprintf("pod %s/%s is pending", pod.Namespace, pod.Name), nil
+		}
+		if t.Sub(pod.CreationTimestamp.Time) < p.idleDuration && pod.Status.Phase != corev1.PodSucceeded && pod.Status.Phase != corev1.PodFailed {
+			return true, fmt.Sprintf("pod %s/%s was created recently", pod.Namespace, pod.Name), nil
+		}
+	}
+
+	usage, err := p.cpuUsage(ctx)
+	if err != nil {
+		// metrics-server is optional, fall back to pod based detection only
+		slog.Debug("Failed to get pod metrics, skipping CPU usage check", "error", err)
+		return false, "", nil
+	}
+	if usage.Cmp(p.cpuThreshold) >= 0 {
+		return true, fmt.Sprintf("non-system CPU usage %s is above threshold %s", usage.String(), p.cpuThreshold.String()), nil
+	}
+
+	return false, "", nil
+}
+
+// displaced returns true if the pod was created during off time to replace a pod evicted by the scale down, or
+// can't be scheduled while the node pools are scaled down
+func displaced(pod *corev1.Pod, offSince time.Time) bool {
+	if offSince.IsZero() || pod.CreationTimestamp.Time.Before(offSince) {
+		return false
+	}
+	if metav1.GetControllerOf(pod) != nil {
+		return true
+	}
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodScheduled && condition.Status == corev1.ConditionFalse && condition.Reason == corev1.PodReasonUnschedulable {
+			return true
+		}
+	}
+	return false
+}
+
+// cpuUsage sums the CPU usage of all non-system pods reported by metrics-server
+func (p *ClusterIdleProvider) cpuUsage(ctx context.Context) (resource.Quantity, error) {
+	total := resource.Quantity{}
+
+	restClient := p.client.Discovery().RESTClient()
+	if restClient == nil {
+		return total, fmt.Errorf("metrics API is not available")
+	}
+
+	data, err := restClient.Get().
+		AbsPath("/apis/metrics.k8s.io/v1beta1/pods").
+		DoRaw(ctx)
+	if err != nil {
+		return total, fmt.Errorf("failed to query metrics API: %v", err)
+	}
+
+	var metrics podMetricsList
+	if err := json.Unmarshal(data, &metrics); err != nil {
+		return total, fmt.Errorf("failed to parse pod metrics: %v", err)
+	}
+
+	for _, item := range metrics.Items {
+		if p.excluded[item.Metadata.Namespace] {
+			continue
+		}
+		for _, container := range item.Containers {
+			if cpu, ok := container.Usage["cpu"]; ok {
+				total.Add(cpu)
+			}
+		}
+	}
+
+	return total, nil
+}
+
+// ObservesActivity returns true, the cluster can only be inspected now
+func (p *ClusterIdleProvider) ObservesActivity() bool {
+	return true
+}
+
+// LastSync returns the zero time, the cluster is inspected on every check
+func (p *ClusterIdleProvider) LastSync() time.Time {
+	return time.Time{}
+}
+
+// Healthy always returns nil, query errors are returned by IsWorkTime
+func (p *ClusterIdleProvider) Healthy() error {
+	return nil
+}
+
+// String returns a string representation of the ClusterIdleProvider
+func (p *ClusterIdleProvider) String() string {
+	return fmt.Sprintf("ClusterIdleProvider{idleDuration: %v, cpuThreshold: %s, excludedNamespaces: %d}",
+		p.idleDuration,
+		p.cpuThreshold.String(),
+		len(p.excluded))
+}
@@ -0,0 +1,207 @@
+package schedule
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestClusterIdleProvider_IsWorkTime(t *testing.T) {
+	now := time.Date(2024, time.June, 3, 22, 0, 0, 0, time.UTC)
+
+	newPod := func(namespace string, phase corev1.PodPhase, created time.Time) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "pod",
+				Namespace:         namespace,
+				CreationTimestamp: metav1.NewTime(created),
+			},
+			Status: corev1.PodStatus{Phase: phase},
+		}
+	}
+
+	tests := []struct {
+		name  string
+		pods  []*corev1.Pod
+		times []time.Time
+		want  bool
+	}{
+		{
+			name:  "Pending Pod",
+			pods:  []*corev1.Pod{newPod("default", corev1.PodPending, now.Add(-24*time.Hour))},
+			times: []time.Time{now, now.Add(2 * time.Hour)},
+			want:  true,
+		},
+		{
+			name:  "Recently Created Pod",
+			pods:  []*corev1.Pod{newPod("default", corev1.PodRunning, now.Add(-10*time.Minute))},
+			times: []time.Time{now},
+			want:  true,
+		},
+		{
+			name:  "System Pods Only",
+			pods:  []*corev1.Pod{newPod("kube-system", corev1.PodPending, now)},
+			times: []time.Time{now, now.Add(time.Hour)},
+			want:  false,
+		},
+		{
+			name:  "Idle Shorter Than Duration",
+			pods:  []*corev1.Pod{newPod("default", corev1.PodRunning, now.Add(-24*time.Hour))},
+			times: []time.Time{now, now.Add(30 * time.Minute)},
+			want:  true,
+		},
+		{
+			name:  "Idle Longer Than Duration",
+			pods:  []*corev1.Pod{newPod("default", corev1.PodRunning, now.Add(-24*time.Hour))},
+			times: []time.Time{now, now.Add(time.Hour)},
+			want:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := fake.NewClientset()
+			for _, pod := range tt.pods {
+				if _, err := client.CoreV1().Pods(pod.Namespace).Create(context.Background(), pod, metav1.CreateOptions{}); err != nil {
+					t.Fatalf("Failed to create pod: %v", err)
+				}
+			}
+
+			provider, err := NewClusterIdleProvider(client, time.Hour, "200m", nil)
+			if err != nil {
+				t.Fatalf("Failed to create provider: %v", err)
+			}
+
+			var got bool
+			for _, checkTime := range tt.times {
+				got, err = provider.IsWorkTime(context.Background(), checkTime)
+				if err != nil {
+					t.Fatalf("IsWorkTime() error = %v", err)
+				}
+			}
+			if got != tt.want {
+				t.Errorf("IsWorkTime() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClusterIdleProvider_IgnoresDisplacedPods(t *testing.T) {
+	now := time.Date(2024, time.June, 3, 22, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+	client := fake.NewClientset()
+	running := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "app-1", Namespace: "default", CreationTimestamp: metav1.NewTime(now.Add(-24 * time.Hour))},
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+	if _, err := client.CoreV1().Pods("default").Create(ctx, running, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("Failed to create pod: %v", err)
+	}
+	provider, err := NewClusterIdleProvider(client, time.Hour, "200m", nil)
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+	isWorkTime := func(at time.Time) bool {
+		t.Helper()
+		got, err := provider.IsWorkTime(ctx, at)
+		if err != nil {
+			t.Fatalf("IsWorkTime() error = %v", err)
+		}
+		return got
+	}
+
+	if !isWorkTime(now) {
+		t.Fatalf("IsWorkTime() = false before idle for the duration, want true")
+	}
+	offAt := now.Add(time.Hour)
+	if isWorkTime(offAt) {
+		t.Fatalf("IsWorkTime() = true after idle for the duration, want false")
+	}
+
+	// The scale down evicts the pod, its ReplicaSet recreates it and it can't be scheduled
+	controller := true
+	if err := client.CoreV1().Pods("default").Delete(ctx, running.Name, metav1.DeleteOptions{}); err != nil {
+		t.Fatalf("Failed to delete pod: %v", err)
+	}
+	replacement := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "app-2",
+			Namespace:         "default",
+			CreationTimestamp: metav1.NewTime(offAt.Add(5 * time.Minute)),
+			OwnerReferences:   []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "app", Controller: &controller}},
+		},
+		Status: corev1.PodStatus{
+			Phase:      corev1.PodPending,
+			Conditions: []corev1.PodCondition{{Type: corev1.PodScheduled, Status: corev1.ConditionFalse, Reason: corev1.PodReasonUnschedulable}},
+		},
+	}
+	if _, err := client.CoreV1().Pods("default").Create(ctx, replacement, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("Failed to create pod: %v", err)
+	}
+	if isWorkTime(offAt.Add(10 * time.Minute)) {
+		t.Errorf("IsWorkTime() = true for a pod displaced by the scale down, want false")
+	}
+
+	// Pods created outside of controllers during off time are still activity
+	adHoc := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "debug", Namespace: "default", CreationTimestamp: metav1.NewTime(offAt.Add(20 * time.Minute))},
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+	if _, err := client.CoreV1().Pods("default").Create(ctx, adHoc, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("Failed to create pod: %v", err)
+	}
+	if !isWorkTime(offAt.Add(25 * time.Minute)) {
+		t.Errorf("IsWorkTime() = false for a pod created during off time, want true")
+	}
+
+	// Back at work time, the pending replacement is activity again
+	if err := client.CoreV1().Pods("default").Delete(ctx, adHoc.Name, metav1.DeleteOptions{}); err != nil {
+		t.Fatalf("Failed to delete pod: %v", err)
+	}
+	if !isWorkTime(offAt.Add(30 * time.Minute)) {
+		t.Errorf("IsWorkTime() = false with a pending pod during work time, want true")
+	}
+}
+
+func TestClusterIdleProvider_OverridesStaticHours(t *testing.T) {
+	now := time.Date(2024, time.June, 3, 22, 0, 0, 0, time.UTC)
+	client := fake.NewClientset()
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "session", Namespace: "default", CreationTimestamp: metav1.NewTime(now.Add(-10 * time.Minute))},
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+	if _, err := client.CoreV1().Pods("default").Create(context.Background(), pod, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("Failed to create pod: %v", err)
+	}
+	idle, err := NewClusterIdleProvider(client, time.Hour, "200m", nil)
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+	composite := NewCompositeProvider(NewStaticProvider("09:00", "17:00", "UTC", nil))
+	composite.AddOverride(idle, 10)
+
+	// Active past the static work hours
+	if isWork, err := composite.IsWorkTime(context.Background(), now); err != nil || !isWork {
+		t.Errorf("IsWorkTime() while active = %v, %v, want work time", isWork, err)
+	}
+
+	// Idle, the static work hours decide
+	if err := client.CoreV1().Pods("default").Delete(context.Background(), pod.Name, metav1.DeleteOptions{}); err != nil {
+		t.Fatalf("Failed to delete pod: %v", err)
+	}
+	for _, checkTime := range []time.Time{now.Add(time.Minute), now.Add(2 * time.Hour)} {
+		if _, err := composite.IsWorkTime(context.Background(), checkTime); err != nil {
+			t.Fatalf("IsWorkTime() error = %v", err)
+		}
+	}
+	if isWork, err := composite.IsWorkTime(context.Background(), now.Add(2*time.Hour)); err != nil || isWork {
+		t.Errorf("IsWorkTime() once idle = %v, %v, want off time of the static hours", isWork, err)
+	}
+	if isWork, err := composite.IsWorkTime(context.Background(), time.Date(2024, time.June, 4, 10, 0, 0, 0, time.UTC)); err != nil || !isWork {
+		t.Errorf("IsWorkTime() during static hours while idle = %v, %v, want work time", isWork, err)
+	}
+}
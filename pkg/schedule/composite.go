@@ -2,36 +2,284 @@ package schedule
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
+	"sort"
 	"time"
 )
 
+// CompositeMode determines how the answers of the providers are combined
+type CompositeMode string
+
+const (
+	// CompositeModeAnd considers it work time only if all providers agree
+	CompositeModeAnd CompositeMode = "and"
+	// CompositeModeOr considers it work time if any provider says so
+	CompositeModeOr CompositeMode = "or"
+	// CompositeModeQuorum considers it work time if at least Quorum providers say so
+	CompositeModeQuorum CompositeMode = "quorum"
+)
+
+// Decision is a schedule decision with the provider that made it
+type Decision struct {
+	IsWorkTime bool `json:"isWorkTime"`
+	// Provider is the provider that decided, empty if none had an opinion
+	Provider string `json:"provider,omitempty"`
+	// Reason explains why the provider decided
+	Reason string `json:"reason,omitempty"`
+	// Detail is what the provider matched, e.g. the calendar event or work hours window
+	Detail string `json:"detail,omitempty"`
+}
+
 // CompositeProvider combines multiple schedule providers.
-// It considers it work time only if ALL providers agree it's work time.
+// By default it considers it work time only if ALL providers agree it's work time.
+// Overrides are consulted first, in priority order, and the first one with an opinion decides.
 type CompositeProvider struct {
 	providers []Provider
+	overrides []prioritizedProvider
+	// Mode is how the providers are combined (default: CompositeModeAnd)
+	Mode CompositeMode
+	// Quorum is the number of providers that must agree it's work time in CompositeModeQuorum
+	Quorum int
 }
 
 // NewCompositeProvider creates a new composite provider with the given providers
 func NewCompositeProvider(providers ...Provider) *CompositeProvider {
 	return &CompositeProvider{
 		providers: providers,
+		Mode:      CompositeModeAnd,
 	}
 }
 
-// IsWorkTime returns true only if all providers agree it's work time
+// prioritizedProvider is an override with its priority
+type prioritizedProvider struct {
+	provider Provider
+	priority int
+}
+
+// AddOverride adds a provider that takes precedence over the combined providers.
+// Higher priorities are consulted first. Providers implementing Overrider only
+// decide when they have an opinion, any other provider always decides.
+func (p *CompositeProvider) AddOverride(provider Provider, priority int) {
+	p.overrides = append(p.overrides, prioritizedProvider{provider: provider, priority: priority})
+	sort.SliceStable(p.overrides, func(i, j int) bool {
+		return p.overrides[i].priority > p.overrides[j].priority
+	})
+}
+
+// IsWorkTime returns the decision of the first override with an opinion,
+// or combines the answers of all providers according to the mode
 func (p *CompositeProvider) IsWorkTime(ctx context.Context, t time.Time) (bool, error) {
+	decision, err := p.Decide(ctx, t)
+	return decision.IsWorkTime, err
+}
+
+// Decide is like IsWorkTime but also returns which provider decided and why
+func (p *CompositeProvider) Decide(ctx context.Context, t time.Time) (Decision, error) {
+	decision, _, err := p.decide(ctx, t, nil)
+	return decision, err
+}
+
+// Explain is like Decide but also returns what the deciding provider matched,
+// e.g. "ICS event '国庆节（休）' 2023-10-01→2023-10-07"
+func (p *CompositeProvider) Explain(ctx context.Context, t time.Time) (Decision, error) {
+	return p.explain(ctx, t, nil)
+}
+
+// explain is Explain with the observations of decide
+func (p *CompositeProvider) explain(ctx context.Context, t time.Time, observed *observations) (Decision, error) {
+	decision, provider, err := p.decide(ctx, t, observed)
+	if err != nil || provider == nil {
+		return decision, err
+	}
+	if decision.Detail, err = describe(ctx, provider, t); err != nil {
+		return Decision{}, err
+	}
+	return decision, nil
+}
+
+// observations are the answers of observers at the current time. Observers can't answer for any other time, and
+// asking them would disturb what they track, e.g. since when the cluster is idle, so decisions at other times reuse
+// their current answers.
+type observations struct {
+	answers map[Provider]observation
+	// reuse answers observers with their recorded answers instead of asking them, observers without one are left out
+	reuse bool
+}
+
+// observation is the answer of an observer, ok is false if it has no opinion as an override
+type observation struct {
+	isWork bool
+	ok     bool
+}
+
+// ask returns the answer of the provider at the given time, as an override if overriding. Observers are answered
+// from the observations if reused, their answers are recorded otherwise. Without observations, all providers are
+// asked.
+func (o *observations) ask(ctx context.Context, provider Provider, t time.Time, overriding bool) (bool, bool, error) {
+	observer := o != nil && isObserver(provider)
+	if observer && o.reuse {
+		answer, found := o.answers[provider]
+		return answer.isWork, found && answer.ok, nil
+	}
+
+	var isWork, ok bool
+	var err error
+	if overriding {
+		isWork, ok, err = decide(ctx, provider, t)
+	} else {
+		isWork, err = provider.IsWorkTime(ctx, t)
+		ok = err == nil
+	}
+	if observer && err == nil && o.answers != nil {
+		o.answers[provider] = observation{isWork: isWork, ok: ok}
+	}
+	return isWork, ok, err
+}
+
+// skips returns true if the provider is left out as it's an observer without a reused answer
+func (o *observations) skips(provider Provider) bool {
+	if o == nil || !o.reuse || !isObserver(provider) {
+		return false
+	}
+	_, found := o.answers[provider]
+	return !found
+}
+
+// decide returns the decision for the given time and the provider that made it, observers are asked or answered
+// as the observations tell
+func (p *CompositeProvider) decide(ctx context.Context, t time.Time, observed *observations) (Decision, Provider, error) {
+	for _, override := range p.overrides {
+		if observed.skips(override.provider) {
+			continue
+		}
+		isWork, ok, err := observed.ask(ctx, override.provider, t, true)
+		if err != nil {
+			return Decision{}, nil, err
+		}
+		if ok {
+			slog.Debug("IsWorkTime overridden", "provider", override.provider, "priority", override.priority, "isWork", isWork)
+			return Decision{
+				IsWorkTime: isWork,
+				Provider:   fmt.Sprint(override.provider),
+				Reason:     fmt.Sprintf("override with priority %d", override.priority),
+			}, override.provider, nil
+		}
+	}
+
+	providers := make([]Provider, 0, len(p.providers))
 	for _, provider := range p.providers {
-		isWork, err := provider.IsWorkTime(ctx, t)
+		if !observed.skips(provider) {
+			providers = append(providers, provider)
+		}
+	}
+
+	switch p.Mode {
+	case CompositeModeOr:
+		return countWorkTime(ctx, t, providers, 1, observed)
+	case CompositeModeQuorum:
+		return countWorkTime(ctx, t, providers, min(p.Quorum, len(providers)), observed)
+	default:
+		return countWorkTime(ctx, t, providers, len(providers), observed)
+	}
+}
+
+// countWorkTime decides it's work time once at least required providers say so and returns the provider that
+// decided it. All providers are asked, as some track state across checks, e.g. since when the cluster is idle.
+// Failed providers only fail the decision if their answer could have changed it.
+func countWorkTime(ctx context.Context, t time.Time, providers []Provider, required int, observed *observations) (Decision, Provider, error) {
+	votes, failed := 0, 0
+	var decision Decision
+	var decider Provider
+	var firstErr error
+	for i, provider := range providers {
+		isWork, _, err := observed.ask(ctx, provider, t, false)
 		if err != nil {
-			return false, err
+			slog.Debug("IsWorkTime failed", "provider", provider, "error", err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			failed++
+			continue
 		}
 		slog.Debug("IsWorkTime", "provider", provider, "isWork", isWork)
-		// If any provider says it's not work time, return false
-		if !isWork {
-			return false, nil
+		if isWork {
+			votes++
+		}
+		if decider != nil {
+			continue
+		}
+		if votes >= required {
+			decision = Decision{
+				IsWorkTime: true,
+				Provider:   fmt.Sprint(provider),
+				Reason:     fmt.Sprintf("%d of %d providers say work time, %d required", votes, len(providers), required),
+			}
+			decider = provider
+		} else if votes+failed+len(providers)-i-1 < required {
+			// Not enough providers left to reach the required votes, even if the failed ones said work time
+			decision = Decision{
+				IsWorkTime: false,
+				Provider:   fmt.Sprint(provider),
+				Reason:     fmt.Sprintf("%d of %d providers say off time, %d must say work time", i+1-votes-failed, len(providers), required),
+			}
+			decider = provider
+		}
+	}
+	if decider != nil {
+		return decision, decider, nil
+	}
+	if firstErr != nil {
+		return Decision{}, nil, firstErr
+	}
+	return Decision{
+		IsWorkTime: votes >= required,
+		Reason:     "no provider has an opinion",
+	}, nil, nil
+}
+
+// decide asks the provider for its opinion, providers that don't implement Overrider always have one
+func decide(ctx context.Context, provider Provider, t time.Time) (bool, bool, error) {
+	if overrider, ok := provider.(Overrider); ok {
+		return overrider.Override(ctx, t)
+	}
+	isWork, err := provider.IsWorkTime(ctx, t)
+	return isWork, err == nil, err
+}
+
+// all returns the overrides followed by the combined providers
+func (p *CompositeProvider) all() []Provider {
+	all := make([]Provider, 0, len(p.overrides)+len(p.providers))
+	for _, override := range p.overrides {
+		all = append(all, override.provider)
+	}
+	return append(all, p.providers...)
+}
+
+// LastSync returns the oldest last sync time of the providers that sync data,
+// or the zero time if none of them do
+func (p *CompositeProvider) LastSync() time.Time {
+	var oldest time.Time
+	for _, provider := range p.all() {
+		lastSync := provider.LastSync()
+		if lastSync.IsZero() {
+			continue
+		}
+		if oldest.IsZero() || lastSync.Before(oldest) {
+			oldest = lastSync
+		}
+	}
+	return oldest
+}
+
+// Healthy returns the errors of all unhealthy providers
+func (p *CompositeProvider) Healthy() error {
+	var errs []error
+	for _, provider := range p.all() {
+		if err := provider.Healthy(); err != nil {
+			errs = append(errs, err)
 		}
 	}
-	// All providers agree it's work time
-	return true, nil
+	return errors.Join(errs...)
 }
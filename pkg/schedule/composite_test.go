@@ -0,0 +1,224 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// fakeProvider is a provider with a fixed answer and sync state
+type fakeProvider struct {
+	isWork   bool
+	err      error
+	lastSync time.Time
+	healthy  error
+}
+
+func (p *fakeProvider) IsWorkTime(ctx context.Context, t time.Time) (bool, error) {
+	return p.isWork, p.err
+}
+
+func (p *fakeProvider) LastSync() time.Time {
+	return p.lastSync
+}
+
+func (p *fakeProvider) Healthy() error {
+	return p.healthy
+}
+
+func TestCompositeProvider_LastSync(t *testing.T) {
+	now := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		providers []Provider
+		want      time.Time
+	}{
+		{
+			name:      "No Syncing Providers",
+			providers: []Provider{&fakeProvider{}, &fakeProvider{}},
+			want:      time.Time{},
+		},
+		{
+			name: "Oldest Sync Wins",
+			providers: []Provider{
+				&fakeProvider{},
+				&fakeProvider{lastSync: now.Add(-time.Hour)},
+				&fakeProvider{lastSync: now.Add(-3 * time.Hour)},
+			},
+			want: now.Add(-3 * time.Hour),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewCompositeProvider(tt.providers...).LastSync()
+			if !got.Equal(tt.want) {
+				t.Errorf("LastSync() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompositeProvider_Healthy(t *testing.T) {
+	errQuota := errors.New("quota exhausted")
+	errSync := errors.New("sync failed")
+
+	provider := NewCompositeProvider(&fakeProvider{}, &fakeProvider{healthy: errQuota}, &fakeProvider{healthy: errSync})
+	err := provider.Healthy()
+	if !errors.Is(err, errQuota) || !errors.Is(err, errSync) {
+		t.Errorf("Healthy() = %v, want both provider errors", err)
+	}
+
+	if err := NewCompositeProvider(&fakeProvider{}).Healthy(); err != nil {
+		t.Errorf("Healthy() = %v, want nil", err)
+	}
+}
+
+func TestCompositeProvider_IsWorkTime(t *testing.T) {
+	work := &fakeProvider{isWork: true}
+	off := &fakeProvider{isWork: false}
+
+	tests := []struct {
+		name      string
+		mode      CompositeMode
+		quorum    int
+		providers []Provider
+		want      bool
+	}{
+		{name: "And All Work", mode: CompositeModeAnd, providers: []Provider{work, work}, want: true},
+		{name: "And One Off", mode: CompositeModeAnd, providers: []Provider{work, off}, want: false},
+		{name: "Or One Work", mode: CompositeModeOr, providers: []Provider{off, work}, want: true},
+		{name: "Or All Off", mode: CompositeModeOr, providers: []Provider{off, off}, want: false},
+		{name: "Quorum Reached", mode: CompositeModeQuorum, quorum: 2, providers: []Provider{work, off, work}, want: true},
+		{name: "Quorum Not Reached", mode: CompositeModeQuorum, quorum: 2, providers: []Provider{off, work, off}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := NewCompositeProvider(tt.providers...)
+			provider.Mode = tt.mode
+			provider.Quorum = tt.quorum
+
+			got, err := provider.IsWorkTime(context.Background(), time.Now())
+			if err != nil {
+				t.Fatalf("IsWorkTime() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("IsWorkTime() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// countingProvider is a provider counting how often it's asked
+type countingProvider struct {
+	fakeProvider
+	calls int
+}
+
+func (p *countingProvider) IsWorkTime(ctx context.Context, t time.Time) (bool, error) {
+	p.calls++
+	return p.fakeProvider.IsWorkTime(ctx, t)
+}
+
+func TestCompositeProvider_AsksAllProviders(t *testing.T) {
+	work := &fakeProvider{isWork: true}
+	off := &fakeProvider{isWork: false}
+	failing := &fakeProvider{err: errors.New("unavailable")}
+
+	tests := []struct {
+		name      string
+		mode      CompositeMode
+		providers []Provider
+		want      bool
+		wantErr   bool
+	}{
+		{name: "And Decided Off", mode: CompositeModeAnd, providers: []Provider{off, work}, want: false},
+		{name: "Or Decided Work", mode: CompositeModeOr, providers: []Provider{work, off}, want: true},
+		{name: "Or Decided Work Despite Failure", mode: CompositeModeOr, providers: []Provider{work, failing}, want: true},
+		{name: "And Decided Off Despite Failure", mode: CompositeModeAnd, providers: []Provider{failing, off}, want: false},
+		{name: "And Failure Decides", mode: CompositeModeAnd, providers: []Provider{work, failing}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stateful := &countingProvider{fakeProvider: fakeProvider{isWork: true}}
+			provider := NewCompositeProvider(append(tt.providers, stateful)...)
+			provider.Mode = tt.mode
+
+			got, err := provider.IsWorkTime(context.Background(), time.Now())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("IsWorkTime() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("IsWorkTime() = %v, want %v", got, tt.want)
+			}
+			if stateful.calls != 1 {
+				t.Errorf("last provider asked %d times, want 1", stateful.calls)
+			}
+		})
+	}
+}
+
+// fakeOverrider is a provider that only has an opinion when ok is set
+type fakeOverrider struct {
+	fakeProvider
+	ok bool
+}
+
+func (p *fakeOverrider) Override(ctx context.Context, t time.Time) (bool, bool, error) {
+	return p.isWork, p.ok, nil
+}
+
+func TestCompositeProvider_Overrides(t *testing.T) {
+	off := &fakeProvider{isWork: false}
+
+	tests := []struct {
+		name      string
+		overrides map[int]Provider
+		want      bool
+	}{
+		{
+			name:      "No Opinion Falls Through",
+			overrides: map[int]Provider{10: &fakeOverrider{fakeProvider: fakeProvider{isWork: true}}},
+			want:      false,
+		},
+		{
+			name:      "Override Decides",
+			overrides: map[int]Provider{10: &fakeOverrider{fakeProvider: fakeProvider{isWork: true}, ok: true}},
+			want:      true,
+		},
+		{
+			name: "Highest Priority Wins",
+			overrides: map[int]Provider{
+				5:  &fakeOverrider{fakeProvider: fakeProvider{isWork: false}, ok: true},
+				10: &fakeOverrider{fakeProvider: fakeProvider{isWork: true}, ok: true},
+			},
+			want: true,
+		},
+		{
+			name:      "Plain Provider Always Decides",
+			overrides: map[int]Provider{1: &fakeProvider{isWork: true}},
+			want:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := NewCompositeProvider(off)
+			for priority, override := range tt.overrides {
+				provider.AddOverride(override, priority)
+			}
+
+			got, err := provider.IsWorkTime(context.Background(), time.Now())
+			if err != nil {
+				t.Fatalf("IsWorkTime() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("IsWorkTime() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,308 @@
+package schedule
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// ScheduleGroupVersion is the API group and version of the Schedule custom resource
+	ScheduleGroupVersion = "bmw-saver.kezhenxu94.github.io/v1alpha1"
+)
+
+// weekdayNames maps lowercase weekday names to time.Weekday
+var weekdayNames = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
+// scheduleList is the subset of a Schedule custom resource list we need
+type scheduleList struct {
+	Items []scheduleResource `json:"items"`
+}
+
+// scheduleResource is a Schedule custom resource
+type scheduleResource struct {
+	Metadata struct {
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+	} `json:"metadata"`
+	Spec scheduleSpec `json:"spec"`
+}
+
+// scheduleSpec is the spec of a Schedule custom resource
+type scheduleSpec struct {
+	// NodePools are the node pools the Schedule applies to
+	NodePools []string         `json:"nodePools"`
+	TimeZone  string           `json:"timeZone"`
+	Windows   []scheduleWindow `json:"windows"`
+	// Exceptions are dates ("2006-01-02") that are always off or always on
+	Exceptions struct {
+		OffDates  []string `json:"offDates"`
+		WorkDates []string `json:"workDates"`
+	} `json:"exceptions"`
+}
+
+// scheduleWindow is a daily work window on the given days
+type scheduleWindow struct {
+	Days      []string `json:"days"`
+	StartTime string   `json:"startTime"`
+	EndTime   string   `json:"endTime"`
+}
+
+// CRDProvider is a schedule provider backed by Schedule custom resources,
+// so that teams can manage their own schedules with GitOps or kubectl.
+// Only the Schedules listing any node pool of the provider's schedule apply, it is work time if any of them
+// says so, and it has no opinion if none applies.
+// The Schedules are watched, they're listed from the API server until the watch is synced.
+type CRDProvider struct {
+	client    kubernetes.Interface
+	namespace string
+	nodePools map[string]bool
+	// watch is nil if the Kubernetes API isn't available
+	watch *resourceWatch
+}
+
+// NewCRDProvider creates a new provider for the Schedule resources of the node pools in the namespace, or all
+// namespaces if empty, onChange is called whenever the Schedules change if not nil
+func NewCRDProvider(client kubernetes.Interface, namespace string, nodePools []string, onChange func()) (*CRDProvider, error) {
+	provider := &CRDProvider{
+		client:    client,
+		namespace: namespace,
+		nodePools: make(map[string]bool),
+	}
+	for _, nodePool := range nodePools {
+		provider.nodePools[nodePool] = true
+	}
+
+	if restClient := client.Discovery().RESTClient(); restClient != nil {
+		watch, err := startResourceWatch(newCustomResourceInformer(restClient, provider.path()), onChange)
+		if err != nil {
+			return nil, fmt.Errorf("failed to watch Schedules: %v", err)
+		}
+		provider.watch = watch
+	}
+	return provider, nil
+}
+
+// IsWorkTime returns true if any Schedule of the node pools says it's work time, or if there are no Schedules
+func (p *CRDProvider) IsWorkTime(ctx context.Context, t time.Time) (bool, error) {
+	isWork, ok, err := p.Override(ctx, t)
+	if err != nil || ok {
+		return isWork, err
+	}
+	return true, nil
+}
+
+// Override returns whether any Schedule of the node pools says it's work time, ok is false if there are no
+// Schedules of the node pools
+func (p *CRDProvider) Override(ctx context.Context, t time.Time) (bool, bool, error) {
+	schedules, err := p.list(ctx)
+	if err != nil {
+		return false, false, err
+	}
+
+	ok := false
+	for _, schedule := range schedules {
+		if !p.applies(schedule) {
+			continue
+		}
+		ok = true
+		isWork, err := schedule.isWorkTime(ctx, t)
+		if err != nil {
+			slog.Warn("Ignoring invalid Schedule",
+				"schedule", schedule.Metadata.Namespace+"/"+schedule.Metadata.Name,
+				"error", err,
+			)
+			continue
+		}
+		if isWork {
+			slog.Debug("Schedule says it's work time", "schedule", schedule.Metadata.Namespace+"/"+schedule.Metadata.Name)
+			return true, true, nil
+		}
+	}
+	return false, ok, nil
+}
+
+// applies returns whether the Schedule lists any node pool of the provider
+func (p *CRDProvider) applies(schedule scheduleResource) bool {
+	for _, nodePool := range schedule.Spec.NodePools {
+		if p.nodePools[nodePool] {
+			return true
+		}
+	}
+	return false
+}
+
+// path returns the API path of the Schedule resources
+func (p *CRDProvider) path() string {
+	if p.namespace != "" {
+		return "/apis/" + ScheduleGroupVersion + "/namespaces/" + p.namespace + "/schedules"
+	}
+	return "/apis/" + ScheduleGroupVersion + "/schedules"
+}
+
+// list returns all Schedule resources, from the cache once the watch is synced
+func (p *CRDProvider) list(ctx context.Context) ([]scheduleResource, error) {
+	if p.watch != nil && p.watch.synced() {
+		var schedules []scheduleResource
+		for _, object := range p.watch.informer.GetStore().List() {
+			data, err := object.(*unstructured.Unstructured).MarshalJSON()
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal Schedule: %v", err)
+			}
+			var schedule scheduleResource
+			if err := json.Unmarshal(data, &schedule); err != nil {
+				return nil, fmt.Errorf("failed to parse Schedule: %v", err)
+			}
+			schedules = append(schedules, schedule)
+		}
+		return schedules, nil
+	}
+
+	restClient := p.client.Discovery().RESTClient()
+	if restClient == nil {
+		return nil, fmt.Errorf("kubernetes API is not available")
+	}
+
+	data, err := restClient.Get().AbsPath(p.path()).DoRaw(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Schedules: %v", err)
+	}
+
+	var list scheduleList
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse Schedules: %v", err)
+	}
+	return list.Items, nil
+}
+
+// isWorkTime evaluates the Schedule, each window is evaluated like a static schedule
+func (s scheduleResource) isWorkTime(ctx context.Context, t time.Time) (bool, error) {
+	timeZone := s.Spec.TimeZone
+	if timeZone == "" {
+		timeZone = "UTC"
+	}
+
+	offDates := make(map[string]bool)
+	for _, date := range s.Spec.Exceptions.OffDates {
+		offDates[date] = true
+	}
+	workDates := make(map[string]bool)
+	for _, date := range s.Spec.Exceptions.WorkDates {
+		workDates[date] = true
+	}
+
+	// Without windows, only the exceptions apply
+	windows := s.Spec.Windows
+	if len(windows) == 0 {
+		windows = []scheduleWindow{{StartTime: "00:00", EndTime: "00:00"}}
+	}
+
+	for _, window := range windows {
+		// Windows without days apply Monday to Friday
+		var workDays map[time.Weekday]bool
+		if len(window.Days) > 0 || len(s.Spec.Windows) == 0 {
+			workDays = make(map[time.Weekday]bool)
+		}
+		for _, day := range window.Days {
+			weekday, ok := weekdayNames[strings.ToLower(day)]
+			if !ok {
+				return false, fmt.Errorf("invalid weekday %q", day)
+			}
+			workDays[weekday] = true
+		}
+
+		static := NewStaticProvider(window.StartTime, window.EndTime, timeZone, workDays)
+		static.OffDates = offDates
+		static.WorkDates = workDates
+
+		isWork, err := static.IsWorkTime(ctx, t)
+		if err != nil {
+			return false, err
+		}
+		if isWork {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ValidateSchedule validates a Schedule resource in JSON, e.g. in an admission webhook,
+// so that invalid Schedules are rejected instead of being ignored when they are evaluated
+func ValidateSchedule(data []byte) error {
+	var schedule scheduleResource
+	if err := json.Unmarshal(data, &schedule); err != nil {
+		return fmt.Errorf("failed to parse Schedule: %v", err)
+	}
+
+	if len(schedule.Spec.NodePools) == 0 {
+		return fmt.Errorf("node pools are required")
+	}
+	if schedule.Spec.TimeZone != "" {
+		if _, err := time.LoadLocation(schedule.Spec.TimeZone); err != nil {
+			return fmt.Errorf("invalid time zone %q: %v", schedule.Spec.TimeZone, err)
+		}
+	}
+	for i, window := range schedule.Spec.Windows {
+		for _, day := range window.Days {
+			if _, ok := weekdayNames[strings.ToLower(day)]; !ok {
+				return fmt.Errorf("invalid weekday %q in window %d", day, i)
+			}
+		}
+		if _, err := time.Parse("15:04", window.StartTime); err != nil {
+			return fmt.Errorf("invalid start time %q in window %d", window.StartTime, i)
+		}
+		if _, err := time.Parse("15:04", window.EndTime); err != nil {
+			return fmt.Errorf("invalid end time %q in window %d", window.EndTime, i)
+		}
+	}
+	for _, dates := range [][]string{schedule.Spec.Exceptions.OffDates, schedule.Spec.Exceptions.WorkDates} {
+		for _, date := range dates {
+			if _, err := time.Parse("2006-01-02", date); err != nil {
+				return fmt.Errorf("invalid exception date %q", date)
+			}
+		}
+	}
+	return nil
+}
+
+// LastSync returns the zero time, the Schedules are watched rather than synced
+func (p *CRDProvider) LastSync() time.Time {
+	return time.Time{}
+}
+
+// Close stops watching the Schedules
+func (p *CRDProvider) Close(ctx context.Context) {
+	if p.watch != nil {
+		p.watch.stop()
+	}
+}
+
+// Healthy always returns nil, API errors are returned by IsWorkTime
+func (p *CRDProvider) Healthy() error {
+	return nil
+}
+
+// String returns a string representation of the CRDProvider
+func (p *CRDProvider) String() string {
+	nodePools := make([]string, 0, len(p.nodePools))
+	for nodePool := range p.nodePools {
+		nodePools = append(nodePools, nodePool)
+	}
+	sort.Strings(nodePools)
+	return fmt.Sprintf("CRDProvider{namespace: %q, nodePools: %v}", p.namespace, nodePools)
+}
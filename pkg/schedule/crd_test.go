@@ -0,0 +1,178 @@
+package schedule
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+func TestScheduleResource_IsWorkTime(t *testing.T) {
+	location, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatalf("Failed to load location: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		spec      string
+		checkTime time.Time
+		want      bool
+		wantErr   bool
+	}{
+		{
+			name:      "Within Window",
+			spec:      `{"timeZone": "Europe/Berlin", "windows": [{"days": ["Monday"], "startTime": "09:00", "endTime": "17:00"}]}`,
+			checkTime: time.Date(2024, time.June, 3, 10, 0, 0, 0, location),
+			want:      true,
+		},
+		{
+			name:      "Outside Window Days",
+			spec:      `{"timeZone": "Europe/Berlin", "windows": [{"days": ["monday"], "startTime": "09:00", "endTime": "17:00"}]}`,
+			checkTime: time.Date(2024, time.June, 4, 10, 0, 0, 0, location),
+			want:      false,
+		},
+		{
+			name: "Second Window",
+			spec: `{"timeZone": "Europe/Berlin", "windows": [
+				{"days": ["monday"], "startTime": "09:00", "endTime": "12:00"},
+				{"days": ["monday"], "startTime": "20:00", "endTime": "23:00"}]}`,
+			checkTime: time.Date(2024, time.June, 3, 21, 0, 0, 0, location),
+			want:      true,
+		},
+		{
+			name:      "Default Days",
+			spec:      `{"timeZone": "Europe/Berlin", "windows": [{"startTime": "09:00", "endTime": "17:00"}]}`,
+			checkTime: time.Date(2024, time.June, 8, 10, 0, 0, 0, location),
+			want:      false,
+		},
+		{
+			name:      "Off Date",
+			spec:      `{"timeZone": "Europe/Berlin", "windows": [{"startTime": "09:00", "endTime": "17:00"}], "exceptions": {"offDates": ["2024-06-03"]}}`,
+			checkTime: time.Date(2024, time.June, 3, 10, 0, 0, 0, location),
+			want:      false,
+		},
+		{
+			name:      "Work Date Without Windows",
+			spec:      `{"timeZone": "Europe/Berlin", "exceptions": {"workDates": ["2024-06-08"]}}`,
+			checkTime: time.Date(2024, time.June, 8, 10, 0, 0, 0, location),
+			want:      true,
+		},
+		{
+			name:      "Invalid Weekday",
+			spec:      `{"windows": [{"days": ["someday"], "startTime": "09:00", "endTime": "17:00"}]}`,
+			checkTime: time.Date(2024, time.June, 3, 10, 0, 0, 0, location),
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var schedule scheduleResource
+			if err := json.Unmarshal([]byte(tt.spec), &schedule.Spec); err != nil {
+				t.Fatalf("Failed to parse spec: %v", err)
+			}
+
+			got, err := schedule.isWorkTime(context.Background(), tt.checkTime)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("isWorkTime() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("isWorkTime() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// scheduleObject returns a Schedule resource of the node pool working from 09:00 to end on weekdays
+func scheduleObject(name, nodePool, end, resourceVersion string) map[string]interface{} {
+	return map[string]interface{}{
+		"apiVersion": ScheduleGroupVersion,
+		"kind":       "Schedule",
+		"metadata":   map[string]interface{}{"name": name, "namespace": "team", "resourceVersion": resourceVersion},
+		"spec": map[string]interface{}{
+			"nodePools": []string{nodePool},
+			"windows":   []interface{}{map[string]interface{}{"startTime": "09:00", "endTime": end}},
+		},
+	}
+}
+
+func TestCRDProvider_Override(t *testing.T) {
+	events := make(chan map[string]interface{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("watch") != "true" {
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"apiVersion": ScheduleGroupVersion,
+				"kind":       "ScheduleList",
+				"metadata":   map[string]interface{}{"resourceVersion": "1"},
+				"items": []interface{}{
+					scheduleObject("team-a", "team-a-pool", "17:00", "1"),
+					// Another team's Schedule doesn't keep the node pool up
+					scheduleObject("team-b", "team-b-pool", "23:00", "1"),
+				},
+			})
+			return
+		}
+		w.(http.Flusher).Flush()
+		for {
+			select {
+			case event := <-events:
+				_ = json.NewEncoder(w).Encode(event)
+				w.(http.Flusher).Flush()
+			case <-r.Context().Done():
+				return
+			}
+		}
+	}))
+	defer server.Close()
+	client := kubernetes.NewForConfigOrDie(&rest.Config{Host: server.URL})
+
+	changed := make(chan struct{}, 1)
+	provider, err := NewCRDProvider(client, "", []string{"team-a-pool"}, func() {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	})
+	if err != nil {
+		t.Fatalf("NewCRDProvider() error = %v", err)
+	}
+	defer provider.Close(context.Background())
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !provider.watch.synced() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Schedule watch not synced")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	monday := time.Date(2024, time.June, 3, 20, 0, 0, 0, time.UTC)
+	if isWork, ok, err := provider.Override(context.Background(), monday); err != nil || !ok || isWork {
+		t.Errorf("Override() = %v, %v, %v, want off time by the node pool's Schedule only", isWork, ok, err)
+	}
+	other, err := NewCRDProvider(client, "", []string{"other-pool"}, nil)
+	if err != nil {
+		t.Fatalf("NewCRDProvider() error = %v", err)
+	}
+	defer other.Close(context.Background())
+	if _, ok, err := other.Override(context.Background(), monday); err != nil || ok {
+		t.Errorf("Override() without Schedules of the node pool = %v, %v, want no opinion", ok, err)
+	}
+
+	events <- map[string]interface{}{"type": "MODIFIED", "object": scheduleObject("team-a", "team-a-pool", "22:00", "2")}
+	select {
+	case <-changed:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("onChange not called after the Schedule was modified")
+	}
+	if isWork, ok, err := provider.Override(context.Background(), monday); err != nil || !ok || !isWork {
+		t.Errorf("Override() = %v, %v, %v, want work time by the modified Schedule", isWork, ok, err)
+	}
+}
@@ -0,0 +1,146 @@
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Twilight determines how far below the horizon the sun may be while it still counts as daylight
+type Twilight string
+
+const (
+	// TwilightNone counts daylight from sunrise to sunset
+	TwilightNone Twilight = "none"
+	// TwilightCivil counts daylight from civil dawn to civil dusk, the sun is at most 6° below the horizon
+	TwilightCivil Twilight = "civil"
+	// TwilightNautical counts daylight from nautical dawn to nautical dusk, the sun is at most 12° below the horizon
+	TwilightNautical Twilight = "nautical"
+)
+
+// twilightElevations is the elevation of the sun's center in degrees at the start and end of daylight,
+// sunrise and sunset account for atmospheric refraction and the solar disc
+var twilightElevations = map[Twilight]float64{
+	TwilightNone:     -0.833,
+	TwilightCivil:    -6,
+	TwilightNautical: -12,
+}
+
+const (
+	// julianUnixEpoch is the Julian date of the Unix epoch
+	julianUnixEpoch = 2440587.5
+	// julian2000 is the Julian date of the J2000 epoch
+	julian2000 = 2451545.0
+)
+
+// DaylightProvider is a schedule provider that follows daylight at a location,
+// for clusters whose usage tracks daylight, e.g. demo and solar-powered edge sites.
+// It is work time between dawn and dusk.
+type DaylightProvider struct {
+	latitude  float64
+	longitude float64
+	twilight  Twilight
+	elevation float64
+}
+
+// NewDaylightProvider creates a new daylight provider for the location in degrees, east and north are positive
+func NewDaylightProvider(latitude, longitude float64, twilight Twilight) (*DaylightProvider, error) {
+	if latitude < -90 || latitude > 90 {
+		return nil, fmt.Errorf("invalid latitude %v", latitude)
+	}
+	if longitude < -180 || longitude > 180 {
+		return nil, fmt.Errorf("invalid longitude %v", longitude)
+	}
+	elevation, ok := twilightElevations[twilight]
+	if !ok {
+		return nil, fmt.Errorf("unsupported twilight: %s", twilight)
+	}
+
+	return &DaylightProvider{
+		latitude:  latitude,
+		longitude: longitude,
+		twilight:  twilight,
+		elevation: elevation,
+	}, nil
+}
+
+// IsWorkTime returns true between dawn and dusk, or all day during polar day
+func (p *DaylightProvider) IsWorkTime(ctx context.Context, t time.Time) (bool, error) {
+	dawn, dusk, polar := p.daylight(t)
+	if polar != nil {
+		return *polar, nil
+	}
+	return !t.Before(dawn) && t.Before(dusk), nil
+}
+
+// Describe returns dawn and dusk of the day containing t, or whether it is polar day or night
+func (p *DaylightProvider) Describe(ctx context.Context, t time.Time) (string, error) {
+	dawn, dusk, polar := p.daylight(t)
+	if polar != nil {
+		if *polar {
+			return "polar day", nil
+		}
+		return "polar night", nil
+	}
+	return fmt.Sprintf("daylight %s", formatRange(dawn, dusk, time.UTC)), nil
+}
+
+// daylight returns dawn and dusk of the local solar day containing t using the sunrise equation.
+// During polar day or night there is no dawn or dusk, polar is then whether the sun stays up.
+func (p *DaylightProvider) daylight(t time.Time) (time.Time, time.Time, *bool) {
+	// Local solar day, shifted from UTC by the longitude
+	solar := t.UTC().Add(time.Duration(p.longitude / 15 * float64(time.Hour)))
+	noon := time.Date(solar.Year(), solar.Month(), solar.Day(), 12, 0, 0, 0, time.UTC)
+	n := math.Round(float64(noon.Unix())/86400 + julianUnixEpoch - julian2000)
+
+	meanSolarTime := n - p.longitude/360
+	anomaly := math.Mod(357.5291+0.98560028*meanSolarTime, 360)
+	center := 1.9148*sin(anomaly) + 0.0200*sin(2*anomaly) + 0.0003*sin(3*anomaly)
+	eclipticLongitude := math.Mod(anomaly+center+180+102.9372, 360)
+	transit := julian2000 + meanSolarTime + 0.0053*sin(anomaly) - 0.0069*sin(2*eclipticLongitude)
+	declination := math.Asin(sin(eclipticLongitude) * sin(23.4397))
+
+	cosHourAngle := (sin(p.elevation) - sin(p.latitude)*math.Sin(declination)) /
+		(cos(p.latitude) * math.Cos(declination))
+	if cosHourAngle < -1 || cosHourAngle > 1 {
+		sunUp := cosHourAngle < -1
+		return time.Time{}, time.Time{}, &sunUp
+	}
+
+	hourAngle := math.Acos(cosHourAngle) * 180 / math.Pi
+	return fromJulian(transit - hourAngle/360), fromJulian(transit + hourAngle/360), nil
+}
+
+// sin is math.Sin for degrees
+func sin(degrees float64) float64 {
+	return math.Sin(degrees * math.Pi / 180)
+}
+
+// cos is math.Cos for degrees
+func cos(degrees float64) float64 {
+	return math.Cos(degrees * math.Pi / 180)
+}
+
+// fromJulian converts a Julian date to time
+func fromJulian(julian float64) time.Time {
+	return time.Unix(0, int64((julian-julianUnixEpoch)*86400*float64(time.Second))).UTC()
+}
+
+// LastSync returns the zero time, daylight is computed on every check
+func (p *DaylightProvider) LastSync() time.Time {
+	return time.Time{}
+}
+
+// Healthy always returns nil
+func (p *DaylightProvider) Healthy() error {
+	return nil
+}
+
+// String returns a string representation of the DaylightProvider
+func (p *DaylightProvider) String() string {
+	return fmt.Sprintf("DaylightProvider{latitude: %v, longitude: %v, twilight: %s}",
+		p.latitude,
+		p.longitude,
+		p.twilight)
+}
@@ -0,0 +1,111 @@
+package schedule
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestDaylightProvider_IsWorkTime(t *testing.T) {
+	tests := []struct {
+		name      string
+		latitude  float64
+		longitude float64
+		twilight  Twilight
+		checkTime time.Time
+		want      bool
+	}{
+		{
+			name:      "Berlin Midday",
+			latitude:  52.52,
+			longitude: 13.405,
+			twilight:  TwilightCivil,
+			checkTime: time.Date(2024, time.June, 21, 12, 0, 0, 0, time.UTC),
+			want:      true,
+		},
+		{
+			// Civil dawn is around 01:53 UTC
+			name:      "Berlin Before Civil Dawn",
+			latitude:  52.52,
+			longitude: 13.405,
+			twilight:  TwilightCivil,
+			checkTime: time.Date(2024, time.June, 21, 1, 30, 0, 0, time.UTC),
+			want:      false,
+		},
+		{
+			// Sunrise is around 02:43 UTC
+			name:      "Berlin Civil Twilight",
+			latitude:  52.52,
+			longitude: 13.405,
+			twilight:  TwilightCivil,
+			checkTime: time.Date(2024, time.June, 21, 2, 15, 0, 0, time.UTC),
+			want:      true,
+		},
+		{
+			name:      "Berlin Before Sunrise",
+			latitude:  52.52,
+			longitude: 13.405,
+			twilight:  TwilightNone,
+			checkTime: time.Date(2024, time.June, 21, 2, 15, 0, 0, time.UTC),
+			want:      false,
+		},
+		{
+			// Civil dusk is around 20:23 UTC
+			name:      "Berlin After Civil Dusk",
+			latitude:  52.52,
+			longitude: 13.405,
+			twilight:  TwilightCivil,
+			checkTime: time.Date(2024, time.June, 21, 20, 45, 0, 0, time.UTC),
+			want:      false,
+		},
+		{
+			name:      "Western Longitude Evening",
+			latitude:  37.77,
+			longitude: -122.42,
+			twilight:  TwilightCivil,
+			checkTime: time.Date(2024, time.June, 22, 2, 0, 0, 0, time.UTC), // 19:00 in San Francisco
+			want:      true,
+		},
+		{
+			name:      "Polar Day",
+			latitude:  69.65,
+			longitude: 18.96,
+			twilight:  TwilightCivil,
+			checkTime: time.Date(2024, time.June, 21, 23, 0, 0, 0, time.UTC),
+			want:      true,
+		},
+		{
+			name:      "Polar Night",
+			latitude:  78.22,
+			longitude: 15.65,
+			twilight:  TwilightCivil,
+			checkTime: time.Date(2024, time.December, 21, 11, 0, 0, 0, time.UTC),
+			want:      false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider, err := NewDaylightProvider(tt.latitude, tt.longitude, tt.twilight)
+			if err != nil {
+				t.Fatalf("NewDaylightProvider() error = %v", err)
+			}
+			got, err := provider.IsWorkTime(context.Background(), tt.checkTime)
+			if err != nil {
+				t.Fatalf("IsWorkTime() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("IsWorkTime() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewDaylightProvider_Invalid(t *testing.T) {
+	if _, err := NewDaylightProvider(91, 0, TwilightCivil); err == nil {
+		t.Error("NewDaylightProvider() error = nil, want error for invalid latitude")
+	}
+	if _, err := NewDaylightProvider(0, 0, "astronomical"); err == nil {
+		t.Error("NewDaylightProvider() error = nil, want error for unsupported twilight")
+	}
+}
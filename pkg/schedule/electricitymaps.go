@@ -0,0 +1,73 @@
+package schedule
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// electricityMapsAPIURL is the base URL of the ElectricityMaps API
+const electricityMapsAPIURL = "https://api.electricitymap.org"
+
+// electricityMapsSource forecasts the carbon intensity of a zone with ElectricityMaps
+type electricityMapsSource struct {
+	client   httpClient
+	baseURL  string
+	apiToken string
+	zone     string
+}
+
+// electricityMapsForecastResponse is the subset of the ElectricityMaps forecast response we need
+type electricityMapsForecastResponse struct {
+	Forecast []struct {
+		CarbonIntensity float64   `json:"carbonIntensity"`
+		Datetime        time.Time `json:"datetime"`
+	} `json:"forecast"`
+}
+
+// newElectricityMapsSource creates a new ElectricityMaps source authenticated with the API token
+func newElectricityMapsSource(baseURL, apiToken, zone string) *electricityMapsSource {
+	return &electricityMapsSource{
+		client:   &http.Client{Timeout: 30 * time.Second},
+		baseURL:  baseURL,
+		apiToken: apiToken,
+		zone:     zone,
+	}
+}
+
+// forecast returns the hourly carbon intensity forecast of the zone
+func (s *electricityMapsSource) forecast(ctx context.Context) ([]carbonIntensity, error) {
+	params := url.Values{}
+	params.Set("zone", s.zone)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.baseURL+"/v3/carbon-intensity/forecast?"+params.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create ElectricityMaps request: %v", err)
+	}
+	req.Header.Set("auth-token", s.apiToken)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ElectricityMaps forecast: %v", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("failed to get ElectricityMaps forecast: status %d: %s", resp.StatusCode, body)
+	}
+
+	var forecast electricityMapsForecastResponse
+	if err := json.NewDecoder(resp.Body).Decode(&forecast); err != nil {
+		return nil, fmt.Errorf("failed to decode ElectricityMaps forecast: %v", err)
+	}
+	intensities := make([]carbonIntensity, 0, len(forecast.Forecast))
+	for _, point := range forecast.Forecast {
+		intensities = append(intensities, carbonIntensity{At: point.Datetime, Intensity: point.CarbonIntensity})
+	}
+	return intensities, nil
+}
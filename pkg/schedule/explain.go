@@ -0,0 +1,98 @@
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Describer is implemented by providers that can tell what matched at a given time,
+// e.g. the calendar event or the work hours window
+type Describer interface {
+	// Describe returns what the decision for the given time is based on, empty if nothing specific matched
+	Describe(ctx context.Context, t time.Time) (string, error)
+}
+
+// Explain returns the decision of the provider at the given time, with the provider that made it
+// and what matched, so that users can debug why nodes were (not) scaled
+func Explain(ctx context.Context, provider Provider, t time.Time) (Decision, error) {
+	if composite, ok := provider.(*CompositeProvider); ok {
+		return composite.Explain(ctx, t)
+	}
+	isWork, err := provider.IsWorkTime(ctx, t)
+	if err != nil {
+		return Decision{}, err
+	}
+	decision := Decision{IsWorkTime: isWork, Provider: fmt.Sprint(provider)}
+	if decision.Detail, err = describe(ctx, provider, t); err != nil {
+		return Decision{}, err
+	}
+	return decision, nil
+}
+
+// ExplainAhead is like Explain at now, and also returns the decision at ahead, e.g. to act on work time ahead of it.
+// Observed activity can't be predicted, so observers keep their answers at now for the decision at ahead.
+func ExplainAhead(ctx context.Context, provider Provider, now, ahead time.Time) (Decision, Decision, error) {
+	if composite, ok := provider.(*CompositeProvider); ok {
+		observed := &observations{answers: make(map[Provider]observation)}
+		current, err := composite.explain(ctx, now, observed)
+		if err != nil {
+			return Decision{}, Decision{}, err
+		}
+		observed.reuse = true
+		later, err := composite.explain(ctx, ahead, observed)
+		if err != nil {
+			return Decision{}, Decision{}, err
+		}
+		return current, later, nil
+	}
+
+	current, err := Explain(ctx, provider, now)
+	if err != nil || isObserver(provider) {
+		return current, current, err
+	}
+	later, err := Explain(ctx, provider, ahead)
+	if err != nil {
+		return Decision{}, Decision{}, err
+	}
+	return current, later, nil
+}
+
+// ExplainUnobserved is like Explain for other times than now, e.g. to explain a past decision. Observers can only
+// answer for now and asking them would disturb what they track, so they are left out of composite providers.
+func ExplainUnobserved(ctx context.Context, provider Provider, t time.Time) (Decision, error) {
+	if isObserver(provider) {
+		return Decision{}, fmt.Errorf("provider %v decides based on observed activity and can only explain now", provider)
+	}
+	if composite, ok := provider.(*CompositeProvider); ok {
+		return composite.explain(ctx, t, &observations{reuse: true})
+	}
+	return Explain(ctx, provider, t)
+}
+
+// describe returns what the provider matched at the given time, if it can tell
+func describe(ctx context.Context, provider Provider, t time.Time) (string, error) {
+	describer, ok := provider.(Describer)
+	if !ok {
+		return "", nil
+	}
+	return describer.Describe(ctx, t)
+}
+
+// formatRange formats an event time range, whole days are shown as inclusive dates
+func formatRange(start, end time.Time, location *time.Location) string {
+	start, end = start.In(location), end.In(location)
+	if isMidnight(start) && isMidnight(end) {
+		last := end.AddDate(0, 0, -1)
+		if !last.After(start) {
+			return start.Format("2006-01-02")
+		}
+		return start.Format("2006-01-02") + "→" + last.Format("2006-01-02")
+	}
+	return start.Format("2006-01-02 15:04") + "→" + end.Format("2006-01-02 15:04")
+}
+
+// isMidnight returns true if the time is at the start of a day
+func isMidnight(t time.Time) bool {
+	return t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0 && t.Nanosecond() == 0
+}
@@ -0,0 +1,177 @@
+package schedule
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCompositeProvider_Explain(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(cnZhIcs)
+	}))
+	defer server.Close()
+
+	location, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Fatalf("Failed to load location: %v", err)
+	}
+
+	calendar, err := NewICSCalendarProvider(ICSCalendarOptions{
+		URLs:            []string{server.URL},
+		SyncInterval:    time.Hour,
+		WorkDayPatterns: []string{".*（班）"},
+		HolidayPatterns: []string{".*（休）"},
+		Location:        location,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+	static := NewStaticProvider("09:00", "18:00", "Asia/Shanghai", nil)
+
+	composite := NewCompositeProvider(static)
+	composite.AddOverride(calendar, 10)
+
+	tests := []struct {
+		name       string
+		checkTime  time.Time
+		wantWork   bool
+		wantDetail string
+	}{
+		{
+			name:       "Holiday Event",
+			checkTime:  time.Date(2023, time.October, 3, 10, 0, 0, 0, location),
+			wantWork:   false,
+			wantDetail: "ICS event '国庆节（休）' 2023-09-30→2023-10-06",
+		},
+		{
+			name:       "Work Hours",
+			checkTime:  time.Date(2023, time.October, 10, 10, 0, 0, 0, location),
+			wantWork:   true,
+			wantDetail: "within Tuesday work hours 09:00-18:00",
+		},
+		{
+			name:       "Outside Work Hours",
+			checkTime:  time.Date(2023, time.October, 10, 20, 0, 0, 0, location),
+			wantWork:   false,
+			wantDetail: "outside Tuesday work hours 09:00-18:00",
+		},
+		{
+			name:       "Weekend",
+			checkTime:  time.Date(2023, time.October, 14, 10, 0, 0, 0, location),
+			wantWork:   false,
+			wantDetail: "Saturday is not a work day",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Explain(context.Background(), composite, tt.checkTime)
+			if err != nil {
+				t.Fatalf("Explain() error = %v", err)
+			}
+			if got.IsWorkTime != tt.wantWork {
+				t.Errorf("Explain().IsWorkTime = %v, want %v", got.IsWorkTime, tt.wantWork)
+			}
+			if got.Detail != tt.wantDetail {
+				t.Errorf("Explain().Detail = %q, want %q", got.Detail, tt.wantDetail)
+			}
+		})
+	}
+}
+
+func TestFormatRange(t *testing.T) {
+	tests := []struct {
+		name  string
+		start time.Time
+		end   time.Time
+		want  string
+	}{
+		{
+			name:  "Single Day",
+			start: time.Date(2023, time.April, 5, 0, 0, 0, 0, time.UTC),
+			end:   time.Date(2023, time.April, 6, 0, 0, 0, 0, time.UTC),
+			want:  "2023-04-05",
+		},
+		{
+			name:  "Multiple Days",
+			start: time.Date(2023, time.April, 29, 0, 0, 0, 0, time.UTC),
+			end:   time.Date(2023, time.May, 4, 0, 0, 0, 0, time.UTC),
+			want:  "2023-04-29→2023-05-03",
+		},
+		{
+			name:  "Timed",
+			start: time.Date(2023, time.April, 5, 9, 0, 0, 0, time.UTC),
+			end:   time.Date(2023, time.April, 5, 17, 30, 0, 0, time.UTC),
+			want:  "2023-04-05 09:00→2023-04-05 17:30",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatRange(tt.start, tt.end, time.UTC); got != tt.want {
+				t.Errorf("formatRange() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// recordingObserver is a fake observer with a fixed answer, recording when it's asked
+type recordingObserver struct {
+	observingProvider
+	isWork bool
+	asked  []time.Time
+}
+
+func (p *recordingObserver) IsWorkTime(ctx context.Context, t time.Time) (bool, error) {
+	p.asked = append(p.asked, t)
+	return p.isWork, nil
+}
+
+func TestExplainAhead(t *testing.T) {
+	now := time.Date(2024, time.June, 4, 8, 30, 0, 0, time.UTC)
+	ahead := now.Add(time.Hour)
+
+	for _, active := range []bool{true, false} {
+		observer := &recordingObserver{isWork: active}
+		composite := NewCompositeProvider(NewStaticProvider("09:00", "17:00", "UTC", nil), observer)
+
+		current, later, err := ExplainAhead(context.Background(), composite, now, ahead)
+		if err != nil {
+			t.Fatalf("ExplainAhead() error = %v", err)
+		}
+		if current.IsWorkTime {
+			t.Errorf("ExplainAhead() current = work time, want off time before work hours")
+		}
+		// The observer keeps its current answer for the decision ahead
+		if later.IsWorkTime != active {
+			t.Errorf("ExplainAhead() ahead = %v, want %v with active %v", later.IsWorkTime, active, active)
+		}
+		if len(observer.asked) != 1 || !observer.asked[0].Equal(now) {
+			t.Errorf("observer asked at %v, want only at %v", observer.asked, now)
+		}
+	}
+}
+
+func TestExplainUnobserved(t *testing.T) {
+	at := time.Date(2024, time.June, 4, 10, 0, 0, 0, time.UTC)
+	observer := &recordingObserver{isWork: false}
+	composite := NewCompositeProvider(NewStaticProvider("09:00", "17:00", "UTC", nil), observer)
+
+	decision, err := ExplainUnobserved(context.Background(), composite, at)
+	if err != nil {
+		t.Fatalf("ExplainUnobserved() error = %v", err)
+	}
+	// The observer is left out, so the work hours decide
+	if !decision.IsWorkTime {
+		t.Errorf("ExplainUnobserved() = off time, want work time within work hours")
+	}
+	if len(observer.asked) != 0 {
+		t.Errorf("observer asked at %v, want it left out", observer.asked)
+	}
+	if _, err := ExplainUnobserved(context.Background(), observer, at); err == nil {
+		t.Errorf("ExplainUnobserved() of an observer error = nil, want error")
+	}
+}
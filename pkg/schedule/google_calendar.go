@@ -2,7 +2,10 @@ package schedule
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
 	"path/filepath"
 	"sync"
@@ -12,20 +15,31 @@ import (
 
 	"golang.org/x/oauth2/google"
 	"google.golang.org/api/calendar/v3"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/impersonate"
 	"google.golang.org/api/option"
+	"k8s.io/apimachinery/pkg/util/wait"
 )
 
 type cachedEvent struct {
-	Start time.Time
-	End   time.Time
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
 }
 
 type eventCache struct {
 	events    map[string][]cachedEvent // date string -> events
+	absences  map[string][]cachedEvent // attendee -> out-of-office events
 	lastSync  time.Time
 	syncMutex sync.RWMutex
 }
 
+// eventCacheSnapshot is the persisted form of the event cache
+type eventCacheSnapshot struct {
+	LastSync time.Time                `json:"lastSync"`
+	Events   map[string][]cachedEvent `json:"events"`
+	Absences map[string][]cachedEvent `json:"absences"`
+}
+
 // GoogleCalendarProvider is a schedule provider that uses Google Calendar
 type GoogleCalendarProvider struct {
 	service       *calendar.Service
@@ -35,51 +49,164 @@ type GoogleCalendarProvider struct {
 	// Configurable settings
 	syncInterval time.Duration // How often to refresh the cache
 	cacheDays    int           // How many days of events to cache
+	// Attendees whose out-of-office events mark off time
+	outOfOfficeAttendees []string
+	// Working location types (e.g. "homeOffice") that count as away for the attendees
+	offTimeWorkingLocations map[string]bool
+	// quotaErr is the last quota error, cleared by the next successful API call
+	quotaErr error
+	// syncErr is the error of the last sync, nil if it succeeded
+	syncErr  error
+	store    CacheStore
+	cacheKey string
+	// stop stops the background sync
+	stop context.CancelFunc
+}
+
+// GoogleCalendarOptions contains the settings for creating a GoogleCalendarProvider
+type GoogleCalendarOptions struct {
+	// CredentialsPath is the absolute path of the service account credentials file,
+	// Application Default Credentials are used if empty
+	CredentialsPath string
+	// Subject is the user impersonated through domain-wide delegation, if set
+	Subject string
+	// ServiceAccount is the service account email used for domain-wide delegation
+	// with Application Default Credentials
+	ServiceAccount string
+	// CalendarID is the calendar searched for off-time events
+	CalendarID string
+	// OffTimeEvents is the search query for off-time events
+	OffTimeEvents string
+	// SyncInterval is how often to refresh the cache
+	SyncInterval time.Duration
+	// CacheDays is how many days of events to cache
+	CacheDays int
+	// OutOfOfficeAttendees are the users whose native out-of-office events mark off time.
+	// It is off time only when all of them are out of office.
+	OutOfOfficeAttendees []string
+	// OffTimeWorkingLocations are working location types ("homeOffice", "officeLocation",
+	// "customLocation") that count as out of office for the attendees
+	OffTimeWorkingLocations []string
+	// CacheStore persists the event cache across restarts, optional
+	CacheStore CacheStore
 }
 
 // NewGoogleCalendarProvider creates a new GoogleCalendarProvider
-func NewGoogleCalendarProvider(credentialsPath, calendarID string, offTimeEvents string, syncInterval time.Duration, cacheDays int) (*GoogleCalendarProvider, error) {
+func NewGoogleCalendarProvider(opts GoogleCalendarOptions) (*GoogleCalendarProvider, error) {
 	ctx := context.Background()
-	if !filepath.IsAbs(credentialsPath) {
-		return nil, fmt.Errorf("credentials path must be absolute: %s", credentialsPath)
-	}
-	b, err := os.ReadFile(filepath.Clean(credentialsPath))
-	if err != nil {
-		return nil, fmt.Errorf("failed to read credentials file: %v", err)
-	}
 
-	config, err := google.JWTConfigFromJSON(b, calendar.CalendarReadonlyScope)
+	service, err := newCalendarService(ctx, opts)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse credentials: %v", err)
+		return nil, err
 	}
 
-	service, err := calendar.NewService(ctx, option.WithHTTPClient(config.Client(ctx)))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create calendar service: %v", err)
+	workingLocations := make(map[string]bool)
+	for _, location := range opts.OffTimeWorkingLocations {
+		workingLocations[location] = true
 	}
 
 	provider := &GoogleCalendarProvider{
 		service:       service,
-		calendarID:    calendarID,
-		offTimeEvents: offTimeEvents,
+		calendarID:    opts.CalendarID,
+		offTimeEvents: opts.OffTimeEvents,
 		cache: &eventCache{
-			events: make(map[string][]cachedEvent),
+			events:   make(map[string][]cachedEvent),
+			absences: make(map[string][]cachedEvent),
 		},
-		syncInterval: syncInterval,
-		cacheDays:    cacheDays,
+		syncInterval:            opts.SyncInterval,
+		cacheDays:               opts.CacheDays,
+		outOfOfficeAttendees:    opts.OutOfOfficeAttendees,
+		offTimeWorkingLocations: workingLocations,
+		store:                   opts.CacheStore,
+		cacheKey:                cacheKey("gcal", append([]string{opts.CalendarID, opts.OffTimeEvents}, opts.OutOfOfficeAttendees...)...),
 	}
 
+	loaded := provider.loadCache(ctx)
+
 	// Initial sync
-	if err := provider.syncEvents(ctx); err != nil {
-		return nil, fmt.Errorf("failed initial event sync: %v", err)
+	if err := provider.sync(ctx); err != nil {
+		if !loaded {
+			return nil, fmt.Errorf("failed initial event sync: %v", err)
+		}
+		slog.Warn("Initial Google Calendar sync failed, using persisted cache",
+			"last_sync", provider.cache.lastSync,
+			"error", err,
+		)
 	}
 
 	// Start background sync
-	go provider.backgroundSync(context.Background())
+	syncCtx, stop := context.WithCancel(context.Background())
+	provider.stop = stop
+	go provider.backgroundSync(syncCtx)
 
 	return provider, nil
 }
 
+// newCalendarService creates a calendar service authenticated with the credentials file if configured,
+// or with Application Default Credentials (e.g. GKE Workload Identity) otherwise
+func newCalendarService(ctx context.Context, opts GoogleCalendarOptions) (*calendar.Service, error) {
+	if opts.CredentialsPath == "" && opts.Subject != "" {
+		// Domain-wide delegation with ADC requires signing the JWT through the IAM Credentials API
+		if opts.ServiceAccount == "" {
+			return nil, fmt.Errorf("service account is required to impersonate %s with Application Default Credentials", opts.Subject)
+		}
+		slog.Info("Using Application Default Credentials with domain-wide delegation",
+			"service_account", opts.ServiceAccount,
+			"subject", opts.Subject,
+		)
+		ts, err := impersonate.CredentialsTokenSource(ctx, impersonate.CredentialsConfig{
+			TargetPrincipal: opts.ServiceAccount,
+			Scopes:          []string{calendar.CalendarReadonlyScope},
+			Subject:         opts.Subject,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create impersonated credentials: %v", err)
+		}
+
+		service, err := calendar.NewService(ctx, option.WithTokenSource(ts))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create calendar service: %v", err)
+		}
+		return service, nil
+	}
+
+	if opts.CredentialsPath == "" {
+		slog.Info("No credentials file configured, using Application Default Credentials")
+		creds, err := google.FindDefaultCredentials(ctx, calendar.CalendarReadonlyScope)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find default credentials: %v", err)
+		}
+
+		service, err := calendar.NewService(ctx, option.WithCredentials(creds))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create calendar service: %v", err)
+		}
+		return service, nil
+	}
+
+	credentialsPath := opts.CredentialsPath
+	if !filepath.IsAbs(credentialsPath) {
+		return nil, fmt.Errorf("credentials path must be absolute: %s", credentialsPath)
+	}
+	b, err := os.ReadFile(filepath.Clean(credentialsPath))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read credentials file: %v", err)
+	}
+
+	config, err := google.JWTConfigFromJSON(b, calendar.CalendarReadonlyScope)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse credentials: %v", err)
+	}
+	// Impersonate the user if the service account has domain-wide delegation
+	config.Subject = opts.Subject
+
+	service, err := calendar.NewService(ctx, option.WithHTTPClient(config.Client(ctx)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create calendar service: %v", err)
+	}
+	return service, nil
+}
+
 func (p *GoogleCalendarProvider) backgroundSync(ctx context.Context) {
 	ticker := time.NewTicker(p.syncInterval)
 	defer ticker.Stop()
@@ -89,134 +216,350 @@ func (p *GoogleCalendarProvider) backgroundSync(ctx context.Context) {
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
-			if err := p.syncEvents(ctx); err != nil {
+			if err := p.sync(ctx); err != nil {
 				slog.Error("Failed to sync calendar events", "error", err)
 			}
 		}
 	}
 }
 
-func (p *GoogleCalendarProvider) syncEvents(ctx context.Context) error {
+// sync syncs the events and records the result for Healthy
+func (p *GoogleCalendarProvider) sync(ctx context.Context) error {
+	err := p.syncEvents(ctx)
+
 	p.cache.syncMutex.Lock()
 	defer p.cache.syncMutex.Unlock()
+	p.syncErr = err
+	return err
+}
 
-	// Clear existing cache
-	p.cache.events = make(map[string][]cachedEvent)
-
+func (p *GoogleCalendarProvider) syncEvents(ctx context.Context) error {
 	// Calculate time range
 	now := time.Now()
 	timeMin := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()).Format(time.RFC3339)
 	timeMax := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()).AddDate(0, 0, p.cacheDays).Format(time.RFC3339)
 	slog.Info("Syncing calendar events", "timeMin", timeMin, "timeMax", timeMax)
 
-	// Build query for all off-time events
-	query := p.offTimeEvents
-	if query == "" {
-		return nil
-	}
-
-	events, err := p.service.Events.List(p.calendarID).
-		TimeMin(timeMin).
-		TimeMax(timeMax).
-		Q(query).
-		SingleEvents(true).
-		OrderBy("startTime").
-		Do()
-	if err != nil {
-		return fmt.Errorf("failed to list calendar events: %v", err)
-	}
+	events := make(map[string][]cachedEvent)
+	count := 0
 
-	// Process and cache events
-	for _, event := range events.Items {
-		var start, end time.Time
-		var err error
+	// Build query for all off-time events
+	if query := p.offTimeEvents; query != "" {
+		items, err := p.listEvents(ctx, p.service.Events.List(p.calendarID).
+			TimeMin(timeMin).
+			TimeMax(timeMax).
+			Q(query).
+			SingleEvents(true).
+			OrderBy("startTime"))
+		if err != nil {
+			return fmt.Errorf("failed to list calendar events: %v", err)
+		}
 
-		if event.Start.DateTime != "" {
-			start, err = time.Parse(time.RFC3339, event.Start.DateTime)
-			if err != nil {
-				slog.Warn("Failed to parse event start time", "event", event.Summary, "error", err)
+		// Process and cache events
+		for _, event := range items.Items {
+			entry, ok := toCachedEvent(event)
+			if !ok {
 				continue
 			}
-		} else if event.Start.Date != "" {
-			// Handle all-day events
-			start, err = time.Parse("2006-01-02", event.Start.Date)
-			if err != nil {
-				slog.Warn("Failed to parse event start date", "event", event.Summary, "error", err)
-				continue
+
+			// Store event for each day in its range
+			for current := entry.Start; current.Before(entry.End); current = current.AddDate(0, 0, 1) {
+				dateKey := current.Format("2006-01-02")
+				events[dateKey] = append(events[dateKey], entry)
 			}
-		} else {
-			slog.Warn("Event has no start time or date", "event", event.Summary)
-			continue
 		}
+		count += len(items.Items)
+	}
 
-		if event.End.DateTime != "" {
-			end, err = time.Parse(time.RFC3339, event.End.DateTime)
-			if err != nil {
-				slog.Warn("Failed to parse event end time", "event", event.Summary, "error", err)
-				continue
-			}
-		} else if event.End.Date != "" {
-			// Handle all-day events
-			end, err = time.Parse("2006-01-02", event.End.Date)
-			if err != nil {
-				slog.Warn("Failed to parse event end date", "event", event.Summary, "error", err)
-				continue
-			}
-		} else {
-			slog.Warn("Event has no end time or date", "event", event.Summary)
-			continue
+	absences := make(map[string][]cachedEvent)
+	for _, attendee := range p.outOfOfficeAttendees {
+		eventTypes := []string{"outOfOffice"}
+		if len(p.offTimeWorkingLocations) > 0 {
+			eventTypes = append(eventTypes, "workingLocation")
 		}
 
-		// Create event entry
-		entry := cachedEvent{
-			Start: start,
-			End:   end,
+		items, err := p.listEvents(ctx, p.service.Events.List(attendee).
+			TimeMin(timeMin).
+			TimeMax(timeMax).
+			EventTypes(eventTypes...).
+			SingleEvents(true).
+			OrderBy("startTime"))
+		if err != nil {
+			return fmt.Errorf("failed to list out-of-office events of %s: %v", attendee, err)
 		}
 
-		// Store event for each day in its range
-		for current := start; current.Before(end); current = current.AddDate(0, 0, 1) {
-			dateKey := current.Format("2006-01-02")
-			p.cache.events[dateKey] = append(p.cache.events[dateKey], entry)
+		absences[attendee] = []cachedEvent{}
+		for _, event := range items.Items {
+			if event.EventType == "workingLocation" &&
+				(event.WorkingLocationProperties == nil || !p.offTimeWorkingLocations[event.WorkingLocationProperties.Type]) {
+				continue
+			}
+			if entry, ok := toCachedEvent(event); ok {
+				absences[attendee] = append(absences[attendee], entry)
+			}
 		}
+		count += len(absences[attendee])
 	}
 
+	p.cache.syncMutex.Lock()
+	p.cache.events = events
+	p.cache.absences = absences
 	p.cache.lastSync = time.Now()
+	p.cache.syncMutex.Unlock()
+
 	slog.Info("Google Calendar events synced successfully",
-		"events_count", len(events.Items),
+		"events_count", count,
 	)
+
+	p.saveCache(ctx)
 	return nil
 }
 
+// loadCache loads the persisted event cache, it returns true if a cache was loaded
+func (p *GoogleCalendarProvider) loadCache(ctx context.Context) bool {
+	if p.store == nil {
+		return false
+	}
+
+	data, err := p.store.Load(ctx, p.cacheKey)
+	if err != nil {
+		slog.Warn("Failed to load persisted Google Calendar cache", "error", err)
+		return false
+	}
+	if data == nil {
+		return false
+	}
+
+	var snapshot eventCacheSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		slog.Warn("Failed to parse persisted Google Calendar cache", "error", err)
+		return false
+	}
+
+	p.cache.syncMutex.Lock()
+	defer p.cache.syncMutex.Unlock()
+	p.cache.events = snapshot.Events
+	p.cache.absences = snapshot.Absences
+	p.cache.lastSync = snapshot.LastSync
+	slog.Info("Loaded persisted Google Calendar cache", "last_sync", snapshot.LastSync)
+	return true
+}
+
+// saveCache persists the event cache
+func (p *GoogleCalendarProvider) saveCache(ctx context.Context) {
+	if p.store == nil {
+		return
+	}
+
+	p.cache.syncMutex.RLock()
+	data, err := json.Marshal(eventCacheSnapshot{
+		LastSync: p.cache.lastSync,
+		Events:   p.cache.events,
+		Absences: p.cache.absences,
+	})
+	p.cache.syncMutex.RUnlock()
+	if err != nil {
+		slog.Error("Failed to marshal Google Calendar cache", "error", err)
+		return
+	}
+
+	if err := p.store.Save(ctx, p.cacheKey, data); err != nil {
+		slog.Error("Failed to persist Google Calendar cache", "error", err)
+	}
+}
+
+// Close stops the background sync and persists the cache
+func (p *GoogleCalendarProvider) Close(ctx context.Context) {
+	p.stop()
+	p.saveCache(ctx)
+}
+
+// listEvents executes the list call, retrying with exponential backoff on rate limit,
+// quota and server errors. Persistent quota errors are reported through Healthy.
+func (p *GoogleCalendarProvider) listEvents(ctx context.Context, call *calendar.EventsListCall) (*calendar.Events, error) {
+	var events *calendar.Events
+	var lastErr error
+
+	err := wait.ExponentialBackoffWithContext(ctx, listBackoff, func(ctx context.Context) (bool, error) {
+		events, lastErr = call.Context(ctx).Do()
+		if lastErr == nil {
+			return true, nil
+		}
+		if !isRetryableCalendarError(lastErr) {
+			return false, lastErr
+		}
+		slog.Warn("Google Calendar API call failed, retrying", "error", lastErr)
+		return false, nil
+	})
+
+	p.cache.syncMutex.Lock()
+	defer p.cache.syncMutex.Unlock()
+
+	if err != nil {
+		if lastErr != nil {
+			err = lastErr
+		}
+		if isQuotaError(err) {
+			p.quotaErr = err
+		}
+		return nil, err
+	}
+
+	p.quotaErr = nil
+	return events, nil
+}
+
+// listBackoff is the retry policy for Google Calendar API calls
+var listBackoff = wait.Backoff{
+	Duration: time.Second,
+	Factor:   2,
+	Jitter:   0.1,
+	Steps:    5,
+	Cap:      30 * time.Second,
+}
+
+// isRetryableCalendarError checks if the error is a rate limit, quota or server error
+func isRetryableCalendarError(err error) bool {
+	var gerr *googleapi.Error
+	if !errors.As(err, &gerr) {
+		return false
+	}
+	return gerr.Code == http.StatusTooManyRequests || gerr.Code >= 500 || isQuotaError(err)
+}
+
+// isQuotaError checks if the error is caused by an exhausted quota or rate limit
+func isQuotaError(err error) bool {
+	var gerr *googleapi.Error
+	if !errors.As(err, &gerr) {
+		return false
+	}
+	if gerr.Code == http.StatusTooManyRequests {
+		return true
+	}
+	if gerr.Code != http.StatusForbidden {
+		return false
+	}
+	for _, item := range gerr.Errors {
+		switch item.Reason {
+		case "rateLimitExceeded", "userRateLimitExceeded", "quotaExceeded", "dailyLimitExceeded":
+			return true
+		}
+	}
+	return false
+}
+
+// Healthy returns an error if the last calendar sync failed, e.g. because of an exhausted API quota
+func (p *GoogleCalendarProvider) Healthy() error {
+	p.cache.syncMutex.RLock()
+	defer p.cache.syncMutex.RUnlock()
+
+	if p.quotaErr != nil {
+		return fmt.Errorf("google calendar API quota exhausted: %v", p.quotaErr)
+	}
+	if p.syncErr != nil {
+		return fmt.Errorf("google calendar sync failed: %v", p.syncErr)
+	}
+	return nil
+}
+
+// LastSync returns when the events were last synced successfully
+func (p *GoogleCalendarProvider) LastSync() time.Time {
+	p.cache.syncMutex.RLock()
+	defer p.cache.syncMutex.RUnlock()
+	return p.cache.lastSync
+}
+
+// toCachedEvent converts a calendar event to a cache entry, it returns false if the event has no valid times
+func toCachedEvent(event *calendar.Event) (cachedEvent, bool) {
+	start, err := parseEventDateTime(event.Start)
+	if err != nil {
+		slog.Warn("Failed to parse event start time", "event", event.Summary, "error", err)
+		return cachedEvent{}, false
+	}
+
+	end, err := parseEventDateTime(event.End)
+	if err != nil {
+		slog.Warn("Failed to parse event end time", "event", event.Summary, "error", err)
+		return cachedEvent{}, false
+	}
+
+	return cachedEvent{
+		Start: start,
+		End:   end,
+	}, true
+}
+
+// parseEventDateTime parses the date time of timed events or the date of all-day events
+func parseEventDateTime(dt *calendar.EventDateTime) (time.Time, error) {
+	if dt == nil {
+		return time.Time{}, fmt.Errorf("event has no time or date")
+	}
+	if dt.DateTime != "" {
+		return time.Parse(time.RFC3339, dt.DateTime)
+	}
+	if dt.Date != "" {
+		// Handle all-day events
+		return time.Parse("2006-01-02", dt.Date)
+	}
+	return time.Time{}, fmt.Errorf("event has no time or date")
+}
+
 // IsWorkTime checks if the given time is within working hours
 func (p *GoogleCalendarProvider) IsWorkTime(ctx context.Context, t time.Time) (bool, error) {
+	isWork, ok, err := p.Override(ctx, t)
+	if err != nil || ok {
+		return isWork, err
+	}
+
+	// No off-time events found for this time
+	return true, nil
+}
+
+// Override returns off time if an off-time event covers the time or all attendees are
+// out of office, the provider has no opinion otherwise
+func (p *GoogleCalendarProvider) Override(ctx context.Context, t time.Time) (bool, bool, error) {
 	p.cache.syncMutex.RLock()
 	defer p.cache.syncMutex.RUnlock()
 
 	// Check if we have events for this date
 	dateKey := t.Format("2006-01-02")
-	events, ok := p.cache.events[dateKey]
-	if !ok {
-		// If no events for this date, consider it work time
-		return true, nil
-	}
+	events := p.cache.events[dateKey]
 
 	// Check if time falls within any off-time event
+	if coversTime(events, t) {
+		return false, true, nil
+	}
+
+	// Check if all attendees are out of office
+	if len(p.outOfOfficeAttendees) > 0 {
+		for _, attendee := range p.outOfOfficeAttendees {
+			if !coversTime(p.cache.absences[attendee], t) {
+				return false, false, nil
+			}
+		}
+		return false, true, nil
+	}
+
+	return false, false, nil
+}
+
+// coversTime checks if the time falls within any of the events
+func coversTime(events []cachedEvent, t time.Time) bool {
 	for _, event := range events {
 		// Event end dates are exclusive, so we check if time is >= start and < end
 		if !t.Before(event.Start) && t.Before(event.End) {
-			return false, nil
+			return true
 		}
 	}
-
-	// No off-time events found for this time
-	return true, nil
+	return false
 }
 
 // String returns a string representation of the GoogleCalendarProvider
 func (p *GoogleCalendarProvider) String() string {
-	return fmt.Sprintf("GoogleCalendarProvider{calendarId: %s, offTimeEvents: %v, syncInterval: %v, cacheDays: %d, cacheSize: %d}",
+	return fmt.Sprintf("GoogleCalendarProvider{calendarId: %s, offTimeEvents: %v, outOfOfficeAttendees: %v, syncInterval: %v, cacheDays: %d, cacheSize: %d}",
 		p.calendarID,
 		p.offTimeEvents,
+		p.outOfOfficeAttendees,
 		p.syncInterval,
 		p.cacheDays,
 		len(p.cache.events))
@@ -0,0 +1,117 @@
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/api/calendar/v3"
+	"google.golang.org/api/option"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+func TestGoogleCalendarProvider_SyncRetries(t *testing.T) {
+	// Retry right away, the policy is the same otherwise
+	backoff := listBackoff
+	listBackoff = wait.Backoff{Duration: time.Millisecond, Factor: 2, Steps: backoff.Steps}
+	t.Cleanup(func() { listBackoff = backoff })
+
+	apiError := func(code int, reason string) string {
+		return fmt.Sprintf(`{"error":{"code":%d,"message":%q,"errors":[{"domain":"usageLimits","reason":%q}]}}`, code, reason, reason)
+	}
+	tests := []struct {
+		name string
+		// failures are the status codes of the failing calls before the first successful one, with the error reason
+		failures []int
+		reason   string
+		// wantCalls is how many calls are made, wantErr whether the sync fails
+		wantCalls int
+		wantErr   bool
+		// wantHealthy is the error of Healthy afterwards, empty if healthy
+		wantHealthy string
+	}{
+		{name: "Rate Limit Exceeded", failures: []int{http.StatusForbidden, http.StatusForbidden}, reason: "rateLimitExceeded", wantCalls: 3},
+		{name: "Too Many Requests", failures: []int{http.StatusTooManyRequests}, reason: "rateLimitExceeded", wantCalls: 2},
+		{name: "Server Error", failures: []int{http.StatusServiceUnavailable, http.StatusInternalServerError}, reason: "backendError", wantCalls: 3},
+		{
+			name:        "Quota Exhausted",
+			failures:    []int{http.StatusForbidden, http.StatusForbidden, http.StatusForbidden, http.StatusForbidden, http.StatusForbidden},
+			reason:      "quotaExceeded",
+			wantCalls:   5,
+			wantErr:     true,
+			wantHealthy: "quota exhausted",
+		},
+		{
+			name:        "Persistent Server Error",
+			failures:    []int{http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError},
+			reason:      "backendError",
+			wantCalls:   5,
+			wantErr:     true,
+			wantHealthy: "sync failed",
+		},
+		{
+			name:        "Forbidden",
+			failures:    []int{http.StatusForbidden},
+			reason:      "forbidden",
+			wantCalls:   1,
+			wantErr:     true,
+			wantHealthy: "sync failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				call := int(calls.Add(1))
+				if call <= len(tt.failures) {
+					w.WriteHeader(tt.failures[call-1])
+					_, _ = w.Write([]byte(apiError(tt.failures[call-1], tt.reason)))
+					return
+				}
+				_, _ = w.Write([]byte(`{"items":[]}`))
+			}))
+			defer server.Close()
+
+			service, err := calendar.NewService(context.Background(),
+				option.WithEndpoint(server.URL+"/"), option.WithHTTPClient(server.Client()))
+			if err != nil {
+				t.Fatalf("Failed to create calendar service: %v", err)
+			}
+			provider := &GoogleCalendarProvider{
+				service:       service,
+				calendarID:    "primary",
+				offTimeEvents: "Holiday",
+				cacheDays:     7,
+				cache: &eventCache{
+					events:   make(map[string][]cachedEvent),
+					absences: make(map[string][]cachedEvent),
+				},
+			}
+
+			err = provider.sync(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("sync() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := int(calls.Load()); got != tt.wantCalls {
+				t.Errorf("%d calls, want %d", got, tt.wantCalls)
+			}
+			healthy := provider.Healthy()
+			if tt.wantHealthy == "" && healthy != nil {
+				t.Errorf("Healthy() = %v, want nil", healthy)
+			}
+			if tt.wantHealthy != "" && (healthy == nil || !strings.Contains(healthy.Error(), tt.wantHealthy)) {
+				t.Errorf("Healthy() = %v, want %q", healthy, tt.wantHealthy)
+			}
+			if !tt.wantErr && provider.LastSync().IsZero() {
+				t.Errorf("LastSync() is zero after a successful sync")
+			}
+		})
+	}
+}
@@ -0,0 +1,328 @@
+package schedule
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// timeOffSource lists the approved time off of employees in an HR system
+type timeOffSource interface {
+	// listTimeOff returns the approved time off overlapping the date range
+	listTimeOff(ctx context.Context, start, end time.Time) ([]timeOff, error)
+}
+
+// timeOff is an approved absence of an employee, dates are inclusive and in "2006-01-02" format
+type timeOff struct {
+	Employee  string
+	StartDate string
+	EndDate   string
+}
+
+// HRTimeOffProvider is a schedule provider that reads approved time off from an HR system,
+// so that PTO doesn't need to be duplicated into a shared calendar.
+// It is off time on days when the whole team is on time off.
+type HRTimeOffProvider struct {
+	system       string
+	source       timeOffSource
+	employees    []string
+	location     *time.Location
+	syncInterval time.Duration
+	cacheDays    int
+	// absences maps dates to the team members on time off
+	absences map[string]map[string]bool
+	lastSync time.Time
+	// syncErr is the error of the last sync, nil if it succeeded
+	syncErr  error
+	mu       sync.RWMutex
+	store    CacheStore
+	cacheKey string
+	// stop stops the background sync
+	stop context.CancelFunc
+}
+
+// hrTimeOffCacheSnapshot is the persisted form of the absence cache
+type hrTimeOffCacheSnapshot struct {
+	LastSync time.Time                  `json:"lastSync"`
+	Absences map[string]map[string]bool `json:"absences"`
+}
+
+// HRTimeOffOptions contains the settings for creating an HRTimeOffProvider
+type HRTimeOffOptions struct {
+	// System is the HR system: "bamboohr" or "personio"
+	System string
+	// CompanyDomain is the BambooHR company subdomain
+	CompanyDomain string
+	// APIKey is the BambooHR API key
+	APIKey string
+	// ClientID is the Personio API client ID
+	ClientID string
+	// ClientSecret is the Personio API client secret
+	ClientSecret string
+	// Employees are the HR system IDs of the team members
+	Employees []string
+	// Location is the time zone of the time off dates (default: time.Local)
+	Location *time.Location
+	// SyncInterval is how often to refresh the absence cache
+	SyncInterval time.Duration
+	// CacheDays is how many days of time off to cache
+	CacheDays int
+	// CacheStore persists the absence cache across restarts, optional
+	CacheStore CacheStore
+}
+
+// NewHRTimeOffProvider creates a new HR time off provider for the configured HR system
+func NewHRTimeOffProvider(opts HRTimeOffOptions) (*HRTimeOffProvider, error) {
+	var source timeOffSource
+	switch opts.System {
+	case "bamboohr":
+		if opts.CompanyDomain == "" || opts.APIKey == "" {
+			return nil, fmt.Errorf("company domain and API key are required for BambooHR")
+		}
+		source = newBambooHRSource(bambooHRBaseURL+opts.CompanyDomain, opts.APIKey)
+	case "personio":
+		if opts.ClientID == "" || opts.ClientSecret == "" {
+			return nil, fmt.Errorf("client ID and client secret are required for Personio")
+		}
+		source = newPersonioSource(personioBaseURL, opts.ClientID, opts.ClientSecret)
+	default:
+		return nil, fmt.Errorf("unsupported HR system: %s", opts.System)
+	}
+
+	return newHRTimeOffProvider(context.Background(), source, opts)
+}
+
+// newHRTimeOffProvider creates the provider with the given source and syncs the time off
+func newHRTimeOffProvider(ctx context.Context, source timeOffSource, opts HRTimeOffOptions) (*HRTimeOffProvider, error) {
+	if len(opts.Employees) == 0 {
+		return nil, fmt.Errorf("at least one employee is required for HR time off")
+	}
+	location := opts.Location
+	if location == nil {
+		location = time.Local
+	}
+
+	provider := &HRTimeOffProvider{
+		system:       opts.System,
+		source:       source,
+		employees:    opts.Employees,
+		location:     location,
+		syncInterval: opts.SyncInterval,
+		cacheDays:    opts.CacheDays,
+		absences:     make(map[string]map[string]bool),
+		store:        opts.CacheStore,
+		cacheKey:     cacheKey("hr-"+opts.System, append([]string{opts.CompanyDomain}, opts.Employees...)...),
+	}
+
+	loaded := provider.loadCache(ctx)
+
+	// Initial sync
+	if err := provider.sync(ctx); err != nil {
+		if !loaded {
+			return nil, fmt.Errorf("failed initial time off sync: %v", err)
+		}
+		slog.Warn("Initial HR time off sync failed, using persisted cache",
+			"last_sync", provider.lastSync,
+			"error", err,
+		)
+	}
+
+	// Start background sync
+	syncCtx, stop := context.WithCancel(context.Background())
+	provider.stop = stop
+	go provider.backgroundSync(syncCtx)
+
+	return provider, nil
+}
+
+func (p *HRTimeOffProvider) backgroundSync(ctx context.Context) {
+	ticker := time.NewTicker(p.syncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := p.sync(ctx); err != nil {
+				slog.Error("Failed to sync HR time off", "error", err)
+			}
+		}
+	}
+}
+
+// sync syncs the time off and records the result for Healthy
+func (p *HRTimeOffProvider) sync(ctx context.Context) error {
+	err := p.syncTimeOff(ctx)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.syncErr = err
+	return err
+}
+
+func (p *HRTimeOffProvider) syncTimeOff(ctx context.Context) error {
+	now := time.Now().In(p.location)
+	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, p.location)
+	end := start.AddDate(0, 0, p.cacheDays)
+	slog.Info("Syncing HR time off", "start", start, "end", end)
+
+	entries, err := p.source.listTimeOff(ctx, start, end)
+	if err != nil {
+		return err
+	}
+
+	team := toSet(p.employees)
+	absences := make(map[string]map[string]bool)
+	for _, entry := range entries {
+		if !team[entry.Employee] {
+			continue
+		}
+		first, err := time.ParseInLocation("2006-01-02", entry.StartDate, p.location)
+		if err != nil {
+			slog.Warn("Ignoring time off with invalid start date", "employee", entry.Employee, "error", err)
+			continue
+		}
+		last, err := time.ParseInLocation("2006-01-02", entry.EndDate, p.location)
+		if err != nil {
+			slog.Warn("Ignoring time off with invalid end date", "employee", entry.Employee, "error", err)
+			continue
+		}
+		for day := first; !day.After(last); day = day.AddDate(0, 0, 1) {
+			dateKey := day.Format("2006-01-02")
+			if absences[dateKey] == nil {
+				absences[dateKey] = make(map[string]bool)
+			}
+			absences[dateKey][entry.Employee] = true
+		}
+	}
+
+	p.mu.Lock()
+	p.absences = absences
+	p.lastSync = time.Now()
+	p.mu.Unlock()
+
+	slog.Info("HR time off synced successfully", "time_off_count", len(entries))
+
+	p.saveCache(ctx)
+	return nil
+}
+
+// loadCache loads the persisted absence cache, it returns true if a cache was loaded
+func (p *HRTimeOffProvider) loadCache(ctx context.Context) bool {
+	if p.store == nil {
+		return false
+	}
+
+	data, err := p.store.Load(ctx, p.cacheKey)
+	if err != nil {
+		slog.Warn("Failed to load persisted HR time off cache", "error", err)
+		return false
+	}
+	if data == nil {
+		return false
+	}
+
+	var snapshot hrTimeOffCacheSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		slog.Warn("Failed to parse persisted HR time off cache", "error", err)
+		return false
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.absences = snapshot.Absences
+	p.lastSync = snapshot.LastSync
+	slog.Info("Loaded persisted HR time off cache", "last_sync", snapshot.LastSync, "days", len(snapshot.Absences))
+	return true
+}
+
+// saveCache persists the absence cache
+func (p *HRTimeOffProvider) saveCache(ctx context.Context) {
+	if p.store == nil {
+		return
+	}
+
+	p.mu.RLock()
+	data, err := json.Marshal(hrTimeOffCacheSnapshot{
+		LastSync: p.lastSync,
+		Absences: p.absences,
+	})
+	p.mu.RUnlock()
+	if err != nil {
+		slog.Error("Failed to marshal HR time off cache", "error", err)
+		return
+	}
+
+	if err := p.store.Save(ctx, p.cacheKey, data); err != nil {
+		slog.Error("Failed to persist HR time off cache", "error", err)
+	}
+}
+
+// Close stops the background sync and persists the cache
+func (p *HRTimeOffProvider) Close(ctx context.Context) {
+	p.stop()
+	p.saveCache(ctx)
+}
+
+// IsWorkTime returns false if the whole team is on time off, true otherwise
+func (p *HRTimeOffProvider) IsWorkTime(ctx context.Context, t time.Time) (bool, error) {
+	isWork, ok, err := p.Override(ctx, t)
+	if err != nil || ok {
+		return isWork, err
+	}
+	return true, nil
+}
+
+// Override returns off time if the whole team is on time off, the provider has no opinion otherwise
+func (p *HRTimeOffProvider) Override(ctx context.Context, t time.Time) (bool, bool, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	absent := p.absences[t.In(p.location).Format("2006-01-02")]
+	for _, employee := range p.employees {
+		if !absent[employee] {
+			return false, false, nil
+		}
+	}
+	return false, true, nil
+}
+
+// Describe returns the date the whole team is on time off, if it is
+func (p *HRTimeOffProvider) Describe(ctx context.Context, t time.Time) (string, error) {
+	_, ok, err := p.Override(ctx, t)
+	if err != nil || !ok {
+		return "", err
+	}
+	return fmt.Sprintf("all %d employees on %s time off on %s", len(p.employees), p.system, t.In(p.location).Format("2006-01-02")), nil
+}
+
+// LastSync returns when the time off was last synced successfully
+func (p *HRTimeOffProvider) LastSync() time.Time {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.lastSync
+}
+
+// Healthy returns an error if the last sync failed
+func (p *HRTimeOffProvider) Healthy() error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.syncErr != nil {
+		return fmt.Errorf("HR time off sync failed: %v", p.syncErr)
+	}
+	return nil
+}
+
+// String returns a string representation of the HRTimeOffProvider
+func (p *HRTimeOffProvider) String() string {
+	return fmt.Sprintf("HRTimeOffProvider{system: %s, employees: %v, location: %s, syncInterval: %v, cacheDays: %d}",
+		p.system,
+		p.employees,
+		p.location,
+		p.syncInterval,
+		p.cacheDays)
+}
@@ -0,0 +1,138 @@
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHRTimeOffProvider_IsWorkTime(t *testing.T) {
+	today := time.Now().UTC()
+	date := func(days int) string {
+		return today.AddDate(0, 0, days).Format("2006-01-02")
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, _, ok := r.BasicAuth(); !ok || user != "api-key" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != "/acme/v1/time_off/requests/" || r.URL.Query().Get("status") != "approved" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = fmt.Fprintf(w, `[
+			{"employeeId": "1", "start": %q, "end": %q},
+			{"employeeId": "2", "start": %q, "end": %q},
+			{"employeeId": "3", "start": %q, "end": %q}
+		]`, date(1), date(3), date(2), date(2), date(0), date(5))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name      string
+		checkTime time.Time
+		want      bool
+	}{
+		{
+			name:      "Part Of Team Absent",
+			checkTime: today.AddDate(0, 0, 1),
+			want:      true,
+		},
+		{
+			name:      "Whole Team Absent",
+			checkTime: today.AddDate(0, 0, 2),
+			want:      false,
+		},
+		{
+			name:      "Nobody Absent",
+			checkTime: today.AddDate(0, 0, 4),
+			want:      true,
+		},
+	}
+
+	source := newBambooHRSource(server.URL+"/acme", "api-key")
+	provider, err := newHRTimeOffProvider(context.Background(), source, HRTimeOffOptions{
+		System:       "bamboohr",
+		Employees:    []string{"1", "2"},
+		Location:     time.UTC,
+		SyncInterval: time.Hour,
+		CacheDays:    7,
+	})
+	if err != nil {
+		t.Fatalf("newHRTimeOffProvider() error = %v", err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := provider.IsWorkTime(context.Background(), tt.checkTime)
+			if err != nil {
+				t.Fatalf("IsWorkTime() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("IsWorkTime() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPersonioSource_ListTimeOff(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/auth":
+			_, _ = fmt.Fprint(w, `{"success": true, "data": {"token": "token-1"}}`)
+		case "/company/time-offs":
+			if r.Header.Get("Authorization") != "Bearer token-1" {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			period := func(id int, status, start, end string) string {
+				return fmt.Sprintf(`{"attributes": {"status": %q, "start_date": %q, "end_date": %q,
+					"employee": {"attributes": {"id": {"value": %d}}}}}`, status, start, end, id)
+			}
+			if r.URL.Query().Get("offset") == "0" {
+				_, _ = fmt.Fprintf(w, `{"success": true, "data": [%s, %s], "metadata": {"total_pages": 2}}`,
+					period(1, "approved", "2024-06-03T00:00:00+02:00", "2024-06-05T00:00:00+02:00"),
+					period(2, "pending", "2024-06-03T00:00:00+02:00", "2024-06-03T00:00:00+02:00"))
+				return
+			}
+			_, _ = fmt.Fprintf(w, `{"success": true, "data": [%s], "metadata": {"total_pages": 2}}`,
+				period(3, "approved", "2024-06-04T00:00:00+02:00", "2024-06-04T00:00:00+02:00"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	source := newPersonioSource(server.URL, "client-id", "client-secret")
+	start := time.Date(2024, time.June, 1, 0, 0, 0, 0, time.UTC)
+	got, err := source.listTimeOff(context.Background(), start, start.AddDate(0, 0, 7))
+	if err != nil {
+		t.Fatalf("listTimeOff() error = %v", err)
+	}
+
+	want := []timeOff{
+		{Employee: "1", StartDate: "2024-06-03", EndDate: "2024-06-05"},
+		{Employee: "3", StartDate: "2024-06-04", EndDate: "2024-06-04"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("listTimeOff() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("listTimeOff()[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestNewHRTimeOffProvider_Invalid(t *testing.T) {
+	if _, err := NewHRTimeOffProvider(HRTimeOffOptions{System: "workday", Employees: []string{"1"}}); err == nil {
+		t.Error("NewHRTimeOffProvider() error = nil, want error for unsupported HR system")
+	}
+	if _, err := NewHRTimeOffProvider(HRTimeOffOptions{System: "bamboohr", Employees: []string{"1"}}); err == nil {
+		t.Error("NewHRTimeOffProvider() error = nil, want error for missing API key")
+	}
+}
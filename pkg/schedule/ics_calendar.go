@@ -3,11 +3,18 @@ package schedule
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
 	"regexp"
+	"strings"
 	"sync"
 	"time"
 
@@ -16,32 +23,89 @@ import (
 
 // httpClient interface allows mocking http.Client in tests
 type httpClient interface {
-	Get(url string) (*http.Response, error)
+	Do(req *http.Request) (*http.Response, error)
 }
 
-// ICSCalendarProvider is a schedule provider that uses ICS calendar URLs
+// ICSCalendarProvider is a schedule provider that uses ICS calendar URLs or local files.
+// Events from all sources are merged into a single cache.
 type ICSCalendarProvider struct {
-	url             string
+	urls            []string
 	syncInterval    time.Duration
+	location        *time.Location
 	workPatterns    []*regexp.Regexp
 	holidayPatterns []*regexp.Regexp
 	events          map[string][]calendarEvent
 	mu              sync.RWMutex
 	client          httpClient
+	// sources holds the parsed events and cache validators per source,
+	// it is only accessed by the (serial) sync
+	sources  map[string]*icsSource
+	lastSync time.Time
+	// syncErr is the error of the last sync, nil if it succeeded
+	syncErr  error
+	store    CacheStore
+	cacheKey string
+	// stop stops the background sync
+	stop context.CancelFunc
+}
+
+// icsSource is the last successfully parsed state of a single calendar source
+type icsSource struct {
+	version     sourceVersion
+	events      map[string][]calendarEvent
+	eventsCount int
+}
+
+// sourceVersion identifies the content of a source for conditional fetches
+type sourceVersion struct {
+	etag         string
+	lastModified string
 }
 
 type calendarEvent struct {
-	Start   time.Time
-	End     time.Time
-	Summary string
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+	Summary string    `json:"summary"`
+}
+
+// ICSCalendarOptions contains the settings for creating an ICSCalendarProvider
+type ICSCalendarOptions struct {
+	// URLs are the calendar sources: HTTP(S) URLs, file:// URLs or absolute file paths
+	URLs []string
+	// SyncInterval is how often to refresh the event cache
+	SyncInterval time.Duration
+	// WorkDayPatterns match the summaries of work day events
+	WorkDayPatterns []string
+	// HolidayPatterns match the summaries of holiday events
+	HolidayPatterns []string
+	// Location is used for floating times and all-day events (default: time.Local)
+	Location *time.Location
+	// CacheStore persists the event cache across restarts, optional
+	CacheStore CacheStore
+}
+
+// icsCacheSnapshot is the persisted form of the event cache
+type icsCacheSnapshot struct {
+	LastSync time.Time                  `json:"lastSync"`
+	Events   map[string][]calendarEvent `json:"events"`
 }
 
-// NewICSCalendarProvider creates a new ICS calendar provider
-func NewICSCalendarProvider(url string, syncInterval time.Duration, workDayPatterns, holidayPatterns []string) (*ICSCalendarProvider, error) {
+// NewICSCalendarProvider creates a new ICS calendar provider.
+// If a cache store is configured, the persisted cache is loaded first and a
+// failed initial sync is tolerated as long as a persisted cache exists.
+func NewICSCalendarProvider(opts ICSCalendarOptions) (*ICSCalendarProvider, error) {
+	if len(opts.URLs) == 0 {
+		return nil, fmt.Errorf("at least one ICS calendar URL is required")
+	}
+	location := opts.Location
+	if location == nil {
+		location = time.Local
+	}
+
 	var workDayEventPatterns, holidayEventPatterns []*regexp.Regexp
 
 	// Compile work day patterns
-	for _, pattern := range workDayPatterns {
+	for _, pattern := range opts.WorkDayPatterns {
 		regex, err := regexp.Compile(pattern)
 		if err != nil {
 			return nil, fmt.Errorf("invalid work day pattern %q: %v", pattern, err)
@@ -50,7 +114,7 @@ func NewICSCalendarProvider(url string, syncInterval time.Duration, workDayPatte
 	}
 
 	// Compile holiday patterns
-	for _, pattern := range holidayPatterns {
+	for _, pattern := range opts.HolidayPatterns {
 		regex, err := regexp.Compile(pattern)
 		if err != nil {
 			return nil, fmt.Errorf("invalid holiday pattern %q: %v", pattern, err)
@@ -59,25 +123,97 @@ func NewICSCalendarProvider(url string, syncInterval time.Duration, workDayPatte
 	}
 
 	provider := &ICSCalendarProvider{
-		url:             url,
-		syncInterval:    syncInterval,
+		urls:            opts.URLs,
+		syncInterval:    opts.SyncInterval,
+		location:        location,
 		workPatterns:    workDayEventPatterns,
 		holidayPatterns: holidayEventPatterns,
 		events:          make(map[string][]calendarEvent),
 		client:          &http.Client{},
+		sources:         make(map[string]*icsSource),
+		store:           opts.CacheStore,
+		cacheKey:        cacheKey("ics", opts.URLs...),
 	}
 
+	ctx := context.Background()
+	loaded := provider.loadCache(ctx)
+
 	// Initial sync
-	if err := provider.syncEvents(context.Background()); err != nil {
-		return nil, fmt.Errorf("failed initial event sync: %v", err)
+	if err := provider.sync(ctx); err != nil {
+		if !loaded {
+			return nil, fmt.Errorf("failed initial event sync: %v", err)
+		}
+		slog.Warn("Initial ICS calendar sync failed, using persisted cache",
+			"last_sync", provider.lastSync,
+			"error", err,
+		)
 	}
 
 	// Start background sync
-	go provider.backgroundSync(context.Background())
+	syncCtx, stop := context.WithCancel(context.Background())
+	provider.stop = stop
+	go provider.backgroundSync(syncCtx)
 
 	return provider, nil
 }
 
+// loadCache loads the persisted event cache, it returns true if a cache was loaded
+func (p *ICSCalendarProvider) loadCache(ctx context.Context) bool {
+	if p.store == nil {
+		return false
+	}
+
+	data, err := p.store.Load(ctx, p.cacheKey)
+	if err != nil {
+		slog.Warn("Failed to load persisted ICS calendar cache", "error", err)
+		return false
+	}
+	if data == nil {
+		return false
+	}
+
+	var snapshot icsCacheSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		slog.Warn("Failed to parse persisted ICS calendar cache", "error", err)
+		return false
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.events = snapshot.Events
+	p.lastSync = snapshot.LastSync
+	slog.Info("Loaded persisted ICS calendar cache", "last_sync", snapshot.LastSync, "days", len(snapshot.Events))
+	return true
+}
+
+// saveCache persists the event cache
+func (p *ICSCalendarProvider) saveCache(ctx context.Context) {
+	if p.store == nil {
+		return
+	}
+
+	p.mu.RLock()
+	data, err := json.Marshal(icsCacheSnapshot{
+		LastSync: p.lastSync,
+		Events:   p.events,
+	})
+	p.mu.RUnlock()
+	if err != nil {
+		slog.Error("Failed to marshal ICS calendar cache", "error", err)
+		return
+	}
+
+	if err := p.store.Save(ctx, p.cacheKey, data); err != nil {
+		slog.Error("Failed to persist ICS calendar cache", "error", err)
+	}
+}
+
+// Close stops the background sync and persists the cache
+func (p *ICSCalendarProvider) Close(ctx context.Context) {
+	p.stop()
+	p.saveCache(ctx)
+}
+
 func (p *ICSCalendarProvider) backgroundSync(ctx context.Context) {
 	ticker := time.NewTicker(p.syncInterval)
 	defer ticker.Stop()
@@ -87,18 +223,136 @@ func (p *ICSCalendarProvider) backgroundSync(ctx context.Context) {
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
-			if err := p.syncEvents(ctx); err != nil {
+			if err := p.sync(ctx); err != nil {
 				slog.Error("Failed to sync ICS calendar events", "error", err)
 			}
 		}
 	}
 }
 
+// sync syncs the events and records the result for Healthy
+func (p *ICSCalendarProvider) sync(ctx context.Context) error {
+	err := p.syncEvents(ctx)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.syncErr = err
+	return err
+}
+
+// LastSync returns when the events were last synced successfully
+func (p *ICSCalendarProvider) LastSync() time.Time {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.lastSync
+}
+
+// Healthy returns an error if the last sync failed
+func (p *ICSCalendarProvider) Healthy() error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.syncErr != nil {
+		return fmt.Errorf("ICS calendar sync failed: %v", p.syncErr)
+	}
+	return nil
+}
+
 func (p *ICSCalendarProvider) syncEvents(ctx context.Context) error {
+	total := 0
+	changed := false
+
+	for _, source := range p.urls {
+		state, ok := p.sources[source]
+		if !ok {
+			state = &icsSource{}
+			p.sources[source] = state
+		}
+
+		body, version, err := p.readSource(ctx, source, state.version)
+		if err != nil {
+			return err
+		}
+
+		if body == nil {
+			slog.Debug("ICS calendar not modified, skipping parse", "source", source)
+		} else {
+			calendar, err := ics.ParseCalendar(bytes.NewReader(body))
+			if err != nil {
+				return fmt.Errorf("failed to parse ICS calendar %s: %v", source, err)
+			}
+			state.events = make(map[string][]calendarEvent)
+			p.collectEvents(calendar, state.events)
+			state.eventsCount = len(calendar.Events())
+			state.version = version
+			changed = true
+		}
+		total += state.eventsCount
+	}
+
+	if !changed {
+		p.mu.Lock()
+		p.lastSync = time.Now()
+		p.mu.Unlock()
+		return nil
+	}
+
+	// Merge the events of all sources
+	events := make(map[string][]calendarEvent)
+	for _, source := range p.urls {
+		for dateKey, entries := range p.sources[source].events {
+			events[dateKey] = append(events[dateKey], entries...)
+		}
+	}
+
+	p.mu.Lock()
+	// Replace the whole cache so that a failed source never leaves a partial cache
+	p.events = events
+	p.lastSync = time.Now()
+	p.mu.Unlock()
+
+	slog.Info("ICS calendar events synced successfully",
+		"events_count", total,
+		"sources", len(p.urls),
+	)
+
+	p.saveCache(ctx)
+	return nil
+}
+
+// readSource reads the raw calendar from an HTTP(S) URL, a file:// URL or an absolute file path.
+// Local files allow shipping a static calendar, e.g. mounted from a ConfigMap, in air-gapped clusters.
+// It returns a nil body if the source has not changed since the given version.
+func (p *ICSCalendarProvider) readSource(ctx context.Context, source string, version sourceVersion) ([]byte, sourceVersion, error) {
+	path := ""
+	if strings.HasPrefix(source, "file://") {
+		u, err := url.Parse(source)
+		if err != nil {
+			return nil, version, fmt.Errorf("invalid ICS calendar file URL %s: %v", source, err)
+		}
+		path = u.Path
+	} else if filepath.IsAbs(source) {
+		path = source
+	}
+
+	if path != "" {
+		return readFileSource(path, version)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		return nil, version, fmt.Errorf("failed to create request for ICS calendar %s: %v", source, err)
+	}
+	if version.etag != "" {
+		req.Header.Set("If-None-Match", version.etag)
+	}
+	if version.lastModified != "" {
+		req.Header.Set("If-Modified-Since", version.lastModified)
+	}
+
 	// Fetch ICS calendar
-	resp, err := p.client.Get(p.url)
+	resp, err := p.client.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to fetch ICS calendar: %v", err)
+		return nil, version, fmt.Errorf("failed to fetch ICS calendar %s: %v", source, err)
 	}
 	defer func() {
 		if e := resp.Body.Close(); e != nil {
@@ -106,33 +360,64 @@ func (p *ICSCalendarProvider) syncEvents(ctx context.Context) error {
 		}
 	}()
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return fmt.Errorf("failed to read response body: %v", err)
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, version, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, version, fmt.Errorf("failed to fetch ICS calendar %s: unexpected status code %d", source, resp.StatusCode)
 	}
 
-	calendar, err := ics.ParseCalendar(bytes.NewReader(body))
+	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return fmt.Errorf("failed to parse ICS calendar: %v", err)
+		return nil, version, fmt.Errorf("failed to read response body of %s: %v", source, err)
 	}
+	return body, sourceVersion{
+		etag:         resp.Header.Get("ETag"),
+		lastModified: resp.Header.Get("Last-Modified"),
+	}, nil
+}
 
-	p.mu.Lock()
-	defer p.mu.Unlock()
+// readFileSource reads a local calendar file, using the SHA-256 hash of its content as version, modification times
+// may stay the same across changes, e.g. of ConfigMap mounts or files copied while preserving times
+func readFileSource(path string, version sourceVersion) ([]byte, sourceVersion, error) {
+	if !filepath.IsAbs(path) {
+		return nil, version, fmt.Errorf("ICS calendar file path must be absolute: %s", path)
+	}
+	path = filepath.Clean(path)
 
-	slog.Info("ICS calendar events synced successfully", "events_count", len(calendar.Events()))
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return nil, version, fmt.Errorf("failed to read ICS calendar file %s: %v", path, err)
+	}
+	sum := sha256.Sum256(body)
+	hash := hex.EncodeToString(sum[:])
+	if version.etag == hash {
+		return nil, version, nil
+	}
+	return body, sourceVersion{etag: hash}, nil
+}
 
-	// Clear existing cache
-	p.events = make(map[string][]calendarEvent)
+// collectEvents adds the events of the calendar to the date keyed events map
+func (p *ICSCalendarProvider) collectEvents(calendar *ics.Calendar, events map[string][]calendarEvent) {
+	timezones := newICSTimezones(calendar, p.location)
 
 	for _, event := range calendar.Events() {
-		start, err := event.GetStartAt()
+		startProp := event.GetProperty(ics.ComponentPropertyDtStart)
+		if startProp == nil {
+			slog.Warn("Event has no start time", "summary", event.GetProperty(ics.ComponentPropertySummary))
+			continue
+		}
+		start, allDay, err := timezones.parseTime(startProp)
 		if err != nil {
 			slog.Warn("Failed to parse event start time", "error", err)
 			continue
 		}
 
-		end, err := event.GetEndAt()
-		if err != nil {
+		var end time.Time
+		if endProp := event.GetProperty(ics.ComponentPropertyDtEnd); endProp != nil {
+			end, _, err = timezones.parseTime(endProp)
+		}
+		if end.IsZero() || err != nil {
 			// If no end time specified, treat it as a one-day event
 			// End time is exclusive, so add one day to start time
 			end = start.AddDate(0, 0, 1)
@@ -140,6 +425,7 @@ func (p *ICSCalendarProvider) syncEvents(ctx context.Context) error {
 				"summary", event.GetProperty(ics.ComponentPropertySummary),
 				"start", start,
 				"end", end,
+				"all_day", allDay,
 			)
 		}
 
@@ -156,59 +442,81 @@ func (p *ICSCalendarProvider) syncEvents(ctx context.Context) error {
 			Summary: summary.Value,
 		}
 
-		// Store event for each day in its range
-		for current := start; current.Before(end); current = current.AddDate(0, 0, 1) {
+		// Store event for each day in its range, in the provider's location
+		startDay := start.In(p.location)
+		startDay = time.Date(startDay.Year(), startDay.Month(), startDay.Day(), 0, 0, 0, 0, p.location)
+		for current := startDay; current.Before(end); current = current.AddDate(0, 0, 1) {
 			dateKey := current.Format("2006-01-02")
-			p.events[dateKey] = append(p.events[dateKey], entry)
+			events[dateKey] = append(events[dateKey], entry)
 		}
 	}
-
-	slog.Info("ICS calendar events synced successfully",
-		"events_count", len(calendar.Events()),
-	)
-	return nil
 }
 
 // IsWorkTime checks if the given time is within working hours
 func (p *ICSCalendarProvider) IsWorkTime(ctx context.Context, t time.Time) (bool, error) {
+	isWork, ok, err := p.Override(ctx, t)
+	if err != nil || ok {
+		return isWork, err
+	}
+
+	// No matching events found, default to work time
+	return true, nil
+}
+
+// Override returns the decision of the matching holiday or work day event, if any
+func (p *ICSCalendarProvider) Override(ctx context.Context, t time.Time) (bool, bool, error) {
+	isWork, event := p.match(t)
+	return isWork, event != nil, nil
+}
+
+// Describe returns the holiday or work day event matching the given time, if any
+func (p *ICSCalendarProvider) Describe(ctx context.Context, t time.Time) (string, error) {
+	_, event := p.match(t)
+	if event == nil {
+		return "", nil
+	}
+	return fmt.Sprintf("ICS event '%s' %s", event.Summary, formatRange(event.Start, event.End, p.location)), nil
+}
+
+// match returns the first holiday or work day event in progress at the given time and its decision
+func (p *ICSCalendarProvider) match(t time.Time) (bool, *calendarEvent) {
 	p.mu.RLock()
 	defer p.mu.RUnlock()
 
 	// Check if we have events for this date
-	dateKey := t.Format("2006-01-02")
+	dateKey := t.In(p.location).Format("2006-01-02")
 	events, ok := p.events[dateKey]
 	if !ok {
-		// If no events for this date, consider it work time
-		return true, nil
+		return false, nil
 	}
 
-	for _, event := range events {
+	for i, event := range events {
 		// ICS event end dates are exclusive, so we check if time is >= start and < end
 		if !t.Before(event.Start) && t.Before(event.End) {
 			// Check if it's a holiday
 			for _, pattern := range p.holidayPatterns {
 				if pattern.MatchString(event.Summary) {
-					return false, nil
+					return false, &events[i]
 				}
 			}
 			// Check if it's a work day
 			for _, pattern := range p.workPatterns {
 				if pattern.MatchString(event.Summary) {
-					return true, nil
+					return true, &events[i]
 				}
 			}
 		}
 	}
 
-	// No matching events found, default to work time
-	return true, nil
+	return false, nil
 }
 
 // String returns a string representation of the ICSCalendarProvider
 func (p *ICSCalendarProvider) String() string {
-	return fmt.Sprintf("ICSCalendarProvider{url: %s, syncInterval: %v, workPatterns: %d, holidayPatterns: %d, events: %d}",
-		p.url,
+	return fmt.Sprintf("ICSCalendarProvider{urls: %v, syncInterval: %v, location: %s, workPatterns: %d, holidayPatterns: %d, events: %d}",
+		p.urls,
 		p.syncInterval,
+		p.location,
 		len(p.workPatterns),
 		len(p.holidayPatterns),
 		len(p.events))
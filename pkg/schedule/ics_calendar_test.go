@@ -4,6 +4,10 @@ import (
 	"context"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -79,12 +83,13 @@ func TestICSCalendarProvider_IsWorkTime(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			provider, err := NewICSCalendarProvider(
-				server.URL,
-				1*time.Hour,
-				tt.workPatterns,
-				tt.holidayPatterns,
-			)
+			provider, err := NewICSCalendarProvider(ICSCalendarOptions{
+				URLs:            []string{server.URL},
+				SyncInterval:    1 * time.Hour,
+				WorkDayPatterns: tt.workPatterns,
+				HolidayPatterns: tt.holidayPatterns,
+				Location:        location,
+			})
 			if err != nil {
 				t.Fatalf("Failed to create provider: %v", err)
 			}
@@ -124,12 +129,13 @@ func TestICSCalendarProvider_InvalidPatterns(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			_, err := NewICSCalendarProvider(
-				"http://example.com",
-				1*time.Hour,
-				tt.workPatterns,
-				tt.holidayPatterns,
-			)
+			_, err := NewICSCalendarProvider(ICSCalendarOptions{
+				URLs:            []string{"http://example.com"},
+				SyncInterval:    1 * time.Hour,
+				WorkDayPatterns: tt.workPatterns,
+				HolidayPatterns: tt.holidayPatterns,
+				Location:        time.UTC,
+			})
 			if err == nil {
 				t.Error("Expected error, got nil")
 				return
@@ -141,6 +147,348 @@ func TestICSCalendarProvider_InvalidPatterns(t *testing.T) {
 	}
 }
 
+const tzidCalendar = `BEGIN:VCALENDAR
+VERSION:2.0
+PRODID:test
+BEGIN:VTIMEZONE
+TZID:Custom Central European
+BEGIN:STANDARD
+DTSTART:19701025T030000
+TZOFFSETFROM:+0200
+TZOFFSETTO:+0100
+RRULE:FREQ=YEARLY;BYMONTH=10;BYDAY=-1SU
+END:STANDARD
+BEGIN:DAYLIGHT
+DTSTART:19700329T020000
+TZOFFSETFROM:+0100
+TZOFFSETTO:+0200
+RRULE:FREQ=YEARLY;BYMONTH=3;BYDAY=-1SU
+END:DAYLIGHT
+END:VTIMEZONE
+BEGIN:VEVENT
+UID:summer
+DTSTART;TZID=Custom Central European:20240331T080000
+DTEND;TZID=Custom Central European:20240331T180000
+SUMMARY:Holiday (summer time)
+END:VEVENT
+BEGIN:VEVENT
+UID:winter
+DTSTART;TZID=Custom Central European:20241027T080000
+DTEND;TZID=Custom Central European:20241027T180000
+SUMMARY:Holiday (winter time)
+END:VEVENT
+BEGIN:VEVENT
+UID:iana
+DTSTART;TZID=America/New_York:20240310T090000
+DTEND;TZID=America/New_York:20240310T170000
+SUMMARY:Holiday (new york)
+END:VEVENT
+BEGIN:VEVENT
+UID:floating
+DTSTART:20240601T090000
+DTEND:20240601T170000
+SUMMARY:Holiday (floating)
+END:VEVENT
+END:VCALENDAR
+`
+
+func TestICSCalendarProvider_TimeZones(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(strings.ReplaceAll(tzidCalendar, "\n", "\r\n")))
+	}))
+	defer server.Close()
+
+	location, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatalf("Failed to load location: %v", err)
+	}
+
+	provider, err := NewICSCalendarProvider(ICSCalendarOptions{
+		URLs:            []string{server.URL},
+		SyncInterval:    time.Hour,
+		HolidayPatterns: []string{"Holiday"},
+		Location:        location,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		checkTime time.Time
+		want      bool
+	}{
+		// 08:00 CEST on the day DST starts is 06:00 UTC
+		{"VTIMEZONE Daylight Start", time.Date(2024, time.March, 31, 6, 0, 0, 0, time.UTC), false},
+		{"VTIMEZONE Daylight Before Start", time.Date(2024, time.March, 31, 5, 59, 0, 0, time.UTC), true},
+		// 08:00 CET on the day DST ends is 07:00 UTC
+		{"VTIMEZONE Standard Start", time.Date(2024, time.October, 27, 7, 0, 0, 0, time.UTC), false},
+		{"VTIMEZONE Standard Before Start", time.Date(2024, time.October, 27, 6, 59, 0, 0, time.UTC), true},
+		// 09:00 EDT on the day DST starts in New York is 13:00 UTC
+		{"IANA TZID Start", time.Date(2024, time.March, 10, 13, 0, 0, 0, time.UTC), false},
+		{"IANA TZID Before Start", time.Date(2024, time.March, 10, 12, 59, 0, 0, time.UTC), true},
+		// Floating times are interpreted in the provider location
+		{"Floating Start", time.Date(2024, time.June, 1, 9, 0, 0, 0, location), false},
+		{"Floating End", time.Date(2024, time.June, 1, 17, 0, 0, 0, location), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := provider.IsWorkTime(context.Background(), tt.checkTime)
+			if err != nil {
+				t.Fatalf("IsWorkTime() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("IsWorkTime() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestICSCalendarProvider_MultipleURLs(t *testing.T) {
+	holidays := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(cnZhIcs)
+	}))
+	defer holidays.Close()
+
+	pto := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(strings.ReplaceAll(tzidCalendar, "\n", "\r\n")))
+	}))
+	defer pto.Close()
+
+	location, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Fatalf("Failed to load location: %v", err)
+	}
+
+	provider, err := NewICSCalendarProvider(ICSCalendarOptions{
+		URLs:            []string{holidays.URL, pto.URL},
+		SyncInterval:    time.Hour,
+		WorkDayPatterns: []string{".*（班）"},
+		HolidayPatterns: []string{".*（休）", "Holiday"},
+		Location:        location,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	for _, checkTime := range []time.Time{
+		time.Date(2023, time.January, 22, 10, 0, 0, 0, location),
+		time.Date(2024, time.June, 1, 10, 0, 0, 0, location),
+	} {
+		got, err := provider.IsWorkTime(context.Background(), checkTime)
+		if err != nil {
+			t.Fatalf("IsWorkTime() error = %v", err)
+		}
+		if got {
+			t.Errorf("IsWorkTime(%v) = true, want false", checkTime)
+		}
+	}
+}
+
+func TestICSCalendarProvider_FileSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cn_zh.ics")
+	if err := os.WriteFile(path, cnZhIcs, 0o600); err != nil {
+		t.Fatalf("Failed to write calendar file: %v", err)
+	}
+
+	location, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Fatalf("Failed to load location: %v", err)
+	}
+
+	for _, source := range []string{path, "file://" + path} {
+		t.Run(source, func(t *testing.T) {
+			provider, err := NewICSCalendarProvider(ICSCalendarOptions{
+				URLs:            []string{source},
+				SyncInterval:    time.Hour,
+				HolidayPatterns: []string{".*（休）"},
+				Location:        location,
+			})
+			if err != nil {
+				t.Fatalf("Failed to create provider: %v", err)
+			}
+
+			got, err := provider.IsWorkTime(context.Background(), time.Date(2023, time.January, 22, 10, 0, 0, 0, location))
+			if err != nil {
+				t.Fatalf("IsWorkTime() error = %v", err)
+			}
+			if got {
+				t.Errorf("IsWorkTime() = true, want false")
+			}
+		})
+	}
+}
+
+func TestReadFileSource_ContentVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "calendar.ics")
+	if err := os.WriteFile(path, []byte("v1"), 0o600); err != nil {
+		t.Fatalf("Failed to write calendar file: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Failed to stat calendar file: %v", err)
+	}
+
+	body, version, err := readFileSource(path, sourceVersion{})
+	if err != nil || string(body) != "v1" {
+		t.Fatalf("readFileSource() = %q, %v, want v1", body, err)
+	}
+	if body, _, err = readFileSource(path, version); err != nil || body != nil {
+		t.Errorf("readFileSource() of unchanged file = %q, %v, want nothing", body, err)
+	}
+
+	// Changed content with the same modification time is read again
+	if err := os.WriteFile(path, []byte("v2"), 0o600); err != nil {
+		t.Fatalf("Failed to write calendar file: %v", err)
+	}
+	if err := os.Chtimes(path, info.ModTime(), info.ModTime()); err != nil {
+		t.Fatalf("Failed to set modification time: %v", err)
+	}
+	if body, _, err = readFileSource(path, version); err != nil || string(body) != "v2" {
+		t.Errorf("readFileSource() of changed file = %q, %v, want v2", body, err)
+	}
+}
+
+func TestICSCalendarProvider_ConditionalFetch(t *testing.T) {
+	requests := 0
+	conditionalRequests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			conditionalRequests++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write(cnZhIcs)
+	}))
+	defer server.Close()
+
+	location, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Fatalf("Failed to load location: %v", err)
+	}
+
+	provider, err := NewICSCalendarProvider(ICSCalendarOptions{
+		URLs:            []string{server.URL},
+		SyncInterval:    time.Hour,
+		HolidayPatterns: []string{".*（休）"},
+		Location:        location,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	if err := provider.syncEvents(context.Background()); err != nil {
+		t.Fatalf("syncEvents() error = %v", err)
+	}
+	if requests != 2 || conditionalRequests != 1 {
+		t.Errorf("Expected 2 requests with 1 conditional request, got %d and %d", requests, conditionalRequests)
+	}
+
+	got, err := provider.IsWorkTime(context.Background(), time.Date(2023, time.January, 22, 10, 0, 0, 0, location))
+	if err != nil {
+		t.Fatalf("IsWorkTime() error = %v", err)
+	}
+	if got {
+		t.Errorf("IsWorkTime() = true after not modified sync, want false")
+	}
+}
+
+func TestICSCalendarProvider_PersistedCache(t *testing.T) {
+	var unavailable atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if unavailable.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write(cnZhIcs)
+	}))
+	defer server.Close()
+
+	location, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Fatalf("Failed to load location: %v", err)
+	}
+
+	store, err := NewFileCacheStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create cache store: %v", err)
+	}
+	opts := ICSCalendarOptions{
+		URLs:            []string{server.URL},
+		SyncInterval:    time.Hour,
+		HolidayPatterns: []string{".*（休）"},
+		Location:        location,
+		CacheStore:      store,
+	}
+
+	if _, err := NewICSCalendarProvider(opts); err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	// The calendar is down after a restart, the persisted cache must be used
+	unavailable.Store(true)
+	provider, err := NewICSCalendarProvider(opts)
+	if err != nil {
+		t.Fatalf("Failed to create provider with persisted cache: %v", err)
+	}
+
+	got, err := provider.IsWorkTime(context.Background(), time.Date(2023, time.January, 22, 10, 0, 0, 0, location))
+	if err != nil {
+		t.Fatalf("IsWorkTime() error = %v", err)
+	}
+	if got {
+		t.Errorf("IsWorkTime() = true with persisted cache, want false")
+	}
+
+	// Without a persisted cache, the failed initial sync is fatal
+	opts.CacheStore = nil
+	if _, err := NewICSCalendarProvider(opts); err == nil {
+		t.Errorf("Expected error without persisted cache, got nil")
+	}
+}
+
+func TestICSCalendarProvider_Close(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		_, _ = w.Write(cnZhIcs)
+	}))
+	defer server.Close()
+
+	store, err := NewFileCacheStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create cache store: %v", err)
+	}
+	provider, err := NewICSCalendarProvider(ICSCalendarOptions{
+		URLs:            []string{server.URL},
+		SyncInterval:    10 * time.Millisecond,
+		HolidayPatterns: []string{".*（休）"},
+		Location:        time.UTC,
+		CacheStore:      store,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	Close(context.Background(), NewCompositeProvider(provider))
+
+	// Let a sync started right before closing finish
+	time.Sleep(50 * time.Millisecond)
+	closed := requests.Load()
+	time.Sleep(50 * time.Millisecond)
+	if got := requests.Load(); got != closed {
+		t.Errorf("Calendar fetched %d times after Close(), want 0", got-closed)
+	}
+
+	data, err := store.Load(context.Background(), provider.cacheKey)
+	if err != nil || data == nil {
+		t.Errorf("Cache not persisted on Close(), data = %v, error = %v", data, err)
+	}
+}
+
 func contains(s, substr string) bool {
 	return len(s) >= len(substr) && s[0:len(substr)] == substr
 }
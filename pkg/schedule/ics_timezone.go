@@ -0,0 +1,356 @@
+package schedule
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	ics "github.com/arran4/golang-ical"
+)
+
+const (
+	icsDateFormat          = "20060102"
+	icsLocalDateTimeFormat = "20060102T150405"
+	icsUTCDateTimeFormat   = "20060102T150405Z"
+)
+
+// icsTimezones resolves TZID parameters of a calendar to UTC offsets.
+// IANA time zone names are resolved with the system database, anything else
+// falls back to the VTIMEZONE definitions embedded in the calendar.
+type icsTimezones struct {
+	definitions map[string]*vtimezone
+	locations   map[string]*time.Location
+	// floating is the location used for floating times and dates without TZID
+	floating *time.Location
+}
+
+// vtimezone is a parsed VTIMEZONE component
+type vtimezone struct {
+	tzid        string
+	observances []tzObservance
+}
+
+// tzObservance is a parsed STANDARD or DAYLIGHT sub-component
+type tzObservance struct {
+	// start is the local onset in TZOFFSETFROM, stored as a wall clock in UTC
+	start      time.Time
+	offsetFrom int
+	offsetTo   int
+	// rule is set if the observance recurs yearly
+	rule *tzRule
+}
+
+// tzRule is the yearly recurrence of an observance, e.g. FREQ=YEARLY;BYMONTH=3;BYDAY=-1SU
+type tzRule struct {
+	month    time.Month
+	weekday  time.Weekday
+	nth      int
+	monthDay int
+	until    time.Time
+}
+
+// newICSTimezones parses all VTIMEZONE definitions of the calendar
+func newICSTimezones(calendar *ics.Calendar, floating *time.Location) *icsTimezones {
+	tz := &icsTimezones{
+		definitions: make(map[string]*vtimezone),
+		locations:   make(map[string]*time.Location),
+		floating:    floating,
+	}
+
+	for _, component := range calendar.Timezones() {
+		def, err := parseVTimezone(component)
+		if err != nil {
+			slog.Warn("Failed to parse VTIMEZONE, ignoring", "error", err)
+			continue
+		}
+		tz.definitions[def.tzid] = def
+	}
+
+	return tz
+}
+
+// parseTime parses a DATE or DATE-TIME property honoring its TZID parameter.
+// It also reports whether the value was a date without time.
+func (tz *icsTimezones) parseTime(prop *ics.IANAProperty) (time.Time, bool, error) {
+	value := strings.TrimSpace(prop.Value)
+	isDate := len(value) == len(icsDateFormat)
+	if values, ok := prop.ICalParameters[string(ics.ParameterValue)]; ok && len(values) == 1 && values[0] == string(ics.ValueDataTypeDate) {
+		isDate = true
+	}
+
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse(icsUTCDateTimeFormat, value)
+		return t, false, err
+	}
+
+	layout := icsLocalDateTimeFormat
+	if isDate {
+		layout = icsDateFormat
+	}
+
+	tzids := prop.ICalParameters[string(ics.ParameterTzid)]
+	if len(tzids) == 0 {
+		t, err := time.ParseInLocation(layout, value, tz.floating)
+		return t, isDate, err
+	}
+
+	tzid := strings.Trim(tzids[0], `"`)
+	if loc := tz.location(tzid); loc != nil {
+		t, err := time.ParseInLocation(layout, value, loc)
+		return t, isDate, err
+	}
+
+	wall, err := time.Parse(layout, value)
+	if err != nil {
+		return time.Time{}, isDate, err
+	}
+	if def, ok := tz.definitions[tzid]; ok {
+		return def.toTime(wall), isDate, nil
+	}
+
+	slog.Warn("Unknown TZID, treating time as floating", "tzid", tzid)
+	t, err := time.ParseInLocation(layout, value, tz.floating)
+	return t, isDate, err
+}
+
+// location returns the IANA location for the TZID or nil if it is unknown
+func (tz *icsTimezones) location(tzid string) *time.Location {
+	if loc, ok := tz.locations[tzid]; ok {
+		return loc
+	}
+	loc, err := time.LoadLocation(tzid)
+	if err != nil {
+		loc = nil
+	}
+	tz.locations[tzid] = loc
+	return loc
+}
+
+func parseVTimezone(component *ics.VTimezone) (*vtimezone, error) {
+	tzidProp := component.GetProperty(ics.ComponentPropertyTzid)
+	if tzidProp == nil || tzidProp.Value == "" {
+		return nil, fmt.Errorf("VTIMEZONE has no TZID")
+	}
+
+	def := &vtimezone{tzid: tzidProp.Value}
+	for _, sub := range component.Components {
+		var base *ics.ComponentBase
+		switch c := sub.(type) {
+		case *ics.Standard:
+			base = &c.ComponentBase
+		case *ics.Daylight:
+			base = &c.ComponentBase
+		default:
+			continue
+		}
+
+		observance, err := parseObservance(base)
+		if err != nil {
+			return nil, fmt.Errorf("invalid observance in %s: %v", def.tzid, err)
+		}
+		def.observances = append(def.observances, observance)
+	}
+
+	if len(def.observances) == 0 {
+		return nil, fmt.Errorf("VTIMEZONE %s has no observances", def.tzid)
+	}
+	return def, nil
+}
+
+func parseObservance(base *ics.ComponentBase) (tzObservance, error) {
+	var observance tzObservance
+
+	start := base.GetProperty(ics.ComponentPropertyDtStart)
+	if start == nil {
+		return observance, fmt.Errorf("missing DTSTART")
+	}
+	t, err := time.Parse(icsLocalDateTimeFormat, strings.TrimSpace(start.Value))
+	if err != nil {
+		return observance, fmt.Errorf("invalid DTSTART %q: %v", start.Value, err)
+	}
+	observance.start = t
+
+	if observance.offsetFrom, err = parseUTCOffset(base.GetProperty(ics.ComponentProperty(ics.PropertyTzoffsetfrom))); err != nil {
+		return observance, err
+	}
+	if observance.offsetTo, err = parseUTCOffset(base.GetProperty(ics.ComponentProperty(ics.PropertyTzoffsetto))); err != nil {
+		return observance, err
+	}
+
+	if rrule := base.GetProperty(ics.ComponentPropertyRrule); rrule != nil {
+		if observance.rule, err = parseTZRule(rrule.Value); err != nil {
+			return observance, err
+		}
+	}
+
+	return observance, nil
+}
+
+// parseUTCOffset parses offsets like "+0800" or "-043000" into seconds
+func parseUTCOffset(prop *ics.IANAProperty) (int, error) {
+	if prop == nil {
+		return 0, fmt.Errorf("missing UTC offset")
+	}
+	value := strings.TrimSpace(prop.Value)
+	if len(value) != 5 && len(value) != 7 {
+		return 0, fmt.Errorf("invalid UTC offset %q", value)
+	}
+
+	sign := 1
+	switch value[0] {
+	case '+':
+	case '-':
+		sign = -1
+	default:
+		return 0, fmt.Errorf("invalid UTC offset %q", value)
+	}
+
+	seconds := 0
+	for i, unit := range []int{3600, 60, 1} {
+		if 1+2*i >= len(value) {
+			break
+		}
+		n, err := strconv.Atoi(value[1+2*i : 3+2*i])
+		if err != nil {
+			return 0, fmt.Errorf("invalid UTC offset %q", value)
+		}
+		seconds += n * unit
+	}
+	return sign * seconds, nil
+}
+
+// parseTZRule parses the subset of RRULE used by VTIMEZONE definitions
+func parseTZRule(value string) (*tzRule, error) {
+	rule := &tzRule{}
+	for _, part := range strings.Split(value, ";") {
+		key, val, _ := strings.Cut(part, "=")
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			if strings.ToUpper(val) != "YEARLY" {
+				return nil, fmt.Errorf("unsupported time zone rule frequency %q", val)
+			}
+		case "BYMONTH":
+			month, err := strconv.Atoi(val)
+			if err != nil || month < 1 || month > 12 {
+				return nil, fmt.Errorf("invalid BYMONTH %q", val)
+			}
+			rule.month = time.Month(month)
+		case "BYMONTHDAY":
+			day, err := strconv.Atoi(val)
+			if err != nil {
+				return nil, fmt.Errorf("invalid BYMONTHDAY %q", val)
+			}
+			rule.monthDay = day
+		case "BYDAY":
+			if len(val) < 2 {
+				return nil, fmt.Errorf("invalid BYDAY %q", val)
+			}
+			weekday, ok := icsWeekdays[strings.ToUpper(val[len(val)-2:])]
+			if !ok {
+				return nil, fmt.Errorf("invalid BYDAY %q", val)
+			}
+			rule.weekday = weekday
+			rule.nth = 1
+			if prefix := val[:len(val)-2]; prefix != "" {
+				nth, err := strconv.Atoi(prefix)
+				if err != nil {
+					return nil, fmt.Errorf("invalid BYDAY %q", val)
+				}
+				rule.nth = nth
+			}
+		case "UNTIL":
+			until, err := time.Parse(icsUTCDateTimeFormat, val)
+			if err != nil {
+				if until, err = time.Parse(icsDateFormat, val); err != nil {
+					return nil, fmt.Errorf("invalid UNTIL %q", val)
+				}
+			}
+			rule.until = until
+		}
+	}
+	if rule.month == 0 {
+		return nil, fmt.Errorf("time zone rule %q has no BYMONTH", value)
+	}
+	return rule, nil
+}
+
+var icsWeekdays = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+// onset returns the local onset of the observance in the given year, if any
+func (o tzObservance) onset(year int) (time.Time, bool) {
+	if year < o.start.Year() {
+		return time.Time{}, false
+	}
+	if o.rule == nil {
+		return o.start, year == o.start.Year()
+	}
+
+	var day time.Time
+	switch {
+	case o.rule.monthDay != 0:
+		day = time.Date(year, o.rule.month, o.rule.monthDay, 0, 0, 0, 0, time.UTC)
+	case o.rule.nth > 0:
+		day = time.Date(year, o.rule.month, 1, 0, 0, 0, 0, time.UTC)
+		day = day.AddDate(0, 0, (int(o.rule.weekday)-int(day.Weekday())+7)%7+(o.rule.nth-1)*7)
+	default:
+		day = time.Date(year, o.rule.month+1, 0, 0, 0, 0, 0, time.UTC)
+		day = day.AddDate(0, 0, -((int(day.Weekday())-int(o.rule.weekday)+7)%7)+(o.rule.nth+1)*7)
+	}
+
+	onset := time.Date(day.Year(), day.Month(), day.Day(),
+		o.start.Hour(), o.start.Minute(), o.start.Second(), 0, time.UTC)
+	if onset.Before(o.start) {
+		return time.Time{}, false
+	}
+	if !o.rule.until.IsZero() && onset.Add(-time.Duration(o.offsetFrom)*time.Second).After(o.rule.until) {
+		return time.Time{}, false
+	}
+	return onset, true
+}
+
+// toTime converts a wall clock time (stored in UTC) in this time zone to an absolute time
+func (z *vtimezone) toTime(wall time.Time) time.Time {
+	var latest time.Time
+	var current *tzObservance
+	for i := range z.observances {
+		o := &z.observances[i]
+		for _, year := range []int{wall.Year() - 1, wall.Year()} {
+			onset, ok := o.onset(year)
+			if !ok || onset.After(wall) {
+				continue
+			}
+			if current == nil || onset.After(latest) {
+				latest = onset
+				current = o
+			}
+		}
+	}
+
+	offset := 0
+	if current != nil {
+		offset = current.offsetTo
+	} else {
+		// Before the first onset the earliest observance's previous offset applies
+		earliest := &z.observances[0]
+		for i := range z.observances {
+			if z.observances[i].start.Before(earliest.start) {
+				earliest = &z.observances[i]
+			}
+		}
+		offset = earliest.offsetFrom
+	}
+
+	return time.Date(wall.Year(), wall.Month(), wall.Day(),
+		wall.Hour(), wall.Minute(), wall.Second(), wall.Nanosecond(),
+		time.FixedZone(z.tzid, offset))
+}
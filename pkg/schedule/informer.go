@@ -0,0 +1,121 @@
+package schedule
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+)
+
+// resourceWatch keeps the resources read by a provider in the cache of an informer, instead of reading them from
+// the API server on every check, and calls onChange whenever they change after the initial list, so that the
+// change is acted on right away instead of at the next reconciliation
+type resourceWatch struct {
+	informer cache.SharedIndexInformer
+	// stop stops the informer
+	stop context.CancelFunc
+}
+
+// startResourceWatch starts the informer, onChange is called on changes if not nil
+func startResourceWatch(informer cache.SharedIndexInformer, onChange func()) (*resourceWatch, error) {
+	changed := func() {
+		if onChange != nil {
+			onChange()
+		}
+	}
+	_, err := informer.AddEventHandler(cache.ResourceEventHandlerDetailedFuncs{
+		AddFunc: func(obj interface{}, isInInitialList bool) {
+			if !isInInitialList {
+				changed()
+			}
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			// Resyncs don't change anything
+			oldMeta, oldErr := meta.Accessor(oldObj)
+			newMeta, newErr := meta.Accessor(newObj)
+			if oldErr != nil || newErr != nil || oldMeta.GetResourceVersion() != newMeta.GetResourceVersion() {
+				changed()
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			changed()
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to add event handler: %v", err)
+	}
+
+	ctx, stop := context.WithCancel(context.Background())
+	go informer.Run(ctx.Done())
+	return &resourceWatch{informer: informer, stop: stop}, nil
+}
+
+// synced returns whether the cache of the informer is synced
+func (w *resourceWatch) synced() bool {
+	return w.informer.HasSynced()
+}
+
+// newCustomResourceInformer creates an informer of the custom resources at the API path, e.g.
+// /apis/group/version/namespaces/namespace/resources, as unstructured objects
+func newCustomResourceInformer(restClient rest.Interface, path string) cache.SharedIndexInformer {
+	listWatch := &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			data, err := restClient.Get().AbsPath(path).VersionedParams(&options, metav1.ParameterCodec).DoRaw(context.Background())
+			if err != nil {
+				return nil, err
+			}
+			list := &unstructured.UnstructuredList{}
+			if err := list.UnmarshalJSON(data); err != nil {
+				return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+			}
+			return list, nil
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			options.Watch = true
+			stream, err := restClient.Get().AbsPath(path).VersionedParams(&options, metav1.ParameterCodec).Stream(context.Background())
+			if err != nil {
+				return nil, err
+			}
+			decoder := &watchDecoder{stream: stream, decoder: json.NewDecoder(stream)}
+			return watch.NewStreamWatcher(decoder, k8serrors.NewClientErrorReporter(http.StatusInternalServerError, "GET", "ClientWatchDecoding")), nil
+		},
+	}
+	return cache.NewSharedIndexInformer(listWatch, &unstructured.Unstructured{}, 0, cache.Indexers{})
+}
+
+// watchDecoder decodes the JSON watch events of custom resources into unstructured objects
+type watchDecoder struct {
+	stream  io.ReadCloser
+	decoder *json.Decoder
+}
+
+// Decode decodes the next watch event
+func (d *watchDecoder) Decode() (watch.EventType, runtime.Object, error) {
+	var event struct {
+		Type   watch.EventType `json:"type"`
+		Object json.RawMessage `json:"object"`
+	}
+	if err := d.decoder.Decode(&event); err != nil {
+		return "", nil, err
+	}
+	object := &unstructured.Unstructured{}
+	if err := object.UnmarshalJSON(event.Object); err != nil {
+		return "", nil, fmt.Errorf("failed to parse %s watch event: %v", event.Type, err)
+	}
+	return event.Type, object, nil
+}
+
+// Close closes the watch stream
+func (d *watchDecoder) Close() {
+	_ = d.stream.Close()
+}
@@ -0,0 +1,136 @@
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+)
+
+const (
+	// OverrideWorkUntilKey is the ConfigMap key forcing work time until the given RFC 3339 time
+	OverrideWorkUntilKey = "work-until"
+	// OverrideOffUntilKey is the ConfigMap key forcing off time until the given RFC 3339 time
+	OverrideOffUntilKey = "off-until"
+)
+
+// ManualOverrideProvider is a schedule provider that lets engineers force work or off time
+// through a ConfigMap, e.g. to keep the cluster up for a late-night session.
+// Overrides expire automatically, an expired or missing override has no opinion.
+// The ConfigMap is watched, it's read from the API server until the watch is synced.
+type ManualOverrideProvider struct {
+	client    kubernetes.Interface
+	namespace string
+	name      string
+	watch     *resourceWatch
+	lister    corelisters.ConfigMapLister
+}
+
+// NewManualOverrideProvider creates a new manual override provider watching the named ConfigMap,
+// onChange is called whenever the ConfigMap changes if not nil
+func NewManualOverrideProvider(client kubernetes.Interface, namespace, name string, onChange func()) (*ManualOverrideProvider, error) {
+	if name == "" {
+		return nil, fmt.Errorf("override ConfigMap name is required")
+	}
+
+	factory := informers.NewSharedInformerFactoryWithOptions(client, 0,
+		informers.WithNamespace(namespace),
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.FieldSelector = fields.OneTermEqualSelector("metadata.name", name).String()
+		}),
+	)
+	configMaps := factory.Core().V1().ConfigMaps()
+	watch, err := startResourceWatch(configMaps.Informer(), onChange)
+	if err != nil {
+		return nil, fmt.Errorf("failed to watch override ConfigMap: %v", err)
+	}
+
+	return &ManualOverrideProvider{
+		client:    client,
+		namespace: namespace,
+		name:      name,
+		watch:     watch,
+		lister:    configMaps.Lister(),
+	}, nil
+}
+
+// IsWorkTime returns the override decision, or true if there is no active override
+func (p *ManualOverrideProvider) IsWorkTime(ctx context.Context, t time.Time) (bool, error) {
+	isWork, ok, err := p.Override(ctx, t)
+	if err != nil || ok {
+		return isWork, err
+	}
+	return true, nil
+}
+
+// Override returns the decision of the active override, if any.
+// If both keys are active, work time wins so that the cluster is never shut down by mistake.
+func (p *ManualOverrideProvider) Override(ctx context.Context, t time.Time) (bool, bool, error) {
+	cm, err := p.configMap(ctx)
+	if k8serrors.IsNotFound(err) {
+		return false, false, nil
+	}
+	if err != nil {
+		return false, false, fmt.Errorf("failed to get override ConfigMap: %v", err)
+	}
+
+	for _, override := range []struct {
+		key    string
+		isWork bool
+	}{
+		{OverrideWorkUntilKey, true},
+		{OverrideOffUntilKey, false},
+	} {
+		value, ok := cm.Data[override.key]
+		if !ok {
+			continue
+		}
+		until, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			slog.Warn("Ignoring invalid manual override", "key", override.key, "value", value, "error", err)
+			continue
+		}
+		if t.Before(until) {
+			slog.Debug("Manual override is active", "key", override.key, "until", until)
+			return override.isWork, true, nil
+		}
+	}
+
+	return false, false, nil
+}
+
+// configMap returns the override ConfigMap from the cache, or from the API server until the cache is synced
+func (p *ManualOverrideProvider) configMap(ctx context.Context) (*corev1.ConfigMap, error) {
+	if !p.watch.synced() {
+		return p.client.CoreV1().ConfigMaps(p.namespace).Get(ctx, p.name, metav1.GetOptions{})
+	}
+	return p.lister.ConfigMaps(p.namespace).Get(p.name)
+}
+
+// LastSync returns the zero time, the ConfigMap is watched rather than synced
+func (p *ManualOverrideProvider) LastSync() time.Time {
+	return time.Time{}
+}
+
+// Close stops watching the ConfigMap
+func (p *ManualOverrideProvider) Close(ctx context.Context) {
+	p.watch.stop()
+}
+
+// Healthy always returns nil, read errors are returned by IsWorkTime
+func (p *ManualOverrideProvider) Healthy() error {
+	return nil
+}
+
+// String returns a string representation of the ManualOverrideProvider
+func (p *ManualOverrideProvider) String() string {
+	return fmt.Sprintf("ManualOverrideProvider{configMap: %s/%s}", p.namespace, p.name)
+}
@@ -0,0 +1,133 @@
+package schedule
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestManualOverrideProvider_Override(t *testing.T) {
+	now := time.Date(2024, time.June, 1, 20, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		data   map[string]string
+		wantOK bool
+		want   bool
+	}{
+		{
+			name:   "No Override",
+			data:   nil,
+			wantOK: false,
+		},
+		{
+			name:   "Work Until",
+			data:   map[string]string{OverrideWorkUntilKey: "2024-06-01T23:00:00Z"},
+			wantOK: true,
+			want:   true,
+		},
+		{
+			name:   "Off Until",
+			data:   map[string]string{OverrideOffUntilKey: "2024-06-01T21:00:00Z"},
+			wantOK: true,
+			want:   false,
+		},
+		{
+			name:   "Expired",
+			data:   map[string]string{OverrideWorkUntilKey: "2024-06-01T19:00:00Z"},
+			wantOK: false,
+		},
+		{
+			name:   "Invalid Time",
+			data:   map[string]string{OverrideOffUntilKey: "tomorrow"},
+			wantOK: false,
+		},
+		{
+			name: "Work Wins Over Off",
+			data: map[string]string{
+				OverrideWorkUntilKey: "2024-06-01T23:00:00Z",
+				OverrideOffUntilKey:  "2024-06-01T23:00:00Z",
+			},
+			wantOK: true,
+			want:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := fake.NewClientset()
+			if tt.data != nil {
+				_, err := client.CoreV1().ConfigMaps("bmw-saver").Create(context.Background(), &corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{Name: "bmw-saver-override", Namespace: "bmw-saver"},
+					Data:       tt.data,
+				}, metav1.CreateOptions{})
+				if err != nil {
+					t.Fatalf("Failed to create ConfigMap: %v", err)
+				}
+			}
+
+			provider, err := NewManualOverrideProvider(client, "bmw-saver", "bmw-saver-override", nil)
+			if err != nil {
+				t.Fatalf("Failed to create provider: %v", err)
+			}
+			defer provider.Close(context.Background())
+
+			got, ok, err := provider.Override(context.Background(), now)
+			if err != nil {
+				t.Fatalf("Override() error = %v", err)
+			}
+			if ok != tt.wantOK {
+				t.Fatalf("Override() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && got != tt.want {
+				t.Errorf("Override() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestManualOverrideProvider_Watch(t *testing.T) {
+	now := time.Date(2024, time.June, 1, 20, 0, 0, 0, time.UTC)
+	client := fake.NewClientset()
+	changed := make(chan struct{}, 1)
+	provider, err := NewManualOverrideProvider(client, "bmw-saver", "bmw-saver-override", func() {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+	defer provider.Close(context.Background())
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !provider.watch.synced() {
+		if time.Now().After(deadline) {
+			t.Fatalf("ConfigMap watch not synced")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	_, err = client.CoreV1().ConfigMaps("bmw-saver").Create(context.Background(), &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "bmw-saver-override", Namespace: "bmw-saver"},
+		Data:       map[string]string{OverrideWorkUntilKey: "2024-06-01T23:00:00Z"},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create ConfigMap: %v", err)
+	}
+	select {
+	case <-changed:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("onChange not called after the ConfigMap was created")
+	}
+
+	got, ok, err := provider.Override(context.Background(), now)
+	if err != nil || !ok || !got {
+		t.Errorf("Override() = %v, %v, %v, want work time from the watched ConfigMap", got, ok, err)
+	}
+}
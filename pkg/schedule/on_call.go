@@ -0,0 +1,186 @@
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// onCallSource lists the shifts of an on-call schedule in an incident management system
+type onCallSource interface {
+	// listShifts returns the on-call shifts overlapping the time range
+	listShifts(ctx context.Context, start, end time.Time) ([]onCallShift, error)
+}
+
+// onCallShift is a time range someone is on call
+type onCallShift struct {
+	Who   string
+	Start time.Time
+	End   time.Time
+}
+
+// OnCallProvider is a schedule provider that says work time while an on-call rotation is staffed,
+// e.g. for a capacity tier keeping a minimum capacity for the on-call engineer on weekends
+type OnCallProvider struct {
+	system       string
+	scheduleID   string
+	source       onCallSource
+	syncInterval time.Duration
+	cacheDays    int
+	shifts       []onCallShift
+	lastSync     time.Time
+	// syncErr is the error of the last sync, nil if it succeeded
+	syncErr error
+	mu      sync.RWMutex
+	// stop stops the background sync
+	stop context.CancelFunc
+}
+
+// OnCallOptions contains the settings for creating an OnCallProvider
+type OnCallOptions struct {
+	// System is the incident management system: "pagerduty" or "opsgenie"
+	System string
+	// ScheduleID is the ID of the on-call schedule
+	ScheduleID string
+	// APIKey is the PagerDuty REST API key or the Opsgenie API key
+	APIKey string
+	// SyncInterval is how often to refresh the on-call shifts
+	SyncInterval time.Duration
+	// CacheDays is how many days of on-call shifts to cache
+	CacheDays int
+}
+
+// NewOnCallProvider creates a new on-call provider for the configured incident management system
+func NewOnCallProvider(opts OnCallOptions) (*OnCallProvider, error) {
+	if opts.ScheduleID == "" || opts.APIKey == "" {
+		return nil, fmt.Errorf("schedule ID and API key are required for on-call schedules")
+	}
+
+	var source onCallSource
+	switch opts.System {
+	case "pagerduty":
+		source = newPagerDutyOnCallSource(pagerDutyAPIURL, opts.APIKey, opts.ScheduleID)
+	case "opsgenie":
+		source = newOpsgenieOnCallSource(opsgenieAPIURL, opts.APIKey, opts.ScheduleID)
+	default:
+		return nil, fmt.Errorf("unsupported on-call system: %s", opts.System)
+	}
+
+	return newOnCallProvider(context.Background(), source, opts)
+}
+
+// newOnCallProvider creates the provider with the given source and syncs the on-call shifts
+func newOnCallProvider(ctx context.Context, source onCallSource, opts OnCallOptions) (*OnCallProvider, error) {
+	provider := &OnCallProvider{
+		system:       opts.System,
+		scheduleID:   opts.ScheduleID,
+		source:       source,
+		syncInterval: opts.SyncInterval,
+		cacheDays:    opts.CacheDays,
+	}
+
+	// Initial sync
+	if err := provider.sync(ctx); err != nil {
+		return nil, fmt.Errorf("failed initial on-call sync: %v", err)
+	}
+
+	// Start background sync
+	syncCtx, stop := context.WithCancel(context.Background())
+	provider.stop = stop
+	go provider.backgroundSync(syncCtx)
+
+	return provider, nil
+}
+
+func (p *OnCallProvider) backgroundSync(ctx context.Context) {
+	ticker := time.NewTicker(p.syncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := p.sync(ctx); err != nil {
+				slog.Error("Failed to sync on-call shifts", "system", p.system, "error", err)
+			}
+		}
+	}
+}
+
+// sync syncs the on-call shifts and records the result for Healthy
+func (p *OnCallProvider) sync(ctx context.Context) error {
+	now := time.Now()
+	shifts, err := p.source.listShifts(ctx, now, now.AddDate(0, 0, p.cacheDays))
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.syncErr = err
+	if err != nil {
+		return err
+	}
+	p.shifts = shifts
+	p.lastSync = now
+	slog.Info("On-call shifts synced successfully", "system", p.system, "schedule_id", p.scheduleID, "shift_count", len(shifts))
+	return nil
+}
+
+// Close stops the background sync
+func (p *OnCallProvider) Close(ctx context.Context) {
+	p.stop()
+}
+
+// IsWorkTime returns true while someone is on call
+func (p *OnCallProvider) IsWorkTime(ctx context.Context, t time.Time) (bool, error) {
+	_, ok := p.shiftAt(t)
+	return ok, nil
+}
+
+// Describe returns who is on call, if anyone is
+func (p *OnCallProvider) Describe(ctx context.Context, t time.Time) (string, error) {
+	shift, ok := p.shiftAt(t)
+	if !ok {
+		return "", nil
+	}
+	return fmt.Sprintf("%s on call in %s until %s", shift.Who, p.system, shift.End.Format(time.RFC3339)), nil
+}
+
+// shiftAt returns the on-call shift at the given time, false if nobody is on call
+func (p *OnCallProvider) shiftAt(t time.Time) (onCallShift, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	for _, shift := range p.shifts {
+		if !t.Before(shift.Start) && t.Before(shift.End) {
+			return shift, true
+		}
+	}
+	return onCallShift{}, false
+}
+
+// LastSync returns when the on-call shifts were last synced successfully
+func (p *OnCallProvider) LastSync() time.Time {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.lastSync
+}
+
+// Healthy returns an error if the last sync failed
+func (p *OnCallProvider) Healthy() error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.syncErr != nil {
+		return fmt.Errorf("on-call sync failed: %v", p.syncErr)
+	}
+	return nil
+}
+
+// String returns a string representation of the OnCallProvider
+func (p *OnCallProvider) String() string {
+	return fmt.Sprintf("OnCallProvider{system: %s, scheduleID: %s, syncInterval: %v, cacheDays: %d}",
+		p.system,
+		p.scheduleID,
+		p.syncInterval,
+		p.cacheDays)
+}
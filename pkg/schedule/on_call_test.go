@@ -0,0 +1,87 @@
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestOnCallProvider_IsWorkTime(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Hour)
+	at := func(hours int) string {
+		return now.Add(time.Duration(hours) * time.Hour).Format(time.RFC3339)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/oncalls":
+			if r.Header.Get("Authorization") != "Token token=api-key" {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			if r.URL.Query().Get("schedule_ids[]") != "PSCHED" {
+				t.Errorf("schedule_ids[] = %q, want PSCHED", r.URL.Query().Get("schedule_ids[]"))
+			}
+			// Two pages of on-calls
+			if r.URL.Query().Get("offset") == "0" {
+				_, _ = fmt.Fprintf(w, `{"oncalls": [{"user": {"summary": "Alice"}, "start": %q, "end": %q}], "more": true}`, at(-1), at(2))
+				return
+			}
+			_, _ = fmt.Fprintf(w, `{"oncalls": [{"user": {"summary": "Bob"}, "start": %q, "end": %q}], "more": false}`, at(4), at(6))
+		case "/v2/schedules/ops-schedule/timeline":
+			if r.Header.Get("Authorization") != "GenieKey api-key" {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			_, _ = fmt.Fprintf(w, `{"data": {"finalTimeline": {"rotations": [{"periods": [
+				{"startDate": %q, "endDate": %q, "recipient": {"name": "alice@example.com"}},
+				{"startDate": %q, "endDate": %q, "recipient": {"name": "bob@example.com"}}
+			]}]}}}`, at(-1), at(2), at(4), at(6))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	sources := map[string]onCallSource{
+		"pagerduty": newPagerDutyOnCallSource(server.URL, "api-key", "PSCHED"),
+		"opsgenie":  newOpsgenieOnCallSource(server.URL, "api-key", "ops-schedule"),
+	}
+	tests := []struct {
+		name  string
+		hours int
+		want  bool
+	}{
+		{name: "Staffed", hours: 1, want: true},
+		{name: "Between Shifts", hours: 3, want: false},
+		{name: "Next Shift", hours: 5, want: true},
+		{name: "After Shifts", hours: 7, want: false},
+	}
+	for system, source := range sources {
+		provider, err := newOnCallProvider(context.Background(), source, OnCallOptions{
+			System:       system,
+			ScheduleID:   "schedule",
+			SyncInterval: time.Hour,
+			CacheDays:    7,
+		})
+		if err != nil {
+			t.Fatalf("%s: newOnCallProvider() error = %v", system, err)
+		}
+		defer provider.Close(context.Background())
+
+		for _, tt := range tests {
+			t.Run(system+" "+tt.name, func(t *testing.T) {
+				got, err := provider.IsWorkTime(context.Background(), now.Add(time.Duration(tt.hours)*time.Hour))
+				if err != nil {
+					t.Fatalf("IsWorkTime() error = %v", err)
+				}
+				if got != tt.want {
+					t.Errorf("IsWorkTime() = %v, want %v", got, tt.want)
+				}
+			})
+		}
+	}
+}
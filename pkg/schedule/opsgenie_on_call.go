@@ -0,0 +1,96 @@
+package schedule
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// opsgenieAPIURL is the base URL of the Opsgenie API
+const opsgenieAPIURL = "https://api.opsgenie.com"
+
+// opsgenieOnCallSource lists the on-call shifts of an Opsgenie schedule
+type opsgenieOnCallSource struct {
+	client     httpClient
+	baseURL    string
+	apiKey     string
+	scheduleID string
+}
+
+// opsgenieTimelineResponse is the subset of the Opsgenie schedule timeline response we need
+type opsgenieTimelineResponse struct {
+	Data struct {
+		FinalTimeline struct {
+			Rotations []struct {
+				Periods []struct {
+					StartDate time.Time `json:"startDate"`
+					EndDate   time.Time `json:"endDate"`
+					Recipient struct {
+						Name string `json:"name"`
+					} `json:"recipient"`
+				} `json:"periods"`
+			} `json:"rotations"`
+		} `json:"finalTimeline"`
+	} `json:"data"`
+}
+
+// newOpsgenieOnCallSource creates a new Opsgenie source authenticated with the API key
+func newOpsgenieOnCallSource(baseURL, apiKey, scheduleID string) *opsgenieOnCallSource {
+	return &opsgenieOnCallSource{
+		client:     &http.Client{Timeout: 30 * time.Second},
+		baseURL:    baseURL,
+		apiKey:     apiKey,
+		scheduleID: scheduleID,
+	}
+}
+
+// listShifts returns the periods of the final timeline of the schedule overlapping the time range,
+// including overrides
+func (s *opsgenieOnCallSource) listShifts(ctx context.Context, start, end time.Time) ([]onCallShift, error) {
+	days := int(end.Sub(start).Hours()/24) + 1
+	params := url.Values{}
+	params.Set("identifierType", "id")
+	params.Set("date", start.UTC().Format(time.RFC3339))
+	params.Set("interval", strconv.Itoa(days))
+	params.Set("intervalUnit", "days")
+
+	u := fmt.Sprintf("%s/v2/schedules/%s/timeline?%s", s.baseURL, url.PathEscape(s.scheduleID), params.Encode())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Opsgenie request: %v", err)
+	}
+	req.Header.Set("Authorization", "GenieKey "+s.apiKey)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Opsgenie schedule timeline: %v", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("failed to get Opsgenie schedule timeline: status %d: %s", resp.StatusCode, body)
+	}
+
+	var timeline opsgenieTimelineResponse
+	if err := json.NewDecoder(resp.Body).Decode(&timeline); err != nil {
+		return nil, fmt.Errorf("failed to decode Opsgenie schedule timeline: %v", err)
+	}
+
+	var shifts []onCallShift
+	for _, rotation := range timeline.Data.FinalTimeline.Rotations {
+		for _, period := range rotation.Periods {
+			if period.Recipient.Name == "" {
+				continue
+			}
+			shifts = append(shifts, onCallShift{Who: period.Recipient.Name, Start: period.StartDate, End: period.EndDate})
+		}
+	}
+	return shifts, nil
+}
@@ -0,0 +1,99 @@
+package schedule
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// pagerDutyAPIURL is the base URL of the PagerDuty REST API
+const pagerDutyAPIURL = "https://api.pagerduty.com"
+
+// pagerDutyPageSize is the number of on-call entries requested per page
+const pagerDutyPageSize = 100
+
+// pagerDutyOnCallSource lists the on-call shifts of a PagerDuty schedule
+type pagerDutyOnCallSource struct {
+	client     httpClient
+	baseURL    string
+	apiKey     string
+	scheduleID string
+}
+
+// pagerDutyOnCallsResponse is the subset of the PagerDuty on-calls response we need
+type pagerDutyOnCallsResponse struct {
+	OnCalls []struct {
+		User struct {
+			Summary string `json:"summary"`
+		} `json:"user"`
+		Start time.Time `json:"start"`
+		End   time.Time `json:"end"`
+	} `json:"oncalls"`
+	More bool `json:"more"`
+}
+
+// newPagerDutyOnCallSource creates a new PagerDuty source authenticated with the REST API key
+func newPagerDutyOnCallSource(baseURL, apiKey, scheduleID string) *pagerDutyOnCallSource {
+	return &pagerDutyOnCallSource{
+		client:     &http.Client{Timeout: 30 * time.Second},
+		baseURL:    baseURL,
+		apiKey:     apiKey,
+		scheduleID: scheduleID,
+	}
+}
+
+// listShifts returns the on-call entries of the schedule overlapping the time range
+func (s *pagerDutyOnCallSource) listShifts(ctx context.Context, start, end time.Time) ([]onCallShift, error) {
+	var shifts []onCallShift
+	for offset := 0; ; offset += pagerDutyPageSize {
+		params := url.Values{}
+		params.Set("schedule_ids[]", s.scheduleID)
+		params.Set("since", start.UTC().Format(time.RFC3339))
+		params.Set("until", end.UTC().Format(time.RFC3339))
+		params.Set("limit", strconv.Itoa(pagerDutyPageSize))
+		params.Set("offset", strconv.Itoa(offset))
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.baseURL+"/oncalls?"+params.Encode(), nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create PagerDuty request: %v", err)
+		}
+		req.Header.Set("Authorization", "Token token="+s.apiKey)
+		req.Header.Set("Accept", "application/vnd.pagerduty+json;version=2")
+
+		page, err := s.get(req)
+		if err != nil {
+			return nil, err
+		}
+		for _, onCall := range page.OnCalls {
+			shifts = append(shifts, onCallShift{Who: onCall.User.Summary, Start: onCall.Start, End: onCall.End})
+		}
+		if !page.More {
+			return shifts, nil
+		}
+	}
+}
+
+func (s *pagerDutyOnCallSource) get(req *http.Request) (*pagerDutyOnCallsResponse, error) {
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list PagerDuty on-calls: %v", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("failed to list PagerDuty on-calls: status %d: %s", resp.StatusCode, body)
+	}
+
+	var page pagerDutyOnCallsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+		return nil, fmt.Errorf("failed to decode PagerDuty on-calls: %v", err)
+	}
+	return &page, nil
+}
@@ -0,0 +1,170 @@
+package schedule
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// personioBaseURL is the base URL of the Personio API
+const personioBaseURL = "https://api.personio.de/v1"
+
+// personioPageSize is the number of time off periods requested per page
+const personioPageSize = 200
+
+// personioSource lists approved time off periods from Personio
+type personioSource struct {
+	client       httpClient
+	baseURL      string
+	clientID     string
+	clientSecret string
+}
+
+// personioAuthResponse is the response of the Personio authentication endpoint
+type personioAuthResponse struct {
+	Success bool `json:"success"`
+	Data    struct {
+		Token string `json:"token"`
+	} `json:"data"`
+}
+
+// personioTimeOffResponse is the subset of the Personio time off list response we need
+type personioTimeOffResponse struct {
+	Success bool `json:"success"`
+	Data    []struct {
+		Attributes struct {
+			Status    string `json:"status"`
+			StartDate string `json:"start_date"`
+			EndDate   string `json:"end_date"`
+			Employee  struct {
+				Attributes struct {
+					ID struct {
+						Value json.Number `json:"value"`
+					} `json:"id"`
+				} `json:"attributes"`
+			} `json:"employee"`
+		} `json:"attributes"`
+	} `json:"data"`
+	Metadata struct {
+		TotalPages int `json:"total_pages"`
+	} `json:"metadata"`
+}
+
+// newPersonioSource creates a new Personio source authenticated with the API credentials
+func newPersonioSource(baseURL, clientID, clientSecret string) *personioSource {
+	return &personioSource{
+		client:       &http.Client{Timeout: 30 * time.Second},
+		baseURL:      baseURL,
+		clientID:     clientID,
+		clientSecret: clientSecret,
+	}
+}
+
+// listTimeOff returns the approved time off periods overlapping the date range
+func (s *personioSource) listTimeOff(ctx context.Context, start, end time.Time) ([]timeOff, error) {
+	token, err := s.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []timeOff
+	for page := 0; ; page++ {
+		params := url.Values{}
+		params.Set("start_date", start.Format("2006-01-02"))
+		params.Set("end_date", end.Format("2006-01-02"))
+		params.Set("limit", strconv.Itoa(personioPageSize))
+		params.Set("offset", strconv.Itoa(page))
+
+		var list personioTimeOffResponse
+		if err := s.get(ctx, "/company/time-offs?"+params.Encode(), token, &list); err != nil {
+			return nil, fmt.Errorf("failed to list Personio time off: %v", err)
+		}
+
+		for _, period := range list.Data {
+			if period.Attributes.Status != "approved" {
+				continue
+			}
+			// Dates are timestamps at midnight in the company time zone, e.g. "2024-06-03T00:00:00+02:00"
+			if len(period.Attributes.StartDate) < 10 || len(period.Attributes.EndDate) < 10 {
+				slog.Warn("Ignoring Personio time off with invalid dates",
+					"start_date", period.Attributes.StartDate,
+					"end_date", period.Attributes.EndDate,
+				)
+				continue
+			}
+			entries = append(entries, timeOff{
+				Employee:  period.Attributes.Employee.Attributes.ID.Value.String(),
+				StartDate: period.Attributes.StartDate[:10],
+				EndDate:   period.Attributes.EndDate[:10],
+			})
+		}
+
+		if page+1 >= list.Metadata.TotalPages {
+			return entries, nil
+		}
+	}
+}
+
+// authenticate exchanges the API credentials for a token
+func (s *personioSource) authenticate(ctx context.Context) (string, error) {
+	form := url.Values{}
+	form.Set("client_id", s.clientID)
+	form.Set("client_secret", s.clientSecret)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.baseURL+"/auth?"+form.Encode(), nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create Personio auth request: %v", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	var auth personioAuthResponse
+	if err := s.do(req, &auth); err != nil {
+		return "", fmt.Errorf("failed to authenticate with Personio: %v", err)
+	}
+	if !auth.Success || auth.Data.Token == "" {
+		return "", fmt.Errorf("failed to authenticate with Personio: no token returned")
+	}
+	return auth.Data.Token, nil
+}
+
+// get fetches the API path with the token and decodes the JSON response into v
+func (s *personioSource) get(ctx context.Context, path, token string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.baseURL+path, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/json")
+	return s.do(req, v)
+}
+
+// do executes the request and decodes the JSON response into v
+func (s *personioSource) do(req *http.Request, v interface{}) error {
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if e := resp.Body.Close(); e != nil {
+			slog.Error("Failed to close Personio response body", "error", e)
+		}
+	}()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("failed to parse response: %v", err)
+	}
+	return nil
+}
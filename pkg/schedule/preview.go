@@ -0,0 +1,150 @@
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const (
+	// defaultPreviewCount is how many transitions are returned by default
+	defaultPreviewCount = 10
+	// defaultPreviewStep is how often the schedule is checked by default
+	defaultPreviewStep = 15 * time.Minute
+	// defaultPreviewHorizon is how far ahead the schedule is checked by default
+	defaultPreviewHorizon = 14 * 24 * time.Hour
+)
+
+// Transition is a change between work and off time
+type Transition struct {
+	// Time is when the new decision takes effect
+	Time time.Time `json:"time"`
+	Decision
+}
+
+// PreviewOptions contains the settings for previewing a schedule
+type PreviewOptions struct {
+	// Count is the maximum number of transitions to return (default: 10)
+	Count int
+	// Step is how often the schedule is checked, windows shorter than the step may be missed (default: 15m)
+	Step time.Duration
+	// Horizon is how far ahead the schedule is checked (default: 14 days)
+	Horizon time.Duration
+}
+
+// Preview returns the upcoming transitions between work and off time after from,
+// with the provider that decided and why. Transition times are accurate to the second.
+// Observers can't tell the future, they are left out of composite providers.
+func Preview(ctx context.Context, provider Provider, from time.Time, opts PreviewOptions) ([]Transition, error) {
+	if isObserver(provider) {
+		return nil, fmt.Errorf("provider %v decides based on observed activity and can't be previewed", provider)
+	}
+	if opts.Count <= 0 {
+		opts.Count = defaultPreviewCount
+	}
+	if opts.Step <= 0 {
+		opts.Step = defaultPreviewStep
+	}
+	if opts.Horizon <= 0 {
+		opts.Horizon = defaultPreviewHorizon
+	}
+
+	previous, err := previewDecision(ctx, provider, from)
+	if err != nil {
+		return nil, err
+	}
+
+	var transitions []Transition
+	end := from.Add(opts.Horizon)
+	for t := from; t.Before(end) && len(transitions) < opts.Count; t = t.Add(opts.Step) {
+		next := t.Add(opts.Step)
+		decision, err := previewDecision(ctx, provider, next)
+		if err != nil {
+			return nil, err
+		}
+		if decision.IsWorkTime == previous.IsWorkTime {
+			continue
+		}
+
+		transition, err := findTransition(ctx, provider, t, next, decision)
+		if err != nil {
+			return nil, err
+		}
+		transitions = append(transitions, transition)
+		previous = decision
+	}
+	return transitions, nil
+}
+
+// findTransition narrows down when the decision changed between before and after,
+// the decision at after is already known to differ from the one at before
+func findTransition(ctx context.Context, provider Provider, before, after time.Time, decision Decision) (Transition, error) {
+	for after.Sub(before) > time.Second {
+		middle := before.Add(after.Sub(before) / 2).Truncate(time.Second)
+		if !middle.After(before) {
+			break
+		}
+		d, err := previewDecision(ctx, provider, middle)
+		if err != nil {
+			return Transition{}, err
+		}
+		if d.IsWorkTime == decision.IsWorkTime {
+			after, decision = middle, d
+		} else {
+			before = middle
+		}
+	}
+	return Transition{Time: after, Decision: decision}, nil
+}
+
+// previewDecision returns the decision of the provider at the given time, leaving out observers
+func previewDecision(ctx context.Context, provider Provider, t time.Time) (Decision, error) {
+	if composite, ok := provider.(*CompositeProvider); ok {
+		decision, _, err := composite.decide(ctx, t, &observations{reuse: true})
+		return decision, err
+	}
+	isWork, err := provider.IsWorkTime(ctx, t)
+	if err != nil {
+		return Decision{}, err
+	}
+	return Decision{IsWorkTime: isWork, Provider: fmt.Sprint(provider)}, nil
+}
+
+// observerCheckInterval is how often providers with observers are checked, observed activity may change any time
+const observerCheckInterval = time.Minute
+
+// NextChange returns the earliest time after from at which the decision of the provider may change,
+// ok is false if it doesn't change within the horizon. Observers may change their decision any time,
+// providers including them may change again after a minute.
+func NextChange(ctx context.Context, provider Provider, from time.Time, horizon time.Duration) (time.Time, bool, error) {
+	if hasObserver(provider) {
+		return from.Add(observerCheckInterval), true, nil
+	}
+
+	transitions, err := Preview(ctx, provider, from, PreviewOptions{
+		Count:   1,
+		Step:    time.Minute,
+		Horizon: horizon,
+	})
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	if len(transitions) == 0 {
+		return time.Time{}, false, nil
+	}
+	return transitions[0].Time, true, nil
+}
+
+// hasObserver returns true if the provider or any provider combined by it decides based on observed activity
+func hasObserver(provider Provider) bool {
+	composite, ok := provider.(*CompositeProvider)
+	if !ok {
+		return isObserver(provider)
+	}
+	for _, p := range composite.all() {
+		if isObserver(p) {
+			return true
+		}
+	}
+	return false
+}
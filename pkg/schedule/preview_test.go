@@ -0,0 +1,145 @@
+package schedule
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// observingProvider is a fake observer, it fails the test if asked
+type observingProvider struct {
+	t *testing.T
+}
+
+func (p *observingProvider) IsWorkTime(ctx context.Context, t time.Time) (bool, error) {
+	p.t.Errorf("observer asked for %v", t)
+	return false, nil
+}
+
+func (p *observingProvider) LastSync() time.Time {
+	return time.Time{}
+}
+
+func (p *observingProvider) Healthy() error {
+	return nil
+}
+
+func (p *observingProvider) ObservesActivity() bool {
+	return true
+}
+
+func TestPreview(t *testing.T) {
+	static := NewStaticProvider("09:00", "17:00", "UTC", nil)
+	static.OffDates = map[string]bool{"2024-06-05": true}
+	composite := NewCompositeProvider(static, &observingProvider{t: t})
+
+	from := time.Date(2024, time.June, 3, 12, 0, 0, 0, time.UTC) // Monday
+	transitions, err := Preview(context.Background(), composite, from, PreviewOptions{Count: 4})
+	if err != nil {
+		t.Fatalf("Preview() error = %v", err)
+	}
+
+	want := []struct {
+		time   time.Time
+		isWork bool
+	}{
+		{time.Date(2024, time.June, 3, 17, 0, 0, 0, time.UTC), false},
+		{time.Date(2024, time.June, 4, 9, 0, 0, 0, time.UTC), true},
+		{time.Date(2024, time.June, 4, 17, 0, 0, 0, time.UTC), false},
+		// Wednesday is an off date
+		{time.Date(2024, time.June, 6, 9, 0, 0, 0, time.UTC), true},
+	}
+	if len(transitions) != len(want) {
+		t.Fatalf("Preview() returned %d transitions, want %d: %v", len(transitions), len(want), transitions)
+	}
+	for i, w := range want {
+		if !transitions[i].Time.Equal(w.time) || transitions[i].IsWorkTime != w.isWork {
+			t.Errorf("transition %d = %v %v, want %v %v", i, transitions[i].Time, transitions[i].IsWorkTime, w.time, w.isWork)
+		}
+		if transitions[i].Provider != static.String() {
+			t.Errorf("transition %d provider = %q, want %q", i, transitions[i].Provider, static.String())
+		}
+	}
+}
+
+func TestPreview_Override(t *testing.T) {
+	override := &fakeOverrider{fakeProvider: fakeProvider{isWork: true}, ok: true}
+	composite := NewCompositeProvider(NewStaticProvider("09:00", "17:00", "UTC", nil))
+	composite.AddOverride(override, 10)
+
+	from := time.Date(2024, time.June, 3, 12, 0, 0, 0, time.UTC)
+	transitions, err := Preview(context.Background(), composite, from, PreviewOptions{Horizon: 48 * time.Hour})
+	if err != nil {
+		t.Fatalf("Preview() error = %v", err)
+	}
+	if len(transitions) != 0 {
+		t.Errorf("Preview() = %v, want no transitions while overridden", transitions)
+	}
+}
+
+func TestPreview_Observer(t *testing.T) {
+	if _, err := Preview(context.Background(), &observingProvider{t: t}, time.Now(), PreviewOptions{}); err == nil {
+		t.Error("Preview() error = nil, want error for an observer")
+	}
+}
+
+func TestNextChange(t *testing.T) {
+	static := NewStaticProvider("09:00", "17:00", "UTC", nil)
+	from := time.Date(2024, time.June, 3, 12, 0, 0, 0, time.UTC) // Monday
+
+	tests := []struct {
+		name     string
+		provider Provider
+		horizon  time.Duration
+		want     time.Time
+		wantOK   bool
+	}{
+		{
+			name:     "End Of Work Hours",
+			provider: static,
+			horizon:  24 * time.Hour,
+			want:     time.Date(2024, time.June, 3, 17, 0, 0, 0, time.UTC),
+			wantOK:   true,
+		},
+		{
+			name:     "Beyond Horizon",
+			provider: static,
+			horizon:  time.Hour,
+		},
+		{
+			name:     "Observer",
+			provider: NewCompositeProvider(static, &observingProvider{t: t}),
+			horizon:  24 * time.Hour,
+			want:     from.Add(time.Minute),
+			wantOK:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok, err := NextChange(context.Background(), tt.provider, from, tt.horizon)
+			if err != nil {
+				t.Fatalf("NextChange() error = %v", err)
+			}
+			if ok != tt.wantOK || !got.Equal(tt.want) {
+				t.Errorf("NextChange() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestTierSchedule_NextChange(t *testing.T) {
+	tiers := NewTierSchedule(
+		Tier{Name: "evening", Schedule: NewStaticProvider("18:00", "22:00", "UTC", nil)},
+		Tier{Name: "morning", Schedule: NewStaticProvider("06:00", "09:00", "UTC", nil)},
+	)
+	from := time.Date(2024, time.June, 3, 19, 0, 0, 0, time.UTC) // Monday
+
+	got, ok, err := tiers.NextChange(context.Background(), from, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("NextChange() error = %v", err)
+	}
+	if want := time.Date(2024, time.June, 3, 22, 0, 0, 0, time.UTC); !ok || !got.Equal(want) {
+		t.Errorf("NextChange() = %v, %v, want %v", got, ok, want)
+	}
+}
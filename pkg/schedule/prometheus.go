@@ -0,0 +1,177 @@
+package schedule
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PrometheusProvider is a schedule provider that uses a PromQL query to decide
+// whether the cluster is in use. It reports off time only when the metric has
+// stayed below the threshold for the whole configured duration.
+type PrometheusProvider struct {
+	url       string
+	query     string
+	threshold float64
+	duration  time.Duration
+	client    *http.Client
+	// noData is whether the last query returned no data, e.g. because the metric was renamed
+	noData bool
+	mu     sync.Mutex
+}
+
+// prometheusResponse is the subset of the Prometheus HTTP API response we need
+type prometheusResponse struct {
+	Status    string `json:"status"`
+	ErrorType string `json:"errorType"`
+	Error     string `json:"error"`
+	Data      struct {
+		ResultType string `json:"resultType"`
+		Result     []struct {
+			Value []interface{} `json:"value"`
+		} `json:"result"`
+	} `json:"data"`
+}
+
+// NewPrometheusProvider creates a new Prometheus schedule provider
+func NewPrometheusProvider(url, query string, threshold float64, duration time.Duration) (*PrometheusProvider, error) {
+	if url == "" {
+		return nil, fmt.Errorf("prometheus URL is required")
+	}
+	if query == "" {
+		return nil, fmt.Errorf("prometheus query is required")
+	}
+	if duration <= 0 {
+		return nil, fmt.Errorf("prometheus idle duration must be positive")
+	}
+
+	return &PrometheusProvider{
+		url:       strings.TrimSuffix(url, "/"),
+		query:     query,
+		threshold: threshold,
+		duration:  duration,
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}, nil
+}
+
+// IsWorkTime returns false only if the metric stayed below the threshold for the whole duration
+func (p *PrometheusProvider) IsWorkTime(ctx context.Context, t time.Time) (bool, error) {
+	peak, found, err := p.queryPeak(ctx, t)
+	if err != nil {
+		return false, err
+	}
+	p.mu.Lock()
+	p.noData = !found
+	p.mu.Unlock()
+	if !found {
+		// No series can't be told apart from a broken query or a renamed metric, keep the cluster running
+		slog.Warn("Prometheus query returned no data, treating as work time", "query", p.query)
+		return true, nil
+	}
+	return peak >= p.threshold, nil
+}
+
+// Override returns work time while the metric is above the threshold or has no data, and has no opinion once it
+// stayed below the threshold for the whole duration, so that with a priority it keeps the cluster up past the work
+// hours of the other providers while it's in use
+func (p *PrometheusProvider) Override(ctx context.Context, t time.Time) (bool, bool, error) {
+	isWork, err := p.IsWorkTime(ctx, t)
+	if err != nil || !isWork {
+		return false, false, err
+	}
+	return true, true, nil
+}
+
+// queryPeak returns the highest value of the query over the configured duration ending at t
+func (p *PrometheusProvider) queryPeak(ctx context.Context, t time.Time) (float64, bool, error) {
+	query := fmt.Sprintf("max(max_over_time((%s)[%ds:]))", p.query, int64(p.duration.Seconds()))
+
+	params := url.Values{}
+	params.Set("query", query)
+	params.Set("time", strconv.FormatInt(t.Unix(), 10))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.url+"/api/v1/query?"+params.Encode(), nil)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to create prometheus request: %v", err)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to query prometheus: %v", err)
+	}
+	defer func() {
+		if e := resp.Body.Close(); e != nil {
+			slog.Error("Failed to close prometheus response body", "error", e)
+		}
+	}()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to read prometheus response: %v", err)
+	}
+
+	var result prometheusResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return 0, false, fmt.Errorf("failed to parse prometheus response (status %d): %v", resp.StatusCode, err)
+	}
+	if result.Status != "success" {
+		return 0, false, fmt.Errorf("prometheus query failed: %s: %s", result.ErrorType, result.Error)
+	}
+	if result.Data.ResultType != "vector" {
+		return 0, false, fmt.Errorf("unexpected prometheus result type: %s", result.Data.ResultType)
+	}
+	if len(result.Data.Result) == 0 || len(result.Data.Result[0].Value) != 2 {
+		return 0, false, nil
+	}
+
+	raw, ok := result.Data.Result[0].Value[1].(string)
+	if !ok {
+		return 0, false, fmt.Errorf("unexpected prometheus sample value: %v", result.Data.Result[0].Value[1])
+	}
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to parse prometheus sample value %q: %v", raw, err)
+	}
+
+	return value, true, nil
+}
+
+// ObservesActivity returns true, the metric only reflects past activity
+func (p *PrometheusProvider) ObservesActivity() bool {
+	return true
+}
+
+// LastSync returns the zero time, Prometheus is queried on every check
+func (p *PrometheusProvider) LastSync() time.Time {
+	return time.Time{}
+}
+
+// Healthy returns an error if the last query returned no data, query errors are returned by IsWorkTime
+func (p *PrometheusProvider) Healthy() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.noData {
+		return fmt.Errorf("prometheus query %q returned no data", p.query)
+	}
+	return nil
+}
+
+// String returns a string representation of the PrometheusProvider
+func (p *PrometheusProvider) String() string {
+	return fmt.Sprintf("PrometheusProvider{url: %s, query: %s, threshold: %v, duration: %v}",
+		p.url,
+		p.query,
+		p.threshold,
+		p.duration)
+}
@@ -0,0 +1,77 @@
+package schedule
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPrometheusProvider_IsWorkTime(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		want     bool
+		wantErr  bool
+		// wantUnhealthy is whether Healthy returns an error after the query
+		wantUnhealthy bool
+	}{
+		{
+			name:     "Active",
+			response: `{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[1700000000,"5"]}]}}`,
+			want:     true,
+		},
+		{
+			name:     "Idle",
+			response: `{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[1700000000,"0.01"]}]}}`,
+			want:     false,
+		},
+		{
+			name:          "Empty Vector",
+			response:      `{"status":"success","data":{"resultType":"vector","result":[]}}`,
+			want:          true,
+			wantUnhealthy: true,
+		},
+		{
+			name:     "Query Error",
+			response: `{"status":"error","errorType":"bad_data","error":"parse error"}`,
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if got := r.URL.Query().Get("query"); got != "max(max_over_time((up)[1800s:]))" {
+					t.Errorf("unexpected query %q", got)
+				}
+				_, _ = w.Write([]byte(tt.response))
+			}))
+			defer server.Close()
+
+			provider, err := NewPrometheusProvider(server.URL, "up", 1, 30*time.Minute)
+			if err != nil {
+				t.Fatalf("Failed to create provider: %v", err)
+			}
+
+			got, err := provider.IsWorkTime(context.Background(), time.Now())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("IsWorkTime() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("IsWorkTime() = %v, want %v", got, tt.want)
+			}
+			if err := provider.Healthy(); (err != nil) != tt.wantUnhealthy {
+				t.Errorf("Healthy() error = %v, wantUnhealthy %v", err, tt.wantUnhealthy)
+			}
+
+			// As an override, it only has an opinion while the cluster is in use
+			got, ok, err := provider.Override(context.Background(), time.Now())
+			if (err != nil) != tt.wantErr || ok != tt.want || got != tt.want {
+				t.Errorf("Override() = %v, %v, %v, want %v with an opinion only during work time", got, ok, err, tt.want)
+			}
+		})
+	}
+}
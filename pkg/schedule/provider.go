@@ -9,4 +9,49 @@ import (
 type Provider interface {
 	// IsWorkTime checks if the given time is within working hours
 	IsWorkTime(ctx context.Context, t time.Time) (bool, error)
+	// LastSync returns when the provider last synced its data successfully,
+	// or the zero time if the provider doesn't sync data in the background
+	LastSync() time.Time
+	// Healthy returns an error if the provider can't currently refresh its data
+	Healthy() error
+}
+
+// Overrider is implemented by providers that only have an opinion at some times,
+// e.g. a calendar that only decides when a matching event exists.
+// Such providers can take precedence over others without deciding all the time.
+type Overrider interface {
+	// Override returns the decision for the given time, ok is false if the provider has no opinion
+	Override(ctx context.Context, t time.Time) (isWork bool, ok bool, err error)
+}
+
+// Observer is implemented by providers that decide based on observed activity rather than a schedule,
+// e.g. metrics. They can only answer for the current time and are left out of previews.
+type Observer interface {
+	// ObservesActivity returns true if the provider decides based on observed activity
+	ObservesActivity() bool
+}
+
+// isObserver returns true if the provider decides based on observed activity
+func isObserver(provider Provider) bool {
+	observer, ok := provider.(Observer)
+	return ok && observer.ObservesActivity()
+}
+
+// Closer is implemented by providers syncing data in the background
+type Closer interface {
+	// Close stops syncing and persists the provider's state, e.g. its cache
+	Close(ctx context.Context)
+}
+
+// Close closes the provider and any provider combined by it that syncs data in the background
+func Close(ctx context.Context, provider Provider) {
+	providers := []Provider{provider}
+	if composite, ok := provider.(*CompositeProvider); ok {
+		providers = composite.all()
+	}
+	for _, p := range providers {
+		if closer, ok := p.(Closer); ok {
+			closer.Close(ctx)
+		}
+	}
 }
@@ -12,6 +12,18 @@ type StaticProvider struct {
 	EndTime   string
 	TimeZone  string
 	WorkDays  map[time.Weekday]bool
+	// DayHours overrides StartTime and EndTime for individual weekdays
+	DayHours map[time.Weekday]TimeRange
+	// OffDates are dates ("2006-01-02") that are off all day, e.g. company holidays
+	OffDates map[string]bool
+	// WorkDates are dates ("2006-01-02") that are work time all day, e.g. release days
+	WorkDates map[string]bool
+}
+
+// TimeRange is a daily time range in "HH:MM" format
+type TimeRange struct {
+	StartTime string
+	EndTime   string
 }
 
 // NewStaticProvider creates a new static schedule provider
@@ -35,49 +47,179 @@ func NewStaticProvider(startTime, endTime, timeZone string, workDays map[time.We
 	}
 }
 
-// IsWorkTime checks if the current time is within the working hours
+// IsWorkTime checks if the current time is within the working hours.
+// Exception dates are checked first, then the weekday and time.
+// If the end time is not after the start time, the window is treated as an
+// overnight window that starts on a work day and ends on the following day.
 func (p *StaticProvider) IsWorkTime(ctx context.Context, now time.Time) (bool, error) {
+	isWork, _, err := p.evaluate(now)
+	return isWork, err
+}
+
+// Describe returns the exception date or work hours window the given time falls in
+func (p *StaticProvider) Describe(ctx context.Context, now time.Time) (string, error) {
+	_, detail, err := p.evaluate(now)
+	return detail, err
+}
+
+// evaluate returns whether it's work time and the exception date or window that decided it
+func (p *StaticProvider) evaluate(now time.Time) (bool, string, error) {
 	location, err := time.LoadLocation(p.TimeZone)
 	if err != nil {
-		return false, err
+		return false, "", err
 	}
 
 	nowInTz := now.In(location)
 
-	// Check if current day is a work day
+	date := nowInTz.Format("2006-01-02")
+	if p.OffDates[date] {
+		return false, fmt.Sprintf("off date %s", date), nil
+	}
+	if p.WorkDates[date] {
+		return true, fmt.Sprintf("work date %s", date), nil
+	}
+
+	startTime, endTime, err := p.hoursFor(nowInTz.Weekday(), location)
+	if err != nil {
+		return false, "", err
+	}
+
+	window := fmt.Sprintf("%s work hours %s-%s", nowInTz.Weekday(), startTime.Format("15:04"), endTime.Format("15:04"))
+	if p.WorkDays[nowInTz.Weekday()] {
+		start := atClock(nowInTz, startTime, location, false)
+		if endTime.After(startTime) {
+			end := atClock(nowInTz, endTime, location, true)
+			if !nowInTz.Before(start) && nowInTz.Before(end) {
+				return true, "within " + window, nil
+			}
+		} else if !nowInTz.Before(start) {
+			// Evening part of an overnight window, e.g. 22:00-06:00
+			return true, "within " + window, nil
+		}
+	}
+
+	// The morning part may belong to an overnight window started on the previous day, unless it was an off date
+	yesterday := nowInTz.AddDate(0, 0, -1).Weekday()
+	if p.isWorkDay(nowInTz.AddDate(0, 0, -1)) {
+		startTime, endTime, err := p.hoursFor(yesterday, location)
+		if err != nil {
+			return false, "", err
+		}
+		if !endTime.After(startTime) && nowInTz.Before(atClock(nowInTz, endTime, location, true)) {
+			return true, fmt.Sprintf("within %s work hours %s-%s", yesterday, startTime.Format("15:04"), endTime.Format("15:04")), nil
+		}
+	}
+
 	if !p.WorkDays[nowInTz.Weekday()] {
-		return false, nil
+		return false, fmt.Sprintf("%s is not a work day", nowInTz.Weekday()), nil
 	}
+	return false, "outside " + window, nil
+}
 
-	startTime, err := time.ParseInLocation("15:04", p.StartTime, location)
+// isWorkDay returns true if the day of t is a work date, or a work day that isn't an off date
+func (p *StaticProvider) isWorkDay(t time.Time) bool {
+	date := t.Format("2006-01-02")
+	if p.OffDates[date] {
+		return false
+	}
+	return p.WorkDates[date] || p.WorkDays[t.Weekday()]
+}
+
+// hoursFor returns the start and end clock times configured for the given weekday
+func (p *StaticProvider) hoursFor(day time.Weekday, location *time.Location) (time.Time, time.Time, error) {
+	start, end := p.StartTime, p.EndTime
+	if hours, ok := p.DayHours[day]; ok {
+		start, end = hours.StartTime, hours.EndTime
+	}
+
+	startTime, err := time.ParseInLocation("15:04", start, location)
 	if err != nil {
-		return false, err
+		return time.Time{}, time.Time{}, err
 	}
 
-	endTime, err := time.ParseInLocation("15:04", p.EndTime, location)
+	endTime, err := time.ParseInLocation("15:04", end, location)
 	if err != nil {
-		return false, err
+		return time.Time{}, time.Time{}, err
+	}
+
+	return startTime, endTime, nil
+}
+
+// atClock returns the time on the same day as day with the hour and minute of clock.
+// On DST changes, a wall clock time skipped by the gap resolves to the end of the gap,
+// and a repeated wall clock time resolves to its first occurrence, or its last one if late is set,
+// so that work hours are never shortened by a DST change.
+func atClock(day, clock time.Time, location *time.Location, late bool) time.Time {
+	hour, minute := clock.Hour(), clock.Minute()
+	t := time.Date(day.Year(), day.Month(), day.Day(), hour, minute, 0, 0, location)
+
+	if t.Hour() != hour || t.Minute() != minute {
+		// Skipped by a DST gap, time.Date moved it to either side of the gap
+		start, end := t.ZoneBounds()
+		if t.Hour()*60+t.Minute() < hour*60+minute {
+			return end
+		}
+		return start
+	}
+
+	// Repeated by a DST overlap, the same wall clock time also exists with the offset of a neighbouring zone
+	first, last := t, t
+	_, offset := t.Zone()
+	for _, other := range neighbourOffsets(t) {
+		candidate := t.Add(time.Duration(offset-other) * time.Second)
+		if c := candidate.In(location); c.Day() != t.Day() || c.Hour() != hour || c.Minute() != minute {
+			continue
+		}
+		if candidate.Before(first) {
+			first = candidate
+		}
+		if candidate.After(last) {
+			last = candidate
+		}
+	}
+	if late {
+		return last
 	}
+	return first
+}
 
-	startTime = time.Date(nowInTz.Year(), nowInTz.Month(), nowInTz.Day(),
-		startTime.Hour(), startTime.Minute(), 0, 0, location)
-	endTime = time.Date(nowInTz.Year(), nowInTz.Month(), nowInTz.Day(),
-		endTime.Hour(), endTime.Minute(), 0, 0, location)
+// neighbourOffsets returns the offsets of the zones right before and after the zone of t
+func neighbourOffsets(t time.Time) []int {
+	var offsets []int
+	start, end := t.ZoneBounds()
+	if !start.IsZero() {
+		_, offset := start.Add(-time.Nanosecond).Zone()
+		offsets = append(offsets, offset)
+	}
+	if !end.IsZero() {
+		_, offset := end.Zone()
+		offsets = append(offsets, offset)
+	}
+	return offsets
+}
+
+// LastSync returns the zero time, the static schedule has nothing to sync
+func (p *StaticProvider) LastSync() time.Time {
+	return time.Time{}
+}
 
-	return nowInTz.After(startTime) && nowInTz.Before(endTime), nil
+// Healthy always returns nil
+func (p *StaticProvider) Healthy() error {
+	return nil
 }
 
 // String returns a string representation of the StaticProvider
 func (p *StaticProvider) String() string {
 	workDays := make([]string, 0, len(p.WorkDays))
-	for day, enabled := range p.WorkDays {
-		if enabled {
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if p.WorkDays[day] {
 			workDays = append(workDays, day.String())
 		}
 	}
-	return fmt.Sprintf("StaticProvider{startTime: %s, endTime: %s, timeZone: %s, workDays: %v}",
+	return fmt.Sprintf("StaticProvider{startTime: %s, endTime: %s, timeZone: %s, workDays: %v, dayHours: %v}",
 		p.StartTime,
 		p.EndTime,
 		p.TimeZone,
-		workDays)
+		workDays,
+		p.DayHours)
 }
@@ -0,0 +1,420 @@
+package schedule
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestStaticProvider_IsWorkTime(t *testing.T) {
+	location, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Fatalf("Failed to load location: %v", err)
+	}
+
+	weekdays := map[time.Weekday]bool{
+		time.Monday:    true,
+		time.Tuesday:   true,
+		time.Wednesday: true,
+		time.Thursday:  true,
+		time.Friday:    true,
+	}
+
+	tests := []struct {
+		name      string
+		startTime string
+		endTime   string
+		dayHours  map[time.Weekday]TimeRange
+		offDates  map[string]bool
+		workDates map[string]bool
+		checkTime time.Time
+		want      bool
+	}{
+		{
+			name:      "Within Work Hours",
+			startTime: "09:00",
+			endTime:   "17:00",
+			checkTime: time.Date(2024, time.June, 3, 10, 0, 0, 0, location), // Monday
+			want:      true,
+		},
+		{
+			name:      "After Work Hours",
+			startTime: "09:00",
+			endTime:   "17:00",
+			checkTime: time.Date(2024, time.June, 3, 18, 0, 0, 0, location),
+			want:      false,
+		},
+		{
+			name:      "Weekend",
+			startTime: "09:00",
+			endTime:   "17:00",
+			checkTime: time.Date(2024, time.June, 8, 10, 0, 0, 0, location), // Saturday
+			want:      false,
+		},
+		{
+			name:      "At Start Of Work Hours",
+			startTime: "09:00",
+			endTime:   "17:00",
+			checkTime: time.Date(2024, time.June, 3, 9, 0, 0, 0, location),
+			want:      true,
+		},
+		{
+			name:      "At End Of Work Hours",
+			startTime: "09:00",
+			endTime:   "17:00",
+			checkTime: time.Date(2024, time.June, 3, 17, 0, 0, 0, location),
+			want:      false,
+		},
+		{
+			name:      "Overnight At Start",
+			startTime: "22:00",
+			endTime:   "06:00",
+			checkTime: time.Date(2024, time.June, 3, 22, 0, 0, 0, location),
+			want:      true,
+		},
+		{
+			name:      "Overnight At End",
+			startTime: "22:00",
+			endTime:   "06:00",
+			checkTime: time.Date(2024, time.June, 4, 6, 0, 0, 0, location),
+			want:      false,
+		},
+		{
+			name:      "Overnight Evening Part",
+			startTime: "22:00",
+			endTime:   "06:00",
+			checkTime: time.Date(2024, time.June, 3, 23, 0, 0, 0, location),
+			want:      true,
+		},
+		{
+			name:      "Overnight Morning Part",
+			startTime: "22:00",
+			endTime:   "06:00",
+			checkTime: time.Date(2024, time.June, 4, 5, 0, 0, 0, location),
+			want:      true,
+		},
+		{
+			name:      "Overnight Daytime Gap",
+			startTime: "22:00",
+			endTime:   "06:00",
+			checkTime: time.Date(2024, time.June, 4, 12, 0, 0, 0, location),
+			want:      false,
+		},
+		{
+			name:      "Overnight Saturday Morning After Friday Shift",
+			startTime: "22:00",
+			endTime:   "06:00",
+			checkTime: time.Date(2024, time.June, 8, 5, 0, 0, 0, location),
+			want:      true,
+		},
+		{
+			name:      "Overnight Monday Morning After Weekend",
+			startTime: "22:00",
+			endTime:   "06:00",
+			checkTime: time.Date(2024, time.June, 3, 5, 0, 0, 0, location),
+			want:      false,
+		},
+		{
+			name:      "Overnight Saturday Evening",
+			startTime: "22:00",
+			endTime:   "06:00",
+			checkTime: time.Date(2024, time.June, 8, 23, 0, 0, 0, location),
+			want:      false,
+		},
+		{
+			name:      "Short Friday Within Hours",
+			startTime: "09:00",
+			endTime:   "17:00",
+			dayHours:  map[time.Weekday]TimeRange{time.Friday: {StartTime: "09:00", EndTime: "14:00"}},
+			checkTime: time.Date(2024, time.June, 7, 13, 0, 0, 0, location),
+			want:      true,
+		},
+		{
+			name:      "Short Friday After Hours",
+			startTime: "09:00",
+			endTime:   "17:00",
+			dayHours:  map[time.Weekday]TimeRange{time.Friday: {StartTime: "09:00", EndTime: "14:00"}},
+			checkTime: time.Date(2024, time.June, 7, 15, 0, 0, 0, location),
+			want:      false,
+		},
+		{
+			name:      "Day Hours Do Not Affect Other Days",
+			startTime: "09:00",
+			endTime:   "17:00",
+			dayHours:  map[time.Weekday]TimeRange{time.Friday: {StartTime: "09:00", EndTime: "14:00"}},
+			checkTime: time.Date(2024, time.June, 6, 15, 0, 0, 0, location),
+			want:      true,
+		},
+		{
+			name:      "Overnight Day Hours Spill Into Next Day",
+			startTime: "09:00",
+			endTime:   "17:00",
+			dayHours:  map[time.Weekday]TimeRange{time.Friday: {StartTime: "20:00", EndTime: "02:00"}},
+			checkTime: time.Date(2024, time.June, 8, 1, 0, 0, 0, location),
+			want:      true,
+		},
+		{
+			name:      "Off Date Within Work Hours",
+			startTime: "09:00",
+			endTime:   "17:00",
+			offDates:  map[string]bool{"2024-06-03": true},
+			checkTime: time.Date(2024, time.June, 3, 10, 0, 0, 0, location),
+			want:      false,
+		},
+		{
+			name:      "Overnight Morning After Off Date",
+			startTime: "22:00",
+			endTime:   "06:00",
+			offDates:  map[string]bool{"2024-06-03": true},
+			checkTime: time.Date(2024, time.June, 4, 5, 0, 0, 0, location),
+			want:      false,
+		},
+		{
+			name:      "Overnight Evening After Off Date",
+			startTime: "22:00",
+			endTime:   "06:00",
+			offDates:  map[string]bool{"2024-06-03": true},
+			checkTime: time.Date(2024, time.June, 4, 23, 0, 0, 0, location),
+			want:      true,
+		},
+		{
+			name:      "Overnight Sunday Morning After Work Date",
+			startTime: "22:00",
+			endTime:   "06:00",
+			workDates: map[string]bool{"2024-06-08": true},
+			checkTime: time.Date(2024, time.June, 9, 5, 0, 0, 0, location),
+			want:      true,
+		},
+		{
+			name:      "Work Date On Weekend",
+			startTime: "09:00",
+			endTime:   "17:00",
+			workDates: map[string]bool{"2024-06-08": true},
+			checkTime: time.Date(2024, time.June, 8, 22, 0, 0, 0, location),
+			want:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := NewStaticProvider(tt.startTime, tt.endTime, "Asia/Shanghai", weekdays)
+			provider.DayHours = tt.dayHours
+			provider.OffDates = tt.offDates
+			provider.WorkDates = tt.workDates
+
+			got, err := provider.IsWorkTime(context.Background(), tt.checkTime)
+			if err != nil {
+				t.Fatalf("IsWorkTime() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("IsWorkTime() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStaticProvider_DST(t *testing.T) {
+	everyDay := map[time.Weekday]bool{
+		time.Sunday:    true,
+		time.Monday:    true,
+		time.Tuesday:   true,
+		time.Wednesday: true,
+		time.Thursday:  true,
+		time.Friday:    true,
+		time.Saturday:  true,
+	}
+
+	// Check times are in UTC as wall clock times are skipped or repeated on DST changes.
+	// Europe/Berlin skips 02:00-03:00 on 2024-03-31 (01:00 UTC) and repeats 02:00-03:00 on 2024-10-27 (01:00 UTC),
+	// America/New_York skips 02:00-03:00 on 2024-03-10 (07:00 UTC) and repeats 01:00-02:00 on 2024-11-03 (06:00 UTC).
+	tests := []struct {
+		name      string
+		timeZone  string
+		startTime string
+		endTime   string
+		checkTime time.Time
+		want      bool
+	}{
+		{
+			name:      "Spring Forward Before Work Hours",
+			timeZone:  "Europe/Berlin",
+			startTime: "09:00",
+			endTime:   "18:00",
+			checkTime: time.Date(2024, time.March, 31, 6, 59, 30, 0, time.UTC), // 08:59:30 CEST
+			want:      false,
+		},
+		{
+			name:      "Spring Forward Start Of Work Hours",
+			timeZone:  "Europe/Berlin",
+			startTime: "09:00",
+			endTime:   "18:00",
+			checkTime: time.Date(2024, time.March, 31, 7, 0, 30, 0, time.UTC), // 09:00:30 CEST
+			want:      true,
+		},
+		{
+			name:      "Spring Forward End Of Work Hours",
+			timeZone:  "Europe/Berlin",
+			startTime: "09:00",
+			endTime:   "18:00",
+			checkTime: time.Date(2024, time.March, 31, 16, 0, 30, 0, time.UTC), // 18:00:30 CEST
+			want:      false,
+		},
+		{
+			name:      "Spring Forward Start In Gap",
+			timeZone:  "Europe/Berlin",
+			startTime: "02:30",
+			endTime:   "06:00",
+			checkTime: time.Date(2024, time.March, 31, 1, 10, 0, 0, time.UTC), // 03:10 CEST
+			want:      true,
+		},
+		{
+			name:      "Spring Forward Before Gap",
+			timeZone:  "Europe/Berlin",
+			startTime: "02:30",
+			endTime:   "06:00",
+			checkTime: time.Date(2024, time.March, 31, 0, 59, 0, 0, time.UTC), // 01:59 CET
+			want:      false,
+		},
+		{
+			name:      "Spring Forward Overnight End In Gap",
+			timeZone:  "Europe/Berlin",
+			startTime: "22:00",
+			endTime:   "02:30",
+			checkTime: time.Date(2024, time.March, 31, 0, 59, 0, 0, time.UTC), // 01:59 CET
+			want:      true,
+		},
+		{
+			name:      "Spring Forward Overnight After Gap",
+			timeZone:  "Europe/Berlin",
+			startTime: "22:00",
+			endTime:   "02:30",
+			checkTime: time.Date(2024, time.March, 31, 1, 20, 0, 0, time.UTC), // 03:20 CEST
+			want:      false,
+		},
+		{
+			name:      "Spring Forward Overnight Morning",
+			timeZone:  "Europe/Berlin",
+			startTime: "22:00",
+			endTime:   "06:00",
+			checkTime: time.Date(2024, time.March, 31, 3, 30, 0, 0, time.UTC), // 05:30 CEST
+			want:      true,
+		},
+		{
+			name:      "Spring Forward Overnight After End",
+			timeZone:  "Europe/Berlin",
+			startTime: "22:00",
+			endTime:   "06:00",
+			checkTime: time.Date(2024, time.March, 31, 4, 30, 0, 0, time.UTC), // 06:30 CEST
+			want:      false,
+		},
+		{
+			name:      "Fall Back Before Work Hours",
+			timeZone:  "Europe/Berlin",
+			startTime: "09:00",
+			endTime:   "18:00",
+			checkTime: time.Date(2024, time.October, 27, 7, 59, 30, 0, time.UTC), // 08:59:30 CET
+			want:      false,
+		},
+		{
+			name:      "Fall Back Start Of Work Hours",
+			timeZone:  "Europe/Berlin",
+			startTime: "09:00",
+			endTime:   "18:00",
+			checkTime: time.Date(2024, time.October, 27, 8, 0, 30, 0, time.UTC), // 09:00:30 CET
+			want:      true,
+		},
+		{
+			name:      "Fall Back End Of Work Hours",
+			timeZone:  "Europe/Berlin",
+			startTime: "09:00",
+			endTime:   "18:00",
+			checkTime: time.Date(2024, time.October, 27, 17, 0, 30, 0, time.UTC), // 18:00:30 CET
+			want:      false,
+		},
+		{
+			name:      "Fall Back Overnight First Occurrence",
+			timeZone:  "Europe/Berlin",
+			startTime: "22:00",
+			endTime:   "02:30",
+			checkTime: time.Date(2024, time.October, 27, 0, 15, 0, 0, time.UTC), // 02:15 CEST
+			want:      true,
+		},
+		{
+			name:      "Fall Back Overnight Second Occurrence",
+			timeZone:  "Europe/Berlin",
+			startTime: "22:00",
+			endTime:   "02:30",
+			checkTime: time.Date(2024, time.October, 27, 1, 15, 0, 0, time.UTC), // 02:15 CET
+			want:      true,
+		},
+		{
+			name:      "Fall Back Overnight After Second Occurrence",
+			timeZone:  "Europe/Berlin",
+			startTime: "22:00",
+			endTime:   "02:30",
+			checkTime: time.Date(2024, time.October, 27, 1, 35, 0, 0, time.UTC), // 02:35 CET
+			want:      false,
+		},
+		{
+			name:      "Fall Back Start At First Occurrence",
+			timeZone:  "Europe/Berlin",
+			startTime: "02:30",
+			endTime:   "06:00",
+			checkTime: time.Date(2024, time.October, 27, 0, 35, 0, 0, time.UTC), // 02:35 CEST
+			want:      true,
+		},
+		{
+			name:      "Fall Back Before First Occurrence",
+			timeZone:  "Europe/Berlin",
+			startTime: "02:30",
+			endTime:   "06:00",
+			checkTime: time.Date(2024, time.October, 27, 0, 25, 0, 0, time.UTC), // 02:25 CEST
+			want:      false,
+		},
+		{
+			name:      "New York Spring Forward Start In Gap",
+			timeZone:  "America/New_York",
+			startTime: "02:30",
+			endTime:   "09:00",
+			checkTime: time.Date(2024, time.March, 10, 7, 5, 0, 0, time.UTC), // 03:05 EDT
+			want:      true,
+		},
+		{
+			name:      "New York Fall Back Overnight First Occurrence",
+			timeZone:  "America/New_York",
+			startTime: "22:00",
+			endTime:   "01:30",
+			checkTime: time.Date(2024, time.November, 3, 5, 15, 0, 0, time.UTC), // 01:15 EDT
+			want:      true,
+		},
+		{
+			name:      "New York Fall Back Overnight Second Occurrence",
+			timeZone:  "America/New_York",
+			startTime: "22:00",
+			endTime:   "01:30",
+			checkTime: time.Date(2024, time.November, 3, 6, 15, 0, 0, time.UTC), // 01:15 EST
+			want:      true,
+		},
+		{
+			name:      "New York Fall Back Overnight After End",
+			timeZone:  "America/New_York",
+			startTime: "22:00",
+			endTime:   "01:30",
+			checkTime: time.Date(2024, time.November, 3, 6, 35, 0, 0, time.UTC), // 01:35 EST
+			want:      false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := NewStaticProvider(tt.startTime, tt.endTime, tt.timeZone, everyDay)
+			got, err := provider.IsWorkTime(context.Background(), tt.checkTime)
+			if err != nil {
+				t.Fatalf("IsWorkTime() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("IsWorkTime() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
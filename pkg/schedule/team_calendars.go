@@ -0,0 +1,404 @@
+package schedule
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TeamCalendarsProvider is a schedule provider that reads events from Atlassian Team Calendars for Confluence,
+// for organizations that track team availability in Confluence rather than Google Calendar or ICS feeds.
+// It is off time while an event of one of the configured event types is in progress.
+type TeamCalendarsProvider struct {
+	client         httpClient
+	baseURL        string
+	username       string
+	token          string
+	subCalendarIDs []string
+	eventTypes     map[string]bool
+	location       *time.Location
+	syncInterval   time.Duration
+	cacheDays      int
+	events         []calendarEvent
+	lastSync       time.Time
+	// syncErr is the error of the last sync, nil if it succeeded
+	syncErr  error
+	mu       sync.RWMutex
+	store    CacheStore
+	cacheKey string
+	// stop stops the background sync
+	stop context.CancelFunc
+}
+
+// teamCalendarsCacheSnapshot is the persisted form of the event cache
+type teamCalendarsCacheSnapshot struct {
+	LastSync time.Time       `json:"lastSync"`
+	Events   []calendarEvent `json:"events"`
+}
+
+// teamCalendarsEventList is the subset of the Team Calendars events response we need
+type teamCalendarsEventList struct {
+	Success bool `json:"success"`
+	Events  []struct {
+		Title             string `json:"title"`
+		Start             string `json:"start"`
+		End               string `json:"end"`
+		AllDay            bool   `json:"allDay"`
+		EventType         string `json:"eventType"`
+		CustomEventTypeID string `json:"customEventTypeId"`
+	} `json:"events"`
+}
+
+// TeamCalendarsOptions contains the settings for creating a TeamCalendarsProvider
+type TeamCalendarsOptions struct {
+	// BaseURL is the Confluence base URL, e.g. "https://example.atlassian.net/wiki"
+	BaseURL string
+	// Username is the Atlassian account email for Confluence Cloud, empty to use Token as a personal access token
+	Username string
+	// Token is the API token (Cloud) or personal access token (Data Center)
+	Token string
+	// SubCalendarIDs are the team calendars whose events are read
+	SubCalendarIDs []string
+	// EventTypes are the event types (e.g. "leaves") or custom event type IDs that mark off time
+	EventTypes []string
+	// Location is used for all-day events (default: time.Local)
+	Location *time.Location
+	// SyncInterval is how often to refresh the event cache
+	SyncInterval time.Duration
+	// CacheDays is how many days of events to cache
+	CacheDays int
+	// CacheStore persists the event cache across restarts, optional
+	CacheStore CacheStore
+}
+
+// NewTeamCalendarsProvider creates a new Team Calendars provider
+func NewTeamCalendarsProvider(opts TeamCalendarsOptions) (*TeamCalendarsProvider, error) {
+	if opts.Token == "" {
+		return nil, fmt.Errorf("token is required for Team Calendars")
+	}
+	return newTeamCalendarsProvider(context.Background(), &http.Client{Timeout: 30 * time.Second}, opts)
+}
+
+// newTeamCalendarsProvider creates the provider with the given client and syncs the events
+func newTeamCalendarsProvider(ctx context.Context, client httpClient, opts TeamCalendarsOptions) (*TeamCalendarsProvider, error) {
+	if opts.BaseURL == "" {
+		return nil, fmt.Errorf("base URL is required for Team Calendars")
+	}
+	if len(opts.SubCalendarIDs) == 0 {
+		return nil, fmt.Errorf("at least one sub-calendar ID is required for Team Calendars")
+	}
+	if len(opts.EventTypes) == 0 {
+		return nil, fmt.Errorf("at least one event type is required for Team Calendars")
+	}
+	location := opts.Location
+	if location == nil {
+		location = time.Local
+	}
+
+	provider := &TeamCalendarsProvider{
+		client:         client,
+		baseURL:        strings.TrimSuffix(opts.BaseURL, "/"),
+		username:       opts.Username,
+		token:          opts.Token,
+		subCalendarIDs: opts.SubCalendarIDs,
+		eventTypes:     toSet(opts.EventTypes),
+		location:       location,
+		syncInterval:   opts.SyncInterval,
+		cacheDays:      opts.CacheDays,
+		store:          opts.CacheStore,
+		cacheKey:       cacheKey("team-calendars", append([]string{opts.BaseURL}, opts.SubCalendarIDs...)...),
+	}
+
+	loaded := provider.loadCache(ctx)
+
+	// Initial sync
+	if err := provider.sync(ctx); err != nil {
+		if !loaded {
+			return nil, fmt.Errorf("failed initial event sync: %v", err)
+		}
+		slog.Warn("Initial Team Calendars sync failed, using persisted cache",
+			"last_sync", provider.lastSync,
+			"error", err,
+		)
+	}
+
+	// Start background sync
+	syncCtx, stop := context.WithCancel(context.Background())
+	provider.stop = stop
+	go provider.backgroundSync(syncCtx)
+
+	return provider, nil
+}
+
+func (p *TeamCalendarsProvider) backgroundSync(ctx context.Context) {
+	ticker := time.NewTicker(p.syncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := p.sync(ctx); err != nil {
+				slog.Error("Failed to sync Team Calendars events", "error", err)
+			}
+		}
+	}
+}
+
+// sync syncs the events and records the result for Healthy
+func (p *TeamCalendarsProvider) sync(ctx context.Context) error {
+	err := p.syncEvents(ctx)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.syncErr = err
+	return err
+}
+
+func (p *TeamCalendarsProvider) syncEvents(ctx context.Context) error {
+	// Start a day early to include events already in progress
+	now := time.Now().In(p.location)
+	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, p.location).AddDate(0, 0, -1)
+	end := start.AddDate(0, 0, p.cacheDays+1)
+	slog.Info("Syncing Team Calendars events", "start", start, "end", end)
+
+	var events []calendarEvent
+	for _, subCalendarID := range p.subCalendarIDs {
+		list, err := p.listEvents(ctx, subCalendarID, start, end)
+		if err != nil {
+			return err
+		}
+		for _, event := range list.Events {
+			if !p.eventTypes[event.EventType] && !p.eventTypes[event.CustomEventTypeID] {
+				continue
+			}
+			eventStart, eventEnd, err := p.parseEventTimes(event.Start, event.End, event.AllDay)
+			if err != nil {
+				slog.Warn("Ignoring Team Calendars event with invalid times", "title", event.Title, "error", err)
+				continue
+			}
+			events = append(events, calendarEvent{
+				Start:   eventStart,
+				End:     eventEnd,
+				Summary: event.Title,
+			})
+		}
+	}
+
+	p.mu.Lock()
+	p.events = events
+	p.lastSync = time.Now()
+	p.mu.Unlock()
+
+	slog.Info("Team Calendars events synced successfully", "events_count", len(events))
+
+	p.saveCache(ctx)
+	return nil
+}
+
+// parseEventTimes parses the event start and end. All-day events span whole days in the
+// provider location and their end date is inclusive, so it is moved to the next midnight.
+func (p *TeamCalendarsProvider) parseEventTimes(start, end string, allDay bool) (time.Time, time.Time, error) {
+	if !allDay {
+		eventStart, err := time.Parse(time.RFC3339, start)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		eventEnd, err := time.Parse(time.RFC3339, end)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		return eventStart, eventEnd, nil
+	}
+
+	if len(start) < 10 || len(end) < 10 {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid all-day event dates %q to %q", start, end)
+	}
+	eventStart, err := time.ParseInLocation("2006-01-02", start[:10], p.location)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	eventEnd, err := time.ParseInLocation("2006-01-02", end[:10], p.location)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return eventStart, eventEnd.AddDate(0, 0, 1), nil
+}
+
+// listEvents fetches the events of a sub-calendar in the time range
+func (p *TeamCalendarsProvider) listEvents(ctx context.Context, subCalendarID string, start, end time.Time) (*teamCalendarsEventList, error) {
+	params := url.Values{}
+	params.Set("subCalendarId", subCalendarID)
+	params.Set("userTimeZoneId", p.location.String())
+	params.Set("start", start.Format(time.RFC3339))
+	params.Set("end", end.Format(time.RFC3339))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		p.baseURL+"/rest/calendar-services/1.0/calendar/events.json?"+params.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Team Calendars request: %v", err)
+	}
+	if p.username != "" {
+		req.SetBasicAuth(p.username, p.token)
+	} else {
+		req.Header.Set("Authorization", "Bearer "+p.token)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Team Calendars events: %v", err)
+	}
+	defer func() {
+		if e := resp.Body.Close(); e != nil {
+			slog.Error("Failed to close Team Calendars response body", "error", e)
+		}
+	}()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Team Calendars response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to list Team Calendars events: unexpected status code %d", resp.StatusCode)
+	}
+
+	var list teamCalendarsEventList
+	if err := json.Unmarshal(body, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse Team Calendars events: %v", err)
+	}
+	if !list.Success {
+		return nil, fmt.Errorf("failed to list Team Calendars events of sub-calendar %s", subCalendarID)
+	}
+	return &list, nil
+}
+
+// loadCache loads the persisted event cache, it returns true if a cache was loaded
+func (p *TeamCalendarsProvider) loadCache(ctx context.Context) bool {
+	if p.store == nil {
+		return false
+	}
+
+	data, err := p.store.Load(ctx, p.cacheKey)
+	if err != nil {
+		slog.Warn("Failed to load persisted Team Calendars cache", "error", err)
+		return false
+	}
+	if data == nil {
+		return false
+	}
+
+	var snapshot teamCalendarsCacheSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		slog.Warn("Failed to parse persisted Team Calendars cache", "error", err)
+		return false
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.events = snapshot.Events
+	p.lastSync = snapshot.LastSync
+	slog.Info("Loaded persisted Team Calendars cache", "last_sync", snapshot.LastSync, "events", len(snapshot.Events))
+	return true
+}
+
+// saveCache persists the event cache
+func (p *TeamCalendarsProvider) saveCache(ctx context.Context) {
+	if p.store == nil {
+		return
+	}
+
+	p.mu.RLock()
+	data, err := json.Marshal(teamCalendarsCacheSnapshot{
+		LastSync: p.lastSync,
+		Events:   p.events,
+	})
+	p.mu.RUnlock()
+	if err != nil {
+		slog.Error("Failed to marshal Team Calendars cache", "error", err)
+		return
+	}
+
+	if err := p.store.Save(ctx, p.cacheKey, data); err != nil {
+		slog.Error("Failed to persist Team Calendars cache", "error", err)
+	}
+}
+
+// Close stops the background sync and persists the cache
+func (p *TeamCalendarsProvider) Close(ctx context.Context) {
+	p.stop()
+	p.saveCache(ctx)
+}
+
+// IsWorkTime returns false while a matching event is in progress, true otherwise
+func (p *TeamCalendarsProvider) IsWorkTime(ctx context.Context, t time.Time) (bool, error) {
+	isWork, ok, err := p.Override(ctx, t)
+	if err != nil || ok {
+		return isWork, err
+	}
+	return true, nil
+}
+
+// Override returns off time while a matching event is in progress, the provider has no opinion otherwise
+func (p *TeamCalendarsProvider) Override(ctx context.Context, t time.Time) (bool, bool, error) {
+	return false, p.match(t) != nil, nil
+}
+
+// Describe returns the matching event in progress at the given time, if any
+func (p *TeamCalendarsProvider) Describe(ctx context.Context, t time.Time) (string, error) {
+	event := p.match(t)
+	if event == nil {
+		return "", nil
+	}
+	return fmt.Sprintf("Team Calendars event '%s' %s", event.Summary, formatRange(event.Start, event.End, p.location)), nil
+}
+
+// match returns the first matching event in progress at the given time
+func (p *TeamCalendarsProvider) match(t time.Time) *calendarEvent {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	for i, event := range p.events {
+		if !t.Before(event.Start) && t.Before(event.End) {
+			return &p.events[i]
+		}
+	}
+	return nil
+}
+
+// LastSync returns when the events were last synced successfully
+func (p *TeamCalendarsProvider) LastSync() time.Time {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.lastSync
+}
+
+// Healthy returns an error if the last sync failed
+func (p *TeamCalendarsProvider) Healthy() error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.syncErr != nil {
+		return fmt.Errorf("team calendars sync failed: %v", p.syncErr)
+	}
+	return nil
+}
+
+// String returns a string representation of the TeamCalendarsProvider
+func (p *TeamCalendarsProvider) String() string {
+	return fmt.Sprintf("TeamCalendarsProvider{baseURL: %s, subCalendars: %v, eventTypes: %d, location: %s, syncInterval: %v, cacheDays: %d, events: %d}",
+		p.baseURL,
+		p.subCalendarIDs,
+		len(p.eventTypes),
+		p.location,
+		p.syncInterval,
+		p.cacheDays,
+		len(p.events))
+}
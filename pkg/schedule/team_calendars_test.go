@@ -0,0 +1,114 @@
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTeamCalendarsProvider_IsWorkTime(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Hour)
+	today := now.Format("2006-01-02")
+	tomorrow := now.AddDate(0, 0, 1).Format("2006-01-02")
+	inTwoDays := now.AddDate(0, 0, 2)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, token, ok := r.BasicAuth(); !ok || user != "me@example.com" || token != "api-token" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != "/wiki/rest/calendar-services/1.0/calendar/events.json" {
+			http.NotFound(w, r)
+			return
+		}
+		switch r.URL.Query().Get("subCalendarId") {
+		case "holidays":
+			_, _ = fmt.Fprintf(w, `{"success": true, "events": [
+				{"title": "Company Holiday", "start": %q, "end": %q, "allDay": true, "eventType": "custom", "customEventTypeId": "holiday-type"}
+			]}`, tomorrow, tomorrow)
+		case "leaves":
+			_, _ = fmt.Fprintf(w, `{"success": true, "events": [
+				{"title": "Offsite", "start": %q, "end": %q, "allDay": false, "eventType": "leaves"},
+				{"title": "Conference", "start": %q, "end": %q, "allDay": true, "eventType": "travel"}
+			]}`, inTwoDays.Format(time.RFC3339), inTwoDays.Add(4*time.Hour).Format(time.RFC3339), today, today)
+		default:
+			_, _ = fmt.Fprint(w, `{"success": false}`)
+		}
+	}))
+	defer server.Close()
+
+	provider, err := newTeamCalendarsProvider(context.Background(), server.Client(), TeamCalendarsOptions{
+		BaseURL:        server.URL + "/wiki/",
+		Username:       "me@example.com",
+		Token:          "api-token",
+		SubCalendarIDs: []string{"holidays", "leaves"},
+		EventTypes:     []string{"leaves", "holiday-type"},
+		Location:       time.UTC,
+		SyncInterval:   time.Hour,
+		CacheDays:      7,
+	})
+	if err != nil {
+		t.Fatalf("newTeamCalendarsProvider() error = %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		checkTime time.Time
+		want      bool
+	}{
+		{
+			name:      "Event Of Other Type",
+			checkTime: now,
+			want:      true,
+		},
+		{
+			name:      "All Day Custom Event Type",
+			checkTime: now.AddDate(0, 0, 1),
+			want:      false,
+		},
+		{
+			name:      "Timed Leave In Progress",
+			checkTime: inTwoDays.Add(time.Hour),
+			want:      false,
+		},
+		{
+			name:      "After Timed Leave",
+			checkTime: inTwoDays.Add(5 * time.Hour),
+			want:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := provider.IsWorkTime(context.Background(), tt.checkTime)
+			if err != nil {
+				t.Fatalf("IsWorkTime() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("IsWorkTime() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTeamCalendarsProvider_SyncError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `{"success": false}`)
+	}))
+	defer server.Close()
+
+	_, err := newTeamCalendarsProvider(context.Background(), server.Client(), TeamCalendarsOptions{
+		BaseURL:        server.URL,
+		Token:          "pat",
+		SubCalendarIDs: []string{"unknown"},
+		EventTypes:     []string{"leaves"},
+		SyncInterval:   time.Hour,
+		CacheDays:      7,
+	})
+	if err == nil {
+		t.Error("newTeamCalendarsProvider() error = nil, want error for a failed initial sync")
+	}
+}
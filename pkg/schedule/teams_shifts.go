@@ -0,0 +1,373 @@
+package schedule
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2/clientcredentials"
+	"golang.org/x/oauth2/microsoft"
+)
+
+const (
+	// graphBaseURL is the base URL of the Microsoft Graph API
+	graphBaseURL = "https://graph.microsoft.com/v1.0"
+	// graphScope is the OAuth2 scope for Microsoft Graph with application permissions
+	graphScope = "https://graph.microsoft.com/.default"
+)
+
+// TeamsShiftsProvider is a schedule provider that reads shift assignments from Microsoft Teams Shifts,
+// so that clusters supporting shift-based operations teams are up exactly when a shift is staffed.
+// It is work time while any published shift is in progress.
+type TeamsShiftsProvider struct {
+	client           httpClient
+	baseURL          string
+	teamID           string
+	schedulingGroups map[string]bool
+	syncInterval     time.Duration
+	cacheDays        int
+	shifts           []shiftEntry
+	lastSync         time.Time
+	// syncErr is the error of the last sync, nil if it succeeded
+	syncErr  error
+	mu       sync.RWMutex
+	store    CacheStore
+	cacheKey string
+	// stop stops the background sync
+	stop context.CancelFunc
+}
+
+// shiftEntry is a staffed shift
+type shiftEntry struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// teamsShiftsCacheSnapshot is the persisted form of the shift cache
+type teamsShiftsCacheSnapshot struct {
+	LastSync time.Time    `json:"lastSync"`
+	Shifts   []shiftEntry `json:"shifts"`
+}
+
+// graphShiftList is the subset of the Graph API shift list response we need
+type graphShiftList struct {
+	Value []struct {
+		UserID            string `json:"userId"`
+		SchedulingGroupID string `json:"schedulingGroupId"`
+		SharedShift       *struct {
+			StartDateTime time.Time `json:"startDateTime"`
+			EndDateTime   time.Time `json:"endDateTime"`
+		} `json:"sharedShift"`
+	} `json:"value"`
+	NextLink string `json:"@odata.nextLink"`
+}
+
+// TeamsShiftsOptions contains the settings for creating a TeamsShiftsProvider
+type TeamsShiftsOptions struct {
+	// TenantID is the Microsoft Entra tenant of the app registration
+	TenantID string
+	// ClientID is the application (client) ID of the app registration
+	ClientID string
+	// ClientSecret is a client secret of the app registration
+	ClientSecret string
+	// TeamID is the team whose schedule is read
+	TeamID string
+	// SchedulingGroupIDs limits the shifts to these scheduling groups, all groups if empty
+	SchedulingGroupIDs []string
+	// SyncInterval is how often to refresh the shift cache
+	SyncInterval time.Duration
+	// CacheDays is how many days of shifts to cache
+	CacheDays int
+	// CacheStore persists the shift cache across restarts, optional
+	CacheStore CacheStore
+}
+
+// NewTeamsShiftsProvider creates a new Teams Shifts provider authenticated with the client credentials
+// of an app registration with the Schedule.Read.All application permission
+func NewTeamsShiftsProvider(opts TeamsShiftsOptions) (*TeamsShiftsProvider, error) {
+	if opts.TenantID == "" || opts.ClientID == "" || opts.ClientSecret == "" {
+		return nil, fmt.Errorf("tenant ID, client ID and client secret are required for Teams Shifts")
+	}
+
+	ctx := context.Background()
+	credentials := clientcredentials.Config{
+		ClientID:     opts.ClientID,
+		ClientSecret: opts.ClientSecret,
+		TokenURL:     microsoft.AzureADEndpoint(opts.TenantID).TokenURL,
+		Scopes:       []string{graphScope},
+	}
+
+	return newTeamsShiftsProvider(ctx, credentials.Client(ctx), graphBaseURL, opts)
+}
+
+// newTeamsShiftsProvider creates the provider with the given authenticated client and syncs the shifts
+func newTeamsShiftsProvider(ctx context.Context, client httpClient, baseURL string, opts TeamsShiftsOptions) (*TeamsShiftsProvider, error) {
+	if opts.TeamID == "" {
+		return nil, fmt.Errorf("team ID is required for Teams Shifts")
+	}
+
+	var schedulingGroups map[string]bool
+	if len(opts.SchedulingGroupIDs) > 0 {
+		schedulingGroups = toSet(opts.SchedulingGroupIDs)
+	}
+
+	provider := &TeamsShiftsProvider{
+		client:           client,
+		baseURL:          baseURL,
+		teamID:           opts.TeamID,
+		schedulingGroups: schedulingGroups,
+		syncInterval:     opts.SyncInterval,
+		cacheDays:        opts.CacheDays,
+		store:            opts.CacheStore,
+		cacheKey:         cacheKey("teams-shifts", append([]string{opts.TeamID}, opts.SchedulingGroupIDs...)...),
+	}
+
+	loaded := provider.loadCache(ctx)
+
+	// Initial sync
+	if err := provider.sync(ctx); err != nil {
+		if !loaded {
+			return nil, fmt.Errorf("failed initial shift sync: %v", err)
+		}
+		slog.Warn("Initial Teams Shifts sync failed, using persisted cache",
+			"last_sync", provider.lastSync,
+			"error", err,
+		)
+	}
+
+	// Start background sync
+	syncCtx, stop := context.WithCancel(context.Background())
+	provider.stop = stop
+	go provider.backgroundSync(syncCtx)
+
+	return provider, nil
+}
+
+// toSet converts a list of strings to a set
+func toSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, value := range values {
+		set[value] = true
+	}
+	return set
+}
+
+func (p *TeamsShiftsProvider) backgroundSync(ctx context.Context) {
+	ticker := time.NewTicker(p.syncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := p.sync(ctx); err != nil {
+				slog.Error("Failed to sync Teams shifts", "error", err)
+			}
+		}
+	}
+}
+
+// sync syncs the shifts and records the result for Healthy
+func (p *TeamsShiftsProvider) sync(ctx context.Context) error {
+	err := p.syncShifts(ctx)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.syncErr = err
+	return err
+}
+
+func (p *TeamsShiftsProvider) syncShifts(ctx context.Context) error {
+	// Start a day early to include shifts already in progress
+	now := time.Now().UTC()
+	timeMin := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, -1)
+	timeMax := timeMin.AddDate(0, 0, p.cacheDays+1)
+	slog.Info("Syncing Teams shifts", "timeMin", timeMin, "timeMax", timeMax)
+
+	params := url.Values{}
+	params.Set("$filter", fmt.Sprintf("sharedShift/startDateTime ge %s and sharedShift/endDateTime le %s",
+		timeMin.Format(time.RFC3339), timeMax.Format(time.RFC3339)))
+	next := p.baseURL + "/teams/" + url.PathEscape(p.teamID) + "/schedule/shifts?" + params.Encode()
+
+	var shifts []shiftEntry
+	for next != "" {
+		list, err := p.listShifts(ctx, next)
+		if err != nil {
+			return err
+		}
+		for _, shift := range list.Value {
+			// Open shifts and drafts have no shared shift
+			if shift.SharedShift == nil || shift.UserID == "" {
+				continue
+			}
+			if p.schedulingGroups != nil && !p.schedulingGroups[shift.SchedulingGroupID] {
+				continue
+			}
+			shifts = append(shifts, shiftEntry{
+				Start: shift.SharedShift.StartDateTime,
+				End:   shift.SharedShift.EndDateTime,
+			})
+		}
+		next = list.NextLink
+	}
+
+	p.mu.Lock()
+	p.shifts = shifts
+	p.lastSync = time.Now()
+	p.mu.Unlock()
+
+	slog.Info("Teams shifts synced successfully", "shifts_count", len(shifts))
+
+	p.saveCache(ctx)
+	return nil
+}
+
+// listShifts fetches one page of shifts
+func (p *TeamsShiftsProvider) listShifts(ctx context.Context, pageURL string) (*graphShiftList, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Teams shifts request: %v", err)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Teams shifts: %v", err)
+	}
+	defer func() {
+		if e := resp.Body.Close(); e != nil {
+			slog.Error("Failed to close Teams shifts response body", "error", e)
+		}
+	}()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Teams shifts response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to list Teams shifts: unexpected status code %d: %s", resp.StatusCode, body)
+	}
+
+	var list graphShiftList
+	if err := json.Unmarshal(body, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse Teams shifts: %v", err)
+	}
+	return &list, nil
+}
+
+// loadCache loads the persisted shift cache, it returns true if a cache was loaded
+func (p *TeamsShiftsProvider) loadCache(ctx context.Context) bool {
+	if p.store == nil {
+		return false
+	}
+
+	data, err := p.store.Load(ctx, p.cacheKey)
+	if err != nil {
+		slog.Warn("Failed to load persisted Teams shifts cache", "error", err)
+		return false
+	}
+	if data == nil {
+		return false
+	}
+
+	var snapshot teamsShiftsCacheSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		slog.Warn("Failed to parse persisted Teams shifts cache", "error", err)
+		return false
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.shifts = snapshot.Shifts
+	p.lastSync = snapshot.LastSync
+	slog.Info("Loaded persisted Teams shifts cache", "last_sync", snapshot.LastSync, "shifts", len(snapshot.Shifts))
+	return true
+}
+
+// saveCache persists the shift cache
+func (p *TeamsShiftsProvider) saveCache(ctx context.Context) {
+	if p.store == nil {
+		return
+	}
+
+	p.mu.RLock()
+	data, err := json.Marshal(teamsShiftsCacheSnapshot{
+		LastSync: p.lastSync,
+		Shifts:   p.shifts,
+	})
+	p.mu.RUnlock()
+	if err != nil {
+		slog.Error("Failed to marshal Teams shifts cache", "error", err)
+		return
+	}
+
+	if err := p.store.Save(ctx, p.cacheKey, data); err != nil {
+		slog.Error("Failed to persist Teams shifts cache", "error", err)
+	}
+}
+
+// Close stops the background sync and persists the cache
+func (p *TeamsShiftsProvider) Close(ctx context.Context) {
+	p.stop()
+	p.saveCache(ctx)
+}
+
+// IsWorkTime returns true if any shift is in progress at the given time
+func (p *TeamsShiftsProvider) IsWorkTime(ctx context.Context, t time.Time) (bool, error) {
+	return p.match(t) != nil, nil
+}
+
+// Describe returns the shift in progress at the given time, if any
+func (p *TeamsShiftsProvider) Describe(ctx context.Context, t time.Time) (string, error) {
+	shift := p.match(t)
+	if shift == nil {
+		return "no shift in progress", nil
+	}
+	return fmt.Sprintf("Teams shift %s", formatRange(shift.Start, shift.End, time.UTC)), nil
+}
+
+// match returns the first shift in progress at the given time
+func (p *TeamsShiftsProvider) match(t time.Time) *shiftEntry {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	for i, shift := range p.shifts {
+		if !t.Before(shift.Start) && t.Before(shift.End) {
+			return &p.shifts[i]
+		}
+	}
+	return nil
+}
+
+// LastSync returns when the shifts were last synced successfully
+func (p *TeamsShiftsProvider) LastSync() time.Time {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.lastSync
+}
+
+// Healthy returns an error if the last sync failed
+func (p *TeamsShiftsProvider) Healthy() error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.syncErr != nil {
+		return fmt.Errorf("teams shifts sync failed: %v", p.syncErr)
+	}
+	return nil
+}
+
+// String returns a string representation of the TeamsShiftsProvider
+func (p *TeamsShiftsProvider) String() string {
+	return fmt.Sprintf("TeamsShiftsProvider{teamId: %s, schedulingGroups: %d, syncInterval: %v, cacheDays: %d, shifts: %d}",
+		p.teamID,
+		len(p.schedulingGroups),
+		p.syncInterval,
+		p.cacheDays,
+		len(p.shifts))
+}
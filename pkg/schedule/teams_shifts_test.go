@@ -0,0 +1,103 @@
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTeamsShiftsProvider_IsWorkTime(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Hour)
+	shift := func(userID, group string, start, end time.Time) string {
+		return fmt.Sprintf(`{"userId": %q, "schedulingGroupId": %q, "sharedShift": {"startDateTime": %q, "endDateTime": %q}}`,
+			userID, group, start.Format(time.RFC3339), end.Format(time.RFC3339))
+	}
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/teams/team-1/schedule/shifts" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.URL.Query().Get("page") == "" {
+			_, _ = fmt.Fprintf(w, `{"value": [%s, %s], "@odata.nextLink": %q}`,
+				shift("user-1", "ops", now.Add(-time.Hour), now.Add(2*time.Hour)),
+				shift("user-2", "support", now.Add(3*time.Hour), now.Add(4*time.Hour)),
+				server.URL+"/teams/team-1/schedule/shifts?page=2")
+			return
+		}
+		_, _ = fmt.Fprintf(w, `{"value": [%s, {"userId": "", "sharedShift": null}]}`,
+			shift("user-3", "ops", now.Add(5*time.Hour), now.Add(6*time.Hour)))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name      string
+		groups    []string
+		checkTime time.Time
+		want      bool
+	}{
+		{
+			name:      "Shift In Progress",
+			checkTime: now,
+			want:      true,
+		},
+		{
+			name:      "Between Shifts",
+			checkTime: now.Add(150 * time.Minute),
+			want:      false,
+		},
+		{
+			name:      "Shift On Next Page",
+			checkTime: now.Add(5*time.Hour + 30*time.Minute),
+			want:      true,
+		},
+		{
+			name:      "Shift Of Other Scheduling Group",
+			groups:    []string{"ops"},
+			checkTime: now.Add(3*time.Hour + 30*time.Minute),
+			want:      false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider, err := newTeamsShiftsProvider(context.Background(), server.Client(), server.URL, TeamsShiftsOptions{
+				TeamID:             "team-1",
+				SchedulingGroupIDs: tt.groups,
+				SyncInterval:       time.Hour,
+				CacheDays:          7,
+			})
+			if err != nil {
+				t.Fatalf("newTeamsShiftsProvider() error = %v", err)
+			}
+
+			got, err := provider.IsWorkTime(context.Background(), tt.checkTime)
+			if err != nil {
+				t.Fatalf("IsWorkTime() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("IsWorkTime() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTeamsShiftsProvider_SyncError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error": {"code": "Forbidden"}}`, http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	_, err := newTeamsShiftsProvider(context.Background(), server.Client(), server.URL, TeamsShiftsOptions{
+		TeamID:       "team-1",
+		SyncInterval: time.Hour,
+		CacheDays:    7,
+	})
+	if err == nil {
+		t.Error("newTeamsShiftsProvider() error = nil, want error for a failed initial sync")
+	}
+}
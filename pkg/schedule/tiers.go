@@ -0,0 +1,85 @@
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// TierProvider is implemented by schedules that name the capacity tier of a time,
+// so that node pools can run at reduced instead of off-time capacity
+type TierProvider interface {
+	// Tier returns the name of the tier at the given time, empty if no tier applies
+	Tier(ctx context.Context, t time.Time) (string, error)
+	// NextChange returns the earliest time after from at which the tier may change,
+	// ok is false if it doesn't change within the horizon
+	NextChange(ctx context.Context, from time.Time, horizon time.Duration) (time.Time, bool, error)
+}
+
+// Tier is a named window of reduced capacity, e.g. "half" in the evening
+type Tier struct {
+	// Name is referenced by the tier counts of the node pools
+	Name string
+	// Schedule decides when the tier applies, it applies whenever the schedule says it's work time
+	Schedule Provider
+}
+
+// TierSchedule picks the capacity tier outside work time, the first matching tier wins
+type TierSchedule struct {
+	tiers []Tier
+}
+
+// NewTierSchedule creates a new tier schedule, tiers are matched in the given order
+func NewTierSchedule(tiers ...Tier) *TierSchedule {
+	return &TierSchedule{tiers: tiers}
+}
+
+// Tier returns the name of the first tier applying at the given time, empty if none does
+func (s *TierSchedule) Tier(ctx context.Context, t time.Time) (string, error) {
+	for _, tier := range s.tiers {
+		applies, err := tier.Schedule.IsWorkTime(ctx, t)
+		if err != nil {
+			return "", fmt.Errorf("failed to check tier %s: %v", tier.Name, err)
+		}
+		if applies {
+			slog.Debug("Capacity tier applies", "tier", tier.Name)
+			return tier.Name, nil
+		}
+	}
+	return "", nil
+}
+
+// NextChange returns the earliest time after from at which any tier starts or ends,
+// ok is false if none does within the horizon
+func (s *TierSchedule) NextChange(ctx context.Context, from time.Time, horizon time.Duration) (time.Time, bool, error) {
+	var next time.Time
+	found := false
+	for _, tier := range s.tiers {
+		change, ok, err := NextChange(ctx, tier.Schedule, from, horizon)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("failed to check tier %s: %v", tier.Name, err)
+		}
+		if ok && (!found || change.Before(next)) {
+			next, found = change, true
+		}
+	}
+	return next, found, nil
+}
+
+// Close stops the background syncs of the tier schedules, e.g. of on-call tiers
+func (s *TierSchedule) Close(ctx context.Context) {
+	for _, tier := range s.tiers {
+		Close(ctx, tier.Schedule)
+	}
+}
+
+// String returns a string representation of the TierSchedule
+func (s *TierSchedule) String() string {
+	names := make([]string, 0, len(s.tiers))
+	for _, tier := range s.tiers {
+		names = append(names, tier.Name)
+	}
+	return fmt.Sprintf("TierSchedule{tiers: [%s]}", strings.Join(names, ", "))
+}
@@ -0,0 +1,48 @@
+package schedule
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTierSchedule_Tier(t *testing.T) {
+	tiers := NewTierSchedule(
+		Tier{Name: "half", Schedule: NewStaticProvider("18:00", "22:00", "UTC", nil)},
+		Tier{Name: "evening", Schedule: NewStaticProvider("17:00", "23:00", "UTC", nil)},
+	)
+
+	tests := []struct {
+		name      string
+		checkTime time.Time
+		want      string
+	}{
+		{
+			name:      "First Matching Tier Wins",
+			checkTime: time.Date(2024, time.June, 3, 19, 0, 0, 0, time.UTC),
+			want:      "half",
+		},
+		{
+			name:      "Second Tier",
+			checkTime: time.Date(2024, time.June, 3, 22, 30, 0, 0, time.UTC),
+			want:      "evening",
+		},
+		{
+			name:      "No Tier",
+			checkTime: time.Date(2024, time.June, 4, 2, 0, 0, 0, time.UTC),
+			want:      "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tiers.Tier(context.Background(), tt.checkTime)
+			if err != nil {
+				t.Fatalf("Tier() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Tier() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package schedule
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const (
+	// wattTimeAPIURL is the base URL of the WattTime API
+	wattTimeAPIURL = "https://api.watttime.org"
+	// lbsPerMWhInGramsPerKWh converts the marginal emissions of WattTime to gCO2eq/kWh
+	lbsPerMWhInGramsPerKWh = 0.453592
+)
+
+// wattTimeSource forecasts the marginal carbon intensity of a region with WattTime
+type wattTimeSource struct {
+	client   httpClient
+	baseURL  string
+	username string
+	password string
+	region   string
+}
+
+// wattTimeForecastResponse is the subset of the WattTime forecast response we need
+type wattTimeForecastResponse struct {
+	Data []struct {
+		PointTime time.Time `json:"point_time"`
+		Value     float64   `json:"value"`
+	} `json:"data"`
+}
+
+// newWattTimeSource creates a new WattTime source logging in with the credentials
+func newWattTimeSource(baseURL, username, password, region string) *wattTimeSource {
+	return &wattTimeSource{
+		client:   &http.Client{Timeout: 30 * time.Second},
+		baseURL:  baseURL,
+		username: username,
+		password: password,
+		region:   region,
+	}
+}
+
+// forecast returns the marginal carbon intensity forecast of the region, in 5 minute steps.
+// WattTime tokens expire after 30 minutes, so it logs in for every forecast.
+func (s *wattTimeSource) forecast(ctx context.Context) ([]carbonIntensity, error) {
+	token, err := s.login(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	params := url.Values{}
+	params.Set("region", s.region)
+	params.Set("signal_type", "co2_moer")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.baseURL+"/v3/forecast?"+params.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create WattTime request: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	var forecast wattTimeForecastResponse
+	if err := s.get(req, &forecast); err != nil {
+		return nil, fmt.Errorf("failed to get WattTime forecast: %v", err)
+	}
+	intensities := make([]carbonIntensity, 0, len(forecast.Data))
+	for _, point := range forecast.Data {
+		intensities = append(intensities, carbonIntensity{At: point.PointTime, Intensity: point.Value * lbsPerMWhInGramsPerKWh})
+	}
+	return intensities, nil
+}
+
+// login returns a token for the credentials
+func (s *wattTimeSource) login(ctx context.Context) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.baseURL+"/login", nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create WattTime login request: %v", err)
+	}
+	req.SetBasicAuth(s.username, s.password)
+
+	var login struct {
+		Token string `json:"token"`
+	}
+	if err := s.get(req, &login); err != nil {
+		return "", fmt.Errorf("failed to log in to WattTime: %v", err)
+	}
+	return login.Token, nil
+}
+
+// get sends the request and decodes the JSON response into v
+func (s *wattTimeSource) get(req *http.Request, v interface{}) error {
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("status %d: %s", resp.StatusCode, body)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode response: %v", err)
+	}
+	return nil
+}
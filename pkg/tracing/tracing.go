@@ -0,0 +1,87 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+const (
+	// defaultServiceName is the service name of the spans if OTEL_SERVICE_NAME isn't set
+	defaultServiceName = "bmw-saver"
+	// exportInterval is how often the ended spans are exported
+	exportInterval = 5 * time.Second
+	// maxQueuedSpans is how many ended spans are kept until they are exported, further spans are dropped
+	maxQueuedSpans = 2048
+)
+
+// Options contains settings for exporting spans
+type Options struct {
+	// Endpoint is the URL the spans are posted to, e.g. "http://otel-collector:4318/v1/traces"
+	Endpoint string
+	// Headers are sent with every export, e.g. for authentication
+	Headers map[string]string
+	// ServiceName is the service.name resource attribute of the spans
+	ServiceName string
+}
+
+// OptionsFromEnv returns the options from the standard OpenTelemetry environment variables,
+// and false if no OTLP endpoint is configured
+func OptionsFromEnv() (Options, bool) {
+	opts := Options{
+		Endpoint:    os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"),
+		Headers:     parseHeaders(os.Getenv("OTEL_EXPORTER_OTLP_HEADERS")),
+		ServiceName: os.Getenv("OTEL_SERVICE_NAME"),
+	}
+	if opts.Endpoint == "" {
+		if endpoint := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"); endpoint != "" {
+			opts.Endpoint = strings.TrimSuffix(endpoint, "/") + "/v1/traces"
+		}
+	}
+	for key, value := range parseHeaders(os.Getenv("OTEL_EXPORTER_OTLP_TRACES_HEADERS")) {
+		opts.Headers[key] = value
+	}
+	if opts.ServiceName == "" {
+		opts.ServiceName = defaultServiceName
+	}
+	return opts, opts.Endpoint != ""
+}
+
+// parseHeaders parses headers in the format "key1=value1,key2=value2"
+func parseHeaders(headers string) map[string]string {
+	parsed := make(map[string]string)
+	for _, header := range strings.Split(headers, ",") {
+		key, value, ok := strings.Cut(header, "=")
+		if ok && strings.TrimSpace(key) != "" {
+			parsed[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+	return parsed
+}
+
+// NewProvider creates a tracer provider batching the ended spans and exporting them over OTLP/HTTP in the
+// background until it is shut down
+func NewProvider(ctx context.Context, opts Options) (*sdktrace.TracerProvider, error) {
+	exporter, err := otlptracehttp.New(ctx,
+		otlptracehttp.WithEndpointURL(opts.Endpoint),
+		otlptracehttp.WithHeaders(opts.Headers),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP exporter: %v", err)
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(semconv.ServiceName(opts.ServiceName)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create resource: %v", err)
+	}
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter, sdktrace.WithBatchTimeout(exportInterval), sdktrace.WithMaxQueueSize(maxQueuedSpans)),
+		sdktrace.WithResource(res),
+	), nil
+}
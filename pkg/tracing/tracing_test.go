@@ -0,0 +1,101 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestProvider(t *testing.T) {
+	var got coltracepb.ExportTraceServiceRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" {
+			t.Errorf("Exported to %s, want /v1/traces", r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			t.Errorf("Authorization header = %q", r.Header.Get("Authorization"))
+		}
+		body, _ := io.ReadAll(r.Body)
+		if err := proto.Unmarshal(body, &got); err != nil {
+			t.Errorf("Failed to decode spans: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/x-protobuf")
+	}))
+	defer server.Close()
+
+	provider, err := NewProvider(context.Background(), Options{
+		Endpoint:    server.URL + "/v1/traces",
+		Headers:     map[string]string{"Authorization": "Bearer secret"},
+		ServiceName: "bmw-saver",
+	})
+	if err != nil {
+		t.Fatalf("NewProvider() error = %v", err)
+	}
+	tracer := provider.Tracer("test")
+
+	ctx, parent := tracer.Start(context.Background(), "reconcile")
+	_, child := tracer.Start(ctx, "ScaleNodePool", trace.WithAttributes(attribute.String("node_pool", "default-pool")))
+	child.RecordError(errors.New("quota exceeded"))
+	child.SetStatus(codes.Error, "quota exceeded")
+	child.End()
+	parent.End()
+
+	// The batched spans are exported on shutdown
+	if err := provider.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+
+	if len(got.ResourceSpans) != 1 || len(got.ResourceSpans[0].ScopeSpans) != 1 {
+		t.Fatalf("Exported spans = %v, want one scope", &got)
+	}
+	serviceName := ""
+	for _, kv := range got.ResourceSpans[0].Resource.GetAttributes() {
+		if kv.Key == "service.name" {
+			serviceName = kv.Value.GetStringValue()
+		}
+	}
+	if serviceName != "bmw-saver" {
+		t.Errorf("service.name = %q, want bmw-saver", serviceName)
+	}
+	spans := got.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 2 {
+		t.Fatalf("Exported %d spans, want 2", len(spans))
+	}
+	scaled, reconciled := spans[0], spans[1]
+	if scaled.Name != "ScaleNodePool" || reconciled.Name != "reconcile" {
+		t.Errorf("Span names = %q, %q", scaled.Name, reconciled.Name)
+	}
+	if !bytes.Equal(scaled.TraceId, reconciled.TraceId) || !bytes.Equal(scaled.ParentSpanId, reconciled.SpanId) || len(reconciled.ParentSpanId) != 0 {
+		t.Errorf("ScaleNodePool span = %v is not a child of reconcile span = %v", scaled, reconciled)
+	}
+	if scaled.Status.GetCode() != tracepb.Status_STATUS_CODE_ERROR || scaled.Status.GetMessage() != "quota exceeded" || len(scaled.Events) != 1 {
+		t.Errorf("ScaleNodePool status = %v, events = %v, want an error", scaled.Status, scaled.Events)
+	}
+	if len(scaled.Attributes) != 1 || scaled.Attributes[0].Value.GetStringValue() != "default-pool" {
+		t.Errorf("ScaleNodePool attributes = %v", scaled.Attributes)
+	}
+}
+
+func TestOptionsFromEnv(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://otel-collector:4318/")
+	t.Setenv("OTEL_EXPORTER_OTLP_HEADERS", "x-api-key=secret, x-team = platform")
+
+	opts, ok := OptionsFromEnv()
+	if !ok || opts.Endpoint != "http://otel-collector:4318/v1/traces" || opts.ServiceName != "bmw-saver" {
+		t.Errorf("OptionsFromEnv() = %+v, %v", opts, ok)
+	}
+	if opts.Headers["x-api-key"] != "secret" || opts.Headers["x-team"] != "platform" {
+		t.Errorf("Headers = %v", opts.Headers)
+	}
+}
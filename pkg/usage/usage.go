@@ -0,0 +1,209 @@
+// Package usage tracks the sizes and CPU utilizations of node pools observed during work time, so that they
+// can be restored to the size they actually needed on the last workdays rather than to their saved size,
+// and recommendations can be made to right-size them.
+package usage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kezhenxu94/bmw-saver/pkg/schedule"
+)
+
+// MaxDays is how many of the last days with observations are kept per node pool
+const MaxDays = 30
+
+// dayLayout is the layout of the days of observations
+const dayLayout = "2006-01-02"
+
+// Tracker records the sizes and CPU utilizations of node pools observed during work time per day, and persists
+// them in a store so that they survive restarts. It is safe for concurrent use.
+type Tracker struct {
+	store schedule.CacheStore
+	key   string
+	mu    sync.Mutex
+	pools map[string]*poolUsage
+}
+
+// poolUsage is what was observed of a node pool
+type poolUsage struct {
+	// Sizes maps days to node counts to how often they were observed on the day
+	Sizes observations `json:"sizes,omitempty"`
+	// CPU maps days to CPU utilizations in percent to how often they were observed on the day
+	CPU observations `json:"cpu,omitempty"`
+}
+
+// observations maps days to values to how often they were observed on the day
+type observations map[string]map[int]int
+
+// NewTracker creates a new tracker and loads the observations from key of the store, which may be nil
+func NewTracker(ctx context.Context, store schedule.CacheStore, key string) (*Tracker, error) {
+	t := &Tracker{
+		store: store,
+		key:   key,
+		pools: make(map[string]*poolUsage),
+	}
+	if store == nil {
+		return t, nil
+	}
+
+	data, err := store.Load(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load usage: %v", err)
+	}
+	if data != nil {
+		if err := json.Unmarshal(data, &t.pools); err != nil {
+			return nil, fmt.Errorf("failed to parse usage: %v", err)
+		}
+	}
+	return t, nil
+}
+
+// Observe records the size of the node pool at now, days beyond the last MaxDays are forgotten
+func (t *Tracker) Observe(nodePool string, size int, now time.Time) {
+	if t == nil || size < 0 {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	pool := t.pool(nodePool)
+	if pool.Sizes == nil {
+		pool.Sizes = make(observations)
+	}
+	pool.Sizes.add(size, now)
+}
+
+// ObserveCPU records the CPU utilization of the node pool at now as a fraction of its allocatable CPU
+func (t *Tracker) ObserveCPU(nodePool string, utilization float64, now time.Time) {
+	if t == nil || utilization < 0 {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	pool := t.pool(nodePool)
+	if pool.CPU == nil {
+		pool.CPU = make(observations)
+	}
+	pool.CPU.add(int(math.Round(utilization*100)), now)
+}
+
+// Percentile returns the percentile of the sizes of the node pool observed on its last workdays, by the
+// nearest rank. ok is false until sizes were observed on as many days.
+func (t *Tracker) Percentile(nodePool string, workdays int, percentile float64) (size int, ok bool) {
+	if t == nil || workdays <= 0 {
+		return 0, false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	pool, found := t.pools[nodePool]
+	if !found {
+		return 0, false
+	}
+	return pool.Sizes.percentile(workdays, percentile)
+}
+
+// CPUPercentile returns the percentile of the CPU utilizations of the node pool observed on its last workdays
+// as a fraction, by the nearest rank. ok is false until they were observed on as many days.
+func (t *Tracker) CPUPercentile(nodePool string, workdays int, percentile float64) (utilization float64, ok bool) {
+	if t == nil || workdays <= 0 {
+		return 0, false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	pool, found := t.pools[nodePool]
+	if !found {
+		return 0, false
+	}
+	value, ok := pool.CPU.percentile(workdays, percentile)
+	return float64(value) / 100, ok
+}
+
+// Save persists the observations in the store
+func (t *Tracker) Save(ctx context.Context) error {
+	if t == nil || t.store == nil {
+		return nil
+	}
+	t.mu.Lock()
+	data, err := json.Marshal(t.pools)
+	t.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to marshal usage: %v", err)
+	}
+	return t.store.Save(ctx, t.key, data)
+}
+
+// pool returns the observations of the node pool, adding them if they don't exist yet
+func (t *Tracker) pool(nodePool string) *poolUsage {
+	pool, ok := t.pools[nodePool]
+	if !ok {
+		pool = &poolUsage{}
+		t.pools[nodePool] = pool
+	}
+	return pool
+}
+
+// add records the value observed at now, days beyond the last MaxDays are forgotten
+func (o observations) add(value int, now time.Time) {
+	day := now.Format(dayLayout)
+	if o[day] == nil {
+		o[day] = make(map[int]int)
+	}
+	o[day][value]++
+
+	recent := o.sortedDays()
+	for len(recent) > MaxDays {
+		delete(o, recent[len(recent)-1])
+		recent = recent[:len(recent)-1]
+	}
+}
+
+// percentile returns the percentile of the values observed on the last days by the nearest rank,
+// ok is false until values were observed on as many days
+func (o observations) percentile(days int, percentile float64) (int, bool) {
+	recent := o.sortedDays()
+	if len(recent) < days {
+		return 0, false
+	}
+
+	counts := make(map[int]int)
+	total := 0
+	for _, day := range recent[:days] {
+		for value, n := range o[day] {
+			counts[value] += n
+			total += n
+		}
+	}
+	values := make([]int, 0, len(counts))
+	for value := range counts {
+		values = append(values, value)
+	}
+	sort.Ints(values)
+
+	rank := max(int(math.Ceil(percentile/100*float64(total))), 1)
+	for _, value := range values {
+		rank -= counts[value]
+		if rank <= 0 {
+			return value, true
+		}
+	}
+	return values[len(values)-1], true
+}
+
+// sortedDays returns the days with observations, the most recent first
+func (o observations) sortedDays() []string {
+	sorted := make([]string, 0, len(o))
+	for day := range o {
+		sorted = append(sorted, day)
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(sorted)))
+	return sorted
+}
@@ -0,0 +1,121 @@
+package usage
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// memoryStore is a cache store in memory
+type memoryStore map[string][]byte
+
+func (s memoryStore) Load(ctx context.Context, key string) ([]byte, error) {
+	return s[key], nil
+}
+
+func (s memoryStore) Save(ctx context.Context, key string, data []byte) error {
+	s[key] = data
+	return nil
+}
+
+func TestTracker(t *testing.T) {
+	store := memoryStore{}
+	tracker, err := NewTracker(context.Background(), store, "usage")
+	if err != nil {
+		t.Fatalf("NewTracker() error = %v", err)
+	}
+
+	monday := time.Date(2024, time.June, 3, 9, 0, 0, 0, time.UTC)
+	// 20 samples a day, mostly 3 nodes with a peak of 6 nodes on Monday only
+	for day := 0; day < 3; day++ {
+		for i := 0; i < 20; i++ {
+			size := 3
+			if day == 0 && i == 0 {
+				size = 6
+			}
+			if day == 2 && i < 2 {
+				size = 4
+			}
+			tracker.Observe("default-pool", size, monday.AddDate(0, 0, day).Add(time.Duration(i)*15*time.Minute))
+		}
+	}
+
+	tests := []struct {
+		name       string
+		workdays   int
+		percentile float64
+		want       int
+		wantOK     bool
+	}{
+		{name: "not enough workdays", workdays: 4, percentile: 95, wantOK: false},
+		{name: "last workday", workdays: 1, percentile: 95, want: 4, wantOK: true},
+		{name: "p95 of all workdays", workdays: 3, percentile: 95, want: 3, wantOK: true},
+		{name: "p98 of all workdays", workdays: 3, percentile: 98, want: 4, wantOK: true},
+		{name: "max of all workdays", workdays: 3, percentile: 100, want: 6, wantOK: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tracker.Percentile("default-pool", tt.workdays, tt.percentile)
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("Percentile() = %d, %v, want %d, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+
+	if err := tracker.Save(context.Background()); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	loaded, err := NewTracker(context.Background(), store, "usage")
+	if err != nil {
+		t.Fatalf("NewTracker() error = %v", err)
+	}
+	if got, ok := loaded.Percentile("default-pool", 3, 100); !ok || got != 6 {
+		t.Errorf("Loaded Percentile() = %d, %v, want 6, true", got, ok)
+	}
+}
+
+func TestTracker_ForgetsOldDays(t *testing.T) {
+	tracker, err := NewTracker(context.Background(), nil, "usage")
+	if err != nil {
+		t.Fatalf("NewTracker() error = %v", err)
+	}
+
+	start := time.Date(2024, time.June, 1, 12, 0, 0, 0, time.UTC)
+	tracker.Observe("default-pool", 10, start)
+	for day := 1; day <= MaxDays; day++ {
+		tracker.Observe("default-pool", 2, start.AddDate(0, 0, day))
+	}
+	if got, ok := tracker.Percentile("default-pool", MaxDays, 100); !ok || got != 2 {
+		t.Errorf("Percentile() = %d, %v, want 2, true", got, ok)
+	}
+	if _, ok := tracker.Percentile("default-pool", MaxDays+1, 100); ok {
+		t.Errorf("Percentile() over %d days is ok, want the oldest day forgotten", MaxDays+1)
+	}
+}
+
+func TestTracker_CPU(t *testing.T) {
+	tracker, err := NewTracker(context.Background(), nil, "usage")
+	if err != nil {
+		t.Fatalf("NewTracker() error = %v", err)
+	}
+
+	monday := time.Date(2024, time.June, 3, 9, 0, 0, 0, time.UTC)
+	for day := 0; day < 2; day++ {
+		for _, utilization := range []float64{0.1, 0.12, 0.25, 0.4} {
+			tracker.ObserveCPU("default-pool", utilization, monday.AddDate(0, 0, day))
+		}
+	}
+
+	if _, ok := tracker.CPUPercentile("default-pool", 3, 95); ok {
+		t.Errorf("CPUPercentile() over 3 workdays is ok, want not enough workdays")
+	}
+	if got, ok := tracker.CPUPercentile("default-pool", 2, 95); !ok || got != 0.4 {
+		t.Errorf("CPUPercentile() = %v, %v, want 0.4, true", got, ok)
+	}
+	if got, ok := tracker.CPUPercentile("default-pool", 2, 50); !ok || got != 0.12 {
+		t.Errorf("CPUPercentile() = %v, %v, want 0.12, true", got, ok)
+	}
+	if _, ok := tracker.Percentile("default-pool", 1, 95); ok {
+		t.Errorf("Percentile() is ok, want no sizes observed")
+	}
+}
@@ -0,0 +1,89 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/kezhenxu94/bmw-saver/pkg/config"
+	"github.com/kezhenxu94/bmw-saver/pkg/schedule"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// ConfigMapName is the ConfigMap of the controller configuration, the only ConfigMap that is validated
+	ConfigMapName = "bmw-saver-config"
+	// configDataKey is the key of the configuration in the ConfigMap
+	configDataKey = "config.yaml"
+)
+
+// Handler serves a validating admission webhook, rejecting an invalid configuration in the bmw-saver-config
+// ConfigMap and invalid Schedule resources at apply time, instead of them being logged and ignored on reload
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var review admissionv1.AdmissionReview
+		if err := json.NewDecoder(r.Body).Decode(&review); err != nil {
+			http.Error(w, "invalid admission review: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if review.Request == nil {
+			http.Error(w, "admission review without request", http.StatusBadRequest)
+			return
+		}
+
+		response := &admissionv1.AdmissionResponse{UID: review.Request.UID, Allowed: true}
+		if err := validate(review.Request); err != nil {
+			slog.Info("Rejected invalid resource",
+				"kind", review.Request.Kind.Kind,
+				"name", review.Request.Name,
+				"namespace", review.Request.Namespace,
+				"error", err,
+			)
+			response.Allowed = false
+			response.Result = &metav1.Status{
+				Status:  metav1.StatusFailure,
+				Message: err.Error(),
+				Reason:  metav1.StatusReasonInvalid,
+				Code:    http.StatusUnprocessableEntity,
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(admissionv1.AdmissionReview{
+			TypeMeta: review.TypeMeta,
+			Response: response,
+		}); err != nil {
+			slog.Error("Failed to write admission response", "error", err)
+		}
+	})
+}
+
+// validate returns why the object of the request is invalid, nil if it is valid or not validated
+func validate(request *admissionv1.AdmissionRequest) error {
+	if request.Operation != admissionv1.Create && request.Operation != admissionv1.Update {
+		return nil
+	}
+
+	switch request.Kind.Kind {
+	case "ConfigMap":
+		var cm corev1.ConfigMap
+		if err := json.Unmarshal(request.Object.Raw, &cm); err != nil {
+			return fmt.Errorf("failed to parse ConfigMap: %v", err)
+		}
+		if cm.Name != ConfigMapName {
+			return nil
+		}
+		if _, err := config.ReadConfigFromBytes([]byte(cm.Data[configDataKey])); err != nil {
+			return fmt.Errorf("invalid %s: %v", configDataKey, err)
+		}
+	case "Schedule":
+		if err := schedule.ValidateSchedule(request.Object.Raw); err != nil {
+			return fmt.Errorf("invalid Schedule: %v", err)
+		}
+	}
+	return nil
+}
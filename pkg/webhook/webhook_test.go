@@ -0,0 +1,126 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestHandler(t *testing.T) {
+	validConfig := `
+schedule:
+  startTime: "09:00"
+  endTime: "18:00"
+  timeZone: "Europe/Berlin"
+nodeSpecs:
+  - nodePoolName: "default-pool"
+    cloudProvider: "gke"
+`
+
+	tests := []struct {
+		name        string
+		kind        string
+		operation   admissionv1.Operation
+		object      interface{}
+		wantAllowed bool
+	}{
+		{
+			name:        "Valid Config",
+			kind:        "ConfigMap",
+			operation:   admissionv1.Create,
+			object:      configMap(ConfigMapName, validConfig),
+			wantAllowed: true,
+		},
+		{
+			name:      "Invalid Time Zone",
+			kind:      "ConfigMap",
+			operation: admissionv1.Update,
+			object:    configMap(ConfigMapName, `schedule: {startTime: "09:00", endTime: "18:00", timeZone: "Europe/Berln"}`),
+		},
+		{
+			name:      "Unsupported Cloud Provider",
+			kind:      "ConfigMap",
+			operation: admissionv1.Update,
+			object: configMap(ConfigMapName, `
+schedule: {startTime: "09:00", endTime: "18:00", timeZone: "UTC"}
+nodeSpecs: [{nodePoolName: "default-pool", cloudProvider: "gcp"}]`),
+		},
+		{
+			name:        "Other ConfigMap",
+			kind:        "ConfigMap",
+			operation:   admissionv1.Create,
+			object:      configMap("other", "not: [valid"),
+			wantAllowed: true,
+		},
+		{
+			name:      "Invalid Schedule",
+			kind:      "Schedule",
+			operation: admissionv1.Create,
+			object: map[string]interface{}{
+				"metadata": map[string]interface{}{"name": "team-a"},
+				"spec": map[string]interface{}{
+					"nodePools": []string{"team-a-pool"},
+					"timeZone":  "UTC",
+					"windows":   []interface{}{map[string]interface{}{"days": []string{"monday"}, "startTime": "9am", "endTime": "18:00"}},
+				},
+			},
+		},
+		{
+			name:        "Delete",
+			kind:        "Schedule",
+			operation:   admissionv1.Delete,
+			wantAllowed: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := &admissionv1.AdmissionRequest{
+				UID:       "uid",
+				Kind:      metav1.GroupVersionKind{Kind: tt.kind},
+				Operation: tt.operation,
+			}
+			if tt.object != nil {
+				raw, err := json.Marshal(tt.object)
+				if err != nil {
+					t.Fatalf("Failed to marshal object: %v", err)
+				}
+				request.Object = runtime.RawExtension{Raw: raw}
+			}
+			body, _ := json.Marshal(admissionv1.AdmissionReview{
+				TypeMeta: metav1.TypeMeta{APIVersion: "admission.k8s.io/v1", Kind: "AdmissionReview"},
+				Request:  request,
+			})
+
+			recorder := httptest.NewRecorder()
+			Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/validate", bytes.NewReader(body)))
+			if recorder.Code != http.StatusOK {
+				t.Fatalf("Status = %d, want 200: %s", recorder.Code, recorder.Body.String())
+			}
+
+			var review admissionv1.AdmissionReview
+			if err := json.Unmarshal(recorder.Body.Bytes(), &review); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if review.Response.UID != "uid" {
+				t.Errorf("UID = %q, want the request UID", review.Response.UID)
+			}
+			if review.Response.Allowed != tt.wantAllowed {
+				t.Errorf("Allowed = %v, want %v (%+v)", review.Response.Allowed, tt.wantAllowed, review.Response.Result)
+			}
+		})
+	}
+}
+
+func configMap(name, data string) map[string]interface{} {
+	return map[string]interface{}{
+		"metadata": map[string]interface{}{"name": name},
+		"data":     map[string]string{configDataKey: data},
+	}
+}